@@ -3,11 +3,13 @@ package ai
 
 // TranscriptSegment сегмент с таймстемпами
 type TranscriptSegment struct {
-	Start   int64            // миллисекунды
-	End     int64            // миллисекунды
-	Text    string           // полный текст сегмента
-	Words   []TranscriptWord // слова с точными timestamps (word-level)
-	Speaker string           // идентификатор спикера
+	Start          int64            // миллисекунды
+	End            int64            // миллисекунды
+	Text           string           // полный текст сегмента
+	Words          []TranscriptWord // слова с точными timestamps (word-level)
+	Speaker        string           // идентификатор спикера
+	Overlap        bool             // наложение речи: одновременно со Speaker говорит OverlapSpeaker
+	OverlapSpeaker string           // второй спикер при наложении речи
 }
 
 // TranscriptWord слово с точными таймстемпами
@@ -56,6 +58,21 @@ type TranscriptionEngine interface {
 	SupportedLanguages() []string
 }
 
+// ProgressFunc получает прогресс текущего вызова распознавания (0-100)
+type ProgressFunc func(percent int)
+
+// StreamingProgressEngine движок, который сообщает прогресс внутри одного вызова
+// распознавания (whisper.cpp - через progress callback)
+type StreamingProgressEngine interface {
+	TranscribeWithSegmentsProgress(samples []float32, onProgress ProgressFunc) ([]TranscriptSegment, error)
+}
+
+// PromptContextEngine движок, который учитывает текст, предшествующий фрагменту (Whisper - initial prompt).
+// Контекст передаётся в каждый вызов, поэтому каналы и чанки, распознаваемые параллельно, не мешают друг другу
+type PromptContextEngine interface {
+	TranscribeWithPrompt(samples []float32, previousText string, onProgress ProgressFunc) ([]TranscriptSegment, error)
+}
+
 // EngineType тип движка транскрипции
 type EngineType string
 
@@ -3,11 +3,23 @@ package ai
 
 import (
 	"aiwisper/models"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 )
 
+// minIdleCheckInterval минимальный интервал проверки простоя движка
+const minIdleCheckInterval = time.Second
+
+// maxIdleCheckInterval максимальный интервал проверки простоя движка
+const maxIdleCheckInterval = 30 * time.Second
+
+// errNoActiveEngine модель не выбрана
+var errNoActiveEngine = errors.New("no active engine")
+
 // EngineManager управляет движками транскрипции
 // Позволяет переключаться между Whisper и GigaAM
 type EngineManager struct {
@@ -15,6 +27,26 @@ type EngineManager struct {
 	activeEngine  TranscriptionEngine
 	activeModelID string
 	mu            sync.RWMutex
+
+	// Выгрузка движка при простое. После выгрузки activeModelID сохраняется,
+	// и движок загружается заново при следующем обращении
+	idleTimeout    time.Duration
+	lastUsed       time.Time
+	inFlight       int           // Количество выполняющихся транскрипций
+	reaperStop     chan struct{} // Останавливает фоновую проверку простоя
+	language       string        // Восстанавливается после повторной загрузки
+	pauseThreshold float64       // Восстанавливается после повторной загрузки (FluidASR)
+	hotwords       []string      // Восстанавливаются после повторной загрузки
+	reloading      *engineReload // Выполняющаяся загрузка после простоя (nil - нет)
+
+	// newEngine создаёт движок при повторной загрузке (nil - CreateEngineForModel)
+	newEngine func(modelID string) (TranscriptionEngine, error)
+}
+
+// engineReload загрузка модели после выгрузки по простою, которую ждут одновременные обращения
+type engineReload struct {
+	done chan struct{}
+	err  error
 }
 
 // NewEngineManager создаёт новый менеджер движков
@@ -24,11 +56,28 @@ func NewEngineManager(modelsManager *models.Manager) *EngineManager {
 	}
 }
 
-// GetActiveEngine возвращает активный движок
+// GetActiveEngine возвращает активный движок.
+// Если движок был выгружен по простою, он загружается заново. Для проверки,
+// выбрана ли модель, нужен HasActiveEngine: он не загружает модель
 func (em *EngineManager) GetActiveEngine() TranscriptionEngine {
+	if err := em.lockLoadedEngine(); err != nil {
+		if !errors.Is(err, errNoActiveEngine) {
+			log.Printf("EngineManager: %v", err)
+		}
+		return nil
+	}
+	defer em.mu.Unlock()
+
+	em.lastUsed = time.Now()
+	return em.activeEngine
+}
+
+// HasActiveEngine проверяет, что модель выбрана: движок загружен или будет загружен
+// при следующем обращении после выгрузки по простою
+func (em *EngineManager) HasActiveEngine() bool {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
-	return em.activeEngine
+	return em.activeEngine != nil || em.activeModelID != ""
 }
 
 // GetActiveModelID возвращает ID активной модели
@@ -112,6 +161,7 @@ func (em *EngineManager) SetActiveModel(modelID string) error {
 
 	em.activeEngine = newEngine
 	em.activeModelID = modelID
+	em.lastUsed = time.Now()
 
 	// Обновляем активную модель в models.Manager
 	if err := em.modelsManager.SetActiveModel(modelID); err != nil {
@@ -124,20 +174,34 @@ func (em *EngineManager) SetActiveModel(modelID string) error {
 
 // SetLanguage устанавливает язык для активного движка
 func (em *EngineManager) SetLanguage(lang string) {
-	em.mu.RLock()
+	em.mu.Lock()
 	engine := em.activeEngine
-	em.mu.RUnlock()
+	em.language = lang
+	em.mu.Unlock()
 
 	if engine != nil {
 		engine.SetLanguage(lang)
 	}
 }
 
+// SetHotwords устанавливает словарь подсказок активному движку и сохраняет его для повторной загрузки
+func (em *EngineManager) SetHotwords(words []string) {
+	em.mu.Lock()
+	engine := em.activeEngine
+	em.hotwords = words
+	em.mu.Unlock()
+
+	if engine != nil {
+		engine.SetHotwords(words)
+	}
+}
+
 // SetPauseThreshold устанавливает порог паузы для сегментации (только для FluidASR)
 func (em *EngineManager) SetPauseThreshold(threshold float64) {
-	em.mu.RLock()
+	em.mu.Lock()
 	engine := em.activeEngine
-	em.mu.RUnlock()
+	em.pauseThreshold = threshold
+	em.mu.Unlock()
 
 	if engine != nil {
 		// Проверяем, поддерживает ли движок SetPauseThreshold
@@ -149,39 +213,70 @@ func (em *EngineManager) SetPauseThreshold(threshold float64) {
 
 // Transcribe транскрибирует аудио через активный движок
 func (em *EngineManager) Transcribe(samples []float32, useContext bool) (string, error) {
-	em.mu.RLock()
-	engine := em.activeEngine
-	em.mu.RUnlock()
-
-	if engine == nil {
-		return "", fmt.Errorf("no active engine")
+	engine, err := em.acquireEngine()
+	if err != nil {
+		return "", err
 	}
+	defer em.releaseEngine()
 
 	return engine.Transcribe(samples, useContext)
 }
 
 // TranscribeWithSegments транскрибирует аудио с сегментами
 func (em *EngineManager) TranscribeWithSegments(samples []float32) ([]TranscriptSegment, error) {
-	em.mu.RLock()
-	engine := em.activeEngine
-	em.mu.RUnlock()
+	engine, err := em.acquireEngine()
+	if err != nil {
+		return nil, err
+	}
+	defer em.releaseEngine()
+
+	return engine.TranscribeWithSegments(samples)
+}
+
+// TranscribeWithSegmentsProgress транскрибирует аудио с сегментами и сообщает прогресс,
+// если движок это поддерживает (StreamingProgressEngine). Иначе onProgress не вызывается
+func (em *EngineManager) TranscribeWithSegmentsProgress(samples []float32, onProgress ProgressFunc) ([]TranscriptSegment, error) {
+	engine, err := em.acquireEngine()
+	if err != nil {
+		return nil, err
+	}
+	defer em.releaseEngine()
+
+	if streaming, ok := engine.(StreamingProgressEngine); ok && onProgress != nil {
+		return streaming.TranscribeWithSegmentsProgress(samples, onProgress)
+	}
+	return engine.TranscribeWithSegments(samples)
+}
+
+// TranscribeWithPrompt как TranscribeWithSegmentsProgress, но передаёт движку текст предыдущего
+// фрагмента как контекст (PromptContextEngine). Движки без поддержки контекста его игнорируют
+func (em *EngineManager) TranscribeWithPrompt(samples []float32, previousText string, onProgress ProgressFunc) ([]TranscriptSegment, error) {
+	if previousText == "" {
+		return em.TranscribeWithSegmentsProgress(samples, onProgress)
+	}
 
-	if engine == nil {
-		return nil, fmt.Errorf("no active engine")
+	engine, err := em.acquireEngine()
+	if err != nil {
+		return nil, err
 	}
+	defer em.releaseEngine()
 
+	if prompted, ok := engine.(PromptContextEngine); ok {
+		return prompted.TranscribeWithPrompt(samples, previousText, onProgress)
+	}
+	if streaming, ok := engine.(StreamingProgressEngine); ok && onProgress != nil {
+		return streaming.TranscribeWithSegmentsProgress(samples, onProgress)
+	}
 	return engine.TranscribeWithSegments(samples)
 }
 
 // TranscribeHighQuality выполняет высококачественную транскрипцию
 func (em *EngineManager) TranscribeHighQuality(samples []float32) ([]TranscriptSegment, error) {
-	em.mu.RLock()
-	engine := em.activeEngine
-	em.mu.RUnlock()
-
-	if engine == nil {
-		return nil, fmt.Errorf("no active engine")
+	engine, err := em.acquireEngine()
+	if err != nil {
+		return nil, err
 	}
+	defer em.releaseEngine()
 
 	return engine.TranscribeHighQuality(samples)
 }
@@ -191,6 +286,11 @@ func (em *EngineManager) Close() {
 	em.mu.Lock()
 	defer em.mu.Unlock()
 
+	if em.reaperStop != nil {
+		close(em.reaperStop)
+		em.reaperStop = nil
+	}
+
 	if em.activeEngine != nil {
 		em.activeEngine.Close()
 		em.activeEngine = nil
@@ -198,6 +298,47 @@ func (em *EngineManager) Close() {
 	em.activeModelID = ""
 }
 
+// EngineCapabilities возможности активной модели, от которых зависят гибридный режим
+// и функции на основе confidence слов
+type EngineCapabilities struct {
+	ModelID            string `json:"modelId"`
+	Engine             string `json:"engine"`
+	WordTimestamps     bool   `json:"wordTimestamps"`     // Таймстемпы и вероятность (P) для каждого слова
+	Language           string `json:"language"`           // Текущий язык распознавания (пусто - по умолчанию)
+	PunctuatesNatively bool   `json:"punctuatesNatively"` // Модель сама расставляет пунктуацию
+}
+
+// Capabilities возвращает возможности активной модели (nil, если модель не выбрана).
+// Определяются по реестру моделей, поэтому доступны и после выгрузки движка по простою
+func (em *EngineManager) Capabilities() *EngineCapabilities {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	if em.activeModelID == "" {
+		return nil
+	}
+	caps := &EngineCapabilities{
+		ModelID:  em.activeModelID,
+		Language: em.language,
+	}
+
+	modelInfo := models.GetModelByID(em.activeModelID)
+	if modelInfo == nil {
+		return caps
+	}
+	caps.Engine = string(modelInfo.Engine)
+	caps.WordTimestamps = modelInfo.SupportsWordTimestamps
+
+	switch modelInfo.Engine {
+	case models.EngineTypeWhisper, models.EngineTypeFluidASR:
+		caps.PunctuatesNatively = true
+	case models.EngineTypeGigaAM:
+		// Только E2E модели GigaAM обучены с пунктуацией
+		caps.PunctuatesNatively = strings.Contains(strings.ToLower(modelInfo.ID), "e2e")
+	}
+	return caps
+}
+
 // GetEngineInfo возвращает информацию об активном движке
 func (em *EngineManager) GetEngineInfo() map[string]interface{} {
 	em.mu.RLock()
@@ -206,6 +347,8 @@ func (em *EngineManager) GetEngineInfo() map[string]interface{} {
 	info := map[string]interface{}{
 		"activeModelID": em.activeModelID,
 		"hasEngine":     em.activeEngine != nil,
+		"idleUnloaded":  em.activeEngine == nil && em.activeModelID != "",
+		"idleTimeoutMs": em.idleTimeout.Milliseconds(),
 	}
 
 	if em.activeEngine != nil {
@@ -216,13 +359,245 @@ func (em *EngineManager) GetEngineInfo() map[string]interface{} {
 	return info
 }
 
+// SetIdleUnloadTimeout задаёт время простоя, после которого движок выгружается из памяти
+// (0 - не выгружать). Выгруженный движок загружается заново при следующей транскрипции
+func (em *EngineManager) SetIdleUnloadTimeout(timeout time.Duration) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if timeout < 0 {
+		timeout = 0
+	}
+	em.idleTimeout = timeout
+	if em.reaperStop != nil {
+		close(em.reaperStop)
+		em.reaperStop = nil
+	}
+	if timeout == 0 {
+		return
+	}
+
+	interval := timeout / 4
+	if interval < minIdleCheckInterval {
+		interval = minIdleCheckInterval
+	}
+	if interval > maxIdleCheckInterval {
+		interval = maxIdleCheckInterval
+	}
+	em.reaperStop = make(chan struct{})
+	go em.idleReaper(interval, em.reaperStop)
+	log.Printf("EngineManager: idle unload enabled (timeout=%v)", timeout)
+}
+
+// idleReaper периодически выгружает движок, простаивающий дольше idleTimeout
+func (em *EngineManager) idleReaper(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			em.unloadIfIdle(time.Now())
+		}
+	}
+}
+
+// unloadIfIdle выгружает движок, если он не используется и простаивает дольше idleTimeout.
+// Возвращает true, если движок был выгружен
+func (em *EngineManager) unloadIfIdle(now time.Time) bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if em.idleTimeout <= 0 || em.activeEngine == nil || em.inFlight > 0 {
+		return false
+	}
+	idle := now.Sub(em.lastUsed)
+	if idle < em.idleTimeout {
+		return false
+	}
+
+	em.activeEngine.Close()
+	em.activeEngine = nil
+	log.Printf("EngineManager: unloaded model %s after %v idle", em.activeModelID, idle.Round(time.Second))
+	return true
+}
+
+// acquireEngine возвращает активный движок (загружая его после выгрузки по простою)
+// и отмечает начало транскрипции. После завершения нужно вызвать releaseEngine
+func (em *EngineManager) acquireEngine() (TranscriptionEngine, error) {
+	if err := em.lockLoadedEngine(); err != nil {
+		return nil, err
+	}
+	defer em.mu.Unlock()
+
+	em.inFlight++
+	em.lastUsed = time.Now()
+	return em.activeEngine, nil
+}
+
+// releaseEngine отмечает завершение транскрипции
+func (em *EngineManager) releaseEngine() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if em.inFlight > 0 {
+		em.inFlight--
+	}
+	em.lastUsed = time.Now()
+}
+
+// lockLoadedEngine захватывает em.mu, предварительно загрузив выгруженную по простою модель.
+// При успехе возвращается под em.mu с загруженным em.activeEngine
+func (em *EngineManager) lockLoadedEngine() error {
+	for {
+		em.mu.Lock()
+		if em.activeEngine != nil {
+			return nil
+		}
+		em.mu.Unlock()
+
+		if err := em.reload(); err != nil {
+			return err
+		}
+	}
+}
+
+// reload заново загружает выгруженную модель и восстанавливает настройки движка.
+// Загрузка идёт вне em.mu, чтобы остальные обращения к менеджеру её не ждали;
+// одновременные вызовы ждут одну загрузку. Движок устанавливается, только если за время
+// загрузки модель не сменилась и не была загружена другим путём, иначе закрывается
+func (em *EngineManager) reload() error {
+	em.mu.Lock()
+	if em.activeEngine != nil {
+		em.mu.Unlock()
+		return nil
+	}
+	if em.activeModelID == "" {
+		em.mu.Unlock()
+		return errNoActiveEngine
+	}
+	if call := em.reloading; call != nil {
+		em.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &engineReload{done: make(chan struct{})}
+	em.reloading = call
+	modelID := em.activeModelID
+	newEngine := em.newEngine
+	if newEngine == nil {
+		newEngine = em.CreateEngineForModel
+	}
+	em.mu.Unlock()
+
+	start := time.Now()
+	engine, err := newEngine(modelID)
+
+	em.mu.Lock()
+	em.reloading = nil
+	switch {
+	case err != nil:
+		call.err = fmt.Errorf("failed to reload model %s: %w", modelID, err)
+	case em.activeModelID != modelID || em.activeEngine != nil:
+		// Пока модель загружалась, её сменили (SetActiveModel) или закрыли менеджер
+		engine.Close()
+	default:
+		// Настройки берутся на момент установки: SetLanguage во время загрузки не теряется
+		if em.language != "" {
+			engine.SetLanguage(em.language)
+		}
+		if len(em.hotwords) > 0 {
+			engine.SetHotwords(em.hotwords)
+		}
+		if fluidEngine, ok := engine.(*FluidASREngine); ok && em.pauseThreshold > 0 {
+			fluidEngine.SetPauseThreshold(em.pauseThreshold)
+		}
+		em.activeEngine = engine
+		em.lastUsed = time.Now()
+		log.Printf("EngineManager: reloaded model %s after idle unload in %v", modelID, time.Since(start))
+	}
+	em.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// Engine возвращает движок, все вызовы которого идут через менеджер: с учётом выполняющихся
+// транскрипций (выгрузка по простою их не прерывает), загрузкой после простоя и переходом
+// на новую модель после SetActiveModel. Его, а не GetActiveEngine, нужно передавать
+// компонентам, которые хранят движок (Pipeline, HybridTranscriber)
+func (em *EngineManager) Engine() TranscriptionEngine {
+	return &managedEngine{em: em}
+}
+
+// managedEngine TranscriptionEngine поверх EngineManager. Close ничего не делает:
+// движком владеет менеджер
+type managedEngine struct {
+	em *EngineManager
+}
+
+func (m *managedEngine) Transcribe(samples []float32, useContext bool) (string, error) {
+	return m.em.Transcribe(samples, useContext)
+}
+
+func (m *managedEngine) TranscribeWithSegments(samples []float32) ([]TranscriptSegment, error) {
+	return m.em.TranscribeWithSegments(samples)
+}
+
+func (m *managedEngine) TranscribeWithSegmentsProgress(samples []float32, onProgress ProgressFunc) ([]TranscriptSegment, error) {
+	return m.em.TranscribeWithSegmentsProgress(samples, onProgress)
+}
+
+func (m *managedEngine) TranscribeWithPrompt(samples []float32, previousText string, onProgress ProgressFunc) ([]TranscriptSegment, error) {
+	return m.em.TranscribeWithPrompt(samples, previousText, onProgress)
+}
+
+func (m *managedEngine) TranscribeHighQuality(samples []float32) ([]TranscriptSegment, error) {
+	return m.em.TranscribeHighQuality(samples)
+}
+
+func (m *managedEngine) SetLanguage(lang string) { m.em.SetLanguage(lang) }
+
+func (m *managedEngine) SetHotwords(words []string) { m.em.SetHotwords(words) }
+
+// SetModel не поддерживается: модель переключается через EngineManager.SetActiveModel
+func (m *managedEngine) SetModel(path string) error {
+	return fmt.Errorf("managed engine: switch models with EngineManager.SetActiveModel")
+}
+
+func (m *managedEngine) Close() {}
+
+// Name возвращает имя активного движка, а после выгрузки по простою - тип движка модели
+func (m *managedEngine) Name() string {
+	m.em.mu.RLock()
+	defer m.em.mu.RUnlock()
+	if m.em.activeEngine != nil {
+		return m.em.activeEngine.Name()
+	}
+	return string(m.em.unloadedEngineTypeLocked())
+}
+
+// SupportedLanguages возвращает языки активного движка, а после выгрузки - языки модели из реестра
+func (m *managedEngine) SupportedLanguages() []string {
+	m.em.mu.RLock()
+	defer m.em.mu.RUnlock()
+	if m.em.activeEngine != nil {
+		return m.em.activeEngine.SupportedLanguages()
+	}
+	if info := models.GetModelByID(m.em.activeModelID); info != nil {
+		return info.Languages
+	}
+	return nil
+}
+
 // IsGigaAMActive проверяет, активен ли GigaAM движок (CTC или RNNT)
 func (em *EngineManager) IsGigaAMActive() bool {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
 
 	if em.activeEngine == nil {
-		return false
+		return em.unloadedEngineTypeLocked() == models.EngineTypeGigaAM
 	}
 	name := em.activeEngine.Name()
 	return name == "gigaam" || name == "gigaam-rnnt"
@@ -234,11 +609,22 @@ func (em *EngineManager) IsWhisperActive() bool {
 	defer em.mu.RUnlock()
 
 	if em.activeEngine == nil {
-		return false
+		return em.unloadedEngineTypeLocked() == models.EngineTypeWhisper
 	}
 	return em.activeEngine.Name() == "whisper"
 }
 
+// unloadedEngineTypeLocked возвращает тип движка модели, выгруженной по простою
+func (em *EngineManager) unloadedEngineTypeLocked() models.EngineType {
+	if em.activeModelID == "" {
+		return ""
+	}
+	if info := models.GetModelByID(em.activeModelID); info != nil {
+		return info.Engine
+	}
+	return ""
+}
+
 // CreateEngineForModel создаёт движок для указанной модели без установки его как активного
 // Используется для гибридной транскрипции (вторичная модель)
 func (em *EngineManager) CreateEngineForModel(modelID string) (TranscriptionEngine, error) {
@@ -0,0 +1,273 @@
+package ai
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngineManager_UnloadIfIdle(t *testing.T) {
+	em := NewEngineManager(nil)
+	em.activeEngine = &mockTranscriber{name: "mock"}
+	em.activeModelID = "mock-model"
+	em.idleTimeout = time.Minute
+	em.lastUsed = time.Now()
+
+	if em.unloadIfIdle(time.Now().Add(30 * time.Second)) {
+		t.Fatal("engine should not be unloaded before idle timeout")
+	}
+
+	// Движок занят транскрипцией - не выгружаем даже после таймаута
+	engine, err := em.acquireEngine()
+	if err != nil || engine == nil {
+		t.Fatalf("acquireEngine: %v", err)
+	}
+	if em.unloadIfIdle(time.Now().Add(2 * time.Minute)) {
+		t.Fatal("engine in use must not be unloaded")
+	}
+	em.releaseEngine()
+
+	if !em.unloadIfIdle(time.Now().Add(2 * time.Minute)) {
+		t.Fatal("idle engine should be unloaded")
+	}
+	if em.activeEngine != nil {
+		t.Error("activeEngine should be nil after unload")
+	}
+	if em.GetActiveModelID() != "mock-model" {
+		t.Errorf("active model ID should be kept for reload, got %q", em.GetActiveModelID())
+	}
+	if info := em.GetEngineInfo(); info["idleUnloaded"] != true {
+		t.Errorf("idleUnloaded = %v, want true", info["idleUnloaded"])
+	}
+}
+
+func TestEngineManager_IdleUnloadDisabled(t *testing.T) {
+	em := NewEngineManager(nil)
+	em.activeEngine = &mockTranscriber{name: "mock"}
+	em.activeModelID = "mock-model"
+	em.lastUsed = time.Now().Add(-time.Hour)
+
+	if em.unloadIfIdle(time.Now()) {
+		t.Error("engine must not be unloaded when idle timeout is disabled")
+	}
+}
+
+func TestEngineManager_AcquireWithoutModel(t *testing.T) {
+	em := NewEngineManager(nil)
+	if _, err := em.TranscribeWithSegments(nil); err == nil {
+		t.Error("expected error without active engine")
+	}
+}
+
+func TestEngineManager_Capabilities(t *testing.T) {
+	em := NewEngineManager(nil)
+	if em.Capabilities() != nil {
+		t.Error("capabilities should be nil without active model")
+	}
+
+	em.activeModelID = "gigaam-v3-ctc"
+	em.language = "ru"
+	caps := em.Capabilities()
+	if caps == nil || !caps.WordTimestamps || caps.PunctuatesNatively || caps.Language != "ru" {
+		t.Errorf("gigaam-v3-ctc capabilities = %+v", caps)
+	}
+
+	em.activeModelID = "gigaam-v3-e2e-ctc"
+	if caps := em.Capabilities(); !caps.PunctuatesNatively {
+		t.Errorf("gigaam-v3-e2e-ctc should punctuate natively: %+v", caps)
+	}
+}
+
+// closableTranscriber движок, который после Close отвечает ошибкой, как выгруженный нативный
+type closableTranscriber struct {
+	mockTranscriber
+	mu      sync.Mutex
+	closed  bool
+	started chan struct{} // Сигнал о начале распознавания (nil - не сигналить)
+	release chan struct{} // Распознавание ждёт закрытия (nil - не ждать)
+}
+
+func (c *closableTranscriber) TranscribeWithSegments(samples []float32) ([]TranscriptSegment, error) {
+	if c.started != nil {
+		c.started <- struct{}{}
+	}
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errors.New("engine is closed")
+	}
+	return c.segments, nil
+}
+
+func (c *closableTranscriber) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+}
+
+func TestEngineManager_PipelineAfterIdleUnload(t *testing.T) {
+	var loads []*closableTranscriber
+	em := NewEngineManager(nil)
+	em.newEngine = func(modelID string) (TranscriptionEngine, error) {
+		engine := &closableTranscriber{mockTranscriber: mockTranscriber{name: "mock", segments: []TranscriptSegment{{Text: "привет"}}}}
+		loads = append(loads, engine)
+		return engine, nil
+	}
+	em.activeModelID = "mock-model"
+	em.idleTimeout = time.Minute
+	em.SetLanguage("ru")
+	em.SetHotwords([]string{"AIWisper"})
+
+	pipeline, err := NewAudioPipeline(em.Engine(), PipelineConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Движок выгружен по простою до первого вызова - Pipeline загружает его заново
+	result, err := pipeline.Process(make([]float32, 16000))
+	if err != nil || result.FullText != "привет" {
+		t.Fatalf("Process after unload: result = %+v, err = %v", result, err)
+	}
+	if len(loads) != 1 || loads[0].lang != "ru" {
+		t.Fatalf("loads = %d, language not restored", len(loads))
+	}
+
+	// Срабатывает таймер простоя: закрытый движок Pipeline больше не использует
+	if !em.unloadIfIdle(time.Now().Add(2 * time.Minute)) {
+		t.Fatal("idle engine should be unloaded")
+	}
+	result, err = pipeline.Process(make([]float32, 16000))
+	if err != nil || result.FullText != "привет" {
+		t.Fatalf("Process after reaper: result = %+v, err = %v", result, err)
+	}
+	if len(loads) != 2 {
+		t.Errorf("loads = %d, want reload after unload", len(loads))
+	}
+
+	// Пока Pipeline распознаёт, таймер простоя движок не выгружает
+	busy := loads[1]
+	busy.started = make(chan struct{})
+	busy.release = make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := pipeline.Process(make([]float32, 16000))
+		done <- err
+	}()
+	<-busy.started
+	if em.unloadIfIdle(time.Now().Add(2 * time.Minute)) {
+		t.Error("engine unloaded while pipeline transcription is running")
+	}
+	close(busy.release)
+	if err := <-done; err != nil {
+		t.Errorf("in-flight Process: %v", err)
+	}
+}
+
+func TestEngineManager_ReloadOutsideLock(t *testing.T) {
+	var mu sync.Mutex
+	var loads []*closableTranscriber
+	loading := make(chan struct{})
+	proceed := make(chan struct{})
+	em := NewEngineManager(nil)
+	em.newEngine = func(modelID string) (TranscriptionEngine, error) {
+		engine := &closableTranscriber{mockTranscriber: mockTranscriber{name: modelID}}
+		mu.Lock()
+		loads = append(loads, engine)
+		first := len(loads) == 1
+		mu.Unlock()
+		if first {
+			close(loading)
+			<-proceed
+		}
+		return engine, nil
+	}
+	em.activeModelID = "mock-model"
+
+	// Одновременные обращения ждут одну загрузку
+	var wg sync.WaitGroup
+	engines := make([]TranscriptionEngine, 3)
+	for i := range engines {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			engines[i] = em.GetActiveEngine()
+		}(i)
+	}
+	<-loading
+
+	// Пока модель загружается, менеджер не заблокирован
+	if !em.HasActiveEngine() || em.GetActiveModelID() != "mock-model" {
+		t.Error("manager state unavailable during reload")
+	}
+	em.SetLanguage("ru")
+	close(proceed)
+	wg.Wait()
+
+	if len(loads) != 1 {
+		t.Fatalf("loads = %d, want a single reload", len(loads))
+	}
+	for i, engine := range engines {
+		if engine != loads[0] {
+			t.Errorf("caller %d got %v, want the reloaded engine", i, engine)
+		}
+	}
+	if loads[0].lang != "ru" {
+		t.Errorf("language set during reload was lost: %q", loads[0].lang)
+	}
+}
+
+func TestEngineManager_ReloadDiscardedAfterModelSwitch(t *testing.T) {
+	loading := make(chan struct{})
+	proceed := make(chan struct{})
+	var stale *closableTranscriber
+	em := NewEngineManager(nil)
+	em.newEngine = func(modelID string) (TranscriptionEngine, error) {
+		stale = &closableTranscriber{mockTranscriber: mockTranscriber{name: modelID}}
+		close(loading)
+		<-proceed
+		return stale, nil
+	}
+	em.activeModelID = "old-model"
+
+	done := make(chan TranscriptionEngine)
+	go func() { done <- em.GetActiveEngine() }()
+	<-loading
+
+	// Пока старая модель загружалась, выбрали другую
+	switched := &mockTranscriber{name: "new-model"}
+	em.mu.Lock()
+	em.activeModelID = "new-model"
+	em.activeEngine = switched
+	em.mu.Unlock()
+	close(proceed)
+
+	if got := <-done; got != switched {
+		t.Errorf("GetActiveEngine = %v, want the switched engine", got)
+	}
+	if !stale.closed {
+		t.Error("engine loaded for the previous model must be closed")
+	}
+	if em.activeEngine != switched {
+		t.Error("stale reload replaced the active engine")
+	}
+}
+
+func TestEngineManager_HasActiveEngine(t *testing.T) {
+	em := NewEngineManager(nil)
+	em.newEngine = func(modelID string) (TranscriptionEngine, error) {
+		t.Errorf("HasActiveEngine must not load model %s", modelID)
+		return nil, errors.New("unexpected load")
+	}
+	if em.HasActiveEngine() {
+		t.Error("no model selected")
+	}
+	// Выгруженная по простою модель считается выбранной и не загружается проверкой
+	em.activeModelID = "mock-model"
+	if !em.HasActiveEngine() {
+		t.Error("idle-unloaded model should count as active")
+	}
+}
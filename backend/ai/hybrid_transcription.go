@@ -39,6 +39,43 @@ type HybridTranscriptionConfig struct {
 	OllamaURL           string       // URL Ollama API
 	Hotwords            []string     // Словарь подсказок для моделей (термины, имена)
 	Voting              VotingConfig // Конфигурация voting-системы
+
+	// Группировка проблемных участков (режим confidence)
+	RegionMergeGapMs int64 // Макс. пауза между участками для объединения (мс, 0 - DefaultRegionMergeGapMs)
+	RegionPaddingMs  int64 // Запас аудио вокруг участка для вторичной модели (мс, 0 - DefaultRegionPaddingMs)
+}
+
+// DefaultRegionMergeGapMs пауза, при которой соседние участки с низкой уверенностью объединяются
+const DefaultRegionMergeGapMs int64 = 500
+
+// DefaultRegionPaddingMs запас аудио вокруг участка при повторном распознавании
+const DefaultRegionPaddingMs int64 = 100
+
+// Validate проверяет параметры группировки участков
+func (c HybridTranscriptionConfig) Validate() error {
+	if c.RegionMergeGapMs < 0 {
+		return fmt.Errorf("region merge gap must be non-negative, got %d ms", c.RegionMergeGapMs)
+	}
+	if c.RegionPaddingMs < 0 {
+		return fmt.Errorf("region padding must be non-negative, got %d ms", c.RegionPaddingMs)
+	}
+	return nil
+}
+
+// regionMergeGapMs возвращает эффективную паузу объединения участков
+func (c HybridTranscriptionConfig) regionMergeGapMs() int64 {
+	if c.RegionMergeGapMs > 0 {
+		return c.RegionMergeGapMs
+	}
+	return DefaultRegionMergeGapMs
+}
+
+// regionPaddingMs возвращает эффективный запас аудио вокруг участка
+func (c HybridTranscriptionConfig) regionPaddingMs() int64 {
+	if c.RegionPaddingMs > 0 {
+		return c.RegionPaddingMs
+	}
+	return DefaultRegionPaddingMs
 }
 
 // VotingConfig конфигурация системы голосования для выбора лучшего слова
@@ -156,6 +193,11 @@ func NewHybridTranscriber(
 	config HybridTranscriptionConfig,
 	llmSelector LLMTranscriptionSelector,
 ) *HybridTranscriber {
+	if err := config.Validate(); err != nil {
+		log.Printf("[HybridTranscriber] Invalid config (%v), using default region merge gap and padding", err)
+		config.RegionMergeGapMs = DefaultRegionMergeGapMs
+		config.RegionPaddingMs = DefaultRegionPaddingMs
+	}
 	return &HybridTranscriber{
 		primaryEngine:   primary,
 		secondaryEngine: secondary,
@@ -1308,8 +1350,8 @@ func (h *HybridTranscriber) findLowConfidenceRegions(segments []TranscriptSegmen
 		}
 	}
 
-	// Объединяем близкие регионы (менее 500мс между ними)
-	regions = h.mergeCloseRegions(regions, 500)
+	// Объединяем близкие регионы (по умолчанию менее 500мс между ними)
+	regions = h.mergeCloseRegions(regions, h.config.regionMergeGapMs())
 
 	// Логируем итоговую статистику
 	avgConf := float32(0)
@@ -1381,11 +1423,12 @@ func (h *HybridTranscriber) retranscribeRegions(
 ) []TranscriptionImprovement {
 	var improvements []TranscriptionImprovement
 	sampleRate := 16000 // 16kHz
+	paddingMs := h.config.regionPaddingMs()
 
 	for _, region := range regions {
-		// Извлекаем аудио для региона с небольшим запасом (100мс)
-		startSample := int((region.StartMs - 100) * int64(sampleRate) / 1000)
-		endSample := int((region.EndMs + 100) * int64(sampleRate) / 1000)
+		// Извлекаем аудио для региона с небольшим запасом (по умолчанию 100мс)
+		startSample := int((region.StartMs - paddingMs) * int64(sampleRate) / 1000)
+		endSample := int((region.EndMs + paddingMs) * int64(sampleRate) / 1000)
 
 		if startSample < 0 {
 			startSample = 0
@@ -1546,6 +1589,7 @@ func (h *HybridTranscriber) mergeResults(
 				// для точного позиционирования
 				newText := replaceTextInSegment(seg.Text, imp.OriginalText, imp.ImprovedText)
 				result[i].Text = newText
+				result[i].Words = applyImprovedConfidence(result[i].Words, imp)
 
 				log.Printf("[HybridTranscriber] Applied improvement: '%s' -> '%s' (source: %s)",
 					imp.OriginalText, imp.ImprovedText, imp.Source)
@@ -1556,6 +1600,22 @@ func (h *HybridTranscriber) mergeResults(
 	return result
 }
 
+// applyImprovedConfidence переносит уверенность выбранного варианта на слова участка улучшения,
+// чтобы уверенность сегмента отражала итоговый текст, а не заменённые слова
+func applyImprovedConfidence(words []TranscriptWord, imp TranscriptionImprovement) []TranscriptWord {
+	if imp.ImprovedConf <= 0 {
+		return words
+	}
+	result := make([]TranscriptWord, len(words))
+	copy(result, words)
+	for i, w := range result {
+		if w.Start < imp.EndMs && w.End > imp.StartMs {
+			result[i].P = imp.ImprovedConf
+		}
+	}
+	return result
+}
+
 // replaceTextInSegment заменяет текст в сегменте
 func replaceTextInSegment(segmentText, original, replacement string) string {
 	// Простая замена подстроки
@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"testing"
+)
+
+// TestHybridRegionGroupingConfig проверяет настраиваемую паузу объединения участков
+func TestHybridRegionGroupingConfig(t *testing.T) {
+	if err := (HybridTranscriptionConfig{RegionMergeGapMs: -1}).Validate(); err == nil {
+		t.Error("expected error for negative merge gap")
+	}
+	if err := (HybridTranscriptionConfig{RegionPaddingMs: -1}).Validate(); err == nil {
+		t.Error("expected error for negative padding")
+	}
+
+	// Невалидная конфигурация заменяется дефолтами
+	h := NewHybridTranscriber(nil, nil, HybridTranscriptionConfig{RegionMergeGapMs: -5, RegionPaddingMs: 50}, nil)
+	if got := h.config.regionMergeGapMs(); got != DefaultRegionMergeGapMs {
+		t.Errorf("merge gap = %d, want default %d", got, DefaultRegionMergeGapMs)
+	}
+	if got := h.config.regionPaddingMs(); got != DefaultRegionPaddingMs {
+		t.Errorf("padding = %d, want default %d", got, DefaultRegionPaddingMs)
+	}
+
+	regions := func() []LowConfidenceRegion {
+		return []LowConfidenceRegion{
+			{StartMs: 0, EndMs: 1000},
+			{StartMs: 1800, EndMs: 2500},
+		}
+	}
+
+	// Пауза 800мс: по умолчанию (500мс) участки раздельные
+	h = NewHybridTranscriber(nil, nil, HybridTranscriptionConfig{}, nil)
+	if got := h.mergeCloseRegions(regions(), h.config.regionMergeGapMs()); len(got) != 2 {
+		t.Errorf("default gap: got %d regions, want 2", len(got))
+	}
+
+	h = NewHybridTranscriber(nil, nil, HybridTranscriptionConfig{RegionMergeGapMs: 1000}, nil)
+	got := h.mergeCloseRegions(regions(), h.config.regionMergeGapMs())
+	if len(got) != 1 || got[0].EndMs != 2500 {
+		t.Errorf("gap 1000ms: got %+v, want single region 0-2500", got)
+	}
+}
+
+// TestMergeResultsCarriesImprovedConfidence проверяет, что слова заменённого участка получают уверенность
+// выбранного варианта, а остальные слова сегмента - нет
+func TestMergeResultsCarriesImprovedConfidence(t *testing.T) {
+	h := NewHybridTranscriber(nil, nil, HybridTranscriptionConfig{}, nil)
+	segments := []TranscriptSegment{{
+		Start: 0, End: 3000, Text: "привет мир тест",
+		Words: []TranscriptWord{
+			{Start: 0, End: 900, Text: "привет", P: 0.9},
+			{Start: 1000, End: 1900, Text: "мир", P: 0.3},
+			{Start: 2000, End: 3000, Text: "тест", P: 0.8},
+		},
+	}}
+	improvements := []TranscriptionImprovement{{StartMs: 1000, EndMs: 1900, OriginalText: "мир", ImprovedText: "мира", ImprovedConf: 0.85}}
+
+	merged := h.mergeResults(segments, improvements)
+	if merged[0].Text != "привет мира тест" {
+		t.Errorf("text = %q", merged[0].Text)
+	}
+	if got := []float32{merged[0].Words[0].P, merged[0].Words[1].P, merged[0].Words[2].P}; got[0] != 0.9 || got[1] != 0.85 || got[2] != 0.8 {
+		t.Errorf("word probabilities = %v, want [0.9 0.85 0.8]", got)
+	}
+	if segments[0].Words[1].P != 0.3 {
+		t.Error("original segment words are modified")
+	}
+}
@@ -21,6 +21,11 @@ type PipelineConfig struct {
 	MinDurationOn       float32 // Мин. длительность речи (сек)
 	MinDurationOff      float32 // Мин. длительность паузы (сек)
 
+	// Консолидация спикеров при разбиении сегментов по словам (0 - по умолчанию)
+	MinSpeakerRatio      float32 // Мин. доля речи спикера в чанке, меньше - спикер поглощается соседним (0-0.5)
+	MinSpeakerSegmentSec float32 // Мин. длительность сегмента диаризации, короче - объединяется с соседом (сек, 0-5)
+	MinOverlapRatio      float32 // Мин. доля слова, которую покрывает каждый из двух спикеров, чтобы отметить наложение речи (0-1)
+
 	// ONNX
 	NumThreads int    // Количество потоков
 	Provider   string // ONNX provider: cpu, cuda, coreml
@@ -33,6 +38,26 @@ type SpeakerProfile struct {
 	Count     int       // Количество встреченных сегментов
 }
 
+// Значения консолидации спикеров по умолчанию
+const (
+	DefaultMinSpeakerRatio      float32 = 0.10
+	DefaultMinSpeakerSegmentSec float32 = 1.0
+	DefaultMinOverlapRatio      float32 = 0.5
+)
+
+// Допустимые пределы консолидации спикеров
+const (
+	MaxMinSpeakerRatio      float32 = 0.5
+	MaxMinSpeakerSegmentSec float32 = 5.0
+)
+
+// Допустимые пределы параметров кластеризации диаризации (используются бэкендом Sherpa)
+const (
+	MinClusteringThreshold float32 = 0.1
+	MaxClusteringThreshold float32 = 0.95
+	MaxMinDurationSec      float32 = 5.0
+)
+
 // DefaultPipelineConfig возвращает конфигурацию по умолчанию
 // Provider "auto" означает автоматическое определение лучшего устройства
 func DefaultPipelineConfig() PipelineConfig {
@@ -46,6 +71,58 @@ func DefaultPipelineConfig() PipelineConfig {
 	}
 }
 
+// ValidateSpeakerConsolidation проверяет параметры консолидации спикеров
+func (c PipelineConfig) ValidateSpeakerConsolidation() error {
+	if c.MinSpeakerRatio < 0 || c.MinSpeakerRatio > MaxMinSpeakerRatio {
+		return fmt.Errorf("min speaker ratio must be in [0, %.2f], got %.3f", MaxMinSpeakerRatio, c.MinSpeakerRatio)
+	}
+	if c.MinSpeakerSegmentSec < 0 || c.MinSpeakerSegmentSec > MaxMinSpeakerSegmentSec {
+		return fmt.Errorf("min speaker segment must be in [0, %.1f] sec, got %.2f", MaxMinSpeakerSegmentSec, c.MinSpeakerSegmentSec)
+	}
+	if c.MinOverlapRatio < 0 || c.MinOverlapRatio > 1 {
+		return fmt.Errorf("min overlap ratio must be in [0, 1], got %.3f", c.MinOverlapRatio)
+	}
+	return nil
+}
+
+// ValidateClustering проверяет порог кластеризации и мин. длительности речи и паузы
+func (c PipelineConfig) ValidateClustering() error {
+	if c.ClusteringThreshold < MinClusteringThreshold || c.ClusteringThreshold > MaxClusteringThreshold {
+		return fmt.Errorf("clustering threshold must be in [%.2f, %.2f], got %.3f", MinClusteringThreshold, MaxClusteringThreshold, c.ClusteringThreshold)
+	}
+	if c.MinDurationOn < 0 || c.MinDurationOn > MaxMinDurationSec {
+		return fmt.Errorf("min duration on must be in [0, %.1f] sec, got %.2f", MaxMinDurationSec, c.MinDurationOn)
+	}
+	if c.MinDurationOff < 0 || c.MinDurationOff > MaxMinDurationSec {
+		return fmt.Errorf("min duration off must be in [0, %.1f] sec, got %.2f", MaxMinDurationSec, c.MinDurationOff)
+	}
+	return nil
+}
+
+// EffectiveMinSpeakerRatio возвращает мин. долю речи спикера с учётом значения по умолчанию
+func (c PipelineConfig) EffectiveMinSpeakerRatio() float32 {
+	if c.MinSpeakerRatio > 0 {
+		return c.MinSpeakerRatio
+	}
+	return DefaultMinSpeakerRatio
+}
+
+// EffectiveMinSpeakerSegmentSec возвращает мин. длительность сегмента диаризации с учётом значения по умолчанию
+func (c PipelineConfig) EffectiveMinSpeakerSegmentSec() float32 {
+	if c.MinSpeakerSegmentSec > 0 {
+		return c.MinSpeakerSegmentSec
+	}
+	return DefaultMinSpeakerSegmentSec
+}
+
+// EffectiveMinOverlapRatio возвращает мин. долю наложения речи с учётом значения по умолчанию
+func (c PipelineConfig) EffectiveMinOverlapRatio() float32 {
+	if c.MinOverlapRatio > 0 {
+		return c.MinOverlapRatio
+	}
+	return DefaultMinOverlapRatio
+}
+
 // PipelineResult результат обработки аудио пайплайном
 type PipelineResult struct {
 	Segments          []TranscriptSegment // Сегменты с текстом и таймстемпами
@@ -68,6 +145,10 @@ type AudioPipeline struct {
 	// Глобальное состояние спикеров
 	speakerProfiles map[int]*SpeakerProfile // ID -> Profile
 	nextSpeakerID   int                     // Следующий свободный ID (начинаем с 1)
+
+	// Отдельный реестр спикеров MIC канала (DiarizeMic): люди у микрофона не сопоставляются с собеседниками SYS
+	micSpeakerProfiles map[int]*SpeakerProfile
+	micNextSpeakerID   int
 }
 
 // NewAudioPipeline создаёт новый пайплайн обработки аудио
@@ -77,10 +158,12 @@ func NewAudioPipeline(transcriber TranscriptionEngine, config PipelineConfig) (*
 	}
 
 	pipeline := &AudioPipeline{
-		transcriber:     transcriber,
-		config:          config,
-		speakerProfiles: make(map[int]*SpeakerProfile),
-		nextSpeakerID:   1, // Спикеры начинаются с 1 (Собеседник 1)
+		transcriber:        transcriber,
+		config:             config,
+		speakerProfiles:    make(map[int]*SpeakerProfile),
+		nextSpeakerID:      1, // Спикеры начинаются с 1 (Собеседник 1)
+		micSpeakerProfiles: make(map[int]*SpeakerProfile),
+		micNextSpeakerID:   1,
 	}
 
 	// Инициализируем диаризатор если включен
@@ -162,6 +245,8 @@ func (p *AudioPipeline) ResetSpeakers() {
 	defer p.mu.Unlock()
 	p.speakerProfiles = make(map[int]*SpeakerProfile)
 	p.nextSpeakerID = 1
+	p.micSpeakerProfiles = make(map[int]*SpeakerProfile)
+	p.micNextSpeakerID = 1
 	log.Printf("AudioPipeline: speaker registry reset")
 }
 
@@ -202,7 +287,7 @@ func (p *AudioPipeline) Process(samples []float32) (*PipelineResult, error) {
 		} else {
 			// b. Глобальное сопоставление спикеров
 			// Преобразуем локальные ID (0, 1...) в глобальные (1, 2...)
-			globalSegments := p.mapToGlobalSpeakers(samples, localSegments)
+			globalSegments := p.mapToGlobalSpeakers(samples, localSegments, p.speakerProfiles, &p.nextSpeakerID)
 
 			result.SpeakerSegments = globalSegments
 			result.NumSpeakers = p.countUniqueSpeakers(globalSegments)
@@ -243,18 +328,37 @@ func (p *AudioPipeline) diarizeWithTimeout(samples []float32, timeout time.Durat
 	}
 }
 
-// mapToGlobalSpeakers сопоставляет локальных спикеров с глобальным реестром
-func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []SpeakerSegment) []SpeakerSegment {
-	if len(localSegments) == 0 {
+// mapToGlobalSpeakers сопоставляет локальных спикеров с глобальным реестром profiles
+// (nextID - следующий свободный ID этого реестра)
+func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []SpeakerSegment, profiles map[int]*SpeakerProfile, nextID *int) []SpeakerSegment {
+	// Если encoder не инициализирован (FluidAudio backend), используем локальные ID
+	// FluidAudio делает собственную кластеризацию, поэтому просто преобразуем формат
+	if len(localSegments) == 0 || p.encoder == nil {
 		return localSegments
 	}
+	return assignGlobalSpeakers(localSegments, p.localSpeakerEmbeddings(samples, localSegments), profiles, nextID)
+}
 
-	// Если encoder не инициализирован (FluidAudio backend), используем локальные ID
-	// FluidAudio делает собственную кластеризацию, поэтому просто преобразуем формат
-	if p.encoder == nil {
-		// Преобразуем к формату "Собеседник N" (speaker IDs уже корректные от FluidAudio)
+// registerGlobalSpeakers сопоставляет локальных спикеров с реестром SYS или MIC канала (mic)
+// под блокировкой на запись. Вызывать без удержания p.mu
+func (p *AudioPipeline) registerGlobalSpeakers(localSegments []SpeakerSegment, embeddings map[int][]float32, mic bool) []SpeakerSegment {
+	if len(localSegments) == 0 || embeddings == nil {
 		return localSegments
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if mic {
+		return assignGlobalSpeakers(localSegments, embeddings, p.micSpeakerProfiles, &p.micNextSpeakerID)
+	}
+	return assignGlobalSpeakers(localSegments, embeddings, p.speakerProfiles, &p.nextSpeakerID)
+}
+
+// localSpeakerEmbeddings вычисляет вектор каждого локального спикера по его самому длинному сегменту.
+// nil - encoder не инициализирован (локальные ID не сопоставляются), nil вектор - спикер не распознан
+func (p *AudioPipeline) localSpeakerEmbeddings(samples []float32, localSegments []SpeakerSegment) map[int][]float32 {
+	if p.encoder == nil {
+		return nil
+	}
 
 	// 1. Группируем сегменты по локальным спикерам
 	localSpeakers := make(map[int][]SpeakerSegment)
@@ -262,11 +366,10 @@ func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []S
 		localSpeakers[seg.Speaker] = append(localSpeakers[seg.Speaker], seg)
 	}
 
-	// 2. Карта соответствия: Local ID -> Global ID
-	mapping := make(map[int]int)
-
-	// Для каждого локального спикера вычисляем эмбеддинг и ищем совпадение
+	embeddings := make(map[int][]float32, len(localSpeakers))
 	for localID, segs := range localSpeakers {
+		embeddings[localID] = nil
+
 		// Находим самый длинный сегмент для лучшего качества эмбеддинга
 		var bestSeg SpeakerSegment
 		maxLen := float32(0)
@@ -280,7 +383,6 @@ func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []S
 		}
 
 		if maxLen < 0.1 { // Слишком короткий сегмент, пропускаем
-			mapping[localID] = -1 // Unknown
 			continue
 		}
 
@@ -295,29 +397,42 @@ func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []S
 		}
 
 		if startIdx >= endIdx {
-			mapping[localID] = -1
 			continue
 		}
 
-		audioSeg := samples[startIdx:endIdx]
-
 		// Получаем вектор
-		embedding, err := p.encoder.Encode(audioSeg)
+		embedding, err := p.encoder.Encode(samples[startIdx:endIdx])
 		if err != nil {
 			log.Printf("Failed to encode speaker %d segment: %v", localID, err)
-			mapping[localID] = -1
+			continue
+		}
+		embeddings[localID] = embedding
+	}
+
+	return embeddings
+}
+
+// assignGlobalSpeakers переводит локальные ID сегментов в ID реестра profiles, регистрируя новых спикеров.
+// Меняет реестр: вызывающий должен владеть им монопольно
+func assignGlobalSpeakers(localSegments []SpeakerSegment, embeddings map[int][]float32, profiles map[int]*SpeakerProfile, nextID *int) []SpeakerSegment {
+	// 2. Карта соответствия: Local ID -> Global ID
+	mapping := make(map[int]int)
+
+	for localID, embedding := range embeddings {
+		if embedding == nil {
+			mapping[localID] = -1 // Unknown
 			continue
 		}
 
 		// Ищем совпадение в реестре
-		globalID, found := p.findMatchingGlobalSpeaker(embedding)
+		globalID, found := findMatchingGlobalSpeaker(profiles, embedding)
 
 		if found {
 			mapping[localID] = globalID
 			// Можно обновить профиль (усреднить вектор), но пока просто используем существующий
 		} else {
 			// Создаём нового спикера
-			newID := p.registerNewSpeaker(embedding)
+			newID := registerNewSpeaker(profiles, nextID, embedding)
 			mapping[localID] = newID
 			log.Printf("New speaker detected: Global ID %d (was Local %d)", newID, localID)
 		}
@@ -332,7 +447,6 @@ func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []S
 			// Или можно оставить как есть, но тогда будет конфликт
 			// Лучше назначить новый ID? Нет, лучше -1 и потом обработать как "Unknown"
 			// Но для простоты пока используем nextID (временный)
-			globalID = p.nextSpeakerID // Просто резервируем, но не сохраняем в реестр?
 			// Нет, давайте просто оставим локальный + offset, если совсем плохо
 			// Но лучше всего - просто пропустить или присвоить "Speaker ?"
 			// В текущей реализации UI ожидает "Speaker N".
@@ -356,12 +470,12 @@ func (p *AudioPipeline) mapToGlobalSpeakers(samples []float32, localSegments []S
 }
 
 // findMatchingGlobalSpeaker ищет похожего спикера в реестре
-func (p *AudioPipeline) findMatchingGlobalSpeaker(embedding []float32) (int, bool) {
+func findMatchingGlobalSpeaker(profiles map[int]*SpeakerProfile, embedding []float32) (int, bool) {
 	bestDist := 2.0 // Максимальное косинусное расстояние
 	bestID := -1
 	threshold := 0.5 // Порог похожести (чем меньше, тем строже)
 
-	for id, profile := range p.speakerProfiles {
+	for id, profile := range profiles {
 		dist := cosineDistance(embedding, profile.Embedding)
 		if dist < bestDist {
 			bestDist = dist
@@ -377,15 +491,15 @@ func (p *AudioPipeline) findMatchingGlobalSpeaker(embedding []float32) (int, boo
 }
 
 // registerNewSpeaker добавляет нового спикера в реестр
-func (p *AudioPipeline) registerNewSpeaker(embedding []float32) int {
-	id := p.nextSpeakerID
-	p.nextSpeakerID++
+func registerNewSpeaker(profiles map[int]*SpeakerProfile, nextID *int, embedding []float32) int {
+	id := *nextID
+	*nextID++
 
 	// Копируем вектор
 	embCopy := make([]float32, len(embedding))
 	copy(embCopy, embedding)
 
-	p.speakerProfiles[id] = &SpeakerProfile{
+	profiles[id] = &SpeakerProfile{
 		ID:        id,
 		Embedding: embCopy,
 		Count:     1,
@@ -444,56 +558,67 @@ func assignSpeakersToSegments(segments []TranscriptSegment, speakerSegments []Sp
 // DiarizeOnly выполняет только диаризацию без транскрипции
 // Используется для per-region режима, где транскрипция уже выполнена
 func (p *AudioPipeline) DiarizeOnly(samples []float32) (*PipelineResult, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	return p.diarizeOnly(samples, false)
+}
 
+// DiarizeMicOnly выполняет диаризацию MIC канала (несколько человек у одного микрофона).
+// Спикеры сопоставляются по отдельному реестру и не смешиваются с собеседниками SYS канала
+func (p *AudioPipeline) DiarizeMicOnly(samples []float32) (*PipelineResult, error) {
+	return p.diarizeOnly(samples, true)
+}
+
+// diarizeOnly диаризация с сопоставлением спикеров по реестру SYS или MIC канала (mic).
+// Диаризация идёт под блокировкой на чтение, реестр обновляется под блокировкой на запись
+func (p *AudioPipeline) diarizeOnly(samples []float32, mic bool) (*PipelineResult, error) {
 	if len(samples) == 0 {
 		return &PipelineResult{}, nil
 	}
 
 	result := &PipelineResult{}
 
+	p.mu.RLock()
+	localSegments, embeddings, err := p.diarizeLocal(samples, result)
+	p.mu.RUnlock()
+	if err != nil {
+		return result, err
+	}
+
+	// Глобальное сопоставление спикеров
+	globalSegments := p.registerGlobalSpeakers(localSegments, embeddings, mic)
+
+	result.SpeakerSegments = globalSegments
+	result.NumSpeakers = p.countUniqueSpeakers(globalSegments)
+
+	log.Printf("DiarizeOnly: found %d speaker segments, %d unique speakers, %d embeddings",
+		len(globalSegments), result.NumSpeakers, len(result.SpeakerEmbeddings))
+
+	return result, nil
+}
+
+// diarizeLocal локальная диаризация и векторы локальных спикеров для сопоставления с реестром.
+// Embeddings FluidDiarizer записываются в result. Вызывается под p.mu.RLock
+func (p *AudioPipeline) diarizeLocal(samples []float32, result *PipelineResult) ([]SpeakerSegment, map[int][]float32, error) {
 	// Проверяем что диаризация включена
 	if p.diarizer == nil || !p.diarizer.IsInitialized() {
-		return result, fmt.Errorf("diarization not enabled")
+		return nil, nil, fmt.Errorf("diarization not enabled")
 	}
 
 	// Пробуем использовать FluidDiarizer с embeddings
 	if fluidDiarizer, ok := p.diarizer.(*FluidDiarizer); ok {
 		diarResult, err := p.diarizeWithEmbeddingsTimeout(fluidDiarizer, samples, 20*time.Second)
 		if err != nil {
-			return result, fmt.Errorf("diarization failed: %w", err)
+			return nil, nil, fmt.Errorf("diarization failed: %w", err)
 		}
-
-		// Глобальное сопоставление спикеров
-		globalSegments := p.mapToGlobalSpeakers(samples, diarResult.Segments)
-
-		result.SpeakerSegments = globalSegments
 		result.SpeakerEmbeddings = diarResult.SpeakerEmbeddings
-		result.NumSpeakers = p.countUniqueSpeakers(globalSegments)
-
-		log.Printf("DiarizeOnly (FluidAudio): found %d speaker segments, %d unique speakers, %d embeddings",
-			len(globalSegments), result.NumSpeakers, len(result.SpeakerEmbeddings))
-
-		return result, nil
+		return diarResult.Segments, p.localSpeakerEmbeddings(samples, diarResult.Segments), nil
 	}
 
 	// Fallback для других диаризаторов (без embeddings)
 	localSegments, err := p.diarizeWithTimeout(samples, 20*time.Second)
 	if err != nil {
-		return result, fmt.Errorf("diarization failed: %w", err)
+		return nil, nil, fmt.Errorf("diarization failed: %w", err)
 	}
-
-	// Глобальное сопоставление спикеров
-	globalSegments := p.mapToGlobalSpeakers(samples, localSegments)
-
-	result.SpeakerSegments = globalSegments
-	result.NumSpeakers = p.countUniqueSpeakers(globalSegments)
-
-	log.Printf("DiarizeOnly: found %d speaker segments, %d unique speakers",
-		len(globalSegments), result.NumSpeakers)
-
-	return result, nil
+	return localSegments, p.localSpeakerEmbeddings(samples, localSegments), nil
 }
 
 // diarizeWithEmbeddingsTimeout выполняет диаризацию с embeddings и таймаутом
@@ -552,7 +677,7 @@ func (p *AudioPipeline) ProcessHighQuality(samples []float32) (*PipelineResult,
 			log.Printf("Warning: diarization failed: %v", err)
 		} else {
 			// b. Глобальное сопоставление
-			globalSegments := p.mapToGlobalSpeakers(samples, localSegments)
+			globalSegments := p.mapToGlobalSpeakers(samples, localSegments, p.speakerProfiles, &p.nextSpeakerID)
 
 			result.SpeakerSegments = globalSegments
 			result.NumSpeakers = p.countUniqueSpeakers(globalSegments)
@@ -604,6 +729,13 @@ func (p *AudioPipeline) IsDiarizationEnabled() bool {
 	return p.diarizer != nil && p.diarizer.IsInitialized()
 }
 
+// GetConfig возвращает конфигурацию пайплайна
+func (p *AudioPipeline) GetConfig() PipelineConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
 // GetDiarizationProvider возвращает текущий provider для диаризации (cpu, coreml, cuda, fluid)
 // Возвращает пустую строку если диаризация не включена
 func (p *AudioPipeline) GetDiarizationProvider() string {
@@ -760,5 +892,7 @@ func (p *AudioPipeline) ResetSpeakerProfiles() {
 	defer p.mu.Unlock()
 	p.speakerProfiles = make(map[int]*SpeakerProfile)
 	p.nextSpeakerID = 1
+	p.micSpeakerProfiles = make(map[int]*SpeakerProfile)
+	p.micNextSpeakerID = 1
 	log.Printf("Speaker profiles reset")
 }
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"sync"
 	"testing"
 )
 
@@ -286,3 +287,69 @@ func TestPipelineResult(t *testing.T) {
 		t.Errorf("Expected 'Тест', got %q", result.FullText)
 	}
 }
+
+func TestAudioPipeline_MicSpeakerRegistry(t *testing.T) {
+	p, err := NewAudioPipeline(&mockTranscriber{name: "mock"}, DefaultPipelineConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	voice := []float32{0.2, 0.9, 0.1}
+
+	// Голос у микрофона регистрируется только в реестре MIC канала
+	if id := registerNewSpeaker(p.micSpeakerProfiles, &p.micNextSpeakerID, voice); id != 1 {
+		t.Errorf("first mic speaker ID = %d, want 1", id)
+	}
+	if _, found := findMatchingGlobalSpeaker(p.speakerProfiles, voice); found {
+		t.Error("mic speaker matched in SYS registry")
+	}
+	if p.GetSpeakerCount() != 0 || p.nextSpeakerID != 1 {
+		t.Errorf("SYS registry changed: %d speakers, next ID %d", p.GetSpeakerCount(), p.nextSpeakerID)
+	}
+	if id, found := findMatchingGlobalSpeaker(p.micSpeakerProfiles, voice); !found || id != 1 {
+		t.Errorf("mic speaker lookup = %d, %v", id, found)
+	}
+
+	p.ResetSpeakers()
+	if len(p.micSpeakerProfiles) != 0 || p.micNextSpeakerID != 1 {
+		t.Error("ResetSpeakers must clear the mic registry")
+	}
+}
+
+func TestAudioPipeline_RegisterMicSpeakersConcurrent(t *testing.T) {
+	p, err := NewAudioPipeline(&mockTranscriber{name: "mock"}, DefaultPipelineConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := []SpeakerSegment{{Start: 0, End: 1, Speaker: 0}}
+
+	// Реестр MIC канала обновляется под блокировкой на запись: параллельные чанки и чтение не гоняются (go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			voice := make([]float32, 8)
+			voice[i] = 1
+			segments := p.registerGlobalSpeakers(local, map[int][]float32{0: voice}, true)
+			if len(segments) != 1 || segments[0].Speaker < 0 {
+				t.Errorf("mic segments = %+v", segments)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			p.GetSpeakerCount()
+		}()
+	}
+	wg.Wait()
+
+	if len(p.micSpeakerProfiles) != 8 || p.micNextSpeakerID != 9 {
+		t.Errorf("mic registry: %d speakers, next ID %d", len(p.micSpeakerProfiles), p.micNextSpeakerID)
+	}
+	if p.GetSpeakerCount() != 0 {
+		t.Errorf("SYS registry changed: %d speakers", p.GetSpeakerCount())
+	}
+	// Без encoder (FluidAudio) локальные ID сохраняются и реестр не трогается
+	if segments := p.registerGlobalSpeakers(local, nil, true); segments[0].Speaker != 0 || len(p.micSpeakerProfiles) != 8 {
+		t.Errorf("segments without embeddings = %+v", segments)
+	}
+}
@@ -235,6 +235,15 @@ func (v *SileroVAD) ProcessChunk(samples []float32) (float32, error) {
 // DetectSpeechRegions определяет участки речи в аудио
 // Возвращает список сегментов с началом и концом каждого участка речи
 func (v *SileroVAD) DetectSpeechRegions(samples []float32) ([]SileroVADSegment, error) {
+	return v.DetectSpeechRegionsWithThreshold(samples, v.config.Threshold)
+}
+
+// DetectSpeechRegionsWithThreshold определяет участки речи с указанным порогом вероятности
+// (0 - порог из конфигурации). Позволяет использовать разные пороги для разных каналов
+func (v *SileroVAD) DetectSpeechRegionsWithThreshold(samples []float32, threshold float32) ([]SileroVADSegment, error) {
+	if threshold <= 0 || threshold >= 1 {
+		threshold = v.config.Threshold
+	}
 	v.ResetState()
 
 	// Размер окна для обработки
@@ -285,7 +294,7 @@ func (v *SileroVAD) DetectSpeechRegions(samples []float32) ([]SileroVADSegment,
 		}
 
 		currentMs := int64(float64(i) * 1000 / float64(v.config.SampleRate))
-		isSpeech := prob >= v.config.Threshold
+		isSpeech := prob >= threshold
 
 		if isSpeech {
 			silenceCount = 0
@@ -30,6 +30,7 @@ type Engine = WhisperEngine
 
 // Проверяем что WhisperEngine реализует TranscriptionEngine
 var _ TranscriptionEngine = (*WhisperEngine)(nil)
+var _ PromptContextEngine = (*WhisperEngine)(nil)
 
 // NewWhisperEngine создаёт новый движок с указанной моделью
 func NewWhisperEngine(modelPath string) (*WhisperEngine, error) {
@@ -100,6 +101,17 @@ func (e *WhisperEngine) Transcribe(samples []float32, useContext bool) (string,
 
 // TranscribeWithSegments возвращает сегменты с таймстемпами
 func (e *WhisperEngine) TranscribeWithSegments(samples []float32) ([]TranscriptSegment, error) {
+	return e.TranscribeWithSegmentsProgress(samples, nil)
+}
+
+// TranscribeWithSegmentsProgress как TranscribeWithSegments, но сообщает прогресс декодирования
+func (e *WhisperEngine) TranscribeWithSegmentsProgress(samples []float32, onProgress ProgressFunc) ([]TranscriptSegment, error) {
+	return e.TranscribeWithPrompt(samples, "", onProgress)
+}
+
+// TranscribeWithPrompt как TranscribeWithSegmentsProgress, но добавляет previousText
+// (конец текста предыдущего чанка) в initial prompt
+func (e *WhisperEngine) TranscribeWithPrompt(samples []float32, previousText string, onProgress ProgressFunc) ([]TranscriptSegment, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -135,15 +147,13 @@ func (e *WhisperEngine) TranscribeWithSegments(samples []float32) ([]TranscriptS
 	// Включаем таймстемпы токенов для точных временных меток
 	ctx.SetTokenTimestamps(true)
 
-	// Используем hotwords в initial prompt если они заданы
-	// Это помогает Whisper лучше распознавать специфические термины
-	if len(e.hotwords) > 0 {
-		prompt := "Термины: " + strings.Join(e.hotwords, ", ") + "."
-		ctx.SetInitialPrompt(prompt)
-		log.Printf("TranscribeWithSegments: using hotwords prompt: %s", prompt)
-	} else {
-		// Пустой начальный промпт для предотвращения зацикливания
-		ctx.SetInitialPrompt("")
+	// Используем hotwords и текст предыдущего чанка в initial prompt, если они заданы
+	// Это помогает Whisper лучше распознавать специфические термины и имена на стыке чанков
+	// Без них промпт пустой для предотвращения зацикливания
+	prompt := buildInitialPrompt(e.hotwords, previousText)
+	ctx.SetInitialPrompt(prompt)
+	if prompt != "" {
+		log.Printf("TranscribeWithSegments: using initial prompt: %s", prompt)
 	}
 
 	log.Printf("TranscribeWithSegments: samples=%d duration=%.1fs lang=%s", len(samples), float64(len(samples))/16000, e.language)
@@ -153,6 +163,9 @@ func (e *WhisperEngine) TranscribeWithSegments(samples []float32) ([]TranscriptS
 		if progress%10 == 0 { // Логируем каждые 10%
 			log.Printf("TranscribeWithSegments progress: %d%%", progress)
 		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
 	}
 
 	log.Printf("TranscribeWithSegments: starting ctx.Process...")
@@ -582,6 +595,19 @@ func (e *WhisperEngine) SetLanguage(lang string) {
 	e.language = lang
 }
 
+// buildInitialPrompt собирает initial prompt из hotwords и текста предыдущего чанка.
+// Текст предыдущего чанка идёт последним: Whisper воспринимает промпт как предшествующую речь
+func buildInitialPrompt(hotwords []string, previousText string) string {
+	var parts []string
+	if len(hotwords) > 0 {
+		parts = append(parts, "Термины: "+strings.Join(hotwords, ", ")+".")
+	}
+	if previousText = strings.TrimSpace(previousText); previousText != "" {
+		parts = append(parts, previousText)
+	}
+	return strings.Join(parts, " ")
+}
+
 // SetHotwords устанавливает словарь подсказок
 // Для Whisper используется как часть initial prompt
 func (e *WhisperEngine) SetHotwords(words []string) {
@@ -0,0 +1,88 @@
+package ai
+
+import "strings"
+
+// DiffOpType тип участка пословного diff
+type DiffOpType string
+
+const (
+	DiffEqual   DiffOpType = "equal"   // Слова совпадают
+	DiffInsert  DiffOpType = "insert"  // Слова добавлены во втором тексте
+	DiffDelete  DiffOpType = "delete"  // Слова удалены из первого текста
+	DiffReplace DiffOpType = "replace" // Слова заменены (включая регистр и пунктуацию)
+)
+
+// DiffOp участок пословного diff: From - слова первого текста, To - второго
+type DiffOp struct {
+	Op      DiffOpType `json:"op"`
+	From    string     `json:"from,omitempty"`
+	To      string     `json:"to,omitempty"`
+	StartMs int64      `json:"startMs"` // Начало участка (по второму тексту, для удаления - по первому)
+}
+
+// DiffWords строит пословный diff двух последовательностей слов тем же выравниванием
+// Needleman-Wunsch, что и гибридная транскрипция. Соседние изменения объединяются в один участок
+func DiffWords(from, to []TranscriptWord) []DiffOp {
+	alignment := alignWordsNeedlemanWunsch(from, to)
+	if alignment == nil {
+		// Один из текстов пуст - выравнивать нечего
+		for i := range from {
+			alignment = append(alignment, WordAlignment{PrimaryIdx: i, SecondaryIdx: -1})
+		}
+		for j := range to {
+			alignment = append(alignment, WordAlignment{PrimaryIdx: -1, SecondaryIdx: j})
+		}
+	}
+
+	var ops []DiffOp
+	var fromWords, toWords []string
+	var equal bool
+	var fromStart, toStart int64 = -1, -1
+
+	flush := func() {
+		if len(fromWords) == 0 && len(toWords) == 0 {
+			return
+		}
+		op := DiffOp{From: strings.Join(fromWords, " "), To: strings.Join(toWords, " "), StartMs: toStart}
+		if toStart < 0 {
+			op.StartMs = fromStart
+		}
+		switch {
+		case equal:
+			op.Op = DiffEqual
+		case len(fromWords) == 0:
+			op.Op = DiffInsert
+		case len(toWords) == 0:
+			op.Op = DiffDelete
+		default:
+			op.Op = DiffReplace
+		}
+		ops = append(ops, op)
+		fromWords, toWords = nil, nil
+		fromStart, toStart = -1, -1
+	}
+
+	for _, a := range alignment {
+		same := a.PrimaryIdx >= 0 && a.SecondaryIdx >= 0 && from[a.PrimaryIdx].Text == to[a.SecondaryIdx].Text
+		if same != equal {
+			flush()
+			equal = same
+		}
+		if a.PrimaryIdx >= 0 {
+			word := from[a.PrimaryIdx]
+			if fromStart < 0 {
+				fromStart = word.Start
+			}
+			fromWords = append(fromWords, word.Text)
+		}
+		if a.SecondaryIdx >= 0 {
+			word := to[a.SecondaryIdx]
+			if toStart < 0 {
+				toStart = word.Start
+			}
+			toWords = append(toWords, word.Text)
+		}
+	}
+	flush()
+	return ops
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func diffTestWords(text string) []TranscriptWord {
+	var words []TranscriptWord
+	for i, w := range strings.Fields(text) {
+		words = append(words, TranscriptWord{Start: int64(i * 100), Text: w})
+	}
+	return words
+}
+
+func TestDiffWords(t *testing.T) {
+	ops := DiffWords(diffTestWords("привет как дела у тебя"), diffTestWords("Привет, как дела у вас сегодня"))
+	var got []string
+	for _, op := range ops {
+		got = append(got, string(op.Op)+":"+op.From+"|"+op.To)
+	}
+	want := []string{
+		"replace:привет|Привет,",
+		"equal:как дела у|как дела у",
+		"replace:тебя|вас сегодня",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ops = %q, want %q", got, want)
+	}
+	if ops[2].StartMs != 400 {
+		t.Errorf("replace start = %d, want 400", ops[2].StartMs)
+	}
+
+	ops = DiffWords(nil, diffTestWords("новый текст"))
+	if len(ops) != 1 || ops[0].Op != DiffInsert || ops[0].To != "новый текст" {
+		t.Errorf("diff from empty = %+v", ops)
+	}
+	ops = DiffWords(diffTestWords("удалено"), nil)
+	if len(ops) != 1 || ops[0].Op != DiffDelete || ops[0].StartMs != 0 {
+		t.Errorf("diff to empty = %+v", ops)
+	}
+}
@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
@@ -21,6 +22,13 @@ type AudioDevice struct {
 	IsOutput bool   `json:"isOutput"`
 }
 
+// CapturableApp приложение, звук которого можно захватить через ScreenCaptureKit
+type CapturableApp struct {
+	BundleID string `json:"bundleId"`
+	Name     string `json:"name"`
+	PID      int    `json:"pid"`
+}
+
 // AudioChannel представляет источник аудио (микрофон или системный звук)
 type AudioChannel int
 
@@ -68,6 +76,8 @@ type Capture struct {
 	useScreenCaptureKit bool                // Использовать ScreenCaptureKit для системного звука (macOS 13+)
 	useCoreAudioTap     bool                // Использовать Core Audio tap (macOS 14.2+)
 	systemCaptureMethod SystemCaptureMethod // Метод захвата системного звука
+	captureApp          string              // Bundle ID приложения для захвата через ScreenCaptureKit (пусто - весь звук)
+	rawDump             *RawCaptureDump     // Дамп сырого потока кадров для отладки (nil - выключен)
 }
 
 func NewCapture() (*Capture, error) {
@@ -243,6 +253,35 @@ func (c *Capture) SetSystemCaptureMethod(method SystemCaptureMethod) {
 	}
 }
 
+// SetCaptureApp ограничивает захват системного звука через ScreenCaptureKit одним приложением
+// (bundle ID). Пустая строка - захват всего системного звука
+func (c *Capture) SetCaptureApp(bundleID string) {
+	c.captureApp = strings.TrimSpace(bundleID)
+}
+
+// GetCaptureApp возвращает bundle ID приложения для захвата (пусто - весь звук)
+func (c *Capture) GetCaptureApp() string {
+	return c.captureApp
+}
+
+// screenCaptureArgs аргументы screencapture-audio: режим и bundle ID приложения, если захват ограничен им
+func screenCaptureArgs(mode, app string) []string {
+	args := []string{mode}
+	if app != "" {
+		args = append(args, app)
+	}
+	return args
+}
+
+// parseCapturableApps разбирает вывод `screencapture-audio list-apps` (JSON массив приложений)
+func parseCapturableApps(output []byte) ([]CapturableApp, error) {
+	var apps []CapturableApp
+	if err := json.Unmarshal(output, &apps); err != nil {
+		return nil, fmt.Errorf("failed to parse capturable apps: %w", err)
+	}
+	return apps, nil
+}
+
 // GetSystemCaptureMethod возвращает текущий метод захвата системного звука
 func (c *Capture) GetSystemCaptureMethod() SystemCaptureMethod {
 	return c.systemCaptureMethod
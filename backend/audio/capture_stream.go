@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// Маркеры каналов в потоке screencapture-audio и coreaudio-tap
+const (
+	MarkerMicrophone byte = 0x4D // 'M' - микрофон
+	MarkerSystem     byte = 0x53 // 'S' - системный звук
+)
+
+// maxFrameSamples защита от мусора в потоке: кадр больше считается ошибкой
+const maxFrameSamples = 1000000
+
+// ChannelForMarker возвращает канал по маркеру кадра
+func ChannelForMarker(marker byte) (AudioChannel, bool) {
+	switch marker {
+	case MarkerMicrophone:
+		return ChannelMicrophone, true
+	case MarkerSystem:
+		return ChannelSystem, true
+	}
+	return 0, false
+}
+
+// ReadCaptureFrames читает поток кадров [маркер 1 байт][размер 4 байта][float32 данные]
+// до EOF и передаёт каждый кадр в handle. Если dump не nil, исходные байты кадров
+// дописываются в него. Возвращает nil при штатном завершении потока
+func ReadCaptureFrames(r io.Reader, dump *RawCaptureDump, handle func(marker byte, samples []float32)) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 5) // 1 байт маркер + 4 байта размер
+
+	for {
+		// Читаем заголовок
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read frame header: %w", err)
+		}
+
+		marker := header[0]
+		sampleCount := binary.LittleEndian.Uint32(header[1:5])
+
+		if sampleCount == 0 || sampleCount > maxFrameSamples {
+			log.Printf("Invalid sample count: %d", sampleCount)
+			continue
+		}
+
+		// Читаем данные
+		data := make([]byte, int(sampleCount)*4)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read audio data: %w", err)
+		}
+
+		if dump != nil {
+			dump.writeFrame(header, data)
+		}
+
+		// Конвертируем bytes в float32
+		samples := make([]float32, sampleCount)
+		for i := uint32(0); i < sampleCount; i++ {
+			bits := binary.LittleEndian.Uint32(data[i*4 : (i+1)*4])
+			samples[i] = float32frombits(bits)
+		}
+
+		handle(marker, samples)
+	}
+}
+
+// RawCaptureDump сохраняет сырой поток кадров захвата в файл, чтобы воспроизвести
+// проблемы со звуком без окружения пользователя. Кадры пишутся целиком;
+// после достижения лимита размера запись прекращается
+type RawCaptureDump struct {
+	mu       sync.Mutex
+	file     *os.File
+	path     string
+	written  int64
+	maxBytes int64
+	capped   bool
+}
+
+// CreateRawCaptureDump создаёт файл дампа с лимитом размера maxBytes
+func CreateRawCaptureDump(path string, maxBytes int64) (*RawCaptureDump, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("raw capture size limit must be positive")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raw capture dump: %w", err)
+	}
+	return &RawCaptureDump{file: f, path: path, maxBytes: maxBytes}, nil
+}
+
+// writeFrame дописывает кадр в дамп, если он помещается в лимит
+func (d *RawCaptureDump) writeFrame(header, data []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil || d.capped {
+		return
+	}
+	size := int64(len(header) + len(data))
+	if d.written+size > d.maxBytes {
+		d.capped = true
+		log.Printf("Raw capture dump %s reached size limit (%d bytes), further frames are not saved", d.path, d.maxBytes)
+		return
+	}
+	if _, err := d.file.Write(header); err == nil {
+		_, err = d.file.Write(data)
+		if err == nil {
+			d.written += size
+			return
+		}
+	}
+	d.capped = true
+	log.Printf("Raw capture dump %s: write failed, dump stopped", d.path)
+}
+
+// Written возвращает количество записанных байт
+func (d *RawCaptureDump) Written() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written
+}
+
+// Close закрывает файл дампа
+func (d *RawCaptureDump) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	log.Printf("Raw capture dump closed: %s (%d bytes)", d.path, d.written)
+	return err
+}
+
+// SetRawCaptureDump включает запись сырого потока screencapture-audio/coreaudio-tap в дамп
+// (nil - выключить). Применяется при следующем запуске захвата
+func (c *Capture) SetRawCaptureDump(dump *RawCaptureDump) {
+	c.rawDump = dump
+}
@@ -0,0 +1,41 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetCaptureApp(t *testing.T) {
+	c := &Capture{}
+	c.SetCaptureApp("  us.zoom.xos \n")
+	if got := c.GetCaptureApp(); got != "us.zoom.xos" {
+		t.Errorf("capture app = %q", got)
+	}
+	if got := screenCaptureArgs("system", c.GetCaptureApp()); !reflect.DeepEqual(got, []string{"system", "us.zoom.xos"}) {
+		t.Errorf("args with app = %v", got)
+	}
+
+	// Пустое значение - снова весь системный звук
+	c.SetCaptureApp(" ")
+	if got := screenCaptureArgs("both", c.GetCaptureApp()); !reflect.DeepEqual(got, []string{"both"}) {
+		t.Errorf("args without app = %v", got)
+	}
+}
+
+func TestParseCapturableApps(t *testing.T) {
+	apps, err := parseCapturableApps([]byte(`[{"bundleId":"us.zoom.xos","name":"zoom.us","pid":42},{"bundleId":"com.google.Chrome","name":"Google Chrome","pid":7}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CapturableApp{{BundleID: "us.zoom.xos", Name: "zoom.us", PID: 42}, {BundleID: "com.google.Chrome", Name: "Google Chrome", PID: 7}}
+	if !reflect.DeepEqual(apps, want) {
+		t.Errorf("apps = %+v", apps)
+	}
+
+	if apps, err := parseCapturableApps([]byte(`[]`)); err != nil || len(apps) != 0 {
+		t.Errorf("empty list: apps = %+v, err = %v", apps, err)
+	}
+	if _, err := parseCapturableApps([]byte("ERROR: permission denied")); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
@@ -4,9 +4,7 @@ package audio
 
 import (
 	"bufio"
-	"encoding/binary"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -134,6 +132,7 @@ func (c *Capture) StartCoreAudioTap() error {
 
 	// Горутина для чтения аудио данных из stdout
 	// Формат: [маркер 1 байт][размер 4 байта][float32 данные]
+	dump := c.rawDump
 	go func() {
 		defer func() {
 			coreAudioMu.Lock()
@@ -141,57 +140,18 @@ func (c *Capture) StartCoreAudioTap() error {
 			coreAudioMu.Unlock()
 		}()
 
-		reader := bufio.NewReader(stdout)
-		header := make([]byte, 5) // 1 байт маркер + 4 байта размер
-
-		for {
-			// Читаем заголовок
-			_, err := io.ReadFull(reader, header)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading header from coreaudio-tap: %v", err)
-				}
-				return
-			}
-
-			marker := header[0]
-			sampleCount := binary.LittleEndian.Uint32(header[1:5])
-
-			if sampleCount == 0 || sampleCount > 1000000 {
-				log.Printf("Invalid sample count: %d", sampleCount)
-				continue
-			}
-
-			// Читаем данные
-			dataSize := int(sampleCount) * 4
-			data := make([]byte, dataSize)
-			_, err = io.ReadFull(reader, data)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading audio data: %v", err)
-				}
-				return
-			}
-
-			// Конвертируем bytes в float32
-			samples := make([]float32, sampleCount)
-			for i := uint32(0); i < sampleCount; i++ {
-				bits := binary.LittleEndian.Uint32(data[i*4 : (i+1)*4])
-				samples[i] = float32frombits(bits)
-			}
-
-			// Определяем канал по маркеру (Core Audio tap только системный звук)
-			var channel AudioChannel
-			switch marker {
-			case 0x53: // 'S' - системный звук
-				channel = ChannelSystem
-			default:
+		err := ReadCaptureFrames(stdout, dump, func(marker byte, samples []float32) {
+			// Core Audio tap передаёт только системный звук
+			if marker != MarkerSystem {
 				log.Printf("Unknown channel marker: 0x%02X", marker)
-				continue
+				return
 			}
 
 			// Отправляем в канал
-			c.dataChan <- ChannelData{Channel: channel, Samples: samples}
+			c.dataChan <- ChannelData{Channel: ChannelSystem, Samples: samples}
+		})
+		if err != nil {
+			log.Printf("Error reading from coreaudio-tap: %v", err)
 		}
 	}()
 
@@ -6,6 +6,10 @@
 //   system - только системный звук (маркер 'S')
 //   mic    - только микрофон (маркер 'M') - требует macOS 15+
 //   both   - ДВА ОТДЕЛЬНЫХ потока: системный + микрофон (требует macOS 15+)
+//   list-apps - вывести в stdout JSON список приложений, доступных для захвата
+//
+// Второй аргумент (опционально) - bundle ID приложения: системный звук
+// захватывается только из него. Если приложение не найдено - захватывается весь звук
 //
 // Формат вывода:
 //   [маркер 1 байт][размер 4 байта little-endian][float32 данные]
@@ -257,6 +261,30 @@ func performCleanup() async {
 
 // MARK: - Main
 
+// MARK: - Список приложений
+
+/// Выводит в stdout JSON массив приложений, звук которых можно захватить
+func listCapturableApps() async {
+    do {
+        let content = try await SCShareableContent.excludingDesktopWindows(false, onScreenWindowsOnly: false)
+        let ownPID = ProcessInfo.processInfo.processIdentifier
+        var apps: [[String: Any]] = []
+        for app in content.applications where !app.bundleIdentifier.isEmpty && app.processID != ownPID {
+            apps.append([
+                "bundleId": app.bundleIdentifier,
+                "name": app.applicationName,
+                "pid": Int(app.processID),
+            ])
+        }
+        let data = try JSONSerialization.data(withJSONObject: apps, options: [])
+        FileHandle.standardOutput.write(data)
+        FileHandle.standardOutput.write("\n".data(using: .utf8)!)
+    } catch {
+        fputs("ERROR: \(error.localizedDescription)\n", stderr)
+        exit(1)
+    }
+}
+
 @main
 struct ScreenCaptureAudio {
     static var systemStream: SCStream?
@@ -272,7 +300,13 @@ struct ScreenCaptureAudio {
         
         // Парсим аргументы
         let args = CommandLine.arguments
-        let captureMode = args.count > 1 ? args[1] : "system" // system, mic, both
+        let captureMode = args.count > 1 ? args[1] : "system" // system, mic, both, list-apps
+        let captureApp = args.count > 2 ? args[2] : ""
+        
+        if captureMode == "list-apps" {
+            await listCapturableApps()
+            exit(0)
+        }
         
         signal(SIGINT, SIG_IGN)
         signal(SIGTERM, SIG_IGN)
@@ -329,6 +363,17 @@ struct ScreenCaptureAudio {
             
             let filter = SCContentFilter(display: display, excludingWindows: [])
             
+            // Фильтр системного звука: только выбранное приложение или весь звук
+            var sysFilter = filter
+            if !captureApp.isEmpty {
+                if let app = content.applications.first(where: { $0.bundleIdentifier == captureApp }) {
+                    sysFilter = SCContentFilter(display: display, including: [app], exceptingWindows: [])
+                    fputs("Capturing audio of app: \(app.applicationName) (\(captureApp))\n", stderr)
+                } else {
+                    fputs("WARNING: App \(captureApp) not found, capturing all system audio\n", stderr)
+                }
+            }
+            
             // Запускаем системный звук (для режимов system и both)
             if captureMode == "system" || captureMode == "both" {
                 let sysConfig = SCStreamConfiguration()
@@ -348,7 +393,7 @@ struct ScreenCaptureAudio {
                 sysConfig.showsCursor = false
                 
                 systemDelegate = AudioCaptureDelegate(marker: 0x53, streamName: "System")
-                systemStream = SCStream(filter: filter, configuration: sysConfig, delegate: systemDelegate)
+                systemStream = SCStream(filter: sysFilter, configuration: sysConfig, delegate: systemDelegate)
                 
                 // Используем выделенную очередь для обработки аудио буферов
                 let audioQueue = DispatchQueue(label: "system.audio.capture", qos: .userInteractive)
@@ -4,9 +4,8 @@ package audio
 
 import (
 	"bufio"
-	"encoding/binary"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -21,6 +20,7 @@ var (
 	screenCaptureMu      sync.Mutex
 	screenCaptureRunning bool
 	screenCaptureMode    string // "system", "mic", "both"
+	screenCaptureMixing  MixingStrategy
 )
 
 // getScreenCaptureBinaryPath возвращает путь к screencapture-audio binary
@@ -84,14 +84,36 @@ func VoiceIsolationAvailable() bool {
 	return major >= 15
 }
 
+// listAppsTimeout ограничивает время получения списка приложений от ScreenCaptureKit
+const listAppsTimeout = 5 * time.Second
+
+// ListCapturableApps возвращает приложения, звук которых можно захватить отдельно
+func ListCapturableApps() ([]CapturableApp, error) {
+	binaryPath := getScreenCaptureBinaryPath()
+	if _, err := os.Stat(binaryPath); err != nil {
+		return nil, fmt.Errorf("screencapture-audio binary not found at %s", binaryPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listAppsTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binaryPath, "list-apps").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capturable apps: %w", err)
+	}
+
+	return parseCapturableApps(output)
+}
+
 // StartScreenCaptureKitAudio запускает захват аудио через ScreenCaptureKit
 // mode: "system" - только системный звук, "mic" - только микрофон, "both" - оба с voice isolation
 func (c *Capture) StartScreenCaptureKitAudio() error {
-	return c.StartScreenCaptureKitAudioWithMode("system")
+	return c.StartScreenCaptureKitAudioWithMode("system", MixingPaired)
 }
 
-// StartScreenCaptureKitAudioWithMode запускает захват с указанным режимом
-func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string) error {
+// StartScreenCaptureKitAudioWithMode запускает захват с указанным режимом.
+// mixing - стратегия выравнивания MIC и SYS каналов для режима "both" (см. MixingStrategy)
+func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string, mixing MixingStrategy) error {
 	screenCaptureMu.Lock()
 	defer screenCaptureMu.Unlock()
 
@@ -104,9 +126,10 @@ func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string) error {
 		return fmt.Errorf("screencapture-audio binary not found at %s. Build it with: cd backend/audio/screencapture && swift build -c release", binaryPath)
 	}
 
-	// Запускаем процесс с режимом
-	screenCaptureCmd = exec.Command(binaryPath, mode)
+	// Запускаем процесс с режимом (и приложением, если захват ограничен одним приложением)
+	screenCaptureCmd = exec.Command(binaryPath, screenCaptureArgs(mode, c.captureApp)...)
 	screenCaptureMode = mode
+	screenCaptureMixing = mixing
 
 	// Получаем stdout для чтения аудио данных
 	stdout, err := screenCaptureCmd.StdoutPipe()
@@ -133,7 +156,7 @@ func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string) error {
 		for scanner.Scan() {
 			line := scanner.Text()
 			if strings.HasPrefix(line, "READY") {
-				log.Printf("ScreenCaptureKit audio capture started (mode=%s)", mode)
+				log.Printf("ScreenCaptureKit audio capture started (mode=%s, mixing=%s, app=%q)", mode, mixing, c.captureApp)
 			} else if strings.HasPrefix(line, "ERROR:") {
 				log.Printf("ScreenCaptureKit: %s", line)
 			} else {
@@ -144,6 +167,7 @@ func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string) error {
 
 	// Горутина для чтения аудио данных из stdout
 	// Формат: [маркер 1 байт][размер 4 байта][float32 данные]
+	dump := c.rawDump
 	go func() {
 		defer func() {
 			screenCaptureMu.Lock()
@@ -151,65 +175,36 @@ func (c *Capture) StartScreenCaptureKitAudioWithMode(mode string) error {
 			screenCaptureMu.Unlock()
 		}()
 
-		reader := bufio.NewReader(stdout)
-		header := make([]byte, 5) // 1 байт маркер + 4 байта размер
-
-		for {
-			// Читаем заголовок
-			_, err := io.ReadFull(reader, header)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading header from screencapture-audio: %v", err)
-				}
-				return
-			}
-
-			marker := header[0]
-			sampleCount := binary.LittleEndian.Uint32(header[1:5])
-
-			if sampleCount == 0 || sampleCount > 1000000 {
-				log.Printf("Invalid sample count: %d", sampleCount)
-				continue
-			}
-
-			// Читаем данные
-			dataSize := int(sampleCount) * 4
-			data := make([]byte, dataSize)
-			_, err = io.ReadFull(reader, data)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading audio data: %v", err)
-				}
-				return
-			}
-
-			// Конвертируем bytes в float32
-			samples := make([]float32, sampleCount)
-			for i := uint32(0); i < sampleCount; i++ {
-				bits := binary.LittleEndian.Uint32(data[i*4 : (i+1)*4])
-				samples[i] = float32frombits(bits)
-			}
-
+		err := ReadCaptureFrames(stdout, dump, func(marker byte, samples []float32) {
 			// Определяем канал по маркеру
-			var channel AudioChannel
-			switch marker {
-			case 0x4D: // 'M' - микрофон
-				channel = ChannelMicrophone
-			case 0x53: // 'S' - системный звук
-				channel = ChannelSystem
-			default:
+			channel, ok := ChannelForMarker(marker)
+			if !ok {
 				log.Printf("Unknown channel marker: 0x%02X", marker)
-				continue
+				return
 			}
 
 			// Отправляем в канал
 			c.dataChan <- ChannelData{Channel: channel, Samples: samples}
+		})
+		if err != nil {
+			log.Printf("Error reading from screencapture-audio: %v", err)
 		}
 	}()
 
 	return nil
 }
 
+// MixingStrategy возвращает стратегию микширования каналов текущего захвата:
+// выбранную для Voice Isolation ("both"), иначе paired
+func (c *Capture) MixingStrategy() MixingStrategy {
+	screenCaptureMu.Lock()
+	defer screenCaptureMu.Unlock()
+	if !screenCaptureRunning || screenCaptureMode != "both" || screenCaptureMixing == "" {
+		return MixingPaired
+	}
+	return screenCaptureMixing
+}
+
 // StopScreenCaptureKitAudio останавливает захват системного аудио
 func (c *Capture) StopScreenCaptureKitAudio() {
 	screenCaptureMu.Lock()
@@ -0,0 +1,161 @@
+package audio
+
+import "fmt"
+
+// MixingStrategy определяет, как выравниваются MIC и SYS каналы перед записью в стерео
+type MixingStrategy string
+
+const (
+	// MixingPaired пишет только пары семплов: лишние семплы одного канала ждут другой (min)
+	MixingPaired MixingStrategy = "paired"
+	// MixingPadded пишет всё накопленное, дополняя короткий канал тишиной (max)
+	MixingPadded MixingStrategy = "padded"
+	// MixingResampled подгоняет SYS канал под MIC по соотношению полученных семплов:
+	// разница частот устройств не копится, и каналы не расходятся на длинных записях
+	MixingResampled MixingStrategy = "resampled"
+)
+
+// ParseMixingStrategy проверяет стратегию микширования (пусто - paired)
+func ParseMixingStrategy(value string) (MixingStrategy, error) {
+	switch strategy := MixingStrategy(value); strategy {
+	case "":
+		return MixingPaired, nil
+	case MixingPaired, MixingPadded, MixingResampled:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown mixing strategy: %q (expected paired, padded or resampled)", value)
+}
+
+// StereoMixer накапливает семплы MIC и SYS каналов и выдаёт их выровненными парами
+type StereoMixer struct {
+	strategy MixingStrategy
+	mic      []float32
+	sys      []float32
+
+	// Для resampled: всего получено семплов и дробный остаток позиции SYS
+	micTotal int64
+	sysTotal int64
+	sysCarry float64
+}
+
+// NewStereoMixer создаёт микшер с указанной стратегией
+func NewStereoMixer(strategy MixingStrategy) *StereoMixer {
+	if strategy == "" {
+		strategy = MixingPaired
+	}
+	return &StereoMixer{strategy: strategy}
+}
+
+// Strategy возвращает стратегию микширования
+func (m *StereoMixer) Strategy() MixingStrategy {
+	return m.strategy
+}
+
+// Add добавляет семплы канала
+func (m *StereoMixer) Add(channel AudioChannel, samples []float32) {
+	if channel == ChannelMicrophone {
+		m.mic = append(m.mic, samples...)
+		m.micTotal += int64(len(samples))
+	} else {
+		m.sys = append(m.sys, samples...)
+		m.sysTotal += int64(len(samples))
+	}
+}
+
+// Reset отбрасывает накопленные семплы (например, на паузе записи)
+func (m *StereoMixer) Reset() {
+	m.mic = m.mic[:0]
+	m.sys = m.sys[:0]
+	m.micTotal, m.sysTotal = 0, 0
+	m.sysCarry = 0
+}
+
+// Next выдаёт готовые семплы каналов одинаковой длины (пусто - ждём данных)
+func (m *StereoMixer) Next() (mic, sys []float32) {
+	switch m.strategy {
+	case MixingPadded:
+		n := max(len(m.mic), len(m.sys))
+		if n == 0 {
+			return nil, nil
+		}
+		mic = make([]float32, n)
+		sys = make([]float32, n)
+		copy(mic, m.mic)
+		copy(sys, m.sys)
+		m.mic, m.sys = m.mic[:0], m.sys[:0]
+		return mic, sys
+
+	case MixingResampled:
+		if len(m.mic) == 0 || len(m.sys) == 0 {
+			return nil, nil
+		}
+		// SYS семплов на один семпл MIC (по всем полученным данным, так сглаживается неравномерность кадров)
+		ratio := float64(m.sysTotal) / float64(m.micTotal)
+		n := min(len(m.mic), int((float64(len(m.sys))-m.sysCarry)/ratio))
+		if n == 0 {
+			return nil, nil
+		}
+		want := float64(n)*ratio + m.sysCarry
+		take := min(int(want), len(m.sys))
+		m.sysCarry = want - float64(take)
+
+		mic = append([]float32(nil), m.mic[:n]...)
+		sys = resampleLinear(m.sys[:take], n)
+		m.mic = consumeSamples(m.mic, n)
+		m.sys = consumeSamples(m.sys, take)
+		return mic, sys
+	}
+
+	n := min(len(m.mic), len(m.sys))
+	if n == 0 {
+		return nil, nil
+	}
+	mic = append([]float32(nil), m.mic[:n]...)
+	sys = append([]float32(nil), m.sys[:n]...)
+	m.mic = consumeSamples(m.mic, n)
+	m.sys = consumeSamples(m.sys, n)
+	return mic, sys
+}
+
+// Interleave собирает стерео [mic, sys, mic, sys, ...] из каналов одинаковой длины
+func Interleave(mic, sys []float32) []float32 {
+	n := min(len(mic), len(sys))
+	stereo := make([]float32, n*2)
+	for i := 0; i < n; i++ {
+		stereo[i*2] = mic[i]
+		stereo[i*2+1] = sys[i]
+	}
+	return stereo
+}
+
+// resampleLinear растягивает или сжимает семплы до n линейной интерполяцией
+func resampleLinear(samples []float32, n int) []float32 {
+	result := make([]float32, n)
+	if len(samples) == 0 {
+		return result
+	}
+	if len(samples) == n {
+		copy(result, samples)
+		return result
+	}
+	step := float64(len(samples)) / float64(n)
+	for i := range result {
+		pos := float64(i) * step
+		j := int(pos)
+		if j >= len(samples)-1 {
+			result[i] = samples[len(samples)-1]
+			continue
+		}
+		frac := float32(pos - float64(j))
+		result[i] = samples[j]*(1-frac) + samples[j+1]*frac
+	}
+	return result
+}
+
+// consumeSamples убирает n первых семплов буфера, сохраняя его ёмкость
+func consumeSamples(buf []float32, n int) []float32 {
+	if n >= len(buf) {
+		return buf[:0]
+	}
+	return buf[n:]
+}
@@ -0,0 +1,95 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// ramp семплы со значениями start, start+1, ... (по значению видно исходную позицию)
+func ramp(start, n int) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(start + i)
+	}
+	return samples
+}
+
+func TestStereoMixerStrategies(t *testing.T) {
+	// SYS устройство чуть быстрее: на 1000 семплов MIC приходит 1010 SYS
+	feed := func(m *StereoMixer) (mic, sys []float32) {
+		m.Add(ChannelMicrophone, ramp(0, 500))
+		m.Add(ChannelSystem, ramp(0, 505))
+		a, b := m.Next()
+		mic, sys = append(mic, a...), append(sys, b...)
+		m.Add(ChannelMicrophone, ramp(500, 500))
+		m.Add(ChannelSystem, ramp(505, 505))
+		a, b = m.Next()
+		return append(mic, a...), append(sys, b...)
+	}
+
+	t.Run("paired", func(t *testing.T) {
+		m := NewStereoMixer(MixingPaired)
+		mic, sys := feed(m)
+		if len(mic) != 1000 || len(sys) != 1000 {
+			t.Fatalf("len = %d/%d, want 1000", len(mic), len(sys))
+		}
+		// Лишние SYS семплы ждут пары: каналы сдвигаются на разницу
+		if sys[999] != 999 || len(m.sys) != 10 {
+			t.Errorf("sys[999] = %v, pending sys = %d", sys[999], len(m.sys))
+		}
+	})
+
+	t.Run("padded", func(t *testing.T) {
+		m := NewStereoMixer(MixingPadded)
+		mic, sys := feed(m)
+		if len(mic) != 1010 || len(sys) != 1010 {
+			t.Fatalf("len = %d/%d, want 1010", len(mic), len(sys))
+		}
+		// Недостающие MIC семплы - тишина в середине потока
+		if mic[500] != 0 || mic[505] != 500 || sys[505] != 505 {
+			t.Errorf("mic[500]=%v mic[505]=%v sys[505]=%v", mic[500], mic[505], sys[505])
+		}
+	})
+
+	t.Run("resampled", func(t *testing.T) {
+		m := NewStereoMixer(MixingResampled)
+		mic, sys := feed(m)
+		if len(mic) != 1000 || len(sys) != 1000 {
+			t.Fatalf("len = %d/%d, want 1000", len(mic), len(sys))
+		}
+		if len(m.mic) != 0 || len(m.sys) != 0 {
+			t.Errorf("pending mic=%d sys=%d, want none", len(m.mic), len(m.sys))
+		}
+		// SYS сжат под MIC: семпл i соответствует позиции i*1.01 исходного потока
+		for _, i := range []int{0, 250, 600, 999} {
+			if want := float64(i) * 1.01; math.Abs(float64(sys[i])-want) > 1 {
+				t.Errorf("sys[%d] = %v, want ~%.1f", i, sys[i], want)
+			}
+			if mic[i] != float32(i) {
+				t.Errorf("mic[%d] = %v", i, mic[i])
+			}
+		}
+	})
+
+	t.Run("waits for both channels", func(t *testing.T) {
+		for _, strategy := range []MixingStrategy{MixingPaired, MixingResampled} {
+			m := NewStereoMixer(strategy)
+			m.Add(ChannelMicrophone, ramp(0, 100))
+			if mic, _ := m.Next(); len(mic) != 0 {
+				t.Errorf("%s: mixed %d samples without SYS", strategy, len(mic))
+			}
+		}
+	})
+}
+
+func TestParseMixingStrategy(t *testing.T) {
+	if s, err := ParseMixingStrategy(""); err != nil || s != MixingPaired {
+		t.Errorf("empty = %q, %v", s, err)
+	}
+	if s, err := ParseMixingStrategy("resampled"); err != nil || s != MixingResampled {
+		t.Errorf("resampled = %q, %v", s, err)
+	}
+	if _, err := ParseMixingStrategy("max"); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
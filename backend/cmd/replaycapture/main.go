@@ -0,0 +1,129 @@
+// Воспроизведение дампа сырого потока захвата (raw_capture.bin)
+// Запуск: go run ./cmd/replaycapture -in <session>/raw_capture.bin -out /tmp/replay
+//
+// Дамп пишется при SessionConfig.RecordRawCapture (бэкенд запущен с -raw-capture-max-mb).
+// Поток разбирается тем же кодом, что и при записи (audio.ReadCaptureFrames), результат:
+// - mic.wav, sys.wav - каналы по отдельности
+// - stereo.wav - MIC слева, SYS справа, склейка как в RecordingService.processAudio
+// - статистика кадров по каналам в логе
+
+package main
+
+import (
+	"aiwisper/audio"
+	"aiwisper/session"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+type channelStats struct {
+	frames  int
+	samples int64
+	sumSq   float64
+	peak    float32
+}
+
+func (st *channelStats) add(samples []float32) {
+	st.frames++
+	st.samples += int64(len(samples))
+	for _, s := range samples {
+		st.sumSq += float64(s) * float64(s)
+		if a := float32(math.Abs(float64(s))); a > st.peak {
+			st.peak = a
+		}
+	}
+}
+
+func (st *channelStats) log(name string, sampleRate int) {
+	rms := 0.0
+	if st.samples > 0 {
+		rms = math.Sqrt(st.sumSq / float64(st.samples))
+	}
+	log.Printf("%s: frames=%d, samples=%d (%.2fs), rms=%.4f, peak=%.4f",
+		name, st.frames, st.samples, float64(st.samples)/float64(sampleRate), rms, st.peak)
+}
+
+func main() {
+	inPath := flag.String("in", "raw_capture.bin", "Raw capture dump to replay")
+	outDir := flag.String("out", ".", "Directory for mic.wav, sys.wav and stereo.wav")
+	sampleRate := flag.Int("rate", session.SampleRate, "Sample rate of the capture stream")
+	flag.Parse()
+
+	f, err := os.Open(*inPath)
+	if err != nil {
+		log.Fatalf("Failed to open dump: %v", err)
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("Failed to create output dir: %v", err)
+	}
+
+	newWriter := func(name string, channels int) *session.WAVWriter {
+		w, err := session.NewWAVWriter(filepath.Join(*outDir, name), *sampleRate, channels, 16)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", name, err)
+		}
+		return w
+	}
+	micWAV := newWriter("mic.wav", 1)
+	sysWAV := newWriter("sys.wav", 1)
+	stereoWAV := newWriter("stereo.wav", 2)
+
+	var micStats, sysStats channelStats
+	var micBuffer, sysBuffer []float32
+	unknown := 0
+
+	err = audio.ReadCaptureFrames(f, nil, func(marker byte, samples []float32) {
+		channel, ok := audio.ChannelForMarker(marker)
+		if !ok {
+			unknown++
+			log.Printf("Unknown channel marker: 0x%02X (%d samples)", marker, len(samples))
+			return
+		}
+
+		if channel == audio.ChannelMicrophone {
+			micStats.add(samples)
+			micWAV.Write(samples)
+			micBuffer = append(micBuffer, samples...)
+		} else {
+			sysStats.add(samples)
+			sysWAV.Write(samples)
+			sysBuffer = append(sysBuffer, samples...)
+		}
+
+		// Стерео пишется только когда есть данные обоих каналов
+		if n := min(len(micBuffer), len(sysBuffer)); n > 0 {
+			stereo := make([]float32, n*2)
+			for i := 0; i < n; i++ {
+				stereo[i*2] = micBuffer[i]
+				stereo[i*2+1] = sysBuffer[i]
+			}
+			stereoWAV.Write(stereo)
+			micBuffer = micBuffer[n:]
+			sysBuffer = sysBuffer[n:]
+		}
+	})
+	if err != nil {
+		log.Printf("Stream ended with error: %v", err)
+	}
+
+	for _, w := range []*session.WAVWriter{micWAV, sysWAV, stereoWAV} {
+		if err := w.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", w.FilePath(), err)
+		}
+	}
+
+	micStats.log("MIC", *sampleRate)
+	sysStats.log("SYS", *sampleRate)
+	if unknown > 0 {
+		log.Printf("Unknown frames: %d", unknown)
+	}
+	if len(micBuffer) > 0 || len(sysBuffer) > 0 {
+		log.Printf("Unpaired samples left out of stereo.wav: mic=%d, sys=%d", len(micBuffer), len(sysBuffer))
+	}
+	log.Printf("Output: %s", *outDir)
+}
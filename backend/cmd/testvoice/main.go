@@ -1,6 +1,6 @@
 // Тест полного стека Voice Isolation
 // Использует audio.Capture с Voice Isolation режимом
-// и сравнивает старую (max) и новую (min) логику микширования
+// и сравнивает старую (max), новую (min) и resampled логику микширования
 //
 // Запуск: cd backend && go run ./cmd/testvoice
 // Остановка: Ctrl+C
@@ -8,6 +8,7 @@
 // Создаёт файлы:
 // - /tmp/voice_fixed.wav - ИСПРАВЛЕННАЯ логика (min) - должен звучать чисто
 // - /tmp/voice_broken.wav - СТАРАЯ логика (max) - звучит роботизированно
+// - /tmp/voice_resampled.wav - SYS подогнан под MIC по числу семплов (без расхождения каналов)
 // - /tmp/voice_mic_only.wav - только микрофон (эталон)
 // - /tmp/voice_sys_only.wav - только системный звук
 
@@ -30,6 +31,7 @@ const (
 
 	outputFileFixed  = "/tmp/voice_fixed.wav"
 	outputFileBroken = "/tmp/voice_broken.wav"
+	outputFileResamp = "/tmp/voice_resampled.wav"
 	outputFileMic    = "/tmp/voice_mic_only.wav"
 	outputFileSys    = "/tmp/voice_sys_only.wav"
 )
@@ -137,6 +139,7 @@ func main() {
 	log.Println("Создаём файлы:")
 	log.Printf("  - %s (ИСПРАВЛЕННАЯ логика min - должен быть чистый)", outputFileFixed)
 	log.Printf("  - %s (СТАРАЯ логика max - роботизированный звук)", outputFileBroken)
+	log.Printf("  - %s (resampled - каналы выровнены по числу семплов)", outputFileResamp)
 	log.Printf("  - %s (только микрофон - эталон)", outputFileMic)
 	log.Printf("  - %s (только системный звук)", outputFileSys)
 	log.Println()
@@ -162,6 +165,12 @@ func main() {
 	}
 	defer writerBroken.Close()
 
+	writerResamp, err := NewWAVWriter(outputFileResamp, 2) // стерео
+	if err != nil {
+		log.Fatalf("Ошибка создания %s: %v", outputFileResamp, err)
+	}
+	defer writerResamp.Close()
+
 	writerMic, err := NewWAVWriter(outputFileMic, 1) // моно
 	if err != nil {
 		log.Fatalf("Ошибка создания %s: %v", outputFileMic, err)
@@ -175,7 +184,7 @@ func main() {
 	defer writerSys.Close()
 
 	// Запускаем Voice Isolation mode
-	if err := capture.StartScreenCaptureKitAudioWithMode("both"); err != nil {
+	if err := capture.StartScreenCaptureKitAudioWithMode("both", audio.MixingPaired); err != nil {
 		log.Fatalf("Ошибка запуска Voice Isolation: %v", err)
 	}
 
@@ -186,6 +195,7 @@ func main() {
 	// Отдельные буферы для разных логик
 	var micBufferFixed, sysBufferFixed []float32   // для исправленной логики
 	var micBufferBroken, sysBufferBroken []float32 // для старой логики
+	resampled := audio.NewStereoMixer(audio.MixingResampled)
 
 	consume := func(buf []float32, n int) []float32 {
 		if n >= len(buf) {
@@ -231,6 +241,12 @@ func main() {
 					writerSys.WriteMono(samples)
 				}
 
+				// === RESAMPLED ===
+				resampled.Add(channel, samples)
+				if mic, sys := resampled.Next(); len(mic) > 0 {
+					writerResamp.WriteStereo(audio.Interleave(mic, sys))
+				}
+
 				// === ИСПРАВЛЕННАЯ ЛОГИКА (min) ===
 				// Записываем только когда оба буфера имеют данные
 				micLen := len(micBufferFixed)
@@ -303,9 +319,11 @@ func main() {
 	log.Println()
 	log.Printf("Fixed (min):  %d стерео сэмплов (%.1f сек)", writerFixed.SamplesWritten(), float64(writerFixed.SamplesWritten())/sampleRate)
 	log.Printf("Broken (max): %d стерео сэмплов (%.1f сек)", writerBroken.SamplesWritten(), float64(writerBroken.SamplesWritten())/sampleRate)
+	log.Printf("Resampled:    %d стерео сэмплов (%.1f сек)", writerResamp.SamplesWritten(), float64(writerResamp.SamplesWritten())/sampleRate)
 	log.Println()
 	log.Println("=== Сравните файлы ===")
 	log.Printf("open %s  # Исправленный - должен быть чистый", outputFileFixed)
 	log.Printf("open %s  # Сломанный - роботизированный звук", outputFileBroken)
+	log.Printf("open %s  # Resampled - без расхождения каналов", outputFileResamp)
 	log.Printf("open %s  # Эталон микрофона", outputFileMic)
 }
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"aiwisper/models"
+)
+
+// chunkDurationWarning предупреждение, если чанки короче минимального окна модели (пусто - всё в порядке)
+func chunkDurationWarning(modelID string, chunkDurationMs int64) string {
+	info := models.GetModelByID(modelID)
+	if info == nil || chunkDurationMs <= 0 {
+		return ""
+	}
+	minUseful := info.MinUsefulChunkDuration()
+	if time.Duration(chunkDurationMs)*time.Millisecond >= minUseful {
+		return ""
+	}
+	return fmt.Sprintf("Чанки по %.1f с короче минимального окна модели %s (%.0f с): качество распознавания может снизиться",
+		float64(chunkDurationMs)/1000, info.Name, minUseful.Seconds())
+}
@@ -0,0 +1,82 @@
+package api
+
+import "fmt"
+
+// Причина отключения диаризации при полной ретранскрипции
+const (
+	DiarizationLimitChunks = "chunks" // Сессия длиннее лимита чанков
+	DiarizationLimitMemory = "memory" // Пиковый RSS вырос больше допустимого
+)
+
+// DiarizationLimit сведения об отключении диаризации (diarization_warning)
+type DiarizationLimit struct {
+	Reason          string `json:"reason"`                    // chunks или memory
+	TotalChunks     int    `json:"totalChunks"`               // Чанков в сессии
+	MaxChunks       int    `json:"maxChunks,omitempty"`       // Действующий лимит чанков (0 - без ограничения)
+	MaxMemGrowthMB  int    `json:"maxMemGrowthMb,omitempty"`  // Действующий лимит роста памяти (0 - не проверяется)
+	MemGrowthMB     int    `json:"memGrowthMb,omitempty"`     // Рост памяти к моменту отключения
+	DisabledAtChunk int    `json:"disabledAtChunk,omitempty"` // С какого чанка (с 1) диаризация отключена
+}
+
+// message текст предупреждения для пользователя
+func (l *DiarizationLimit) message() string {
+	if l.Reason == DiarizationLimitMemory {
+		return fmt.Sprintf("Диаризация отключена с чанка %d из %d: память выросла на %d МБ (лимит %d МБ) из-за известной проблемы с памятью",
+			l.DisabledAtChunk, l.TotalChunks, l.MemGrowthMB, l.MaxMemGrowthMB)
+	}
+	return fmt.Sprintf("Диаризация отключена для длинных сессий (%d чанков при лимите %d) из-за известной проблемы с памятью",
+		l.TotalChunks, l.MaxChunks)
+}
+
+// diarizationLimits возвращает лимиты диаризации для полной ретранскрипции из конфигурации
+func (s *Server) diarizationLimits() (maxChunks, maxMemMB int) {
+	if s.Config == nil {
+		return 0, 0
+	}
+	return max(0, s.Config.RetranscribeDiarizationMaxChunks), max(0, s.Config.RetranscribeDiarizationMaxMemMB)
+}
+
+// diarizationChunkLimit проверяет лимит чанков. nil - диаризацию можно использовать
+func diarizationChunkLimit(totalChunks, maxChunks, maxMemMB int) *DiarizationLimit {
+	if maxChunks <= 0 || totalChunks <= maxChunks {
+		return nil
+	}
+	return &DiarizationLimit{
+		Reason:         DiarizationLimitChunks,
+		TotalChunks:    totalChunks,
+		MaxChunks:      maxChunks,
+		MaxMemGrowthMB: maxMemMB,
+	}
+}
+
+// diarizationMemGuard следит за ростом пикового RSS во время ретранскрипции
+type diarizationMemGuard struct {
+	limitMB  int
+	baseline uint64
+	peakRSS  func() uint64
+}
+
+// newDiarizationMemGuard возвращает nil, если лимит выключен или RSS недоступен на платформе
+func newDiarizationMemGuard(limitMB int, peakRSS func() uint64) *diarizationMemGuard {
+	if limitMB <= 0 {
+		return nil
+	}
+	baseline := peakRSS()
+	if baseline == 0 {
+		return nil
+	}
+	return &diarizationMemGuard{limitMB: limitMB, baseline: baseline, peakRSS: peakRSS}
+}
+
+// check возвращает рост памяти в МБ и признак превышения лимита
+func (g *diarizationMemGuard) check() (int, bool) {
+	if g == nil {
+		return 0, false
+	}
+	peak := g.peakRSS()
+	if peak <= g.baseline {
+		return 0, false
+	}
+	growthMB := int((peak - g.baseline) >> 20)
+	return growthMB, growthMB > g.limitMB
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// exportSkippedFile список файлов, не вошедших в архив из-за лимита размера
+const exportSkippedFile = "export-skipped.txt"
+
+// exportArchive ZIP архив пакетного экспорта, пишется потоком в ответ
+type exportArchive struct {
+	zw       *zip.Writer
+	method   uint16 // Метод сжатия текстовых файлов (аудио всегда без сжатия)
+	maxBytes int64  // Лимит суммарного размера файлов до сжатия (0 - без ограничения)
+	written  int64
+	skipped  []string
+}
+
+// validateZipCompressionLevel проверяет уровень сжатия: nil - по умолчанию, 0 - без сжатия, 1-9
+func validateZipCompressionLevel(level *int) error {
+	if level != nil && (*level < flate.NoCompression || *level > flate.BestCompression) {
+		return fmt.Errorf("invalid compression level %d: expected 0-9", *level)
+	}
+	return nil
+}
+
+// newExportArchive создаёт архив с уровнем сжатия level (nil - по умолчанию)
+func newExportArchive(w io.Writer, level *int, maxBytes int64) *exportArchive {
+	a := &exportArchive{zw: zip.NewWriter(w), method: zip.Deflate, maxBytes: maxBytes}
+	switch {
+	case level == nil:
+	case *level == flate.NoCompression:
+		a.method = zip.Store
+	default:
+		lvl := *level
+		a.zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, lvl)
+		})
+	}
+	return a
+}
+
+// reserve проверяет, помещается ли файл в лимит, и учитывает его размер.
+// Не поместившийся файл попадает в список пропущенных
+func (a *exportArchive) reserve(name string, size int64) bool {
+	if a.maxBytes > 0 && a.written+size > a.maxBytes {
+		a.skipped = append(a.skipped, fmt.Sprintf("%s (%d bytes)", name, size))
+		return false
+	}
+	a.written += size
+	return true
+}
+
+// addText добавляет текстовый файл. false - файл не поместился в лимит
+func (a *exportArchive) addText(name, content string) (bool, error) {
+	if !a.reserve(name, int64(len(content))) {
+		return false, nil
+	}
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: a.method})
+	if err != nil {
+		return false, fmt.Errorf("failed to create zip entry %s: %w", name, err)
+	}
+	if _, err := io.WriteString(fw, content); err != nil {
+		return false, fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// addFile копирует файл с диска без сжатия (MP3 уже сжат). false - файл не поместился в лимит
+func (a *exportArchive) addFile(name string, f *os.File, size int64) (bool, error) {
+	if !a.reserve(name, size) {
+		return false, nil
+	}
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return false, fmt.Errorf("failed to create zip entry %s: %w", name, err)
+	}
+	if _, err := io.Copy(fw, f); err != nil {
+		return false, fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// close дописывает список пропущенных файлов (если есть) и завершает архив
+func (a *exportArchive) close() error {
+	if len(a.skipped) > 0 {
+		content := fmt.Sprintf("Files skipped: export size limit of %d bytes reached\n\n%s\n", a.maxBytes, strings.Join(a.skipped, "\n"))
+		fw, err := a.zw.Create(exportSkippedFile)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, content); err != nil {
+			return err
+		}
+	}
+	return a.zw.Close()
+}
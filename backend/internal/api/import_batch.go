@@ -0,0 +1,291 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Параллельность пакетного импорта: конвертация и транскрипция файлов
+const (
+	defaultImportBatchConcurrency = 2
+	maxImportBatchConcurrency     = 4
+)
+
+// importBatchSource файл пакетного импорта: загруженный или из папки на сервере
+type importBatchSource struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// importBatchResult результат импорта одного файла пакета
+type importBatchResult struct {
+	File      string `json:"file"`
+	SessionID string `json:"sessionId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// errImportRecordingActive пакетный импорт переключает модель и язык движка - во время записи нельзя
+var errImportRecordingActive = errors.New("batch import is not available while recording")
+
+// handleImportBatch импортирует несколько файлов (поле audio, можно несколько)
+// или все аудио из папки на сервере (поле folder, только внутри -import-root):
+// по сессии на файл с общими моделью и языком.
+// Сессии создаются до ответа, транскрипция идёт в фоне с прогрессом import_batch_progress.
+// success - импортированы все файлы, files - результат по каждому файлу
+func (s *Server) handleImportBatch(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Файлы сверх лимита памяти временно сохраняются на диск
+	if err := r.ParseMultipartForm(500 << 20); err != nil {
+		http.Error(w, "Invalid multipart form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.SessionMgr.IsActive() {
+		http.Error(w, errImportRecordingActive.Error(), http.StatusConflict)
+		return
+	}
+
+	modelID := r.FormValue("model")
+	language := r.FormValue("language")
+	if language == "" {
+		language = "ru"
+	}
+	language, switched, err := s.resolveModelLanguage(modelID, language, r.FormValue("forceLanguage") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	concurrency := importBatchConcurrency(r.FormValue("concurrency"))
+
+	var sources []importBatchSource
+	if folder := r.FormValue("folder"); folder != "" {
+		importRoot := ""
+		if s.Config != nil {
+			importRoot = s.Config.ImportRoot
+		}
+		dir, err := resolveImportFolder(importRoot, folder)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if sources, err = importSourcesFromDir(dir); err != nil {
+			http.Error(w, "Failed to read folder: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if r.MultipartForm != nil {
+		for _, fh := range r.MultipartForm.File["audio"] {
+			sources = append(sources, importBatchSource{
+				Name: fh.Filename,
+				Open: func() (io.ReadCloser, error) { return fh.Open() },
+			})
+		}
+	}
+	if len(sources) == 0 {
+		http.Error(w, "No audio files to import", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Import batch: %d files, model=%s, language=%s, concurrency=%d", len(sources), modelID, language, concurrency)
+
+	imported, results := s.importBatch(sources, language, modelID, concurrency)
+	sessionIDs := make([]string, len(imported))
+	for i, item := range imported {
+		sessionIDs[i] = item.Session.ID
+	}
+
+	if len(imported) > 0 {
+		go s.transcribeImportBatch(imported, modelID, language, concurrency)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    len(imported) == len(sources),
+		"sessionIds": sessionIDs,
+		"files":      results,
+		"language":   language,
+		"switched":   switched,
+	})
+}
+
+// importBatch создаёт сессии для файлов (не более concurrency одновременно).
+// Порядок сессий и результатов совпадает с порядком файлов
+func (s *Server) importBatch(sources []importBatchSource, language, modelID string, concurrency int) ([]*importedAudio, []importBatchResult) {
+	results := make([]*importedAudio, len(sources))
+	errs := make([]error, len(sources))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src importBatchSource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			file, err := src.Open()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer file.Close()
+			results[i], errs[i] = s.importAudio(file, src.Name, language, modelID)
+		}(i, src)
+	}
+	wg.Wait()
+
+	var imported []*importedAudio
+	fileResults := make([]importBatchResult, len(sources))
+	for i, src := range sources {
+		fileResults[i].File = src.Name
+		if errs[i] != nil {
+			log.Printf("Import batch: %s failed: %v", src.Name, errs[i])
+			fileResults[i].Error = errs[i].Error()
+			continue
+		}
+		fileResults[i].SessionID = results[i].Session.ID
+		imported = append(imported, results[i])
+	}
+	return imported, fileResults
+}
+
+// transcribeImportBatch транскрибирует сессии пакета и сообщает общий прогресс.
+// Модель и язык переключаются один раз на весь пакет. Если за время конвертации
+// началась запись, транскрипция не запускается: переключение сменило бы модель записи
+func (s *Server) transcribeImportBatch(imported []*importedAudio, modelID, language string, concurrency int) {
+	sessionIDs := make([]string, len(imported))
+	for i, item := range imported {
+		sessionIDs[i] = item.Session.ID
+	}
+	if s.SessionMgr.IsActive() {
+		log.Printf("Import batch: %v, %d sessions left untranscribed", errImportRecordingActive, len(imported))
+		s.broadcast(Message{Type: "import_batch_completed", SessionIDs: sessionIDs, Error: errImportRecordingActive.Error()})
+		return
+	}
+
+	if s.EngineMgr != nil {
+		s.EngineMgr.SetLanguage(language)
+		if modelID != "" {
+			if err := s.EngineMgr.SetActiveModel(modelID); err != nil {
+				log.Printf("Import batch: failed to set model: %v", err)
+			}
+		}
+	}
+
+	total := len(imported)
+	s.broadcast(Message{
+		Type:       "import_batch_progress",
+		SessionIDs: sessionIDs,
+		Data:       fmt.Sprintf("Транскрипция: 0 из %d", total),
+	})
+
+	var mu sync.Mutex
+	done := 0
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, item := range imported {
+		wg.Add(1)
+		go func(item *importedAudio) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			s.transcribeImported(item, "", "")
+
+			mu.Lock()
+			done++
+			progress := Message{
+				Type:       "import_batch_progress",
+				SessionID:  item.Session.ID,
+				SessionIDs: sessionIDs,
+				Progress:   float64(done) / float64(total),
+				Data:       fmt.Sprintf("Транскрипция: %d из %d", done, total),
+			}
+			mu.Unlock()
+			s.broadcast(progress)
+		}(item)
+	}
+	wg.Wait()
+
+	log.Printf("Import batch: transcribed %d sessions", total)
+	s.broadcast(Message{Type: "import_batch_completed", SessionIDs: sessionIDs, Progress: 1.0})
+}
+
+// resolveImportFolder проверяет, что папка пакетного импорта находится внутри root
+// (с учётом символических ссылок), и возвращает её путь. Пустой root - импорт папок выключен
+func resolveImportFolder(root, folder string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("folder import is disabled: start the backend with -import-root")
+	}
+	if !filepath.IsAbs(folder) {
+		return "", fmt.Errorf("folder must be an absolute path: %q", folder)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("import root is not available: %w", err)
+	}
+	realFolder, err := filepath.EvalSymlinks(folder)
+	if err != nil {
+		return "", fmt.Errorf("failed to read folder: %w", err)
+	}
+	rel, err := filepath.Rel(realRoot, realFolder)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("folder %s is outside the import root", folder)
+	}
+	return realFolder, nil
+}
+
+// importSourcesFromDir собирает поддерживаемые аудио файлы папки (без вложенных) по имени
+func importSourcesFromDir(dir string) ([]importBatchSource, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []importBatchSource
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !importSupportedFormats[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		sources = append(sources, importBatchSource{
+			Name: name,
+			Open: func() (io.ReadCloser, error) { return os.Open(path) },
+		})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return sources, nil
+}
+
+// importBatchConcurrency разбирает параметр concurrency (по умолчанию 2, не больше 4)
+func importBatchConcurrency(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return defaultImportBatchConcurrency
+	}
+	return min(n, maxImportBatchConcurrency)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"strings"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+// LayerDiffChunk пословный diff диалога одного чанка
+type LayerDiffChunk struct {
+	ChunkIndex int         `json:"chunkIndex"`
+	Changed    bool        `json:"changed"`
+	Ops        []ai.DiffOp `json:"ops"`
+}
+
+// LayerDiff сравнение двух слоёв диалога сессии (diff_layers)
+type LayerDiff struct {
+	From     string           `json:"from"`
+	To       string           `json:"to"` // Пусто - показанный в чанках диалог
+	Chunks   []LayerDiffChunk `json:"chunks"`
+	Inserted int              `json:"inserted"` // Добавлено слов
+	Deleted  int              `json:"deleted"`  // Удалено слов
+	Replaced int              `json:"replaced"` // Участков с заменой слов
+}
+
+// diffDialogueLayers сравнивает два слоя диалога по чанкам: выравнивание внутри чанка
+// держит размер матрицы Needleman-Wunsch небольшим и для длинных сессий
+func (s *Server) diffDialogueLayers(sessionID, from, to string) (*LayerDiff, error) {
+	if from == "" {
+		from = session.DialogueLayerRaw
+	}
+	fromChunks, err := s.SessionMgr.GetDialogueLayerChunks(sessionID, from)
+	if err != nil {
+		return nil, err
+	}
+	toChunks, err := s.SessionMgr.GetDialogueLayerChunks(sessionID, to)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &LayerDiff{From: from, To: to, Chunks: make([]LayerDiffChunk, 0, len(fromChunks))}
+	for i := range fromChunks {
+		if i >= len(toChunks) || i >= len(sess.Chunks) {
+			break // Чанк добавлен между запросами слоёв
+		}
+		chunk := LayerDiffChunk{
+			ChunkIndex: sess.Chunks[i].Index,
+			Ops:        ai.DiffWords(dialogueWords(fromChunks[i]), dialogueWords(toChunks[i])),
+		}
+		for _, op := range chunk.Ops {
+			switch op.Op {
+			case ai.DiffInsert:
+				diff.Inserted += len(strings.Fields(op.To))
+			case ai.DiffDelete:
+				diff.Deleted += len(strings.Fields(op.From))
+			case ai.DiffReplace:
+				diff.Replaced++
+			}
+			chunk.Changed = chunk.Changed || op.Op != ai.DiffEqual
+		}
+		diff.Chunks = append(diff.Chunks, chunk)
+	}
+	return diff, nil
+}
+
+// dialogueWords разбивает диалог на слова. Берётся текст сегментов: слова с таймкодами
+// после правки LLM могут не совпадать с текстом. Время слова - начало его сегмента
+func dialogueWords(dialogue []session.TranscriptSegment) []ai.TranscriptWord {
+	var words []ai.TranscriptWord
+	for _, seg := range dialogue {
+		for _, text := range strings.Fields(seg.Text) {
+			words = append(words, ai.TranscriptWord{Start: seg.Start, End: seg.End, Text: text})
+		}
+	}
+	return words
+}
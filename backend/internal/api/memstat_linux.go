@@ -0,0 +1,12 @@
+package api
+
+import "syscall"
+
+// peakRSSBytes пиковый RSS процесса (включая нативную память sherpa-onnx, которую не видит Go runtime)
+func peakRSSBytes() uint64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return uint64(usage.Maxrss) * 1024 // Linux: килобайты
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package api
+
+// peakRSSBytes недоступен на этой платформе: лимит по памяти не применяется
+func peakRSSBytes() uint64 {
+	return 0
+}
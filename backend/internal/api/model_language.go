@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"log"
+
+	"aiwisper/models"
+)
+
+// Реакция на язык, который модель не поддерживает (Config.LanguageMismatch)
+const (
+	LanguageMismatchError  = "error"  // Отклонить запрос
+	LanguageMismatchSwitch = "switch" // Переключиться на язык модели
+)
+
+// resolveModelLanguage проверяет язык по списку языков модели (пустой modelID - активная модель).
+// В режиме switch неподдерживаемый язык заменяется основным языком модели, switched = true.
+// force пропускает проверку. Модели вне реестра не проверяются
+func (s *Server) resolveModelLanguage(modelID, language string, force bool) (resolved string, switched bool, err error) {
+	if modelID == "" && s.EngineMgr != nil {
+		modelID = s.EngineMgr.GetActiveModelID()
+	}
+	info := models.GetModelByID(modelID)
+	if force || info == nil {
+		return language, false, nil
+	}
+	err = info.CheckLanguage(language)
+	if err == nil {
+		return language, false, nil
+	}
+
+	if s.Config != nil && s.Config.LanguageMismatch == LanguageMismatchSwitch {
+		if fallback := info.DefaultLanguage(); fallback != "" {
+			log.Printf("Language %q is not supported by %s, switching to %q", language, modelID, fallback)
+			return fallback, true, nil
+		}
+	}
+	return "", false, fmt.Errorf("%w. Choose a supported language or set forceLanguage", err)
+}
+
+// languageSwitchedMessage уведомление о замене языка сессии на язык модели
+func languageSwitchedMessage(modelID, from, to string) Message {
+	return Message{
+		Type:     "language_switched",
+		ModelID:  modelID,
+		Language: to,
+		Data:     fmt.Sprintf("Язык %q не поддерживается моделью, используется %q", from, to),
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"aiwisper/models"
+)
+
+// ModelProgressResponse состояние загрузки одной модели (GET /api/models/{id}/progress)
+type ModelProgressResponse struct {
+	ID       string             `json:"id"`
+	Status   models.ModelStatus `json:"status"`
+	Progress float64            `json:"progress"` // 0-100, для скачанной модели - 100
+	Error    string             `json:"error,omitempty"`
+	Ready    bool               `json:"ready"` // Модель скачана и её можно использовать
+}
+
+// modelProgressResponse сводит состояние модели к полям, нужным для ожидания загрузки
+func modelProgressResponse(state models.ModelState) ModelProgressResponse {
+	resp := ModelProgressResponse{ID: state.ID, Status: state.Status, Progress: state.Progress, Error: state.Error}
+	if state.Status == models.ModelStatusDownloaded || state.Status == models.ModelStatusActive {
+		resp.Progress = 100
+		resp.Ready = true
+	}
+	return resp
+}
+
+// handleModelsAPI отдаёт состояние моделей для клиентов без WebSocket (скрипты, автоматизация)
+// URL: GET /api/models - все модели со статусом и прогрессом загрузки
+//
+//	GET /api/models/{id}/progress - состояние одной модели
+func (s *Server) handleModelsAPI(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.ModelMgr == nil {
+		http.Error(w, "Model manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/models"), "/")
+	states := s.ModelMgr.GetAllModelsState()
+	if path == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"models": states})
+		return
+	}
+
+	modelID, rest, _ := strings.Cut(path, "/")
+	if rest != "progress" {
+		http.Error(w, "Invalid path. Expected: /api/models or /api/models/{id}/progress", http.StatusBadRequest)
+		return
+	}
+	for _, state := range states {
+		if state.ID == modelID {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(modelProgressResponse(state))
+			return
+		}
+	}
+	http.Error(w, "Model not found", http.StatusNotFound)
+}
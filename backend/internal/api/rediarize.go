@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// startFullRediarization заново определяет спикеров во всех чанках сессии без распознавания речи
+// (rediarize_full): текст и тайминги слов сохраняются, диаризация SYS канала запускается заново.
+// Прогресс, отмена и завершение сообщаются теми же сообщениями, что и у retranscribe_full
+func (s *Server) startFullRediarization(send sendFunc, sessionID string) {
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		log.Printf("Full rediarization error: %v", err)
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: err.Error()})
+		return
+	}
+	if s.TranscriptionService == nil || !s.TranscriptionService.IsDiarizationEnabled() {
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: "diarization is not enabled"})
+		return
+	}
+
+	totalChunks := len(sess.Chunks)
+
+	// Тот же лимит, что и у полной ретранскрипции: утечка памяти sherpa-onnx при многократных вызовах
+	maxChunks, maxMemMB := s.diarizationLimits()
+	if limit := diarizationChunkLimit(totalChunks, maxChunks, maxMemMB); limit != nil {
+		log.Printf("Full rediarization rejected: %d chunks > %d max", totalChunks, maxChunks)
+		s.broadcast(Message{
+			Type:             "diarization_warning",
+			SessionID:        sessionID,
+			Data:             limit.message(),
+			DiarizationLimit: limit,
+		})
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: limit.message()})
+		return
+	}
+
+	releaseBusy, err := s.SessionMgr.TryMarkSessionBusy(sessionID, false)
+	if err != nil {
+		log.Printf("Full rediarization error: %v", err)
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: err.Error()})
+		return
+	}
+
+	s.TranscriptionService.ResetDiarizationState()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.retranscribeCancelsMu.Lock()
+	if prevCancel, exists := s.retranscribeCancels[sessionID]; exists {
+		prevCancel()
+	}
+	s.retranscribeCancels[sessionID] = cancel
+	s.retranscribeCancelsMu.Unlock()
+
+	log.Printf("Sending full_transcription_started for session %s (rediarization)", sessionID)
+	s.broadcast(Message{Type: "full_transcription_started", SessionID: sessionID})
+
+	go func() {
+		defer releaseBusy()
+		defer func() {
+			s.retranscribeCancelsMu.Lock()
+			delete(s.retranscribeCancels, sessionID)
+			s.retranscribeCancelsMu.Unlock()
+		}()
+
+		// Переименования применяются один раз в конце, а не в колбэке каждого чанка
+		cachedRenames := s.getExistingSpeakerRenames(sessionID)
+		s.fullRetranscribeActiveMu.Lock()
+		s.fullRetranscribeActive[sessionID] = true
+		s.fullRetranscribeActiveMu.Unlock()
+		finish := func() {
+			s.fullRetranscribeActiveMu.Lock()
+			delete(s.fullRetranscribeActive, sessionID)
+			s.fullRetranscribeActiveMu.Unlock()
+			s.applySpeakerRenames(sessionID, cachedRenames)
+			s.invalidateSessionSpeakersCache(sessionID)
+		}
+
+		// Сохранённые профили (embeddings) служат якорями: тот же голос получает прежний номер
+		s.TranscriptionService.AnchorSessionSpeakerProfiles(sessionID)
+		memGuard := newDiarizationMemGuard(maxMemMB, peakRSSBytes)
+
+		rediarized := 0
+		for i, chunk := range sess.Chunks {
+			select {
+			case <-ctx.Done():
+				log.Printf("Full rediarization cancelled for session %s at chunk %d/%d", sessionID, i+1, totalChunks)
+				finish()
+				partialSess, _ := s.SessionMgr.GetSession(sessionID)
+				s.broadcast(Message{
+					Type:      "full_transcription_cancelled",
+					SessionID: sessionID,
+					Session:   partialSess,
+					Progress:  float64(i) / float64(totalChunks),
+					Data:      fmt.Sprintf("Отменено на чанке %d из %d", i+1, totalChunks),
+				})
+				return
+			default:
+			}
+
+			if growthMB, exceeded := memGuard.check(); exceeded {
+				log.Printf("WARNING: Stopping rediarization at chunk %d/%d: peak RSS grew by %d MB (limit %d MB)",
+					i+1, totalChunks, growthMB, maxMemMB)
+				limit := &DiarizationLimit{
+					Reason:          DiarizationLimitMemory,
+					TotalChunks:     totalChunks,
+					MaxChunks:       maxChunks,
+					MaxMemGrowthMB:  maxMemMB,
+					MemGrowthMB:     growthMB,
+					DisabledAtChunk: i + 1,
+				}
+				s.broadcast(Message{
+					Type:             "diarization_warning",
+					SessionID:        sessionID,
+					Data:             limit.message(),
+					DiarizationLimit: limit,
+				})
+				break
+			}
+
+			s.broadcast(Message{
+				Type:      "full_transcription_progress",
+				SessionID: sessionID,
+				Progress:  float64(i) / float64(totalChunks),
+				Data:      fmt.Sprintf("Диаризация чанка %d из %d...", i+1, totalChunks),
+			})
+
+			applied, err := s.TranscriptionService.RediarizeChunk(chunk)
+			if err != nil {
+				log.Printf("Full rediarization: chunk %d failed: %v", chunk.Index, err)
+				continue
+			}
+			if applied {
+				rediarized++
+			}
+		}
+
+		s.broadcast(Message{
+			Type:      "full_transcription_progress",
+			SessionID: sessionID,
+			Progress:  1.0,
+			Data:      "Применение имён спикеров...",
+		})
+		finish()
+
+		updatedSess, _ := s.SessionMgr.GetSession(sessionID)
+		log.Printf("Full rediarization completed for session %s: %d/%d chunks", sessionID, rediarized, totalChunks)
+		s.broadcast(Message{Type: "full_transcription_completed", SessionID: sessionID, Session: updatedSess})
+	}()
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"aiwisper/session"
+)
+
+// broadcastRetentionReport рассылает итог очистки по политике хранения и обновлённый список сессий
+func (s *Server) broadcastRetentionReport(report *session.RetentionReport) {
+	for _, entry := range report.Deleted {
+		s.invalidateSessionSpeakersCache(entry.SessionID)
+	}
+	s.broadcast(Message{Type: "retention_cleanup", RetentionReport: report})
+
+	if report.Empty() {
+		return
+	}
+	sessions := s.SessionMgr.ListSessions()
+	infos := make([]*SessionInfo, len(sessions))
+	for i, sess := range sessions {
+		infos[i] = sessionToInfo(sess)
+	}
+	s.broadcast(Message{Type: "sessions_list", Sessions: infos})
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"aiwisper/session"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// Допустимые режимы и методы VAD для reprocess_session (пусто - оставить текущий)
+var (
+	reprocessVADModes = []session.VADMode{
+		"", session.VADModeAuto, session.VADModeCompression, session.VADModePerRegion, session.VADModeOff,
+	}
+	reprocessVADMethods = []session.VADMethod{
+		"", session.VADMethodAuto, session.VADMethodSilero, session.VADMethodEnergy,
+	}
+)
+
+// validateReprocessSettings проверяет все настройки reprocess_session вместе, до применения любой из них.
+// Возвращает ошибку со списком всех найденных проблем
+func (s *Server) validateReprocessSettings(msg Message) error {
+	if msg.SessionID == "" {
+		return fmt.Errorf("sessionId is required")
+	}
+	if _, err := s.SessionMgr.GetSession(msg.SessionID); err != nil {
+		return err
+	}
+
+	var problems []string
+	primaryModel := msg.Model
+	if primaryModel != "" {
+		if s.ModelMgr == nil || !s.ModelMgr.IsModelDownloaded(primaryModel) {
+			problems = append(problems, fmt.Sprintf("model %s is not downloaded", primaryModel))
+		}
+	} else if s.EngineMgr != nil {
+		primaryModel = s.EngineMgr.GetActiveModelID()
+	}
+
+	if msg.HybridEnabled {
+		switch hybridConfig := hybridConfigFromMessage(msg); {
+		case hybridConfig == nil:
+			problems = append(problems, "hybrid secondary model is required")
+		case hybridConfig.SecondaryModelID == primaryModel:
+			problems = append(problems, "hybrid secondary model must differ from the primary model")
+		case s.ModelMgr == nil || !s.ModelMgr.IsModelDownloaded(hybridConfig.SecondaryModelID):
+			problems = append(problems, fmt.Sprintf("hybrid secondary model %s is not downloaded", hybridConfig.SecondaryModelID))
+		default:
+			if err := hybridConfig.Validate(); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if !slices.Contains(reprocessVADModes, session.VADMode(msg.VADMode)) {
+		problems = append(problems, fmt.Sprintf("unknown VAD mode %q", msg.VADMode))
+	}
+	for _, method := range []string{msg.VADMethod, msg.MicVADMethod, msg.SysVADMethod} {
+		if !slices.Contains(reprocessVADMethods, session.VADMethod(method)) {
+			problems = append(problems, fmt.Sprintf("unknown VAD method %q", method))
+		}
+	}
+
+	if s.TranscriptionService != nil {
+		cfg := s.TranscriptionService.RegionMerge.WithOverrides(msg.RegionMergeMinMs, msg.RegionMergeMaxGapMs)
+		if err := cfg.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if msg.DiarizationEnabled && (s.TranscriptionService == nil || !s.TranscriptionService.IsDiarizationEnabled()) {
+		problems = append(problems, "diarization is requested but not enabled")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid reprocess settings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// applyReprocessSettings применяет проверенные настройки reprocess_session.
+// Пустые язык, модель и режимы VAD оставляют текущие значения
+func (s *Server) applyReprocessSettings(msg Message) error {
+	if s.EngineMgr != nil {
+		if msg.Language != "" {
+			s.EngineMgr.SetLanguage(msg.Language)
+		}
+		if msg.Model != "" {
+			if err := s.EngineMgr.SetActiveModel(msg.Model); err != nil {
+				return fmt.Errorf("failed to set model %s: %w", msg.Model, err)
+			}
+			// Обновляем transcriber в Pipeline если диаризация включена
+			s.updatePipelineTranscriber()
+		}
+	}
+
+	if s.TranscriptionService == nil {
+		return nil
+	}
+	if msg.VADMode != "" {
+		s.TranscriptionService.SetVADMode(session.VADMode(msg.VADMode))
+	}
+	if msg.VADMethod != "" {
+		s.TranscriptionService.SetVADMethod(session.VADMethod(msg.VADMethod))
+	}
+	s.TranscriptionService.SetChannelVADConfig(channelVADConfigsFromMessage(msg))
+	if err := s.applyRegionMergeSettings(msg); err != nil {
+		return err
+	}
+	s.TranscriptionService.SetHybridConfig(hybridConfigFromMessage(msg))
+	return nil
+}
+
+// startFullRetranscription запускает полную ретранскрипцию сессии с текущими настройками
+// (общая часть retranscribe_full и reprocess_session)
+func (s *Server) startFullRetranscription(send sendFunc, sessionID string, diarizationRequested bool) {
+	// Проверяем сессию заранее для определения количества чанков
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		log.Printf("Full retranscription error: %v", err)
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: err.Error()})
+		return
+	}
+
+	totalChunks := len(sess.Chunks)
+
+	// Определяем использование диаризации
+	// ВАЖНО: sherpa-onnx имеет известную утечку памяти при многократных вызовах
+	// (https://github.com/k2-fsa/sherpa-onnx/issues/974, #1939)
+	// Ограничиваем диаризацию короткими сессиями (-retranscribe-diarization-max-chunks)
+	// и ростом памяти во время ретранскрипции (-retranscribe-diarization-max-mem-mb)
+	maxChunks, maxMemMB := s.diarizationLimits()
+	useDiarization := diarizationRequested && s.TranscriptionService.IsDiarizationEnabled()
+
+	if limit := diarizationChunkLimit(totalChunks, maxChunks, maxMemMB); useDiarization && limit != nil {
+		log.Printf("WARNING: Disabling diarization for batch retranscription (%d chunks > %d max) due to sherpa-onnx memory leak",
+			totalChunks, maxChunks)
+		useDiarization = false
+		// Уведомляем пользователя
+		s.broadcast(Message{
+			Type:             "diarization_warning",
+			SessionID:        sessionID,
+			Data:             limit.message(),
+			DiarizationLimit: limit,
+		})
+	}
+
+	// Сессия занята до конца ретранскрипции: политика хранения её не очистит.
+	// Пока сессия переносится, сливается или разделяется, ретранскрипция не запускается
+	releaseBusy, err := s.SessionMgr.TryMarkSessionBusy(sessionID, false)
+	if err != nil {
+		log.Printf("Full retranscription error: %v", err)
+		send(Message{Type: "full_transcription_error", SessionID: sessionID, Error: err.Error()})
+		return
+	}
+
+	// Сбрасываем состояние диаризации (спикеров) перед полной ретранскрипцией
+	if useDiarization {
+		s.TranscriptionService.ResetDiarizationState()
+	}
+
+	// Создаём context для отмены
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Сохраняем cancel функцию
+	s.retranscribeCancelsMu.Lock()
+	// Отменяем предыдущую ретранскрипцию если была
+	if prevCancel, exists := s.retranscribeCancels[sessionID]; exists {
+		prevCancel()
+	}
+	s.retranscribeCancels[sessionID] = cancel
+	s.retranscribeCancelsMu.Unlock()
+
+	// Отправляем через broadcast для всех клиентов
+	log.Printf("Sending full_transcription_started for session %s (diarization=%v)", sessionID, useDiarization)
+	s.broadcast(Message{Type: "full_transcription_started", SessionID: sessionID})
+
+	go func() {
+		defer releaseBusy()
+		defer func() {
+			// Удаляем cancel функцию после завершения
+			s.retranscribeCancelsMu.Lock()
+			delete(s.retranscribeCancels, sessionID)
+			s.retranscribeCancelsMu.Unlock()
+		}()
+
+		// Кэшируем существующие переименования спикеров ПЕРЕД очисткой профилей
+		// чтобы применить их в конце ретранскрипции
+		cachedRenames := s.getExistingSpeakerRenames(sessionID)
+		if len(cachedRenames) > 0 {
+			s.speakerRenamesCacheMu.Lock()
+			s.speakerRenamesCache[sessionID] = cachedRenames
+			s.speakerRenamesCacheMu.Unlock()
+			log.Printf("Full retranscription: cached %d speaker renames for session %s", len(cachedRenames), sessionID[:8])
+		}
+
+		// Устанавливаем флаг активной полной ретранскрипции
+		s.fullRetranscribeActiveMu.Lock()
+		s.fullRetranscribeActive[sessionID] = true
+		s.fullRetranscribeActiveMu.Unlock()
+
+		// Сохранённые профили спикеров служат якорями нумерации:
+		// тот же голос получит тот же номер "Собеседник N", что и до ретранскрипции
+		s.TranscriptionService.AnchorSessionSpeakerProfiles(sessionID)
+
+		if totalChunks == 0 {
+			log.Printf("Full retranscription: no chunks to process")
+			s.fullRetranscribeActiveMu.Lock()
+			delete(s.fullRetranscribeActive, sessionID)
+			s.fullRetranscribeActiveMu.Unlock()
+			s.broadcast(Message{Type: "full_transcription_completed", SessionID: sessionID, Session: sess})
+			return
+		}
+
+		log.Printf("Full retranscription: processing %d chunks (diarization=%v)", totalChunks, useDiarization)
+
+		var memGuard *diarizationMemGuard
+		if useDiarization {
+			memGuard = newDiarizationMemGuard(maxMemMB, peakRSSBytes)
+		}
+
+		for i, chunk := range sess.Chunks {
+			// Проверяем отмену перед каждым чанком
+			select {
+			case <-ctx.Done():
+				log.Printf("Full retranscription cancelled for session %s at chunk %d/%d", sessionID, i+1, totalChunks)
+				// Очищаем флаг и кэш при отмене
+				s.fullRetranscribeActiveMu.Lock()
+				delete(s.fullRetranscribeActive, sessionID)
+				s.fullRetranscribeActiveMu.Unlock()
+				s.speakerRenamesCacheMu.Lock()
+				delete(s.speakerRenamesCache, sessionID)
+				s.speakerRenamesCacheMu.Unlock()
+
+				// Уже обработанные чанки остаются: сохраняем профили спикеров и переименования,
+				// чтобы клиент получил согласованное частичное состояние сессии
+				if err := s.TranscriptionService.SaveSessionSpeakerProfiles(sessionID); err != nil {
+					log.Printf("Full retranscription: failed to save partial speaker profiles: %v", err)
+				}
+				s.applySpeakerRenames(sessionID, cachedRenames)
+
+				partialSess, _ := s.SessionMgr.GetSession(sessionID)
+				s.broadcast(Message{
+					Type:      "full_transcription_cancelled",
+					SessionID: sessionID,
+					Session:   partialSess,
+					Progress:  float64(i) / float64(totalChunks),
+					Data:      fmt.Sprintf("Отменено на чанке %d из %d", i+1, totalChunks),
+				})
+				return
+			default:
+			}
+
+			// Отправляем прогресс
+			progress := float64(i) / float64(totalChunks)
+			log.Printf("Full retranscription progress: %d/%d (%.1f%%)", i+1, totalChunks, progress*100)
+			s.broadcast(Message{
+				Type:      "full_transcription_progress",
+				SessionID: sessionID,
+				Progress:  progress,
+				Data:      fmt.Sprintf("Обработка чанка %d из %d...", i+1, totalChunks),
+			})
+
+			if growthMB, exceeded := memGuard.check(); useDiarization && exceeded {
+				log.Printf("WARNING: Disabling diarization from chunk %d/%d: peak RSS grew by %d MB (limit %d MB)",
+					i+1, totalChunks, growthMB, maxMemMB)
+				useDiarization = false
+				limit := &DiarizationLimit{
+					Reason:          DiarizationLimitMemory,
+					TotalChunks:     totalChunks,
+					MaxChunks:       maxChunks,
+					MaxMemGrowthMB:  maxMemMB,
+					MemGrowthMB:     growthMB,
+					DisabledAtChunk: i + 1,
+				}
+				s.broadcast(Message{
+					Type:             "diarization_warning",
+					SessionID:        sessionID,
+					Data:             limit.message(),
+					DiarizationLimit: limit,
+				})
+			}
+
+			log.Printf("Retranscribing chunk %d/%d (id=%s, diarization=%v)", i+1, totalChunks, chunk.ID, useDiarization)
+			// Используем синхронный метод с явным флагом диаризации
+			s.TranscriptionService.HandleChunkSyncWithDiarization(chunk, useDiarization)
+		}
+
+		// Финальный прогресс 100%
+		s.broadcast(Message{
+			Type:      "full_transcription_progress",
+			SessionID: sessionID,
+			Progress:  1.0,
+			Data:      "Применение имён спикеров...",
+		})
+
+		// Снимаем флаг активной ретранскрипции
+		s.fullRetranscribeActiveMu.Lock()
+		delete(s.fullRetranscribeActive, sessionID)
+		s.fullRetranscribeActiveMu.Unlock()
+
+		// Применяем кэшированные переименования спикеров
+		s.speakerRenamesCacheMu.RLock()
+		finalRenames := s.speakerRenamesCache[sessionID]
+		s.speakerRenamesCacheMu.RUnlock()
+
+		if len(finalRenames) > 0 {
+			s.applySpeakerRenames(sessionID, finalRenames)
+			// Очищаем кэш переименований
+			s.speakerRenamesCacheMu.Lock()
+			delete(s.speakerRenamesCache, sessionID)
+			s.speakerRenamesCacheMu.Unlock()
+		}
+
+		updatedSess, _ := s.SessionMgr.GetSession(sessionID)
+		log.Printf("Full retranscription completed for session %s", sessionID)
+		s.broadcast(Message{Type: "full_transcription_completed", SessionID: sessionID, Session: updatedSess})
+	}()
+}
@@ -8,23 +8,29 @@ import (
 	"aiwisper/models"
 	"aiwisper/session"
 	"aiwisper/voiceprint"
-	"archive/zip"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var upgrader = websocket.Upgrader{
@@ -46,6 +52,8 @@ type wsClient struct {
 func (c *wsClient) Send(msg Message) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	// Дедлайн записи: зависшее соединение не должно блокировать writer навсегда
+	_ = c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
 	return c.conn.WriteJSON(msg)
 }
 
@@ -54,23 +62,112 @@ func (c *wsClient) Close() error {
 }
 
 type grpcClient struct {
-	stream Control_StreamServer
-	mu     sync.Mutex
+	stream  Control_StreamServer
+	cancel  context.CancelFunc // Завершает обработчик Stream, gRPC при этом закрывает поток
+	timeout time.Duration      // Таймаут отправки одного сообщения
+	mu      sync.Mutex
+	broken  bool // Отправка зависла: поток больше не используем
 }
 
 func (c *grpcClient) Send(msg Message) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.stream.Send(&msg)
+	if c.broken {
+		return errClientClosed
+	}
+
+	// У gRPC Send нет дедлайна: при заполненном окне flow control он ждёт клиента сколько угодно
+	done := make(chan error, 1)
+	go func() { done <- c.stream.Send(&msg) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.timeout):
+		// Зависший Send вернётся с ошибкой, когда поток закроется
+		c.broken = true
+		c.cancel()
+		return fmt.Errorf("grpc send timeout after %v", c.timeout)
+	}
 }
 
 func (c *grpcClient) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	// gRPC поток закрывается на стороне клиента или через контекст
+	c.cancel()
 	return nil
 }
 
+const (
+	// clientSendQueueSize размер очереди исходящих сообщений на клиента
+	clientSendQueueSize = 256
+	// clientWriteTimeout таймаут записи одного сообщения в WebSocket
+	clientWriteTimeout = 10 * time.Second
+)
+
+var errClientClosed = fmt.Errorf("client closed")
+
+// queuedClient оборачивает transportClient очередью исходящих сообщений и отдельной
+// writer-горутиной. Send не блокируется: если клиент не успевает забирать сообщения
+// (например, фоновая вкладка браузера) и очередь переполнена, клиент закрывается,
+// чтобы не тормозить доставку уведомлений остальным клиентам.
+type queuedClient struct {
+	transportClient
+	out       chan Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newQueuedClient(c transportClient) *queuedClient {
+	qc := &queuedClient{
+		transportClient: c,
+		out:             make(chan Message, clientSendQueueSize),
+		done:            make(chan struct{}),
+	}
+	go qc.writeLoop()
+	return qc
+}
+
+func (c *queuedClient) writeLoop() {
+	for {
+		select {
+		case msg := <-c.out:
+			if err := c.transportClient.Send(msg); err != nil {
+				log.Printf("Client write error: %v, closing client", err)
+				_ = c.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *queuedClient) Send(msg Message) error {
+	select {
+	case <-c.done:
+		return errClientClosed
+	default:
+	}
+
+	select {
+	case c.out <- msg:
+		return nil
+	case <-c.done:
+		return errClientClosed
+	default:
+		log.Printf("Client send queue is full (%d messages), dropping slow client", clientSendQueueSize)
+		_ = c.Close()
+		return fmt.Errorf("client send queue is full")
+	}
+}
+
+func (c *queuedClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.transportClient.Close()
+	})
+	return err
+}
+
 type Server struct {
 	Config                        *config.Config
 	SessionMgr                    *session.Manager
@@ -103,6 +200,14 @@ type Server struct {
 	// Кэш спикеров сессии для оптимизации производительности
 	sessionSpeakersCache   map[string]sessionSpeakersCacheEntry
 	sessionSpeakersCacheMu sync.RWMutex
+
+	// Кэш нормализованных по громкости сэмплов спикеров (session/localID)
+	speakerSampleCache   map[string]speakerSampleCacheEntry
+	speakerSampleCacheMu sync.Mutex
+
+	// Декодированные WAV сессий для /audio.wav (sessionID)
+	wavCache   map[string]*wavCacheEntry
+	wavCacheMu sync.Mutex
 }
 
 // sessionSpeakersCacheEntry хранит кэшированные данные о спикерах
@@ -154,10 +259,16 @@ func (s *Server) Start() {
 	http.HandleFunc("/api/sessions/", s.handleSessionsAPI)
 	http.HandleFunc("/api/waveform/", s.handleWaveformAPI)
 	http.HandleFunc("/api/import", s.handleImportAudio)
+	http.HandleFunc("/api/import-batch", s.handleImportBatch)
 	http.HandleFunc("/api/export/batch", s.handleBatchExport)
+	http.HandleFunc("/api/export/", s.handleSingleExport)
 	http.HandleFunc("/api/speaker-sample/", s.handleSpeakerSampleAPI)
+	http.HandleFunc("/api/diarization-eval/", s.handleDiarizationEvalAPI)
+	http.HandleFunc("/api/speaker-embeddings/", s.handleSpeakerEmbeddingsAPI)
 	http.HandleFunc("/api/voiceprints/", s.handleVoiceprintsAPI)
 	http.HandleFunc("/api/voiceprints", s.handleVoiceprintsAPI)
+	http.HandleFunc("/api/models", s.handleModelsAPI)
+	http.HandleFunc("/api/models/", s.handleModelsAPI)
 
 	log.Printf("Backend listening on HTTP :%s and gRPC %s", s.Config.Port, s.Config.GRPCAddr)
 	if err := http.ListenAndServe(":"+s.Config.Port, nil); err != nil {
@@ -210,6 +321,11 @@ func (s *Server) setupCallbacks() {
 				StreamingIsConfirmed: update.IsConfirmed,
 				StreamingConfidence:  update.Confidence,
 				StreamingTimestamp:   update.Timestamp.UnixMilli(),
+
+				StreamingCorrection:     update.Correction,
+				StreamingRevisedFromMs:  update.RevisedFromMs,
+				StreamingConfirmedEndMs: update.ConfirmedEndMs,
+				StreamingTentativeText:  update.TentativeText,
 			})
 		}
 	}
@@ -229,6 +345,43 @@ func (s *Server) setupCallbacks() {
 		}
 	})
 
+	// Transcription backlog -> Warn
+	if s.TranscriptionService != nil {
+		s.TranscriptionService.OnBacklog = func(status service.QueueStatus, cleared bool) {
+			msgType := "transcription_backlog"
+			if cleared {
+				msgType = "transcription_backlog_cleared"
+			}
+			s.broadcast(Message{Type: msgType, QueueStatus: &status})
+		}
+
+		// Прогресс внутри длинного чанка
+		s.TranscriptionService.OnChunkProgress = func(progress service.ChunkProgress) {
+			s.broadcast(Message{Type: "chunk_progress", SessionID: progress.SessionID, ChunkProgress: &progress})
+		}
+
+		// Автоулучшение всей сессии после остановки записи (те же события, что у improve_transcription)
+		s.TranscriptionService.OnSessionImproveStarted = func(sessionID string) {
+			s.broadcast(Message{Type: "improve_started", SessionID: sessionID})
+		}
+		s.TranscriptionService.OnSessionImproved = func(sessionID string, err error) {
+			if err != nil {
+				s.broadcast(Message{Type: "improve_error", SessionID: sessionID, Error: err.Error()})
+				return
+			}
+			updatedSess, _ := s.SessionMgr.GetSession(sessionID)
+			s.broadcast(Message{Type: "improve_completed", SessionID: sessionID, Session: updatedSess, DialogueLayer: session.DialogueLayerImproved})
+		}
+
+		// Перегруженный (клиппированный) вход
+		s.TranscriptionService.OnAudioQualityWarning = func(warning service.AudioQualityWarning) {
+			s.broadcast(Message{Type: "audio_quality_warning", SessionID: warning.SessionID, AudioQualityWarning: &warning})
+		}
+	}
+
+	// Фоновая очистка по политике хранения -> итог всем клиентам
+	s.SessionMgr.SetOnRetentionCleanup(s.broadcastRetentionReport)
+
 	// Chunk Transcribed -> Notify
 	s.SessionMgr.SetOnChunkTranscribed(func(chunk *session.Chunk) {
 		// Проверяем, идёт ли полная ретранскрипция
@@ -253,9 +406,10 @@ func (s *Server) setupCallbacks() {
 		}
 
 		s.broadcast(Message{
-			Type:      "chunk_transcribed",
-			SessionID: chunk.SessionID,
-			Chunk:     chunk,
+			Type:          "chunk_transcribed",
+			SessionID:     chunk.SessionID,
+			Chunk:         chunk,
+			VADMethodUsed: chunkVADMethodUsed(chunk),
 		})
 	})
 }
@@ -311,6 +465,7 @@ func sessionToInfo(sess *session.Session) *SessionInfo {
 		TotalDuration: int64(duration / time.Millisecond),
 		ChunksCount:   len(sess.Chunks),
 		Title:         sess.Title,
+		Pinned:        sess.Pinned,
 	}
 }
 
@@ -321,7 +476,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := &wsClient{conn: conn}
+	client := newQueuedClient(&wsClient{conn: conn})
 	s.addClient(client)
 
 	defer func() {
@@ -340,24 +495,45 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 }
 
 // Stream реализует gRPC bidirectional поток, повторяя поведение WebSocket.
+// Сервер закрывает поток (медленный клиент, зависшая отправка), завершая обработчик через ctx
 func (s *Server) Stream(stream Control_StreamServer) error {
-	client := &grpcClient{stream: stream}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	client := newQueuedClient(&grpcClient{stream: stream, cancel: cancel, timeout: clientWriteTimeout})
 	s.addClient(client)
 	defer s.removeClient(client)
 
-	for {
-		msg, err := stream.Recv()
-		if err != nil {
-			if err == io.EOF {
-				return nil
+	// Recv не прерывается отменой ctx, поэтому читаем в отдельной горутине:
+	// после выхода обработчика gRPC закрывает поток, и Recv вернёт ошибку
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
 			}
-			log.Printf("gRPC recv error: %v", err)
-			return err
+			if msg == nil {
+				continue
+			}
+			s.processMessage(client.Send, *msg)
 		}
-		if msg == nil {
-			continue
+	}()
+
+	select {
+	case err := <-recvErr:
+		if err == io.EOF {
+			return nil
 		}
-		s.processMessage(client.Send, *msg)
+		log.Printf("gRPC recv error: %v", err)
+		return err
+	case <-ctx.Done():
+		if stream.Context().Err() != nil {
+			return stream.Context().Err()
+		}
+		log.Printf("gRPC client closed by server")
+		return status.Error(codes.Unavailable, "client closed by server")
 	}
 }
 
@@ -369,17 +545,26 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			send(Message{Type: "error", Data: err.Error()})
 			return
 		}
+		sckAvailable := audio.ScreenCaptureKitAvailable()
+		var apps []audio.CapturableApp
+		if sckAvailable {
+			if apps, err = audio.ListCapturableApps(); err != nil {
+				log.Printf("Failed to list capturable apps: %v", err)
+			}
+		}
 		send(Message{
 			Type:                      "devices",
 			Devices:                   devices,
-			ScreenCaptureKitAvailable: audio.ScreenCaptureKitAvailable(),
+			ScreenCaptureKitAvailable: sckAvailable,
+			CapturableApps:            apps,
 		})
 
 	case "get_models":
 		modelStates := s.ModelMgr.GetAllModelsState()
 		send(Message{
-			Type:   "models_list",
-			Models: modelStates,
+			Type:         "models_list",
+			Models:       modelStates,
+			Capabilities: s.engineCapabilities(),
 		})
 
 	case "download_model":
@@ -408,7 +593,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 		s.ModelMgr.DeleteModel(msg.ModelID)
 		send(Message{Type: "model_deleted", ModelID: msg.ModelID})
-		send(Message{Type: "models_list", Models: s.ModelMgr.GetAllModelsState()})
+		send(Message{Type: "models_list", Models: s.ModelMgr.GetAllModelsState(), Capabilities: s.engineCapabilities()})
 
 	case "set_active_model":
 		if msg.ModelID == "" {
@@ -427,8 +612,8 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			// Обновляем transcriber в Pipeline если диаризация включена
 			s.updatePipelineTranscriber()
 		}
-		send(Message{Type: "active_model_changed", ModelID: msg.ModelID})
-		send(Message{Type: "models_list", Models: s.ModelMgr.GetAllModelsState()})
+		send(Message{Type: "active_model_changed", ModelID: msg.ModelID, Capabilities: s.engineCapabilities()})
+		send(Message{Type: "models_list", Models: s.ModelMgr.GetAllModelsState(), Capabilities: s.engineCapabilities()})
 
 	case "get_sessions":
 		sessions := s.SessionMgr.ListSessions()
@@ -451,6 +636,137 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		s.invalidateSessionSpeakersCache(msg.SessionID)
 		send(Message{Type: "session_deleted", SessionID: msg.SessionID})
 
+	case "set_session_pinned":
+		// Закреплённые сессии не очищаются политикой хранения
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		if err := s.SessionMgr.SetSessionPinned(msg.SessionID, msg.Pinned); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		send(Message{Type: "session_pinned", SessionID: msg.SessionID, Pinned: msg.Pinned})
+		if updatedSess, err := s.SessionMgr.GetSession(msg.SessionID); err == nil {
+			s.broadcast(Message{Type: "session_details", Session: updatedSess})
+		}
+
+	case "get_retention_policy":
+		policy := s.SessionMgr.GetRetentionPolicy()
+		send(Message{Type: "retention_policy", RetentionPolicy: &policy})
+
+	case "set_retention_policy":
+		if msg.RetentionPolicy == nil {
+			send(Message{Type: "error", Data: "retentionPolicy is required"})
+			return
+		}
+		if err := s.SessionMgr.SetRetentionPolicy(*msg.RetentionPolicy); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		log.Printf("set_retention_policy: mode=%s, maxAgeDays=%d", msg.RetentionPolicy.Mode, msg.RetentionPolicy.MaxAgeDays)
+		send(Message{Type: "retention_policy", RetentionPolicy: msg.RetentionPolicy})
+
+		// Новая политика применяется сразу, не дожидаясь фоновой очистки
+		go s.broadcastRetentionReport(s.SessionMgr.ApplyRetention(time.Now()))
+
+	case "move_session":
+		// Data - директория назначения (например, на внешнем диске)
+		if msg.SessionID == "" || msg.Data == "" {
+			send(Message{Type: "error", Data: "sessionId and target directory (data) are required"})
+			return
+		}
+		s.fullRetranscribeActiveMu.RLock()
+		retranscribing := s.fullRetranscribeActive[msg.SessionID]
+		s.fullRetranscribeActiveMu.RUnlock()
+		if retranscribing {
+			send(Message{Type: "error", Data: "cannot move session during full retranscription"})
+			return
+		}
+		go func() {
+			sess, err := s.SessionMgr.MoveSession(msg.SessionID, msg.Data)
+			if err != nil {
+				log.Printf("move_session failed for %s: %v", msg.SessionID, err)
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+			s.invalidateSessionSpeakersCache(msg.SessionID)
+			send(Message{Type: "session_moved", SessionID: msg.SessionID, Session: sess, Data: sess.DataDir})
+		}()
+
+	case "merge_sessions":
+		// SessionID - целевая сессия, SessionIDs - присоединяемые к ней по порядку
+		if msg.SessionID == "" || len(msg.SessionIDs) == 0 {
+			send(Message{Type: "error", Data: "sessionId and sessionIds are required"})
+			return
+		}
+		s.fullRetranscribeActiveMu.RLock()
+		retranscribing := s.fullRetranscribeActive[msg.SessionID]
+		for _, id := range msg.SessionIDs {
+			retranscribing = retranscribing || s.fullRetranscribeActive[id]
+		}
+		s.fullRetranscribeActiveMu.RUnlock()
+		if retranscribing {
+			send(Message{Type: "error", Data: "cannot merge sessions during full retranscription"})
+			return
+		}
+		go func() {
+			sess, err := s.SessionMgr.MergeSessions(msg.SessionID, msg.SessionIDs)
+			if err != nil {
+				log.Printf("merge_sessions failed for %s: %v", msg.SessionID, err)
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+			// Профили спикеров цели перечитываются с диска уже с собеседниками присоединённых сессий
+			for _, id := range append([]string{msg.SessionID}, msg.SessionIDs...) {
+				if s.TranscriptionService != nil {
+					s.TranscriptionService.ClearSessionSpeakerProfiles(id)
+				}
+				s.invalidateSessionSpeakersCache(id)
+			}
+			send(Message{Type: "sessions_merged", SessionID: msg.SessionID, Session: sess, SessionIDs: msg.SessionIDs})
+
+			sessions := s.SessionMgr.ListSessions()
+			infos := make([]*SessionInfo, len(sessions))
+			for i, listed := range sessions {
+				infos[i] = sessionToInfo(listed)
+			}
+			s.broadcast(Message{Type: "sessions_list", Sessions: infos})
+		}()
+
+	case "split_session":
+		if msg.SessionID == "" || msg.SplitMs <= 0 {
+			send(Message{Type: "error", Data: "sessionId and splitMs are required"})
+			return
+		}
+		s.fullRetranscribeActiveMu.RLock()
+		retranscribing := s.fullRetranscribeActive[msg.SessionID]
+		s.fullRetranscribeActiveMu.RUnlock()
+		if retranscribing {
+			send(Message{Type: "error", Data: "cannot split session during full retranscription"})
+			return
+		}
+		go func() {
+			first, second, err := s.SessionMgr.SplitSession(msg.SessionID, msg.SplitMs)
+			if err != nil {
+				log.Printf("split_session failed for %s: %v", msg.SessionID, err)
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+			if s.TranscriptionService != nil {
+				s.TranscriptionService.ClearSessionSpeakerProfiles(msg.SessionID)
+			}
+			s.invalidateSessionSpeakersCache(msg.SessionID)
+			send(Message{Type: "session_split", SessionID: msg.SessionID, SessionIDs: []string{first.ID, second.ID}})
+
+			sessions := s.SessionMgr.ListSessions()
+			infos := make([]*SessionInfo, len(sessions))
+			for i, listed := range sessions {
+				infos[i] = sessionToInfo(listed)
+			}
+			s.broadcast(Message{Type: "sessions_list", Sessions: infos})
+		}()
+
 	case "rename_session":
 		if msg.SessionID == "" {
 			send(Message{Type: "error", Data: "sessionId is required"})
@@ -497,6 +813,39 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		send(Message{Type: "search_results", SearchResults: searchResults, TotalCount: total})
 
 	case "start_session":
+		// Все параметры проверяются до загрузки модели и сброса состояния транскрипции:
+		// отклонённый запрос не должен менять настройки текущей записи
+		if err := session.ValidateChunkDurationMs(msg.ChunkDurationMs); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		if _, err := audio.ParseMixingStrategy(msg.VoiceMixing); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		hybridConfig := hybridConfigFromMessage(msg)
+		if hybridConfig != nil {
+			if err := hybridConfig.Validate(); err != nil {
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+		}
+		if s.TranscriptionService != nil {
+			if err := s.TranscriptionService.RegionMerge.WithOverrides(msg.RegionMergeMinMs, msg.RegionMergeMaxGapMs).Validate(); err != nil {
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+		}
+		// Язык проверяется до загрузки модели, чтобы не загружать её зря
+		if language, switched, err := s.resolveModelLanguage(msg.Model, msg.Language, msg.ForceLanguage); err != nil {
+			log.Printf("start_session: %v", err)
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		} else if switched {
+			send(languageSwitchedMessage(msg.Model, msg.Language, language))
+			msg.Language = language
+		}
+
 		// Configure Engine Model first, then Language
 		if s.EngineMgr != nil {
 			if msg.Model != "" {
@@ -524,8 +873,15 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 				if err := s.EngineMgr.SetActiveModel(msg.Model); err != nil {
 					log.Printf("start_session: failed to set active model %s: %v", msg.Model, err)
 					send(Message{Type: "model_load_error", ModelID: msg.Model, ModelName: modelName, Error: err.Error()})
-					send(Message{Type: "error", Data: fmt.Sprintf("Failed to load model %s: %v", msg.Model, err)})
-					return
+
+					fallbackID, ok := s.activateFallbackModel(send, msg.Model, msg.Language, msg.FallbackModels, err)
+					if !ok {
+						send(Message{Type: "error", Data: fmt.Sprintf("Failed to load model %s: %v", msg.Model, err)})
+						return
+					}
+					// Сессия записывается с фактически использованной моделью
+					msg.Model = fallbackID
+					needsLoading = false
 				}
 
 				if needsLoading {
@@ -538,7 +894,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 				s.updatePipelineTranscriber()
 			} else {
 				// Если модель не указана, проверяем есть ли активный движок
-				if s.EngineMgr.GetActiveEngine() == nil {
+				if !s.EngineMgr.HasActiveEngine() {
 					log.Printf("start_session: no model specified and no active engine")
 					send(Message{Type: "error", Data: "No model selected. Please select a model in settings."})
 					return
@@ -557,15 +913,27 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		config := session.SessionConfig{
-			Language:      msg.Language,
-			Model:         msg.Model,
-			MicDevice:     msg.MicDevice,
-			SystemDevice:  msg.SystemDevice,
-			CaptureSystem: msg.CaptureSystem,
-			UseNative:     msg.UseNative,
-			VADMode:       session.VADMode(msg.VADMode),
-			VADMethod:     session.VADMethod(msg.VADMethod),
-		}
+			Language:         msg.Language,
+			Model:            msg.Model,
+			MicDevice:        msg.MicDevice,
+			SystemDevice:     msg.SystemDevice,
+			CaptureSystem:    msg.CaptureSystem,
+			UseNative:        msg.UseNative,
+			VADMode:          session.VADMode(msg.VADMode),
+			VADMethod:        session.VADMethod(msg.VADMethod),
+			DiarizeMic:       msg.DiarizeMic,
+			MicOnly:          msg.MicOnly,
+			CaptureApp:       msg.CaptureApp,
+			SwapChannels:     session.ChannelSwapMode(msg.SwapChannels),
+			RecordRawCapture: msg.RecordRawCapture,
+			VoiceMixing:      msg.VoiceMixing,
+			ChunkDurationMs:  msg.ChunkDurationMs,
+		}
+		if warning := chunkDurationWarning(msg.Model, msg.ChunkDurationMs); warning != "" {
+			log.Printf("start_session: %s", warning)
+			send(Message{Type: "chunk_duration_warning", ModelID: msg.Model, Data: warning})
+		}
+		config.MicVAD, config.SysVAD = channelVADConfigsFromMessage(msg)
 
 		// Echo Cancel default 0.4
 		ec := float32(0.4)
@@ -573,52 +941,27 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			ec = float32(msg.EchoCancel)
 		}
 
+		if err := s.applyRegionMergeSettings(msg); err != nil {
+			log.Printf("start_session: %v", err)
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+
 		// Сбрасываем состояние диаризации (спикеров) перед новой сессией
 		if s.TranscriptionService != nil {
 			s.TranscriptionService.ResetDiarizationState()
 			// Устанавливаем режим VAD и метод детекции
 			s.TranscriptionService.SetVADMode(config.VADMode)
 			s.TranscriptionService.SetVADMethod(config.VADMethod)
+			s.TranscriptionService.SetChannelVADConfig(config.MicVAD, config.SysVAD)
 
-			// Настраиваем гибридную транскрипцию если включена
-			if msg.HybridEnabled && msg.HybridSecondaryModelID != "" {
-				hybridConfig := &ai.HybridTranscriptionConfig{
-					Enabled:             true,
-					SecondaryModelID:    msg.HybridSecondaryModelID,
-					ConfidenceThreshold: float32(msg.HybridConfidenceThreshold),
-					ContextWords:        msg.HybridContextWords,
-					UseLLMForMerge:      msg.HybridUseLLMForMerge,
-					Mode:                ai.HybridMode(msg.HybridMode),
-					OllamaModel:         msg.HybridOllamaModel,
-					OllamaURL:           msg.HybridOllamaURL,
-					Hotwords:            msg.HybridHotwords,
-				}
-				// Устанавливаем дефолты если не указаны
-				if hybridConfig.ConfidenceThreshold <= 0 {
-					hybridConfig.ConfidenceThreshold = 0.7 // Повышен с 0.5 до 0.7
-				}
-				if hybridConfig.ContextWords <= 0 {
-					hybridConfig.ContextWords = 3
-				}
-				if hybridConfig.Mode == "" {
-					hybridConfig.Mode = ai.HybridModeFullCompare // По умолчанию - полное сравнение
-				}
-				// Дефолты для Ollama
-				if hybridConfig.OllamaModel == "" {
-					hybridConfig.OllamaModel = msg.OllamaModel // Берём из общих настроек
-				}
-				if hybridConfig.OllamaURL == "" {
-					hybridConfig.OllamaURL = msg.OllamaUrl
-				}
-				s.TranscriptionService.SetHybridConfig(hybridConfig)
-			} else {
-				s.TranscriptionService.SetHybridConfig(nil)
-			}
+			// Настраиваем гибридную транскрипцию (nil - выключена)
+			s.TranscriptionService.SetHybridConfig(hybridConfig)
 		}
 
 		sess, err := s.RecordingService.StartSession(config, ec, msg.UseVoiceIsolation)
 		if err != nil {
-			send(Message{Type: "error", Data: err.Error()})
+			send(errorMessage(err))
 			return
 		}
 		send(Message{Type: "session_started", Session: sess})
@@ -630,6 +973,26 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			return
 		}
 		send(Message{Type: "session_stopped", Session: sess})
+		if s.TranscriptionService != nil && sess != nil {
+			s.TranscriptionService.ForgetChannelSwap(sess.ID)
+			s.TranscriptionService.ScheduleSessionAutoImprove(sess.ID)
+		}
+
+	case "pause_session":
+		sess, err := s.RecordingService.PauseSession()
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		send(Message{Type: "session_paused", Session: sess})
+
+	case "resume_session":
+		sess, err := s.RecordingService.ResumeSession()
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		send(Message{Type: "session_resumed", Session: sess})
 
 	case "generate_summary":
 		if s.LLMService == nil {
@@ -649,7 +1012,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		go func() {
-			summary, err := s.LLMService.GenerateSummaryWithLLM(text.String(), msg.OllamaModel, msg.OllamaUrl)
+			summary, err := s.LLMService.GenerateSummaryWithLLM(text.String(), msg.OllamaModel, msg.OllamaUrl, msg.LLMOptions)
 			if err != nil {
 				s.broadcast(Message{Type: "summary_error", SessionID: msg.SessionID, Error: err.Error()})
 				return
@@ -664,6 +1027,13 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			send(Message{Type: "error", Data: "Transcription service not available"})
 			return
 		}
+		if msg.AutoImproveScope != "" {
+			if err := s.TranscriptionService.SetAutoImproveScope(service.AutoImproveScope(msg.AutoImproveScope)); err != nil {
+				send(Message{Type: "error", Data: err.Error()})
+				return
+			}
+		}
+		scope := string(s.TranscriptionService.AutoImproveScope)
 		if msg.AutoImproveEnabled {
 			url := msg.OllamaUrl
 			if url == "" {
@@ -674,16 +1044,16 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			if model == "" {
 				log.Printf("Auto-improve: model not specified, auto-improve will be disabled")
 				s.TranscriptionService.DisableAutoImprove()
-				send(Message{Type: "auto_improve_status", AutoImproveEnabled: false, Error: "Ollama model not configured"})
+				send(Message{Type: "auto_improve_status", AutoImproveEnabled: false, AutoImproveScope: scope, Error: "Ollama model not configured"})
 				return
 			}
 			s.TranscriptionService.EnableAutoImprove(url, model)
-			send(Message{Type: "auto_improve_status", AutoImproveEnabled: true, OllamaModel: model, OllamaUrl: url})
+			send(Message{Type: "auto_improve_status", AutoImproveEnabled: true, AutoImproveScope: scope, OllamaModel: model, OllamaUrl: url})
 		} else {
 			s.TranscriptionService.DisableAutoImprove()
-			send(Message{Type: "auto_improve_status", AutoImproveEnabled: false})
+			send(Message{Type: "auto_improve_status", AutoImproveEnabled: false, AutoImproveScope: scope})
 		}
-		log.Printf("Auto-improve: enabled=%v, model=%s, url=%s", msg.AutoImproveEnabled, msg.OllamaModel, msg.OllamaUrl)
+		log.Printf("Auto-improve: enabled=%v, scope=%s, model=%s, url=%s", msg.AutoImproveEnabled, scope, msg.OllamaModel, msg.OllamaUrl)
 
 	case "get_auto_improve_status":
 		// Получить текущий статус автоулучшения
@@ -694,6 +1064,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		send(Message{
 			Type:               "auto_improve_status",
 			AutoImproveEnabled: s.TranscriptionService.AutoImproveWithLLM,
+			AutoImproveScope:   string(s.TranscriptionService.AutoImproveScope),
 			OllamaModel:        s.TranscriptionService.OllamaModel,
 			OllamaUrl:          s.TranscriptionService.OllamaURL,
 		})
@@ -704,33 +1075,10 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			send(Message{Type: "error", Data: "Transcription service not available"})
 			return
 		}
-		if msg.HybridEnabled && msg.HybridSecondaryModelID != "" {
-			hybridConfig := &ai.HybridTranscriptionConfig{
-				Enabled:             true,
-				SecondaryModelID:    msg.HybridSecondaryModelID,
-				ConfidenceThreshold: float32(msg.HybridConfidenceThreshold),
-				ContextWords:        msg.HybridContextWords,
-				UseLLMForMerge:      msg.HybridUseLLMForMerge,
-				Mode:                ai.HybridMode(msg.HybridMode),
-				OllamaModel:         msg.HybridOllamaModel,
-				OllamaURL:           msg.HybridOllamaURL,
-				Hotwords:            msg.HybridHotwords,
-			}
-			if hybridConfig.ConfidenceThreshold <= 0 {
-				hybridConfig.ConfidenceThreshold = 0.7 // Повышен с 0.5 до 0.7
-			}
-			if hybridConfig.ContextWords <= 0 {
-				hybridConfig.ContextWords = 3
-			}
-			if hybridConfig.Mode == "" {
-				hybridConfig.Mode = ai.HybridModeFullCompare // По умолчанию - полное сравнение
-			}
-			// Дефолты для Ollama
-			if hybridConfig.OllamaModel == "" {
-				hybridConfig.OllamaModel = msg.OllamaModel
-			}
-			if hybridConfig.OllamaURL == "" {
-				hybridConfig.OllamaURL = msg.OllamaUrl
+		if hybridConfig := hybridConfigFromMessage(msg); hybridConfig != nil {
+			if err := hybridConfig.Validate(); err != nil {
+				send(Message{Type: "error", Data: err.Error()})
+				return
 			}
 			s.TranscriptionService.SetHybridConfig(hybridConfig)
 			send(Message{
@@ -808,16 +1156,76 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		go func() {
-			improved, err := s.LLMService.ImproveTranscriptionWithLLM(dialogue, msg.OllamaModel, msg.OllamaUrl)
+			improved, err := s.LLMService.ImproveTranscriptionWithLLM(dialogue, msg.OllamaModel, msg.OllamaUrl, msg.LLMOptions)
 			if err != nil {
 				s.broadcast(Message{Type: "improve_error", SessionID: msg.SessionID, Error: err.Error()})
 				return
 			}
-			s.SessionMgr.UpdateImprovedDialogue(msg.SessionID, improved)
+			if err := s.SessionMgr.SetDialogueLayer(msg.SessionID, session.DialogueLayerImproved, improved); err != nil {
+				s.broadcast(Message{Type: "improve_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
+			updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
+			s.broadcast(Message{Type: "improve_completed", SessionID: msg.SessionID, Session: updatedSess, DialogueLayer: session.DialogueLayerImproved})
+		}()
+
+	case "restore_punctuation":
+		// Только пунктуация и регистр, без перефразирования; результат - альтернативный диалог
+		if s.LLMService == nil {
+			send(Message{Type: "error", Data: "LLM Service not available"})
+			return
+		}
+		sess, err := s.SessionMgr.GetSession(msg.SessionID)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		dialogue := collectSessionDialogue(sess)
+		if len(dialogue) == 0 {
+			send(Message{Type: "error", Data: "Session has no transcription"})
+			return
+		}
+		send(Message{Type: "punctuation_started", SessionID: msg.SessionID})
+
+		go func() {
+			punctuated, err := s.LLMService.RestorePunctuation(dialogue, msg.OllamaModel, msg.OllamaUrl)
+			if err == nil {
+				err = s.SessionMgr.SetPunctuatedDialogue(msg.SessionID, punctuated)
+			}
+			if err != nil {
+				s.broadcast(Message{Type: "punctuation_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
 			updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
-			s.broadcast(Message{Type: "improve_completed", SessionID: msg.SessionID, Session: updatedSess})
+			s.broadcast(Message{Type: "punctuation_completed", SessionID: msg.SessionID, Session: updatedSess})
 		}()
 
+	case "set_dialogue_layer":
+		// Показать сохранённый вариант диалога; исходный результат ASR - слой "raw"
+		if msg.SessionID == "" || msg.DialogueLayer == "" {
+			send(Message{Type: "error", Data: "sessionId and dialogueLayer are required"})
+			return
+		}
+		if err := s.SessionMgr.ApplyDialogueLayer(msg.SessionID, msg.DialogueLayer); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
+		s.broadcast(Message{Type: "dialogue_layer_changed", SessionID: msg.SessionID, Session: updatedSess, DialogueLayer: msg.DialogueLayer})
+
+	case "diff_layers":
+		// Что изменили LLM/гибрид: пословный diff baseLayer (по умолчанию "raw") -> dialogueLayer (по умолчанию показанный)
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		diff, err := s.diffDialogueLayers(msg.SessionID, msg.BaseLayer, msg.DialogueLayer)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		send(Message{Type: "layers_diff", SessionID: msg.SessionID, LayerDiff: diff})
+
 	case "diarize_with_llm":
 		// Диаризация всего текста с помощью LLM - разбивает "Собеседник" на "Собеседник 1", "Собеседник 2" и т.д.
 		if s.LLMService == nil {
@@ -850,9 +1258,12 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 				s.broadcast(Message{Type: "diarize_error", SessionID: msg.SessionID, Error: err.Error()})
 				return
 			}
-			s.SessionMgr.UpdateImprovedDialogue(msg.SessionID, diarized)
+			if err := s.SessionMgr.SetDialogueLayer(msg.SessionID, session.DialogueLayerDiarized, diarized); err != nil {
+				s.broadcast(Message{Type: "diarize_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
 			updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
-			s.broadcast(Message{Type: "diarize_completed", SessionID: msg.SessionID, Session: updatedSess})
+			s.broadcast(Message{Type: "diarize_completed", SessionID: msg.SessionID, Session: updatedSess, DialogueLayer: session.DialogueLayerDiarized})
 		}()
 
 	case "retranscribe_chunk":
@@ -880,34 +1291,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		// Настраиваем гибридную транскрипцию если включена
-		if msg.HybridEnabled && msg.HybridSecondaryModelID != "" {
-			hybridConfig := &ai.HybridTranscriptionConfig{
-				Enabled:             true,
-				SecondaryModelID:    msg.HybridSecondaryModelID,
-				ConfidenceThreshold: float32(msg.HybridConfidenceThreshold),
-				ContextWords:        msg.HybridContextWords,
-				UseLLMForMerge:      msg.HybridUseLLMForMerge,
-				Mode:                ai.HybridMode(msg.HybridMode),
-				OllamaModel:         msg.HybridOllamaModel,
-				OllamaURL:           msg.HybridOllamaURL,
-				Hotwords:            msg.HybridHotwords,
-			}
-			if hybridConfig.ConfidenceThreshold <= 0 {
-				hybridConfig.ConfidenceThreshold = 0.7
-			}
-			if hybridConfig.ContextWords <= 0 {
-				hybridConfig.ContextWords = 3
-			}
-			if hybridConfig.Mode == "" {
-				hybridConfig.Mode = ai.HybridModeFullCompare
-			}
-			// Дефолты для Ollama
-			if hybridConfig.OllamaModel == "" {
-				hybridConfig.OllamaModel = msg.OllamaModel
-			}
-			if hybridConfig.OllamaURL == "" {
-				hybridConfig.OllamaURL = msg.OllamaUrl
-			}
+		if hybridConfig := hybridConfigFromMessage(msg); hybridConfig != nil {
 			s.TranscriptionService.SetHybridConfig(hybridConfig)
 			log.Printf("Hybrid transcription configured for retranscribe: mode=%s, secondary=%s, ollamaModel=%s, hotwords=%d",
 				hybridConfig.Mode, hybridConfig.SecondaryModelID, hybridConfig.OllamaModel, len(hybridConfig.Hotwords))
@@ -937,7 +1321,112 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			}
 
 			log.Printf("Retranscribing chunk %d (id=%s)", targetChunk.Index, targetChunk.ID)
-			s.TranscriptionService.HandleChunk(targetChunk)
+			if err := s.TranscriptionService.RetranscribeChunk(targetChunk); err != nil {
+				log.Printf("retranscribe_chunk failed: %v", err)
+				s.broadcast(Message{Type: "chunk_transcribed", SessionID: msg.SessionID, Error: err.Error()})
+			}
+		}()
+
+	case "cancel_retranscribe_chunk":
+		if msg.SessionID == "" || msg.Data == "" {
+			send(Message{Type: "error", Data: "sessionId and chunkId (data) are required"})
+			return
+		}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
+		}
+
+		canceled := s.TranscriptionService.CancelChunkRetranscription(msg.SessionID, msg.Data)
+		log.Printf("Received cancel_retranscribe_chunk: sessionId=%s, chunkId=%s, canceled=%v", msg.SessionID, msg.Data, canceled)
+		if !canceled {
+			send(Message{Type: "error", Data: "no active retranscription for chunk " + msg.Data})
+			return
+		}
+		send(Message{Type: "chunk_retranscribe_canceled", SessionID: msg.SessionID, Data: msg.Data})
+
+	case "retranscribe_range":
+		log.Printf("Received retranscribe_range: sessionId=%s, range=%d-%dms, model=%s, language=%s, hybrid=%v",
+			msg.SessionID, msg.RangeStartMs, msg.RangeEndMs, msg.Model, msg.Language, msg.HybridEnabled)
+
+		if msg.SessionID == "" || msg.RangeEndMs <= msg.RangeStartMs || msg.RangeStartMs < 0 {
+			send(Message{Type: "error", Data: "sessionId and valid rangeStartMs/rangeEndMs are required"})
+			return
+		}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
+		}
+
+		// Update engine with specified model/language
+		if s.EngineMgr != nil {
+			if msg.Language != "" {
+				s.EngineMgr.SetLanguage(msg.Language)
+			}
+			if msg.Model != "" {
+				if err := s.EngineMgr.SetActiveModel(msg.Model); err != nil {
+					log.Printf("Failed to set model: %v", err)
+				} else {
+					s.updatePipelineTranscriber()
+				}
+			}
+		}
+		s.TranscriptionService.SetHybridConfig(hybridConfigFromMessage(msg))
+
+		send(Message{Type: "range_retranscribe_started", SessionID: msg.SessionID, RangeStartMs: msg.RangeStartMs, RangeEndMs: msg.RangeEndMs})
+
+		go func() {
+			startMs, endMs, err := s.TranscriptionService.RetranscribeRange(msg.SessionID, msg.RangeStartMs, msg.RangeEndMs)
+			if err != nil {
+				log.Printf("retranscribe_range failed: %v", err)
+				send(Message{Type: "range_retranscribe_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
+
+			// Переименования уже применены в колбэке chunk_transcribed для каждого чанка
+			s.invalidateSessionSpeakersCache(msg.SessionID)
+			updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
+			s.broadcast(Message{
+				Type:         "range_retranscribed",
+				SessionID:    msg.SessionID,
+				Session:      updatedSess,
+				RangeStartMs: startMs,
+				RangeEndMs:   endMs,
+			})
+		}()
+
+	case "benchmark_models":
+		if msg.SessionID == "" || len(msg.ModelIDs) == 0 {
+			send(Message{Type: "error", Data: "sessionId and modelIds are required"})
+			return
+		}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
+		}
+
+		// Пропускаем неизвестные и не скачанные модели
+		var available, skipped []string
+		for _, modelID := range msg.ModelIDs {
+			if models.GetModelByID(modelID) == nil || !s.ModelMgr.IsModelDownloaded(modelID) {
+				skipped = append(skipped, modelID)
+				continue
+			}
+			available = append(available, modelID)
+		}
+
+		log.Printf("Received benchmark_models: sessionId=%s, chunkId=%s, models=%v, skipped=%v",
+			msg.SessionID, msg.Data, available, skipped)
+
+		send(Message{Type: "benchmark_started", SessionID: msg.SessionID, ModelIDs: available, SkippedModels: skipped})
+
+		go func() {
+			results, err := s.TranscriptionService.BenchmarkModels(msg.SessionID, msg.Data, available, msg.Language)
+			if err != nil {
+				send(Message{Type: "benchmark_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
+			send(Message{Type: "benchmark_completed", SessionID: msg.SessionID, BenchmarkResults: results, SkippedModels: skipped})
 		}()
 
 	case "retranscribe_full":
@@ -948,6 +1437,10 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			send(Message{Type: "error", Data: "sessionId is required"})
 			return
 		}
+		if err := s.applyRegionMergeSettings(msg); err != nil {
+			send(Message{Type: "full_transcription_error", SessionID: msg.SessionID, Error: err.Error()})
+			return
+		}
 
 		// Update engine with specified model/language
 		if s.EngineMgr != nil {
@@ -976,6 +1469,8 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 				OllamaModel:         msg.HybridOllamaModel,
 				OllamaURL:           msg.HybridOllamaURL,
 				Hotwords:            msg.HybridHotwords,
+				RegionMergeGapMs:    msg.HybridRegionMergeGapMs,
+				RegionPaddingMs:     msg.HybridRegionPaddingMs,
 			}
 			if hybridConfig.ConfidenceThreshold <= 0 {
 				hybridConfig.ConfidenceThreshold = 0.7
@@ -992,168 +1487,34 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			s.TranscriptionService.SetHybridConfig(nil)
 		}
 
-		// Проверяем сессию заранее для определения количества чанков
-		sess, err := s.SessionMgr.GetSession(msg.SessionID)
-		if err != nil {
-			log.Printf("Full retranscription error: %v", err)
-			send(Message{Type: "full_transcription_error", SessionID: msg.SessionID, Error: err.Error()})
+		s.startFullRetranscription(send, msg.SessionID, msg.DiarizationEnabled)
+
+	case "rediarize_full":
+		// Повторная диаризация без транскрипции: исправляет спикеров, не распознавая речь заново
+		log.Printf("Received rediarize_full: sessionId=%s", msg.SessionID)
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
 			return
 		}
+		s.startFullRediarization(send, msg.SessionID)
 
-		totalChunks := len(sess.Chunks)
-
-		// Определяем использование диаризации
-		// ВАЖНО: sherpa-onnx имеет известную утечку памяти при многократных вызовах
-		// (https://github.com/k2-fsa/sherpa-onnx/issues/974, #1939)
-		// Ограничиваем диаризацию только короткими сессиями (до 10 чанков = 5 минут)
-		const maxChunksForDiarization = 10
-		useDiarization := msg.DiarizationEnabled && s.TranscriptionService.IsDiarizationEnabled()
-
-		if useDiarization && totalChunks > maxChunksForDiarization {
-			log.Printf("WARNING: Disabling diarization for batch retranscription (%d chunks > %d max) due to sherpa-onnx memory leak",
-				totalChunks, maxChunksForDiarization)
-			useDiarization = false
-			// Уведомляем пользователя
-			s.broadcast(Message{
-				Type:      "diarization_warning",
-				SessionID: msg.SessionID,
-				Data:      fmt.Sprintf("Диаризация отключена для длинных сессий (>%d чанков) из-за известной проблемы с памятью", maxChunksForDiarization),
-			})
-		}
+	case "reprocess_session":
+		// Повторная обработка сохранённого аудио с новыми настройками: модель, язык, VAD,
+		// диаризация и гибридный режим проверяются вместе и применяются до единой ретранскрипции
+		log.Printf("Received reprocess_session: sessionId=%s, model=%s, language=%s, vadMode=%s, vadMethod=%s, diarization=%v, hybrid=%v",
+			msg.SessionID, msg.Model, msg.Language, msg.VADMode, msg.VADMethod, msg.DiarizationEnabled, msg.HybridEnabled)
 
-		// Сбрасываем состояние диаризации (спикеров) перед полной ретранскрипцией
-		if useDiarization {
-			s.TranscriptionService.ResetDiarizationState()
+		if err := s.validateReprocessSettings(msg); err != nil {
+			log.Printf("reprocess_session rejected: %v", err)
+			send(Message{Type: "full_transcription_error", SessionID: msg.SessionID, Error: err.Error()})
+			return
 		}
-
-		// Создаём context для отмены
-		ctx, cancel := context.WithCancel(context.Background())
-		sessionID := msg.SessionID
-
-		// Сохраняем cancel функцию
-		s.retranscribeCancelsMu.Lock()
-		// Отменяем предыдущую ретранскрипцию если была
-		if prevCancel, exists := s.retranscribeCancels[sessionID]; exists {
-			prevCancel()
+		if err := s.applyReprocessSettings(msg); err != nil {
+			log.Printf("reprocess_session failed: %v", err)
+			send(Message{Type: "full_transcription_error", SessionID: msg.SessionID, Error: err.Error()})
+			return
 		}
-		s.retranscribeCancels[sessionID] = cancel
-		s.retranscribeCancelsMu.Unlock()
-
-		// Отправляем через broadcast для всех клиентов
-		log.Printf("Sending full_transcription_started for session %s (diarization=%v)", sessionID, useDiarization)
-		s.broadcast(Message{Type: "full_transcription_started", SessionID: sessionID})
-
-		go func() {
-			defer func() {
-				// Удаляем cancel функцию после завершения
-				s.retranscribeCancelsMu.Lock()
-				delete(s.retranscribeCancels, sessionID)
-				s.retranscribeCancelsMu.Unlock()
-			}()
-
-			// Кэшируем существующие переименования спикеров ПЕРЕД очисткой профилей
-			// чтобы применить их в конце ретранскрипции
-			cachedRenames := s.getExistingSpeakerRenames(sessionID)
-			if len(cachedRenames) > 0 {
-				s.speakerRenamesCacheMu.Lock()
-				s.speakerRenamesCache[sessionID] = cachedRenames
-				s.speakerRenamesCacheMu.Unlock()
-				log.Printf("Full retranscription: cached %d speaker renames for session %s", len(cachedRenames), sessionID[:8])
-			}
-
-			// Устанавливаем флаг активной полной ретранскрипции
-			s.fullRetranscribeActiveMu.Lock()
-			s.fullRetranscribeActive[sessionID] = true
-			s.fullRetranscribeActiveMu.Unlock()
-
-			// Очищаем профили спикеров для сессии при полной ретранскрипции
-			// чтобы начать сопоставление с чистого листа
-			s.TranscriptionService.ClearSessionSpeakerProfiles(sessionID)
-
-			if totalChunks == 0 {
-				log.Printf("Full retranscription: no chunks to process")
-				s.fullRetranscribeActiveMu.Lock()
-				delete(s.fullRetranscribeActive, sessionID)
-				s.fullRetranscribeActiveMu.Unlock()
-				s.broadcast(Message{Type: "full_transcription_completed", SessionID: sessionID, Session: sess})
-				return
-			}
-
-			log.Printf("Full retranscription: processing %d chunks (diarization=%v)", totalChunks, useDiarization)
-
-			for i, chunk := range sess.Chunks {
-				// Проверяем отмену перед каждым чанком
-				select {
-				case <-ctx.Done():
-					log.Printf("Full retranscription cancelled for session %s at chunk %d/%d", sessionID, i+1, totalChunks)
-					// Очищаем флаг и кэш при отмене
-					s.fullRetranscribeActiveMu.Lock()
-					delete(s.fullRetranscribeActive, sessionID)
-					s.fullRetranscribeActiveMu.Unlock()
-					s.speakerRenamesCacheMu.Lock()
-					delete(s.speakerRenamesCache, sessionID)
-					s.speakerRenamesCacheMu.Unlock()
-					s.broadcast(Message{
-						Type:      "full_transcription_cancelled",
-						SessionID: sessionID,
-						Data:      fmt.Sprintf("Отменено на чанке %d из %d", i+1, totalChunks),
-					})
-					return
-				default:
-				}
-
-				// Отправляем прогресс
-				progress := float64(i) / float64(totalChunks)
-				log.Printf("Full retranscription progress: %d/%d (%.1f%%)", i+1, totalChunks, progress*100)
-				s.broadcast(Message{
-					Type:      "full_transcription_progress",
-					SessionID: sessionID,
-					Progress:  progress,
-					Data:      fmt.Sprintf("Обработка чанка %d из %d...", i+1, totalChunks),
-				})
-
-				log.Printf("Retranscribing chunk %d/%d (id=%s, diarization=%v)", i+1, totalChunks, chunk.ID, useDiarization)
-				// Используем синхронный метод с явным флагом диаризации
-				s.TranscriptionService.HandleChunkSyncWithDiarization(chunk, useDiarization)
-			}
-
-			// Финальный прогресс 100%
-			s.broadcast(Message{
-				Type:      "full_transcription_progress",
-				SessionID: sessionID,
-				Progress:  1.0,
-				Data:      "Применение имён спикеров...",
-			})
-
-			// Снимаем флаг активной ретранскрипции
-			s.fullRetranscribeActiveMu.Lock()
-			delete(s.fullRetranscribeActive, sessionID)
-			s.fullRetranscribeActiveMu.Unlock()
-
-			// Применяем кэшированные переименования спикеров
-			s.speakerRenamesCacheMu.RLock()
-			finalRenames := s.speakerRenamesCache[sessionID]
-			s.speakerRenamesCacheMu.RUnlock()
-
-			if len(finalRenames) > 0 {
-				log.Printf("Full retranscription: applying %d cached speaker renames", len(finalRenames))
-				for oldName, newName := range finalRenames {
-					if err := s.SessionMgr.UpdateSpeakerName(sessionID, oldName, newName); err == nil {
-						log.Printf("Full retranscription: applied rename '%s' -> '%s'", oldName, newName)
-					}
-				}
-				// Очищаем кэш переименований
-				s.speakerRenamesCacheMu.Lock()
-				delete(s.speakerRenamesCache, sessionID)
-				s.speakerRenamesCacheMu.Unlock()
-				// Инвалидируем кэш спикеров
-				s.invalidateSessionSpeakersCache(sessionID)
-			}
-
-			updatedSess, _ := s.SessionMgr.GetSession(sessionID)
-			log.Printf("Full retranscription completed for session %s", sessionID)
-			s.broadcast(Message{Type: "full_transcription_completed", SessionID: sessionID, Session: updatedSess})
-		}()
+		s.startFullRetranscription(send, msg.SessionID, msg.DiarizationEnabled)
 
 	case "cancel_full_transcription":
 		sessionID := msg.SessionID
@@ -1194,8 +1555,30 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		if backend == "" {
 			backend = "fluid" // По умолчанию используем FluidAudio на macOS
 		}
-		log.Printf("Received enable_diarization: backend=%s, provider=%s, segmentation=%s, embedding=%s",
-			backend, provider, msg.SegmentationModelPath, msg.EmbeddingModelPath)
+		log.Printf("Received enable_diarization: backend=%s, provider=%s, segmentation=%s, embedding=%s, minSpeakerRatio=%.3f, minSegment=%.2fs, matchThreshold=%.2f, clustering=%.2f, minOn=%.2fs, minOff=%.2fs",
+			backend, provider, msg.SegmentationModelPath, msg.EmbeddingModelPath, msg.MinSpeakerRatio, msg.MinSpeakerSegmentSec, msg.SpeakerMatchThreshold,
+			msg.ClusteringThreshold, msg.MinDurationOn, msg.MinDurationOff)
+
+		diarizationParams := service.DiarizationParams{
+			ClusteringThreshold:  float32(msg.ClusteringThreshold),
+			MinDurationOn:        float32(msg.MinDurationOn),
+			MinDurationOff:       float32(msg.MinDurationOff),
+			MinSpeakerRatio:      float32(msg.MinSpeakerRatio),
+			MinSpeakerSegmentSec: float32(msg.MinSpeakerSegmentSec),
+			MinOverlapRatio:      float32(msg.MinOverlapRatio),
+		}
+		if err := diarizationParams.Validate(); err != nil {
+			send(Message{Type: "diarization_error", Error: err.Error()})
+			return
+		}
+
+		// Порог сопоставления спикеров между чанками проверяем до включения диаризации
+		if msg.SpeakerMatchThreshold != 0 {
+			if err := s.TranscriptionService.SetSpeakerMatchThreshold(float32(msg.SpeakerMatchThreshold)); err != nil {
+				send(Message{Type: "diarization_error", Error: err.Error()})
+				return
+			}
+		}
 
 		// Для FluidAudio не нужны пути к моделям (они скачиваются автоматически)
 		if backend != "fluid" && (msg.SegmentationModelPath == "" || msg.EmbeddingModelPath == "") {
@@ -1204,7 +1587,7 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		// Проверяем есть ли активный engine, если нет - пробуем загрузить активную модель
-		if s.EngineMgr != nil && s.EngineMgr.GetActiveEngine() == nil {
+		if s.EngineMgr != nil && !s.EngineMgr.HasActiveEngine() {
 			// Пробуем загрузить активную модель из ModelMgr
 			activeModelID := ""
 			if s.ModelMgr != nil {
@@ -1223,8 +1606,8 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 			}
 		}
 
-		err := s.TranscriptionService.EnableDiarizationWithBackend(
-			msg.SegmentationModelPath, msg.EmbeddingModelPath, provider, backend)
+		err := s.TranscriptionService.EnableDiarizationWithParams(
+			msg.SegmentationModelPath, msg.EmbeddingModelPath, provider, backend, diarizationParams)
 		if err != nil {
 			log.Printf("Failed to enable diarization: %v", err)
 			send(Message{Type: "diarization_error", Error: err.Error()})
@@ -1232,13 +1615,45 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		}
 
 		actualProvider := s.TranscriptionService.GetDiarizationProvider()
+		applied := s.TranscriptionService.GetDiarizationParams()
 		send(Message{
-			Type:                "diarization_enabled",
-			DiarizationEnabled:  true,
-			DiarizationProvider: actualProvider,
-			DiarizationBackend:  backend,
+			Type:                  "diarization_enabled",
+			DiarizationEnabled:    true,
+			DiarizationProvider:   actualProvider,
+			DiarizationBackend:    backend,
+			SpeakerMatchThreshold: float64(s.TranscriptionService.GetSpeakerMatchThreshold()),
+			ClusteringThreshold:   float64(applied.ClusteringThreshold),
+			MinDurationOn:         float64(applied.MinDurationOn),
+			MinDurationOff:        float64(applied.MinDurationOff),
+			MinSpeakerRatio:       float64(applied.MinSpeakerRatio),
+			MinSpeakerSegmentSec:  float64(applied.MinSpeakerSegmentSec),
+			MinOverlapRatio:       float64(applied.MinOverlapRatio),
 		})
 
+	case "compare_diarization":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
+		}
+
+		log.Printf("Received compare_diarization: sessionId=%s, chunkId=%s, provider=%s",
+			msg.SessionID, msg.Data, msg.DiarizationProvider)
+		send(Message{Type: "diarization_comparison_started", SessionID: msg.SessionID})
+
+		go func() {
+			results, err := s.TranscriptionService.CompareDiarizationBackends(
+				msg.SessionID, msg.Data, msg.SegmentationModelPath, msg.EmbeddingModelPath, msg.DiarizationProvider)
+			if err != nil {
+				send(Message{Type: "diarization_comparison_error", SessionID: msg.SessionID, Error: err.Error()})
+				return
+			}
+			send(Message{Type: "diarization_comparison", SessionID: msg.SessionID, DiarizationComparison: results})
+		}()
+
 	case "disable_diarization":
 		log.Printf("Received disable_diarization")
 		s.TranscriptionService.DisableDiarization()
@@ -1248,9 +1663,10 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		enabled := s.TranscriptionService.IsDiarizationEnabled()
 		provider := s.TranscriptionService.GetDiarizationProvider()
 		send(Message{
-			Type:                "diarization_status",
-			DiarizationEnabled:  enabled,
-			DiarizationProvider: provider,
+			Type:                  "diarization_status",
+			DiarizationEnabled:    enabled,
+			DiarizationProvider:   provider,
+			SpeakerMatchThreshold: float64(s.TranscriptionService.GetSpeakerMatchThreshold()),
 		})
 
 	// === Streaming Transcription ===
@@ -1394,114 +1810,340 @@ func (s *Server) processMessage(send sendFunc, msg Message) {
 		speakers := s.getSessionSpeakers(msg.SessionID)
 		log.Printf("get_session_speakers: sessionID=%s, found %d speakers", msg.SessionID, len(speakers))
 		for i, sp := range speakers {
-			log.Printf("  speaker[%d]: localID=%d, name=%s, isMic=%v, segments=%d, duration=%.1fs",
-				i, sp.LocalID, sp.DisplayName, sp.IsMic, sp.SegmentCount, sp.TotalDuration)
+			log.Printf("  speaker[%d]: localID=%d, name=%s, isMic=%v, segments=%d, duration=%.1fs, stability=%.2f (%d), minMatch=%.2f",
+				i, sp.LocalID, sp.DisplayName, sp.IsMic, sp.SegmentCount, sp.TotalDuration, sp.Stability, sp.StabilitySamples, sp.MinMatchSimilarity)
 		}
 		send(Message{Type: "session_speakers", SessionID: msg.SessionID, SessionSpeakers: speakers})
 
-	case "rename_session_speaker":
-		if msg.SessionID == "" || msg.SpeakerName == "" {
-			send(Message{Type: "error", Data: "sessionId and speakerName are required"})
+	case "get_session_stats":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
 			return
 		}
 
-		// Переименовываем спикера в сессии
-		if err := s.renameSpeakerInSession(msg.SessionID, msg.LocalSpeakerID, msg.SpeakerName); err != nil {
+		stats, err := s.SessionMgr.GetProcessingStats(msg.SessionID)
+		if err != nil {
 			send(Message{Type: "error", Data: err.Error()})
 			return
 		}
+		log.Printf("get_session_stats: sessionID=%s, %s", msg.SessionID, stats)
+		send(Message{Type: "session_stats", SessionID: msg.SessionID, ProcessingStats: stats})
 
-		// Инвалидируем кэш спикеров после переименования
-		s.invalidateSessionSpeakersCache(msg.SessionID)
+	case "preview_compression":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "transcription service not available"})
+			return
+		}
 
-		// Если запрошено сохранение в глобальную базу
-		var voiceprintID string
-		if msg.SaveAsVoiceprint && s.VoicePrintStore != nil {
-			log.Printf("[VoicePrint] Attempting to save voiceprint for speaker %d in session %s", msg.LocalSpeakerID, msg.SessionID)
-			embedding, source, err := s.getSpeakerEmbedding(msg.SessionID, msg.LocalSpeakerID)
+		// Декодирование записи целиком может занять время
+		go func() {
+			preview, err := s.TranscriptionService.PreviewCompression(msg.SessionID)
 			if err != nil {
-				log.Printf("[VoicePrint] Failed to get embedding: %v", err)
-			} else if len(embedding) == 0 {
-				log.Printf("[VoicePrint] Empty embedding returned")
-			} else {
-				vp, err := s.VoicePrintStore.Add(msg.SpeakerName, embedding, source)
-				if err != nil {
-					log.Printf("[VoicePrint] Failed to save: %v", err)
-				} else {
-					voiceprintID = vp.ID
-					log.Printf("[VoicePrint] Saved from rename: %s (%s)", vp.Name, vp.ID[:8])
-				}
+				send(Message{Type: "error", Data: err.Error()})
+				return
 			}
-		}
+			for _, ch := range preview.Channels {
+				log.Printf("preview_compression: session=%s %s: %dms -> %dms (%.0f%%), %d regions",
+					msg.SessionID, ch.Channel, ch.OriginalMs, ch.CompressedMs, ch.KeptRatio*100, ch.Regions)
+			}
+			send(Message{Type: "compression_preview", SessionID: msg.SessionID, CompressionPreview: preview})
+		}()
 
-		send(Message{
-			Type:           "speaker_renamed",
-			SessionID:      msg.SessionID,
-			LocalSpeakerID: msg.LocalSpeakerID,
-			SpeakerName:    msg.SpeakerName,
-			VoicePrintID:   voiceprintID,
-		})
+	case "get_keyword_index":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
 
-		// Обновляем сессию для всех клиентов
-		if updatedSess, err := s.SessionMgr.GetSession(msg.SessionID); err == nil {
-			s.broadcast(Message{Type: "session_details", Session: updatedSess})
+		index, err := s.SessionMgr.GetKeywordIndex(msg.SessionID, session.DefaultKeywordIndexSize)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
 		}
+		send(Message{Type: "keyword_index", SessionID: msg.SessionID, KeywordIndex: index})
 
-	case "merge_speakers":
-		// Объединение нескольких спикеров в одного
+	case "match_session_speakers":
 		if msg.SessionID == "" {
 			send(Message{Type: "error", Data: "sessionId is required"})
 			return
 		}
-		if len(msg.SourceSpeakerIDs) < 2 {
-			send(Message{Type: "error", Data: "at least 2 speakers required for merge"})
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
 			return
 		}
 
-		log.Printf("merge_speakers: session=%s, sources=%v, target=%d, name=%s, mergeEmb=%v, saveVP=%v",
-			msg.SessionID[:8], msg.SourceSpeakerIDs, msg.TargetSpeakerID, msg.SpeakerName, msg.MergeEmbeddings, msg.SaveAsVoiceprint)
-
-		// Выполняем объединение в SessionMgr
-		mergedCount, err := s.SessionMgr.MergeSpeakers(msg.SessionID, msg.SourceSpeakerIDs, msg.TargetSpeakerID, msg.SpeakerName)
+		matches, err := s.TranscriptionService.ProposeSessionSpeakerMatches(msg.SessionID)
 		if err != nil {
 			send(Message{Type: "error", Data: err.Error()})
 			return
 		}
+		log.Printf("match_session_speakers: sessionID=%s, %d speaker profiles checked", msg.SessionID, len(matches))
+		send(Message{Type: "session_speaker_matches", SessionID: msg.SessionID, SpeakerMatches: matches})
 
-		// Определяем финальное имя
-		finalName := msg.SpeakerName
-		if finalName == "" {
-			finalName = fmt.Sprintf("Собеседник %d", msg.TargetSpeakerID+1)
+	case "find_similar_speakers":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
 		}
-
-		// Опционально: объединяем embeddings в TranscriptionService
-		if msg.MergeEmbeddings && s.TranscriptionService != nil {
-			if err := s.TranscriptionService.MergeSpeakerProfiles(msg.SessionID, msg.SourceSpeakerIDs, msg.TargetSpeakerID); err != nil {
-				log.Printf("merge_speakers: failed to merge embeddings: %v", err)
-			} else {
-				log.Printf("merge_speakers: embeddings merged for session %s", msg.SessionID[:8])
-			}
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
 		}
 
-		// Опционально: сохраняем в глобальную базу voiceprints
-		var voiceprintID string
-		if msg.SaveAsVoiceprint && s.VoicePrintStore != nil {
-			embedding, source, err := s.getSpeakerEmbedding(msg.SessionID, msg.TargetSpeakerID)
-			if err != nil {
-				log.Printf("merge_speakers: failed to get embedding for voiceprint: %v", err)
-			} else if len(embedding) > 0 {
-				vp, err := s.VoicePrintStore.Add(finalName, embedding, source)
-				if err != nil {
-					log.Printf("merge_speakers: failed to save voiceprint: %v", err)
-				} else {
-					voiceprintID = vp.ID
-					log.Printf("merge_speakers: voiceprint saved: %s (%s)", vp.Name, vp.ID[:8])
-				}
-			}
+		similar, err := s.TranscriptionService.FindSimilarSpeakers(msg.SessionID, msg.LocalSpeakerID, float32(msg.SpeakerMatchThreshold))
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
 		}
+		log.Printf("find_similar_speakers: sessionID=%s, localID=%d, %d similar speakers found", msg.SessionID, msg.LocalSpeakerID, len(similar))
+		send(Message{Type: "similar_speakers", SessionID: msg.SessionID, LocalSpeakerID: msg.LocalSpeakerID, SimilarSpeakers: similar})
 
-		// Инвалидируем кэш спикеров
-		s.invalidateSessionSpeakersCache(msg.SessionID)
+	case "auto_name_speakers":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		if s.LLMService == nil {
+			send(Message{Type: "error", Data: "LLM Service not available"})
+			return
+		}
+
+		sess, err := s.SessionMgr.GetSession(msg.SessionID)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+
+		var dialogue []session.TranscriptSegment
+		for _, c := range sess.Chunks {
+			dialogue = append(dialogue, c.Dialogue...)
+		}
+
+		send(Message{Type: "auto_name_speakers_started", SessionID: msg.SessionID})
+
+		go func() {
+			// Если клиент прислал подтверждённый маппинг - применяем его без повторного запроса к LLM
+			names := msg.SpeakerNames
+			if len(names) == 0 {
+				inferred, err := s.LLMService.InferSpeakerNames(dialogue, msg.OllamaModel, msg.OllamaUrl)
+				if err != nil {
+					send(Message{Type: "auto_name_speakers_error", SessionID: msg.SessionID, Error: err.Error()})
+					return
+				}
+				names = inferred
+			}
+
+			if msg.Preview {
+				send(Message{Type: "auto_name_speakers_preview", SessionID: msg.SessionID, SpeakerNames: names})
+				return
+			}
+
+			for label, name := range names {
+				localID, ok := session.ParseSpeakerLabel(label)
+				if !ok {
+					log.Printf("auto_name_speakers: skipping unknown label '%s'", label)
+					continue
+				}
+				if err := s.renameSpeakerInSession(msg.SessionID, localID, name); err != nil {
+					log.Printf("auto_name_speakers: failed to rename '%s' -> '%s': %v", label, name, err)
+				}
+			}
+			s.invalidateSessionSpeakersCache(msg.SessionID)
+
+			log.Printf("auto_name_speakers: session=%s, applied %d names", msg.SessionID, len(names))
+			updatedSess, _ := s.SessionMgr.GetSession(msg.SessionID)
+			s.broadcast(Message{Type: "auto_name_speakers_completed", SessionID: msg.SessionID, SpeakerNames: names, Session: updatedSess})
+		}()
+
+	case "get_queue_status":
+		if s.TranscriptionService == nil {
+			send(Message{Type: "error", Data: "Transcription service not available"})
+			return
+		}
+		status := s.TranscriptionService.GetQueueStatus()
+		send(Message{Type: "queue_status", QueueStatus: &status})
+
+	case "get_speaker_timeline":
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+
+		sess, err := s.SessionMgr.GetSession(msg.SessionID)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+
+		timeline := s.computeSpeakerTimeline(sess, msg.SessionID)
+		log.Printf("get_speaker_timeline: sessionID=%s, tracks=%d", msg.SessionID, len(timeline))
+		send(Message{Type: "speaker_timeline", SessionID: msg.SessionID, SpeakerTimeline: timeline})
+
+	case "rename_session_speaker":
+		if msg.SessionID == "" || msg.SpeakerName == "" {
+			send(Message{Type: "error", Data: "sessionId and speakerName are required"})
+			return
+		}
+
+		// Переименовываем спикера в сессии
+		if err := s.renameSpeakerInSession(msg.SessionID, msg.LocalSpeakerID, msg.SpeakerName); err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+
+		// Инвалидируем кэш спикеров после переименования
+		s.invalidateSessionSpeakersCache(msg.SessionID)
+
+		// Если запрошено сохранение в глобальную базу
+		var voiceprintID string
+		if msg.SaveAsVoiceprint && s.VoicePrintStore != nil {
+			log.Printf("[VoicePrint] Attempting to save voiceprint for speaker %d in session %s", msg.LocalSpeakerID, msg.SessionID)
+			embedding, source, err := s.getSpeakerEmbedding(msg.SessionID, msg.LocalSpeakerID)
+			if err != nil {
+				log.Printf("[VoicePrint] Failed to get embedding: %v", err)
+			} else if len(embedding) == 0 {
+				log.Printf("[VoicePrint] Empty embedding returned")
+			} else {
+				vp, err := s.VoicePrintStore.Add(msg.SpeakerName, embedding, source)
+				if err != nil {
+					log.Printf("[VoicePrint] Failed to save: %v", err)
+				} else {
+					voiceprintID = vp.ID
+					log.Printf("[VoicePrint] Saved from rename: %s (%s)", vp.Name, vp.ID[:8])
+				}
+			}
+		}
+
+		send(Message{
+			Type:           "speaker_renamed",
+			SessionID:      msg.SessionID,
+			LocalSpeakerID: msg.LocalSpeakerID,
+			SpeakerName:    msg.SpeakerName,
+			VoicePrintID:   voiceprintID,
+		})
+
+		// Обновляем сессию для всех клиентов
+		if updatedSess, err := s.SessionMgr.GetSession(msg.SessionID); err == nil {
+			s.broadcast(Message{Type: "session_details", Session: updatedSess})
+		}
+
+	case "undo_speaker_rename":
+		// Отмена последнего переименования спикера в сессии
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+
+		undone, err := s.SessionMgr.UndoSpeakerRename(msg.SessionID)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		s.invalidateSessionSpeakersCache(msg.SessionID)
+		log.Printf("undo_speaker_rename: session=%s, '%s' -> '%s' (localID=%d)",
+			msg.SessionID, undone.NewName, undone.OldName, undone.LocalID)
+
+		send(Message{
+			Type:           "speaker_rename_undone",
+			SessionID:      msg.SessionID,
+			LocalSpeakerID: undone.LocalID,
+			SpeakerName:    undone.OldName,
+		})
+
+		// Обновляем сессию для всех клиентов
+		if updatedSess, err := s.SessionMgr.GetSession(msg.SessionID); err == nil {
+			s.broadcast(Message{Type: "session_details", Session: updatedSess})
+		}
+
+	case "reassign_speaker_range":
+		// Ручное исправление диаризации: собеседник localSpeakerId для диапазона rangeStartMs-rangeEndMs
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		log.Printf("reassign_speaker_range: session=%s, range=%d-%dms, localID=%d",
+			msg.SessionID, msg.RangeStartMs, msg.RangeEndMs, msg.LocalSpeakerID)
+
+		updated, err := s.SessionMgr.ReassignSpeakerRange(msg.SessionID, msg.RangeStartMs, msg.RangeEndMs, msg.LocalSpeakerID)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+		s.invalidateSessionSpeakersCache(msg.SessionID)
+
+		send(Message{
+			Type:           "speaker_range_reassigned",
+			SessionID:      msg.SessionID,
+			RangeStartMs:   msg.RangeStartMs,
+			RangeEndMs:     msg.RangeEndMs,
+			LocalSpeakerID: msg.LocalSpeakerID,
+			MergedCount:    updated,
+		})
+
+		// Обновляем сессию для всех клиентов
+		if updatedSess, err := s.SessionMgr.GetSession(msg.SessionID); err == nil {
+			s.broadcast(Message{Type: "session_details", Session: updatedSess})
+		}
+
+	case "merge_speakers":
+		// Объединение нескольких спикеров в одного
+		if msg.SessionID == "" {
+			send(Message{Type: "error", Data: "sessionId is required"})
+			return
+		}
+		if len(msg.SourceSpeakerIDs) < 2 {
+			send(Message{Type: "error", Data: "at least 2 speakers required for merge"})
+			return
+		}
+
+		log.Printf("merge_speakers: session=%s, sources=%v, target=%d, name=%s, mergeEmb=%v, saveVP=%v",
+			msg.SessionID[:8], msg.SourceSpeakerIDs, msg.TargetSpeakerID, msg.SpeakerName, msg.MergeEmbeddings, msg.SaveAsVoiceprint)
+
+		// Выполняем объединение в SessionMgr
+		mergedCount, err := s.SessionMgr.MergeSpeakers(msg.SessionID, msg.SourceSpeakerIDs, msg.TargetSpeakerID, msg.SpeakerName)
+		if err != nil {
+			send(Message{Type: "error", Data: err.Error()})
+			return
+		}
+
+		// Определяем финальное имя
+		finalName := msg.SpeakerName
+		if finalName == "" {
+			finalName = session.SpeakerLabel(msg.TargetSpeakerID)
+		}
+
+		// Опционально: объединяем embeddings в TranscriptionService
+		if msg.MergeEmbeddings && s.TranscriptionService != nil {
+			if err := s.TranscriptionService.MergeSpeakerProfiles(msg.SessionID, msg.SourceSpeakerIDs, msg.TargetSpeakerID); err != nil {
+				log.Printf("merge_speakers: failed to merge embeddings: %v", err)
+			} else {
+				log.Printf("merge_speakers: embeddings merged for session %s", msg.SessionID[:8])
+			}
+		}
+
+		// Опционально: сохраняем в глобальную базу voiceprints
+		var voiceprintID string
+		if msg.SaveAsVoiceprint && s.VoicePrintStore != nil {
+			embedding, source, err := s.getSpeakerEmbedding(msg.SessionID, msg.TargetSpeakerID)
+			if err != nil {
+				log.Printf("merge_speakers: failed to get embedding for voiceprint: %v", err)
+			} else if len(embedding) > 0 {
+				vp, err := s.VoicePrintStore.Add(finalName, embedding, source)
+				if err != nil {
+					log.Printf("merge_speakers: failed to save voiceprint: %v", err)
+				} else {
+					voiceprintID = vp.ID
+					log.Printf("merge_speakers: voiceprint saved: %s (%s)", vp.Name, vp.ID[:8])
+				}
+			}
+		}
+
+		// Инвалидируем кэш спикеров
+		s.invalidateSessionSpeakersCache(msg.SessionID)
 
 		// Отправляем ответ
 		send(Message{
@@ -1618,7 +2260,7 @@ func (s *Server) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 	// CORS headers for dev mode (Vite runs on different port)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Range")
 
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
@@ -1644,19 +2286,44 @@ func (s *Server) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// File serving logic
-	// Simplified validation
-	if len(path) < 36 {
-		http.NotFound(w, r)
+	sessionID, requestedFile, err := parseSessionPath(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionID := path[:36]
 	sess, err := s.SessionMgr.GetSession(sessionID)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
+	if requestedFile == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Аудио сессии в WAV PCM 16 бит (декодируется из full.mp3)
+	if requestedFile == "audio.wav" {
+		s.handleSessionAudioWAV(w, r, sess)
+		return
+	}
+
+	// Склеенный диалог сессии для клиентов без WebSocket
+	if requestedFile == "dialogue" {
+		s.handleSessionDialogue(w, r, sess)
+		return
+	}
 
-	requestedFile := path[37:]
+	// Манифест сессии; у старых сессий без manifest.json собирается на лету
+	if requestedFile == session.ManifestFile {
+		manifest, err := s.SessionMgr.GetSessionManifest(sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(manifest)
+		return
+	}
 
 	// Chunk MP3 extraction
 	if strings.HasPrefix(requestedFile, "chunk/") {
@@ -1677,6 +2344,18 @@ func (s *Server) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := session.RequireFFmpeg(); err != nil {
+			// Без FFmpeg вырезаем фрагмент на чистом Go и отдаём WAV
+			data, err := session.ExtractSegmentWAV(session.PlaybackAudioPath(sess.DataDir), targetChunk.StartMs, targetChunk.EndMs)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write(data)
+			return
+		}
+
 		mp3Path := filepath.Join(sess.DataDir, "full.mp3")
 		startSec := float64(targetChunk.StartMs) / 1000.0
 		endSec := float64(targetChunk.EndMs) / 1000.0
@@ -1705,6 +2384,23 @@ func (s *Server) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filePath)
 }
 
+// parseSessionPath разбирает путь вида "{sessionID}/{rest}" (без префикса эндпоинта)
+// Проверяет, что sessionID - корректный UUID в канонической форме.
+// Возвращает sessionID и оставшуюся часть пути (может быть пустой).
+func parseSessionPath(path string) (string, string, error) {
+	sessionID, rest, _ := strings.Cut(path, "/")
+	if sessionID == "" {
+		return "", "", fmt.Errorf("session ID is required")
+	}
+	if len(sessionID) != 36 {
+		return "", "", fmt.Errorf("invalid session ID %q: expected UUID", sessionID)
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return "", "", fmt.Errorf("invalid session ID %q: expected UUID", sessionID)
+	}
+	return sessionID, rest, nil
+}
+
 // handleWaveformAPI обрабатывает GET/POST запросы для кешированных waveform данных
 // GET /api/waveform/{sessionId} - получить кешированный waveform
 // POST /api/waveform/{sessionId} - сохранить waveform в кеш
@@ -1719,12 +2415,11 @@ func (s *Server) handleWaveformAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := r.URL.Path[len("/api/waveform/"):]
-	if len(path) < 36 {
-		http.NotFound(w, r)
+	sessionID, _, err := parseSessionPath(r.URL.Path[len("/api/waveform/"):])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionID := path[:36]
 
 	sess, err := s.SessionMgr.GetSession(sessionID)
 	if err != nil {
@@ -1797,54 +2492,137 @@ func (s *Server) handleImportAudio(w http.ResponseWriter, r *http.Request) {
 	if language == "" {
 		language = "ru"
 	}
-
-	// Проверяем расширение файла
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	supportedFormats := map[string]bool{".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".flac": true}
-	if !supportedFormats[ext] {
-		http.Error(w, "Unsupported audio format. Supported: mp3, wav, m4a, ogg, flac", http.StatusBadRequest)
+	language, switched, err := s.resolveModelLanguage(modelID, language, r.FormValue("forceLanguage") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	log.Printf("Import: received file %s (%d bytes), model=%s, language=%s",
 		header.Filename, header.Size, modelID, language)
 
+	imported, err := s.importAudio(file, header.Filename, language, modelID)
+	if err != nil {
+		log.Printf("Import: %v", err)
+		writeImportError(w, err)
+		return
+	}
+
+	// Запускаем полную транскрипцию в фоне
+	go s.transcribeImported(imported, modelID, language)
+
+	// Возвращаем информацию о созданной сессии
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"sessionId": imported.Session.ID,
+		"title":     imported.Title,
+		"duration":  imported.DurationMs,
+		"language":  language,
+		"switched":  switched,
+	})
+}
+
+// errUnsupportedImportFormat формат файла не поддерживается импортом
+var errUnsupportedImportFormat = errors.New("unsupported audio format. Supported: mp3, wav, m4a, ogg, flac")
+
+// importSupportedFormats расширения файлов, которые можно импортировать
+var importSupportedFormats = map[string]bool{".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".flac": true}
+
+// importedAudio сессия, созданная импортом файла и готовая к транскрипции
+type importedAudio struct {
+	Session    *session.Session
+	Title      string
+	DurationMs int64
+	WAVPath    string
+}
+
+// importAudio создаёт сессию из аудио файла: сохраняет его, конвертирует в full.wav
+// (16kHz моно для транскрипции) и full.mp3 (для воспроизведения) и уведомляет клиентов
+func (s *Server) importAudio(src io.Reader, filename, language, modelID string) (*importedAudio, error) {
+	// Проверяем расширение файла
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !importSupportedFormats[ext] {
+		return nil, fmt.Errorf("%s: %w", filename, errUnsupportedImportFormat)
+	}
+
+	// Без FFmpeg импортируются только MP3 и WAV (декодируются на чистом Go)
+	ffmpegErr := session.RequireFFmpeg()
+	if ffmpegErr != nil && ext != ".mp3" && ext != ".wav" {
+		return nil, ffmpegErr
+	}
+
 	// Создаём новую сессию для импорта (без активации)
 	sess, err := s.SessionMgr.CreateImportSession(session.SessionConfig{
 		Language: language,
 		Model:    modelID,
 	})
 	if err != nil {
-		log.Printf("Import: failed to create session: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	// Устанавливаем название из имени файла
-	title := strings.TrimSuffix(header.Filename, ext)
-	s.SessionMgr.SetSessionTitle(sess.ID, title)
+	durationMs, err := s.convertImportedAudio(sess, src, ext, ffmpegErr == nil)
+	if err != nil {
+		// Не оставляем пустую сессию в списке
+		s.SessionMgr.DeleteSession(sess.ID)
+		return nil, err
+	}
+
+	// Устанавливаем название из имени файла
+	title := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	s.SessionMgr.SetSessionTitle(sess.ID, title)
+
+	// Обновляем сессию
+	sess.TotalDuration = time.Duration(durationMs) * time.Millisecond
+	sess.Status = session.SessionStatusCompleted
+	s.SessionMgr.SaveSessionMeta(sess)
+
+	// Уведомляем клиентов о новой сессии
+	s.broadcast(Message{
+		Type:      "session_imported",
+		SessionID: sess.ID,
+		Session:   sess,
+	})
+
+	return &importedAudio{
+		Session:    sess,
+		Title:      title,
+		DurationMs: durationMs,
+		WAVPath:    filepath.Join(sess.DataDir, "full.wav"),
+	}, nil
+}
 
+// convertImportedAudio сохраняет импортируемый файл в сессию и конвертирует его
+// (через FFmpeg или на чистом Go). Возвращает длительность в мс
+func (s *Server) convertImportedAudio(sess *session.Session, src io.Reader, ext string, useFFmpeg bool) (int64, error) {
 	// Сохраняем файл во временную директорию
 	tempPath := filepath.Join(sess.DataDir, "import"+ext)
 	tempFile, err := os.Create(tempPath)
 	if err != nil {
-		log.Printf("Import: failed to create temp file: %v", err)
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("failed to save file: %w", err)
 	}
 
-	_, err = io.Copy(tempFile, file)
+	_, err = io.Copy(tempFile, src)
 	tempFile.Close()
 	if err != nil {
-		log.Printf("Import: failed to save file: %v", err)
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("failed to save file: %w", err)
 	}
+	// Удаляем временный файл
+	defer os.Remove(tempPath)
 
 	// Конвертируем в WAV если нужно
 	wavPath := filepath.Join(sess.DataDir, "full.wav")
 	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
 
+	if !useFFmpeg {
+		// FFmpeg недоступен - конвертируем на чистом Go
+		durationMs, err := session.ImportAudioGo(tempPath, wavPath, mp3Path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert audio: %w", err)
+		}
+		return durationMs, nil
+	}
+
 	// Используем ffmpeg для конвертации
 	ffmpegPath := session.GetFFmpegPath()
 
@@ -1857,133 +2635,139 @@ func (s *Server) handleImportAudio(w http.ResponseWriter, r *http.Request) {
 	)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		log.Printf("Import: ffmpeg WAV conversion failed: %v, output: %s", err, string(output))
-		http.Error(w, "Failed to convert audio", http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("failed to convert audio: %w", err)
 	}
 
 	// Конвертируем в MP3 для воспроизведения (сохраняем оригинальные каналы)
-	cmd = exec.Command(ffmpegPath,
-		"-i", tempPath,
-		"-codec:a", "libmp3lame",
-		"-qscale:a", "2",
-		"-y", mp3Path,
-	)
+	mp3Args := append([]string{"-i", tempPath}, session.MP3EncodeArgs(session.MP3TargetImport)...)
+	mp3Args = append(mp3Args, "-y", mp3Path)
+	cmd = exec.Command(ffmpegPath, mp3Args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		log.Printf("Import: ffmpeg MP3 conversion failed: %v, output: %s", err, string(output))
 		// Не критично, продолжаем
 	}
 
-	// Удаляем временный файл
-	os.Remove(tempPath)
-
 	// Получаем длительность
 	durationMs, err := s.getAudioDuration(wavPath)
 	if err != nil {
 		log.Printf("Import: failed to get duration: %v", err)
 		durationMs = 0
 	}
+	return durationMs, nil
+}
 
-	// Обновляем сессию
-	sess.TotalDuration = time.Duration(durationMs) * time.Millisecond
-	sess.Status = session.SessionStatusCompleted
-	s.SessionMgr.SaveSessionMeta(sess)
+// transcribeImported транскрибирует импортированную сессию одним чанком.
+// Пустые modelID/language - не переключать модель и язык (пакетный импорт задаёт их один раз)
+func (s *Server) transcribeImported(imported *importedAudio, modelID, language string) {
+	sess := imported.Session
+	sessionID := sess.ID
+	log.Printf("Import: starting transcription for session %s", sessionID)
+
+	// Update engine with specified model/language
+	if s.EngineMgr != nil {
+		if language != "" {
+			s.EngineMgr.SetLanguage(language)
+		}
+		if modelID != "" {
+			if err := s.EngineMgr.SetActiveModel(modelID); err != nil {
+				log.Printf("Import: failed to set model: %v", err)
+			}
+		}
+	}
 
-	// Уведомляем клиентов о новой сессии
+	// Уведомляем о начале транскрипции
 	s.broadcast(Message{
-		Type:      "session_imported",
-		SessionID: sess.ID,
-		Session:   sess,
+		Type:      "full_transcription_started",
+		SessionID: sessionID,
 	})
 
-	// Запускаем полную транскрипцию в фоне
-	go func() {
-		sessionID := sess.ID
-		log.Printf("Import: starting transcription for session %s", sessionID)
-
-		// Update engine with specified model/language
-		if s.EngineMgr != nil {
-			if language != "" {
-				s.EngineMgr.SetLanguage(language)
-			}
-			if modelID != "" {
-				if err := s.EngineMgr.SetActiveModel(modelID); err != nil {
-					log.Printf("Import: failed to set model: %v", err)
-				}
-			}
-		}
+	// Создаём один чанк для всего файла
+	chunk := &session.Chunk{
+		ID:        sessionID + "-0",
+		SessionID: sessionID,
+		Index:     0,
+		Duration:  sess.TotalDuration,
+		StartMs:   0,
+		EndMs:     imported.DurationMs,
+		Status:    session.ChunkStatusPending,
+		FilePath:  imported.WAVPath,
+		CreatedAt: time.Now(),
+	}
 
-		// Уведомляем о начале транскрипции
+	// Добавляем чанк в сессию
+	if err := s.SessionMgr.AddChunk(sessionID, chunk); err != nil {
+		log.Printf("Import: failed to add chunk: %v", err)
 		s.broadcast(Message{
-			Type:      "full_transcription_started",
+			Type:      "full_transcription_error",
 			SessionID: sessionID,
+			Error:     err.Error(),
 		})
+		return
+	}
 
-		// Создаём один чанк для всего файла
-		chunk := &session.Chunk{
-			ID:        sessionID + "-0",
-			SessionID: sessionID,
-			Index:     0,
-			Duration:  sess.TotalDuration,
-			StartMs:   0,
-			EndMs:     durationMs,
-			Status:    session.ChunkStatusPending,
-			FilePath:  wavPath,
-			CreatedAt: time.Now(),
-		}
-
-		// Добавляем чанк в сессию
-		if err := s.SessionMgr.AddChunk(sessionID, chunk); err != nil {
-			log.Printf("Import: failed to add chunk: %v", err)
-			s.broadcast(Message{
-				Type:      "full_transcription_error",
-				SessionID: sessionID,
-				Error:     err.Error(),
-			})
-			return
-		}
+	// Отправляем прогресс
+	s.broadcast(Message{
+		Type:      "full_transcription_progress",
+		SessionID: sessionID,
+		Progress:  0.1,
+		Data:      "Транскрипция аудио...",
+	})
 
-		// Отправляем прогресс
-		s.broadcast(Message{
-			Type:      "full_transcription_progress",
-			SessionID: sessionID,
-			Progress:  0.1,
-			Data:      "Транскрипция аудио...",
-		})
+	// Транскрибируем чанк с включённой диаризацией (если доступна)
+	// Для моно файлов это создаст сегментацию с таймкодами и определением спикеров
+	if s.TranscriptionService != nil {
+		s.TranscriptionService.HandleChunkSyncWithDiarization(chunk, true)
+	}
 
-		// Транскрибируем чанк с включённой диаризацией (если доступна)
-		// Для моно файлов это создаст сегментацию с таймкодами и определением спикеров
-		if s.TranscriptionService != nil {
-			s.TranscriptionService.HandleChunkSyncWithDiarization(chunk, true)
-		}
+	// Финальный прогресс
+	s.broadcast(Message{
+		Type:      "full_transcription_progress",
+		SessionID: sessionID,
+		Progress:  1.0,
+		Data:      "Завершение...",
+	})
 
-		// Финальный прогресс
-		s.broadcast(Message{
-			Type:      "full_transcription_progress",
-			SessionID: sessionID,
-			Progress:  1.0,
-			Data:      "Завершение...",
-		})
+	// Получаем обновлённую сессию
+	updatedSess, _ := s.SessionMgr.GetSession(sessionID)
 
-		// Получаем обновлённую сессию
-		updatedSess, _ := s.SessionMgr.GetSession(sessionID)
+	s.broadcast(Message{
+		Type:      "full_transcription_completed",
+		SessionID: sessionID,
+		Session:   updatedSess,
+	})
 
-		s.broadcast(Message{
-			Type:      "full_transcription_completed",
-			SessionID: sessionID,
-			Session:   updatedSess,
-		})
+	log.Printf("Import: transcription completed for session %s", sessionID)
+}
 
-		log.Printf("Import: transcription completed for session %s", sessionID)
-	}()
+// writeImportError отвечает ошибкой импорта с подходящим HTTP статусом
+func writeImportError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedImportFormat) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeHTTPError(w, err, http.StatusInternalServerError)
+}
 
-	// Возвращаем информацию о созданной сессии
+// errorMessage формирует сообщение об ошибке, добавляя код для известных причин
+func errorMessage(err error) Message {
+	msg := Message{Type: "error", Data: err.Error()}
+	if errors.Is(err, session.ErrFFmpegNotFound) {
+		msg.Code = ErrorCodeFFmpegNotFound
+	}
+	return msg
+}
+
+// writeHTTPError отвечает ошибкой. Для известных причин (нет FFmpeg) возвращает
+// 503 и JSON {"error", "code"}, чтобы клиент мог предложить установку
+func writeHTTPError(w http.ResponseWriter, err error, status int) {
+	msg := errorMessage(err)
+	if msg.Code == "" {
+		http.Error(w, err.Error(), status)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":   true,
-		"sessionId": sess.ID,
-		"title":     title,
-		"duration":  durationMs,
-	})
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg.Data, "code": msg.Code})
 }
 
 // getAudioDuration получает длительность аудио файла в миллисекундах
@@ -2145,20 +2929,21 @@ func (s *Server) computeSessionSpeakers(sess *session.Session, sessionID string)
 		displayName := speaker
 		normalizedKey := speaker
 
+		parsedID, isStandard := session.ParseSpeakerLabel(speaker)
 		switch {
-		case speaker == "mic" || speaker == "Вы":
+		case session.IsSelfSpeakerLabel(speaker):
 			isMic = true
 			localID = -1
-			displayName = "Вы"
+			displayName = session.SelfSpeakerLabel()
 			normalizedKey = "mic"
 
-		case speaker == "sys" || speaker == "Собеседник":
+		case session.IsPeerSpeakerLabel(speaker):
 			localID = 0
 			// Проверяем, есть ли кастомное имя для этого спикера
 			if name, ok := localIDToName[0]; ok {
 				displayName = name
 			} else {
-				displayName = "Собеседник 1"
+				displayName = session.SpeakerLabel(0)
 			}
 			normalizedKey = "speaker_0"
 
@@ -2170,14 +2955,12 @@ func (s *Server) computeSessionSpeakers(sess *session.Session, sessionID string)
 			if name, ok := localIDToName[num]; ok {
 				displayName = name
 			} else {
-				displayName = fmt.Sprintf("Собеседник %d", num+1)
+				displayName = session.SpeakerLabel(num)
 			}
 			normalizedKey = fmt.Sprintf("speaker_%d", num)
 
-		case strings.HasPrefix(speaker, "Собеседник "):
-			var num int
-			fmt.Sscanf(speaker, "Собеседник %d", &num)
-			localID = num - 1
+		case isStandard:
+			localID = parsedID
 			// Проверяем, есть ли кастомное имя для этого спикера
 			if name, ok := localIDToName[localID]; ok {
 				displayName = name
@@ -2271,11 +3054,22 @@ func (s *Server) computeSessionSpeakers(sess *session.Session, sessionID string)
 
 		sp.HasSample = sp.TotalDuration >= 2.0
 
+		// Стабильность считаем по embeddings профиля спикера
+		if !sp.IsMic {
+			for i := range profiles {
+				if profiles[i].SpeakerID == sp.LocalID {
+					sp.Stability, sp.StabilitySamples = profiles[i].Stability()
+					sp.LowStability = sp.StabilitySamples >= 2 && sp.Stability < voiceprint.StabilityLow
+					sp.Matches = profiles[i].Matches
+					sp.MinMatchSimilarity = profiles[i].MinMatchSimilarity()
+					break
+				}
+			}
+		}
+
 		// Проверяем, является ли текущее имя "стандартным" (не кастомным)
 		// Стандартные имена: "Собеседник", "Собеседник N", "Speaker N"
-		isStandardName := sp.DisplayName == "Собеседник" ||
-			strings.HasPrefix(sp.DisplayName, "Собеседник ") ||
-			strings.HasPrefix(sp.DisplayName, "Speaker ")
+		isStandardName := session.IsStandardSpeakerLabel(sp.DisplayName)
 
 		// Проверяем распознанное имя из профилей только если:
 		// 1. Это не mic (пользователь)
@@ -2306,26 +3100,195 @@ func (s *Server) computeSessionSpeakers(sess *session.Session, sessionID string)
 	return speakers
 }
 
+// speakerTimelineMergeGapMs максимальная пауза между интервалами одного спикера,
+// которая схлопывается в один интервал таймлайна (если в паузе не говорил никто другой)
+const speakerTimelineMergeGapMs = 1500
+
+// computeSpeakerTimeline строит таймлайн активности спикеров по всей сессии.
+// Использует тот же список спикеров, что и computeSessionSpeakers, но вместо сумм
+// сохраняет интервалы речи (для Gantt-представления и навигации по спикерам)
+func (s *Server) computeSpeakerTimeline(sess *session.Session, sessionID string) []SpeakerTimelineTrack {
+	speakers := s.getSessionSpeakers(sessionID)
+	if len(speakers) == 0 {
+		return nil
+	}
+
+	tracks := make([]SpeakerTimelineTrack, len(speakers))
+	byName := make(map[string]int)
+	byLocalID := make(map[int]int)
+	micIdx := -1
+	for i, sp := range speakers {
+		tracks[i] = SpeakerTimelineTrack{
+			LocalID:     sp.LocalID,
+			DisplayName: sp.DisplayName,
+			IsMic:       sp.IsMic,
+		}
+		byName[sp.DisplayName] = i
+		if sp.IsMic {
+			micIdx = i
+		} else {
+			byLocalID[sp.LocalID] = i
+		}
+	}
+
+	// Определяем дорожку по имени спикера в сегменте (те же форматы, что в computeSessionSpeakers)
+	resolveTrack := func(speaker string) int {
+		switch {
+		case session.IsSelfSpeakerLabel(speaker):
+			return micIdx
+		case session.IsPeerSpeakerLabel(speaker):
+			if idx, ok := byLocalID[0]; ok {
+				return idx
+			}
+		case strings.HasPrefix(speaker, "Speaker "):
+			var num int
+			if _, err := fmt.Sscanf(speaker, "Speaker %d", &num); err == nil {
+				if idx, ok := byLocalID[num]; ok {
+					return idx
+				}
+			}
+		}
+		if idx, ok := byName[speaker]; ok {
+			return idx
+		}
+		if localID, ok := session.ParseSpeakerLabel(speaker); ok {
+			if idx, ok := byLocalID[localID]; ok {
+				return idx
+			}
+		}
+		return -1
+	}
+
+	addSegment := func(speaker string, start, end int64) {
+		if speaker == "" || end <= start {
+			return
+		}
+		// Спикеры, отфильтрованные как "призрачные", в таймлайн не попадают
+		idx := resolveTrack(speaker)
+		if idx < 0 {
+			return
+		}
+		tracks[idx].Intervals = append(tracks[idx].Intervals, SpeakerTimelineInterval{Start: start, End: end})
+	}
+
+	for _, chunk := range sess.Chunks {
+		if len(chunk.Dialogue) > 0 {
+			for _, seg := range chunk.Dialogue {
+				addSegment(seg.Speaker, seg.Start, seg.End)
+			}
+		} else {
+			for _, seg := range chunk.MicSegments {
+				speaker := seg.Speaker
+				if speaker == "" {
+					speaker = "mic"
+				}
+				addSegment(speaker, seg.Start, seg.End)
+			}
+			for _, seg := range chunk.SysSegments {
+				speaker := seg.Speaker
+				if speaker == "" {
+					speaker = "sys"
+				}
+				addSegment(speaker, seg.Start, seg.End)
+			}
+		}
+	}
+
+	result := make([]SpeakerTimelineTrack, 0, len(tracks))
+	for i, track := range tracks {
+		if len(track.Intervals) == 0 {
+			continue
+		}
+		// Паузы, в которых говорили другие спикеры, не схлопываются:
+		// иначе реплики A-B-A дают перекрытие и завышают TotalDuration
+		var others []SpeakerTimelineInterval
+		for j := range tracks {
+			if j != i {
+				others = append(others, tracks[j].Intervals...)
+			}
+		}
+		track.Intervals = mergeTimelineIntervals(track.Intervals, speakerTimelineMergeGapMs, others)
+		var totalMs int64
+		for _, iv := range track.Intervals {
+			totalMs += iv.End - iv.Start
+		}
+		track.TotalDuration = float32(totalMs) / 1000.0
+		result = append(result, track)
+	}
+
+	// Сначала микрофон, затем собеседники по localID
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].IsMic != result[j].IsMic {
+			return result[i].IsMic
+		}
+		return result[i].LocalID < result[j].LocalID
+	})
+
+	return result
+}
+
+// mergeTimelineIntervals сортирует интервалы и объединяет пересекающиеся,
+// смежные и разделённые паузой не длиннее maxGapMs. Пауза не схлопывается,
+// если в ней есть речь из others (интервалы других спикеров)
+func mergeTimelineIntervals(intervals []SpeakerTimelineInterval, maxGapMs int64, others []SpeakerTimelineInterval) []SpeakerTimelineInterval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sorted := make([]SpeakerTimelineInterval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	// Речь других спикеров объединяем без пауз, чтобы проверять паузу бинарным поиском
+	var busy []SpeakerTimelineInterval
+	if len(others) > 0 {
+		busy = mergeTimelineIntervals(others, 0, nil)
+	}
+	interrupted := func(gapStart, gapEnd int64) bool {
+		k := sort.Search(len(busy), func(k int) bool { return busy[k].End > gapStart })
+		return k < len(busy) && busy[k].Start < gapEnd
+	}
+
+	merged := []SpeakerTimelineInterval{sorted[0]}
+	for _, iv := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		gap := iv.Start - last.End
+		if gap <= 0 || (gap <= maxGapMs && !interrupted(last.End, iv.Start)) {
+			if iv.End > last.End {
+				last.End = iv.End
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+
+	return merged
+}
+
 // renameSpeakerInSession переименовывает спикера во всех сегментах сессии
 func (s *Server) renameSpeakerInSession(sessionID string, localSpeakerID int, newName string) error {
 	// Определяем все возможные варианты старого имени по localSpeakerID
 	// Спикер может быть в разных форматах в зависимости от источника
 	var oldNames []string
+	standardName := standardSpeakerName(localSpeakerID)
+	currentName := standardName
 
 	if localSpeakerID < 0 {
-		oldNames = []string{"Вы", "mic"}
+		oldNames = session.SpeakerLabelVariants(-1)
 	} else {
 		// Собеседники могут быть в форматах:
 		// - "Speaker N" (из диаризации)
-		// - "Собеседник N+1" (после конвертации)
+		// - "Собеседник N+1" (после конвертации, в текущей или прежней схеме имён)
 		// - "sys" (если только один собеседник)
 		// - "Собеседник" (без номера)
-		oldNames = []string{
-			fmt.Sprintf("Speaker %d", localSpeakerID),
-			fmt.Sprintf("Собеседник %d", localSpeakerID+1),
-		}
+		oldNames = session.SpeakerLabelVariants(localSpeakerID)
 		if localSpeakerID == 0 {
-			oldNames = append(oldNames, "sys", "Собеседник")
+			oldNames = append(oldNames, "sys", session.PeerSpeakerLabel())
+			if prefix := session.DefaultSpeakerLabelScheme().Prefix; prefix != session.PeerSpeakerLabel() {
+				oldNames = append(oldNames, prefix)
+			}
 		}
 	}
 
@@ -2336,33 +3299,65 @@ func (s *Server) renameSpeakerInSession(sessionID string, localSpeakerID int, ne
 		if sp.LocalID == localSpeakerID && sp.DisplayName != "" && sp.DisplayName != newName {
 			// Добавляем текущее имя первым в список для поиска
 			oldNames = append([]string{sp.DisplayName}, oldNames...)
+			currentName = sp.DisplayName
 			log.Printf("renameSpeakerInSession: found current name '%s' for localID %d", sp.DisplayName, localSpeakerID)
 			break
 		}
 	}
 
-	// Пробуем переименовать каждый вариант
-	var lastErr error
-	renamed := false
-	for _, oldName := range oldNames {
-		err := s.SessionMgr.UpdateSpeakerName(sessionID, oldName, newName)
-		if err == nil {
-			renamed = true
-			log.Printf("Renamed speaker '%s' -> '%s' in session %s", oldName, newName, sessionID)
-		} else {
-			lastErr = err
+	// Переименовываем все варианты разом, запоминая затронутые сегменты
+	renamed, err := s.SessionMgr.RenameSpeakerLabels(sessionID, oldNames, newName)
+	if err != nil {
+		return err
+	}
+	log.Printf("Renamed speaker %v -> '%s' in session %s (%d segments)", oldNames, newName, sessionID, len(renamed))
+
+	// Запоминаем переименование в истории сессии для undo_speaker_rename: отмена вернёт
+	// прежние метки только этим сегментам, а не всем, кого зовут newName
+	if len(renamed) > 0 && currentName != newName {
+		var labels []string
+		for _, seg := range renamed {
+			if !slices.Contains(labels, seg.Label) {
+				labels = append(labels, seg.Label)
+			}
 		}
+		rename := session.SpeakerRename{LocalID: localSpeakerID, StandardName: standardName, OldName: currentName, NewName: newName,
+			Labels: labels, Segments: renamed}
+		if err := s.SessionMgr.RecordSpeakerRename(sessionID, rename); err != nil {
+			log.Printf("renameSpeakerInSession: failed to record rename history: %v", err)
+		}
+	}
+	return nil
+}
+
+// standardSpeakerName стандартное имя спикера по localID: "Вы" для микрофона, иначе "Собеседник N"
+func standardSpeakerName(localSpeakerID int) string {
+	if localSpeakerID < 0 {
+		return session.SelfSpeakerLabel()
 	}
+	return session.SpeakerLabel(localSpeakerID)
+}
 
-	if !renamed && lastErr != nil {
-		return lastErr
+// applySpeakerRenames применяет переименования спикеров, сохранённые до ретранскрипции
+func (s *Server) applySpeakerRenames(sessionID string, renames map[string]string) {
+	if len(renames) == 0 {
+		return
 	}
-	return nil
+	log.Printf("Full retranscription: applying %d cached speaker renames", len(renames))
+	for oldName, newName := range renames {
+		if err := s.SessionMgr.UpdateSpeakerName(sessionID, oldName, newName); err == nil {
+			log.Printf("Full retranscription: applied rename '%s' -> '%s'", oldName, newName)
+		}
+	}
+	// Инвалидируем кэш спикеров
+	s.invalidateSessionSpeakersCache(sessionID)
 }
 
 // getExistingSpeakerRenames возвращает map переименований спикеров в сессии
 // Ключ: стандартное имя ("Собеседник 1", "Собеседник 2", etc.)
 // Значение: пользовательское имя (если было переименовано)
+// Имена берутся из явных записей переименований сессии (speaker_renames.json),
+// поэтому результат не зависит от порядка обхода диалога
 func (s *Server) getExistingSpeakerRenames(sessionID string) map[string]string {
 	renames := make(map[string]string)
 
@@ -2371,61 +3366,22 @@ func (s *Server) getExistingSpeakerRenames(sessionID string) map[string]string {
 		return renames
 	}
 
-	// Стандартные имена, которые мы ищем
-	standardNames := map[string]bool{
-		"Собеседник":   true,
-		"Собеседник 1": true,
-		"Собеседник 2": true,
-		"Собеседник 3": true,
-		"Собеседник 4": true,
-		"Собеседник 5": true,
-	}
-
-	// Собираем все уникальные имена спикеров из диалогов
-	speakerNames := make(map[string]bool)
-	for _, chunk := range sess.Chunks {
-		for _, seg := range chunk.Dialogue {
-			if seg.Speaker != "" && seg.Speaker != "Вы" && seg.Speaker != "mic" {
-				speakerNames[seg.Speaker] = true
-			}
-		}
-	}
-
-	// Стратегия 1: Используем профили спикеров из TranscriptionService
-	// Профили содержат RecognizedName если спикер был распознан из voiceprint
+	// Профили спикеров содержат RecognizedName, если спикер был распознан из voiceprint
 	if s.TranscriptionService != nil {
 		profiles := s.TranscriptionService.GetSessionSpeakerProfiles(sessionID)
 		for _, profile := range profiles {
 			if profile.RecognizedName != "" {
-				// Спикер был распознан - это переименование
-				standardName := fmt.Sprintf("Собеседник %d", profile.SpeakerID)
+				standardName := session.SpeakerLabel(profile.SpeakerID - 1)
 				renames[standardName] = profile.RecognizedName
 				log.Printf("getExistingSpeakerRenames: from profile '%s' -> '%s'", standardName, profile.RecognizedName)
 			}
 		}
 	}
 
-	// Стратегия 2: Ищем пользовательские имена, которые заменили стандартные
-	// Если есть пользовательское имя и нет соответствующего стандартного,
-	// значит стандартное было переименовано
-	for name := range speakerNames {
-		if !standardNames[name] {
-			// Это пользовательское имя
-			// Пытаемся определить какой "Собеседник N" оно заменило
-			// Проверяем какие стандартные имена отсутствуют
-			for i := 1; i <= 5; i++ {
-				standardName := fmt.Sprintf("Собеседник %d", i)
-				if !speakerNames[standardName] {
-					// Стандартное имя отсутствует - возможно оно было переименовано
-					// Но только если у нас ещё нет переименования для этого стандартного имени
-					if _, exists := renames[standardName]; !exists {
-						renames[standardName] = name
-						log.Printf("getExistingSpeakerRenames: inferred '%s' -> '%s'", standardName, name)
-						break // Одно пользовательское имя может заменить только одно стандартное
-					}
-				}
-			}
-		}
+	// Явные переименования пользователя важнее автоматического распознавания
+	for standardName, name := range sess.CurrentSpeakerRenames() {
+		renames[standardName] = name
+		log.Printf("getExistingSpeakerRenames: recorded '%s' -> '%s'", standardName, name)
 	}
 
 	return renames
@@ -2450,51 +3406,213 @@ func (s *Server) applyExistingSpeakerRenames(sessionID string) {
 	s.invalidateSessionSpeakersCache(sessionID)
 }
 
-// updatePipelineTranscriber обновляет transcriber в Pipeline после смены модели
-// Это необходимо потому что Pipeline хранит ссылку на engine, который закрывается при смене модели
-func (s *Server) updatePipelineTranscriber() {
-	if s.TranscriptionService == nil || s.TranscriptionService.Pipeline == nil {
-		return
+// modelFallbackCandidates возвращает упорядоченный список запасных моделей.
+// Берётся явный список из запроса, затем из конфигурации, иначе - все скачанные
+// модели распознавания из реестра. Модели без поддержки языка пропускаются
+func (s *Server) modelFallbackCandidates(failedModelID, language string, requested []string) []string {
+	chain := requested
+	if len(chain) == 0 && s.Config != nil {
+		chain = s.Config.FallbackModels
 	}
-	if s.EngineMgr == nil {
-		return
+	if len(chain) == 0 {
+		for _, m := range models.Registry {
+			if m.IsTranscriptionModel() {
+				chain = append(chain, m.ID)
+			}
+		}
 	}
-	newEngine := s.EngineMgr.GetActiveEngine()
-	if newEngine == nil {
-		return
+
+	seen := map[string]bool{failedModelID: true}
+	var candidates []string
+	for _, id := range chain {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		info := models.GetModelByID(id)
+		if info == nil || !info.IsTranscriptionModel() || !info.SupportsLanguage(language) {
+			continue
+		}
+		if s.ModelMgr == nil || !s.ModelMgr.IsModelDownloaded(id) {
+			continue
+		}
+		candidates = append(candidates, id)
 	}
-	s.TranscriptionService.Pipeline.SetTranscriber(newEngine)
-	log.Printf("Pipeline transcriber updated to new engine")
+	return candidates
 }
 
-// handleBatchExport обрабатывает экспорт нескольких сессий в ZIP архив
-func (s *Server) handleBatchExport(w http.ResponseWriter, r *http.Request) {
-	// CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// activateFallbackModel пробует загрузить запасные модели по порядку после ошибки основной.
+// При успехе уведомляет клиента сообщением model_fallback и возвращает ID загруженной модели
+func (s *Server) activateFallbackModel(send sendFunc, failedModelID, language string, requested []string, loadErr error) (string, bool) {
+	for _, id := range s.modelFallbackCandidates(failedModelID, language, requested) {
+		modelName := id
+		if info := models.GetModelByID(id); info != nil {
+			modelName = info.Name
+		}
 
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
+		log.Printf("Model fallback: trying %s instead of %s", id, failedModelID)
+		send(Message{Type: "model_loading", ModelID: id, ModelName: modelName})
+
+		if err := s.EngineMgr.SetActiveModel(id); err != nil {
+			log.Printf("Model fallback: failed to load %s: %v", id, err)
+			send(Message{Type: "model_load_error", ModelID: id, ModelName: modelName, Error: err.Error()})
+			continue
+		}
+
+		send(Message{Type: "model_loaded", ModelID: id, ModelName: modelName})
+		send(Message{
+			Type:      "model_fallback",
+			ModelID:   id,
+			ModelName: modelName,
+			Data:      failedModelID,
+			Error:     loadErr.Error(),
+		})
+		log.Printf("Model fallback: using %s instead of %s", id, failedModelID)
+		return id, true
 	}
 
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	log.Printf("Model fallback: no usable fallback model for %s (language=%s)", failedModelID, language)
+	return "", false
+}
+
+// chunkVADMethodUsed фактически применённый к чанку метод VAD: один метод, если каналы совпадают,
+// иначе "mic/sys" (например "silero/energy"). Пусто, если VAD не применялся
+func chunkVADMethodUsed(chunk *session.Chunk) string {
+	mic, sys := chunk.MicVADMethod, chunk.SysVADMethod
+	switch {
+	case sys == "" || sys == mic:
+		return string(mic)
+	case mic == "":
+		return string(sys)
+	default:
+		return string(mic) + "/" + string(sys)
 	}
+}
 
-	// Парсим JSON body
-	var req struct {
-		SessionIDs []string `json:"sessionIds"`
-		Format     string   `json:"format"` // txt, srt, vtt, json, md
+// applyRegionMergeSettings применяет параметры склейки коротких регионов из сообщения, если они заданы.
+// Некорректные значения отклоняются без изменения текущих настроек
+func (s *Server) applyRegionMergeSettings(msg Message) error {
+	if s.TranscriptionService == nil || (msg.RegionMergeMinMs == nil && msg.RegionMergeMaxGapMs == nil) {
+		return nil
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	cfg := s.TranscriptionService.RegionMerge.WithOverrides(msg.RegionMergeMinMs, msg.RegionMergeMaxGapMs)
+	return s.TranscriptionService.SetRegionMergeConfig(cfg)
+}
+
+// channelVADConfigsFromMessage собирает настройки VAD каналов MIC и SYS из сообщения.
+// Параметры energy VAD (тишина, минимальная речь, hangover) общие для обоих каналов
+func channelVADConfigsFromMessage(msg Message) (mic, sys session.ChannelVADConfig) {
+	energy := session.EnergyVADConfig{
+		MinSilenceMs: msg.VADMinSilenceMs,
+		MinSpeechMs:  msg.VADMinSpeechMs,
+		HangoverMs:   msg.VADHangoverMs,
 	}
+	mic = session.ChannelVADConfig{Method: session.VADMethod(msg.MicVADMethod), Threshold: msg.MicVADThreshold, Energy: energy}
+	sys = session.ChannelVADConfig{Method: session.VADMethod(msg.SysVADMethod), Threshold: msg.SysVADThreshold, Energy: energy}
+	return mic, sys
+}
 
-	if len(req.SessionIDs) == 0 {
+// hybridConfigFromMessage собирает конфигурацию гибридной транскрипции из сообщения
+// с дефолтами для повторной транскрипции. Возвращает nil, если гибридный режим выключен
+func hybridConfigFromMessage(msg Message) *ai.HybridTranscriptionConfig {
+	if !msg.HybridEnabled || msg.HybridSecondaryModelID == "" {
+		return nil
+	}
+	hybridConfig := &ai.HybridTranscriptionConfig{
+		Enabled:             true,
+		SecondaryModelID:    msg.HybridSecondaryModelID,
+		ConfidenceThreshold: float32(msg.HybridConfidenceThreshold),
+		ContextWords:        msg.HybridContextWords,
+		UseLLMForMerge:      msg.HybridUseLLMForMerge,
+		Mode:                ai.HybridMode(msg.HybridMode),
+		OllamaModel:         msg.HybridOllamaModel,
+		OllamaURL:           msg.HybridOllamaURL,
+		Hotwords:            msg.HybridHotwords,
+		RegionMergeGapMs:    msg.HybridRegionMergeGapMs,
+		RegionPaddingMs:     msg.HybridRegionPaddingMs,
+	}
+	if hybridConfig.ConfidenceThreshold <= 0 {
+		hybridConfig.ConfidenceThreshold = 0.7
+	}
+	if hybridConfig.ContextWords <= 0 {
+		hybridConfig.ContextWords = 3
+	}
+	if hybridConfig.Mode == "" {
+		hybridConfig.Mode = ai.HybridModeFullCompare
+	}
+	// Дефолты для Ollama
+	if hybridConfig.OllamaModel == "" {
+		hybridConfig.OllamaModel = msg.OllamaModel
+	}
+	if hybridConfig.OllamaURL == "" {
+		hybridConfig.OllamaURL = msg.OllamaUrl
+	}
+	return hybridConfig
+}
+
+// engineCapabilities возвращает возможности активной модели для клиента
+func (s *Server) engineCapabilities() *ai.EngineCapabilities {
+	if s.EngineMgr == nil {
+		return nil
+	}
+	return s.EngineMgr.Capabilities()
+}
+
+// updatePipelineTranscriber подключает Pipeline к управляемому движку EngineManager.
+// Управляемый движок сам следует за сменой модели и выгрузкой по простою,
+// поэтому Pipeline никогда не держит закрытый движок
+func (s *Server) updatePipelineTranscriber() {
+	if s.TranscriptionService == nil || s.TranscriptionService.Pipeline == nil {
+		return
+	}
+	if s.EngineMgr == nil {
+		return
+	}
+	s.TranscriptionService.Pipeline.SetTranscriber(s.EngineMgr.Engine())
+	log.Printf("Pipeline transcriber updated to new engine")
+}
+
+// handleBatchExport обрабатывает экспорт нескольких сессий в ZIP архив
+func (s *Server) handleBatchExport(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Парсим JSON body
+	var req struct {
+		SessionIDs     []string `json:"sessionIds"`
+		Format         string   `json:"format"`         // txt, srt, vtt, ass, json, md, rttm, words-csv, words-json
+		LabelLanguage  string   `json:"labelLanguage"`  // Язык подписей спикеров: ru (по умолчанию), en
+		PerSpeaker     bool     `json:"perSpeaker"`     // Отдельный файл на каждого спикера вместо файла на сессию
+		SplitSentences bool     `json:"splitSentences"` // Разбить реплики на отдельные предложения
+		DialogueLayer  string   `json:"dialogueLayer"`  // Вариант диалога (raw, improved, ...), пусто - показанный в сессии
+
+		SpeakerLabels     *SpeakerLabelConfig `json:"speakerLabels"`     // Шаблон подписей спикеров вместо стандартных
+		MergeSpeakerTurns bool                `json:"mergeSpeakerTurns"` // TXT: склеить подряд идущие сегменты одного спикера
+		MaxLineChars      int                 `json:"maxLineChars"`      // SRT/VTT: длина строки субтитров (0 - 42)
+
+		CompressionLevel *int `json:"compressionLevel"` // Уровень сжатия ZIP: 0 - без сжатия, 1-9; по умолчанию - стандартный
+		IncludeAudio     bool `json:"includeAudio"`     // Добавить запись сессии (full.mp3) рядом с текстом
+		MaxSizeMB        int  `json:"maxSizeMb"`        // Лимит размера файлов архива (не больше серверного)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.SessionIDs) == 0 {
 		http.Error(w, "No sessions specified", http.StatusBadRequest)
 		return
 	}
@@ -2502,12 +3620,33 @@ func (s *Server) handleBatchExport(w http.ResponseWriter, r *http.Request) {
 	if req.Format == "" {
 		req.Format = "txt"
 	}
+	if err := validateZipCompressionLevel(req.CompressionLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateSubtitleLineChars(req.MaxLineChars); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SpeakerLabels != nil {
+		if err := req.SpeakerLabels.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	maxBytes := s.exportSizeLimit(req.MaxSizeMB)
+
+	log.Printf("Batch export: %d sessions, format=%s, perSpeaker=%v, splitSentences=%v, dialogueLayer=%q, includeAudio=%v, maxBytes=%d",
+		len(req.SessionIDs), req.Format, req.PerSpeaker, req.SplitSentences, req.DialogueLayer, req.IncludeAudio, maxBytes)
 
-	log.Printf("Batch export: %d sessions, format=%s", len(req.SessionIDs), req.Format)
+	// Заголовки отправляем сразу: ZIP пишется потоком прямо в ответ,
+	// чтобы не держать весь архив в памяти и начать загрузку раньше
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"aiwisper-export-%s.zip\"", time.Now().Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
 
-	// Создаём ZIP архив в памяти
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
+	flusher, _ := w.(http.Flusher)
+	archive := newExportArchive(w, req.CompressionLevel, maxBytes)
 
 	for _, sessionID := range req.SessionIDs {
 		sess, err := s.SessionMgr.GetSession(sessionID)
@@ -2516,55 +3655,193 @@ func (s *Server) handleBatchExport(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// Генерируем контент в нужном формате
-		content, ext := s.generateExportContent(sess, req.Format)
-		if content == "" {
+		dialogue, err := s.exportLayerDialogue(sess, req.DialogueLayer, req.SplitSentences)
+		if err != nil {
+			log.Printf("Batch export: session %s: %v", sessionID, err)
 			continue
 		}
 
-		// Формируем имя файла
-		filename := s.generateExportFilename(sess, ext)
-
-		// Добавляем файл в ZIP
-		fileWriter, err := zipWriter.Create(filename)
-		if err != nil {
-			log.Printf("Batch export: failed to create zip entry: %v", err)
-			continue
+		labels := newExportLabels(req.LabelLanguage).withSpeakerLabels(req.SpeakerLabels)
+		labels.mergeTurns = req.MergeSpeakerTurns
+		labels.maxLineChars = req.MaxLineChars
+		var files []exportFile
+		if req.PerSpeaker {
+			files = s.generateSpeakerExports(sess, dialogue, req.Format, labels)
+		} else if content, ext := s.exportDialogue(sess, dialogue, req.Format, labels); content != "" {
+			files = []exportFile{{name: s.generateExportFilename(sess, ext), content: content}}
+		}
+
+		for _, file := range files {
+			// Добавляем файл в ZIP. Заголовки уже отправлены, поэтому при ошибке записи
+			// прерываем соединение - клиент получит оборванную загрузку, а не битый архив со статусом 200
+			if added, err := archive.addText(file.name, file.content); err != nil {
+				log.Printf("Batch export: %v, aborting", err)
+				panic(http.ErrAbortHandler)
+			} else if !added {
+				log.Printf("Batch export: %s skipped, size limit reached", file.name)
+			}
+		}
+		if req.IncludeAudio {
+			s.addExportAudio(archive, sess)
+		}
+		if flusher != nil {
+			flusher.Flush()
 		}
-		fileWriter.Write([]byte(content))
 	}
 
-	if err := zipWriter.Close(); err != nil {
-		http.Error(w, "Failed to create ZIP", http.StatusInternalServerError)
+	if err := archive.close(); err != nil {
+		log.Printf("Batch export: failed to finalize ZIP: %v, aborting", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportSizeLimit лимит размера архива в байтах: из запроса, но не больше серверного (0 - без ограничения)
+func (s *Server) exportSizeLimit(requestMB int) int64 {
+	limitMB := 0
+	if s.Config != nil {
+		limitMB = max(0, s.Config.ExportMaxSizeMB)
+	}
+	if requestMB > 0 && (limitMB == 0 || requestMB < limitMB) {
+		limitMB = requestMB
+	}
+	return int64(limitMB) << 20
+}
+
+// addExportAudio добавляет запись сессии (full.mp3) в архив под именем сессии.
+// Сессия без записи пропускается, ошибка записи в архив обрывает ответ
+func (s *Server) addExportAudio(archive *exportArchive, sess *session.Session) {
+	f, err := os.Open(filepath.Join(sess.DataDir, "full.mp3"))
+	if err != nil {
+		log.Printf("Batch export: session %s has no audio: %v", sess.ID, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		log.Printf("Batch export: session %s: %v", sess.ID, err)
 		return
 	}
 
-	// Отправляем ZIP
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"aiwisper-export-%s.zip\"", time.Now().Format("2006-01-02")))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
-	w.Write(buf.Bytes())
+	name := s.generateExportFilename(sess, "mp3")
+	if added, err := archive.addFile(name, f, info.Size()); err != nil {
+		log.Printf("Batch export: %v, aborting", err)
+		panic(http.ErrAbortHandler)
+	} else if !added {
+		log.Printf("Batch export: %s skipped, size limit reached", name)
+	}
+}
+
+// exportFile файл в архиве пакетного экспорта
+type exportFile struct {
+	name    string
+	content string
 }
 
 // generateExportFilename генерирует имя файла для экспорта
 func (s *Server) generateExportFilename(sess *session.Session, ext string) string {
-	title := sess.Title
-	if title == "" {
-		title = sess.StartTime.Format("2006-01-02_15-04")
+	return sanitizeExportFilename(exportTitle(sess)) + "." + ext
+}
+
+// exportTitle название сессии для имени файла, без названия - дата начала
+func exportTitle(sess *session.Session) string {
+	if sess.Title == "" {
+		return sess.StartTime.Format("2006-01-02_15-04")
 	}
-	// Очищаем имя от недопустимых символов
-	title = strings.Map(func(r rune) rune {
+	return sess.Title
+}
+
+// sanitizeExportFilename заменяет недопустимые в именах файлов символы на "_"
+func sanitizeExportFilename(name string) string {
+	return strings.Map(func(r rune) rune {
 		if r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|' {
 			return '_'
 		}
 		return r
-	}, title)
-	return fmt.Sprintf("%s.%s", title, ext)
+	}, name)
 }
 
-// generateExportContent генерирует контент для экспорта в указанном формате
-func (s *Server) generateExportContent(sess *session.Session, format string) (string, string) {
-	// Собираем диалог из всех чанков
+// generateSpeakerExports генерирует по файлу на каждого спикера сессии (только его реплики).
+// Спикеры группируются по отображаемому имени, спикеры без текста пропускаются
+func (s *Server) generateSpeakerExports(sess *session.Session, dialogue []session.TranscriptSegment, format string, labels exportLabels) []exportFile {
+	var order []string
+	bySpeaker := make(map[string][]session.TranscriptSegment)
+	for _, seg := range dialogue {
+		if strings.TrimSpace(seg.Text) == "" {
+			continue
+		}
+		name := labels.speaker(seg.Speaker)
+		if _, ok := bySpeaker[name]; !ok {
+			order = append(order, name)
+		}
+		bySpeaker[name] = append(bySpeaker[name], seg)
+	}
+
+	files := make([]exportFile, 0, len(order))
+	for _, name := range order {
+		content, ext := s.exportDialogue(sess, bySpeaker[name], format, labels)
+		if content == "" {
+			continue
+		}
+		files = append(files, exportFile{
+			name:    sanitizeExportFilename(exportTitle(sess)+" - "+name) + "." + ext,
+			content: content,
+		})
+	}
+	return files
+}
+
+// exportLayerDialogue диалог сессии для экспорта из слоя layer (пусто - показанный в сессии)
+func (s *Server) exportLayerDialogue(sess *session.Session, layer string, splitSentences bool) ([]session.TranscriptSegment, error) {
+	if layer == "" {
+		return exportSessionDialogue(sess, splitSentences), nil
+	}
+	dialogue, err := s.SessionMgr.GetDialogueLayer(sess.ID, layer)
+	if err != nil {
+		return nil, err
+	}
+	if splitSentences {
+		dialogue = service.SplitSegmentsBySentence(dialogue)
+	}
+	return dialogue, nil
+}
+
+// exportSessionDialogue диалог сессии для экспорта, при splitSentences - по одному предложению на сегмент
+func exportSessionDialogue(sess *session.Session, splitSentences bool) []session.TranscriptSegment {
+	dialogue := collectSessionDialogue(sess)
+	if splitSentences {
+		dialogue = service.SplitSegmentsBySentence(dialogue)
+	}
+	return dialogue
+}
+
+// exportDialogue форматирует переданный диалог сессии в указанном формате
+func (s *Server) exportDialogue(sess *session.Session, dialogue []session.TranscriptSegment, format string, labels exportLabels) (string, string) {
+	switch format {
+	case "txt":
+		return s.exportToTXT(sess, dialogue, labels), "txt"
+	case "srt":
+		return s.exportToSRT(dialogue, labels), "srt"
+	case "vtt":
+		return s.exportToVTT(dialogue, labels), "vtt"
+	case "ass":
+		return s.exportToASS(sess, dialogue, labels), "ass"
+	case "json":
+		return s.exportToJSON(sess, dialogue), "json"
+	case "md":
+		return s.exportToMarkdown(sess, dialogue, labels), "md"
+	case "rttm":
+		return exportToRTTM(sess.ID, dialogue), "rttm"
+	case "words-csv":
+		return exportToWordsCSV(dialogue, labels), "words.csv"
+	case "words-json":
+		return exportToWordsJSON(sess, dialogue, labels), "words.json"
+	default:
+		return s.exportToTXT(sess, dialogue, labels), "txt"
+	}
+}
+
+// collectSessionDialogue собирает диалог из всех завершённых чанков, отсортированный по времени
+func collectSessionDialogue(sess *session.Session) []session.TranscriptSegment {
 	var dialogue []session.TranscriptSegment
 	for _, chunk := range sess.Chunks {
 		if chunk.Status != session.ChunkStatusCompleted {
@@ -2582,83 +3859,346 @@ func (s *Server) generateExportContent(sess *session.Session, format string) (st
 	sort.Slice(dialogue, func(i, j int) bool {
 		return dialogue[i].Start < dialogue[j].Start
 	})
+	return dialogue
+}
 
-	switch format {
-	case "txt":
-		return s.exportToTXT(sess, dialogue), "txt"
-	case "srt":
-		return s.exportToSRT(dialogue), "srt"
-	case "vtt":
-		return s.exportToVTT(dialogue), "vtt"
-	case "json":
-		return s.exportToJSON(sess, dialogue), "json"
-	case "md":
-		return s.exportToMarkdown(sess, dialogue), "md"
-	default:
-		return s.exportToTXT(sess, dialogue), "txt"
+// handleDiarizationEvalAPI сравнивает диаризацию сессии с эталонным RTTM и возвращает DER
+// URL: POST /api/diarization-eval/{sessionID}?collar=0.25
+// Тело: RTTM текстом или multipart-поле "rttm"
+func (s *Server) handleDiarizationEvalAPI(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, rest, err := parseSessionPath(strings.TrimPrefix(r.URL.Path, "/api/diarization-eval/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rest != "" {
+		http.Error(w, "Invalid path. Expected: /api/diarization-eval/{sessionID}", http.StatusBadRequest)
+		return
+	}
+
+	collar := service.DefaultDERCollar
+	if v := r.URL.Query().Get("collar"); v != "" {
+		collar, err = strconv.ParseFloat(v, 64)
+		if err != nil || collar < 0 || collar > 5 {
+			http.Error(w, "Invalid collar: expected seconds between 0 and 5", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
 	}
+
+	// Эталон: multipart файл или тело запроса (не более 10MB)
+	body := io.Reader(http.MaxBytesReader(w, r.Body, 10<<20))
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("rttm")
+		if err != nil {
+			http.Error(w, "Missing rttm file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	reference, err := service.ParseRTTM(body)
+	if err != nil {
+		http.Error(w, "Invalid RTTM: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(reference) == 0 {
+		http.Error(w, "Reference RTTM has no SPEAKER segments", http.StatusBadRequest)
+		return
+	}
+
+	// Гипотеза: разметка спикеров сессии (те же имена, что в RTTM экспорте)
+	var hypothesis []service.RTTMSegment
+	for _, seg := range collectSessionDialogue(sess) {
+		if seg.End <= seg.Start {
+			continue
+		}
+		hypothesis = append(hypothesis, service.RTTMSegment{
+			Speaker: rttmSpeakerName(seg.Speaker),
+			Start:   float64(seg.Start) / 1000,
+			End:     float64(seg.End) / 1000,
+		})
+	}
+
+	result := service.EvaluateDiarization(reference, hypothesis, collar)
+	log.Printf("Diarization eval: session=%s, collar=%.2fs, DER=%.3f (miss=%.3f, fa=%.3f, conf=%.3f)",
+		sessionID, collar, result.DER, result.MissedRate, result.FalseAlarmRate, result.ConfusionRate)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
 // exportToTXT экспортирует в текстовый формат
-func (s *Server) exportToTXT(sess *session.Session, dialogue []session.TranscriptSegment) string {
+func (s *Server) exportToTXT(sess *session.Session, dialogue []session.TranscriptSegment, labels exportLabels) string {
 	var sb strings.Builder
 
 	// Заголовок
 	title := sess.Title
 	if title == "" {
-		title = "Запись " + sess.StartTime.Format("02.01.2006 15:04")
+		title = labels.untitled(sess.StartTime)
 	}
 	sb.WriteString(title + "\n")
 	sb.WriteString(strings.Repeat("=", len(title)) + "\n\n")
 
 	// Диалог
+	if labels.mergeTurns {
+		dialogue = mergeSpeakerTurns(dialogue, labels)
+	}
 	for _, seg := range dialogue {
-		speaker := formatSpeakerName(seg.Speaker)
+		speaker := labels.speaker(seg.Speaker)
 		timeStr := formatTimestamp(seg.Start)
+		// Наложение речи: собеседник говорил одновременно с другим
+		if seg.Overlap {
+			speaker += " [overlap]"
+		}
 		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", timeStr, speaker, seg.Text))
 	}
 
 	return sb.String()
 }
 
+// mergeSpeakerTurns склеивает подряд идущие сегменты одного спикера (по подписи) в одну реплику:
+// время начала - первого сегмента, текст через пробел. Начала реплик не убывают,
+// даже если сегменты соседних чанков перекрываются
+func mergeSpeakerTurns(dialogue []session.TranscriptSegment, labels exportLabels) []session.TranscriptSegment {
+	var merged []session.TranscriptSegment
+	lastSpeaker := ""
+	for _, seg := range dialogue {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		speaker := labels.speaker(seg.Speaker)
+		if n := len(merged); n > 0 && speaker == lastSpeaker {
+			turn := &merged[n-1]
+			turn.Text += " " + text
+			turn.End = max(turn.End, seg.End)
+			turn.Words = nil
+			if seg.Overlap && !turn.Overlap {
+				turn.Overlap, turn.OverlapSpeaker = true, seg.OverlapSpeaker
+			}
+			continue
+		}
+
+		seg.Text = text
+		seg.Words = nil
+		if n := len(merged); n > 0 && seg.Start < merged[n-1].Start {
+			seg.Start = merged[n-1].Start
+		}
+		merged = append(merged, seg)
+		lastSpeaker = speaker
+	}
+	return merged
+}
+
 // exportToSRT экспортирует в формат субтитров SRT
-func (s *Server) exportToSRT(dialogue []session.TranscriptSegment) string {
+func (s *Server) exportToSRT(dialogue []session.TranscriptSegment, labels exportLabels) string {
 	var sb strings.Builder
 
-	for i, seg := range dialogue {
-		sb.WriteString(fmt.Sprintf("%d\n", i+1))
-		sb.WriteString(fmt.Sprintf("%s --> %s\n", formatSRTTime(seg.Start), formatSRTTime(seg.End)))
-		speaker := formatSpeakerName(seg.Speaker)
-		sb.WriteString(fmt.Sprintf("%s: %s\n\n", speaker, seg.Text))
+	// Длинные реплики переносятся на две строки и делятся на несколько субтитров
+	index := 0
+	for _, seg := range dialogue {
+		speaker := labels.speaker(seg.Speaker)
+		for _, cue := range splitSubtitleCues(seg, speaker+": ", labels.maxLineChars) {
+			index++
+			sb.WriteString(fmt.Sprintf("%d\n", index))
+			sb.WriteString(fmt.Sprintf("%s --> %s\n", formatSRTTime(cue.Start), formatSRTTime(cue.End)))
+			sb.WriteString(strings.Join(cue.Lines, "\n") + "\n\n")
+		}
 	}
 
 	return sb.String()
 }
 
 // exportToVTT экспортирует в формат WebVTT
-func (s *Server) exportToVTT(dialogue []session.TranscriptSegment) string {
+func (s *Server) exportToVTT(dialogue []session.TranscriptSegment, labels exportLabels) string {
 	var sb strings.Builder
 
 	sb.WriteString("WEBVTT\n\n")
 
-	for i, seg := range dialogue {
-		sb.WriteString(fmt.Sprintf("%d\n", i+1))
-		sb.WriteString(fmt.Sprintf("%s --> %s\n", formatVTTTime(seg.Start), formatVTTTime(seg.End)))
-		speaker := formatSpeakerName(seg.Speaker)
-		sb.WriteString(fmt.Sprintf("<v %s>%s\n\n", speaker, seg.Text))
+	index := 0
+	for _, seg := range dialogue {
+		speaker := labels.speaker(seg.Speaker)
+		for _, cue := range splitSubtitleCues(seg, "", labels.maxLineChars) {
+			index++
+			sb.WriteString(fmt.Sprintf("%d\n", index))
+			sb.WriteString(fmt.Sprintf("%s --> %s\n", formatVTTTime(cue.Start), formatVTTTime(cue.End)))
+			sb.WriteString(fmt.Sprintf("<v %s>%s\n\n", speaker, strings.Join(cue.Lines, "\n")))
+		}
 	}
 
 	return sb.String()
 }
 
+// jsonExportFormatVersion версия схемы JSON экспорта (format_version).
+// 2 - у каждого сегмента всегда есть words и confidence
+const jsonExportFormatVersion = 2
+
+// jsonExportSegment сегмент JSON экспорта со словами для интерактивных плееров
+type jsonExportSegment struct {
+	Start          int64                    `json:"start"` // Миллисекунды от начала записи
+	End            int64                    `json:"end"`
+	Text           string                   `json:"text"`
+	Speaker        string                   `json:"speaker"`
+	Words          []session.TranscriptWord `json:"words"`                    // Пустой массив, если модель не выдала слов
+	Confidence     *float64                 `json:"confidence,omitempty"`     // Средняя вероятность слов (нет слов с P - не задана)
+	Overlap        bool                     `json:"overlap,omitempty"`        // Наложение речи
+	OverlapSpeaker string                   `json:"overlapSpeaker,omitempty"` // Второй спикер при наложении речи
+}
+
+// newJSONExportSegment переносит сегмент в JSON экспорт
+func newJSONExportSegment(seg session.TranscriptSegment) jsonExportSegment {
+	out := jsonExportSegment{
+		Start:   seg.Start,
+		End:     seg.End,
+		Text:    seg.Text,
+		Speaker: seg.Speaker,
+		Words:   seg.Words,
+
+		Overlap:        seg.Overlap,
+		OverlapSpeaker: seg.OverlapSpeaker,
+	}
+	if out.Words == nil {
+		out.Words = []session.TranscriptWord{}
+	}
+
+	if confidence := session.SegmentConfidence(seg.Words); confidence != session.NoConfidence {
+		value := float64(confidence)
+		out.Confidence = &value
+	}
+	return out
+}
+
+// exportToASS экспортирует в формат субтитров ASS (Advanced SubStation Alpha) для наложения на видео.
+// У каждого спикера свой стиль с цветом из хеша имени; перекрывающиеся реплики (mic и sys)
+// остаются отдельными строками Dialogue - плеер выводит их одновременно друг над другом
+func (s *Server) exportToASS(sess *session.Session, dialogue []session.TranscriptSegment, labels exportLabels) string {
+	var sb strings.Builder
+
+	title := sess.Title
+	if title == "" {
+		title = labels.untitled(sess.StartTime)
+	}
+	sb.WriteString("[Script Info]\n")
+	sb.WriteString("ScriptType: v4.00+\n")
+	sb.WriteString("Title: " + assText(title) + "\n")
+	sb.WriteString("PlayResX: 1920\nPlayResY: 1080\nWrapStyle: 0\nScaledBorderAndShadow: yes\n\n")
+
+	// Стили в порядке первого появления спикера
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, seg := range dialogue {
+		if name := assName(labels.speaker(seg.Speaker)); strings.TrimSpace(seg.Text) != "" && !seen[name] {
+			seen[name] = true
+			speakers = append(speakers, name)
+		}
+	}
+	sb.WriteString("[V4+ Styles]\n")
+	sb.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	for _, name := range speakers {
+		sb.WriteString(fmt.Sprintf("Style: %s,Arial,48,%s,&H000000FF,&H00000000,&H64000000,0,0,0,0,100,100,0,0,1,2,1,2,40,40,40,1\n", name, assSpeakerColour(name)))
+	}
+
+	sb.WriteString("\n[Events]\n")
+	sb.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, seg := range dialogue {
+		if strings.TrimSpace(seg.Text) == "" {
+			continue
+		}
+		name := assName(labels.speaker(seg.Speaker))
+		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
+			formatASSTime(seg.Start), formatASSTime(seg.End), name, name, assText(strings.TrimSpace(seg.Text))))
+	}
+
+	return sb.String()
+}
+
+// assName имя стиля ASS: запятая разделяет поля, поэтому заменяется
+func assName(speaker string) string {
+	name := strings.TrimSpace(strings.ReplaceAll(speaker, ",", " "))
+	if name == "" {
+		return "Default"
+	}
+	return name
+}
+
+// assText текст реплики ASS: фигурные скобки - блоки тегов, перевод строки - \N
+func assText(text string) string {
+	return strings.NewReplacer("{", "(", "}", ")", "\r\n", "\\N", "\n", "\\N").Replace(text)
+}
+
+// assSpeakerColour цвет спикера в формате ASS (&H00BBGGRR): оттенок из FNV-хеша имени,
+// насыщенный и светлый, чтобы читаться поверх видео
+func assSpeakerColour(speaker string) string {
+	h := fnv.New32a()
+	h.Write([]byte(speaker))
+	hue := float64(h.Sum32()%360) / 60
+
+	// HSV -> RGB при S=0.65, V=1
+	const v, c = 1.0, 0.65
+	x := c * (1 - math.Abs(math.Mod(hue, 2)-1))
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return fmt.Sprintf("&H00%02X%02X%02X", int(math.Round((b+m)*255)), int(math.Round((g+m)*255)), int(math.Round((r+m)*255)))
+}
+
+// formatASSTime форматирует время для ASS (H:MM:SS.cc)
+func formatASSTime(ms int64) string {
+	h := ms / 3600000
+	m := (ms % 3600000) / 60000
+	s := (ms % 60000) / 1000
+	cs := (ms % 1000) / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", h, m, s, cs)
+}
+
 // exportToJSON экспортирует в формат JSON
 func (s *Server) exportToJSON(sess *session.Session, dialogue []session.TranscriptSegment) string {
+	segments := make([]jsonExportSegment, len(dialogue))
+	for i, seg := range dialogue {
+		segments[i] = newJSONExportSegment(seg)
+	}
 	export := map[string]interface{}{
-		"id":        sess.ID,
-		"title":     sess.Title,
-		"startTime": sess.StartTime,
-		"duration":  sess.TotalDuration / time.Millisecond,
-		"dialogue":  dialogue,
+		"format_version": jsonExportFormatVersion,
+		"id":             sess.ID,
+		"title":          sess.Title,
+		"startTime":      sess.StartTime,
+		"duration":       sess.TotalDuration / time.Millisecond,
+		"dialogue":       segments,
 	}
 
 	data, err := json.MarshalIndent(export, "", "  ")
@@ -2669,22 +4209,22 @@ func (s *Server) exportToJSON(sess *session.Session, dialogue []session.Transcri
 }
 
 // exportToMarkdown экспортирует в формат Markdown
-func (s *Server) exportToMarkdown(sess *session.Session, dialogue []session.TranscriptSegment) string {
+func (s *Server) exportToMarkdown(sess *session.Session, dialogue []session.TranscriptSegment, labels exportLabels) string {
 	var sb strings.Builder
 
 	// Заголовок
 	title := sess.Title
 	if title == "" {
-		title = "Запись " + sess.StartTime.Format("02.01.2006 15:04")
+		title = labels.untitled(sess.StartTime)
 	}
 	sb.WriteString(fmt.Sprintf("# %s\n\n", title))
-	sb.WriteString(fmt.Sprintf("**Дата:** %s\n\n", sess.StartTime.Format("02.01.2006 15:04")))
+	sb.WriteString(fmt.Sprintf("**%s:** %s\n\n", labels.date(), labels.formatDate(sess.StartTime)))
 	sb.WriteString("---\n\n")
 
 	// Диалог
 	var currentSpeaker string
 	for _, seg := range dialogue {
-		speaker := formatSpeakerName(seg.Speaker)
+		speaker := labels.speaker(seg.Speaker)
 		if speaker != currentSpeaker {
 			if currentSpeaker != "" {
 				sb.WriteString("\n")
@@ -2698,20 +4238,156 @@ func (s *Server) exportToMarkdown(sess *session.Session, dialogue []session.Tran
 	return sb.String()
 }
 
-// formatSpeakerName форматирует имя спикера
-func formatSpeakerName(speaker string) string {
-	switch speaker {
-	case "mic":
-		return "Вы"
-	case "sys":
-		return "Собеседник"
-	default:
-		if strings.HasPrefix(speaker, "Speaker ") {
-			num := strings.TrimPrefix(speaker, "Speaker ")
-			return "Собеседник " + num
+// exportToRTTM экспортирует разметку спикеров в формат NIST RTTM:
+// SPEAKER <file> 1 <start> <dur> <NA> <NA> <speaker> <NA> <NA>
+// Идентификатор файла - ID сессии, время в секундах
+func exportToRTTM(fileID string, dialogue []session.TranscriptSegment) string {
+	var sb strings.Builder
+	for _, seg := range dialogue {
+		if seg.End <= seg.Start {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("SPEAKER %s 1 %.3f %.3f <NA> <NA> %s <NA> <NA>\n",
+			fileID, float64(seg.Start)/1000, float64(seg.End-seg.Start)/1000, rttmSpeakerName(seg.Speaker)))
+	}
+	return sb.String()
+}
+
+// rttmSpeakerName возвращает имя спикера без пробелов (поля RTTM разделяются пробелами)
+func rttmSpeakerName(speaker string) string {
+	name := strings.Join(strings.Fields(session.FormatSpeakerLabel(speaker)), "_")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// SpeakerLabelConfig шаблон подписей стандартных спикеров в экспорте (поверх языка подписей).
+// Пустые поля оставляют подписи языка. Имена, заданные пользователем, шаблон не меняет
+type SpeakerLabelConfig struct {
+	MicLabel       string         `json:"micLabel,omitempty"`       // Владелец микрофона: "You"
+	SysLabelPrefix string         `json:"sysLabelPrefix,omitempty"` // Префикс собеседников: "Guest" -> "Guest 1", "Guest 2"
+	Overrides      map[int]string `json:"overrides,omitempty"`      // Подписи по localID (-1 - микрофон, 0 - первый собеседник)
+}
+
+// validate проверяет шаблон
+func (c *SpeakerLabelConfig) validate() error {
+	if c.MicLabel != strings.TrimSpace(c.MicLabel) || c.SysLabelPrefix != strings.TrimSpace(c.SysLabelPrefix) {
+		return fmt.Errorf("speaker labels must not have leading or trailing spaces")
+	}
+	for localID, name := range c.Overrides {
+		if localID < -1 {
+			return fmt.Errorf("invalid speaker label override: localId %d (expected -1 for the microphone or >= 0)", localID)
+		}
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("empty speaker label override for localId %d", localID)
+		}
+	}
+	return nil
+}
+
+// label подпись стандартного спикера по шаблону (false - шаблон её не задаёт)
+func (c *SpeakerLabelConfig) label(name string) (string, bool) {
+	localID, ok := -1, session.IsSelfSpeakerLabel(name)
+	peer := !ok && session.IsPeerSpeakerLabel(name)
+	switch {
+	case peer:
+		localID, ok = 0, true
+	case !ok:
+		localID, ok = session.ParseSpeakerLabel(name)
+	}
+	if !ok {
+		return "", false
+	}
+
+	if override := c.Overrides[localID]; override != "" {
+		return override, true
+	}
+	switch {
+	case localID == -1 && c.MicLabel != "":
+		return c.MicLabel, true
+	case localID >= 0 && c.SysLabelPrefix != "":
+		if peer {
+			return c.SysLabelPrefix, true
+		}
+		// Нумерация (с 0/1, цифры/буквы) - как в текущей схеме имён
+		scheme := session.GetSpeakerLabelScheme()
+		scheme.Prefix = c.SysLabelPrefix
+		return scheme.Label(localID), true
+	}
+	return "", false
+}
+
+// exportLabels подписи экспорта (спикеры, заголовок) на выбранном языке и оформление текста
+type exportLabels struct {
+	english    bool
+	custom     *SpeakerLabelConfig // Шаблон подписей спикеров из запроса экспорта
+	mergeTurns bool                // TXT: подряд идущие сегменты спикера - одна реплика
+
+	maxLineChars int // SRT/VTT: длина строки субтитров (0 - DefaultSubtitleLineChars)
+}
+
+// newExportLabels возвращает подписи для языка: "en" - английские, иначе русские
+func newExportLabels(lang string) exportLabels {
+	return exportLabels{english: strings.EqualFold(strings.TrimSpace(lang), "en")}
+}
+
+// withSpeakerLabels возвращает подписи с шаблоном спикеров (nil - подписи языка)
+func (l exportLabels) withSpeakerLabels(custom *SpeakerLabelConfig) exportLabels {
+	l.custom = custom
+	return l
+}
+
+// speaker возвращает подпись спикера. Пользовательские имена не переводятся и не заменяются шаблоном
+func (l exportLabels) speaker(speaker string) string {
+	speaker = session.FormatSpeakerLabel(speaker)
+	if l.custom != nil {
+		if label, ok := l.custom.label(speaker); ok {
+			return label
 		}
+	}
+	if !l.english {
 		return speaker
 	}
+	// Переводятся только имена схемы по умолчанию, заданные в настройках имена остаются как есть
+	defaults := session.DefaultSpeakerLabelScheme()
+	switch speaker {
+	case defaults.Self:
+		return "You"
+	case defaults.Prefix:
+		return "Speaker"
+	}
+	if num, ok := strings.CutPrefix(speaker, defaults.Prefix+" "); ok {
+		return "Speaker " + num
+	}
+	if num, ok := strings.CutPrefix(speaker, defaults.Self+" "); ok {
+		return "You " + num // Человек у микрофона при диаризации MIC канала
+	}
+	return speaker
+}
+
+// untitled возвращает заголовок записи без названия
+func (l exportLabels) untitled(start time.Time) string {
+	if l.english {
+		return "Recording " + l.formatDate(start)
+	}
+	return "Запись " + l.formatDate(start)
+}
+
+// date возвращает подпись даты
+func (l exportLabels) date() string {
+	if l.english {
+		return "Date"
+	}
+	return "Дата"
+}
+
+// formatDate форматирует дату и время записи
+func (l exportLabels) formatDate(t time.Time) string {
+	if l.english {
+		return t.Format("2006-01-02 15:04")
+	}
+	return t.Format("02.01.2006 15:04")
 }
 
 // formatTimestamp форматирует timestamp в MM:SS
@@ -2741,8 +4417,8 @@ func formatVTTTime(ms int64) string {
 }
 
 // handleSpeakerSampleAPI отдаёт аудио-сэмпл спикера для прослушивания
-// URL: /api/speaker-sample/{sessionID}/{localSpeakerID}
-// Возвращает MP3 файл с первыми 5-10 секундами речи спикера
+// URL: /api/speaker-sample/{sessionID}/{localSpeakerID}?normalize=true (с выравниванием громкости)
+// Возвращает MP3 файл с первыми репликами спикера (до 10 секунд речи)
 func (s *Server) handleSpeakerSampleAPI(w http.ResponseWriter, r *http.Request) {
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -2760,16 +4436,18 @@ func (s *Server) handleSpeakerSampleAPI(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Парсим URL: /api/speaker-sample/{sessionID}/{localSpeakerID}
-	path := strings.TrimPrefix(r.URL.Path, "/api/speaker-sample/")
-	parts := strings.Split(path, "/")
-	if len(parts) != 2 {
+	sessionID, speakerPart, err := parseSessionPath(strings.TrimPrefix(r.URL.Path, "/api/speaker-sample/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if speakerPart == "" || strings.Contains(speakerPart, "/") {
 		http.Error(w, "Invalid path. Expected: /api/speaker-sample/{sessionID}/{localSpeakerID}", http.StatusBadRequest)
 		return
 	}
 
-	sessionID := parts[0]
 	var localSpeakerID int
-	if _, err := fmt.Sscanf(parts[1], "%d", &localSpeakerID); err != nil {
+	if _, err := fmt.Sscanf(speakerPart, "%d", &localSpeakerID); err != nil {
 		http.Error(w, "Invalid speaker ID", http.StatusBadRequest)
 		return
 	}
@@ -2783,65 +4461,62 @@ func (s *Server) handleSpeakerSampleAPI(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Находим первый сегмент этого спикера для извлечения аудио
-	var targetSegment *session.TranscriptSegment
+	// Собираем первые реплики спикера: у собеседника, говорящего короткими фразами,
+	// может не быть ни одной длинной реплики
 	speakerNames := s.getSpeakerNamesForLocalIDInSession(sessionID, localSpeakerID)
 	log.Printf("Looking for speaker sample with names: %v", speakerNames)
 
-	for _, chunk := range sess.Chunks {
-		for i := range chunk.Dialogue {
-			seg := &chunk.Dialogue[i]
-			for _, name := range speakerNames {
-				if seg.Speaker == name && (seg.End-seg.Start) >= 2000 { // Минимум 2 секунды
-					targetSegment = seg
-					break
-				}
-			}
-			if targetSegment != nil {
-				break
-			}
-		}
-		if targetSegment != nil {
-			break
-		}
-	}
-
-	if targetSegment == nil {
+	ranges := speakerSampleRanges(sess.Chunks, speakerNames)
+	if len(ranges) == 0 {
 		http.Error(w, "No audio sample found for this speaker", http.StatusNotFound)
 		return
 	}
 
-	// Извлекаем аудио сегмент из full.mp3
-	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
-	if _, err := os.Stat(mp3Path); os.IsNotExist(err) {
+	// Извлекаем аудио сегменты из full.mp3 (или full.wav, если MP3 нет)
+	audioPath := session.PlaybackAudioPath(sess.DataDir)
+	if _, err := os.Stat(audioPath); os.IsNotExist(err) {
 		http.Error(w, "Audio file not found", http.StatusNotFound)
 		return
 	}
 
-	// Ограничиваем длительность сэмпла до 10 секунд
-	startMs := targetSegment.Start
-	endMs := targetSegment.End
-	if endMs-startMs > 10000 {
-		endMs = startMs + 10000
+	var totalMs int64
+	for _, rng := range ranges {
+		totalMs += rng.endMs - rng.startMs
 	}
+	log.Printf("Extracting speaker sample: %d segments from %.2fs (%.2fs total)",
+		len(ranges), float64(ranges[0].startMs)/1000.0, float64(totalMs)/1000.0)
 
-	startSec := float64(startMs) / 1000.0
-	duration := float64(endMs-startMs) / 1000.0
-
-	log.Printf("Extracting speaker sample: %.2fs - %.2fs (%.2fs duration)", startSec, startSec+duration, duration)
+	normalize := r.URL.Query().Get("normalize") == "true"
 
-	// Используем ffmpeg для извлечения сегмента
-	cmd := exec.Command(session.GetFFmpegPath(),
-		"-ss", fmt.Sprintf("%.3f", startSec),
-		"-i", mp3Path,
-		"-t", fmt.Sprintf("%.3f", duration),
-		"-c:a", "libmp3lame",
-		"-q:a", "4", // Качество VBR
-		"-f", "mp3",
-		"pipe:1",
-	)
+	// Без FFmpeg вырезаем сегменты на чистом Go и отдаём WAV
+	if err := session.RequireFFmpeg(); err != nil {
+		if normalize {
+			log.Printf("Speaker sample: loudness normalization needs FFmpeg, sending raw sample")
+		}
+		data, err := extractSpeakerSampleWAV(audioPath, ranges)
+		if err != nil {
+			log.Printf("Error extracting speaker sample without FFmpeg: %v", err)
+			http.Error(w, "Failed to extract audio sample", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(data)
+		return
+	}
 
-	output, err := cmd.Output()
+	var output []byte
+	if normalize {
+		// Выравнивание громкости (EBU R128), результат кешируется
+		output, err = s.normalizedSpeakerSample(sessionID, localSpeakerID, audioPath, ranges)
+	} else {
+		// Используем ffmpeg для извлечения и склейки сегментов
+		args := speakerSampleArgs(audioPath, ranges, "")
+		args = append(args, session.MP3EncodeArgs(session.MP3TargetSample)...) // Качество VBR из настроек
+		args = append(args, "-f", "mp3", "pipe:1")
+		output, err = exec.Command(session.GetFFmpegPath(), args...).Output()
+	}
 	if err != nil {
 		log.Printf("FFmpeg error extracting speaker sample: %v", err)
 		http.Error(w, "Failed to extract audio sample", http.StatusInternalServerError)
@@ -2858,14 +4533,12 @@ func (s *Server) handleSpeakerSampleAPI(w http.ResponseWriter, r *http.Request)
 // getSpeakerNamesForLocalID возвращает все возможные имена спикера по localID
 func (s *Server) getSpeakerNamesForLocalID(localSpeakerID int) []string {
 	if localSpeakerID < 0 {
-		return []string{"Вы", "mic"}
+		return session.SpeakerLabelVariants(-1)
 	}
-	return []string{
-		fmt.Sprintf("Speaker %d", localSpeakerID),
-		fmt.Sprintf("Собеседник %d", localSpeakerID+1),
-		"Собеседник", // Для случая единственного собеседника
+	return append(session.SpeakerLabelVariants(localSpeakerID),
+		session.PeerSpeakerLabel(), // Для случая единственного собеседника
 		"sys",
-	}
+	)
 }
 
 // getSpeakerNamesForLocalIDInSession возвращает все возможные имена спикера по localID,
@@ -2904,10 +4577,11 @@ func (s *Server) getSpeakerNamesForLocalIDInSession(sessionID string, localSpeak
 // GET /api/voiceprints/{id} - получить конкретный voiceprint
 // PATCH /api/voiceprints/{id} - обновить voiceprint (переименовать)
 // DELETE /api/voiceprints/{id} - удалить voiceprint
+// POST /api/voiceprints/preview - похожие voiceprints для спикера сессии (без сохранения)
 func (s *Server) handleVoiceprintsAPI(w http.ResponseWriter, r *http.Request) {
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 	if r.Method == "OPTIONS" {
@@ -2915,6 +4589,12 @@ func (s *Server) handleVoiceprintsAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Предпросмотр работает и без базы voiceprints (тогда совпадений нет)
+	if r.URL.Path == "/api/voiceprints/preview" {
+		s.handleVoiceprintPreview(w, r)
+		return
+	}
+
 	// Проверяем наличие VoicePrintStore
 	if s.VoicePrintStore == nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -1,9 +1,24 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"mime"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,9 +28,12 @@ import (
 	"aiwisper/internal/service"
 	"aiwisper/models"
 	"aiwisper/session"
+	"aiwisper/voiceprint"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // jsonClient is a lightweight gRPC JSON client for the Control stream.
@@ -162,3 +180,1887 @@ func TestControlStream_SessionsAndModels(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeTimelineIntervals(t *testing.T) {
+	intervals := []SpeakerTimelineInterval{
+		{Start: 5000, End: 6000},
+		{Start: 0, End: 1000},
+		{Start: 1200, End: 2000}, // пауза 200мс - схлопывается
+		{Start: 1500, End: 1800}, // вложенный интервал
+		{Start: 6000, End: 7000}, // смежный
+	}
+
+	got := mergeTimelineIntervals(intervals, 500, nil)
+	want := []SpeakerTimelineInterval{
+		{Start: 0, End: 2000},
+		{Start: 5000, End: 7000},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d intervals, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("interval %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if res := mergeTimelineIntervals(nil, 500, nil); res != nil {
+		t.Errorf("expected nil for empty input, got %+v", res)
+	}
+
+	// Пауза с речью другого спикера не схлопывается, пересечение - схлопывается
+	others := []SpeakerTimelineInterval{{Start: 1100, End: 1900}}
+	got = mergeTimelineIntervals([]SpeakerTimelineInterval{
+		{Start: 0, End: 1000},
+		{Start: 2000, End: 3000},
+		{Start: 2500, End: 3200},
+	}, 1500, others)
+	want = []SpeakerTimelineInterval{{Start: 0, End: 1000}, {Start: 2000, End: 3200}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("interrupted gap: expected %+v, got %+v", want, got)
+	}
+}
+
+func TestComputeSpeakerTimelineTurns(t *testing.T) {
+	// Реплики A-B-A-B с паузами < 1.5с: интервалы спикеров не должны перекрываться
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &session.Chunk{ID: "c0", SessionID: sess.ID, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+		{Start: 0, End: 1000, Text: "a1", Speaker: "mic"},
+		{Start: 1100, End: 2000, Text: "b1", Speaker: "Собеседник 1"},
+		{Start: 2100, End: 3000, Text: "a2", Speaker: "mic"},
+		{Start: 3100, End: 4000, Text: "b2", Speaker: "Собеседник 1"},
+		{Start: 4300, End: 5000, Text: "b3", Speaker: "Собеседник 1"},
+	}}
+	if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, sessionSpeakersCache: make(map[string]sessionSpeakersCacheEntry)}
+
+	tracks := s.computeSpeakerTimeline(sess, sess.ID)
+	if len(tracks) != 2 {
+		t.Fatalf("expected 2 tracks, got %+v", tracks)
+	}
+	mic, peer := tracks[0], tracks[1]
+	if !mic.IsMic || len(mic.Intervals) != 2 || mic.TotalDuration != 1.9 {
+		t.Errorf("mic track = %+v", mic)
+	}
+	wantPeer := []SpeakerTimelineInterval{{Start: 1100, End: 2000}, {Start: 3100, End: 5000}}
+	if len(peer.Intervals) != 2 || peer.Intervals[0] != wantPeer[0] || peer.Intervals[1] != wantPeer[1] || peer.TotalDuration != 2.8 {
+		t.Errorf("peer track = %+v", peer)
+	}
+}
+
+func TestExportToRTTM(t *testing.T) {
+	dialogue := []session.TranscriptSegment{
+		{Start: 1500, End: 4250, Speaker: "mic"},
+		{Start: 5000, End: 5000, Speaker: "Собеседник 1"}, // пустой сегмент пропускается
+		{Start: 6000, End: 9000, Speaker: "Собеседник 2"},
+	}
+
+	got := exportToRTTM("sess-1", dialogue)
+	want := "SPEAKER sess-1 1 1.500 2.750 <NA> <NA> Вы <NA> <NA>\n" +
+		"SPEAKER sess-1 1 6.000 3.000 <NA> <NA> Собеседник_2 <NA> <NA>\n"
+	if got != want {
+		t.Errorf("exportToRTTM() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportToWordsCSV(t *testing.T) {
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 1200, Speaker: "mic", Text: "Привет, мир", Words: []session.TranscriptWord{
+			{Start: 0, End: 500, Text: " Привет,", P: 0.91},
+			{Start: 600, End: 1200, Text: " мир", P: 0.5},
+		}},
+		{Start: 2000, End: 3500, Speaker: "Собеседник 1", Text: " Добрый день "}, // без слов - строка сегмента
+	}
+
+	got := exportToWordsCSV(dialogue, newExportLabels(""))
+	want := "start,end,text,p,speaker,segment\n" +
+		"0.000,0.500,\"Привет,\",0.910,Вы,false\n" +
+		"0.600,1.200,мир,0.500,Вы,false\n" +
+		"2.000,3.500,Добрый день,,Собеседник 1,true\n"
+	if got != want {
+		t.Errorf("exportToWordsCSV() =\n%s\nwant\n%s", got, want)
+	}
+
+	rows := wordTimingRows(dialogue, newExportLabels("en"))
+	if len(rows) != 3 || rows[0].P == nil || rows[0].Speaker != "You" || !rows[2].Segment || rows[2].P != nil {
+		t.Errorf("wordTimingRows() = %+v", rows)
+	}
+}
+
+func TestExportLabels(t *testing.T) {
+	tests := []struct {
+		lang, speaker, want string
+	}{
+		{"", "mic", "Вы"},
+		{"ru", "Speaker 2", "Собеседник 3"}, // Метка диаризации нумеруется с нуля
+		{"en", "mic", "You"},
+		{"en", "Вы", "You"},
+		{"EN", "Собеседник 3", "Speaker 3"},
+		{"en", "Speaker 1", "Speaker 2"},
+		{"en", "Вы 2", "You 2"},
+		{"en", "Иван", "Иван"},
+	}
+	for _, tt := range tests {
+		if got := newExportLabels(tt.lang).speaker(tt.speaker); got != tt.want {
+			t.Errorf("speaker(%q, %q) = %q, want %q", tt.lang, tt.speaker, got, tt.want)
+		}
+	}
+}
+
+func TestExportSpeakerLabelTemplates(t *testing.T) {
+	sess := &session.Session{Title: "Планёрка", StartTime: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 1000, Speaker: "mic", Text: "Начнём"},
+		{Start: 1000, End: 2000, Speaker: "Speaker 0", Text: "Да"},
+		{Start: 2000, End: 3000, Speaker: "Собеседник 2", Text: "Согласен"},
+		{Start: 3000, End: 4000, Speaker: "Иван", Text: "Я тоже"}, // Переименован пользователем
+	}
+
+	templates := []struct {
+		name   string
+		lang   string
+		config *SpeakerLabelConfig
+		want   []string
+	}{
+		{"ru", "", &SpeakerLabelConfig{MicLabel: "Ведущий", SysLabelPrefix: "Гость"},
+			[]string{"Ведущий", "Гость 1", "Гость 2", "Иван"}},
+		{"en", "en", &SpeakerLabelConfig{MicLabel: "Host", SysLabelPrefix: "Guest", Overrides: map[int]string{1: "Anna"}},
+			[]string{"Host", "Guest 1", "Anna", "Иван"}},
+		{"language only", "en", nil, []string{"You", "Speaker 1", "Speaker 2", "Иван"}},
+	}
+	s := &Server{}
+	for _, tt := range templates {
+		labels := newExportLabels(tt.lang).withSpeakerLabels(tt.config)
+		for _, format := range []string{"txt", "srt", "vtt", "md"} {
+			content, _ := s.exportDialogue(sess, dialogue, format, labels)
+			for i, want := range tt.want {
+				var line string
+				switch format {
+				case "vtt":
+					line = "<v " + want + ">" + dialogue[i].Text
+				case "md":
+					line = "**" + want + ":**\n> " + dialogue[i].Text
+				default:
+					line = want + ": " + dialogue[i].Text
+				}
+				if !strings.Contains(content, line) {
+					t.Errorf("%s/%s: missing %q in\n%s", tt.name, format, line, content)
+				}
+			}
+		}
+	}
+
+	for _, bad := range []*SpeakerLabelConfig{{MicLabel: " Host"}, {Overrides: map[int]string{-2: "X"}}, {Overrides: map[int]string{0: " "}}} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("config %+v accepted", bad)
+		}
+	}
+}
+
+func TestExportToJSONWords(t *testing.T) {
+	sess := &session.Session{ID: "s1", Title: "Созвон"}
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 1000, Speaker: "mic", Text: "Привет всем", Words: []session.TranscriptWord{
+			{Start: 0, End: 400, Text: "Привет", P: 0.9},
+			{Start: 450, End: 1000, Text: "всем", P: 0.7},
+		}},
+		{Start: 1000, End: 2000, Speaker: "sys", Text: "Добрый день"},
+	}
+
+	var export struct {
+		FormatVersion int `json:"format_version"`
+		Dialogue      []struct {
+			Words      []session.TranscriptWord `json:"words"`
+			Confidence *float64                 `json:"confidence"`
+		} `json:"dialogue"`
+	}
+	content := (&Server{}).exportToJSON(sess, dialogue)
+	if err := json.Unmarshal([]byte(content), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.FormatVersion != jsonExportFormatVersion || len(export.Dialogue) != 2 {
+		t.Fatalf("unexpected export:\n%s", content)
+	}
+	first := export.Dialogue[0]
+	if len(first.Words) != 2 || first.Words[1].End != 1000 || first.Words[0].P != 0.9 {
+		t.Errorf("words not preserved: %+v", first.Words)
+	}
+	if first.Confidence == nil || math.Abs(*first.Confidence-0.8) > 1e-6 {
+		t.Errorf("confidence = %v, want 0.8", first.Confidence)
+	}
+	if export.Dialogue[1].Confidence != nil {
+		t.Errorf("confidence of a segment without words = %v", *export.Dialogue[1].Confidence)
+	}
+	if !strings.Contains(content, `"words": []`) {
+		t.Errorf("segment without words must serialize an empty array:\n%s", content)
+	}
+}
+
+func TestExportToTXTMergeSpeakerTurns(t *testing.T) {
+	sess := &session.Session{Title: "Совещание"}
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 1500, Speaker: "mic", Text: "Всем привет."},
+		{Start: 1600, End: 3000, Speaker: "Вы", Text: " Начнём с отчёта."},
+		{Start: 3100, End: 5000, Speaker: "Собеседник 1", Text: "Отчёт готов."},
+		{Start: 5100, End: 6000, Speaker: "Собеседник 2", Text: "У меня вопрос."},
+		{Start: 6100, End: 7000, Speaker: "Собеседник 2", Text: "  "},
+		// Следующий чанк перекрывается с предыдущим: начало раньше реплики Собеседника 2
+		{Start: 29900, End: 31000, Speaker: "Собеседник 2", Text: "Когда сдаём?"},
+		{Start: 4900, End: 32000, Speaker: "Собеседник 1", Text: "В пятницу."},
+	}
+
+	s := &Server{}
+	plain := s.exportToTXT(sess, dialogue, newExportLabels(""))
+	labels := newExportLabels("")
+	labels.mergeTurns = true
+	merged := s.exportToTXT(sess, dialogue, labels)
+
+	if n := strings.Count(plain, "\n["); n != len(dialogue) {
+		t.Errorf("unmerged export has %d lines, want %d:\n%s", n, len(dialogue), plain)
+	}
+	want := "Совещание\n" + strings.Repeat("=", len("Совещание")) + "\n\n" +
+		"[00:00] Вы: Всем привет. Начнём с отчёта.\n" +
+		"[00:03] Собеседник 1: Отчёт готов.\n" +
+		"[00:05] Собеседник 2: У меня вопрос. Когда сдаём?\n" +
+		"[00:05] Собеседник 1: В пятницу.\n"
+	if merged != want {
+		t.Errorf("merged export:\n%s\nwant:\n%s", merged, want)
+	}
+}
+
+func TestExportToTXTOverlapMarker(t *testing.T) {
+	sess := &session.Session{Title: "Спор"}
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 2000, Speaker: "Собеседник 1", Text: "Я считаю, что", Overlap: true, OverlapSpeaker: "Собеседник 2"},
+		{Start: 2000, End: 3000, Speaker: "Собеседник 2", Text: "Нет."},
+	}
+
+	content := (&Server{}).exportToTXT(sess, dialogue, newExportLabels(""))
+	if !strings.Contains(content, "[00:00] Собеседник 1 [overlap]: Я считаю, что\n") {
+		t.Errorf("overlap marker is missing:\n%s", content)
+	}
+	if !strings.Contains(content, "[00:02] Собеседник 2: Нет.\n") {
+		t.Errorf("segment without overlap is marked:\n%s", content)
+	}
+}
+
+func TestGenerateSpeakerExports(t *testing.T) {
+	sess := &session.Session{
+		Title: "Интервью: финал",
+		Chunks: []*session.Chunk{{
+			Status: session.ChunkStatusCompleted,
+			Dialogue: []session.TranscriptSegment{
+				{Start: 0, End: 1000, Speaker: "mic", Text: "Привет"},
+				{Start: 1000, End: 2000, Speaker: "Собеседник 1", Text: "Здравствуйте"},
+				{Start: 2000, End: 3000, Speaker: "Вы", Text: "Начнём"},
+				{Start: 3000, End: 4000, Speaker: "Собеседник 2", Text: "  "}, // без текста - файла нет
+			},
+		}},
+	}
+
+	files := (&Server{}).generateSpeakerExports(sess, exportSessionDialogue(sess, false), "txt", newExportLabels(""))
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2: %+v", len(files), files)
+	}
+	if files[0].name != "Интервью_ финал - Вы.txt" || files[1].name != "Интервью_ финал - Собеседник 1.txt" {
+		t.Errorf("unexpected file names: %q, %q", files[0].name, files[1].name)
+	}
+	if !strings.Contains(files[0].content, "Привет") || !strings.Contains(files[0].content, "Начнём") ||
+		strings.Contains(files[0].content, "Здравствуйте") {
+		t.Errorf("mic file must contain only own lines:\n%s", files[0].content)
+	}
+}
+
+func TestParseSessionPath(t *testing.T) {
+	const validID = "6c7d4c72-a8bf-4374-ba75-0ea10e0bfa8c"
+
+	tests := []struct {
+		name     string
+		path     string
+		wantID   string
+		wantRest string
+		wantErr  bool
+	}{
+		{name: "empty", path: "", wantErr: true},
+		{name: "only slash", path: "/full.mp3", wantErr: true},
+		{name: "short id", path: "6c7d4c72/full.mp3", wantErr: true},
+		{name: "long id", path: validID + "0/full.mp3", wantErr: true},
+		{name: "non-uuid of uuid length", path: "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz/full.mp3", wantErr: true},
+		{name: "id without rest", path: validID, wantID: validID},
+		{name: "id with trailing slash", path: validID + "/", wantID: validID},
+		{name: "id with file", path: validID + "/full.mp3", wantID: validID, wantRest: "full.mp3"},
+		{name: "id with nested path", path: validID + "/chunk/3.mp3", wantID: validID, wantRest: "chunk/3.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, rest, err := parseSessionPath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got id=%q rest=%q", tt.path, id, rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", tt.path, err)
+			}
+			if id != tt.wantID || rest != tt.wantRest {
+				t.Errorf("parseSessionPath(%q) = (%q, %q), want (%q, %q)", tt.path, id, rest, tt.wantID, tt.wantRest)
+			}
+		})
+	}
+}
+
+// blockingClient имитирует зависшего клиента: Send блокируется до закрытия
+type blockingClient struct {
+	release chan struct{}
+}
+
+func (c *blockingClient) Send(Message) error {
+	<-c.release
+	return nil
+}
+
+func (c *blockingClient) Close() error { return nil }
+
+// countingClient считает доставленные сообщения
+type countingClient struct {
+	received chan Message
+}
+
+func (c *countingClient) Send(msg Message) error {
+	c.received <- msg
+	return nil
+}
+
+func (c *countingClient) Close() error { return nil }
+
+func TestBroadcast_SlowClientDoesNotBlockOthers(t *testing.T) {
+	s := &Server{clients: make(map[transportClient]bool)}
+
+	slow := &blockingClient{release: make(chan struct{})}
+	defer close(slow.release)
+	fast := &countingClient{received: make(chan Message, clientSendQueueSize*2)}
+
+	slowClient := newQueuedClient(slow)
+	s.addClient(slowClient)
+	s.addClient(newQueuedClient(fast))
+
+	// Быстрый клиент забирает каждое сообщение, медленный — ни одного
+	total := clientSendQueueSize + 10
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < total; i++ {
+			s.broadcast(Message{Type: "ping"})
+			select {
+			case <-fast.received:
+			case <-time.After(2 * time.Second):
+				done <- fmt.Errorf("fast client received only %d of %d messages", i, total)
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast blocked by slow client")
+	}
+
+	s.mu.Lock()
+	_, slowStillConnected := s.clients[slowClient]
+	s.mu.Unlock()
+	if slowStillConnected {
+		t.Error("expected slow client to be dropped after its queue overflowed")
+	}
+	if err := slowClient.Send(Message{Type: "ping"}); err == nil {
+		t.Error("expected Send on dropped client to fail")
+	}
+}
+
+// stuckStream gRPC поток, у которого Send и Recv висят до закрытия потока
+type stuckStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stuckStream) Context() context.Context { return s.ctx }
+
+func (s *stuckStream) Send(*Message) error {
+	<-s.ctx.Done()
+	return s.ctx.Err()
+}
+
+func (s *stuckStream) Recv() (*Message, error) {
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func TestGRPCClientClose(t *testing.T) {
+	s := &Server{clients: make(map[transportClient]bool)}
+	streamCtx, closeStream := context.WithCancel(context.Background())
+	defer closeStream()
+
+	done := make(chan error, 1)
+	go func() { done <- s.Stream(&stuckStream{ctx: streamCtx}) }()
+
+	var client transportClient
+	for deadline := time.Now().Add(time.Second); client == nil && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		for c := range s.clients {
+			client = c
+		}
+		s.mu.Unlock()
+	}
+	if client == nil {
+		t.Fatal("stream client was not registered")
+	}
+
+	// Закрытие клиента (например, переполнение очереди) завершает обработчик, а не оставляет зомби-поток
+	client.Close()
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("Stream() = %v, want Unavailable", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stream did not return after client was closed")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) != 0 {
+		t.Error("closed client is still registered")
+	}
+}
+
+func TestGRPCClientSendTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &grpcClient{stream: &stuckStream{ctx: ctx}, cancel: cancel, timeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	if err := client.Send(Message{Type: "ping"}); err == nil {
+		t.Fatal("expected timeout error for stuck peer")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send blocked for %v", elapsed)
+	}
+	// Поток закрывается, а повторные отправки сразу отклоняются
+	if ctx.Err() == nil {
+		t.Error("stream context was not canceled")
+	}
+	if err := client.Send(Message{Type: "ping"}); !errors.Is(err, errClientClosed) {
+		t.Errorf("Send after timeout = %v, want errClientClosed", err)
+	}
+}
+
+func TestWriteHTTPError_FFmpegCode(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeHTTPError(rec, fmt.Errorf("import: %w", session.ErrFFmpegNotFound), http.StatusInternalServerError)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["code"] != ErrorCodeFFmpegNotFound {
+		t.Errorf("body = %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	writeHTTPError(rec, fmt.Errorf("boom"), http.StatusBadRequest)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("plain error status = %d, want 400", rec.Code)
+	}
+	if msg := errorMessage(fmt.Errorf("boom")); msg.Code != "" || msg.Type != "error" {
+		t.Errorf("errorMessage = %+v", msg)
+	}
+}
+
+func TestDiarizationChunkLimit(t *testing.T) {
+	if l := diarizationChunkLimit(10, 10, 0); l != nil {
+		t.Errorf("10 chunks with cap 10 should keep diarization, got %+v", l)
+	}
+	if l := diarizationChunkLimit(500, 0, 0); l != nil {
+		t.Errorf("cap 0 should disable the limit, got %+v", l)
+	}
+	l := diarizationChunkLimit(25, 20, 512)
+	if l == nil || l.Reason != DiarizationLimitChunks || l.MaxChunks != 20 || l.TotalChunks != 25 || l.MaxMemGrowthMB != 512 {
+		t.Fatalf("unexpected limit: %+v", l)
+	}
+	if !strings.Contains(l.message(), "20") {
+		t.Errorf("warning should mention the effective cap: %q", l.message())
+	}
+}
+
+func TestDiarizationMemGuard(t *testing.T) {
+	rss := uint64(1 << 30)
+	peak := func() uint64 { return rss }
+
+	if g := newDiarizationMemGuard(0, peak); g != nil {
+		t.Error("guard should be disabled with limit 0")
+	}
+	if g := newDiarizationMemGuard(100, func() uint64 { return 0 }); g != nil {
+		t.Error("guard should be disabled when RSS is unavailable")
+	}
+
+	g := newDiarizationMemGuard(100, peak)
+	rss += 50 << 20
+	if growth, exceeded := g.check(); exceeded || growth != 50 {
+		t.Errorf("50 MB growth: got %d, exceeded=%v", growth, exceeded)
+	}
+	rss += 60 << 20
+	if growth, exceeded := g.check(); !exceeded || growth != 110 {
+		t.Errorf("110 MB growth: got %d, exceeded=%v", growth, exceeded)
+	}
+
+	var nilGuard *diarizationMemGuard
+	if _, exceeded := nilGuard.check(); exceeded {
+		t.Error("nil guard must never report exceeded")
+	}
+}
+
+func TestImportSourcesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.WAV", "a.mp3", "notes.txt", ".hidden.mp3"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested.mp3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := importSourcesFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[0].Name != "a.mp3" || sources[1].Name != "b.WAV" {
+		t.Fatalf("unexpected sources: %+v", sources)
+	}
+	f, err := sources[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := importSourcesFromDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing folder")
+	}
+
+	// Папки на сервере - только внутри import root
+	root := filepath.Dir(dir)
+	if got, err := resolveImportFolder(root, dir); err != nil || filepath.Base(got) != filepath.Base(dir) {
+		t.Errorf("resolveImportFolder(root, dir) = %q, %v", got, err)
+	}
+	if _, err := resolveImportFolder("", dir); err == nil {
+		t.Error("folder import must be disabled without an import root")
+	}
+	if _, err := resolveImportFolder(dir, root); err == nil {
+		t.Error("folder outside the import root must be rejected")
+	}
+	sibling := dir + "-other"
+	if err := os.Mkdir(sibling, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveImportFolder(dir, sibling); err == nil {
+		t.Error("sibling folder must be rejected")
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(root, link); err == nil {
+		if _, err := resolveImportFolder(dir, link); err == nil {
+			t.Error("symlink leading outside the import root must be rejected")
+		}
+	}
+
+	for value, want := range map[string]int{"": 2, "abc": 2, "0": 2, "3": 3, "16": maxImportBatchConcurrency} {
+		if got := importBatchConcurrency(value); got != want {
+			t.Errorf("importBatchConcurrency(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+// fakeModelFile создаёт в папке моделей файл, который models.Manager считает скачанной моделью
+func fakeModelFile(t *testing.T, dir, name string) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(2 << 20); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModelFallbackCandidates(t *testing.T) {
+	dir := t.TempDir()
+	modelMgr, err := models.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeModelFile(t, dir, "ggml-tiny.bin")
+	fakeModelFile(t, dir, "ggml-base.bin")
+	fakeModelFile(t, dir, "gigaam-v3-ctc.onnx")
+	fakeModelFile(t, dir, "gigaam-v3-ctc_vocab.txt")
+	s := &Server{ModelMgr: modelMgr, Config: &config.Config{FallbackModels: []string{"gigaam-v3-ctc", "ggml-tiny"}}}
+
+	// Явный список: без упавшей модели, повторов, нескачанных и не-ASR моделей
+	requested := []string{"ggml-base", "ggml-small", "silero-vad-v5", "ggml-tiny", "gigaam-v3-ctc", "ggml-tiny", "no-such-model"}
+	if got := s.modelFallbackCandidates("ggml-base", "ru", requested); !slices.Equal(got, []string{"ggml-tiny", "gigaam-v3-ctc"}) {
+		t.Errorf("requested chain = %v", got)
+	}
+	// Язык пользователя сохраняется: русская GigaAM не подходит для английского
+	if got := s.modelFallbackCandidates("ggml-base", "en", requested); !slices.Equal(got, []string{"ggml-tiny"}) {
+		t.Errorf("requested chain for en = %v", got)
+	}
+	// Без списка в запросе - список из конфигурации
+	if got := s.modelFallbackCandidates("ggml-base", "ru", nil); !slices.Equal(got, []string{"gigaam-v3-ctc", "ggml-tiny"}) {
+		t.Errorf("config chain = %v", got)
+	}
+	// Без конфигурации - все скачанные модели распознавания в порядке реестра
+	s.Config = nil
+	got := s.modelFallbackCandidates("ggml-tiny", "ru", nil)
+	if !slices.Contains(got, "ggml-base") || !slices.Contains(got, "gigaam-v3-ctc") || slices.Contains(got, "ggml-tiny") || slices.Contains(got, "ggml-small") {
+		t.Errorf("registry chain = %v", got)
+	}
+	if slices.Index(got, "ggml-base") > slices.Index(got, "gigaam-v3-ctc") {
+		t.Errorf("registry chain is not in registry order: %v", got)
+	}
+}
+
+func TestActivateFallbackModel(t *testing.T) {
+	dir := t.TempDir()
+	modelMgr, err := models.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Файл модели битый - загрузка запасной модели тоже падает
+	fakeModelFile(t, dir, "ggml-tiny.bin")
+	s := &Server{ModelMgr: modelMgr, EngineMgr: ai.NewEngineManager(modelMgr)}
+
+	var sent []Message
+	send := func(msg Message) error {
+		sent = append(sent, msg)
+		return nil
+	}
+	id, ok := s.activateFallbackModel(send, "ggml-base", "ru", []string{"ggml-tiny"}, errors.New("corrupted"))
+	if ok || id != "" {
+		t.Fatalf("activateFallbackModel = %q, %v; want failure", id, ok)
+	}
+	var types []string
+	for _, msg := range sent {
+		types = append(types, msg.Type+":"+msg.ModelID)
+	}
+	if !slices.Equal(types, []string{"model_loading:ggml-tiny", "model_load_error:ggml-tiny"}) {
+		t.Errorf("messages = %v", types)
+	}
+
+	// Запасных моделей нет - клиенту ничего не отправляется
+	sent = nil
+	if _, ok := s.activateFallbackModel(send, "ggml-base", "ru", []string{"ggml-small"}, errors.New("corrupted")); ok || len(sent) != 0 {
+		t.Errorf("no candidates: ok = %v, messages = %v", ok, sent)
+	}
+}
+
+func TestValidateReprocessSettings(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelMgr, err := models.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, ModelMgr: modelMgr}
+
+	// CoreML модели считаются скачанными всегда, ggml-tiny в пустой папке - нет
+	valid := Message{SessionID: sess.ID, Model: "parakeet-tdt-v3", VADMode: "per-region", VADMethod: "silero"}
+	if err := s.validateReprocessSettings(valid); err != nil {
+		t.Fatalf("valid settings rejected: %v", err)
+	}
+
+	if err := s.validateReprocessSettings(Message{SessionID: "missing"}); err == nil {
+		t.Error("expected error for missing session")
+	}
+
+	// Все проблемы сообщаются вместе
+	invalid := valid
+	invalid.Model = "ggml-tiny"
+	invalid.VADMode = "sometimes"
+	invalid.HybridEnabled = true
+	invalid.HybridSecondaryModelID = "ggml-tiny"
+	invalid.DiarizationEnabled = true
+	err = s.validateReprocessSettings(invalid)
+	if err == nil {
+		t.Fatal("expected error for invalid settings")
+	}
+	for _, want := range []string{"model ggml-tiny is not downloaded", "must differ", "VAD mode", "diarization"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	hybrid := valid
+	hybrid.HybridEnabled = true
+	hybrid.HybridSecondaryModelID = "ggml-base"
+	if err := s.validateReprocessSettings(hybrid); err == nil || !strings.Contains(err.Error(), "ggml-base is not downloaded") {
+		t.Errorf("expected missing secondary model error, got %v", err)
+	}
+}
+
+func TestSpeakerEmbeddingsAPI(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := []service.SessionSpeakerProfile{
+		{SpeakerID: 1, Embedding: []float32{0.1, 0.2, 0.3}, Duration: 12.5, Observations: [][]float32{{0.1, 0.2, 0.3}}},
+		{SpeakerID: 2, Embedding: []float32{0.3, 0.2, 0.1}, Duration: 4, RecognizedName: "Анна"},
+		{SpeakerID: 3}, // Профиль без embedding не отдаётся
+	}
+	data, _ := json.Marshal(profiles)
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "speaker_profiles.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, TranscriptionService: service.NewTranscriptionService(sessMgr, nil)}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleSpeakerEmbeddingsAPI(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/speaker-embeddings/" + sess.ID + "?observations=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SpeakerEmbeddingsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.SessionID != sess.ID || resp.Dimension != 3 || len(resp.Speakers) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if sp := resp.Speakers[0]; sp.SpeakerID != 1 || sp.DurationSec != 12.5 || len(sp.Embedding) != 3 || len(sp.Observations) != 1 {
+		t.Errorf("speaker 1 = %+v", sp)
+	}
+	if sp := resp.Speakers[1]; sp.RecognizedName != "Анна" || sp.Observations != nil {
+		t.Errorf("speaker 2 = %+v", sp)
+	}
+
+	// Без ?observations=1 наблюдения по чанкам не отдаются
+	rec = get("/api/speaker-embeddings/" + sess.ID)
+	if strings.Contains(rec.Body.String(), "observations") {
+		t.Errorf("observations returned without request: %s", rec.Body.String())
+	}
+
+	if rec := get("/api/speaker-embeddings/00000000-0000-0000-0000-000000000000"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing session: status = %d", rec.Code)
+	}
+	if rec := get("/api/speaker-embeddings/" + sess.ID + "/extra"); rec.Code != http.StatusBadRequest {
+		t.Errorf("extra path: status = %d", rec.Code)
+	}
+}
+
+func TestVoiceprintPreview(t *testing.T) {
+	root := t.TempDir()
+	sessMgr, err := session.NewManager(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := []service.SessionSpeakerProfile{{SpeakerID: 1, Embedding: []float32{1, 0, 0}, Duration: 12}}
+	data, _ := json.Marshal(profiles)
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "speaker_profiles.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := voiceprint.NewStore(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, embedding := range map[string][]float32{
+		"Алексей": {0.95, 0.3, 0}, // ~0.95
+		"Мария":   {0.8, 0.6, 0},  // 0.8
+		"Олег":    {0.6, 0.8, 0},  // 0.6
+		"Ирина":   {0, 0, 1},      // Ниже порога
+	} {
+		if _, err := store.Add(name, embedding, "sys"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{
+		SessionMgr:           sessMgr,
+		TranscriptionService: service.NewTranscriptionService(sessMgr, nil),
+		VoicePrintStore:      store,
+		VoicePrintMatcher:    voiceprint.NewMatcher(store),
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleVoiceprintsAPI(rec, httptest.NewRequest("POST", "/api/voiceprints/preview", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"sessionId":"` + sess.ID + `","localSpeakerId":0,"limit":2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var preview VoicePrintPreview
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Matches) != 2 || preview.Source != "sys" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if m := preview.Matches[0]; m.Name != "Алексей" || m.Confidence != "high" || m.Similarity < 0.94 {
+		t.Errorf("best match = %+v", m)
+	}
+	if m := preview.Matches[1]; m.Name != "Мария" || m.Confidence != "medium" {
+		t.Errorf("second match = %+v", m)
+	}
+	if store.Count() != 4 {
+		t.Errorf("preview changed the store: %d voiceprints", store.Count())
+	}
+
+	// По умолчанию - до трёх совпадений выше порога
+	rec = post(`{"sessionId":"` + sess.ID + `","localSpeakerId":0}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil || len(preview.Matches) != 3 {
+		t.Errorf("default limit: %s", rec.Body.String())
+	}
+
+	rec = post(`{"sessionId":"` + sess.ID + `","localSpeakerId":-1}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "No embedding available") {
+		t.Errorf("mic speaker: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(`{"sessionId":"` + sess.ID + `","localSpeakerId":5}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown speaker: status = %d", rec.Code)
+	}
+	if rec := post(`{"localSpeakerId":0}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing sessionId: status = %d", rec.Code)
+	}
+}
+
+func TestResolveModelLanguage(t *testing.T) {
+	s := &Server{Config: &config.Config{LanguageMismatch: LanguageMismatchError}}
+
+	if lang, switched, err := s.resolveModelLanguage("gigaam-v3-ctc", "ru", false); err != nil || switched || lang != "ru" {
+		t.Errorf("supported language: %q, %v, %v", lang, switched, err)
+	}
+	_, _, err := s.resolveModelLanguage("gigaam-v3-ctc", "en", false)
+	if !errors.Is(err, models.ErrUnsupportedLanguage) || !strings.Contains(err.Error(), "ru") {
+		t.Errorf("unsupported language error = %v", err)
+	}
+	if lang, _, err := s.resolveModelLanguage("gigaam-v3-ctc", "en", true); err != nil || lang != "en" {
+		t.Errorf("forced language: %q, %v", lang, err)
+	}
+
+	s.Config.LanguageMismatch = LanguageMismatchSwitch
+	if lang, switched, err := s.resolveModelLanguage("gigaam-v3-ctc", "en", false); err != nil || !switched || lang != "ru" {
+		t.Errorf("switch mode: %q, %v, %v", lang, switched, err)
+	}
+}
+
+func TestChunkDurationWarning(t *testing.T) {
+	if warning := chunkDurationWarning("ggml-small", 5000); !strings.Contains(warning, "Small") {
+		t.Errorf("short chunks for Whisper: %q", warning)
+	}
+	for _, tc := range []struct {
+		model string
+		ms    int64
+	}{{"ggml-small", 0}, {"ggml-small", 10000}, {"gigaam-v3-ctc", 5000}, {"unknown", 5000}} {
+		if warning := chunkDurationWarning(tc.model, tc.ms); warning != "" {
+			t.Errorf("%s %d ms: unexpected warning %q", tc.model, tc.ms, warning)
+		}
+	}
+}
+
+func TestDiffDialogueLayers(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range []string{"привет как дела", "всё хорошо"} {
+		chunk := &session.Chunk{ID: fmt.Sprintf("c%d", i), SessionID: sess.ID, Index: i, Duration: 10 * time.Second,
+			Dialogue: []session.TranscriptSegment{{Start: int64(i) * 10000, End: int64(i)*10000 + 1000, Text: text, Speaker: "Вы"}}}
+		if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sessMgr.SetDialogueLayer(sess.ID, session.DialogueLayerImproved, []session.TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет, как дела?", Speaker: "Вы"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr}
+
+	diff, err := s.diffDialogueLayers(sess.ID, "", session.DialogueLayerImproved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.From != session.DialogueLayerRaw || len(diff.Chunks) != 2 || diff.Replaced != 2 || diff.Inserted != 0 || diff.Deleted != 0 {
+		t.Fatalf("diff = %+v", diff)
+	}
+	if !diff.Chunks[0].Changed || diff.Chunks[1].Changed {
+		t.Errorf("changed chunks = %v, %v", diff.Chunks[0].Changed, diff.Chunks[1].Changed)
+	}
+	if op := diff.Chunks[0].Ops[0]; op.Op != ai.DiffReplace || op.From != "привет" || op.To != "Привет," {
+		t.Errorf("first op = %+v", op)
+	}
+
+	if _, err := s.diffDialogueLayers(sess.ID, "", session.DialogueLayerTranslated); err == nil {
+		t.Error("expected error for missing layer")
+	}
+}
+
+func TestModelsAPI(t *testing.T) {
+	modelMgr, err := models.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ModelMgr: modelMgr}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleModelsAPI(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/models")
+	var list struct {
+		Models []models.ModelState `json:"models"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Models) != len(models.Registry) {
+		t.Fatalf("list: status %d, %d models, err %v", rec.Code, len(list.Models), err)
+	}
+
+	rec = get("/api/models/ggml-tiny/progress")
+	var progress ModelProgressResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &progress); err != nil {
+		t.Fatalf("progress: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if progress.ID != "ggml-tiny" || progress.Status != models.ModelStatusNotDownloaded || progress.Ready {
+		t.Errorf("progress = %+v", progress)
+	}
+
+	if rec := get("/api/models/no-such-model/progress"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown model: status = %d", rec.Code)
+	}
+	if rec := get("/api/models/ggml-tiny"); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing /progress: status = %d", rec.Code)
+	}
+}
+
+func TestBatchExportWithAudio(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessMgr.SetSessionTitle(sess.ID, "Встреча")
+	if err := sessMgr.AddChunk(sess.ID, &session.Chunk{ID: "c0", SessionID: sess.ID, Duration: 10 * time.Second,
+		Dialogue: []session.TranscriptSegment{{Start: 0, End: 1000, Text: "привет", Speaker: "Вы"}}}); err != nil {
+		t.Fatal(err)
+	}
+	audio := strings.Repeat("ID3", 1000)
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte(audio), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, Config: &config.Config{ExportMaxSizeMB: 1}}
+
+	export := func(body string) (*httptest.ResponseRecorder, map[string]*zip.File) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleBatchExport(rec, httptest.NewRequest("POST", "/api/export/batch", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			return rec, nil
+		}
+		zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]*zip.File)
+		for _, f := range zr.File {
+			files[f.Name] = f
+		}
+		return rec, files
+	}
+
+	// По умолчанию - только текст
+	if _, files := export(`{"sessionIds":["` + sess.ID + `"]}`); len(files) != 1 || files["Встреча.txt"] == nil {
+		t.Errorf("default export files = %v", files)
+	}
+
+	_, files := export(`{"sessionIds":["` + sess.ID + `"],"includeAudio":true,"compressionLevel":9}`)
+	if files["Встреча.txt"] == nil || files["Встреча.mp3"] == nil {
+		t.Fatalf("export with audio files = %v", files)
+	}
+	if f := files["Встреча.mp3"]; f.Method != zip.Store || f.UncompressedSize64 != uint64(len(audio)) {
+		t.Errorf("audio entry: method %d, size %d", f.Method, f.UncompressedSize64)
+	}
+	if f := files["Встреча.txt"]; f.Method != zip.Deflate {
+		t.Errorf("text entry method = %d", f.Method)
+	}
+
+	// Уровень 0 - все файлы без сжатия
+	_, files = export(`{"sessionIds":["` + sess.ID + `"],"includeAudio":true,"compressionLevel":0}`)
+	if files["Встреча.mp3"] == nil || files["Встреча.txt"].Method != zip.Store {
+		t.Errorf("stored export files = %v", files)
+	}
+
+	if rec, _ := export(`{"sessionIds":["` + sess.ID + `"],"compressionLevel":12}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid compression level: status = %d", rec.Code)
+	}
+}
+
+// failingResponseWriter принимает заголовки, но обрывает запись тела
+type failingResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *failingResponseWriter) Header() http.Header       { return w.header }
+func (w *failingResponseWriter) WriteHeader(status int)    { w.status = status }
+func (w *failingResponseWriter) Write([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestBatchExportStreaming(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, title := range []string{"Первая", "Вторая"} {
+		sess, err := sessMgr.CreateSession(session.SessionConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessMgr.SetSessionTitle(sess.ID, title)
+		if err := sessMgr.AddChunk(sess.ID, &session.Chunk{ID: "c0", SessionID: sess.ID,
+			Dialogue: []session.TranscriptSegment{{Start: 0, End: 1000, Text: title, Speaker: "Вы"}}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sessMgr.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, sess.ID)
+	}
+	s := &Server{SessionMgr: sessMgr, Config: &config.Config{}}
+	body := `{"sessionIds":["` + ids[0] + `","00000000-0000-0000-0000-000000000000","` + ids[1] + `"]}`
+
+	// Архив пишется прямо в ответ и сбрасывается клиенту по мере готовности сессий
+	rec := httptest.NewRecorder()
+	s.handleBatchExport(rec, httptest.NewRequest("POST", "/api/export/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK || !rec.Flushed || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("status %d, flushed %v, headers %v", rec.Code, rec.Flushed, rec.Header())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ненайденная сессия пропускается, остальные экспортируются по порядку
+	if len(zr.File) != 2 || zr.File[0].Name != "Первая.txt" || zr.File[1].Name != "Вторая.txt" {
+		t.Errorf("archive entries = %d", len(zr.File))
+	}
+
+	// Ошибка записи после отправки заголовков обрывает соединение, а не отдаёт битый архив
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want http.ErrAbortHandler", r)
+		}
+	}()
+	s.handleBatchExport(&failingResponseWriter{header: http.Header{}}, httptest.NewRequest("POST", "/api/export/batch", strings.NewReader(body)))
+	t.Error("handler returned after a failed write")
+}
+
+func TestExportArchiveSizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	archive := newExportArchive(&buf, nil, 10)
+	if added, err := archive.addText("a.txt", "12345"); err != nil || !added {
+		t.Fatalf("a.txt: added=%v err=%v", added, err)
+	}
+	if added, err := archive.addText("b.txt", "1234567"); err != nil || added {
+		t.Fatalf("b.txt over the limit: added=%v err=%v", added, err)
+	}
+	if err := archive.close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "a.txt" || zr.File[1].Name != exportSkippedFile {
+		t.Errorf("archive entries = %d", len(zr.File))
+	}
+	if exportSizeLimit := (&Server{Config: &config.Config{ExportMaxSizeMB: 100}}).exportSizeLimit(500); exportSizeLimit != 100<<20 {
+		t.Errorf("request limit above the server cap: %d", exportSizeLimit)
+	}
+}
+
+func TestSingleExport(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessMgr.SetSessionTitle(sess.ID, "Встреча")
+	if err := sessMgr.AddChunk(sess.ID, &session.Chunk{ID: "c0", SessionID: sess.ID, Status: session.ChunkStatusCompleted,
+		Dialogue: []session.TranscriptSegment{{Start: 0, End: 1500, Text: "привет", Speaker: "mic"}}}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleSingleExport(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/export/" + sess.ID + "?format=srt&labelLanguage=en")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/x-subrip") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if _, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition")); err != nil || params["filename"] != "Встреча.srt" {
+		t.Errorf("Content-Disposition = %q (%v)", rec.Header().Get("Content-Disposition"), err)
+	}
+	if want := "1\n00:00:00,000 --> 00:00:01,500\nYou: привет\n"; !strings.HasPrefix(rec.Body.String(), want) {
+		t.Errorf("body = %q, want prefix %q", rec.Body.String(), want)
+	}
+
+	if rec := get("/api/export/" + sess.ID); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("default format: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get("/api/export/" + sess.ID + "?format=docx"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status = %d", rec.Code)
+	}
+	if rec := get("/api/export/6c7d4c72-a8bf-4374-ba75-0ea10e0bfa8c?format=txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing session: status = %d", rec.Code)
+	}
+}
+
+func TestSessionDialogueAPI(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := []*session.Chunk{
+		{ID: "c0", SessionID: sess.ID, Index: 0, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+			{Start: 0, End: 1000, Text: "привет", Speaker: "mic", Words: []session.TranscriptWord{{Start: 0, End: 1000, Text: "привет", Speaker: "mic"}}},
+			{Start: 3000, End: 4000, Text: "как дела", Speaker: "Собеседник 1"},
+		}},
+		{ID: "c1", SessionID: sess.ID, Index: 1, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+			{Start: 1500, End: 2500, Text: "здравствуйте", Speaker: "Собеседник 1"},
+		}},
+		{ID: "c2", SessionID: sess.ID, Index: 2, Status: session.ChunkStatusPending, Dialogue: []session.TranscriptSegment{
+			{Start: 5000, End: 6000, Text: "не готово", Speaker: "mic"},
+		}},
+	}
+	for _, chunk := range chunks {
+		if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{SessionMgr: sessMgr, sessionSpeakersCache: make(map[string]sessionSpeakersCacheEntry)}
+
+	get := func(query string) ([]session.TranscriptSegment, int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleSessionsAPI(rec, httptest.NewRequest("GET", "/api/sessions/"+sess.ID+"/dialogue"+query, nil))
+		var dialogue []session.TranscriptSegment
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &dialogue); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+		}
+		return dialogue, rec.Code
+	}
+	texts := func(dialogue []session.TranscriptSegment) string {
+		var result []string
+		for _, seg := range dialogue {
+			result = append(result, seg.Speaker+": "+seg.Text)
+		}
+		return strings.Join(result, " | ")
+	}
+
+	dialogue, code := get("")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if got, want := texts(dialogue), "Вы: привет | Собеседник 1: здравствуйте | Собеседник 1: как дела"; got != want {
+		t.Errorf("dialogue = %q, want %q", got, want)
+	}
+	if dialogue[0].Words[0].Speaker != "Вы" {
+		t.Errorf("word speaker = %q", dialogue[0].Words[0].Speaker)
+	}
+
+	if dialogue, _ := get("?speaker=0"); texts(dialogue) != "Собеседник 1: здравствуйте | Собеседник 1: как дела" {
+		t.Errorf("speaker=0: %q", texts(dialogue))
+	}
+	if dialogue, _ := get("?speaker=-1&since=500"); dialogue == nil || len(dialogue) != 0 {
+		t.Errorf("speaker=-1&since=500: %+v", dialogue)
+	}
+	if dialogue, _ := get("?since=1500"); texts(dialogue) != "Собеседник 1: здравствуйте | Собеседник 1: как дела" {
+		t.Errorf("since=1500: %q", texts(dialogue))
+	}
+	for _, query := range []string{"?since=abc", "?speaker=x", "?speaker=-2"} {
+		if _, code := get(query); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", query, code)
+		}
+	}
+}
+
+// useFakeFFmpeg подменяет FFmpeg shell-скриптом body (на -version скрипт печатает версию)
+func useFakeFFmpeg(t *testing.T, body string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	fake := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nif [ \"$1\" = \"-version\" ]; then echo 'ffmpeg version test'; exit 0; fi\n" + body
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	session.SetFFmpegPath(fake)
+	t.Cleanup(func() { session.SetFFmpegPath("") })
+}
+
+func TestSessionAudioWAV(t *testing.T) {
+	// Вместо декодирования FFmpeg отдаёт 5 с PCM (в обоих каналах кадра - его номер) и записывает аргументы в runs
+	dir := t.TempDir()
+	pcm := make([]byte, 5*session.SampleRate*4)
+	for i := 0; i < len(pcm)/4; i++ {
+		binary.LittleEndian.PutUint16(pcm[i*4:], uint16(i))
+		binary.LittleEndian.PutUint16(pcm[i*4+2:], uint16(i))
+	}
+	pcmPath, runs := filepath.Join(dir, "pcm"), filepath.Join(dir, "runs")
+	if err := os.WriteFile(pcmPath, pcm, 0644); err != nil {
+		t.Fatal(err)
+	}
+	useFakeFFmpeg(t, "echo \"$@\" >> "+runs+"\ncat "+pcmPath+"\n")
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr}
+	t.Cleanup(func() {
+		for _, entry := range s.wavCache {
+			os.Remove(entry.path)
+		}
+	})
+
+	get := func(query string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/sessions/"+sess.ID+"/audio.wav"+query, nil)
+		maps.Copy(req.Header, header)
+		rec := httptest.NewRecorder()
+		s.handleSessionsAPI(rec, req)
+		return rec
+	}
+
+	if rec := get("", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("no audio: status = %d", rec.Code)
+	}
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := get("?start=1500&end=4000", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("status %d, Content-Type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	body := rec.Body.Bytes()
+	if got := rec.Header().Get("Content-Length"); got != fmt.Sprint(len(body)) {
+		t.Errorf("Content-Length = %s, body %d bytes", got, len(body))
+	}
+	le32 := func(b []byte) uint32 { return binary.LittleEndian.Uint32(b) }
+	le16 := func(b []byte) uint16 { return binary.LittleEndian.Uint16(b) }
+	dataSize := uint32(len(body) - session.WAVHeaderSize)
+	if string(body[0:4]) != "RIFF" || le32(body[4:8]) != 36+dataSize || string(body[8:12]) != "WAVE" ||
+		string(body[12:16]) != "fmt " || string(body[36:40]) != "data" || le32(body[40:44]) != dataSize {
+		t.Errorf("invalid RIFF header: % x", body[:44])
+	}
+	if format, channels, rate, bits := le16(body[20:22]), le16(body[22:24]), le32(body[24:28]), le16(body[34:36]); format != 1 ||
+		channels != 2 || rate != session.SampleRate || bits != 16 || le32(body[28:32]) != rate*4 || le16(body[32:34]) != 4 {
+		t.Errorf("fmt: format=%d channels=%d rate=%d bits=%d", format, channels, rate, bits)
+	}
+	// Фрагмент 1.5-4 с вырезан из декодированной сессии по кадрам
+	frames := (4000 - 1500) * session.SampleRate / 1000
+	if dataSize != uint32(frames*4) || le16(body[44:]) != uint16(1500*session.SampleRate/1000) {
+		t.Errorf("fragment: %d bytes, first frame %d", dataSize, le16(body[44:]))
+	}
+
+	rec = get("", http.Header{"Range": {"bytes=0-3"}})
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "RIFF" || rec.Header().Get("Content-Range") != fmt.Sprintf("bytes 0-3/%d", 44+len(pcm)) {
+		t.Errorf("range request: status %d, body %q, Content-Range %q", rec.Code, rec.Body.String(), rec.Header().Get("Content-Range"))
+	}
+	rec = get("", http.Header{"Range": {"bytes=44-47"}})
+	if rec.Code != http.StatusPartialContent || !bytes.Equal(rec.Body.Bytes(), pcm[:4]) {
+		t.Errorf("data range request: status %d, body % x", rec.Code, rec.Body.Bytes())
+	}
+
+	// FFmpeg запускался один раз на всю сессию, без -ss/-t; после изменения full.mp3 - заново
+	ffmpegRuns := func() []string {
+		t.Helper()
+		data, err := os.ReadFile(runs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	if args := ffmpegRuns(); len(args) != 1 || strings.Contains(args[0], "-ss") || !strings.Contains(args[0], "-f s16le") {
+		t.Errorf("ffmpeg runs = %q", args)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(sess.DataDir, "full.mp3"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get("", nil); rec.Code != http.StatusOK || rec.Body.Len() != 44+len(pcm) {
+		t.Errorf("after audio change: status %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+	if args := ffmpegRuns(); len(args) != 2 {
+		t.Errorf("ffmpeg runs after audio change = %d, want 2", len(args))
+	}
+	if rec := get("?start=3000&end=2000", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("inverted range: status = %d", rec.Code)
+	}
+}
+
+func TestSpeakerSampleLoudnorm(t *testing.T) {
+	// Замер громкости печатает JSON в stderr, кодирование - свои аргументы; каждый запуск пишется в runs
+	runs := filepath.Join(t.TempDir(), "runs")
+	useFakeFFmpeg(t, "echo run >> "+runs+"\n"+
+		"case \"$*\" in *print_format=json*) echo '[Parsed_loudnorm_0] { \"input_i\" : \"-30.00\", \"input_tp\" : \"-12.00\", "+
+		"\"input_lra\" : \"4.00\", \"input_thresh\" : \"-40.00\", \"target_offset\" : \"0.20\" }' >&2 ;; *) echo \"$@\" ;; esac\n")
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &session.Chunk{ID: "c0", SessionID: sess.ID, Index: 0, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+		{Start: 2000, End: 20000, Text: "длинная реплика", Speaker: session.SpeakerLabel(0)},
+	}}
+	if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, sessionSpeakersCache: make(map[string]sessionSpeakersCacheEntry)}
+
+	get := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleSpeakerSampleAPI(rec, httptest.NewRequest("GET", "/api/speaker-sample/"+sess.ID+"/0?normalize=true", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+	countRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	args := get()
+	for _, want := range []string{"-ss 2.000", "-t 10.000", "measured_I=-30.00", "offset=0.20", "linear=true", "aresample=24000"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("encode args %q: missing %q", args, want)
+		}
+	}
+	if countRuns() != 2 {
+		t.Errorf("ffmpeg runs = %d, want measurement and encoding", countRuns())
+	}
+	if get() != args || countRuns() != 2 {
+		t.Errorf("repeated request re-ran ffmpeg (%d runs)", countRuns())
+	}
+}
+
+func TestSpeakerSampleConcatenatesShortSegments(t *testing.T) {
+	// Вместо кодирования FFmpeg печатает свои аргументы
+	useFakeFFmpeg(t, "echo \"$@\"\n")
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Все реплики собеседника короче 2 секунд и разбросаны по записи
+	speaker, other := session.SpeakerLabel(0), session.SpeakerLabel(1)
+	chunks := []*session.Chunk{
+		{ID: "c0", SessionID: sess.ID, Index: 0, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+			{Start: 1000, End: 2500, Text: "да", Speaker: speaker},
+			{Start: 2500, End: 4000, Text: "вопрос", Speaker: other},
+			{Start: 4000, End: 5500, Text: "конечно", Speaker: speaker},
+			{Start: 5500, End: 6800, Text: "согласен", Speaker: speaker}, // Продолжает предыдущую реплику
+		}},
+		{ID: "c1", SessionID: sess.ID, Index: 1, Status: session.ChunkStatusCompleted, Dialogue: []session.TranscriptSegment{
+			{Start: 30000, End: 31900, Text: "хорошо", Speaker: speaker},
+			{Start: 40000, End: 41800, Text: "понятно", Speaker: speaker},
+			{Start: 50000, End: 51500, Text: "верно", Speaker: speaker},
+			{Start: 60000, End: 61900, Text: "спасибо", Speaker: speaker}, // Обрезается по лимиту 10 секунд
+			{Start: 70000, End: 71000, Text: "пока", Speaker: speaker},
+		}},
+	}
+	for _, chunk := range chunks {
+		if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{SessionMgr: sessMgr, sessionSpeakersCache: make(map[string]sessionSpeakersCacheEntry)}
+
+	rec := httptest.NewRecorder()
+	s.handleSpeakerSampleAPI(rec, httptest.NewRequest("GET", "/api/speaker-sample/"+sess.ID+"/0", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	args := rec.Body.String()
+	for _, want := range []string{
+		"-ss 1.000 -t 1.500 -i ",
+		"-ss 4.000 -t 2.800 -i ",
+		"-ss 30.000 -t 1.900 -i ",
+		"-ss 60.000 -t 0.500 -i ",
+		"-filter_complex [0:a][1:a][2:a][3:a][4:a][5:a]concat=n=6:v=0:a=1[out] -map [out]",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args %q: missing %q", args, want)
+		}
+	}
+	for _, unwanted := range []string{"-ss 2.500", "-ss 5.500", "-ss 70.000"} {
+		if strings.Contains(args, unwanted) {
+			t.Errorf("args %q: unexpected %q", args, unwanted)
+		}
+	}
+}
+
+func TestLoudnormFilterSkipsSilence(t *testing.T) {
+	stats, err := parseLoudnormStats("[Parsed_loudnorm_0 @ 0x1]\n{\n\t\"input_i\" : \"-inf\",\n\t\"input_tp\" : \"-inf\"\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loudnormFilter(stats); ok {
+		t.Error("silent segment is normalized")
+	}
+	if _, ok := loudnormFilter(loudnormStats{InputI: "-62.5"}); ok {
+		t.Error("segment below loudness floor is normalized")
+	}
+	if filter, ok := loudnormFilter(loudnormStats{InputI: "-24.0", InputTP: "-3.0", InputLRA: "6.0", InputThresh: "-34.0", TargetOffset: "0.1"}); !ok ||
+		!strings.HasPrefix(filter, "loudnorm=I=-16.0:") {
+		t.Errorf("filter = %q, %v", filter, ok)
+	}
+	if _, err := parseLoudnormStats("no json"); err == nil {
+		t.Error("missing measurement accepted")
+	}
+}
+
+func TestExportToASS(t *testing.T) {
+	sess := &session.Session{Title: "Интервью"}
+	dialogue := []session.TranscriptSegment{
+		{Start: 0, End: 2500, Speaker: "mic", Text: "Расскажите {о себе}"},
+		{Start: 2000, End: 3723456, Speaker: "sys", Text: "Конечно,\nс удовольствием"}, // Перекрывается с mic
+		{Start: 4000, End: 5000, Speaker: "Вы", Text: "Отлично"},
+	}
+	content := (&Server{}).exportToASS(sess, dialogue, newExportLabels(""))
+
+	for _, section := range []string{"[Script Info]\n", "[V4+ Styles]\n", "[Events]\n", "Title: Интервью\n"} {
+		if !strings.Contains(content, section) {
+			t.Errorf("missing %q in\n%s", section, content)
+		}
+	}
+	wantLines := []string{
+		"Dialogue: 0,0:00:00.00,0:00:02.50,Вы,Вы,0,0,0,,Расскажите (о себе)",
+		"Dialogue: 0,0:00:02.00,1:02:03.45,Собеседник,Собеседник,0,0,0,,Конечно,\\Nс удовольствием",
+		"Dialogue: 0,0:00:04.00,0:00:05.00,Вы,Вы,0,0,0,,Отлично",
+	}
+	for _, line := range wantLines {
+		if !strings.Contains(content, line+"\n") {
+			t.Errorf("missing dialogue line %q in\n%s", line, content)
+		}
+	}
+	if n := strings.Count(content, "\nStyle: "); n != 2 {
+		t.Errorf("got %d styles, want one per speaker", n)
+	}
+
+	// Цвет детерминирован и различается у спикеров
+	if assSpeakerColour("Вы") != assSpeakerColour("Вы") || assSpeakerColour("Вы") == assSpeakerColour("Собеседник") {
+		t.Errorf("speaker colours: %s, %s", assSpeakerColour("Вы"), assSpeakerColour("Собеседник"))
+	}
+	if !strings.Contains(content, "Style: Вы,Arial,48,"+assSpeakerColour("Вы")+",") {
+		t.Errorf("style colour not applied:\n%s", content)
+	}
+}
+
+func TestSubtitleLineWrap(t *testing.T) {
+	// 200 символов: 40 слов по 4 буквы
+	words := make([]string, 40)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%03d", i)
+	}
+	text := strings.Join(words, " ")
+	if len(text) != 199 {
+		t.Fatalf("text length = %d", len(text))
+	}
+
+	timed := session.TranscriptSegment{Start: 10000, End: 30000, Speaker: "mic", Text: text}
+	for i, w := range words {
+		// Паузы неравномерные: вторая половина реплики произнесена быстрее
+		start := int64(10000 + i*600)
+		if i >= 20 {
+			start = int64(22000 + (i-20)*400)
+		}
+		timed.Words = append(timed.Words, session.TranscriptWord{Start: start, End: start + 300, Text: " " + w})
+	}
+	untimed := timed
+	untimed.Words = nil
+
+	for name, seg := range map[string]session.TranscriptSegment{"timed": timed, "untimed": untimed} {
+		cues := splitSubtitleCues(seg, "Вы: ", 42)
+		if len(cues) < 3 {
+			t.Fatalf("%s: %d cues, want the segment split", name, len(cues))
+		}
+		var joined []string
+		for i, cue := range cues {
+			if len(cue.Lines) == 0 || len(cue.Lines) > 2 {
+				t.Errorf("%s cue %d: %d lines", name, i, len(cue.Lines))
+			}
+			if !strings.HasPrefix(cue.Lines[0], "Вы: ") {
+				t.Errorf("%s cue %d: speaker missing: %q", name, i, cue.Lines[0])
+			}
+			for _, line := range cue.Lines {
+				if n := len([]rune(line)); n > 42 {
+					t.Errorf("%s cue %d: line of %d chars: %q", name, i, n, line)
+				}
+				joined = append(joined, strings.TrimPrefix(line, "Вы: "))
+			}
+			if i > 0 && cue.Start != cues[i-1].End {
+				t.Errorf("%s cue %d: starts at %d, previous ends at %d", name, i, cue.Start, cues[i-1].End)
+			}
+			if cue.End <= cue.Start {
+				t.Errorf("%s cue %d: empty interval %d-%d", name, i, cue.Start, cue.End)
+			}
+		}
+		if got := strings.Join(joined, " "); got != text {
+			t.Errorf("%s: text changed:\n%s", name, got)
+		}
+		if cues[0].Start != seg.Start || cues[len(cues)-1].End != seg.End {
+			t.Errorf("%s: cues span %d-%d, segment %d-%d", name, cues[0].Start, cues[len(cues)-1].End, seg.Start, seg.End)
+		}
+
+		// Субтитр начинается вместе со своим первым словом (по таймингам или пропорционально символам)
+		second := cues[1]
+		firstWord := strings.Fields(strings.TrimPrefix(second.Lines[0], "Вы: "))[0]
+		index := slices.Index(words, firstWord)
+		want := seg.Start + (seg.End-seg.Start)*int64(index)/int64(len(words)) // Все слова одной длины
+		if name == "timed" {
+			want = seg.Words[index].Start
+		}
+		if second.Start != want {
+			t.Errorf("%s: second cue starts at %d, want %d", name, second.Start, want)
+		}
+	}
+
+	// Слово длиннее строки рвётся
+	long := session.TranscriptSegment{Start: 0, End: 1000, Text: strings.Repeat("ы", 100)}
+	for _, cue := range splitSubtitleCues(long, "", 42) {
+		for _, line := range cue.Lines {
+			if n := len([]rune(line)); n > 42 {
+				t.Errorf("hard break: line of %d chars", n)
+			}
+		}
+	}
+
+	// Короткая реплика - один субтитр на всё время сегмента
+	srt := (&Server{}).exportToSRT([]session.TranscriptSegment{{Start: 0, End: 1000, Speaker: "mic", Text: "Коротко"}}, newExportLabels(""))
+	if srt != "1\n00:00:00,000 --> 00:00:01,000\nВы: Коротко\n\n" {
+		t.Errorf("short SRT = %q", srt)
+	}
+	vtt := (&Server{}).exportToVTT([]session.TranscriptSegment{untimed}, newExportLabels(""))
+	if !strings.Contains(vtt, "\n<v Вы>w000") || strings.Count(vtt, "<v Вы>") < 3 {
+		t.Errorf("long VTT cue not split:\n%s", vtt)
+	}
+}
+
+func TestApplyRegionMergeSettings(t *testing.T) {
+	s := &Server{TranscriptionService: service.NewTranscriptionService(nil, nil)}
+	defaults := service.DefaultRegionMergeConfig()
+
+	if err := s.applyRegionMergeSettings(Message{}); err != nil || s.TranscriptionService.RegionMerge != defaults {
+		t.Fatalf("message without region merge fields changed config: %+v, %v", s.TranscriptionService.RegionMerge, err)
+	}
+
+	tooLong := int64(60000)
+	if err := s.applyRegionMergeSettings(Message{RegionMergeMaxGapMs: &tooLong}); err == nil {
+		t.Error("expected error for max gap over the limit")
+	}
+	if s.TranscriptionService.RegionMerge != defaults {
+		t.Errorf("invalid settings applied: %+v", s.TranscriptionService.RegionMerge)
+	}
+
+	// Заданное поле меняется, отсутствующее остаётся прежним; 0 - допустимое значение
+	zero := int64(0)
+	if err := s.applyRegionMergeSettings(Message{RegionMergeMinMs: &zero}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.TranscriptionService.RegionMerge; got.MinRegionMs != 0 || got.MaxGapMs != defaults.MaxGapMs {
+		t.Errorf("region merge = %+v", got)
+	}
+}
+
+func TestMatchSessionSpeakersMessage(t *testing.T) {
+	root := t.TempDir()
+	sessMgr, err := session.NewManager(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal([]service.SessionSpeakerProfile{{SpeakerID: 0, Embedding: []float32{1, 0, 0}, Duration: 12}})
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "speaker_profiles.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := voiceprint.NewStore(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add("Алексей", []float32{0.95, 0.3, 0}, "sys"); err != nil {
+		t.Fatal(err)
+	}
+	transcription := service.NewTranscriptionService(sessMgr, nil)
+	s := &Server{SessionMgr: sessMgr, TranscriptionService: transcription}
+
+	var reply Message
+	send := func(msg Message) error {
+		reply = msg
+		return nil
+	}
+
+	s.processMessage(send, Message{Type: "match_session_speakers"})
+	if reply.Type != "error" || reply.Data != "sessionId is required" {
+		t.Errorf("without sessionId: %+v", reply)
+	}
+	s.processMessage(send, Message{Type: "match_session_speakers", SessionID: sess.ID})
+	if reply.Type != "error" || !strings.Contains(reply.Data, "matcher") {
+		t.Errorf("without matcher: %+v", reply)
+	}
+
+	transcription.VoicePrintMatcher = voiceprint.NewMatcher(store)
+	s.processMessage(send, Message{Type: "match_session_speakers", SessionID: sess.ID})
+	if reply.Type != "session_speaker_matches" || reply.SessionID != sess.ID || len(reply.SpeakerMatches) != 1 {
+		t.Fatalf("reply = %+v", reply)
+	}
+	if m := reply.SpeakerMatches[0]; !m.Matched || m.Name != "Алексей" || m.LocalID != 0 {
+		t.Errorf("match = %+v", m)
+	}
+}
+
+// hookClient вызывает onSend для каждого сообщения (синхронно, из broadcast)
+type hookClient struct {
+	onSend func(Message)
+}
+
+func (c *hookClient) Send(msg Message) error {
+	c.onSend(msg)
+	return nil
+}
+
+func (c *hookClient) Close() error { return nil }
+
+func TestFullRetranscriptionCancel_PartialResult(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		chunk := &session.Chunk{ID: fmt.Sprintf("c%d", i), SessionID: sess.ID, Index: i, StartMs: int64(i) * 30000, EndMs: int64(i+1) * 30000}
+		if err := sessMgr.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profilesPath := filepath.Join(sess.DataDir, "speaker_profiles.json")
+	if err := os.WriteFile(profilesPath, []byte(`[{"SpeakerID":1,"Embedding":[1,0],"Duration":3}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		SessionMgr:             sessMgr,
+		TranscriptionService:   service.NewTranscriptionService(sessMgr, nil),
+		clients:                make(map[transportClient]bool),
+		retranscribeCancels:    make(map[string]func()),
+		speakerRenamesCache:    make(map[string]map[string]string),
+		fullRetranscribeActive: make(map[string]bool),
+	}
+	send := func(Message) error { return nil }
+
+	cancelled := make(chan Message, 1)
+	s.addClient(&hookClient{onSend: func(msg Message) {
+		switch msg.Type {
+		case "full_transcription_progress":
+			// Профили уже загружены как якоря: файл должен появиться снова при отмене
+			if msg.Progress == 0 {
+				os.Remove(profilesPath)
+				s.processMessage(send, Message{Type: "cancel_full_transcription", SessionID: sess.ID})
+			}
+		case "full_transcription_cancelled":
+			cancelled <- msg
+		}
+	}})
+
+	s.startFullRetranscription(send, sess.ID, false)
+
+	var msg Message
+	select {
+	case msg = <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("full_transcription_cancelled not broadcast")
+	}
+	// Отмена на втором чанке: первый обработан, сессия приходит клиенту
+	if msg.Session == nil || msg.Session.ID != sess.ID || len(msg.Session.Chunks) != 3 {
+		t.Fatalf("cancelled message session = %+v", msg.Session)
+	}
+	if msg.Progress <= 0 || msg.Progress >= 1 {
+		t.Errorf("progress = %v", msg.Progress)
+	}
+
+	data, err := os.ReadFile(profilesPath)
+	if err != nil {
+		t.Fatalf("partial speaker profiles not saved: %v", err)
+	}
+	var profiles []service.SessionSpeakerProfile
+	if err := json.Unmarshal(data, &profiles); err != nil || len(profiles) != 1 || profiles[0].SpeakerID != 1 {
+		t.Errorf("saved profiles = %s (err %v)", data, err)
+	}
+}
+
+func TestStartSessionRejectsInvalidSettingsBeforeChanges(t *testing.T) {
+	transcription := service.NewTranscriptionService(nil, nil)
+	transcription.SetVADMode(session.VADModeCompression)
+	s := &Server{TranscriptionService: transcription}
+
+	var reply Message
+	send := func(msg Message) error {
+		reply = msg
+		return nil
+	}
+	tooLong := int64(time.Hour / time.Millisecond)
+	for name, msg := range map[string]Message{
+		"hybrid":       {HybridEnabled: true, HybridSecondaryModelID: "ggml-base", HybridRegionPaddingMs: -1},
+		"voice mixing": {VoiceMixing: "unknown"},
+		"region merge": {RegionMergeMaxGapMs: &tooLong},
+	} {
+		msg.Type = "start_session"
+		msg.VADMode = string(session.VADModeOff)
+		reply = Message{}
+		s.processMessage(send, msg)
+		if reply.Type != "error" {
+			t.Errorf("%s: reply = %+v, want error", name, reply)
+		}
+		// Отклонённый запрос не меняет настройки транскрипции
+		if transcription.VADMode != session.VADModeCompression {
+			t.Errorf("%s: VAD mode changed to %q", name, transcription.VADMode)
+		}
+	}
+}
+
+func TestTaskStartersRejectBusySession(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateImportSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sessMgr.AddChunk(sess.ID, &session.Chunk{ID: "c0", SessionID: sess.ID, EndMs: 30000}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		SessionMgr:             sessMgr,
+		TranscriptionService:   service.NewTranscriptionService(sessMgr, nil),
+		clients:                make(map[transportClient]bool),
+		retranscribeCancels:    make(map[string]func()),
+		speakerRenamesCache:    make(map[string]map[string]string),
+		fullRetranscribeActive: make(map[string]bool),
+	}
+	var replies []Message
+	send := func(msg Message) error {
+		replies = append(replies, msg)
+		return nil
+	}
+
+	// Сессия переносится: ретранскрипция не запускается
+	release, err := sessMgr.TryMarkSessionBusy(sess.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	s.startFullRetranscription(send, sess.ID, false)
+
+	if len(replies) != 1 || replies[0].Type != "full_transcription_error" || !strings.Contains(replies[0].Error, "busy") {
+		t.Fatalf("replies = %+v, want busy full_transcription_error", replies)
+	}
+	s.retranscribeCancelsMu.Lock()
+	_, started := s.retranscribeCancels[sess.ID]
+	s.retranscribeCancelsMu.Unlock()
+	if started {
+		t.Error("retranscription registered for a busy session")
+	}
+}
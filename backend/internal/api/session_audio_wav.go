@@ -0,0 +1,246 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"aiwisper/session"
+)
+
+// wavExportChannels каналы WAV экспорта: записи хранятся в стерео (MIC слева, SYS справа)
+const wavExportChannels = 2
+
+// handleSessionAudioWAV отдаёт аудио сессии, декодированное из full.mp3 в WAV PCM 16 бит,
+// с правильным заголовком и Content-Length. Range запросы поддерживаются, чтобы браузер мог
+// перематывать без загрузки всего файла. Декодированная сессия кешируется (см. sessionWAV).
+// GET /api/sessions/{id}/audio.wav?start={ms}&end={ms} (без start/end - вся сессия)
+func (s *Server) handleSessionAudioWAV(w http.ResponseWriter, r *http.Request, sess *session.Session) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var startMs, endMs int64
+	for name, target := range map[string]*int64{"start": &startMs, "end": &endMs} {
+		if v := query.Get(name); v != "" {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || ms < 0 {
+				http.Error(w, fmt.Sprintf("Invalid %s: %s", name, v), http.StatusBadRequest)
+				return
+			}
+			*target = ms
+		}
+	}
+	if endMs > 0 && endMs <= startMs {
+		http.Error(w, fmt.Sprintf("Invalid range: %d-%d ms", startMs, endMs), http.StatusBadRequest)
+		return
+	}
+
+	audioPath := session.PlaybackAudioPath(sess.DataDir)
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := session.RequireFFmpeg(); err != nil {
+		writeHTTPError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	file, entry, err := s.sessionWAV(sess.ID, audioPath, info.ModTime())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// Фрагмент вырезается из декодированной сессии по границам кадров
+	frameSize := int64(wavExportChannels * 2)
+	byteOffset := func(ms int64) int64 {
+		return min(ms*int64(entry.sampleRate)/1000*frameSize, entry.dataSize)
+	}
+	offset, end := byteOffset(startMs), entry.dataSize
+	if endMs > 0 {
+		end = byteOffset(endMs)
+	}
+	content := wavRange{
+		header: session.WAVHeader(uint32(end-offset), entry.sampleRate, wavExportChannels),
+		data:   io.NewSectionReader(file, session.WAVHeaderSize+offset, end-offset),
+	}
+
+	name := fmt.Sprintf("%s.wav", sess.ID)
+	if startMs > 0 || endMs > 0 {
+		name = fmt.Sprintf("%s_%d-%d.wav", sess.ID, startMs, endMs)
+	}
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", name))
+	http.ServeContent(w, r, name, info.ModTime(), io.NewSectionReader(content, 0, session.WAVHeaderSize+end-offset))
+}
+
+// wavCacheSessions сколько декодированных WAV сессий держать во временных файлах
+const wavCacheSessions = 2
+
+// wavCacheEntry WAV сессии, декодированный целиком; действителен, пока не изменился исходный файл
+type wavCacheEntry struct {
+	source  string
+	modTime time.Time
+	ready   chan struct{} // Закрывается после декодирования
+
+	path       string // Временный WAV файл
+	sampleRate int
+	dataSize   int64
+	err        error
+}
+
+// sessionWAV открывает WAV сессии, декодированный из audioPath. FFmpeg запускается один раз
+// на версию исходного файла (modTime): Range запросы при перемотке и фрагменты читают готовый файл
+func (s *Server) sessionWAV(sessionID, audioPath string, modTime time.Time) (*os.File, *wavCacheEntry, error) {
+	s.wavCacheMu.Lock()
+	if s.wavCache == nil {
+		s.wavCache = make(map[string]*wavCacheEntry)
+	}
+	entry := s.wavCache[sessionID]
+	if entry == nil || entry.source != audioPath || !entry.modTime.Equal(modTime) {
+		if entry != nil {
+			s.dropWAVCacheLocked(sessionID)
+		}
+		for old := range s.wavCache {
+			if len(s.wavCache) < wavCacheSessions {
+				break
+			}
+			s.dropWAVCacheLocked(old)
+		}
+		entry = &wavCacheEntry{source: audioPath, modTime: modTime, ready: make(chan struct{})}
+		s.wavCache[sessionID] = entry
+		s.wavCacheMu.Unlock()
+
+		entry.path, entry.sampleRate, entry.dataSize, entry.err = decodeWAVFile(audioPath)
+		close(entry.ready)
+	} else {
+		s.wavCacheMu.Unlock()
+		<-entry.ready
+	}
+
+	s.wavCacheMu.Lock()
+	defer s.wavCacheMu.Unlock()
+	if entry.err != nil {
+		// Следующий запрос попробует декодировать заново
+		if s.wavCache[sessionID] == entry {
+			delete(s.wavCache, sessionID)
+		}
+		return nil, nil, entry.err
+	}
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, entry, nil
+}
+
+// dropWAVCacheLocked убирает WAV сессии из кеша и удаляет его файл, когда декодирование закончится.
+// Вызывается под s.wavCacheMu
+func (s *Server) dropWAVCacheLocked(sessionID string) {
+	entry := s.wavCache[sessionID]
+	delete(s.wavCache, sessionID)
+	go func() {
+		<-entry.ready
+		if entry.path != "" {
+			os.Remove(entry.path)
+		}
+	}()
+}
+
+// wavRange WAV из заголовка и участка данных декодированного файла (ReaderAt для http.ServeContent)
+type wavRange struct {
+	header []byte
+	data   io.ReaderAt
+}
+
+func (r wavRange) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < int64(len(r.header)) {
+		n = copy(p, r.header[off:])
+		if n == len(p) {
+			return n, nil
+		}
+	}
+	m, err := r.data.ReadAt(p[n:], off+int64(n)-int64(len(r.header)))
+	return n + m, err
+}
+
+// decodeWAVFile декодирует аудио сессии через FFmpeg в PCM 16 бит с исходной частотой
+// дискретизации во временный WAV файл с заголовком под фактический размер данных
+func decodeWAVFile(audioPath string) (path string, sampleRate int, dataSize int64, err error) {
+	sampleRate = wavSourceSampleRate(audioPath)
+
+	file, err := os.CreateTemp("", "aiwisper-audio-*.wav")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	args := []string{"-v", "error", "-i", audioPath,
+		"-ac", strconv.Itoa(wavExportChannels),
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"pipe:1",
+	}
+
+	// Место под заголовок, данные FFmpeg пишет сразу после него
+	if _, err := file.Write(make([]byte, session.WAVHeaderSize)); err != nil {
+		return "", 0, 0, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	var stderr strings.Builder
+	cmd := exec.Command(session.GetFFmpegPath(), args...)
+	cmd.Stdout = file
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", 0, 0, fmt.Errorf("ffmpeg decode failed: %w, output: %s", err, stderr.String())
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	dataSize = size - session.WAVHeaderSize
+	if dataSize > math.MaxUint32-36 {
+		return "", 0, 0, fmt.Errorf("audio is too long for WAV (%d bytes)", dataSize)
+	}
+	if _, err := file.WriteAt(session.WAVHeader(uint32(dataSize), sampleRate, wavExportChannels), 0); err != nil {
+		return "", 0, 0, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return file.Name(), sampleRate, dataSize, nil
+}
+
+// wavSourceSampleRate частота дискретизации аудио сессии, чтобы WAV не пересэмплировался
+// (если файл не читается - частота записи)
+func wavSourceSampleRate(audioPath string) int {
+	if strings.EqualFold(filepath.Ext(audioPath), ".mp3") {
+		if reader, err := session.NewMP3Reader(audioPath); err == nil {
+			defer reader.Close()
+			if rate := reader.SampleRate(); rate > 0 {
+				return rate
+			}
+		}
+		return session.SampleRate
+	}
+	if rate, err := session.WAVSampleRate(audioPath); err == nil && rate > 0 {
+		return rate
+	}
+	return session.SampleRate
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"aiwisper/session"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// handleSessionDialogue отдаёт склеенный по чанкам диалог сессии, отсортированный по времени
+// (та же сборка, что и в экспорте), с нормализованными именами спикеров.
+// GET /api/sessions/{id}/dialogue?speaker={localID}&since={ms}
+// speaker - только реплики одного спикера (-1 - микрофон), since - реплики, начавшиеся не раньше since
+// (для опроса во время записи)
+func (s *Server) handleSessionDialogue(w http.ResponseWriter, r *http.Request, sess *session.Session) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var since int64
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = strconv.ParseInt(v, 10, 64); err != nil || since < 0 {
+			http.Error(w, "Invalid since: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+	var speakerNames []string
+	if v := query.Get("speaker"); v != "" {
+		localID, err := strconv.Atoi(v)
+		if err != nil || localID < -1 {
+			http.Error(w, "Invalid speaker: "+v, http.StatusBadRequest)
+			return
+		}
+		speakerNames = s.getSpeakerNamesForLocalIDInSession(sess.ID, localID)
+	}
+
+	dialogue := make([]session.TranscriptSegment, 0)
+	for _, seg := range collectSessionDialogue(sess) {
+		if seg.Start < since {
+			continue
+		}
+		name := session.FormatSpeakerLabel(seg.Speaker)
+		if speakerNames != nil && !slices.Contains(speakerNames, seg.Speaker) && !slices.Contains(speakerNames, name) {
+			continue
+		}
+		seg.Speaker = name
+		if seg.Words != nil {
+			words := make([]session.TranscriptWord, len(seg.Words))
+			for i, word := range seg.Words {
+				word.Speaker = session.FormatSpeakerLabel(word.Speaker)
+				words[i] = word
+			}
+			seg.Words = words
+		}
+		dialogue = append(dialogue, seg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dialogue)
+}
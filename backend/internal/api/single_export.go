@@ -0,0 +1,96 @@
+package api
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// singleExportContentTypes форматы экспорта одной сессии и их MIME-типы
+var singleExportContentTypes = map[string]string{
+	"txt":        "text/plain; charset=utf-8",
+	"srt":        "application/x-subrip; charset=utf-8",
+	"vtt":        "text/vtt; charset=utf-8",
+	"ass":        "text/x-ssa; charset=utf-8",
+	"json":       "application/json; charset=utf-8",
+	"md":         "text/markdown; charset=utf-8",
+	"rttm":       "text/plain; charset=utf-8",
+	"words-csv":  "text/csv; charset=utf-8",
+	"words-json": "application/json; charset=utf-8",
+}
+
+// handleSingleExport отдаёт экспорт одной сессии без ZIP - для копирования и предпросмотра в браузере
+// GET /api/export/{sessionId}?format=srt&labelLanguage=en&dialogueLayer=raw&splitSentences=1&mergeSpeakerTurns=1&maxLineChars=42
+func (s *Server) handleSingleExport(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, rest, err := parseSessionPath(strings.TrimPrefix(r.URL.Path, "/api/export/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rest != "" {
+		http.Error(w, "Invalid path. Expected: /api/export/{sessionID}", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "txt"
+	}
+	contentType, ok := singleExportContentTypes[format]
+	if !ok {
+		http.Error(w, "Unknown format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	dialogue, err := s.exportLayerDialogue(sess, query.Get("dialogueLayer"), query.Get("splitSentences") == "1")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	labels := newExportLabels(query.Get("labelLanguage"))
+	labels.mergeTurns = query.Get("mergeSpeakerTurns") == "1"
+	if v := query.Get("maxLineChars"); v != "" {
+		labels.maxLineChars, err = strconv.Atoi(v)
+		if err == nil {
+			err = validateSubtitleLineChars(labels.maxLineChars)
+		}
+		if err != nil {
+			http.Error(w, "Invalid maxLineChars: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+	content, ext := s.exportDialogue(sess, dialogue, format, labels)
+
+	log.Printf("Single export: session=%s, format=%s, %d bytes", sessionID, format, len(content))
+
+	// inline - браузер показывает текст, а при сохранении предлагает имя файла сессии
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": s.generateExportFilename(sess, ext),
+	}))
+	w.Write([]byte(content))
+}
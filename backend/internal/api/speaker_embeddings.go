@@ -0,0 +1,100 @@
+package api
+
+import (
+	"aiwisper/internal/service"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SpeakerEmbeddingsResponse embeddings спикеров сессии для внешней кластеризации
+type SpeakerEmbeddingsResponse struct {
+	SessionID string                    `json:"sessionId"`
+	Dimension int                       `json:"dimension"` // Размерность embeddings (0 - профилей нет)
+	Speakers  []SpeakerEmbeddingsRecord `json:"speakers"`
+}
+
+// SpeakerEmbeddingsRecord профиль одного спикера из speaker_profiles.json
+type SpeakerEmbeddingsRecord struct {
+	SpeakerID      int         `json:"speakerId"`                // ID спикера в speaker_profiles.json
+	DurationSec    float32     `json:"durationSec"`              // Общая длительность речи
+	RecognizedName string      `json:"recognizedName,omitempty"` // Имя из базы voiceprints
+	VoicePrintID   string      `json:"voiceprintId,omitempty"`
+	Embedding      []float32   `json:"embedding"`              // Усреднённый embedding спикера
+	Observations   [][]float32 `json:"observations,omitempty"` // Embeddings из отдельных чанков (?observations=1)
+}
+
+// buildSpeakerEmbeddingsResponse собирает ответ из профилей спикеров сессии
+func buildSpeakerEmbeddingsResponse(sessionID string, profiles []service.SessionSpeakerProfile, withObservations bool) SpeakerEmbeddingsResponse {
+	resp := SpeakerEmbeddingsResponse{SessionID: sessionID, Speakers: make([]SpeakerEmbeddingsRecord, 0, len(profiles))}
+	for _, p := range profiles {
+		if len(p.Embedding) == 0 {
+			continue
+		}
+		if resp.Dimension == 0 {
+			resp.Dimension = len(p.Embedding)
+		}
+		record := SpeakerEmbeddingsRecord{
+			SpeakerID:      p.SpeakerID,
+			DurationSec:    p.Duration,
+			RecognizedName: p.RecognizedName,
+			VoicePrintID:   p.VoicePrintID,
+			Embedding:      p.Embedding,
+		}
+		if withObservations {
+			record.Observations = p.Observations
+		}
+		resp.Speakers = append(resp.Speakers, record)
+	}
+	return resp
+}
+
+// handleSpeakerEmbeddingsAPI отдаёт embeddings спикеров сессии (только чтение)
+// URL: GET /api/speaker-embeddings/{sessionID}?observations=1
+func (s *Server) handleSpeakerEmbeddingsAPI(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, rest, err := parseSessionPath(strings.TrimPrefix(r.URL.Path, "/api/speaker-embeddings/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rest != "" {
+		http.Error(w, "Invalid path. Expected: /api/speaker-embeddings/{sessionID}", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.SessionMgr.GetSession(sessionID); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if s.TranscriptionService == nil {
+		http.Error(w, "Transcription service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	profiles, err := s.TranscriptionService.LoadSessionSpeakerProfiles(sessionID)
+	if err != nil {
+		log.Printf("handleSpeakerEmbeddingsAPI: failed to load speaker profiles for %s: %v", sessionID, err)
+		http.Error(w, "Failed to load speaker profiles", http.StatusInternalServerError)
+		return
+	}
+
+	withObservations := r.URL.Query().Get("observations") == "1"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildSpeakerEmbeddingsResponse(sessionID, profiles, withObservations))
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+
+	"aiwisper/session"
+)
+
+// Ограничения сэмпла спикера
+const (
+	speakerSampleMaxMs       = 10000 // Общая длительность сэмпла
+	speakerSampleMaxSegments = 20    // Сколько реплик склеивать (у коротких реплик много входов FFmpeg)
+)
+
+// sampleRange фрагмент аудио сессии [startMs, endMs)
+type sampleRange struct {
+	startMs, endMs int64
+}
+
+// speakerSampleRanges выбирает первые реплики спикера (по порядку чанков) суммарно до speakerSampleMaxMs:
+// так даже собеседник, говорящий короткими фразами, получает представительный сэмпл.
+// Соседние и перекрывающиеся реплики объединяются, последняя обрезается по лимиту
+func speakerSampleRanges(chunks []*session.Chunk, speakerNames []string) []sampleRange {
+	var ranges []sampleRange
+	var total int64
+	for _, chunk := range chunks {
+		for _, seg := range chunk.Dialogue {
+			if total >= speakerSampleMaxMs || len(ranges) >= speakerSampleMaxSegments {
+				return ranges
+			}
+			if seg.End <= seg.Start || !slices.Contains(speakerNames, seg.Speaker) {
+				continue
+			}
+			start, end := seg.Start, min(seg.End, seg.Start+speakerSampleMaxMs-total)
+			if n := len(ranges); n > 0 && start <= ranges[n-1].endMs && start >= ranges[n-1].startMs {
+				if end > ranges[n-1].endMs {
+					total += end - ranges[n-1].endMs
+					ranges[n-1].endMs = end
+				}
+				continue
+			}
+			ranges = append(ranges, sampleRange{start, end})
+			total += end - start
+		}
+	}
+	return ranges
+}
+
+// speakerSampleArgs аргументы FFmpeg для сэмпла: каждый фрагмент - отдельный вход с перемоткой,
+// входы склеиваются фильтром concat, затем применяется filter (пусто - без фильтра).
+// Результат - единственный аудио выход
+func speakerSampleArgs(audioPath string, ranges []sampleRange, filter string) []string {
+	var args []string
+	for _, r := range ranges {
+		args = append(args,
+			"-ss", fmt.Sprintf("%.3f", float64(r.startMs)/1000),
+			"-t", fmt.Sprintf("%.3f", float64(r.endMs-r.startMs)/1000),
+			"-i", audioPath,
+		)
+	}
+	if len(ranges) == 1 {
+		if filter != "" {
+			args = append(args, "-af", filter)
+		}
+		return args
+	}
+
+	var graph strings.Builder
+	for i := range ranges {
+		fmt.Fprintf(&graph, "[%d:a]", i)
+	}
+	fmt.Fprintf(&graph, "concat=n=%d:v=0:a=1", len(ranges))
+	if filter != "" {
+		graph.WriteString("," + filter)
+	}
+	graph.WriteString("[out]")
+	return append(args, "-filter_complex", graph.String(), "-map", "[out]")
+}
+
+// extractSpeakerSampleWAV склеивает фрагменты без FFmpeg в один WAV
+func extractSpeakerSampleWAV(audioPath string, ranges []sampleRange) ([]byte, error) {
+	var header, data []byte
+	for _, r := range ranges {
+		part, err := session.ExtractSegmentWAV(audioPath, r.startMs, r.endMs)
+		if err != nil {
+			return nil, err
+		}
+		if len(part) < session.WAVHeaderSize {
+			return nil, fmt.Errorf("invalid WAV segment %d-%d ms", r.startMs, r.endMs)
+		}
+		if header == nil {
+			header = slices.Clone(part[:session.WAVHeaderSize])
+		}
+		data = append(data, part[session.WAVHeaderSize:]...)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("no segments to extract")
+	}
+	// Все фрагменты из одного файла: формат общий, меняются только размеры
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+len(data)))
+	binary.LittleEndian.PutUint32(header[40:44], uint32(len(data)))
+	return append(header, data...), nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aiwisper/session"
+)
+
+// Параметры выравнивания громкости сэмплов спикеров (loudnorm, EBU R128)
+const (
+	speakerSampleTargetLUFS   = -16.0 // Целевая громкость
+	speakerSampleLoudnessMin  = -50.0 // Ниже - почти тишина: нормализация только усилит шум
+	speakerSampleCacheEntries = 64    // Сколько нормализованных сэмплов держать в памяти
+)
+
+// speakerSampleCacheEntry нормализованный сэмпл; действителен, пока не изменились фрагменты и аудио сессии
+type speakerSampleCacheEntry struct {
+	ranges       []sampleRange
+	audioModTime time.Time
+	data         []byte
+}
+
+// loudnormStats замер громкости первым проходом loudnorm (print_format=json)
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// normalizedSpeakerSample склеивает фрагменты ranges с выравниванием громкости и кодирует в MP3.
+// Громкость сначала замеряется, и почти тихий сэмпл (ниже speakerSampleLoudnessMin) не усиливается.
+// Результат кешируется по сессии и спикеру
+func (s *Server) normalizedSpeakerSample(sessionID string, localSpeakerID int, audioPath string, ranges []sampleRange) ([]byte, error) {
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s/%d", sessionID, localSpeakerID)
+
+	s.speakerSampleCacheMu.Lock()
+	cached, ok := s.speakerSampleCache[key]
+	s.speakerSampleCacheMu.Unlock()
+	if ok && slices.Equal(cached.ranges, ranges) && cached.audioModTime.Equal(info.ModTime()) {
+		return cached.data, nil
+	}
+
+	// Первый проход: замер громкости
+	measureArgs := append([]string{"-hide_banner", "-nostats"}, speakerSampleArgs(audioPath, ranges, loudnormBaseFilter()+":print_format=json")...)
+	measureArgs = append(measureArgs, "-f", "null", "-")
+	var stderr strings.Builder
+	cmd := exec.Command(session.GetFFmpegPath(), measureArgs...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg loudness measurement failed: %w", err)
+	}
+	stats, err := parseLoudnormStats(stderr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Второй проход: линейная нормализация по замеру и кодирование в MP3
+	filter, ok := loudnormFilter(stats)
+	if ok {
+		// loudnorm работает на 192 kHz - возвращаем исходную частоту
+		filter += fmt.Sprintf(",aresample=%d", wavSourceSampleRate(audioPath))
+	} else {
+		log.Printf("Speaker sample %s: loudness %s LUFS is below %.0f LUFS, skipping normalization", key, stats.InputI, speakerSampleLoudnessMin)
+	}
+	encodeArgs := append(speakerSampleArgs(audioPath, ranges, filter), session.MP3EncodeArgs(session.MP3TargetSample)...)
+	encodeArgs = append(encodeArgs, "-f", "mp3", "pipe:1")
+	data, err := exec.Command(session.GetFFmpegPath(), encodeArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg loudness normalization failed: %w", err)
+	}
+
+	s.speakerSampleCacheMu.Lock()
+	if s.speakerSampleCache == nil {
+		s.speakerSampleCache = make(map[string]speakerSampleCacheEntry)
+	}
+	if _, exists := s.speakerSampleCache[key]; !exists && len(s.speakerSampleCache) >= speakerSampleCacheEntries {
+		// Вытесняем произвольный сэмпл: кэш нужен для повторных прослушиваний подряд
+		for old := range s.speakerSampleCache {
+			delete(s.speakerSampleCache, old)
+			break
+		}
+	}
+	s.speakerSampleCache[key] = speakerSampleCacheEntry{ranges: ranges, audioModTime: info.ModTime(), data: data}
+	s.speakerSampleCacheMu.Unlock()
+	return data, nil
+}
+
+// loudnormBaseFilter фильтр loudnorm с целевыми параметрами
+func loudnormBaseFilter() string {
+	return fmt.Sprintf("loudnorm=I=%.1f:TP=-1.5:LRA=11", speakerSampleTargetLUFS)
+}
+
+// parseLoudnormStats извлекает JSON замера из вывода FFmpeg (последний блок {...})
+func parseLoudnormStats(output string) (loudnormStats, error) {
+	var stats loudnormStats
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return stats, fmt.Errorf("loudnorm measurement not found in ffmpeg output")
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &stats); err != nil {
+		return stats, fmt.Errorf("failed to parse loudnorm measurement: %w", err)
+	}
+	return stats, nil
+}
+
+// loudnormFilter фильтр второго прохода по замеру. ok=false - фрагмент почти тихий
+// (или громкость не измерена, например "-inf") и нормализовать его не нужно
+func loudnormFilter(stats loudnormStats) (string, bool) {
+	inputI, err := strconv.ParseFloat(stats.InputI, 64)
+	if err != nil || inputI < speakerSampleLoudnessMin {
+		return "", false
+	}
+	return fmt.Sprintf("%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		loudnormBaseFilter(), stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset), true
+}
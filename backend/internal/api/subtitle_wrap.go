@@ -0,0 +1,164 @@
+package api
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSubtitleLineChars длина строки субтитров по умолчанию (рекомендация Netflix/BBC для латиницы и кириллицы)
+const DefaultSubtitleLineChars = 42
+
+// minSubtitleLineChars минимальная допустимая длина строки: короче - почти каждое слово на своей строке
+const minSubtitleLineChars = 10
+
+// subtitleLinesPerCue строк в одном субтитре, длинная реплика делится на несколько субтитров
+const subtitleLinesPerCue = 2
+
+// validateSubtitleLineChars проверяет длину строки субтитров из запроса (0 - по умолчанию)
+func validateSubtitleLineChars(chars int) error {
+	if chars != 0 && chars < minSubtitleLineChars {
+		return fmt.Errorf("invalid maxLineChars %d: must be at least %d (0 - default %d)", chars, minSubtitleLineChars, DefaultSubtitleLineChars)
+	}
+	return nil
+}
+
+// subtitleCue субтитр: интервал показа и строки (не больше subtitleLinesPerCue)
+type subtitleCue struct {
+	Start int64
+	End   int64
+	Lines []string
+}
+
+// subtitleToken слово реплики; timed - есть тайминги слова из ASR
+type subtitleToken struct {
+	text       string
+	start, end int64
+	timed      bool
+}
+
+// splitSubtitleCues переносит текст сегмента по словам в строки не длиннее maxChars символов
+// (слово длиннее строки рвётся) и делит реплику на субтитры по две строки.
+// prefix (подпись спикера) начинает каждый субтитр и учитывается в длине первой строки.
+// Время субтитров - по таймингам слов, если они совпадают с текстом, иначе пропорционально числу символов
+func splitSubtitleCues(seg session.TranscriptSegment, prefix string, maxChars int) []subtitleCue {
+	if maxChars <= 0 {
+		maxChars = DefaultSubtitleLineChars
+	}
+	// Куски длинного слова не длиннее строки вместе с подписью (если подпись не занимает большую часть)
+	pieceChars := max(maxChars-utf8.RuneCountInString(prefix), maxChars/2)
+	tokens := subtitleTokens(seg, pieceChars)
+	if len(tokens) == 0 {
+		return []subtitleCue{{Start: seg.Start, End: seg.End, Lines: []string{prefix}}}
+	}
+
+	// Жадно заполняем строки; первая строка каждого субтитра начинается с prefix
+	var lines []string
+	var lineStarts []int // Индекс первого токена строки
+	current, empty := prefix, true
+	for i, token := range tokens {
+		if !empty && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(token.text) > maxChars {
+			lines = append(lines, current)
+			current, empty = "", true
+			if len(lines)%subtitleLinesPerCue == 0 {
+				current = prefix
+			}
+		}
+		if empty {
+			lineStarts = append(lineStarts, i)
+			current += token.text
+		} else {
+			current += " " + token.text
+		}
+		empty = false
+	}
+	lines = append(lines, current)
+
+	if len(lines) <= subtitleLinesPerCue {
+		return []subtitleCue{{Start: seg.Start, End: seg.End, Lines: lines}}
+	}
+
+	// Реплика не помещается в один субтитр: делим время по первым словам субтитров
+	timeAt := subtitleTokenTimes(seg, tokens)
+	var cues []subtitleCue
+	for i := 0; i < len(lines); i += subtitleLinesPerCue {
+		cue := subtitleCue{Start: timeAt[lineStarts[i]], End: seg.End, Lines: lines[i:min(i+subtitleLinesPerCue, len(lines))]}
+		if n := len(cues); n > 0 {
+			cues[n-1].End = cue.Start
+		}
+		cues = append(cues, cue)
+	}
+	cues[0].Start = seg.Start
+	return cues
+}
+
+// subtitleTokens слова сегмента для переноса. Тайминги слов берутся, только если слова складываются
+// в текст сегмента (после ручной правки или LLM они могут не совпадать)
+func subtitleTokens(seg session.TranscriptSegment, maxChars int) []subtitleToken {
+	fields := strings.Fields(seg.Text)
+	var tokens []subtitleToken
+
+	var wordFields []string
+	for _, w := range seg.Words {
+		wordFields = append(wordFields, strings.Fields(w.Text)...)
+	}
+	if len(seg.Words) > 0 && strings.Join(wordFields, " ") == strings.Join(fields, " ") {
+		for _, w := range seg.Words {
+			for _, f := range strings.Fields(w.Text) {
+				tokens = append(tokens, subtitleToken{text: f, start: w.Start, end: w.End, timed: true})
+			}
+		}
+	} else {
+		for _, f := range fields {
+			tokens = append(tokens, subtitleToken{text: f})
+		}
+	}
+
+	// Слово длиннее строки рвётся на куски, время слова делится по символам
+	var result []subtitleToken
+	for _, token := range tokens {
+		runes := []rune(token.text)
+		if len(runes) <= maxChars {
+			result = append(result, token)
+			continue
+		}
+		for i := 0; i < len(runes); i += maxChars {
+			end := min(i+maxChars, len(runes))
+			piece := token
+			piece.text = string(runes[i:end])
+			if token.timed {
+				dur := token.end - token.start
+				piece.start = token.start + dur*int64(i)/int64(len(runes))
+				piece.end = token.start + dur*int64(end)/int64(len(runes))
+			}
+			result = append(result, piece)
+		}
+	}
+	return result
+}
+
+// subtitleTokenTimes время начала каждого токена: по таймингам слов или пропорционально символам
+func subtitleTokenTimes(seg session.TranscriptSegment, tokens []subtitleToken) []int64 {
+	times := make([]int64, len(tokens))
+	if tokens[0].timed {
+		var last int64
+		for i, token := range tokens {
+			// Тайминги слов не убывают, даже если ASR выдал перекрытие
+			last = max(last, token.start, seg.Start)
+			times[i] = min(last, seg.End)
+		}
+		return times
+	}
+
+	total := 0
+	for _, token := range tokens {
+		total += utf8.RuneCountInString(token.text)
+	}
+	before := 0
+	for i, token := range tokens {
+		times[i] = seg.Start + (seg.End-seg.Start)*int64(before)/int64(total)
+		before += utf8.RuneCountInString(token.text)
+	}
+	return times
+}
@@ -1,52 +1,100 @@
 package api
 
 import (
+	"aiwisper/ai"
 	"aiwisper/audio"
+	"aiwisper/internal/service"
 	"aiwisper/models"
 	"aiwisper/session"
 	"aiwisper/voiceprint"
 	"time"
 )
 
+// Коды ошибок (Message.Code), по которым клиент может предложить действие пользователю
+const (
+	ErrorCodeFFmpegNotFound = "ffmpeg_not_found" // FFmpeg не установлен или путь -ffmpeg неверный
+)
+
 // Message WebSocket message structure
 type Message struct {
 	Type string `json:"type"`
 	Data string `json:"data,omitempty"`
+	Code string `json:"code,omitempty"` // Код ошибки для type=error (ErrorCode*)
 
 	// Start Session Parameters
-	Language          string  `json:"language,omitempty"`
-	Model             string  `json:"model,omitempty"`
-	MicDevice         string  `json:"micDevice,omitempty"`
-	SystemDevice      string  `json:"systemDevice,omitempty"`
-	CaptureSystem     bool    `json:"captureSystem,omitempty"`
-	UseNative         bool    `json:"useNativeCapture,omitempty"`
-	UseVoiceIsolation bool    `json:"useVoiceIsolation,omitempty"`
-	VADMode           string  `json:"vadMode,omitempty"`   // auto, compression, per-region, off
-	VADMethod         string  `json:"vadMethod,omitempty"` // energy, silero, auto
-	EchoCancel        float64 `json:"echoCancel,omitempty"`
-	PauseThreshold    float64 `json:"pauseThreshold,omitempty"` // Порог паузы для сегментации (0.3-2.0 сек)
+	Language          string   `json:"language,omitempty"`
+	Model             string   `json:"model,omitempty"`
+	MicDevice         string   `json:"micDevice,omitempty"`
+	SystemDevice      string   `json:"systemDevice,omitempty"`
+	CaptureSystem     bool     `json:"captureSystem,omitempty"`
+	UseNative         bool     `json:"useNativeCapture,omitempty"`
+	CaptureApp        string   `json:"captureApp,omitempty"` // Bundle ID приложения для захвата системного звука
+	UseVoiceIsolation bool     `json:"useVoiceIsolation,omitempty"`
+	VoiceMixing       string   `json:"voiceMixing,omitempty"`     // Микширование каналов Voice Isolation: paired, padded, resampled
+	ChunkDurationMs   int64    `json:"chunkDurationMs,omitempty"` // Длительность чанков записи (5000-120000 мс, 0 - по умолчанию)
+	VADMode           string   `json:"vadMode,omitempty"`         // auto, compression, per-region, off
+	VADMethod         string   `json:"vadMethod,omitempty"`       // energy, silero, auto
+	MicVADMethod      string   `json:"micVadMethod,omitempty"`    // Метод VAD для MIC канала (пусто - vadMethod)
+	SysVADMethod      string   `json:"sysVadMethod,omitempty"`    // Метод VAD для SYS канала (пусто - vadMethod)
+	MicVADThreshold   float64  `json:"micVadThreshold,omitempty"` // Порог VAD для MIC (0 - по умолчанию)
+	SysVADThreshold   float64  `json:"sysVadThreshold,omitempty"` // Порог VAD для SYS (0 - по умолчанию)
+	VADMinSilenceMs   int64    `json:"vadMinSilenceMs,omitempty"` // Energy VAD: тишина, завершающая регион речи (0 - 300 мс)
+	VADMinSpeechMs    int64    `json:"vadMinSpeechMs,omitempty"`  // Energy VAD: минимальная длина региона речи (0 - 100 мс)
+	VADHangoverMs     int64    `json:"vadHangoverMs,omitempty"`   // Energy VAD: hangover после конца речи (0 - 100 мс)
+	EchoCancel        float64  `json:"echoCancel,omitempty"`
+	PauseThreshold    float64  `json:"pauseThreshold,omitempty"`   // Порог паузы для сегментации (0.3-2.0 сек)
+	DiarizeMic        bool     `json:"diarizeMic,omitempty"`       // Диаризация MIC канала (несколько человек у одного микрофона)
+	MicOnly           bool     `json:"micOnly,omitempty"`          // Диктовка: только микрофон, без системного звука и диаризации
+	SwapChannels      string   `json:"swapChannels,omitempty"`     // Перестановка MIC/SYS каналов: auto, on, off
+	RecordRawCapture  bool     `json:"recordRawCapture,omitempty"` // Сохранять сырой поток захвата для отладки
+	FallbackModels    []string `json:"fallbackModels,omitempty"`   // Запасные модели по порядку, если основная не загрузилась
+	ForceLanguage     bool     `json:"forceLanguage,omitempty"`    // Не проверять язык по списку языков модели
+
+	// Диапазон для retranscribe_range и reassign_speaker_range (мс от начала записи)
+	RangeStartMs int64 `json:"rangeStartMs,omitempty"`
+	RangeEndMs   int64 `json:"rangeEndMs,omitempty"`
+
+	// Момент разделения для split_session (мс от начала записи)
+	SplitMs int64 `json:"splitMs,omitempty"`
 
 	// Responses
 	Session   *session.Session `json:"session,omitempty"`
 	Sessions  []*SessionInfo   `json:"sessions,omitempty"`
 	Chunk     *session.Chunk   `json:"chunk,omitempty"`
 	SessionID string           `json:"sessionId,omitempty"`
+	// Фактически применённый к чанку метод VAD в chunk_transcribed ("mic/sys", если каналы различаются)
+	VADMethodUsed string `json:"vadMethodUsed,omitempty"`
 
 	// Audio levels
 	MicLevel    float64 `json:"micLevel,omitempty"`
 	SystemLevel float64 `json:"systemLevel,omitempty"`
 
 	// Devices
-	Devices                   []audio.AudioDevice `json:"devices,omitempty"`
-	ScreenCaptureKitAvailable bool                `json:"screenCaptureKitAvailable,omitempty"`
+	Devices                   []audio.AudioDevice   `json:"devices,omitempty"`
+	ScreenCaptureKitAvailable bool                  `json:"screenCaptureKitAvailable,omitempty"`
+	CapturableApps            []audio.CapturableApp `json:"capturableApps,omitempty"` // Приложения для захвата через ScreenCaptureKit
 
 	// Models
 	Models    []models.ModelState `json:"models,omitempty"`
 	ModelID   string              `json:"modelId,omitempty"`
 	ModelName string              `json:"modelName,omitempty"` // Human-readable название модели
 	Progress  float64             `json:"progress,omitempty"`
+	ModelIDs  []string            `json:"modelIds,omitempty"` // Список моделей (для benchmark_models)
 	Error     string              `json:"error,omitempty"`
 
+	// Возможности активной модели (active_model_changed, models_list)
+	Capabilities *ai.EngineCapabilities `json:"capabilities,omitempty"`
+
+	// Очередь транскрипции (get_queue_status, transcription_backlog)
+	QueueStatus *service.QueueStatus `json:"queueStatus,omitempty"`
+
+	// Прогресс распознавания внутри чанка (chunk_progress)
+	ChunkProgress *service.ChunkProgress `json:"chunkProgress,omitempty"`
+
+	// Benchmark моделей
+	BenchmarkResults []service.ModelBenchmarkResult `json:"benchmarkResults,omitempty"`
+	SkippedModels    []string                       `json:"skippedModels,omitempty"` // Не скачанные или неизвестные модели
+
 	// Summary
 	Summary string `json:"summary,omitempty"`
 
@@ -55,15 +103,40 @@ type Message struct {
 	OllamaUrl    string        `json:"ollamaUrl,omitempty"`
 	OllamaModels []OllamaModel `json:"ollamaModels,omitempty"`
 
+	// AudioQualityWarning проблема входного аудио чанка (audio_quality_warning)
+	AudioQualityWarning *service.AudioQualityWarning `json:"audioQualityWarning,omitempty"`
+
+	// LLMOptions параметры генерации для generate_summary и improve_transcription (поверх конфигурации)
+	LLMOptions *service.LLMOptions `json:"llmOptions,omitempty"`
+
+	// DialogueLayer слой диалога сессии: результат improve/diarize, выбор в set_dialogue_layer ("raw" - исходный ASR)
+	DialogueLayer string `json:"dialogueLayer,omitempty"`
+	// BaseLayer слой, с которым сравнивается dialogueLayer в diff_layers (пусто - "raw")
+	BaseLayer string     `json:"baseLayer,omitempty"`
+	LayerDiff *LayerDiff `json:"layerDiff,omitempty"`
+
 	// Diarization
-	DiarizationEnabled    bool   `json:"diarizationEnabled,omitempty"`
-	DiarizationProvider   string `json:"diarizationProvider,omitempty"` // cpu, coreml, cuda, auto
-	DiarizationBackend    string `json:"diarizationBackend,omitempty"`  // sherpa (default), fluid (FluidAudio/CoreML)
-	SegmentationModelPath string `json:"segmentationModelPath,omitempty"`
-	EmbeddingModelPath    string `json:"embeddingModelPath,omitempty"`
+	DiarizationEnabled    bool    `json:"diarizationEnabled,omitempty"`
+	DiarizationProvider   string  `json:"diarizationProvider,omitempty"`  // cpu, coreml, cuda, auto
+	DiarizationBackend    string  `json:"diarizationBackend,omitempty"`   // sherpa (default), fluid (FluidAudio/CoreML)
+	MinSpeakerRatio       float64 `json:"minSpeakerRatio,omitempty"`      // Мин. доля речи спикера в чанке (0-0.5, 0 - 10%)
+	MinSpeakerSegmentSec  float64 `json:"minSpeakerSegmentSec,omitempty"` // Мин. длительность сегмента диаризации (0-5 сек, 0 - 1 сек)
+	ClusteringThreshold   float64 `json:"clusteringThreshold,omitempty"`  // Порог кластеризации (0.1-0.95, 0 - 0.5): ниже - похожие голоса разделяются
+	MinDurationOn         float64 `json:"minDurationOn,omitempty"`        // Мин. длительность речи для диаризации (0-5 сек, 0 - 0.3 сек)
+	MinDurationOff        float64 `json:"minDurationOff,omitempty"`       // Мин. длительность паузы для диаризации (0-5 сек, 0 - 0.5 сек)
+	MinOverlapRatio       float64 `json:"minOverlapRatio,omitempty"`      // Мин. доля слова под каждым из двух спикеров для отметки наложения речи (0-1, 0 - 0.5)
+	SegmentationModelPath string  `json:"segmentationModelPath,omitempty"`
+	EmbeddingModelPath    string  `json:"embeddingModelPath,omitempty"`
+
+	// Порог сходства спикеров между чанками (0.3-0.95, 0 - не менять), отдельный от порогов voiceprints
+	SpeakerMatchThreshold float64 `json:"speakerMatchThreshold,omitempty"`
+
+	// Сравнение бэкендов диаризации
+	DiarizationComparison []service.DiarizationBackendResult `json:"diarizationComparison,omitempty"`
 
 	// Auto-improve with LLM
-	AutoImproveEnabled bool `json:"autoImproveEnabled,omitempty"`
+	AutoImproveEnabled bool   `json:"autoImproveEnabled,omitempty"`
+	AutoImproveScope   string `json:"autoImproveScope,omitempty"` // "chunk" (по умолчанию) или "session"
 
 	// VoicePrint (спикеры)
 	VoicePrints      []voiceprint.VoicePrint     `json:"voiceprints,omitempty"`
@@ -75,17 +148,50 @@ type Message struct {
 	VoicePrintID     string                      `json:"voiceprintId,omitempty"`
 	Similarity       float32                     `json:"similarity,omitempty"`
 
+	// Пакетное сопоставление спикеров сессии с базой voiceprints (match_session_speakers)
+	SpeakerMatches []service.SpeakerMatchProposal `json:"speakerMatches,omitempty"`
+
+	// Спикеры других сессий с похожим голосом (find_similar_speakers, порог - SpeakerMatchThreshold)
+	SimilarSpeakers []service.SimilarSpeaker `json:"similarSpeakers,omitempty"`
+
+	// Автоматическое именование спикеров через LLM
+	SpeakerNames map[string]string `json:"speakerNames,omitempty"` // "Собеседник N" -> имя
+	Preview      bool              `json:"preview,omitempty"`      // Только вернуть предложение, не применять
+
+	// Индекс ключевых терминов с таймстемпами (get_keyword_index)
+	KeywordIndex *session.KeywordIndex `json:"keywordIndex,omitempty"`
+
+	// Скорость распознавания сессии: RTF по чанкам (get_session_stats)
+	ProcessingStats *session.ProcessingStats `json:"processingStats,omitempty"`
+
+	// Предпросмотр VAD + сжатия без распознавания (preview_compression)
+	CompressionPreview *service.CompressionPreview `json:"compressionPreview,omitempty"`
+
+	// Сессии пакетного импорта (import_batch_progress, import_batch_completed), присоединяемые (merge_sessions)
+	// и части разделённой сессии (session_split)
+	SessionIDs []string `json:"sessionIds,omitempty"`
+
+	// Лимит диаризации при полной ретранскрипции (diarization_warning)
+	DiarizationLimit *DiarizationLimit `json:"diarizationLimit,omitempty"`
+
+	// Speaker Timeline (Gantt-представление активности спикеров)
+	SpeakerTimeline []SpeakerTimelineTrack `json:"speakerTimeline,omitempty"`
+
 	// Merge Speakers
 	SourceSpeakerIDs []int `json:"sourceSpeakerIds,omitempty"` // LocalIDs спикеров для объединения
 	TargetSpeakerID  int   `json:"targetSpeakerId,omitempty"`  // LocalID целевого спикера
 	MergeEmbeddings  bool  `json:"mergeEmbeddings,omitempty"`  // Усреднять embeddings
-	MergedCount      int   `json:"mergedCount,omitempty"`      // Количество объединённых сегментов
+	MergedCount      int   `json:"mergedCount,omitempty"`      // Количество объединённых (merge_speakers) или переназначенных (reassign_speaker_range) сегментов
 
 	// Streaming Transcription (real-time updates)
 	StreamingText                  string  `json:"streamingText,omitempty"`                  // Текущий текст (volatile или confirmed)
 	StreamingIsConfirmed           bool    `json:"streamingIsConfirmed,omitempty"`           // true = confirmed, false = volatile
 	StreamingConfidence            float32 `json:"streamingConfidence,omitempty"`            // Уверенность модели (0.0-1.0)
 	StreamingTimestamp             int64   `json:"streamingTimestamp,omitempty"`             // Unix timestamp в миллисекундах
+	StreamingCorrection            bool    `json:"streamingCorrection,omitempty"`            // streamingText исправляет неподтверждённый текст с streamingRevisedFromMs
+	StreamingRevisedFromMs         int64   `json:"streamingRevisedFromMs,omitempty"`         // Начало исправленного участка (мс от начала потока)
+	StreamingConfirmedEndMs        int64   `json:"streamingConfirmedEndMs,omitempty"`        // Граница подтверждённого текста (мс от начала потока)
+	StreamingTentativeText         string  `json:"streamingTentativeText,omitempty"`         // Весь текущий неподтверждённый хвост
 	StreamingChunkSeconds          float64 `json:"streamingChunkSeconds,omitempty"`          // Размер чанка в секундах (1-30)
 	StreamingConfirmationThreshold float64 `json:"streamingConfirmationThreshold,omitempty"` // Порог подтверждения (0.5-1.0)
 
@@ -99,6 +205,13 @@ type Message struct {
 	HybridOllamaModel         string   `json:"hybridOllamaModel,omitempty"`         // Модель Ollama для LLM
 	HybridOllamaURL           string   `json:"hybridOllamaUrl,omitempty"`           // URL Ollama API
 	HybridHotwords            []string `json:"hybridHotwords,omitempty"`            // Словарь подсказок (термины, имена)
+	HybridRegionMergeGapMs    int64    `json:"hybridRegionMergeGapMs,omitempty"`    // Пауза объединения проблемных участков (мс)
+	HybridRegionPaddingMs     int64    `json:"hybridRegionPaddingMs,omitempty"`     // Запас аудио вокруг участка (мс)
+
+	// Склейка коротких VAD регионов в per-region режиме (start_session, retranscribe_full, reprocess_session).
+	// Указатели: 0 - допустимое значение (не склеивать / без паузы), отсутствие поля - текущие настройки
+	RegionMergeMinMs    *int64 `json:"regionMergeMinMs,omitempty"`
+	RegionMergeMaxGapMs *int64 `json:"regionMergeMaxGapMs,omitempty"`
 
 	// Search (поиск сессий)
 	SearchQuery   string              `json:"searchQuery,omitempty"`   // Текстовый поиск
@@ -109,6 +222,11 @@ type Message struct {
 	Title string   `json:"title,omitempty"` // Название сессии
 	Tags  []string `json:"tags,omitempty"`  // Теги сессии
 	Tag   string   `json:"tag,omitempty"`   // Отдельный тег (для add/remove)
+
+	// Политика хранения (set_retention_policy) и закрепление сессий (set_session_pinned)
+	Pinned          bool                     `json:"pinned,omitempty"`
+	RetentionPolicy *session.RetentionPolicy `json:"retentionPolicy,omitempty"`
+	RetentionReport *session.RetentionReport `json:"retentionReport,omitempty"` // Итог очистки (retention_cleanup)
 }
 
 type OllamaModel struct {
@@ -126,6 +244,7 @@ type SessionInfo struct {
 	TotalDuration int64     `json:"totalDuration"`
 	ChunksCount   int       `json:"chunksCount"`
 	Title         string    `json:"title,omitempty"`
+	Pinned        bool      `json:"pinned,omitempty"`
 }
 
 // SearchSessionInfo расширенная информация о сессии с результатами поиска
@@ -134,3 +253,18 @@ type SearchSessionInfo struct {
 	MatchedText  string `json:"matchedText,omitempty"`  // Текст, где найдено совпадение
 	MatchContext string `json:"matchContext,omitempty"` // Контекст вокруг совпадения
 }
+
+// SpeakerTimelineInterval интервал активности спикера (мс от начала сессии)
+type SpeakerTimelineInterval struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// SpeakerTimelineTrack дорожка спикера в таймлайне сессии (кто и когда говорил)
+type SpeakerTimelineTrack struct {
+	LocalID       int                       `json:"localId"`
+	DisplayName   string                    `json:"displayName"`
+	IsMic         bool                      `json:"isMic"`
+	TotalDuration float32                   `json:"totalDuration"` // Суммарная длительность интервалов (сек)
+	Intervals     []SpeakerTimelineInterval `json:"intervals"`
+}
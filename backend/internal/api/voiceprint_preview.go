@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"aiwisper/voiceprint"
+)
+
+// Сколько совпадений возвращает предпросмотр voiceprint
+const (
+	voiceprintPreviewDefaultLimit = 3
+	voiceprintPreviewMaxLimit     = 10
+)
+
+// VoicePrintPreviewMatch похожий voiceprint из базы
+type VoicePrintPreviewMatch struct {
+	VoicePrintID string  `json:"voiceprintId"`
+	Name         string  `json:"name"`
+	Similarity   float32 `json:"similarity"`
+	Confidence   string  `json:"confidence"` // "high", "medium", "low"
+}
+
+// VoicePrintPreview результат предпросмотра: с какими voiceprints совпадёт спикер, если его сохранить
+type VoicePrintPreview struct {
+	SessionID      string                   `json:"sessionId"`
+	LocalSpeakerID int                      `json:"localSpeakerId"`
+	Source         string                   `json:"source"`  // "mic" или "sys"
+	Matches        []VoicePrintPreviewMatch `json:"matches"` // По убыванию сходства, пусто - похожих нет
+}
+
+// handleVoiceprintPreview сравнивает спикера сессии с базой voiceprints, ничего не сохраняя:
+// UI может предупредить, что такой голос уже есть ("похож на 'Алексей' на 0.91 - объединить?")
+// POST /api/voiceprints/preview {"sessionId": "...", "localSpeakerId": 0, "limit": 3}
+func (s *Server) handleVoiceprintPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		SessionID      string `json:"sessionId"`
+		LocalSpeakerID int    `json:"localSpeakerId"`
+		Limit          int    `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.SessionID == "" {
+		http.Error(w, "sessionId is required", http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 || req.Limit > voiceprintPreviewMaxLimit {
+		http.Error(w, fmt.Sprintf("Invalid limit: %d (expected 1-%d)", req.Limit, voiceprintPreviewMaxLimit), http.StatusBadRequest)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = voiceprintPreviewDefaultLimit
+	}
+
+	// Микрофон не проходит диаризацию - сравнивать нечего
+	if req.LocalSpeakerID < 0 {
+		http.Error(w, "No embedding available for the microphone speaker: only diarized speakers can be matched", http.StatusUnprocessableEntity)
+		return
+	}
+	if s.TranscriptionService == nil {
+		http.Error(w, "Transcription service not available", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := s.SessionMgr.GetSession(req.SessionID); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	embedding, source, err := s.getSpeakerEmbedding(req.SessionID, req.LocalSpeakerID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("No embedding available for speaker %d: %v", req.LocalSpeakerID, err), http.StatusNotFound)
+		return
+	}
+
+	preview := VoicePrintPreview{
+		SessionID:      req.SessionID,
+		LocalSpeakerID: req.LocalSpeakerID,
+		Source:         source,
+		Matches:        []VoicePrintPreviewMatch{},
+	}
+	if s.VoicePrintMatcher != nil {
+		// Порог тот же, что у FindBestMatch: первое совпадение - то, что выберет распознавание
+		matches := s.VoicePrintMatcher.FindAllMatches(embedding, voiceprint.ThresholdMin)
+		for _, match := range matches[:min(len(matches), req.Limit)] {
+			preview.Matches = append(preview.Matches, VoicePrintPreviewMatch{
+				VoicePrintID: match.VoicePrint.ID,
+				Name:         match.VoicePrint.Name,
+				Similarity:   match.Similarity,
+				Confidence:   match.Confidence,
+			})
+		}
+	}
+	log.Printf("[VoicePrint] Preview for speaker %d in session %s: %d matches", req.LocalSpeakerID, req.SessionID, len(preview.Matches))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"aiwisper/session"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// wordTimingRow строка экспорта таймингов слов (форматы words-csv и words-json)
+// для forced-aligners, караоке и других инструментов синхронизации
+type wordTimingRow struct {
+	Start   float64  `json:"start"` // Секунды от начала записи
+	End     float64  `json:"end"`
+	Text    string   `json:"text"`
+	P       *float32 `json:"p,omitempty"` // Вероятность слова, у строк-сегментов нет
+	Speaker string   `json:"speaker"`
+	Segment bool     `json:"segment,omitempty"` // Модель не выдала слов - строка на весь сегмент
+}
+
+// wordTimingRows разворачивает диалог в строки по словам.
+// Сегменты без слов экспортируются целиком с флагом Segment
+func wordTimingRows(dialogue []session.TranscriptSegment, labels exportLabels) []wordTimingRow {
+	var rows []wordTimingRow
+	for _, seg := range dialogue {
+		segSpeaker := labels.speaker(seg.Speaker)
+		if len(seg.Words) == 0 {
+			if strings.TrimSpace(seg.Text) == "" {
+				continue
+			}
+			rows = append(rows, wordTimingRow{
+				Start:   msToSeconds(seg.Start),
+				End:     msToSeconds(seg.End),
+				Text:    strings.TrimSpace(seg.Text),
+				Speaker: segSpeaker,
+				Segment: true,
+			})
+			continue
+		}
+		for _, word := range seg.Words {
+			text := strings.TrimSpace(word.Text)
+			if text == "" {
+				continue
+			}
+			// Спикер слова задан не всеми движками, по умолчанию - спикер сегмента
+			speaker := segSpeaker
+			if word.Speaker != "" && word.Speaker != seg.Speaker {
+				speaker = labels.speaker(word.Speaker)
+			}
+			p := word.P
+			rows = append(rows, wordTimingRow{
+				Start:   msToSeconds(word.Start),
+				End:     msToSeconds(word.End),
+				Text:    text,
+				P:       &p,
+				Speaker: speaker,
+			})
+		}
+	}
+	return rows
+}
+
+// msToSeconds переводит миллисекунды в секунды
+func msToSeconds(ms int64) float64 {
+	return float64(ms) / 1000
+}
+
+// exportToWordsCSV экспортирует тайминги слов в CSV: start,end,text,p,speaker,segment
+func exportToWordsCSV(dialogue []session.TranscriptSegment, labels exportLabels) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write([]string{"start", "end", "text", "p", "speaker", "segment"})
+	for _, row := range wordTimingRows(dialogue, labels) {
+		p := ""
+		if row.P != nil {
+			p = strconv.FormatFloat(float64(*row.P), 'f', 3, 32)
+		}
+		w.Write([]string{
+			strconv.FormatFloat(row.Start, 'f', 3, 64),
+			strconv.FormatFloat(row.End, 'f', 3, 64),
+			row.Text,
+			p,
+			row.Speaker,
+			strconv.FormatBool(row.Segment),
+		})
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// exportToWordsJSON экспортирует тайминги слов в JSON
+func exportToWordsJSON(sess *session.Session, dialogue []session.TranscriptSegment, labels exportLabels) string {
+	rows := wordTimingRows(dialogue, labels)
+	if rows == nil {
+		rows = []wordTimingRow{}
+	}
+	export := map[string]interface{}{
+		"id":        sess.ID,
+		"title":     sess.Title,
+		"startTime": sess.StartTime,
+		"words":     rows,
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
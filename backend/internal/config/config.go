@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -19,6 +21,104 @@ type Config struct {
 	OllamaURL          string // URL Ollama API (по умолчанию http://localhost:11434)
 	OllamaModel        string // Модель для улучшения транскрипции
 	AutoImproveWithLLM bool   // Автоматически улучшать транскрипцию через LLM
+	AutoImproveScope   string // Область автоулучшения: "chunk" или "session"
+
+	// Параметры генерации LLM по операциям
+	LLMSummary    LLMGeneration
+	LLMImprove    LLMGeneration
+	LLMSelectBest LLMGeneration
+
+	// FallbackModels упорядоченный список запасных моделей, если основная не загрузилась
+	FallbackModels []string
+
+	// ModelLanguages переопределение языков моделей: ID модели -> коды языков
+	ModelLanguages map[string][]string
+	// LanguageMismatch реакция на язык, который модель не поддерживает: "error" или "switch" (язык модели)
+	LanguageMismatch string
+
+	// FFmpegPath путь к FFmpeg (пусто - автоматический поиск)
+	FFmpegPath string
+
+	// SileroVADModel путь к ONNX модели Silero VAD (пусто - стандартное расположение с автоскачиванием)
+	SileroVADModel string
+
+	// Mp3Quality качество VBR при кодировании MP3 через FFmpeg (0 - лучшее, 9 - минимальный размер,
+	// -1 - не задано: запись 128k CBR, импорт VBR q2)
+	Mp3Quality int
+
+	// Нормализация громкости каналов перед транскрипцией
+	NormalizeLoudness  bool
+	LoudnessTargetDBFS float64 // Целевой RMS уровень речи (dBFS)
+	// NormalizeNoiseFloorDBFS уровень канала, ниже которого нормализация не усиливает сигнал (dBFS)
+	NormalizeNoiseFloorDBFS float64
+
+	// ChunkOverlap перекрытие соседних чанков при распознавании (0 - без перекрытия)
+	ChunkOverlap time.Duration
+
+	// Контекст между чанками: конец текста предыдущего чанка как initial prompt Whisper
+	PromptCarryOver      bool
+	PromptCarryOverChars int // Максимальная длина контекста в символах
+
+	// Удаление эха между MIC и SYS каналами
+	CrosstalkDedup         bool
+	CrosstalkMinOverlap    float64 // Доля перекрытия по времени (0-1)
+	CrosstalkMinSimilarity float64 // Минимальная схожесть текста (0-1)
+
+	// Веса оценки качества чанка 0-100 (формула - session.QualityWeights)
+	QualityWeightConfidence float64 // Средняя вероятность слов
+	QualityWeightSpeech     float64 // Доля речи по VAD
+	QualityWeightClipping   float64 // Отсутствие клиппинга
+
+	// TranscribeTimeout таймаут распознавания одного канала чанка (0 - без ограничения)
+	TranscribeTimeout time.Duration
+
+	// MaxConcurrentTranscriptions число одновременно распознаваемых чанков (0 - по числу ядер)
+	MaxConcurrentTranscriptions int
+
+	// EngineIdleUnload время простоя, после которого модель выгружается из памяти (0 - не выгружать)
+	EngineIdleUnload time.Duration
+
+	// Лимиты диаризации при полной ретранскрипции (утечка памяти sherpa-onnx)
+	RetranscribeDiarizationMaxChunks int // Максимум чанков с диаризацией (0 - без ограничения)
+	RetranscribeDiarizationMaxMemMB  int // Допустимый рост пикового RSS за ретранскрипцию (0 - не проверять)
+
+	// SilenceGuardDBFS регионы речи тише порога не распознаются (защита от галлюцинаций на тишине), 0 - выключено
+	SilenceGuardDBFS float64
+
+	// ClipWarnRatio доля клиппированных сэмплов чанка для предупреждения о перегрузе (0 - не проверять)
+	ClipWarnRatio float64
+
+	// SpeakerMatchThreshold порог косинусного сходства спикеров между чанками (0.3-0.95)
+	SpeakerMatchThreshold float64
+
+	// Склейка коротких VAD регионов в per-region режиме (GigaAM)
+	RegionMinMs    int64 // Регион короче склеивается с соседним (0 - не склеивать)
+	RegionMaxGapMs int64 // Максимальная пауза между склеиваемыми регионами
+
+	// Схема стандартных имён спикеров ("Вы", "Собеседник 1"...)
+	SpeakerLabelSelf    string // Имя владельца микрофона
+	SpeakerLabelPrefix  string // Префикс собеседников
+	SpeakerLabelBase    int    // Номер первого собеседника (0 или 1)
+	SpeakerLabelLetters bool   // Нумерация буквами вместо цифр
+
+	// RetentionCheckInterval период фоновой очистки по политике хранения сессий (0 - не очищать)
+	RetentionCheckInterval time.Duration
+
+	// ExportMaxSizeMB лимит суммарного размера файлов пакетного экспорта (0 - без ограничения)
+	ExportMaxSizeMB int
+
+	// RawCaptureMaxMB лимит дампа сырого потока захвата (SessionConfig.RecordRawCapture), 0 - дамп запрещён
+	RawCaptureMaxMB int
+
+	// ImportRoot папка, внутри которой пакетный импорт читает папки на сервере (пусто - только загруженные файлы)
+	ImportRoot string
+}
+
+// LLMGeneration параметры генерации Ollama для одной операции
+type LLMGeneration struct {
+	Temperature float64 // Отрицательное - по умолчанию
+	TopP        float64 // 0 - по умолчанию модели
+	MaxTokens   int     // num_predict, 0 - по умолчанию
 }
 
 func Load() *Config {
@@ -33,6 +133,48 @@ func Load() *Config {
 	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "Ollama API URL")
 	ollamaModel := flag.String("ollama-model", "", "Ollama model for transcription improvement (from UI settings)")
 	autoImprove := flag.Bool("auto-improve", false, "Auto-improve transcription with LLM")
+	autoImproveScope := flag.String("auto-improve-scope", "chunk", "Auto-improve each chunk as it is transcribed (chunk) or the whole dialogue once after recording stops (session)")
+	llmSummary := llmGenerationFlags("summary", 0.3, 4096)
+	llmImprove := llmGenerationFlags("improve", 0.1, 16384)
+	llmSelectBest := llmGenerationFlags("select-best", 0.1, 512)
+
+	fallbackModels := flag.String("fallback-models", "", "Comma-separated ordered list of fallback model IDs (default: any downloaded model)")
+	modelLanguages := flag.String("model-languages", "", "Override supported languages of transcription models: id=lang+lang,id2=lang")
+	languageMismatch := flag.String("language-mismatch", "error", "What to do when the session language is not supported by the model: error (reject) or switch (use the model's language); forceLanguage in a request skips the check")
+	ffmpegPath := flag.String("ffmpeg", "", "Path to the ffmpeg binary (default: bundled, next to the backend or from PATH)")
+	sileroVADModel := flag.String("silero-vad-model", "", "Path to a custom Silero VAD ONNX model (default: downloaded to the models directory); energy VAD is used if the file is missing")
+	mp3Quality := flag.Int("mp3-quality", -1, "MP3 VBR quality for ffmpeg encoding (0 best - 9 smallest, -1 keeps per-encoder defaults: 128k CBR recordings, q2 imports)")
+	normalizeLoudness := flag.Bool("normalize-loudness", false, "Normalize per-channel loudness before VAD and transcription")
+	loudnessTarget := flag.Float64("loudness-target", -20, "Target speech RMS level in dBFS for loudness normalization")
+	normalizeNoiseFloor := flag.Float64("normalize-noise-floor", -48, "Channel level in dBFS below which normalization applies no gain, so near-silent channels are not amplified into false speech")
+	chunkOverlap := flag.Duration("chunk-overlap", 0, "Audio overlap between adjacent chunks (e.g. 1s) so words cut by a chunk boundary are heard whole; the overlap is trimmed at the boundary (0 disables)")
+	promptCarryOver := flag.Bool("prompt-carry-over", false, "Pass the tail of the previous chunk's text to Whisper as initial prompt")
+	promptCarryOverChars := flag.Int("prompt-carry-over-chars", 200, "Maximum length of the carried-over prompt in characters")
+	crosstalkDedup := flag.Bool("crosstalk-dedup", false, "Drop duplicated phrases picked up by both mic and system channels")
+	crosstalkOverlap := flag.Float64("crosstalk-overlap", 0.5, "Minimum time overlap ratio (0-1) for crosstalk dedup")
+	crosstalkSimilarity := flag.Float64("crosstalk-similarity", 0.6, "Minimum text similarity (0-1) for crosstalk dedup")
+	qualityWeightConfidence := flag.Float64("quality-weight-confidence", 0.6, "Weight of average word confidence in the per-chunk quality score")
+	qualityWeightSpeech := flag.Float64("quality-weight-speech", 0.25, "Weight of the VAD speech ratio in the per-chunk quality score")
+	qualityWeightClipping := flag.Float64("quality-weight-clipping", 0.15, "Weight of the absence of clipping in the per-chunk quality score")
+	transcribeTimeout := flag.Duration("transcribe-timeout", 5*time.Minute, "Per-chunk transcription timeout (0 disables)")
+	maxConcurrentTranscriptions := flag.Int("max-concurrent-transcriptions", 0, "Maximum number of chunks transcribed at once, live and retranscription alike; the rest wait in queue (0 - half of CPU cores, up to 4)")
+
+	engineIdleUnload := flag.Duration("engine-idle-unload", 0, "Unload the ASR model after this idle period, reloading on demand (0 disables)")
+	retranscribeDiarizationMaxChunks := flag.Int("retranscribe-diarization-max-chunks", 10, "Disable diarization for full retranscription of sessions with more chunks than this (0 disables the cap)")
+	retranscribeDiarizationMaxMemMB := flag.Int("retranscribe-diarization-max-mem-mb", 0, "Stop diarizing during full retranscription once peak RSS grows by this many MB (0 disables)")
+	silenceGuard := flag.Float64("silence-guard-dbfs", -60, "Skip ASR for speech regions quieter than this RMS level in dBFS, so Whisper does not hallucinate on silence that VAD let through (0 disables)")
+	clipWarnRatio := flag.Float64("clip-warn-ratio", 0.001, "Warn about clipped input when this fraction of a chunk's samples hits full scale (0 disables)")
+	speakerMatchThreshold := flag.Float64("speaker-match-threshold", 0.65, "Cosine similarity (0.3-0.95) above which a diarized speaker is matched to a speaker from earlier chunks; higher splits, lower merges")
+	regionMinMs := flag.Int64("region-min-ms", 2000, "Per-region VAD mode: merge speech regions shorter than this with a neighbour, in ms (0 disables merging)")
+	regionMaxGapMs := flag.Int64("region-max-gap-ms", 3000, "Per-region VAD mode: maximum pause between regions that may be merged, in ms")
+	speakerLabelSelf := flag.String("speaker-label-self", "Вы", "Label of the microphone owner in transcripts and exports")
+	speakerLabelPrefix := flag.String("speaker-label-prefix", "Собеседник", "Label prefix for other speakers, e.g. \"Участник\" or \"Guest\"")
+	speakerLabelBase := flag.Int("speaker-label-base", 1, "Number of the first speaker (0 or 1)")
+	speakerLabelLetters := flag.Bool("speaker-label-letters", false, "Number speakers with letters (Guest A, Guest B) instead of digits")
+	retentionCheckInterval := flag.Duration("retention-check-interval", time.Hour, "How often old sessions are cleaned up according to the retention policy set by clients; pinned and recording sessions are never touched (0 disables)")
+	exportMaxSizeMB := flag.Int("export-max-size-mb", 2048, "Maximum total size in MB of files in a batch export archive, audio included; files over the cap are skipped (0 disables)")
+	rawCaptureMaxMB := flag.Int("raw-capture-max-mb", 0, "Allow sessions to dump the raw capture stream for debugging, capped at this size in MB (0 disables)")
+	importRoot := flag.String("import-root", "", "Directory whose subfolders batch import may read by server-side path (empty: uploaded files only)")
 
 	flag.Parse()
 
@@ -52,7 +194,106 @@ func Load() *Config {
 		OllamaURL:          *ollamaURL,
 		OllamaModel:        *ollamaModel,
 		AutoImproveWithLLM: *autoImprove,
+		AutoImproveScope:   *autoImproveScope,
+		LLMSummary:         llmSummary.get(),
+		LLMImprove:         llmImprove.get(),
+		LLMSelectBest:      llmSelectBest.get(),
+		FallbackModels:     splitList(*fallbackModels),
+		LanguageMismatch:   *languageMismatch,
+		ModelLanguages:     parseModelLanguages(*modelLanguages),
+		FFmpegPath:         *ffmpegPath,
+		SileroVADModel:     *sileroVADModel,
+		Mp3Quality:         *mp3Quality,
+		TranscribeTimeout:  *transcribeTimeout,
+		EngineIdleUnload:   *engineIdleUnload,
+		RawCaptureMaxMB:    *rawCaptureMaxMB,
+		ExportMaxSizeMB:    *exportMaxSizeMB,
+		ImportRoot:         *importRoot,
+
+		RetentionCheckInterval: *retentionCheckInterval,
+		ClipWarnRatio:          *clipWarnRatio,
+		SilenceGuardDBFS:       *silenceGuard,
+
+		SpeakerMatchThreshold: *speakerMatchThreshold,
+
+		MaxConcurrentTranscriptions: *maxConcurrentTranscriptions,
+
+		RetranscribeDiarizationMaxChunks: *retranscribeDiarizationMaxChunks,
+		RetranscribeDiarizationMaxMemMB:  *retranscribeDiarizationMaxMemMB,
+
+		NormalizeLoudness:  *normalizeLoudness,
+		LoudnessTargetDBFS: *loudnessTarget,
+
+		NormalizeNoiseFloorDBFS: *normalizeNoiseFloor,
+
+		ChunkOverlap: *chunkOverlap,
+
+		RegionMinMs:    *regionMinMs,
+		RegionMaxGapMs: *regionMaxGapMs,
+
+		SpeakerLabelSelf:    *speakerLabelSelf,
+		SpeakerLabelPrefix:  *speakerLabelPrefix,
+		SpeakerLabelBase:    *speakerLabelBase,
+		SpeakerLabelLetters: *speakerLabelLetters,
+
+		PromptCarryOver:      *promptCarryOver,
+		PromptCarryOverChars: *promptCarryOverChars,
+
+		CrosstalkDedup:         *crosstalkDedup,
+		CrosstalkMinOverlap:    *crosstalkOverlap,
+		CrosstalkMinSimilarity: *crosstalkSimilarity,
+
+		QualityWeightConfidence: *qualityWeightConfidence,
+		QualityWeightSpeech:     *qualityWeightSpeech,
+		QualityWeightClipping:   *qualityWeightClipping,
+	}
+}
+
+// llmGenerationValues флаги параметров генерации одной операции LLM
+type llmGenerationValues struct {
+	temperature, topP *float64
+	maxTokens         *int
+}
+
+// llmGenerationFlags регистрирует флаги -llm-<op>-temperature, -llm-<op>-top-p и -llm-<op>-max-tokens
+func llmGenerationFlags(op string, temperature float64, maxTokens int) llmGenerationValues {
+	return llmGenerationValues{
+		temperature: flag.Float64("llm-"+op+"-temperature", temperature, "LLM sampling temperature for "+op),
+		topP:        flag.Float64("llm-"+op+"-top-p", 0, "LLM nucleus sampling top_p for "+op+" (0 uses the model default)"),
+		maxTokens:   flag.Int("llm-"+op+"-max-tokens", maxTokens, "Maximum tokens generated by the LLM for "+op+" (num_predict)"),
+	}
+}
+
+func (v llmGenerationValues) get() LLMGeneration {
+	return LLMGeneration{Temperature: *v.temperature, TopP: *v.topP, MaxTokens: *v.maxTokens}
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseModelLanguages разбирает список "id=lang+lang,id2=lang".
+// Элемент без языков попадает в результат пустым, чтобы ошибку сообщила проверка реестра
+func parseModelLanguages(value string) map[string][]string {
+	result := make(map[string][]string)
+	for _, item := range splitList(value) {
+		id, langs, _ := strings.Cut(item, "=")
+		var list []string
+		for _, lang := range strings.Split(langs, "+") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				list = append(list, lang)
+			}
+		}
+		result[strings.TrimSpace(id)] = list
 	}
+	return result
 }
 
 func defaultGRPCAddress() string {
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"":                            nil,
+		" , ,":                        nil,
+		"ggml-base":                   {"ggml-base"},
+		" ggml-base, ,gigaam-v3-ctc ": {"ggml-base", "gigaam-v3-ctc"},
+	}
+	for value, want := range tests {
+		if got := splitList(value); !slices.Equal(got, want) {
+			t.Errorf("splitList(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"aiwisper/session"
+	"log"
+)
+
+// Проблемы качества входа (AudioQualityWarning.Issue)
+const (
+	AudioIssueClipping = "clipping" // Перегруз: сэмплы упираются в ±1.0
+)
+
+// AudioQualityWarning предупреждение о качестве входного аудио чанка (audio_quality_warning)
+type AudioQualityWarning struct {
+	SessionID  string                 `json:"sessionId"`
+	ChunkID    string                 `json:"chunkId"`
+	ChunkIndex int                    `json:"chunkIndex"`
+	Issue      string                 `json:"issue"`
+	Channel    string                 `json:"channel"` // mic, sys или mono
+	StartMs    int64                  `json:"startMs"` // Начало первого проблемного участка (от начала записи)
+	EndMs      int64                  `json:"endMs"`   // Конец последнего проблемного участка
+	Ratio      float64                `json:"ratio"`   // Доля клиппированных сэмплов в канале чанка
+	Ranges     []session.ClippedRange `json:"ranges,omitempty"`
+}
+
+// channelSamples сэмплы канала чанка
+type channelSamples struct {
+	channel string
+	samples []float32
+}
+
+// SetClipWarnRatio устанавливает долю клиппированных сэмплов для предупреждения о перегрузе (0 - не проверять)
+func (s *TranscriptionService) SetClipWarnRatio(ratio float64) {
+	s.ClipWarnRatio = max(0, ratio)
+	log.Printf("Clipping warning ratio set to: %.4f", s.ClipWarnRatio)
+}
+
+// checkChunkClipping проверяет сырые (до фильтров и нормализации) каналы чанка на клиппинг,
+// запоминает результат в чанке и уведомляет через OnAudioQualityWarning.
+// Перекрытие с предыдущим чанком не проверяется - оно уже проверено там
+func (s *TranscriptionService) checkChunkClipping(chunk *session.Chunk, extractStart int64, channels ...channelSamples) {
+	if s.ClipWarnRatio <= 0 {
+		return
+	}
+
+	skip := int(max(0, chunk.StartMs-extractStart) * session.WhisperSampleRate / 1000)
+	var clipping []session.ChannelClipping
+	for _, ch := range channels {
+		samples := ch.samples[min(skip, len(ch.samples)):]
+		c := session.DetectClipping(samples, session.WhisperSampleRate, chunk.StartMs, s.ClipWarnRatio)
+		if c == nil || len(c.Ranges) == 0 {
+			continue
+		}
+		c.Channel = ch.channel
+		clipping = append(clipping, *c)
+
+		log.Printf("Clipping detected: chunk %d (%s) %.2f%% samples clipped in %d range(s) %d-%dms",
+			chunk.Index, ch.channel, c.Ratio*100, len(c.Ranges), c.Ranges[0].StartMs, c.Ranges[len(c.Ranges)-1].EndMs)
+		if s.OnAudioQualityWarning != nil {
+			s.OnAudioQualityWarning(AudioQualityWarning{
+				SessionID:  chunk.SessionID,
+				ChunkID:    chunk.ID,
+				ChunkIndex: chunk.Index,
+				Issue:      AudioIssueClipping,
+				Channel:    ch.channel,
+				StartMs:    c.Ranges[0].StartMs,
+				EndMs:      c.Ranges[len(c.Ranges)-1].EndMs,
+				Ratio:      c.Ratio,
+				Ranges:     c.Ranges,
+			})
+		}
+	}
+	s.SessionMgr.MarkChunkClipping(chunk.SessionID, chunk.ID, clipping)
+}
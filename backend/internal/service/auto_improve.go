@@ -0,0 +1,103 @@
+package service
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"log"
+)
+
+// AutoImproveScope область автоматического улучшения транскрипции через LLM
+type AutoImproveScope string
+
+const (
+	// AutoImproveScopeChunk улучшать каждый чанк сразу после транскрипции (живая обратная связь)
+	AutoImproveScopeChunk AutoImproveScope = "chunk"
+	// AutoImproveScopeSession улучшать весь диалог один раз после остановки записи:
+	// LLM видит контекст между чанками, запросов меньше
+	AutoImproveScopeSession AutoImproveScope = "session"
+)
+
+// SetAutoImproveScope устанавливает область автоулучшения (пустая строка - по чанкам)
+func (s *TranscriptionService) SetAutoImproveScope(scope AutoImproveScope) error {
+	switch scope {
+	case "":
+		scope = AutoImproveScopeChunk
+	case AutoImproveScopeChunk, AutoImproveScopeSession:
+	default:
+		return fmt.Errorf("invalid auto-improve scope %q: must be %q or %q", scope, AutoImproveScopeChunk, AutoImproveScopeSession)
+	}
+	s.AutoImproveScope = scope
+	log.Printf("Auto-improve scope set to: %s", scope)
+	return nil
+}
+
+// ScheduleSessionAutoImprove запускает автоулучшение всей сессии после остановки записи.
+// Если чанки сессии ещё транскрибируются, улучшение выполняется после последнего из них.
+// Ничего не делает, если автоулучшение выключено или работает по чанкам
+func (s *TranscriptionService) ScheduleSessionAutoImprove(sessionID string) {
+	if !s.AutoImproveWithLLM || s.LLMService == nil || s.AutoImproveScope != AutoImproveScopeSession {
+		return
+	}
+
+	s.pendingMu.Lock()
+	s.deferredImprove[sessionID] = true
+	ready := s.takeDeferredImproveLocked(sessionID)
+	s.pendingMu.Unlock()
+
+	if ready {
+		go s.autoImproveSession(sessionID)
+	} else {
+		log.Printf("Auto-improve: session %s deferred until pending chunks are transcribed", sessionID)
+	}
+}
+
+// takeDeferredImproveLocked снимает отметку отложенного улучшения, если у сессии
+// не осталось чанков в очереди. Вызывается под pendingMu
+func (s *TranscriptionService) takeDeferredImproveLocked(sessionID string) bool {
+	if !s.deferredImprove[sessionID] {
+		return false
+	}
+	for _, p := range s.pendingChunks {
+		if p.sessionID == sessionID {
+			return false
+		}
+	}
+	delete(s.deferredImprove, sessionID)
+	return true
+}
+
+// autoImproveSession улучшает диалог всей сессии одним запросом к LLM
+func (s *TranscriptionService) autoImproveSession(sessionID string) {
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		log.Printf("Auto-improve: failed to get session: %v", err)
+		return
+	}
+
+	var dialogue []session.TranscriptSegment
+	for _, c := range sess.Chunks {
+		dialogue = append(dialogue, c.Dialogue...)
+	}
+	if len(dialogue) == 0 {
+		log.Printf("Auto-improve: no dialogue to improve for session %s", sessionID)
+		return
+	}
+
+	log.Printf("Auto-improve: improving %d dialogue segments for session %s", len(dialogue), sessionID)
+	if s.OnSessionImproveStarted != nil {
+		s.OnSessionImproveStarted(sessionID)
+	}
+
+	improved, err := s.LLMService.ImproveTranscriptionWithLLM(dialogue, s.OllamaModel, s.OllamaURL, nil)
+	if err == nil {
+		err = s.SessionMgr.SetDialogueLayer(sessionID, session.DialogueLayerImproved, improved)
+	}
+	if err != nil {
+		log.Printf("Auto-improve: session %s failed: %v", sessionID, err)
+	} else {
+		log.Printf("Auto-improve: successfully improved %d -> %d segments for session %s", len(dialogue), len(improved), sessionID)
+	}
+	if s.OnSessionImproved != nil {
+		s.OnSessionImproved(sessionID, err)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"aiwisper/session"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScheduleSessionAutoImprove(t *testing.T) {
+	// Ollama недоступна - достаточно проверить, когда запускается улучшение
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ollama.Close()
+
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := mgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &session.Chunk{ID: "c0", SessionID: sess.ID, Dialogue: []session.TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет", Speaker: "Вы"},
+	}}
+	if err := mgr.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewTranscriptionService(mgr, nil)
+	s.SetLLMService(NewLLMService())
+	started := make(chan string, 1)
+	s.OnSessionImproveStarted = func(sessionID string) { started <- sessionID }
+	s.OnSessionImproved = func(string, error) {}
+
+	if err := s.SetAutoImproveScope("paragraph"); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+
+	// По чанкам (по умолчанию) остановка записи ничего не запускает
+	s.EnableAutoImprove(ollama.URL, "test-model")
+	s.ScheduleSessionAutoImprove(sess.ID)
+
+	if err := s.SetAutoImproveScope(AutoImproveScopeSession); err != nil {
+		t.Fatal(err)
+	}
+	// Чанк ещё в очереди - улучшение ждёт его транскрипции
+	s.enqueueChunk(chunk)
+	s.ScheduleSessionAutoImprove(sess.ID)
+	select {
+	case <-started:
+		t.Fatal("auto-improve started while a chunk is pending")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.dequeueChunk(chunk)
+	select {
+	case id := <-started:
+		if id != sess.ID {
+			t.Errorf("improved session %s, want %s", id, sess.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("auto-improve did not start after the last chunk was transcribed")
+	}
+
+	// Повторная транскрипция чанка не запускает улучшение ещё раз
+	s.enqueueChunk(chunk)
+	s.dequeueChunk(chunk)
+	select {
+	case <-started:
+		t.Error("auto-improve ran twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
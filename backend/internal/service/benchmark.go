@@ -0,0 +1,108 @@
+package service
+
+import (
+	"aiwisper/models"
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// ModelBenchmarkResult результат транскрипции тестового чанка одной моделью
+type ModelBenchmarkResult struct {
+	ModelID   string `json:"modelId"`
+	ModelName string `json:"modelName"`
+	Text      string `json:"text"`
+	ElapsedMs int64  `json:"elapsedMs"`       // Время транскрипции (без загрузки модели)
+	LoadMs    int64  `json:"loadMs"`          // Время загрузки модели
+	Error     string `json:"error,omitempty"` // Ошибка загрузки или транскрипции
+}
+
+// BenchmarkModels транскрибирует один чанк сессии каждой из указанных моделей
+// и возвращает текст и время работы для сравнения (аналог cmd/testregions).
+// Если chunkID пустой, выбирается самый длинный чанк сессии.
+// Модели должны быть скачаны - проверка выполняется вызывающей стороной.
+func (s *TranscriptionService) BenchmarkModels(sessionID, chunkID string, modelIDs []string, language string) ([]ModelBenchmarkResult, error) {
+	if s.EngineMgr == nil {
+		return nil, fmt.Errorf("engine manager not available")
+	}
+
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := representativeChunk(sess.Chunks, chunkID)
+	if chunk == nil {
+		if chunkID != "" {
+			return nil, fmt.Errorf("chunk not found: %s", chunkID)
+		}
+		return nil, fmt.Errorf("session %s has no chunks", sessionID)
+	}
+
+	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	samples, err := session.ExtractSegmentGo(mp3Path, chunk.StartMs, chunk.EndMs, session.WhisperSampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract chunk audio: %w", err)
+	}
+
+	log.Printf("BenchmarkModels: session %s, chunk %d (%dms-%dms), %d models",
+		sessionID, chunk.Index, chunk.StartMs, chunk.EndMs, len(modelIDs))
+
+	results := make([]ModelBenchmarkResult, 0, len(modelIDs))
+	for _, modelID := range modelIDs {
+		result := ModelBenchmarkResult{ModelID: modelID}
+		if info := models.GetModelByID(modelID); info != nil {
+			result.ModelName = info.Name
+		}
+
+		// Движки создаём по одному, чтобы не держать несколько моделей в памяти
+		loadStart := time.Now()
+		engine, err := s.EngineMgr.CreateEngineForModel(modelID)
+		result.LoadMs = time.Since(loadStart).Milliseconds()
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			log.Printf("BenchmarkModels: failed to load %s: %v", modelID, err)
+			continue
+		}
+
+		if language != "" {
+			engine.SetLanguage(language)
+		}
+
+		start := time.Now()
+		segments, err := engine.TranscribeWithSegments(samples)
+		result.ElapsedMs = time.Since(start).Milliseconds()
+		engine.Close()
+
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Text = segmentsToText(segments)
+		}
+
+		log.Printf("BenchmarkModels: %s load=%dms transcribe=%dms", modelID, result.LoadMs, result.ElapsedMs)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// representativeChunk возвращает чанк по ID, а если ID не задан - самый длинный чанк сессии
+func representativeChunk(chunks []*session.Chunk, chunkID string) *session.Chunk {
+	var best *session.Chunk
+	for _, c := range chunks {
+		if chunkID != "" {
+			if c.ID == chunkID {
+				return c
+			}
+			continue
+		}
+		if best == nil || c.EndMs-c.StartMs > best.EndMs-best.StartMs {
+			best = c
+		}
+	}
+	return best
+}
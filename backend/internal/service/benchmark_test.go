@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+func TestRepresentativeChunk(t *testing.T) {
+	chunks := []*session.Chunk{
+		{ID: "c0", StartMs: 0, EndMs: 30000},
+		{ID: "c1", StartMs: 30000, EndMs: 90000}, // Самый длинный
+		{ID: "c2", StartMs: 90000, EndMs: 100000},
+	}
+
+	if c := representativeChunk(chunks, ""); c == nil || c.ID != "c1" {
+		t.Errorf("without chunkID = %+v, want longest c1", c)
+	}
+	if c := representativeChunk(chunks, "c2"); c == nil || c.ID != "c2" {
+		t.Errorf("chunkID c2 = %+v", c)
+	}
+	if c := representativeChunk(chunks, "missing"); c != nil {
+		t.Errorf("unknown chunkID = %+v, want nil", c)
+	}
+	if c := representativeChunk(nil, ""); c != nil {
+		t.Errorf("no chunks = %+v, want nil", c)
+	}
+}
+
+func TestBenchmarkModelsErrors(t *testing.T) {
+	if _, err := (&TranscriptionService{}).BenchmarkModels("s1", "", []string{"ggml-base"}, ""); err == nil {
+		t.Error("expected error without engine manager")
+	}
+
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTranscriptionService(sessMgr, ai.NewEngineManager(nil))
+
+	if _, err := s.BenchmarkModels(sess.ID, "", []string{"ggml-base"}, ""); err == nil || !strings.Contains(err.Error(), "no chunks") {
+		t.Errorf("session without chunks: err = %v", err)
+	}
+
+	if err := sessMgr.AddChunk(sess.ID, &session.Chunk{ID: "c0", SessionID: sess.ID, EndMs: 30000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.BenchmarkModels(sess.ID, "missing", []string{"ggml-base"}, ""); err == nil || !strings.Contains(err.Error(), "chunk not found") {
+		t.Errorf("unknown chunk: err = %v", err)
+	}
+	// Аудио сессии нет - ошибка до загрузки моделей
+	if _, err := s.BenchmarkModels(sess.ID, "c0", []string{"ggml-base"}, ""); err == nil || !strings.Contains(err.Error(), "extract chunk audio") {
+		t.Errorf("missing audio: err = %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"aiwisper/session"
+	"log"
+	"math"
+)
+
+// Параметры автоопределения перепутанных каналов
+const (
+	channelSwapFrameMs     = 10  // Размер кадра огибающей
+	channelSwapMaxLagMs    = 300 // Максимальная задержка эха динамиков в микрофоне
+	channelSwapMinSec      = 5   // Минимальная длительность аудио для решения
+	channelSwapMinCorr     = 0.3 // Минимальная корреляция огибающих (есть заметное эхо)
+	channelSwapMinMargin   = 0.1 // Минимальный перевес одного направления над другим
+	channelSwapMinFrameRMS = 1e-4
+)
+
+// detectSwappedChannels проверяет, не перепутаны ли каналы стерео записи.
+// Микрофон обычно слышит динамики, поэтому в MIC есть задержанная копия SYS, но не наоборот.
+// Сравнивается корреляция огибающих громкости, когда левый канал отстаёт от правого и наоборот.
+// Возвращает swapped=true, если правый канал отстаёт от левого (MIC записан справа),
+// conclusive=false, если эха не хватает для уверенного решения
+func detectSwappedChannels(left, right []float32, sampleRate int) (swapped, conclusive bool, leftLags, rightLags float64) {
+	frameSize := sampleRate * channelSwapFrameMs / 1000
+	if frameSize <= 0 || min(len(left), len(right)) < sampleRate*channelSwapMinSec {
+		return false, false, 0, 0
+	}
+
+	envL := channelEnvelope(left, frameSize)
+	envR := channelEnvelope(right, frameSize)
+	n := min(len(envL), len(envR))
+	envL, envR = envL[:n], envR[:n]
+
+	maxLag := channelSwapMaxLagMs / channelSwapFrameMs
+	for lag := 1; lag <= maxLag; lag++ {
+		leftLags = math.Max(leftLags, laggedCorrelation(envL, envR, lag))
+		rightLags = math.Max(rightLags, laggedCorrelation(envR, envL, lag))
+	}
+
+	if math.Max(leftLags, rightLags) < channelSwapMinCorr || math.Abs(leftLags-rightLags) < channelSwapMinMargin {
+		return false, false, leftLags, rightLags
+	}
+	return rightLags > leftLags, true, leftLags, rightLags
+}
+
+// channelEnvelope вычисляет огибающую громкости (RMS по кадрам) в логарифмической шкале
+func channelEnvelope(samples []float32, frameSize int) []float64 {
+	env := make([]float64, len(samples)/frameSize)
+	for i := range env {
+		rms := session.CalculateRMS(samples[i*frameSize : (i+1)*frameSize])
+		env[i] = math.Log10(math.Max(rms, channelSwapMinFrameRMS))
+	}
+	return env
+}
+
+// laggedCorrelation считает корреляцию Пирсона между delayed[t] и source[t-lag]
+func laggedCorrelation(delayed, source []float64, lag int) float64 {
+	n := len(delayed) - lag
+	if n <= 1 {
+		return 0
+	}
+	x := delayed[lag:]
+	y := source[:n]
+
+	var meanX, meanY float64
+	for i := 0; i < n; i++ {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for i := 0; i < n; i++ {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// orientStereoChannels возвращает каналы в порядке (MIC, SYS) с учётом настройки сессии.
+// В режиме auto решение принимается один раз, по первому стерео чанку сессии, и действует
+// для всех остальных: без уверенного решения порядок каналов не меняется
+func (s *TranscriptionService) orientStereoChannels(sess *session.Session, left, right []float32, sampleRate int) ([]float32, []float32) {
+	switch sess.SwapChannels {
+	case session.ChannelSwapOn:
+		return right, left
+	case session.ChannelSwapAuto:
+		// Решение ниже
+	default:
+		// off и пустая настройка
+		return left, right
+	}
+
+	s.channelSwapMu.Lock()
+	swapped, decided := s.channelSwaps[sess.ID]
+	if !decided {
+		var conclusive bool
+		var leftLags, rightLags float64
+		swapped, conclusive, leftLags, rightLags = detectSwappedChannels(left, right, sampleRate)
+		if s.channelSwaps == nil {
+			s.channelSwaps = make(map[string]bool)
+		}
+		s.channelSwaps[sess.ID] = swapped
+
+		switch {
+		case !conclusive:
+			log.Printf("Channel swap: session %s has not enough echo to detect channel order (echo corr: left lags %.2f, right lags %.2f), keeping MIC left; set swapChannels=on to override",
+				sess.ID, leftLags, rightLags)
+		case swapped:
+			log.Printf("Channel swap: session %s looks like MIC is on the right channel (echo corr: left lags %.2f, right lags %.2f), swapping MIC/SYS; set swapChannels=off to override",
+				sess.ID, leftLags, rightLags)
+		default:
+			log.Printf("Channel swap: session %s channel order looks correct (echo corr: left lags %.2f, right lags %.2f); set swapChannels=on to override",
+				sess.ID, leftLags, rightLags)
+		}
+	}
+	s.channelSwapMu.Unlock()
+
+	if swapped {
+		return right, left
+	}
+	return left, right
+}
+
+// ForgetChannelSwap удаляет решение о перестановке каналов остановленной сессии.
+// Если чанки сессии ещё ждут транскрипции, решение удаляется после последнего из них
+func (s *TranscriptionService) ForgetChannelSwap(sessionID string) {
+	s.pendingMu.Lock()
+	s.deferredSwapForget[sessionID] = true
+	ready := s.takeDeferredSwapForgetLocked(sessionID)
+	s.pendingMu.Unlock()
+
+	if ready {
+		s.dropChannelSwap(sessionID)
+	}
+}
+
+// takeDeferredSwapForgetLocked снимает отметку отложенного удаления решения, если у сессии
+// не осталось чанков в очереди. Вызывается под pendingMu
+func (s *TranscriptionService) takeDeferredSwapForgetLocked(sessionID string) bool {
+	if !s.deferredSwapForget[sessionID] {
+		return false
+	}
+	for _, p := range s.pendingChunks {
+		if p.sessionID == sessionID {
+			return false
+		}
+	}
+	delete(s.deferredSwapForget, sessionID)
+	return true
+}
+
+// dropChannelSwap удаляет решение о перестановке каналов сессии
+func (s *TranscriptionService) dropChannelSwap(sessionID string) {
+	s.channelSwapMu.Lock()
+	delete(s.channelSwaps, sessionID)
+	s.channelSwapMu.Unlock()
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"math/rand"
+	"testing"
+
+	"aiwisper/session"
+)
+
+// burstyNoise генерирует шум с паузами (похоже на огибающую речи)
+func burstyNoise(rng *rand.Rand, n, sampleRate int, amp float32) []float32 {
+	out := make([]float32, n)
+	pos := 0
+	for pos < n {
+		burst := sampleRate/10 + rng.Intn(sampleRate/2)
+		pause := sampleRate/20 + rng.Intn(sampleRate/3)
+		level := amp * (0.3 + rng.Float32())
+		for i := pos; i < pos+burst && i < n; i++ {
+			out[i] = level * (rng.Float32()*2 - 1)
+		}
+		pos += burst + pause
+	}
+	return out
+}
+
+// stereoWithEcho возвращает MIC (своя речь + эхо динамиков с задержкой) и SYS
+func stereoWithEcho(seconds int) (mic, sys []float32) {
+	const sampleRate = 16000
+	rng := rand.New(rand.NewSource(1))
+	n := seconds * sampleRate
+	sys = burstyNoise(rng, n, sampleRate, 0.3)
+	mic = burstyNoise(rng, n, sampleRate, 0.1)
+	delay := sampleRate * 60 / 1000
+	for i := delay; i < n; i++ {
+		mic[i] += 0.5 * sys[i-delay]
+	}
+	return mic, sys
+}
+
+func TestDetectSwappedChannels(t *testing.T) {
+	mic, sys := stereoWithEcho(20)
+
+	swapped, conclusive, _, _ := detectSwappedChannels(mic, sys, 16000)
+	if !conclusive || swapped {
+		t.Errorf("correct order: swapped=%v conclusive=%v, want false/true", swapped, conclusive)
+	}
+
+	swapped, conclusive, _, _ = detectSwappedChannels(sys, mic, 16000)
+	if !conclusive || !swapped {
+		t.Errorf("swapped order: swapped=%v conclusive=%v, want true/true", swapped, conclusive)
+	}
+
+	// Без эха (наушники) решение не принимается
+	rng := rand.New(rand.NewSource(2))
+	left := burstyNoise(rng, 20*16000, 16000, 0.2)
+	right := burstyNoise(rng, 20*16000, 16000, 0.2)
+	if _, conclusive, _, _ := detectSwappedChannels(left, right, 16000); conclusive {
+		t.Error("independent channels should be inconclusive")
+	}
+
+	// Слишком короткий фрагмент
+	if _, conclusive, _, _ := detectSwappedChannels(mic[:16000], sys[:16000], 16000); conclusive {
+		t.Error("short audio should be inconclusive")
+	}
+}
+
+func TestOrientStereoChannels(t *testing.T) {
+	mic, sys := stereoWithEcho(20)
+	s := NewTranscriptionService(nil, nil)
+
+	// Авто: решение запоминается для сессии
+	sess := &session.Session{ID: "auto", SwapChannels: session.ChannelSwapAuto}
+	gotMic, _ := s.orientStereoChannels(sess, sys, mic, 16000)
+	if &gotMic[0] != &mic[0] {
+		t.Error("auto mode should swap channels when MIC is on the right")
+	}
+	gotMic, _ = s.orientStereoChannels(sess, mic[:16000], sys[:16000], 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("auto decision should be reused for later chunks of the session")
+	}
+
+	// Первый чанк без эха: порядок не меняется и для последующих чанков
+	short := &session.Session{ID: "short", SwapChannels: session.ChannelSwapAuto}
+	gotMic, _ = s.orientStereoChannels(short, sys[:16000], mic[:16000], 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("inconclusive auto mode should keep channel order")
+	}
+	gotMic, _ = s.orientStereoChannels(short, sys, mic, 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("auto decision should be made once, on the first chunk")
+	}
+
+	// Явная настройка имеет приоритет, пустая - без перестановки
+	gotMic, _ = s.orientStereoChannels(&session.Session{ID: "off", SwapChannels: session.ChannelSwapOff}, sys, mic, 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("swapChannels=off should keep channel order")
+	}
+	gotMic, _ = s.orientStereoChannels(&session.Session{ID: "default"}, sys, mic, 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("empty swapChannels should keep channel order")
+	}
+	gotMic, _ = s.orientStereoChannels(&session.Session{ID: "on", SwapChannels: session.ChannelSwapOn}, mic, sys, 16000)
+	if &gotMic[0] != &sys[0] {
+		t.Error("swapChannels=on should always swap channels")
+	}
+}
+
+func TestForgetChannelSwap(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	s.channelSwaps = map[string]bool{"s1": true, "s2": true}
+
+	// Чанк s1 ещё в очереди - решение нужно для него
+	chunk := &session.Chunk{ID: "c0", SessionID: "s1"}
+	s.enqueueChunk(chunk)
+	s.ForgetChannelSwap("s1")
+	s.ForgetChannelSwap("s2")
+	if _, ok := s.channelSwaps["s1"]; !ok {
+		t.Error("decision removed while session chunks are pending")
+	}
+	if _, ok := s.channelSwaps["s2"]; ok {
+		t.Error("decision of drained session not removed")
+	}
+
+	s.dequeueChunk(chunk)
+	if _, ok := s.channelSwaps["s1"]; ok {
+		t.Error("decision not removed after last pending chunk")
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"aiwisper/session"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// overlapMatchWindowMs допустимое расхождение таймстемпов одного и того же слова в соседних чанках
+	overlapMatchWindowMs = 500
+	// overlapWaitTimeout сколько чанк с перекрытием ждёт распознавания предыдущего
+	overlapWaitTimeout = 2 * time.Minute
+	// overlapPollInterval период проверки статуса предыдущего чанка
+	overlapPollInterval = 100 * time.Millisecond
+)
+
+// SetChunkOverlap устанавливает перекрытие соседних чанков (0 - без перекрытия, по умолчанию).
+// Чанк захватывает конец предыдущего, чтобы слово, разрезанное границей, было слышно целиком
+func (s *TranscriptionService) SetChunkOverlap(overlap time.Duration) {
+	s.ChunkOverlap = max(0, overlap)
+	log.Printf("Chunk overlap set to: %v", s.ChunkOverlap)
+}
+
+// chunkExtractStart возвращает начало извлекаемого аудио чанка (мс): чанк захватывает
+// конец предыдущего на величину перекрытия
+func (s *TranscriptionService) chunkExtractStart(chunk *session.Chunk) int64 {
+	if chunk.Index == 0 || s.ChunkOverlap <= 0 {
+		return chunk.StartMs
+	}
+	return max(0, chunk.StartMs-s.ChunkOverlap.Milliseconds())
+}
+
+// waitPreviousChunk ждёт, пока предыдущий чанк распознается: перекрытие объединяется с его
+// результатом, поэтому итог не должен зависеть от того, какой чанк закончил раньше.
+// Ждёт не дольше overlapWaitTimeout, слот распознавания при этом не занимается
+func (s *TranscriptionService) waitPreviousChunk(ctx context.Context, chunk *session.Chunk) {
+	if chunk.Index == 0 || s.ChunkOverlap <= 0 {
+		return
+	}
+	deadline := time.After(overlapWaitTimeout)
+	ticker := time.NewTicker(overlapPollInterval)
+	defer ticker.Stop()
+	for s.SessionMgr.PreviousChunkPending(chunk.SessionID, chunk.Index) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			log.Printf("Chunk overlap: chunk %d stopped waiting for chunk %d after %v", chunk.Index, chunk.Index-1, overlapWaitTimeout)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trimChunkOverlap объединяет перекрытие канала с предыдущим чанком: убирает из сегментов
+// то, что он уже распознал, а обрывки слов, целиком услышанных в перекрытии, убирает из него
+func (s *TranscriptionService) trimChunkOverlap(ctx context.Context, chunk *session.Chunk, channel string, segments []session.TranscriptSegment) []session.TranscriptSegment {
+	prev, prevDone := s.SessionMgr.PreviousChunkSegments(chunk.SessionID, chunk.Index, channel)
+	trimmed, prevCutMs := trimOverlapSegments(segments, prev, chunk.StartMs, prevDone)
+	if len(trimmed) != len(segments) || session.JoinSegmentsText(trimmed) != session.JoinSegmentsText(segments) {
+		log.Printf("Chunk overlap: chunk %d (%s) trimmed %q -> %q at boundary %dms", chunk.Index, channel,
+			session.JoinSegmentsText(segments), session.JoinSegmentsText(trimmed), chunk.StartMs)
+	}
+	// Отменённый результат не сохранится, предыдущий чанк трогать нельзя
+	if prevCutMs >= 0 && ctx.Err() == nil &&
+		s.SessionMgr.TrimPreviousChunkTail(chunk.SessionID, chunk.Index, channel, prevCutMs) {
+		log.Printf("Chunk overlap: chunk %d (%s) tail trimmed from %dms", chunk.Index-1, channel, prevCutMs)
+	}
+	return trimmed
+}
+
+// trimOverlapSegments объединяет область перекрытия (до boundaryMs) с предыдущим чанком.
+// Слова целиком до границы предыдущий чанк слышал полностью - они отбрасываются. Слово,
+// разрезанное границей, предыдущий чанк слышал лишь частично: оно остаётся, если там не
+// распознано рядом по времени, а обрывок в предыдущем чанке нужно убрать начиная с
+// prevCutMs (-1 - ничего убирать не нужно). Пока предыдущий чанк не распознан (prevDone),
+// слово принадлежит чанку, в который попадает его середина
+func trimOverlapSegments(segments, prev []session.TranscriptSegment, boundaryMs int64, prevDone bool) ([]session.TranscriptSegment, int64) {
+	prevCutMs := int64(-1)
+	keepCut := func(start, end int64, recognized bool) bool {
+		if !prevDone {
+			return (start+end)/2 >= boundaryMs
+		}
+		if end <= boundaryMs || recognized {
+			return false
+		}
+		if prevCutMs < 0 || start < prevCutMs {
+			prevCutMs = start
+		}
+		return true
+	}
+
+	result := make([]session.TranscriptSegment, 0, len(segments))
+	for _, seg := range segments {
+		if seg.Start >= boundaryMs {
+			result = append(result, seg)
+			continue
+		}
+
+		if len(seg.Words) == 0 {
+			// Без таймстемпов слов решаем по сегменту целиком
+			if keepCut(seg.Start, seg.End, overlapTextRecognized(seg.Text, prev)) {
+				result = append(result, seg)
+			}
+			continue
+		}
+
+		words := make([]session.TranscriptWord, 0, len(seg.Words))
+		for _, w := range seg.Words {
+			if w.Start >= boundaryMs || keepCut(w.Start, w.End, overlapWordRecognized(w, prev)) {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) != len(seg.Words) {
+			texts := make([]string, len(words))
+			for i, w := range words {
+				texts[i] = strings.TrimSpace(w.Text)
+			}
+			seg.Text = strings.Join(texts, " ")
+			seg.Start = words[0].Start
+			seg.Words = words
+			seg.Confidence = session.SegmentConfidence(words)
+		}
+		result = append(result, seg)
+	}
+	return result, prevCutMs
+}
+
+// overlapWordRecognized проверяет, что слово есть в предыдущем чанке примерно в то же время
+func overlapWordRecognized(word session.TranscriptWord, prev []session.TranscriptSegment) bool {
+	text := normalizeForComparison(word.Text)
+	if text == "" {
+		return true // Пунктуация без слова
+	}
+	mid := (word.Start + word.End) / 2
+	for _, seg := range prev {
+		if len(seg.Words) == 0 {
+			if mid >= seg.Start-overlapMatchWindowMs && mid <= seg.End+overlapMatchWindowMs &&
+				strings.Contains(normalizeForComparison(seg.Text), text) {
+				return true
+			}
+			continue
+		}
+		for _, pw := range seg.Words {
+			pmid := (pw.Start + pw.End) / 2
+			if pmid >= mid-overlapMatchWindowMs && pmid <= mid+overlapMatchWindowMs && normalizeForComparison(pw.Text) == text {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapTextRecognized проверяет, что текст сегмента без слов уже есть в предыдущем чанке
+func overlapTextRecognized(text string, prev []session.TranscriptSegment) bool {
+	text = normalizeForComparison(text)
+	if text == "" {
+		return true
+	}
+	return strings.Contains(normalizeForComparison(session.JoinSegmentsText(prev)), text)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aiwisper/session"
+)
+
+func TestTrimOverlapSegments(t *testing.T) {
+	// Новый чанк начат на секунду раньше границы 30000мс и слышит конец предыдущего
+	cur := []session.TranscriptSegment{{
+		Start: 29050, End: 31500, Text: "нас встреча в десять",
+		Words: []session.TranscriptWord{
+			{Start: 29050, End: 29550, Text: "нас"},
+			{Start: 29650, End: 30200, Text: "встреча"}, // Разрезано границей, середина до неё
+			{Start: 30500, End: 30700, Text: "в"},
+			{Start: 30800, End: 31500, Text: "десять"},
+		},
+	}}
+	// Предыдущий чанк услышал только начало разрезанного слова
+	prev := []session.TranscriptSegment{{
+		Start: 28000, End: 30000, Text: "у нас вст",
+		Words: []session.TranscriptWord{
+			{Start: 28000, End: 28300, Text: "у"},
+			{Start: 29100, End: 29500, Text: "нас"},
+			{Start: 29700, End: 30000, Text: "вст"},
+		},
+	}}
+
+	got, cut := trimOverlapSegments(cur, prev, 30000, true)
+	if len(got) != 1 || got[0].Text != "встреча в десять" || got[0].Start != 29650 {
+		t.Fatalf("cut word should be kept and the rest of the overlap dropped, got %+v", got)
+	}
+	if cut != 29650 {
+		t.Errorf("previous chunk cut = %d, want 29650", cut)
+	}
+	if len(cur[0].Words) != 4 {
+		t.Error("input segments modified")
+	}
+
+	// Середина после границы: слово не дублируется с обрывком предыдущего чанка
+	late := []session.TranscriptSegment{{
+		Start: 29850, End: 30600, Text: "встреча",
+		Words: []session.TranscriptWord{{Start: 29850, End: 30600, Text: "встреча"}},
+	}}
+	if got, cut = trimOverlapSegments(late, prev, 30000, true); len(got) != 1 || cut != 29850 {
+		t.Errorf("late cut word: got %+v, cut %d", got, cut)
+	}
+
+	// Слово, целиком распознанное предыдущим чанком, из нового чанка убирается
+	whole := []session.TranscriptSegment{{
+		Start: 29700, End: 30000, Text: "встреча",
+		Words: []session.TranscriptWord{{Start: 29700, End: 30000, Text: "встреча"}},
+	}}
+	prevWhole := []session.TranscriptSegment{{
+		Start: 29650, End: 30000, Text: "Встреча.",
+		Words: []session.TranscriptWord{{Start: 29650, End: 30000, Text: "Встреча."}},
+	}}
+	if got, cut = trimOverlapSegments(late, prevWhole, 30000, true); len(got) != 0 || cut != -1 {
+		t.Errorf("recognized word should be dropped, got %+v, cut %d", got, cut)
+	}
+	if got, _ = trimOverlapSegments(whole, prevWhole, 30000, true); len(got) != 0 {
+		t.Errorf("word before boundary should be dropped, got %+v", got)
+	}
+
+	// Пока предыдущий чанк не готов, слово принадлежит чанку своей середины
+	if got, cut = trimOverlapSegments(cur, nil, 30000, false); len(got) != 1 || got[0].Text != "в десять" || cut != -1 {
+		t.Errorf("without previous chunk: got %+v, cut %d", got, cut)
+	}
+
+	// Сегменты без слов сверяются с текстом предыдущего чанка
+	noWords := []session.TranscriptSegment{
+		{Start: 29000, End: 29600, Text: "нас,"},
+		{Start: 29800, End: 31000, Text: "встреча"},
+	}
+	if got, _ = trimOverlapSegments(noWords, prev, 30000, true); len(got) != 1 || got[0].Text != "встреча" {
+		t.Errorf("segment without words in the overlap should be dropped, got %+v", got)
+	}
+
+	// Без перекрытия ничего не меняется
+	if got, cut = trimOverlapSegments(cur, prev, 29000, true); len(got) != 1 || got[0].Text != cur[0].Text || cut != -1 {
+		t.Errorf("segments after boundary changed: %+v", got)
+	}
+}
+
+func TestTrimChunkOverlapPreviousTail(t *testing.T) {
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := mgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevChunk := &session.Chunk{ID: "c0", SessionID: sess.ID, Index: 0, EndMs: 30000, Status: session.ChunkStatusPending}
+	chunk := &session.Chunk{ID: "c1", SessionID: sess.ID, Index: 1, StartMs: 30000, EndMs: 60000, Status: session.ChunkStatusPending}
+	for _, c := range []*session.Chunk{prevChunk, chunk} {
+		if err := mgr.AddChunk(sess.ID, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewTranscriptionService(mgr, nil)
+	s.SetChunkOverlap(time.Second)
+
+	// Чанк ждёт предыдущий, пока тот распознаётся
+	done := make(chan struct{})
+	go func() {
+		s.waitPreviousChunk(context.Background(), chunk)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("chunk did not wait for the previous one")
+	case <-time.After(3 * overlapPollInterval):
+	}
+
+	mic := []session.TranscriptSegment{{
+		Start: 28000, End: 30000, Text: "у нас вст", Speaker: "Вы",
+		Words: []session.TranscriptWord{
+			{Start: 28000, End: 28300, Text: "у"},
+			{Start: 29100, End: 29500, Text: "нас"},
+			{Start: 29700, End: 30000, Text: "вст"},
+		},
+	}}
+	if err := mgr.UpdateChunkStereoWithSegments(sess.ID, "c0", "у нас вст", "", mic, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("chunk kept waiting after the previous one completed")
+	}
+
+	cur := []session.TranscriptSegment{{
+		Start: 29650, End: 30700, Text: "встреча в", Speaker: "Вы",
+		Words: []session.TranscriptWord{
+			{Start: 29650, End: 30200, Text: "встреча"},
+			{Start: 30500, End: 30700, Text: "в"},
+		},
+	}}
+	got := s.trimChunkOverlap(context.Background(), chunk, "mic", cur)
+	if session.JoinSegmentsText(got) != "встреча в" {
+		t.Errorf("current chunk = %q", session.JoinSegmentsText(got))
+	}
+
+	// Обрывок слова убран из предыдущего чанка вместе с диалогом
+	prevSegs, ok := mgr.PreviousChunkSegments(sess.ID, 1, "mic")
+	if !ok || session.JoinSegmentsText(prevSegs) != "у нас" || prevSegs[0].End != 29500 {
+		t.Errorf("previous chunk mic = %+v", prevSegs)
+	}
+	if dialogue, _ := mgr.PreviousChunkSegments(sess.ID, 1, "mono"); session.JoinSegmentsText(dialogue) != "у нас" {
+		t.Errorf("previous chunk dialogue = %q", session.JoinSegmentsText(dialogue))
+	}
+	if text := mgr.PreviousChunkText(sess.ID, 1, "mic"); text != "у нас" {
+		t.Errorf("previous chunk mic text = %q", text)
+	}
+}
+
+func TestChunkExtractStart(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	chunk := &session.Chunk{Index: 1, StartMs: 30000}
+
+	// Перекрытие включается явно
+	if got := s.chunkExtractStart(chunk); got != 30000 {
+		t.Errorf("default extract start = %d, want no overlap", got)
+	}
+	s.SetChunkOverlap(time.Second)
+	if got := s.chunkExtractStart(chunk); got != 29000 {
+		t.Errorf("extract start = %d, want 29000", got)
+	}
+	if got := s.chunkExtractStart(&session.Chunk{Index: 0}); got != 0 {
+		t.Errorf("first chunk extract start = %d", got)
+	}
+}
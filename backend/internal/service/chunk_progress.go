@@ -0,0 +1,129 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/session"
+	"sync"
+)
+
+// chunkProgressStep минимальный прирост прогресса (в процентах) между уведомлениями
+const chunkProgressStep = 5.0
+
+// Источник оценки прогресса
+const (
+	ProgressSourceEngine  = "engine"  // Прогресс декодирования от движка
+	ProgressSourceRegions = "regions" // Оценка по обработанным регионам речи (per-region режим)
+)
+
+// ChunkProgress прогресс распознавания одного чанка
+type ChunkProgress struct {
+	SessionID  string  `json:"sessionId"`
+	ChunkID    string  `json:"chunkId"`
+	ChunkIndex int     `json:"chunkIndex"`
+	Channel    string  `json:"channel"` // Канал, который распознаётся сейчас: mic, sys или mono
+	Percent    float64 `json:"percent"` // Общий прогресс чанка (0-100)
+	Source     string  `json:"source"`  // engine или regions
+}
+
+// chunkProgressTracker сводит прогресс каналов в общий прогресс чанка.
+// Доля канала пропорциональна длительности его речи
+type chunkProgressTracker struct {
+	s     *TranscriptionService
+	chunk *session.Chunk
+
+	mu       sync.Mutex
+	offsets  map[string]float64 // Начало доли канала (0-1)
+	weights  map[string]float64 // Доля канала (0-1)
+	lastSent float64
+}
+
+// channelWork объём работы канала (например, длительность речи в мс)
+type channelWork struct {
+	channel string
+	work    float64
+}
+
+// newChunkProgress создаёт трекер прогресса. Возвращает nil, если прогресс никто не слушает
+func (s *TranscriptionService) newChunkProgress(chunk *session.Chunk, channels ...channelWork) *chunkProgressTracker {
+	if s.OnChunkProgress == nil {
+		return nil
+	}
+
+	var total float64
+	for _, c := range channels {
+		total += c.work
+	}
+
+	t := &chunkProgressTracker{
+		s:       s,
+		chunk:   chunk,
+		offsets: make(map[string]float64),
+		weights: make(map[string]float64),
+	}
+	var offset float64
+	for _, c := range channels {
+		weight := 1.0 / float64(len(channels))
+		if total > 0 {
+			weight = c.work / total
+		}
+		t.offsets[c.channel] = offset
+		t.weights[c.channel] = weight
+		offset += weight
+	}
+	return t
+}
+
+// report сообщает долю (0-1) выполненной работы канала
+func (t *chunkProgressTracker) report(channel string, fraction float64, source string) {
+	if t == nil {
+		return
+	}
+	fraction = max(0, min(1, fraction))
+
+	t.mu.Lock()
+	percent := (t.offsets[channel] + t.weights[channel]*fraction) * 100
+	if percent < t.lastSent+chunkProgressStep && !(fraction == 1 && percent > t.lastSent) {
+		t.mu.Unlock()
+		return
+	}
+	t.lastSent = percent
+	t.mu.Unlock()
+
+	t.s.OnChunkProgress(ChunkProgress{
+		SessionID:  t.chunk.SessionID,
+		ChunkID:    t.chunk.ID,
+		ChunkIndex: t.chunk.Index,
+		Channel:    channel,
+		Percent:    percent,
+		Source:     source,
+	})
+}
+
+// engineProgress возвращает callback прогресса движка для канала (nil, если трекер выключен)
+func (t *chunkProgressTracker) engineProgress(channel string) ai.ProgressFunc {
+	if t == nil {
+		return nil
+	}
+	return func(percent int) {
+		t.report(channel, float64(percent)/100, ProgressSourceEngine)
+	}
+}
+
+// regionProgress возвращает callback для per-region режима (nil, если трекер выключен)
+func (t *chunkProgressTracker) regionProgress(channel string) func(fraction float64, source string) {
+	if t == nil {
+		return nil
+	}
+	return func(fraction float64, source string) {
+		t.report(channel, fraction, source)
+	}
+}
+
+// speechDurationMs суммарная длительность регионов речи
+func speechDurationMs(regions []session.SpeechRegion) float64 {
+	var total int64
+	for _, r := range regions {
+		total += r.EndMs - r.StartMs
+	}
+	return float64(total)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"aiwisper/session"
+)
+
+func TestChunkProgressTracker(t *testing.T) {
+	var got []ChunkProgress
+	s := &TranscriptionService{OnChunkProgress: func(p ChunkProgress) { got = append(got, p) }}
+	chunk := &session.Chunk{ID: "c1", SessionID: "s1", Index: 2}
+
+	// MIC - 1с речи, SYS - 3с: MIC занимает первые 25%
+	tracker := s.newChunkProgress(chunk, channelWork{"mic", 1000}, channelWork{"sys", 3000})
+
+	tracker.report("mic", 0.5, ProgressSourceEngine)  // 12.5%
+	tracker.report("mic", 0.55, ProgressSourceEngine) // 13.75% - меньше шага, пропускается
+	tracker.report("mic", 1, ProgressSourceEngine)    // 25%
+	tracker.report("sys", 0.5, ProgressSourceRegions) // 62.5%
+	tracker.report("sys", 1, ProgressSourceRegions)   // 100%
+
+	want := []float64{12.5, 25, 62.5, 100}
+	if len(got) != len(want) {
+		t.Fatalf("got %d updates %+v, want %d", len(got), got, len(want))
+	}
+	for i, p := range got {
+		if math.Abs(p.Percent-want[i]) > 1e-9 {
+			t.Errorf("update %d: percent = %.2f, want %.2f", i, p.Percent, want[i])
+		}
+	}
+	if got[3].Channel != "sys" || got[3].Source != ProgressSourceRegions || got[3].ChunkIndex != 2 || got[3].SessionID != "s1" {
+		t.Errorf("unexpected last update: %+v", got[3])
+	}
+
+	// Без подписчика трекер не создаётся, методы nil-safe
+	s.OnChunkProgress = nil
+	tracker = s.newChunkProgress(chunk, channelWork{"mono", 1})
+	if tracker != nil || tracker.engineProgress("mono") != nil || tracker.regionProgress("mono") != nil {
+		t.Error("expected nil tracker without OnChunkProgress")
+	}
+	tracker.report("mono", 1, ProgressSourceEngine)
+}
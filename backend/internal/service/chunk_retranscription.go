@@ -0,0 +1,122 @@
+package service
+
+import (
+	"aiwisper/session"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ErrChunkRetranscribeCanceled ретранскрипция чанка отменена или заменена более новой
+var ErrChunkRetranscribeCanceled = errors.New("chunk retranscription canceled")
+
+// errChunkRetranscribeSuperseded ретранскрипция заменена более новой: чанк обновит она
+var errChunkRetranscribeSuperseded = fmt.Errorf("%w: superseded by a new request", ErrChunkRetranscribeCanceled)
+
+// chunkRetranscriptions активные ретранскрипции отдельных чанков (ключ: sessionID/chunkID)
+type chunkRetranscriptions struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+func chunkRunKey(sessionID, chunkID string) string {
+	return sessionID + "/" + chunkID
+}
+
+// RetranscribeChunk асинхронно распознаёт чанк заново. Предыдущая ретранскрипция того же
+// чанка отменяется: её результат не сохраняется, даже если нативный вызов ещё работает.
+// Возвращает ошибку, если сессия занята переносом, слиянием или разделением
+func (s *TranscriptionService) RetranscribeChunk(chunk *session.Chunk) error {
+	if s.EngineMgr == nil {
+		return fmt.Errorf("transcription engine not available")
+	}
+
+	// Сессия занята до конца распознавания: политика хранения её не очистит
+	releaseBusy, err := s.SessionMgr.TryMarkSessionBusy(chunk.SessionID, false)
+	if err != nil {
+		return err
+	}
+
+	key := chunkRunKey(chunk.SessionID, chunk.ID)
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	s.chunkRuns.mu.Lock()
+	if s.chunkRuns.cancels == nil {
+		s.chunkRuns.cancels = make(map[string]context.CancelCauseFunc)
+	}
+	if prev, ok := s.chunkRuns.cancels[key]; ok {
+		prev(errChunkRetranscribeSuperseded)
+		log.Printf("Retranscription of chunk %d superseded by a new request", chunk.Index)
+	}
+	s.chunkRuns.cancels[key] = cancel
+	s.chunkRuns.mu.Unlock()
+
+	s.enqueueChunk(chunk)
+	go func() {
+		defer releaseBusy()
+		defer s.dequeueChunk(chunk)
+		defer func() {
+			s.chunkRuns.mu.Lock()
+			// Более новый запуск мог уже занять ключ
+			if ctx.Err() == nil {
+				delete(s.chunkRuns.cancels, key)
+			}
+			s.chunkRuns.mu.Unlock()
+			cancel(nil)
+		}()
+
+		log.Printf("Retranscribing chunk %d (session %s)", chunk.Index, chunk.SessionID)
+		s.processStereoFromMP3(ctx, chunk, true)
+	}()
+	return nil
+}
+
+// CancelChunkRetranscription отменяет ретранскрипцию чанка. Возвращает false, если она не выполняется
+func (s *TranscriptionService) CancelChunkRetranscription(sessionID, chunkID string) bool {
+	key := chunkRunKey(sessionID, chunkID)
+
+	s.chunkRuns.mu.Lock()
+	defer s.chunkRuns.mu.Unlock()
+	cancel, ok := s.chunkRuns.cancels[key]
+	if !ok {
+		return false
+	}
+	cancel(ErrChunkRetranscribeCanceled)
+	delete(s.chunkRuns.cancels, key)
+	return true
+}
+
+// chunkRunCanceled проверяет, что результат распознавания больше не нужен
+func chunkRunCanceled(ctx context.Context, chunk *session.Chunk) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	log.Printf("Chunk %d: discarding result (%v)", chunk.Index, context.Cause(ctx))
+	return true
+}
+
+// saveChunkStereo сохраняет результат стерео распознавания, если он не отменён
+func (s *TranscriptionService) saveChunkStereo(ctx context.Context, chunk *session.Chunk, micText, sysText string, micSegments, sysSegments []session.TranscriptSegment, err error) {
+	if chunkRunCanceled(ctx, chunk) {
+		return
+	}
+	s.SessionMgr.UpdateChunkStereoWithSegments(chunk.SessionID, chunk.ID, micText, sysText, micSegments, sysSegments, err)
+}
+
+// saveChunkText сохраняет текст (или ошибку) распознавания, если он не отменён
+func (s *TranscriptionService) saveChunkText(ctx context.Context, chunk *session.Chunk, text string, err error) {
+	if chunkRunCanceled(ctx, chunk) {
+		return
+	}
+	s.SessionMgr.UpdateChunkTranscription(chunk.SessionID, chunk.ID, text, err)
+}
+
+// saveChunkDiarized сохраняет сегменты с диаризацией, если результат не отменён
+func (s *TranscriptionService) saveChunkDiarized(ctx context.Context, chunk *session.Chunk, text string, segments []session.TranscriptSegment, err error) {
+	if chunkRunCanceled(ctx, chunk) {
+		return
+	}
+	s.SessionMgr.UpdateChunkWithDiarizedSegments(chunk.SessionID, chunk.ID, text, segments, err)
+}
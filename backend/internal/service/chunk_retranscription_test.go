@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+func TestTranscribeWithTimeout_Canceled(t *testing.T) {
+	s := &TranscriptionService{TranscribeTimeout: time.Minute}
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.transcribeWithTimeout(ctx, "chunk 1", "mono", func() ([]ai.TranscriptSegment, error) {
+			<-release // Зависший нативный вызов
+			return nil, nil
+		})
+		done <- err
+	}()
+
+	cancel(ErrChunkRetranscribeCanceled)
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("transcribeWithTimeout did not return after cancel")
+	}
+
+	// Уже отменённый контекст не запускает распознавание
+	called := false
+	s.transcribeWithTimeout(ctx, "chunk 1", "mono", func() ([]ai.TranscriptSegment, error) {
+		called = true
+		return nil, nil
+	})
+	if called {
+		t.Error("transcribe called with canceled context")
+	}
+}
+
+func TestCancelChunkRetranscription(t *testing.T) {
+	s := &TranscriptionService{}
+	if s.CancelChunkRetranscription("s1", "c1") {
+		t.Error("cancel without active retranscription should return false")
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.chunkRuns.cancels = map[string]context.CancelCauseFunc{chunkRunKey("s1", "c1"): cancel}
+	if !s.CancelChunkRetranscription("s1", "c1") {
+		t.Fatal("expected active retranscription to be canceled")
+	}
+	if !errors.Is(context.Cause(ctx), ErrChunkRetranscribeCanceled) {
+		t.Errorf("cause = %v", context.Cause(ctx))
+	}
+
+	// Результат отменённого запуска не сохраняется (SessionMgr не трогается)
+	s.saveChunkText(ctx, &session.Chunk{ID: "c1", SessionID: "s1"}, "stale", nil)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"path/filepath"
+)
+
+// ChannelCompressionPreview сколько аудио канала останется после VAD и сжатия
+type ChannelCompressionPreview struct {
+	Channel      string            `json:"channel"`          // mic, sys или mono
+	Method       session.VADMethod `json:"method,omitempty"` // Фактически использованный метод VAD
+	OriginalMs   int64             `json:"originalMs"`
+	CompressedMs int64             `json:"compressedMs"` // Длительность аудио, которое получит модель
+	Regions      int               `json:"regions"`
+	KeptRatio    float64           `json:"keptRatio"` // CompressedMs / OriginalMs
+}
+
+// CompressionPreview результат предпросмотра VAD + сжатия сессии (preview_compression)
+type CompressionPreview struct {
+	SessionID      string                       `json:"sessionId"`
+	ChunksAnalyzed int                          `json:"chunksAnalyzed"`
+	Channels       []*ChannelCompressionPreview `json:"channels"`
+}
+
+// PreviewCompression прогоняет VAD и сжатие речи по чанкам сессии без распознавания,
+// с теми же фильтрами и настройками VAD каналов, что и транскрипция.
+// Чанки с одинаковыми каналами распознаются как моно без сжатия и попадают в канал mono целиком
+func (s *TranscriptionService) PreviewCompression(sessionID string) (*CompressionPreview, error) {
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	chunks := sess.Chunks
+	if len(chunks) == 0 {
+		// Сессия без чанков - анализируем запись целиком
+		chunks = []*session.Chunk{{SessionID: sessionID, StartMs: 0, EndMs: sess.TotalDuration.Milliseconds()}}
+	}
+
+	preview := &CompressionPreview{SessionID: sessionID}
+	channels := make(map[string]*ChannelCompressionPreview)
+	add := func(channel string, method session.VADMethod, samples []float32, regions []session.SpeechRegion, compress bool) {
+		ch, ok := channels[channel]
+		if !ok {
+			ch = &ChannelCompressionPreview{Channel: channel, Method: method}
+			channels[channel] = ch
+			preview.Channels = append(preview.Channels, ch)
+		}
+		originalMs := int64(len(samples)) * 1000 / session.WhisperSampleRate
+		ch.OriginalMs += originalMs
+		if !compress {
+			ch.CompressedMs += originalMs
+			return
+		}
+		compressed := session.CompressSpeechFromRegions(samples, regions, session.WhisperSampleRate)
+		ch.CompressedMs += int64(len(compressed.CompressedSamples)) * 1000 / session.WhisperSampleRate
+		ch.Regions += len(regions)
+	}
+
+	for _, chunk := range chunks {
+		micSamples, sysSamples, err := session.ExtractSegmentStereoGo(mp3Path, chunk.StartMs, chunk.EndMs, session.WhisperSampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", chunk.Index, err)
+		}
+		preview.ChunksAnalyzed++
+
+		if areChannelsSimilar(micSamples, sysSamples) {
+			add("mono", "", micSamples, nil, false)
+			continue
+		}
+
+		micSamples, sysSamples = s.orientStereoChannels(sess, micSamples, sysSamples, session.WhisperSampleRate)
+		micSamples = s.normalizeChannelLoudness(session.FilterChannelForTranscription(micSamples, session.WhisperSampleRate), "mic")
+		sysSamples = s.normalizeChannelLoudness(session.FilterChannelForTranscription(sysSamples, session.WhisperSampleRate), "sys")
+
+		for _, ch := range []struct {
+			name    string
+			samples []float32
+		}{{"mic", micSamples}, {"sys", sysSamples}} {
+			regions, method := session.DetectSpeechRegionsForChannel(ch.samples, session.WhisperSampleRate, s.channelVADConfig(ch.name))
+			add(ch.name, method, ch.samples, regions, true)
+		}
+	}
+
+	for _, ch := range preview.Channels {
+		if ch.OriginalMs > 0 {
+			ch.KeptRatio = float64(ch.CompressedMs) / float64(ch.OriginalMs)
+		}
+	}
+	return preview, nil
+}
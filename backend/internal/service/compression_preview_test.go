@@ -0,0 +1,66 @@
+package service
+
+import (
+	"aiwisper/session"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreviewCompressionReportsPerChannelRatios(t *testing.T) {
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := mgr.CreateImportSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MIC: 3 с речеподобных всплесков и 5 с тишины, SYS: тишина
+	const sampleRate = 16000
+	rng := rand.New(rand.NewSource(7))
+	mic := append(burstyNoise(rng, 3*sampleRate, sampleRate, 0.3), make([]float32, 5*sampleRate)...)
+	sys := make([]float32, len(mic))
+
+	w, err := session.NewShineMP3Writer(filepath.Join(sess.DataDir, "full.mp3"), sampleRate, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteStereoInterleaved(mic, sys); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sess.TotalDuration = 8 * time.Second
+
+	s := &TranscriptionService{SessionMgr: mgr}
+	preview, err := s.PreviewCompression(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.ChunksAnalyzed != 1 {
+		t.Fatalf("chunks analyzed = %d, want 1", preview.ChunksAnalyzed)
+	}
+
+	byName := make(map[string]*ChannelCompressionPreview)
+	for _, ch := range preview.Channels {
+		byName[ch.Channel] = ch
+		if ch.KeptRatio < 0 || ch.KeptRatio > 1 {
+			t.Errorf("%s: kept ratio %.2f out of range", ch.Channel, ch.KeptRatio)
+		}
+	}
+	micPreview, ok := byName["mic"]
+	if !ok {
+		t.Fatalf("no mic channel in preview: %+v", preview.Channels)
+	}
+	if micPreview.OriginalMs < 7000 {
+		t.Errorf("mic original = %d ms, want ~8000", micPreview.OriginalMs)
+	}
+	if micPreview.Regions == 0 || micPreview.CompressedMs >= micPreview.OriginalMs {
+		t.Errorf("mic: regions=%d compressed=%d ms of %d ms, want trailing silence cut",
+			micPreview.Regions, micPreview.CompressedMs, micPreview.OriginalMs)
+	}
+}
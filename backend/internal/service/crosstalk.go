@@ -0,0 +1,138 @@
+package service
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// CrosstalkDedupConfig настройки удаления эха между каналами.
+// Когда микрофон слышит динамики (или наоборот), одна и та же фраза
+// распознаётся и как "Вы", и как "Собеседник".
+// Выключено по умолчанию: в наушниках эха нет, а одинаковые короткие реплики
+// ("да", "угу") у разных людей удалять нельзя
+type CrosstalkDedupConfig struct {
+	Enabled           bool
+	MinOverlapRatio   float64 // Доля перекрытия по времени относительно более короткого сегмента (0-1)
+	MinTextSimilarity float64 // Минимальная схожесть текста (Jaccard по словам, 0-1)
+}
+
+// DefaultCrosstalkDedupConfig возвращает настройки по умолчанию: выключено, пороги 0.5 / 0.6
+func DefaultCrosstalkDedupConfig() CrosstalkDedupConfig {
+	return CrosstalkDedupConfig{
+		MinOverlapRatio:   0.5,
+		MinTextSimilarity: 0.6,
+	}
+}
+
+// Validate проверяет пороги: нулевой порог удалял бы любые пары сегментов
+func (c CrosstalkDedupConfig) Validate() error {
+	if c.MinOverlapRatio <= 0 || c.MinOverlapRatio > 1 {
+		return fmt.Errorf("crosstalk overlap ratio must be in (0, 1], got %g", c.MinOverlapRatio)
+	}
+	if c.MinTextSimilarity <= 0 || c.MinTextSimilarity > 1 {
+		return fmt.Errorf("crosstalk text similarity must be in (0, 1], got %g", c.MinTextSimilarity)
+	}
+	return nil
+}
+
+// SetCrosstalkDedupConfig устанавливает параметры удаления эха между каналами.
+// Некорректные пороги отклоняются, действующие настройки не меняются
+func (s *TranscriptionService) SetCrosstalkDedupConfig(cfg CrosstalkDedupConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s.CrosstalkDedup = cfg
+	log.Printf("Crosstalk dedup: enabled=%v, minOverlap=%.2f, minSimilarity=%.2f",
+		cfg.Enabled, cfg.MinOverlapRatio, cfg.MinTextSimilarity)
+	return nil
+}
+
+// dedupeCrosstalk удаляет дубли фраз, попавших в оба канала.
+// Из пары перекрывающихся сегментов с почти одинаковым текстом остаётся сегмент
+// с большей средней уверенностью слов; при равенстве остаётся SYS (эхо обычно в микрофоне)
+func dedupeCrosstalk(micSegs, sysSegs []session.TranscriptSegment, cfg CrosstalkDedupConfig) ([]session.TranscriptSegment, []session.TranscriptSegment, int) {
+	if !cfg.Enabled || len(micSegs) == 0 || len(sysSegs) == 0 {
+		return micSegs, sysSegs, 0
+	}
+
+	dropMic := make([]bool, len(micSegs))
+	dropSys := make([]bool, len(sysSegs))
+
+	for i, mic := range micSegs {
+		for j, sys := range sysSegs {
+			if dropSys[j] {
+				continue
+			}
+			if segmentOverlapRatio(mic, sys) < cfg.MinOverlapRatio {
+				continue
+			}
+			if textSimilarity(normalizeForComparison(mic.Text), normalizeForComparison(sys.Text)) < cfg.MinTextSimilarity {
+				continue
+			}
+
+			if segmentConfidence(mic) > segmentConfidence(sys) {
+				dropSys[j] = true
+				log.Printf("Crosstalk: dropped SYS echo [%d-%dms] %q (kept MIC)", sys.Start, sys.End, sys.Text)
+				continue
+			}
+			dropMic[i] = true
+			log.Printf("Crosstalk: dropped MIC echo [%d-%dms] %q (kept SYS)", mic.Start, mic.End, mic.Text)
+			break
+		}
+	}
+
+	dropped := 0
+	keptMic := make([]session.TranscriptSegment, 0, len(micSegs))
+	for i, seg := range micSegs {
+		if dropMic[i] {
+			dropped++
+			continue
+		}
+		keptMic = append(keptMic, seg)
+	}
+	keptSys := make([]session.TranscriptSegment, 0, len(sysSegs))
+	for j, seg := range sysSegs {
+		if dropSys[j] {
+			dropped++
+			continue
+		}
+		keptSys = append(keptSys, seg)
+	}
+	return keptMic, keptSys, dropped
+}
+
+// segmentOverlapRatio возвращает перекрытие сегментов относительно более короткого из них
+func segmentOverlapRatio(a, b session.TranscriptSegment) float64 {
+	overlap := min(a.End, b.End) - max(a.Start, b.Start)
+	if overlap <= 0 {
+		return 0
+	}
+	shorter := min(a.End-a.Start, b.End-b.Start)
+	if shorter <= 0 {
+		return 0
+	}
+	return float64(overlap) / float64(shorter)
+}
+
+// segmentConfidence возвращает среднюю уверенность слов сегмента (0, если слов нет)
+func segmentConfidence(seg session.TranscriptSegment) float64 {
+	if len(seg.Words) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, w := range seg.Words {
+		sum += float64(w.P)
+	}
+	return sum / float64(len(seg.Words))
+}
+
+// normalizeForComparison приводит текст к нижнему регистру и убирает пунктуацию
+func normalizeForComparison(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
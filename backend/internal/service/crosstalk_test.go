@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/session"
+)
+
+// enabledCrosstalkConfig включённое удаление эха с порогами по умолчанию
+func enabledCrosstalkConfig() CrosstalkDedupConfig {
+	cfg := DefaultCrosstalkDedupConfig()
+	cfg.Enabled = true
+	return cfg
+}
+
+func wordsWithP(text string, p float32) []session.TranscriptWord {
+	return []session.TranscriptWord{{Text: text, P: p}}
+}
+
+func TestDedupeCrosstalk_KeepsHigherConfidence(t *testing.T) {
+	mic := []session.TranscriptSegment{
+		{Start: 1000, End: 3000, Text: "Привет, как дела?", Speaker: "Вы", Words: wordsWithP("привет", 0.4)},
+		{Start: 5000, End: 6000, Text: "Отлично", Speaker: "Вы", Words: wordsWithP("отлично", 0.9)},
+	}
+	sys := []session.TranscriptSegment{
+		{Start: 1100, End: 3100, Text: "привет как дела", Speaker: "Собеседник 1", Words: wordsWithP("привет", 0.9)},
+	}
+
+	gotMic, gotSys, dropped := dedupeCrosstalk(mic, sys, enabledCrosstalkConfig())
+	if dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", dropped)
+	}
+	if len(gotSys) != 1 || len(gotMic) != 1 || gotMic[0].Text != "Отлично" {
+		t.Errorf("expected MIC echo to be dropped, got mic=%+v sys=%+v", gotMic, gotSys)
+	}
+}
+
+func TestDedupeCrosstalk_ThresholdsAndDisabled(t *testing.T) {
+	mic := []session.TranscriptSegment{{Start: 0, End: 2000, Text: "один два три", Words: wordsWithP("один", 0.9)}}
+	sys := []session.TranscriptSegment{{Start: 1800, End: 4000, Text: "один два три", Words: wordsWithP("один", 0.5)}}
+
+	// Перекрытие 200мс из 2000мс - ниже порога
+	if _, _, dropped := dedupeCrosstalk(mic, sys, enabledCrosstalkConfig()); dropped != 0 {
+		t.Errorf("small overlap: dropped = %d, want 0", dropped)
+	}
+
+	cfg := enabledCrosstalkConfig()
+	cfg.MinOverlapRatio = 0.05
+	if _, gotSys, dropped := dedupeCrosstalk(mic, sys, cfg); dropped != 1 || len(gotSys) != 0 {
+		t.Errorf("lowered overlap threshold: dropped = %d, sys = %+v", dropped, gotSys)
+	}
+
+	cfg.Enabled = false
+	if _, _, dropped := dedupeCrosstalk(mic, sys, cfg); dropped != 0 {
+		t.Errorf("disabled: dropped = %d, want 0", dropped)
+	}
+
+	different := []session.TranscriptSegment{{Start: 0, End: 2000, Text: "совсем другой текст"}}
+	cfg = enabledCrosstalkConfig()
+	if _, _, dropped := dedupeCrosstalk(mic, different, cfg); dropped != 0 {
+		t.Errorf("different text: dropped = %d, want 0", dropped)
+	}
+}
+
+func TestSetCrosstalkDedupConfig(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	if s.CrosstalkDedup.Enabled {
+		t.Error("crosstalk dedup should be opt-in")
+	}
+
+	for _, cfg := range []CrosstalkDedupConfig{
+		{Enabled: true, MinOverlapRatio: 0, MinTextSimilarity: 0.6},
+		{Enabled: true, MinOverlapRatio: 1.5, MinTextSimilarity: 0.6},
+		{Enabled: true, MinOverlapRatio: 0.5, MinTextSimilarity: -0.1},
+		{Enabled: true, MinOverlapRatio: 0.5, MinTextSimilarity: 2},
+	} {
+		if err := s.SetCrosstalkDedupConfig(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+	if s.CrosstalkDedup != DefaultCrosstalkDedupConfig() {
+		t.Errorf("invalid config applied: %+v", s.CrosstalkDedup)
+	}
+
+	if err := s.SetCrosstalkDedupConfig(enabledCrosstalkConfig()); err != nil || !s.CrosstalkDedup.Enabled {
+		t.Errorf("valid config: err = %v, config = %+v", err, s.CrosstalkDedup)
+	}
+}
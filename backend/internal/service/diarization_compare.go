@@ -0,0 +1,159 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// compareDiarizationTimeout таймаут диаризации одним бэкендом при сравнении
+const compareDiarizationTimeout = 60 * time.Second
+
+// DiarizationSegmentInfo граница сегмента спикера (мс от начала чанка)
+type DiarizationSegmentInfo struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`
+	Speaker int   `json:"speaker"`
+}
+
+// DiarizationBackendResult результат диаризации одним бэкендом
+type DiarizationBackendResult struct {
+	Backend     string                   `json:"backend"`            // sherpa, fluid
+	Available   bool                     `json:"available"`          // Бэкенд доступен на этой системе
+	Provider    string                   `json:"provider,omitempty"` // coreml, cpu, cuda
+	NumSpeakers int                      `json:"numSpeakers"`
+	Segments    []DiarizationSegmentInfo `json:"segments,omitempty"`
+	ElapsedMs   int64                    `json:"elapsedMs"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// CompareDiarizationBackends запускает sherpa и fluid диаризацию на одном и том же SYS канале
+// чанка и возвращает результаты обоих бэкендов для сравнения.
+// Если chunkID пустой, выбирается самый длинный чанк сессии.
+// Для sherpa нужны пути к моделям сегментации и embedding, иначе бэкенд помечается недоступным.
+func (s *TranscriptionService) CompareDiarizationBackends(sessionID, chunkID, segmentationPath, embeddingPath, provider string) ([]DiarizationBackendResult, error) {
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := representativeChunk(sess.Chunks, chunkID)
+	if chunk == nil {
+		if chunkID != "" {
+			return nil, fmt.Errorf("chunk not found: %s", chunkID)
+		}
+		return nil, fmt.Errorf("session %s has no chunks", sessionID)
+	}
+
+	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	micSamples, sysSamples, err := session.ExtractSegmentStereoGo(mp3Path, chunk.StartMs, chunk.EndMs, session.WhisperSampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract chunk audio: %w", err)
+	}
+	_, sysSamples = s.orientStereoChannels(sess, micSamples, sysSamples, session.WhisperSampleRate)
+
+	log.Printf("CompareDiarizationBackends: session %s, chunk %d (%dms-%dms), %d SYS samples",
+		sessionID, chunk.Index, chunk.StartMs, chunk.EndMs, len(sysSamples))
+
+	results := []DiarizationBackendResult{
+		s.runSherpaComparison(sysSamples, segmentationPath, embeddingPath, provider),
+		s.runFluidComparison(sysSamples),
+	}
+	return results, nil
+}
+
+// runSherpaComparison выполняет диаризацию через Sherpa-ONNX
+func (s *TranscriptionService) runSherpaComparison(samples []float32, segmentationPath, embeddingPath, provider string) DiarizationBackendResult {
+	result := DiarizationBackendResult{Backend: "sherpa"}
+
+	if segmentationPath == "" || embeddingPath == "" {
+		result.Error = "segmentationModelPath and embeddingModelPath are required for Sherpa backend"
+		return result
+	}
+
+	config := ai.DefaultSherpaDiarizerConfig(segmentationPath, embeddingPath)
+	if provider != "" {
+		config.Provider = provider
+	}
+
+	diarizer, err := ai.NewSherpaDiarizer(config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Available = true
+	result.Provider = diarizer.GetProvider()
+
+	return runDiarizationComparison(result, diarizer, samples, compareDiarizationTimeout)
+}
+
+// runFluidComparison выполняет диаризацию через FluidAudio (CoreML)
+func (s *TranscriptionService) runFluidComparison(samples []float32) DiarizationBackendResult {
+	result := DiarizationBackendResult{Backend: "fluid"}
+
+	diarizer, err := ai.NewFluidDiarizer(ai.DefaultFluidDiarizerConfig())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Available = true
+	result.Provider = "coreml" // FluidAudio всегда работает через CoreML
+
+	return runDiarizationComparison(result, diarizer, samples, compareDiarizationTimeout)
+}
+
+// runDiarizationComparison выполняет диаризацию созданным для сравнения диаризатором
+// и заполняет результат. Диаризатор закрывается в любом случае
+func runDiarizationComparison(result DiarizationBackendResult, diarizer ai.DiarizationProvider, samples []float32, timeout time.Duration) DiarizationBackendResult {
+	segments, elapsed, err := diarizeWithTimeout(diarizer, samples, timeout)
+	result.ElapsedMs = elapsed.Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	fillDiarizationResult(&result, segments)
+	return result
+}
+
+// diarizeWithTimeout выполняет диаризацию с таймаутом и замеряет время.
+// Закрывает диаризатор после возврата нативного вызова: при таймауте он может ещё работать,
+// и освобождать его ресурсы раньше нельзя
+func diarizeWithTimeout(diarizer ai.DiarizationProvider, samples []float32, timeout time.Duration) ([]ai.SpeakerSegment, time.Duration, error) {
+	type res struct {
+		segs []ai.SpeakerSegment
+		err  error
+	}
+
+	start := time.Now()
+	ch := make(chan res, 1)
+	go func() {
+		defer diarizer.Close()
+		segs, err := diarizer.Diarize(samples)
+		ch <- res{segs: segs, err: err}
+	}()
+
+	select {
+	case out := <-ch:
+		return out.segs, time.Since(start), out.err
+	case <-time.After(timeout):
+		return nil, time.Since(start), fmt.Errorf("diarization timeout after %v", timeout)
+	}
+}
+
+// fillDiarizationResult заполняет сегменты и количество спикеров
+func fillDiarizationResult(result *DiarizationBackendResult, segments []ai.SpeakerSegment) {
+	speakers := make(map[int]bool)
+	result.Segments = make([]DiarizationSegmentInfo, len(segments))
+	for i, seg := range segments {
+		result.Segments[i] = DiarizationSegmentInfo{
+			Start:   int64(seg.Start * 1000),
+			End:     int64(seg.End * 1000),
+			Speaker: seg.Speaker,
+		}
+		speakers[seg.Speaker] = true
+	}
+	result.NumSpeakers = len(speakers)
+}
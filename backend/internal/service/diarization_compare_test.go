@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"aiwisper/ai"
+)
+
+// fakeDiarizer диаризатор сравнения: возвращает заданный результат после release
+type fakeDiarizer struct {
+	segments []ai.SpeakerSegment
+	err      error
+	release  chan struct{}
+	closed   chan struct{}
+}
+
+func newFakeDiarizer(segments []ai.SpeakerSegment, err error) *fakeDiarizer {
+	d := &fakeDiarizer{segments: segments, err: err, release: make(chan struct{}), closed: make(chan struct{})}
+	close(d.release)
+	return d
+}
+
+func (d *fakeDiarizer) Diarize([]float32) ([]ai.SpeakerSegment, error) {
+	<-d.release
+	return d.segments, d.err
+}
+
+func (d *fakeDiarizer) IsInitialized() bool { return true }
+
+func (d *fakeDiarizer) Close() { close(d.closed) }
+
+// waitClosed ждёт закрытия диаризатора
+func (d *fakeDiarizer) waitClosed(t *testing.T) {
+	t.Helper()
+	select {
+	case <-d.closed:
+	case <-time.After(time.Second):
+		t.Fatal("diarizer was not closed")
+	}
+}
+
+func TestRunDiarizationComparison(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		d := newFakeDiarizer([]ai.SpeakerSegment{
+			{Start: 0, End: 1.5, Speaker: 0},
+			{Start: 1.5, End: 3.25, Speaker: 1},
+			{Start: 3.25, End: 4, Speaker: 0},
+		}, nil)
+		result := runDiarizationComparison(DiarizationBackendResult{Backend: "sherpa", Available: true}, d, nil, time.Second)
+		if result.Error != "" || result.NumSpeakers != 2 || len(result.Segments) != 3 {
+			t.Fatalf("result = %+v", result)
+		}
+		if seg := result.Segments[1]; seg.Start != 1500 || seg.End != 3250 || seg.Speaker != 1 {
+			t.Errorf("segment = %+v", seg)
+		}
+		d.waitClosed(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		d := newFakeDiarizer(nil, errors.New("native failure"))
+		result := runDiarizationComparison(DiarizationBackendResult{Backend: "fluid", Available: true}, d, nil, time.Second)
+		if result.Error != "native failure" || result.Segments != nil {
+			t.Errorf("result = %+v", result)
+		}
+		d.waitClosed(t)
+	})
+
+	t.Run("timeout closes after late return", func(t *testing.T) {
+		d := &fakeDiarizer{release: make(chan struct{}), closed: make(chan struct{})}
+		result := runDiarizationComparison(DiarizationBackendResult{Backend: "sherpa"}, d, nil, 10*time.Millisecond)
+		if !strings.Contains(result.Error, "timeout") {
+			t.Fatalf("result = %+v, want timeout", result)
+		}
+		// Нативный вызов ещё работает - закрывать нельзя
+		select {
+		case <-d.closed:
+			t.Fatal("diarizer closed while diarization is running")
+		default:
+		}
+		close(d.release)
+		d.waitClosed(t)
+	})
+}
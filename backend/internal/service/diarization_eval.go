@@ -0,0 +1,325 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultDERCollar стандартный collar (сек) вокруг границ эталонных сегментов, не участвующий в оценке
+const DefaultDERCollar = 0.25
+
+// RTTMSegment сегмент речи спикера из RTTM файла (время в секундах)
+type RTTMSegment struct {
+	Speaker string
+	Start   float64
+	End     float64
+}
+
+// SpeakerDERBreakdown ошибки по одному эталонному спикеру
+type SpeakerDERBreakdown struct {
+	Speaker      string  `json:"speaker"`      // Спикер из эталона
+	MappedTo     string  `json:"mappedTo"`     // Сопоставленный спикер сессии (пусто - не сопоставлен)
+	ScoredSec    float64 `json:"scoredSec"`    // Оцениваемая длительность речи
+	CorrectSec   float64 `json:"correctSec"`   // Верно размечено
+	MissedSec    float64 `json:"missedSec"`    // Речь не найдена
+	ConfusionSec float64 `json:"confusionSec"` // Приписано другому спикеру
+	ErrorRate    float64 `json:"errorRate"`    // (missed + confusion) / scored
+}
+
+// DERResult результат оценки диаризации (Diarization Error Rate)
+type DERResult struct {
+	CollarSec      float64               `json:"collarSec"`
+	ScoredSec      float64               `json:"scoredSec"`     // Общая оцениваемая длительность речи эталона
+	MissedSec      float64               `json:"missedSec"`     // Пропущенная речь
+	FalseAlarmSec  float64               `json:"falseAlarmSec"` // Лишняя речь
+	ConfusionSec   float64               `json:"confusionSec"`  // Перепутанные спикеры
+	DER            float64               `json:"der"`           // (missed + falseAlarm + confusion) / scored
+	MissedRate     float64               `json:"missedRate"`
+	FalseAlarmRate float64               `json:"falseAlarmRate"`
+	ConfusionRate  float64               `json:"confusionRate"`
+	SpeakerMap     map[string]string     `json:"speakerMap"` // эталон -> сессия
+	Speakers       []SpeakerDERBreakdown `json:"speakers"`
+}
+
+// ParseRTTM читает строки SPEAKER из RTTM. Остальные типы строк и комментарии пропускаются
+func ParseRTTM(r io.Reader) ([]RTTMSegment, error) {
+	var segments []RTTMSegment
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || fields[0] != "SPEAKER" {
+			continue
+		}
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("line %d: expected at least 8 fields, got %d", lineNum, len(fields))
+		}
+		start, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start %q", lineNum, fields[3])
+		}
+		dur, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil || dur < 0 {
+			return nil, fmt.Errorf("line %d: invalid duration %q", lineNum, fields[4])
+		}
+		if dur == 0 {
+			continue
+		}
+		segments = append(segments, RTTMSegment{Speaker: fields[7], Start: start, End: start + dur})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// EvaluateDiarization вычисляет DER гипотезы относительно эталона.
+// Зоны ±collar вокруг границ эталонных сегментов исключаются из оценки,
+// спикеры сопоставляются один к одному с максимальным суммарным перекрытием (венгерский алгоритм)
+func EvaluateDiarization(reference, hypothesis []RTTMSegment, collar float64) DERResult {
+	if collar < 0 {
+		collar = 0
+	}
+	result := DERResult{CollarSec: collar, SpeakerMap: make(map[string]string)}
+
+	refSpeakers := uniqueSpeakers(reference)
+	hypSpeakers := uniqueSpeakers(hypothesis)
+
+	// Зоны без оценки вокруг границ эталона
+	var noScore [][2]float64
+	if collar > 0 {
+		for _, seg := range reference {
+			noScore = append(noScore, [2]float64{seg.Start - collar, seg.Start + collar})
+			noScore = append(noScore, [2]float64{seg.End - collar, seg.End + collar})
+		}
+	}
+
+	// Элементарные интервалы между всеми границами
+	var bounds []float64
+	for _, seg := range reference {
+		bounds = append(bounds, seg.Start, seg.End)
+	}
+	for _, seg := range hypothesis {
+		bounds = append(bounds, seg.Start, seg.End)
+	}
+	for _, z := range noScore {
+		bounds = append(bounds, z[0], z[1])
+	}
+	sort.Float64s(bounds)
+
+	type interval struct {
+		dur      float64
+		ref, hyp map[string]bool
+	}
+	var intervals []interval
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		if end-start <= 1e-9 {
+			continue
+		}
+		mid := (start + end) / 2
+		if inZones(mid, noScore) {
+			continue
+		}
+		iv := interval{dur: end - start, ref: activeSpeakers(reference, mid), hyp: activeSpeakers(hypothesis, mid)}
+		if len(iv.ref) == 0 && len(iv.hyp) == 0 {
+			continue
+		}
+		intervals = append(intervals, iv)
+	}
+
+	// Матрица перекрытий на оцениваемых интервалах
+	overlap := make([][]float64, len(refSpeakers))
+	for i, r := range refSpeakers {
+		overlap[i] = make([]float64, len(hypSpeakers))
+		for j, h := range hypSpeakers {
+			for _, iv := range intervals {
+				if iv.ref[r] && iv.hyp[h] {
+					overlap[i][j] += iv.dur
+				}
+			}
+		}
+	}
+	for i, j := range maxWeightAssignment(overlap) {
+		if j >= 0 && overlap[i][j] > 0 {
+			result.SpeakerMap[refSpeakers[i]] = hypSpeakers[j]
+		}
+	}
+
+	perSpeaker := make(map[string]*SpeakerDERBreakdown, len(refSpeakers))
+	for _, r := range refSpeakers {
+		perSpeaker[r] = &SpeakerDERBreakdown{Speaker: r, MappedTo: result.SpeakerMap[r]}
+	}
+
+	for _, iv := range intervals {
+		nRef, nHyp := len(iv.ref), len(iv.hyp)
+		nCorrect := 0
+		for r := range iv.ref {
+			b := perSpeaker[r]
+			b.ScoredSec += iv.dur
+			switch {
+			case b.MappedTo != "" && iv.hyp[b.MappedTo]:
+				nCorrect++
+				b.CorrectSec += iv.dur
+			case nHyp == 0:
+				b.MissedSec += iv.dur
+			default:
+				b.ConfusionSec += iv.dur
+			}
+		}
+
+		result.ScoredSec += iv.dur * float64(nRef)
+		result.MissedSec += iv.dur * float64(max(0, nRef-nHyp))
+		result.FalseAlarmSec += iv.dur * float64(max(0, nHyp-nRef))
+		result.ConfusionSec += iv.dur * float64(min(nRef, nHyp)-nCorrect)
+	}
+
+	if result.ScoredSec > 0 {
+		result.MissedRate = result.MissedSec / result.ScoredSec
+		result.FalseAlarmRate = result.FalseAlarmSec / result.ScoredSec
+		result.ConfusionRate = result.ConfusionSec / result.ScoredSec
+		result.DER = result.MissedRate + result.FalseAlarmRate + result.ConfusionRate
+	}
+
+	for _, r := range refSpeakers {
+		b := perSpeaker[r]
+		if b.ScoredSec > 0 {
+			b.ErrorRate = (b.MissedSec + b.ConfusionSec) / b.ScoredSec
+		}
+		result.Speakers = append(result.Speakers, *b)
+	}
+	return result
+}
+
+// uniqueSpeakers возвращает отсортированный список спикеров
+func uniqueSpeakers(segments []RTTMSegment) []string {
+	seen := make(map[string]bool)
+	var speakers []string
+	for _, seg := range segments {
+		if !seen[seg.Speaker] {
+			seen[seg.Speaker] = true
+			speakers = append(speakers, seg.Speaker)
+		}
+	}
+	sort.Strings(speakers)
+	return speakers
+}
+
+// activeSpeakers возвращает спикеров, говорящих в момент t
+func activeSpeakers(segments []RTTMSegment, t float64) map[string]bool {
+	active := make(map[string]bool)
+	for _, seg := range segments {
+		if t >= seg.Start && t < seg.End {
+			active[seg.Speaker] = true
+		}
+	}
+	return active
+}
+
+// inZones проверяет попадание момента t в одну из зон
+func inZones(t float64, zones [][2]float64) bool {
+	for _, z := range zones {
+		if t >= z[0] && t < z[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// maxWeightAssignment находит сопоставление строк со столбцами с максимальной суммой весов
+// (венгерский алгоритм). Возвращает для каждой строки индекс столбца или -1
+func maxWeightAssignment(weights [][]float64) []int {
+	rows := len(weights)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(weights[0])
+	n := max(rows, cols)
+
+	// Квадратная матрица стоимостей: минимизируем (maxWeight - weight)
+	var maxWeight float64
+	for _, row := range weights {
+		for _, w := range row {
+			maxWeight = math.Max(maxWeight, w)
+		}
+	}
+	cost := make([][]float64, n+1)
+	for i := range cost {
+		cost[i] = make([]float64, n+1)
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= n; j++ {
+			w := 0.0
+			if i <= rows && j <= cols {
+				w = weights[i-1][j-1]
+			}
+			cost[i][j] = maxWeight - w
+		}
+	}
+
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	p := make([]int, n+1)
+	way := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0][j] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, rows)
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	for j := 1; j <= n; j++ {
+		if p[j] >= 1 && p[j] <= rows && j <= cols {
+			assignment[p[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
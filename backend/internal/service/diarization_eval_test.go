@@ -0,0 +1,84 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParseRTTM(t *testing.T) {
+	input := `;; comment
+SPEAKER rec 1 0.00 2.50 <NA> <NA> alice <NA> <NA>
+SPKR-INFO rec 1 <NA> <NA> <NA> unknown bob <NA> <NA>
+SPEAKER rec 1 3.00 1.00 <NA> <NA> bob <NA> <NA>
+`
+	segments, err := ParseRTTM(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseRTTM: %v", err)
+	}
+	if len(segments) != 2 || segments[0].Speaker != "alice" || segments[1].End != 4 {
+		t.Errorf("segments = %+v", segments)
+	}
+
+	if _, err := ParseRTTM(strings.NewReader("SPEAKER rec 1 x 1.0 <NA> <NA> a <NA> <NA>")); err == nil {
+		t.Error("expected error for invalid start")
+	}
+}
+
+func TestEvaluateDiarization_PerfectWithRenamedSpeakers(t *testing.T) {
+	ref := []RTTMSegment{{"alice", 0, 5}, {"bob", 5, 10}}
+	hyp := []RTTMSegment{{"Собеседник_1", 0, 5}, {"Собеседник_2", 5, 10}}
+
+	res := EvaluateDiarization(ref, hyp, 0)
+	if res.DER != 0 {
+		t.Errorf("DER = %.3f, want 0", res.DER)
+	}
+	if res.SpeakerMap["alice"] != "Собеседник_1" || res.SpeakerMap["bob"] != "Собеседник_2" {
+		t.Errorf("SpeakerMap = %v", res.SpeakerMap)
+	}
+}
+
+func TestEvaluateDiarization_ErrorComponents(t *testing.T) {
+	ref := []RTTMSegment{{"alice", 0, 10}, {"bob", 10, 20}}
+	hyp := []RTTMSegment{
+		{"A", 0, 8},   // alice: 2с пропущено
+		{"A", 12, 14}, // bob приписан A: 2с путаницы
+		{"B", 14, 20}, // bob верно
+		{"B", 20, 22}, // 2с лишней речи
+	}
+
+	res := EvaluateDiarization(ref, hyp, 0)
+	if !approxEqual(res.ScoredSec, 20) || !approxEqual(res.MissedSec, 4) ||
+		!approxEqual(res.FalseAlarmSec, 2) || !approxEqual(res.ConfusionSec, 2) {
+		t.Errorf("scored=%.2f missed=%.2f fa=%.2f conf=%.2f", res.ScoredSec, res.MissedSec, res.FalseAlarmSec, res.ConfusionSec)
+	}
+	if !approxEqual(res.DER, 0.4) {
+		t.Errorf("DER = %.3f, want 0.4", res.DER)
+	}
+
+	var bob SpeakerDERBreakdown
+	for _, sp := range res.Speakers {
+		if sp.Speaker == "bob" {
+			bob = sp
+		}
+	}
+	if bob.MappedTo != "B" || !approxEqual(bob.ConfusionSec, 2) || !approxEqual(bob.MissedSec, 2) {
+		t.Errorf("bob breakdown = %+v", bob)
+	}
+}
+
+func TestEvaluateDiarization_CollarExcludesBoundaries(t *testing.T) {
+	ref := []RTTMSegment{{"alice", 0, 10}}
+	hyp := []RTTMSegment{{"A", 0.2, 9.8}}
+
+	if res := EvaluateDiarization(ref, hyp, 0); approxEqual(res.DER, 0) {
+		t.Error("without collar boundary errors should count")
+	}
+	if res := EvaluateDiarization(ref, hyp, 0.25); !approxEqual(res.DER, 0) {
+		t.Errorf("with collar DER = %.3f, want 0", res.DER)
+	}
+}
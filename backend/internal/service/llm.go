@@ -9,18 +9,23 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
-type LLMService struct{}
+type LLMService struct {
+	mu      sync.RWMutex
+	options map[LLMOperation]LLMOptions // Параметры генерации операций из конфигурации
+}
 
 func NewLLMService() *LLMService {
 	return &LLMService{}
 }
 
-// GenerateSummaryWithLLM generates a summary using Ollama or fallback
-func (s *LLMService) GenerateSummaryWithLLM(transcriptText string, ollamaModel string, ollamaUrl string) (string, error) {
-	summary, err := s.generateSummaryWithOllama(transcriptText, ollamaModel, ollamaUrl)
+// GenerateSummaryWithLLM generates a summary using Ollama or fallback.
+// opts переопределяет параметры генерации резюме (nil - из конфигурации)
+func (s *LLMService) GenerateSummaryWithLLM(transcriptText string, ollamaModel string, ollamaUrl string, opts *LLMOptions) (string, error) {
+	summary, err := s.generateSummaryWithOllama(transcriptText, ollamaModel, ollamaUrl, opts)
 	if err == nil && summary != "" {
 		return summary, nil
 	}
@@ -28,7 +33,7 @@ func (s *LLMService) GenerateSummaryWithLLM(transcriptText string, ollamaModel s
 	return s.generateSummaryFallback(transcriptText)
 }
 
-func (s *LLMService) generateSummaryWithOllama(transcriptText string, model string, baseUrl string) (string, error) {
+func (s *LLMService) generateSummaryWithOllama(transcriptText string, model string, baseUrl string, opts *LLMOptions) (string, error) {
 	resp, err := http.Get(baseUrl + "/api/tags")
 	if err != nil {
 		return "", fmt.Errorf("Ollama not running at %s", baseUrl)
@@ -62,11 +67,8 @@ func (s *LLMService) generateSummaryWithOllama(transcriptText string, model stri
 			{"role": "system", "content": systemPrompt},
 			{"role": "user", "content": userPrompt},
 		},
-		"stream": false,
-		"options": map[string]interface{}{
-			"temperature": 0.3,
-			"num_predict": 4096,
-		},
+		"stream":  false,
+		"options": s.ollamaOptions(LLMOpSummary, opts),
 	}
 
 	return s.callOllama(baseUrl, reqBody)
@@ -86,24 +88,34 @@ func (s *LLMService) generateSummaryFallback(transcriptText string) (string, err
 		}
 		words := strings.Fields(line)
 		totalWords += len(words)
-		if strings.HasPrefix(line, "Вы:") {
-			youLines++
-		} else if strings.HasPrefix(line, "Собеседник:") {
-			otherLines++
+		// Строки транскрипции: "[MM:SS] Вы: текст"
+		if strings.HasPrefix(line, "[") {
+			if _, rest, ok := strings.Cut(line, "] "); ok {
+				line = rest
+			}
+		}
+		if label, _, ok := parseLLMSpeakerLine(line); ok {
+			if llmSpeakerType(label) == "mic" {
+				youLines++
+			} else {
+				otherLines++
+			}
 		}
 	}
 
+	scheme := session.GetSpeakerLabelScheme()
 	summary := fmt.Sprintf(`📊 Статистика записи:
-• Реплик "Вы": %d
-• Реплик "Собеседник": %d  
+• Реплик "%s": %d
+• Реплик "%s": %d  
 • Всего слов: %d
-💡 Для полноценного AI-анализа установите Ollama.`, youLines, otherLines, totalWords)
+💡 Для полноценного AI-анализа установите Ollama.`, scheme.Self, youLines, scheme.Prefix, otherLines, totalWords)
 	return summary, nil
 }
 
 // ImproveTranscriptionWithLLM improves transcription quality
-// Поддерживает batch обработку для длинных текстов (более 40000 символов)
-func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string) ([]session.TranscriptSegment, error) {
+// Поддерживает batch обработку для длинных текстов (более 40000 символов).
+// opts переопределяет параметры генерации (nil - из конфигурации)
+func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string, opts *LLMOptions) ([]session.TranscriptSegment, error) {
 	resp, err := http.Get(ollamaUrl + "/api/tags")
 	if err != nil {
 		return nil, fmt.Errorf("Ollama not running at %s", ollamaUrl)
@@ -121,7 +133,7 @@ func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSe
 
 	// Если текст короткий - обрабатываем целиком
 	if totalLen <= maxCharsPerBatch {
-		return s.improveDialogueBatch(dialogue, ollamaModel, ollamaUrl)
+		return s.improveDialogueBatch(dialogue, ollamaModel, ollamaUrl, opts)
 	}
 
 	// Разбиваем на батчи по сегментам (не разрезаем реплики)
@@ -136,7 +148,7 @@ func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSe
 
 		// Если добавление сегмента превысит лимит - обрабатываем текущий батч
 		if batchLen+segLen > maxCharsPerBatch && len(batch) > 0 {
-			improved, err := s.improveDialogueBatch(batch, ollamaModel, ollamaUrl)
+			improved, err := s.improveDialogueBatch(batch, ollamaModel, ollamaUrl, opts)
 			if err != nil {
 				log.Printf("LLM Improve batch error: %v, keeping original", err)
 				allImproved = append(allImproved, batch...)
@@ -153,7 +165,7 @@ func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSe
 
 	// Обрабатываем последний батч
 	if len(batch) > 0 {
-		improved, err := s.improveDialogueBatch(batch, ollamaModel, ollamaUrl)
+		improved, err := s.improveDialogueBatch(batch, ollamaModel, ollamaUrl, opts)
 		if err != nil {
 			log.Printf("LLM Improve last batch error: %v, keeping original", err)
 			allImproved = append(allImproved, batch...)
@@ -166,34 +178,16 @@ func (s *LLMService) ImproveTranscriptionWithLLM(dialogue []session.TranscriptSe
 }
 
 // improveDialogueBatch улучшает один батч диалога
-func (s *LLMService) improveDialogueBatch(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string) ([]session.TranscriptSegment, error) {
+func (s *LLMService) improveDialogueBatch(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string, opts *LLMOptions) ([]session.TranscriptSegment, error) {
 	var dialogueText strings.Builder
 	for _, seg := range dialogue {
-		// Определяем отображаемую метку для LLM
-		displaySpeaker := "Вы"
-		if seg.Speaker != "" && seg.Speaker != "mic" {
-			// Поддержка "sys", "Собеседник", "Собеседник 1", "Собеседник 2", "Speaker N" и т.д.
-			switch {
-			case strings.HasPrefix(seg.Speaker, "Собеседник"):
-				displaySpeaker = seg.Speaker // Уже в нужном формате
-			case strings.HasPrefix(seg.Speaker, "Speaker "):
-				// "Speaker 0" -> "Собеседник 1"
-				var num int
-				fmt.Sscanf(seg.Speaker, "Speaker %d", &num)
-				displaySpeaker = fmt.Sprintf("Собеседник %d", num+1)
-			case seg.Speaker == "sys":
-				displaySpeaker = "Собеседник" // Один собеседник без номера
-			default:
-				// Кастомное имя - сохраняем как есть
-				displaySpeaker = seg.Speaker
-			}
-		}
-		dialogueText.WriteString(fmt.Sprintf("[%s] %s\n", displaySpeaker, seg.Text))
+		dialogueText.WriteString(fmt.Sprintf("[%s] %s\n", speakerLabelForLLM(seg.Speaker), seg.Text))
 	}
 
 	text := dialogueText.String()
 
-	systemPrompt := `Ты — эксперт по редактированию транскрипций русской речи.
+	scheme := session.GetSpeakerLabelScheme()
+	systemPrompt := fmt.Sprintf(`Ты — эксперт по редактированию транскрипций русской речи.
 
 ТВОИ ЗАДАЧИ (в порядке приоритета):
 1. РАЗДЕЛЯЙ СКЛЕЕННЫЕ СЛОВА: "вопросеянеможо" → "вопросе я не могу", "какомсостояни" → "каком состоянии"
@@ -203,25 +197,25 @@ func (s *LLMService) improveDialogueBatch(dialogue []session.TranscriptSegment,
 5. РАЗБИВАЙ длинные реплики (больше 2-3 предложений) на отдельные строки с тем же спикером
 
 ФОРМАТ ВХОДА:
-[Вы] текст реплики
-[Собеседник] текст реплики
-[Собеседник 1] текст реплики  
-[Собеседник 2] текст реплики
+[%[1]s] текст реплики
+[%[2]s] текст реплики
+[%[3]s] текст реплики  
+[%[4]s] текст реплики
 
 ФОРМАТ ВЫХОДА (строго такой же, СОХРАНЯЯ НОМЕРА СОБЕСЕДНИКОВ):
-[Вы] Исправленный текст.
-[Собеседник] Исправленный текст.
-[Собеседник 1] Исправленный текст.
-[Собеседник 2] Исправленный текст.
+[%[1]s] Исправленный текст.
+[%[2]s] Исправленный текст.
+[%[3]s] Исправленный текст.
+[%[4]s] Исправленный текст.
 
 СТРОГИЕ ПРАВИЛА:
 - НЕ меняй смысл и порядок слов
 - НЕ удаляй и НЕ добавляй реплики
 - НЕ объединяй реплики разных спикеров
-- СОХРАНЯЙ ТОЧНЫЕ МЕТКИ СПИКЕРОВ: [Собеседник 1] должен остаться [Собеседник 1], а НЕ [Собеседник]
+- СОХРАНЯЙ ТОЧНЫЕ МЕТКИ СПИКЕРОВ: [%[3]s] должен остаться [%[3]s], а НЕ [%[2]s]
 - Сохраняй порядок реплик
 - Если реплика длинная — разбей на несколько строк с ТЕМ ЖЕ спикером и ТОЙ ЖЕ МЕТКОЙ
-- Отвечай ТОЛЬКО исправленным текстом, без комментариев`
+- Отвечай ТОЛЬКО исправленным текстом, без комментариев`, scheme.Self, scheme.Prefix, scheme.Label(0), scheme.Label(1))
 
 	userPrompt := fmt.Sprintf("Улучши эту транскрипцию:\n\n%s", text)
 
@@ -232,7 +226,7 @@ func (s *LLMService) improveDialogueBatch(dialogue []session.TranscriptSegment,
 			{"role": "user", "content": userPrompt},
 		},
 		"stream":  false,
-		"options": map[string]interface{}{"temperature": 0.1, "num_predict": 16384}, // Увеличен для длинных текстов
+		"options": s.ollamaOptions(LLMOpImprove, opts),
 	}
 
 	response, err := s.callOllama(ollamaUrl, reqBody)
@@ -243,19 +237,139 @@ func (s *LLMService) improveDialogueBatch(dialogue []session.TranscriptSegment,
 	return s.parseImprovedDialogue(response, dialogue), nil
 }
 
+// speakerLabelForLLM возвращает отображаемую метку спикера для LLM:
+// "mic", "sys" и "Speaker N" - стандартные имена схемы, кастомные имена сохраняются как есть
+func speakerLabelForLLM(speaker string) string {
+	if speaker == "" {
+		return session.SelfSpeakerLabel()
+	}
+	return session.FormatSpeakerLabel(speaker)
+}
+
+// llmSpeakerType определяет канал спикера по его метке: "mic" для владельца микрофона, иначе "sys"
+func llmSpeakerType(speaker string) string {
+	if speaker == "" || session.IsSelfSpeakerLabel(speaker) || session.IsMicSpeakerLabel(speaker) {
+		return "mic"
+	}
+	return "sys" // Все остальные - собеседники
+}
+
+// parseLLMSpeakerLine разбирает строку ответа LLM вида "[Метка] текст" или "Метка: текст".
+// Во втором виде принимаются только стандартные имена, чтобы не принять за реплику
+// пояснения LLM вроде "Примечание: ..."
+func parseLLMSpeakerLine(line string) (label, text string, ok bool) {
+	if rest, found := strings.CutPrefix(line, "["); found {
+		label, text, found = strings.Cut(rest, "]")
+		label = strings.TrimSpace(label)
+		return label, text, found && label != ""
+	}
+	label, text, found := strings.Cut(line, ":")
+	label = strings.TrimSpace(label)
+	if !found || !session.IsStandardSpeakerLabel(label) {
+		return "", "", false
+	}
+	return label, text, true
+}
+
+// InferSpeakerNames просит LLM определить настоящие имена собеседников по контексту
+// (самопредставления, обращения по имени). Возвращает map: стандартная метка ("Собеседник N") -> имя.
+// В результат попадают только метки, присутствующие в диалоге; владелец микрофона ("Вы") не переименовывается.
+func (s *LLMService) InferSpeakerNames(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string) (map[string]string, error) {
+	resp, err := http.Get(ollamaUrl + "/api/tags")
+	if err != nil {
+		return nil, fmt.Errorf("Ollama not running at %s", ollamaUrl)
+	}
+	resp.Body.Close()
+
+	const maxChars = 40000
+
+	labels := make(map[string]bool)
+	var dialogueText strings.Builder
+	for _, seg := range dialogue {
+		label := speakerLabelForLLM(seg.Speaker)
+		if !session.IsStandardSpeakerLabel(label) || session.IsSelfSpeakerLabel(label) {
+			continue // Уже переименованные спикеры и "Вы" не интересуют
+		}
+		labels[label] = true
+		if dialogueText.Len() < maxChars {
+			dialogueText.WriteString(fmt.Sprintf("[%s] %s\n", label, seg.Text))
+		}
+	}
+	if len(labels) == 0 {
+		return map[string]string{}, nil
+	}
+
+	scheme := session.GetSpeakerLabelScheme()
+	systemPrompt := fmt.Sprintf(`Ты — ассистент, который определяет имена участников разговора по транскрипции.
+
+ЗАДАЧА: по самопредставлениям ("Привет, я Анна", "Меня зовут Олег") и обращениям по имени
+определи настоящие имена спикеров с метками [%[2]s], [%[3]s], [%[4]s] и т.д.
+
+ФОРМАТ ОТВЕТА — строго JSON-объект без комментариев:
+{"%[3]s": "Анна", "%[4]s": "Олег"}
+
+ПРАВИЛА:
+- Включай только спикеров, чьё имя явно следует из текста
+- Если имя не удаётся определить уверенно — НЕ включай спикера
+- Не выдумывай имена
+- Метка [%[1]s] — это пользователь, её не включай`, scheme.Self, scheme.Prefix, scheme.Label(0), scheme.Label(1))
+
+	userPrompt := fmt.Sprintf("Определи имена спикеров:\n\n%s", dialogueText.String())
+
+	reqBody := map[string]interface{}{
+		"model": ollamaModel,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": userPrompt},
+		},
+		"stream":  false,
+		"format":  "json",
+		"options": s.ollamaOptions(LLMOpSpeakerNames, nil),
+	}
+
+	response, err := s.callOllama(ollamaUrl, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSpeakerNames(response, labels)
+}
+
+// parseSpeakerNames разбирает JSON ответ LLM с именами спикеров
+// и оставляет только известные метки с непустыми именами
+func parseSpeakerNames(response string, labels map[string]bool) (map[string]string, error) {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(response)), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+
+	names := make(map[string]string)
+	for label, name := range raw {
+		label = strings.Trim(strings.TrimSpace(label), "[]")
+		name = strings.TrimSpace(name)
+		if !labels[label] || name == "" || name == label || session.IsStandardSpeakerLabel(name) {
+			continue
+		}
+		names[label] = name
+	}
+
+	log.Printf("InferSpeakerNames: LLM proposed %d names for %d speakers", len(names), len(labels))
+	return names, nil
+}
+
 func (s *LLMService) parseImprovedDialogue(improvedText string, originalDialogue []session.TranscriptSegment) []session.TranscriptSegment {
 	lines := strings.Split(improvedText, "\n")
 	var improved []session.TranscriptSegment
 	origIdx := 0 // Индекс в оригинальном диалоге для timestamps
 	var lastSpeakerType string
 
-	// Вспомогательная функция для определения типа спикера (mic или sys)
-	getSpeakerType := func(speaker string) string {
-		if speaker == "mic" || speaker == "Вы" {
-			return "mic"
-		}
-		return "sys" // Все остальные - собеседники
-	}
+	// Тип спикера (mic или sys)
+	getSpeakerType := llmSpeakerType
 
 	// Вспомогательная функция для получения оригинального спикера по типу
 	// Это нужно чтобы сохранить оригинальные метки (sys, Speaker 0, etc.)
@@ -280,35 +394,14 @@ func (s *LLMService) parseImprovedDialogue(improvedText string, originalDialogue
 			continue
 		}
 
-		var parsedSpeakerType, text string
-
-		// Парсим разные форматы спикеров
-		switch {
-		case strings.HasPrefix(line, "[Вы]"):
-			parsedSpeakerType = "mic"
-			text = strings.TrimPrefix(line, "[Вы]")
-		case strings.HasPrefix(line, "[Собеседник"):
-			// Поддержка [Собеседник], [Собеседник 1], [Собеседник 2] и т.д.
-			parsedSpeakerType = "sys"
-			idx := strings.Index(line, "]")
-			if idx > 0 {
-				text = line[idx+1:]
-			}
-		case strings.HasPrefix(line, "Вы:"):
-			parsedSpeakerType = "mic"
-			text = strings.TrimPrefix(line, "Вы:")
-		case strings.HasPrefix(line, "Собеседник"):
-			// Поддержка Собеседник:, Собеседник 1:, Собеседник 2: и т.д.
-			parsedSpeakerType = "sys"
-			idx := strings.Index(line, ":")
-			if idx > 0 {
-				text = line[idx+1:]
-			}
-		default:
+		// Парсим "[Метка] текст" и "Метка: текст"
+		label, text, ok := parseLLMSpeakerLine(line)
+		if !ok {
 			// Если строка без префикса - это продолжение предыдущей реплики
 			// или мусор от LLM - пропускаем
 			continue
 		}
+		parsedSpeakerType := getSpeakerType(label)
 
 		text = strings.TrimSpace(text)
 		if text == "" {
@@ -476,41 +569,42 @@ func (s *LLMService) DiarizeWithLLM(dialogue []session.TranscriptSegment, ollama
 
 // diarizeDialogueBatch разбивает один батч диалога по собеседникам
 func (s *LLMService) diarizeDialogueBatch(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string) ([]session.TranscriptSegment, error) {
+	scheme := session.GetSpeakerLabelScheme()
 	var dialogueText strings.Builder
 	for _, seg := range dialogue {
-		speaker := "Вы"
-		if seg.Speaker != "" && seg.Speaker != "mic" {
-			speaker = "Собеседник"
+		speaker := scheme.Self
+		if llmSpeakerType(seg.Speaker) != "mic" {
+			speaker = scheme.Prefix
 		}
 		dialogueText.WriteString(fmt.Sprintf("[%s] %s\n", speaker, seg.Text))
 	}
 
 	text := dialogueText.String()
 
-	systemPrompt := `Ты — эксперт по анализу диалогов и определению говорящих.
+	systemPrompt := fmt.Sprintf(`Ты — эксперт по анализу диалогов и определению говорящих.
 
 ТВОЯ ЗАДАЧА:
-Проанализировать диалог и разбить реплики "Собеседник" по разным собеседникам (Собеседник 1, Собеседник 2 и т.д.)
+Проанализировать диалог и разбить реплики "%[2]s" по разным собеседникам (%[3]s, %[4]s и т.д.)
 на основе контекста, стиля речи, логики беседы.
 
 ФОРМАТ ВХОДА:
-[Вы] текст вашей реплики
-[Собеседник] текст реплики собеседника
+[%[1]s] текст вашей реплики
+[%[2]s] текст реплики собеседника
 
 ФОРМАТ ВЫХОДА (ОБЯЗАТЕЛЬНО с нумерацией собеседников):
-[Вы] текст вашей реплики
-[Собеседник 1] текст первого собеседника
-[Собеседник 2] текст второго собеседника
+[%[1]s] текст вашей реплики
+[%[3]s] текст первого собеседника
+[%[4]s] текст второго собеседника
 
 ПРАВИЛА ОПРЕДЕЛЕНИЯ СОБЕСЕДНИКОВ:
 1. Анализируй контекст: разные темы обсуждения = разные собеседники
 2. Анализируй стиль: формальный/неформальный, технический/бытовой
 3. Анализируй логику: если реплики противоречат друг другу - скорее всего разные люди
-4. Если разговор один-на-один (только 1 собеседник) - используй просто "Собеседник 1"
+4. Если разговор один-на-один (только 1 собеседник) - используй просто "%[3]s"
 5. НЕ меняй текст реплик, только метки спикеров
 6. НЕ объединяй и НЕ разделяй реплики
 7. Сохраняй порядок реплик
-8. Отвечай ТОЛЬКО размеченным текстом, без комментариев`
+8. Отвечай ТОЛЬКО размеченным текстом, без комментариев`, scheme.Self, scheme.Prefix, scheme.Label(0), scheme.Label(1))
 
 	userPrompt := fmt.Sprintf("Разбей этот диалог по собеседникам:\n\n%s", text)
 
@@ -521,7 +615,7 @@ func (s *LLMService) diarizeDialogueBatch(dialogue []session.TranscriptSegment,
 			{"role": "user", "content": userPrompt},
 		},
 		"stream":  false,
-		"options": map[string]interface{}{"temperature": 0.2, "num_predict": 16384},
+		"options": s.ollamaOptions(LLMOpDiarize, nil),
 	}
 
 	response, err := s.callOllama(ollamaUrl, reqBody)
@@ -556,32 +650,13 @@ func (s *LLMService) parseDiarizedDialogue(diarizedText string, originalDialogue
 			continue
 		}
 
-		var speaker, text string
-
-		switch {
-		case strings.HasPrefix(line, "[Вы]"):
-			speaker = "mic"
-			text = strings.TrimPrefix(line, "[Вы]")
-		case strings.HasPrefix(line, "[Собеседник"):
-			idx := strings.Index(line, "]")
-			if idx > 0 {
-				speakerLabel := strings.TrimSpace(line[1:idx])
-				speaker = speakerLabel
-				text = line[idx+1:]
-			}
-		case strings.HasPrefix(line, "Вы:"):
-			speaker = "mic"
-			text = strings.TrimPrefix(line, "Вы:")
-		case strings.HasPrefix(line, "Собеседник"):
-			idx := strings.Index(line, ":")
-			if idx > 0 {
-				speakerLabel := strings.TrimSpace(line[:idx])
-				speaker = speakerLabel
-				text = line[idx+1:]
-			}
-		default:
+		speaker, text, ok := parseLLMSpeakerLine(line)
+		if !ok {
 			continue
 		}
+		if llmSpeakerType(speaker) == "mic" {
+			speaker = "mic"
+		}
 
 		text = strings.TrimSpace(text)
 		if text == "" {
@@ -606,7 +681,7 @@ func (s *LLMService) parseDiarizedDialogue(diarizedText string, originalDialogue
 			score := textSimilarity(normalizedText, origText)
 
 			// Бонус за совпадение типа спикера (mic vs sys)
-			origIsMic := orig.seg.Speaker == "mic" || orig.seg.Speaker == "Вы"
+			origIsMic := llmSpeakerType(orig.seg.Speaker) == "mic"
 			newIsMic := speaker == "mic"
 			if origIsMic == newIsMic {
 				score += 0.1
@@ -765,11 +840,8 @@ func (s *LLMService) SelectBestTranscription(original, alternative, context, oll
 			{"role": "system", "content": systemPrompt},
 			{"role": "user", "content": userPrompt},
 		},
-		"stream": false,
-		"options": map[string]interface{}{
-			"temperature": 0.1,
-			"num_predict": 512,
-		},
+		"stream":  false,
+		"options": s.ollamaOptions(LLMOpSelectBest, nil),
 	}
 
 	response, err := s.callOllama(ollamaUrl, reqBody)
@@ -0,0 +1,102 @@
+package service
+
+// LLMOperation операция LLM со своими параметрами генерации
+type LLMOperation string
+
+const (
+	LLMOpSummary      LLMOperation = "summary"
+	LLMOpImprove      LLMOperation = "improve"
+	LLMOpSelectBest   LLMOperation = "select_best"
+	LLMOpDiarize      LLMOperation = "diarize"
+	LLMOpSpeakerNames LLMOperation = "speaker_names"
+	LLMOpPunctuation  LLMOperation = "punctuation"
+)
+
+// LLMOptions параметры генерации Ollama (поле options запроса).
+// Незаданные поля берутся из настроек операции
+type LLMOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"topP,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"` // num_predict
+}
+
+// NewLLMOptions создаёт параметры из значений конфигурации:
+// отрицательная температура, top_p <= 0 и maxTokens <= 0 означают "не задано"
+func NewLLMOptions(temperature, topP float64, maxTokens int) LLMOptions {
+	var opts LLMOptions
+	if temperature >= 0 {
+		opts.Temperature = &temperature
+	}
+	if topP > 0 {
+		opts.TopP = &topP
+	}
+	if maxTokens > 0 {
+		opts.MaxTokens = maxTokens
+	}
+	return opts
+}
+
+// defaultLLMOptions параметры операций по умолчанию: правка текста и выбор варианта
+// почти детерминированы, резюме чуть свободнее
+var defaultLLMOptions = map[LLMOperation]LLMOptions{
+	LLMOpSummary:      NewLLMOptions(0.3, 0, 4096),
+	LLMOpImprove:      NewLLMOptions(0.1, 0, 16384), // Увеличен для длинных текстов
+	LLMOpSelectBest:   NewLLMOptions(0.1, 0, 512),
+	LLMOpDiarize:      NewLLMOptions(0.2, 0, 16384),
+	LLMOpSpeakerNames: NewLLMOptions(0.1, 0, 1024),
+	LLMOpPunctuation:  NewLLMOptions(0, 0, 8192),
+}
+
+// merge возвращает o с заданными полями override поверх
+func (o LLMOptions) merge(override *LLMOptions) LLMOptions {
+	if override == nil {
+		return o
+	}
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		o.MaxTokens = override.MaxTokens
+	}
+	return o
+}
+
+// SetOptions задаёт параметры генерации операции поверх значений по умолчанию
+func (s *LLMService) SetOptions(op LLMOperation, opts LLMOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.options == nil {
+		s.options = make(map[LLMOperation]LLMOptions)
+	}
+	s.options[op] = defaultLLMOptions[op].merge(&opts)
+}
+
+// Options возвращает параметры генерации операции с учётом override запроса
+func (s *LLMService) Options(op LLMOperation, override *LLMOptions) LLMOptions {
+	s.mu.RLock()
+	opts, ok := s.options[op]
+	s.mu.RUnlock()
+	if !ok {
+		opts = defaultLLMOptions[op]
+	}
+	return opts.merge(override)
+}
+
+// ollamaOptions формирует объект options запроса Ollama
+func (s *LLMService) ollamaOptions(op LLMOperation, override *LLMOptions) map[string]interface{} {
+	opts := s.Options(op, override)
+	result := make(map[string]interface{}, 3)
+	if opts.Temperature != nil {
+		result["temperature"] = *opts.Temperature
+	}
+	if opts.TopP != nil {
+		result["top_p"] = *opts.TopP
+	}
+	if opts.MaxTokens > 0 {
+		result["num_predict"] = opts.MaxTokens
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package service
+
+import "testing"
+
+func TestLLMOptionsLayering(t *testing.T) {
+	s := NewLLMService()
+
+	// По умолчанию - прежние фиксированные значения, top_p не передаётся
+	got := s.ollamaOptions(LLMOpSummary, nil)
+	if got["temperature"] != 0.3 || got["num_predict"] != 4096 {
+		t.Errorf("default summary options = %v", got)
+	}
+	if _, ok := got["top_p"]; ok {
+		t.Errorf("top_p must not be sent by default: %v", got)
+	}
+
+	// Конфигурация меняет только заданные поля
+	s.SetOptions(LLMOpSummary, NewLLMOptions(-1, 0.8, 0))
+	got = s.ollamaOptions(LLMOpSummary, nil)
+	if got["temperature"] != 0.3 || got["top_p"] != 0.8 || got["num_predict"] != 4096 {
+		t.Errorf("configured summary options = %v", got)
+	}
+
+	// Параметры запроса поверх конфигурации, включая нулевую температуру
+	zero := 0.0
+	got = s.ollamaOptions(LLMOpSummary, &LLMOptions{Temperature: &zero, MaxTokens: 256})
+	if got["temperature"] != 0.0 || got["top_p"] != 0.8 || got["num_predict"] != 256 {
+		t.Errorf("overridden summary options = %v", got)
+	}
+
+	// Остальные операции не затронуты
+	if got := s.ollamaOptions(LLMOpSelectBest, nil); got["temperature"] != 0.1 || got["num_predict"] != 512 {
+		t.Errorf("select best options = %v", got)
+	}
+}
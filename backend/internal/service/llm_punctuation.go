@@ -0,0 +1,133 @@
+package service
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RestorePunctuation расставляет пунктуацию и заглавные буквы в репликах без перефразирования.
+// Реплика принимается, только если LLM сохранила все слова и их порядок, иначе остаётся исходной.
+// Таймстемпы, спикеры и слова (Words) не меняются
+func (s *LLMService) RestorePunctuation(dialogue []session.TranscriptSegment, ollamaModel string, ollamaUrl string) ([]session.TranscriptSegment, error) {
+	resp, err := http.Get(ollamaUrl + "/api/tags")
+	if err != nil {
+		return nil, fmt.Errorf("Ollama not running at %s", ollamaUrl)
+	}
+	resp.Body.Close()
+
+	const maxCharsPerBatch = 20000 // Ответ повторяет весь текст, поэтому батчи меньше, чем у improve
+
+	result := make([]session.TranscriptSegment, len(dialogue))
+	copy(result, dialogue)
+
+	restored := 0
+	for start := 0; start < len(result); {
+		end, batchLen := start, 0
+		for end < len(result) && (end == start || batchLen+len(result[end].Text)+8 <= maxCharsPerBatch) {
+			batchLen += len(result[end].Text) + 8 // +8 на номер строки
+			end++
+		}
+
+		n, err := s.restorePunctuationBatch(result[start:end], ollamaModel, ollamaUrl)
+		if err != nil {
+			log.Printf("LLM Punctuation batch %d-%d error: %v, keeping original", start, end, err)
+		}
+		restored += n
+		start = end
+	}
+
+	log.Printf("LLM Punctuation: restored %d of %d segments", restored, len(result))
+	return result, nil
+}
+
+// restorePunctuationBatch обновляет текст реплик батча на месте, возвращает число принятых реплик
+func (s *LLMService) restorePunctuationBatch(batch []session.TranscriptSegment, ollamaModel string, ollamaUrl string) (int, error) {
+	var text strings.Builder
+	for i, seg := range batch {
+		text.WriteString(fmt.Sprintf("%d| %s\n", i+1, seg.Text))
+	}
+
+	systemPrompt := `Ты — корректор транскрипций речи. Расставь знаки препинания и заглавные буквы.
+
+СТРОГО ЗАПРЕЩЕНО:
+- менять, добавлять, удалять или переставлять слова
+- исправлять ошибки распознавания и перефразировать
+- объединять или разбивать строки
+
+ФОРМАТ: каждая строка начинается с номера и "|". Верни ВСЕ строки с теми же номерами:
+1| Текст с пунктуацией.
+2| Текст с пунктуацией?
+
+Отвечай ТОЛЬКО строками, без комментариев`
+
+	reqBody := map[string]interface{}{
+		"model": ollamaModel,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": "Расставь пунктуацию:\n\n" + text.String()},
+		},
+		"stream":  false,
+		"options": s.ollamaOptions(LLMOpPunctuation, nil),
+	}
+
+	response, err := s.callOllama(ollamaUrl, reqBody)
+	if err != nil {
+		return 0, err
+	}
+	return applyPunctuationResponse(batch, response), nil
+}
+
+// applyPunctuationResponse применяет ответ LLM ("N| текст") к репликам батча.
+// Строки с изменёнными словами, неизвестными номерами и мусор пропускаются
+func applyPunctuationResponse(batch []session.TranscriptSegment, response string) int {
+	applied := 0
+	for _, line := range strings.Split(response, "\n") {
+		num, text, ok := strings.Cut(strings.TrimSpace(line), "|")
+		if !ok {
+			continue
+		}
+		idx, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || idx < 1 || idx > len(batch) {
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || text == batch[idx-1].Text {
+			continue
+		}
+		if !sameWords(batch[idx-1].Text, text) {
+			log.Printf("LLM Punctuation: segment %d rephrased, keeping original", idx)
+			continue
+		}
+		batch[idx-1].Text = text
+		applied++
+	}
+	return applied
+}
+
+// sameWords сравнивает тексты по словам без учёта регистра, пунктуации и ё/е
+func sameWords(a, b string) bool {
+	wa, wb := punctuationFreeWords(a), punctuationFreeWords(b)
+	if len(wa) != len(wb) {
+		return false
+	}
+	for i := range wa {
+		if wa[i] != wb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// punctuationFreeWords разбивает текст на слова в нижнем регистре без знаков препинания.
+// Дефисы и апострофы считаются разделителями: "кто-то" и "кто то" равны
+func punctuationFreeWords(text string) []string {
+	text = strings.NewReplacer("ё", "е", "Ё", "е").Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
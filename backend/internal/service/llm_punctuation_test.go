@@ -0,0 +1,32 @@
+package service
+
+import (
+	"aiwisper/session"
+	"testing"
+)
+
+func TestApplyPunctuationResponse(t *testing.T) {
+	batch := []session.TranscriptSegment{
+		{Speaker: "mic", Text: "привет как дела"},
+		{Speaker: "sys", Text: "нормально а у тебя"},
+		{Speaker: "sys", Text: "ну ещё кто то сказал"},
+		{Speaker: "mic", Text: "да"},
+	}
+
+	response := "Вот результат:\n" +
+		"1| Привет, как дела?\n" +
+		"2| Нормально. А как у тебя?\n" + // добавлено слово - отклоняется
+		"3| Ну, еще кто-то сказал.\n" + // ё/е и дефис не считаются изменением слов
+		"7| Лишняя строка.\n"
+
+	if got := applyPunctuationResponse(batch, response); got != 2 {
+		t.Errorf("applied = %d, want 2", got)
+	}
+
+	want := []string{"Привет, как дела?", "нормально а у тебя", "Ну, еще кто-то сказал.", "да"}
+	for i, seg := range batch {
+		if seg.Text != want[i] {
+			t.Errorf("segment %d = %q, want %q", i+1, seg.Text, want[i])
+		}
+	}
+}
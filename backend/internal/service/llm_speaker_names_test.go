@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aiwisper/session"
+)
+
+func TestSpeakerLabelForLLM(t *testing.T) {
+	tests := map[string]string{
+		"":             "Вы",
+		"mic":          "Вы",
+		"sys":          "Собеседник",
+		"Speaker 0":    "Собеседник 1",
+		"Speaker 2":    "Собеседник 3",
+		"Собеседник 2": "Собеседник 2",
+		"Анна":         "Анна",
+	}
+	for speaker, want := range tests {
+		if got := speakerLabelForLLM(speaker); got != want {
+			t.Errorf("speakerLabelForLLM(%q) = %q, want %q", speaker, got, want)
+		}
+	}
+}
+
+func TestParseSpeakerNames(t *testing.T) {
+	labels := map[string]bool{"Собеседник 1": true, "Собеседник 2": true}
+	names, err := parseSpeakerNames("```json\n"+`{"[Собеседник 1]": " Анна ", "Собеседник 2": "Собеседник 2", "Собеседник 3": "Олег", "Вы": "Иван"}`+"\n```", labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Только известные метки с настоящими именами
+	if len(names) != 1 || names["Собеседник 1"] != "Анна" {
+		t.Errorf("names = %v", names)
+	}
+
+	if _, err := parseSpeakerNames("не JSON", labels); err == nil {
+		t.Error("expected error for invalid response")
+	}
+}
+
+func TestInferSpeakerNames(t *testing.T) {
+	var prompt string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models":[]}`))
+			return
+		}
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		json.NewEncoder(w).Encode(map[string]any{"message": map[string]string{"content": `{"Собеседник 1": "Анна"}`}})
+	}))
+	defer ollama.Close()
+
+	dialogue := []session.TranscriptSegment{
+		{Speaker: "mic", Text: "Здравствуйте, Анна"},
+		{Speaker: "Speaker 0", Text: "Добрый день"},
+		{Speaker: "Олег", Text: "Уже переименован"},
+	}
+	names, err := NewLLMService().InferSpeakerNames(dialogue, "test-model", ollama.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names["Собеседник 1"] != "Анна" {
+		t.Errorf("names = %v", names)
+	}
+	// В LLM уходят только неназванные собеседники
+	if !strings.Contains(prompt, "[Собеседник 1] Добрый день") || strings.Contains(prompt, "Олег") || strings.Contains(prompt, "[Вы]") {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	// Все спикеры уже названы - LLM не вызывается
+	prompt = ""
+	names, err = NewLLMService().InferSpeakerNames(dialogue[2:], "test-model", ollama.URL)
+	if err != nil || len(names) != 0 || prompt != "" {
+		t.Errorf("named dialogue: names = %v, err = %v, prompt = %q", names, err, prompt)
+	}
+}
+
+func TestInferSpeakerNames_CustomSpeakerLabelScheme(t *testing.T) {
+	t.Cleanup(func() { session.SetSpeakerLabelScheme(session.DefaultSpeakerLabelScheme()) })
+	if err := session.SetSpeakerLabelScheme(session.SpeakerLabelScheme{Prefix: "Участник", Base: 1, Self: "Me"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var system, prompt string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models":[]}`))
+			return
+		}
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		system, prompt = req.Messages[0].Content, req.Messages[len(req.Messages)-1].Content
+		json.NewEncoder(w).Encode(map[string]any{"message": map[string]string{"content": `{"Участник 1": "Анна", "Участник 2": "Участник 3"}`}})
+	}))
+	defer ollama.Close()
+
+	dialogue := []session.TranscriptSegment{
+		{Speaker: "Me", Text: "Здравствуйте, Анна"},
+		{Speaker: "Участник 1", Text: "Добрый день"},
+		{Speaker: "Speaker 1", Text: "Привет"},
+		{Speaker: "Олег", Text: "Уже переименован"},
+	}
+	names, err := NewLLMService().InferSpeakerNames(dialogue, "test-model", ollama.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Стандартное имя схемы не принимается как настоящее
+	if len(names) != 1 || names["Участник 1"] != "Анна" {
+		t.Errorf("names = %v", names)
+	}
+	if !strings.Contains(prompt, "[Участник 1] Добрый день") || !strings.Contains(prompt, "[Участник 2] Привет") ||
+		strings.Contains(prompt, "Олег") || strings.Contains(prompt, "[Me]") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if !strings.Contains(system, `{"Участник 1": "Анна", "Участник 2": "Олег"}`) || strings.Contains(system, "Собеседник") {
+		t.Errorf("system prompt does not follow the label scheme: %q", system)
+	}
+}
+
+func TestLLMDialogue_CustomSpeakerLabelScheme(t *testing.T) {
+	t.Cleanup(func() { session.SetSpeakerLabelScheme(session.DefaultSpeakerLabelScheme()) })
+	if err := session.SetSpeakerLabelScheme(session.SpeakerLabelScheme{Prefix: "Участник", Base: 1, Self: "Me"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := speakerLabelForLLM("mic"); got != "Me" {
+		t.Errorf("speakerLabelForLLM(mic) = %q", got)
+	}
+	if got := speakerLabelForLLM("Speaker 1"); got != "Участник 2" {
+		t.Errorf("speakerLabelForLLM(Speaker 1) = %q", got)
+	}
+
+	s := NewLLMService()
+	original := []session.TranscriptSegment{
+		{Start: 0, End: 1000, Speaker: "Me", Text: "привет"},
+		{Start: 1000, End: 2000, Speaker: "Участник 1", Text: "здравствуйте"},
+	}
+	improved := s.parseImprovedDialogue("[Me] Привет!\n[Участник 1] Здравствуйте!", original)
+	if len(improved) != 2 || improved[0].Speaker != "Me" || improved[1].Speaker != "Участник 1" || improved[1].Text != "Здравствуйте!" {
+		t.Errorf("improved = %+v", improved)
+	}
+
+	diarized := s.parseDiarizedDialogue("Me: привет\n[Участник 2] здравствуйте", original)
+	if len(diarized) != 2 || diarized[0].Speaker != "mic" || diarized[1].Speaker != "Участник 2" || diarized[1].Start != 1000 {
+		t.Errorf("diarized = %+v", diarized)
+	}
+}
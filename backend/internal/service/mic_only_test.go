@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+// captureWriter запоминает записанные семплы вместо кодирования MP3
+type captureWriter struct{ samples []float32 }
+
+func (w *captureWriter) Write(samples []float32) error {
+	w.samples = append(w.samples, samples...)
+	return nil
+}
+func (w *captureWriter) SamplesWritten() int64   { return int64(len(w.samples) / 2) }
+func (w *captureWriter) Duration() time.Duration { return 0 }
+func (w *captureWriter) Close() error            { return nil }
+func (w *captureWriter) FilePath() string        { return "" }
+
+func TestWriteMicOnly(t *testing.T) {
+	s := &RecordingService{}
+	writer := &captureWriter{}
+	buffer := session.NewChunkBuffer(session.DictationVADConfig(), session.SampleRate)
+	defer buffer.Close()
+
+	var streamed int
+	s.OnAudioStream = func(samples []float32) { streamed += len(samples) }
+
+	mic := []float32{0.1, -0.2, 0.3}
+	s.writeMicOnly(writer, buffer, mic)
+
+	want := []float32{0.1, 0, -0.2, 0, 0.3, 0}
+	if len(writer.samples) != len(want) {
+		t.Fatalf("written %v, want %v", writer.samples, want)
+	}
+	for i := range want {
+		if writer.samples[i] != want[i] {
+			t.Fatalf("written %v, want %v", writer.samples, want)
+		}
+	}
+	if buffer.TotalSamples() != int64(len(mic)) || streamed != len(mic) {
+		t.Errorf("chunk buffer got %d samples, stream %d, want %d", buffer.TotalSamples(), streamed, len(mic))
+	}
+}
+
+func TestConvertMicSegmentsWithDiarization(t *testing.T) {
+	t.Cleanup(func() { session.SetSpeakerLabelScheme(session.DefaultSpeakerLabelScheme()) })
+	// Префикс собеседников совпадает с прежним именем людей у микрофона
+	if err := session.SetSpeakerLabelScheme(session.SpeakerLabelScheme{Prefix: "Участник", Base: 1, Self: "Вы"}); err != nil {
+		t.Fatal(err)
+	}
+
+	segs := convertMicSegmentsWithDiarization([]ai.TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет", Speaker: "Speaker 0"},
+		{Start: 1000, End: 2000, Text: "да", Speaker: "Speaker 1", Overlap: true, OverlapSpeaker: "Speaker 0"},
+		{Start: 2000, End: 3000, Text: "ага"}, // Без диаризации
+	}, 60000)
+
+	want := []string{"Вы 1", "Вы 2", "Вы"}
+	for i, seg := range segs {
+		if seg.Speaker != want[i] {
+			t.Errorf("segment %d speaker = %q, want %q", i, seg.Speaker, want[i])
+		}
+		if seg.Speaker == session.SpeakerLabel(i) {
+			t.Errorf("mic speaker %q collides with SYS speaker label", seg.Speaker)
+		}
+	}
+	if segs[1].OverlapSpeaker != "Вы 1" || segs[0].Start != 60000 {
+		t.Errorf("segment = %+v", segs[1])
+	}
+}
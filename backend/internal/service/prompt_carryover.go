@@ -0,0 +1,52 @@
+package service
+
+import (
+	"aiwisper/session"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultPromptCarryOverChars длина контекста предыдущего чанка по умолчанию.
+// Initial prompt Whisper ограничен ~224 токенами, длинный контекст повышает риск зацикливания
+const DefaultPromptCarryOverChars = 200
+
+// SetPromptCarryOver включает передачу конца текста предыдущего чанка как контекста распознавания.
+// maxChars <= 0 - длина по умолчанию
+func (s *TranscriptionService) SetPromptCarryOver(enabled bool, maxChars int) {
+	if maxChars <= 0 {
+		maxChars = DefaultPromptCarryOverChars
+	}
+	s.PromptCarryOver = enabled
+	s.PromptCarryOverChars = maxChars
+	log.Printf("Prompt carry-over: enabled=%v, maxChars=%d", enabled, maxChars)
+}
+
+// previousChunkPrompt возвращает конец текста того же канала в предыдущем чанке сессии.
+// Пусто, если контекст выключен или предыдущий чанк ещё не распознан
+func (s *TranscriptionService) previousChunkPrompt(chunk *session.Chunk, channel string) string {
+	if !s.PromptCarryOver || chunk.Index == 0 {
+		return ""
+	}
+	text := s.SessionMgr.PreviousChunkText(chunk.SessionID, chunk.Index, channel)
+	return promptTail(text, s.PromptCarryOverChars)
+}
+
+// promptTail обрезает текст до последних maxChars символов, не разрывая слова
+func promptTail(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	runes := []rune(text)
+	start := len(runes) - maxChars
+	tail := string(runes[start:])
+	// Отбрасываем первое слово, если оно обрезано посередине
+	if !unicode.IsSpace(runes[start-1]) {
+		if i := strings.IndexFunc(tail, unicode.IsSpace); i >= 0 {
+			tail = tail[i:]
+		}
+	}
+	return strings.TrimSpace(tail)
+}
@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestPromptTail(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxChars int
+		want     string
+	}{
+		{"  короткий текст ", 50, "короткий текст"},
+		{"первое второе третье", 0, "первое второе третье"},
+		{"первое второе третье", 12, "третье"},
+		{"первое второе третье", 13, "второе третье"},
+		{"одно_длинное_слово", 5, "слово"},
+	}
+	for _, tt := range tests {
+		if got := promptTail(tt.text, tt.maxChars); got != tt.want {
+			t.Errorf("promptTail(%q, %d) = %q, want %q", tt.text, tt.maxChars, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/session"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// RetranscribeRange повторно распознаёт произвольный диапазон [startMs, endMs] из full.mp3
+// и заменяет пересекающиеся сегменты сессии новыми. Диапазон расширяется до границ
+// затронутых сегментов, чтобы не потерять их текст. Возвращает фактический диапазон
+func (s *TranscriptionService) RetranscribeRange(sessionID string, startMs, endMs int64) (int64, int64, error) {
+	if s.EngineMgr == nil {
+		return 0, 0, fmt.Errorf("transcription engine not available")
+	}
+	if startMs < 0 || endMs <= startMs {
+		return 0, 0, fmt.Errorf("invalid range: start=%d end=%d", startMs, endMs)
+	}
+
+	sess, err := s.SessionMgr.GetSession(sessionID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(sess.Chunks) == 0 {
+		return 0, 0, fmt.Errorf("session has no chunks")
+	}
+	releaseBusy, err := s.SessionMgr.TryMarkSessionBusy(sessionID, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer releaseBusy()
+
+	release, err := s.acquireTranscribeSlot(context.Background())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer release()
+
+	startMs, endMs = session.ExpandRangeToSegments(sess.Chunks, startMs, endMs)
+	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	rangeStart := time.Now()
+
+	log.Printf("RetranscribeRange: session=%s, range=%d-%dms", sessionID, startMs, endMs)
+
+	var micSegs, sysSegs []session.TranscriptSegment
+
+	micSamples, sysSamples, stereoErr := session.ExtractSegmentStereoGo(mp3Path, startMs, endMs, 16000)
+	if stereoErr == nil && (len(micSamples) > 0 || len(sysSamples) > 0) && !areChannelsSimilar(micSamples, sysSamples) {
+		// Стерео: MIC и SYS распознаются раздельно, спикеры берутся из заменяемых сегментов
+		micSamples, sysSamples = s.orientStereoChannels(sess, micSamples, sysSamples, 16000)
+		micSamples = session.FilterChannelForTranscription(micSamples, 16000)
+		sysSamples = session.FilterChannelForTranscription(sysSamples, 16000)
+		micSamples = s.normalizeChannelLoudness(micSamples, "mic")
+		sysSamples = s.normalizeChannelLoudness(sysSamples, "sys")
+
+		micAI, err := s.transcribeRangeChannel(micSamples, "mic")
+		if err != nil {
+			return 0, 0, err
+		}
+		sysAI, err := s.transcribeRangeChannel(sysSamples, "sys")
+		if err != nil {
+			return 0, 0, err
+		}
+		micSegs = convertMicSegmentsWithDiarization(micAI, startMs)
+		sysSegs = convertSysSegmentsWithDiarization(sysAI, startMs)
+		micSegs, sysSegs, _ = dedupeCrosstalk(micSegs, sysSegs, s.CrosstalkDedup)
+	} else {
+		samples, err := session.ExtractSegmentGo(mp3Path, startMs, endMs, session.WhisperSampleRate)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to extract range: %w", err)
+		}
+		monoAI, err := s.transcribeRangeChannel(samples, "mono")
+		if err != nil {
+			return 0, 0, err
+		}
+		micSegs = convertPipelineSegments(monoAI, startMs)
+	}
+
+	if _, err := s.SessionMgr.ReplaceSegmentsInRange(sessionID, startMs, endMs, micSegs, sysSegs); err != nil {
+		return 0, 0, err
+	}
+
+	log.Printf("RetranscribeRange complete: session=%s, range=%d-%dms, mic=%d, sys=%d segments, took %v",
+		sessionID, startMs, endMs, len(micSegs), len(sysSegs), time.Since(rangeStart))
+	return startMs, endMs, nil
+}
+
+// transcribeRangeChannel распознаёт один канал диапазона (с гибридным режимом, если включён)
+func (s *TranscriptionService) transcribeRangeChannel(samples []float32, channel string) ([]ai.TranscriptSegment, error) {
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	// Тишину не распознаём, чтобы не получить галлюцинации движка
+	vadConfig := session.ChannelVADConfig{Method: s.getEffectiveVADMethod()}
+	if channel == "mic" || channel == "sys" {
+		vadConfig = s.channelVADConfig(channel)
+	}
+	regions := session.DetectSpeechRegionsWithChannelConfig(samples, 16000, vadConfig)
+	if regions = s.dropSilentRegions("range", channel, samples, regions, 16000); len(regions) == 0 {
+		log.Printf("RetranscribeRange: no speech in %s channel, skipping", channel)
+		return nil, nil
+	}
+	segments, err := s.transcribeWithTimeout(context.Background(), "range", channel, func() ([]ai.TranscriptSegment, error) {
+		return s.transcribeWithHybrid(samples)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s transcription failed: %w", channel, err)
+	}
+	return segments, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,11 +22,18 @@ type RecordingService struct {
 
 	// State
 	currentSession *session.Session
-	mp3Writer      *session.MP3Writer
+	mp3Writer      session.AudioWriter
 	chunkBuffer    *session.ChunkBuffer
 	stopChan       chan struct{}
+	rawDump        *audio.RawCaptureDump
+	voiceIsolation bool                 // Захват через ScreenCaptureKit (для перезапуска после паузы)
+	voiceMixing    audio.MixingStrategy // Стратегия микширования каналов Voice Isolation
+	paused         bool
 	mu             sync.Mutex
 
+	// Лимит дампа сырого потока захвата в байтах (0 - дамп запрещён)
+	RawCaptureMaxBytes int64
+
 	// Callbacks
 	OnAudioLevel  AudioLevelCallback
 	OnAudioStream AudioStreamCallback // Для streaming transcription
@@ -38,6 +46,30 @@ func NewRecordingService(sessMgr *session.Manager, capture *audio.Capture) *Reco
 	}
 }
 
+// SetRawCaptureLimit разрешает сессиям с RecordRawCapture сохранять сырой поток захвата
+// и ограничивает размер дампа (0 - запретить)
+func (s *RecordingService) SetRawCaptureLimit(maxBytes int64) {
+	s.RawCaptureMaxBytes = maxBytes
+	if maxBytes > 0 {
+		log.Printf("Raw capture dump allowed, limit %d MB", maxBytes>>20)
+	}
+}
+
+// resolveCaptureApp возвращает приложение для захвата системного звука (пусто - весь звук).
+// Захват отдельного приложения поддерживает только ScreenCaptureKit: если он недоступен,
+// записывается весь системный звук
+func resolveCaptureApp(requested string, screenCaptureKitAvailable bool) string {
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		return ""
+	}
+	if !screenCaptureKitAvailable {
+		log.Printf("Per-app capture of %s requires ScreenCaptureKit, falling back to full system audio", requested)
+		return ""
+	}
+	return requested
+}
+
 func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel float32, voiceIsolation bool) (*session.Session, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -45,11 +77,26 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 	if s.currentSession != nil {
 		return nil, fmt.Errorf("session already active")
 	}
+	voiceMixing, err := audio.ParseMixingStrategy(config.VoiceMixing)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.ValidateChunkDurationMs(config.ChunkDurationMs); err != nil {
+		return nil, err
+	}
 
 	// 1. Clear buffers
 	s.Capture.ClearBuffers()
 	log.Println("Audio buffers cleared for new session")
 
+	// Диктовка: системный звук не захватывается, SYS канал записи остаётся тишиной
+	if config.MicOnly {
+		config.CaptureSystem = false
+		config.CaptureApp = ""
+		config.DiarizeMic = false
+		voiceIsolation = false
+	}
+
 	// 2. Create session
 	sess, err := s.SessionMgr.CreateSession(config)
 	if err != nil {
@@ -58,7 +105,7 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 
 	// 3. Create MP3 Writer
 	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
-	mp3Writer, err := session.NewMP3Writer(mp3Path, session.SampleRate, 2, "128k")
+	mp3Writer, err := session.NewRecordingMP3Writer(mp3Path, session.SampleRate, 2)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +113,10 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 	// 4. Create Chunk Buffer
 	// Для stereo режима (captureSystem=true) ИЛИ если отключен VAD используем фиксированные интервалы
 	var vadConfig session.VADConfig
-	if config.CaptureSystem || config.VADMode == session.VADModeOff {
+	if config.MicOnly && config.VADMode != session.VADModeOff {
+		vadConfig = session.DictationVADConfig()
+		log.Println("Mic-only session: short VAD chunks on the microphone")
+	} else if config.CaptureSystem || config.VADMode == session.VADModeOff {
 		vadConfig = session.FixedIntervalConfig()
 		if config.VADMode == session.VADModeOff {
 			log.Println("VAD disabled by user setting (fixed interval chunking)")
@@ -81,6 +131,10 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 	if vadConfig.VADMode == "" {
 		vadConfig.VADMode = session.VADModeAuto
 	}
+	if config.ChunkDurationMs > 0 {
+		vadConfig = vadConfig.WithChunkDuration(time.Duration(config.ChunkDurationMs) * time.Millisecond)
+		log.Printf("Chunk duration set to %d ms", config.ChunkDurationMs)
+	}
 	chunkBuffer := session.NewChunkBuffer(vadConfig, session.SampleRate)
 
 	s.currentSession = sess
@@ -98,6 +152,7 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 		if s.mp3Writer != nil {
 			s.mp3Writer.Close()
 		}
+		s.closeRawDump()
 		s.currentSession = nil
 		s.mp3Writer = nil
 		s.chunkBuffer = nil
@@ -115,10 +170,16 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 	coreAudioAvailable := audio.CoreAudioTapAvailable()
 	preferCoreAudio := config.CaptureSystem && config.UseNative && coreAudioAvailable && !voiceIsolation
 
+	captureApp := resolveCaptureApp(config.CaptureApp, audio.ScreenCaptureKitAvailable())
+	if captureApp != "" {
+		preferCoreAudio = false
+	}
+	s.Capture.SetCaptureApp(captureApp)
+
 	log.Printf("Recording config: voiceIsolation=%v, voiceIsolationAvailable=%v, useVoiceIsolation=%v",
 		voiceIsolation, voiceIsolationAvailable, useVoiceIsolation)
-	log.Printf("Recording config: captureSystem=%v, useNative=%v, coreAudioAvailable=%v, preferCoreAudio=%v",
-		config.CaptureSystem, config.UseNative, coreAudioAvailable, preferCoreAudio)
+	log.Printf("Recording config: captureSystem=%v, useNative=%v, coreAudioAvailable=%v, preferCoreAudio=%v, captureApp=%q",
+		config.CaptureSystem, config.UseNative, coreAudioAvailable, preferCoreAudio, captureApp)
 
 	if voiceIsolation && !voiceIsolationAvailable {
 		log.Println("Voice Isolation requested but not available on this system")
@@ -162,10 +223,23 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 		s.Capture.EnableSystemCapture(false)
 	}
 
+	// Дамп сырого потока кадров (screencapture-audio/coreaudio-tap) для воспроизведения багов
+	s.Capture.SetRawCaptureDump(nil)
+	if config.RecordRawCapture {
+		if s.RawCaptureMaxBytes <= 0 {
+			log.Println("Raw capture dump requested but disabled (start backend with -raw-capture-max-mb)")
+		} else if dump, err := audio.CreateRawCaptureDump(filepath.Join(sess.DataDir, "raw_capture.bin"), s.RawCaptureMaxBytes); err != nil {
+			log.Printf("Failed to enable raw capture dump: %v", err)
+		} else {
+			s.rawDump = dump
+			s.Capture.SetRawCaptureDump(dump)
+		}
+	}
+
 	// Стартуем выбранный метод захвата
 	if useVoiceIsolation {
 		log.Println("Voice Isolation: Using ScreenCaptureKit for mic+system (macOS 15+)")
-		if err := s.Capture.StartScreenCaptureKitAudioWithMode("both"); err != nil {
+		if err := s.Capture.StartScreenCaptureKitAudioWithMode("both", voiceMixing); err != nil {
 			log.Printf("Failed to start Voice Isolation mode: %v, falling back to standard capture", err)
 			useVoiceIsolation = false
 		}
@@ -177,10 +251,14 @@ func (s *RecordingService) StartSession(config session.SessionConfig, echoCancel
 		}
 	}
 
+	s.voiceIsolation = useVoiceIsolation
+	s.voiceMixing = voiceMixing
+	s.paused = false
+
 	// 6. Start Goroutines
 	// isStereo = true когда захватываем системный звук (даёт разделение "Вы" / "Собеседник")
 	isStereo := config.CaptureSystem
-	go s.processAudio(sess, echoCancel, useVoiceIsolation)
+	go s.processAudio(sess, echoCancel, useVoiceIsolation, config.MicOnly)
 	go s.processChunks(sess, isStereo)
 
 	return sess, nil
@@ -202,7 +280,8 @@ func (s *RecordingService) StopSession() (*session.Session, error) {
 
 	// Close stop channel to signal goroutines
 	close(s.stopChan)
-	s.Capture.Stop()
+	stopCapture(s.Capture, s.voiceIsolation)
+	s.closeRawDump()
 
 	s.mu.Unlock() // Unlock for processing
 
@@ -241,30 +320,129 @@ func (s *RecordingService) StopSession() (*session.Session, error) {
 	s.currentSession = nil
 	s.mp3Writer = nil
 	s.chunkBuffer = nil
+	s.paused = false
 	s.mu.Unlock()
 
 	return finalSess, nil
 }
 
+// PauseSession приостанавливает запись: захват останавливается, накопленные чанки не финализируются.
+// MP3 пишется по семплам, поэтому пауза не превращается в тишину - после возобновления
+// запись продолжается с того же места, и время чанков не включает паузу
+func (s *RecordingService) PauseSession() (*session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.currentSession == nil {
+		return nil, fmt.Errorf("no active session")
+	}
+	if s.paused {
+		return nil, fmt.Errorf("session already paused")
+	}
+
+	sess, err := s.SessionMgr.PauseSession()
+	if err != nil {
+		return nil, err
+	}
+	s.paused = true
+	stopCapture(s.Capture, s.voiceIsolation)
+	if s.chunkBuffer != nil {
+		s.chunkBuffer.Pause()
+	}
+	log.Printf("Session paused: %s", sess.ID)
+	return sess, nil
+}
+
+// ResumeSession возобновляет приостановленную запись тем же методом захвата
+func (s *RecordingService) ResumeSession() (*session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.currentSession == nil {
+		return nil, fmt.Errorf("no active session")
+	}
+	if !s.paused {
+		return nil, fmt.Errorf("session is not paused")
+	}
+
+	// Данные, пришедшие до остановки захвата, не должны попасть после паузы
+	s.Capture.ClearBuffers()
+	if err := startCapture(s.Capture, s.voiceIsolation, s.voiceMixing); err != nil {
+		return nil, err
+	}
+
+	sess, err := s.SessionMgr.ResumeSession()
+	if err != nil {
+		stopCapture(s.Capture, s.voiceIsolation)
+		return nil, err
+	}
+	if s.chunkBuffer != nil {
+		s.chunkBuffer.Resume()
+	}
+	s.paused = false
+	log.Printf("Session resumed: %s (paused %v in total)", sess.ID, sess.PausedDuration)
+	return sess, nil
+}
+
+// recordingCapture методы захвата, которыми запись останавливается и запускается заново
+type recordingCapture interface {
+	Start(deviceID int) error
+	Stop() error
+	StartScreenCaptureKitAudioWithMode(mode string, mixing audio.MixingStrategy) error
+	StopScreenCaptureKitAudio()
+}
+
+// stopCapture останавливает захват записи. Voice Isolation запускает ScreenCaptureKit
+// напрямую, минуя Capture.Start, поэтому Capture.Stop его не останавливает
+func stopCapture(c recordingCapture, voiceIsolation bool) {
+	if voiceIsolation {
+		c.StopScreenCaptureKitAudio()
+		return
+	}
+	c.Stop()
+}
+
+// startCapture запускает захват тем же методом, что и при старте записи. Ошибку Voice Isolation
+// возвращает: обычный захват вместо него дал бы запись другим методом или второй источник звука
+func startCapture(c recordingCapture, voiceIsolation bool, mixing audio.MixingStrategy) error {
+	if voiceIsolation {
+		if err := c.StartScreenCaptureKitAudioWithMode("both", mixing); err != nil {
+			return fmt.Errorf("failed to resume Voice Isolation capture: %w", err)
+		}
+		return nil
+	}
+	if err := c.Start(0); err != nil {
+		return fmt.Errorf("failed to resume audio capture: %w", err)
+	}
+	return nil
+}
+
+// closeRawDump закрывает дамп сырого потока захвата. Вызывается под s.mu
+func (s *RecordingService) closeRawDump() {
+	if s.rawDump == nil {
+		return
+	}
+	s.Capture.SetRawCaptureDump(nil)
+	if err := s.rawDump.Close(); err != nil {
+		log.Printf("Failed to close raw capture dump: %v", err)
+	}
+	s.rawDump = nil
+}
+
 func (s *RecordingService) GetCurrentSession() *session.Session {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.currentSession
 }
 
-func (s *RecordingService) processAudio(sess *session.Session, echoCancel float32, useVoiceIsolation bool) {
+func (s *RecordingService) processAudio(sess *session.Session, echoCancel float32, useVoiceIsolation, micOnly bool) {
 	var micLevel, systemLevel float64
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
-	var micBuffer []float32
-	var systemBuffer []float32
-	consume := func(buf []float32, n int) []float32 {
-		if n >= len(buf) {
-			return buf[:0]
-		}
-		return buf[n:]
-	}
+	// Стратегия выравнивания каналов: для Voice Isolation - выбранная в сессии, иначе paired
+	mixer := audio.NewStereoMixer(s.Capture.MixingStrategy())
+	log.Printf("Channel mixing strategy: %s", mixer.Strategy())
 
 	for {
 		select {
@@ -272,6 +450,11 @@ func (s *RecordingService) processAudio(sess *session.Session, echoCancel float3
 			return
 
 		case <-ticker.C:
+			s.mu.Lock()
+			if s.paused {
+				micLevel, systemLevel = 0, 0
+			}
+			s.mu.Unlock()
 			if s.OnAudioLevel != nil {
 				s.OnAudioLevel(micLevel, systemLevel)
 			}
@@ -287,10 +470,8 @@ func (s *RecordingService) processAudio(sess *session.Session, echoCancel float3
 			rms := session.CalculateRMS(samples)
 			if channel == audio.ChannelMicrophone {
 				micLevel = rms
-				micBuffer = append(micBuffer, samples...)
 			} else {
 				systemLevel = rms
-				systemBuffer = append(systemBuffer, samples...)
 			}
 
 			s.mu.Lock()
@@ -301,22 +482,27 @@ func (s *RecordingService) processAudio(sess *session.Session, echoCancel float3
 				return
 			}
 
-			// Используем минимум из двух буферов (как в оригинальной версии 1.7.2)
-			// Это гарантирует что мы записываем только когда есть данные из обоих каналов
-			minLen := len(micBuffer)
-			if len(systemBuffer) < minLen {
-				minLen = len(systemBuffer)
+			// Пауза: хвосты каналов отбрасываются, чтобы после возобновления MIC и SYS не разъехались
+			if s.paused {
+				mixer.Reset()
+				s.mu.Unlock()
+				continue
 			}
 
-			if minLen > 0 {
-				// Interleave mic и sys в стерео
-				stereo := make([]float32, minLen*2)
-				for i := 0; i < minLen; i++ {
-					stereo[i*2] = micBuffer[i]
-					stereo[i*2+1] = systemBuffer[i]
+			// Диктовка: ждать SYS канал не нужно, микрофон пишется сразу с тишиной справа
+			if micOnly {
+				if channel == audio.ChannelMicrophone {
+					s.writeMicOnly(writer, chunkBuf, samples)
 				}
+				s.mu.Unlock()
+				continue
+			}
 
-				if err := writer.Write(stereo); err != nil {
+			// Пишем только выровненные пары семплов обоих каналов (см. audio.MixingStrategy)
+			mixer.Add(channel, samples)
+			micSamples, sysSamples := mixer.Next()
+			if len(micSamples) > 0 {
+				if err := writer.Write(audio.Interleave(micSamples, sysSamples)); err != nil {
 					log.Printf("Failed to write audio: %v", err)
 				}
 
@@ -324,22 +510,40 @@ func (s *RecordingService) processAudio(sess *session.Session, echoCancel float3
 				// ВСЕГДА используем ProcessStereo когда захватываем системный звук
 				// Это даёт разделение "Вы" / "Собеседник" при транскрипции
 				if chunkBuf != nil {
-					chunkBuf.ProcessStereo(micBuffer[:minLen], systemBuffer[:minLen])
+					chunkBuf.ProcessStereo(micSamples, sysSamples)
 				}
 
 				// Streaming transcription (только микрофон, моно)
 				if s.OnAudioStream != nil {
-					s.OnAudioStream(micBuffer[:minLen])
+					s.OnAudioStream(micSamples)
 				}
-
-				micBuffer = consume(micBuffer, minLen)
-				systemBuffer = consume(systemBuffer, minLen)
 			}
 			s.mu.Unlock()
 		}
 	}
 }
 
+// writeMicOnly записывает микрофон сессии MicOnly: в MP3 - стерео с тишиной в SYS канале,
+// в буфер чанков - моно (VAD по микрофону). Вызывается под s.mu
+func (s *RecordingService) writeMicOnly(writer session.AudioWriter, chunkBuf *session.ChunkBuffer, mic []float32) {
+	if len(mic) == 0 {
+		return
+	}
+	stereo := make([]float32, len(mic)*2)
+	for i, sample := range mic {
+		stereo[i*2] = sample
+	}
+	if err := writer.Write(stereo); err != nil {
+		log.Printf("Failed to write audio: %v", err)
+	}
+	if chunkBuf != nil {
+		chunkBuf.Process(mic)
+	}
+	if s.OnAudioStream != nil {
+		s.OnAudioStream(mic)
+	}
+}
+
 func (s *RecordingService) processChunks(sess *session.Session, isStereo bool) {
 	// Need to access chunkBuffer safely.
 	// But chunkBuffer.Output() returns a channel. We can just read from it.
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"aiwisper/audio"
+	"errors"
+	"testing"
+)
+
+func TestResolveCaptureApp(t *testing.T) {
+	tests := []struct {
+		requested string
+		available bool
+		want      string
+	}{
+		{"", true, ""},
+		{" us.zoom.xos ", true, "us.zoom.xos"},
+		{"us.zoom.xos", false, ""}, // Без ScreenCaptureKit - весь системный звук
+	}
+	for _, tt := range tests {
+		if got := resolveCaptureApp(tt.requested, tt.available); got != tt.want {
+			t.Errorf("resolveCaptureApp(%q, %v) = %q, want %q", tt.requested, tt.available, got, tt.want)
+		}
+	}
+}
+
+// fakeCapture повторяет поведение audio.Capture: Stop останавливает только то, что запущено Start,
+// ScreenCaptureKit может работать лишь в одном экземпляре
+type fakeCapture struct {
+	standard, screenCapture bool
+	screenCaptureErr        error
+}
+
+func (c *fakeCapture) Start(int) error {
+	if c.standard {
+		return errors.New("already running")
+	}
+	c.standard = true
+	return nil
+}
+
+func (c *fakeCapture) Stop() error {
+	c.standard = false
+	return nil
+}
+
+func (c *fakeCapture) StartScreenCaptureKitAudioWithMode(string, audio.MixingStrategy) error {
+	if c.screenCapture {
+		return errors.New("audio capture already running")
+	}
+	if c.screenCaptureErr != nil {
+		return c.screenCaptureErr
+	}
+	c.screenCapture = true
+	return nil
+}
+
+func (c *fakeCapture) StopScreenCaptureKitAudio() {
+	c.screenCapture = false
+}
+
+func TestPauseResumeCapture(t *testing.T) {
+	// Voice Isolation: пауза останавливает ScreenCaptureKit, возобновление запускает только его
+	c := &fakeCapture{screenCapture: true}
+	stopCapture(c, true)
+	if c.screenCapture {
+		t.Fatal("Voice Isolation capture still running after pause")
+	}
+	if err := startCapture(c, true, audio.MixingPaired); err != nil {
+		t.Fatal(err)
+	}
+	if !c.screenCapture || c.standard {
+		t.Errorf("after resume: screenCapture=%v standard=%v, want only Voice Isolation", c.screenCapture, c.standard)
+	}
+
+	// Ошибка Voice Isolation не подменяется обычным захватом
+	stopCapture(c, true)
+	c.screenCaptureErr = errors.New("permission denied")
+	if err := startCapture(c, true, audio.MixingPaired); err == nil {
+		t.Error("Voice Isolation resume error not returned")
+	}
+	if c.standard || c.screenCapture {
+		t.Errorf("failed resume started capture: screenCapture=%v standard=%v", c.screenCapture, c.standard)
+	}
+
+	// Обычный захват
+	c = &fakeCapture{standard: true}
+	stopCapture(c, false)
+	if err := startCapture(c, false, audio.MixingPaired); err != nil || !c.standard || c.screenCapture {
+		t.Errorf("standard resume: err=%v, %+v", err, c)
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// RediarizeChunk заново определяет спикеров SYS канала чанка без распознавания речи:
+// текст и тайминги слов берутся из сохранённых сегментов, на аудио SYS канала запускается только
+// диаризация, спикеры сопоставляются с профилями сессии и применяются к сегментам заново.
+// Возвращает false, если чанк пропущен (нет сегментов SYS канала или диаризация не нашла речь)
+func (s *TranscriptionService) RediarizeChunk(chunk *session.Chunk) (bool, error) {
+	if s.Pipeline == nil || !s.Pipeline.IsDiarizationEnabled() {
+		return false, fmt.Errorf("diarization is not enabled")
+	}
+	if len(chunk.SysSegments) == 0 {
+		log.Printf("RediarizeChunk: chunk %d has no SYS segments, skipping", chunk.Index)
+		return false, nil
+	}
+
+	sess, err := s.SessionMgr.GetSession(chunk.SessionID)
+	if err != nil {
+		return false, err
+	}
+
+	// Аудио извлекается с тем же перекрытием, что и при распознавании: диаризация видит тот же фрагмент
+	extractStart := s.chunkExtractStart(chunk)
+	micSamples, sysSamples, err := session.ExtractSegmentStereoGo(filepath.Join(sess.DataDir, "full.mp3"), extractStart, chunk.EndMs, 16000)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract stereo segment: %w", err)
+	}
+	_, sysSamples = s.orientStereoChannels(sess, micSamples, sysSamples, 16000)
+	sysSamples = session.FilterChannelForTranscription(sysSamples, 16000)
+	sysSamples = s.normalizeChannelLoudness(sysSamples, "sys")
+
+	diarResult, err := s.Pipeline.DiarizeOnly(sysSamples)
+	if err != nil {
+		return false, fmt.Errorf("diarization failed: %w", err)
+	}
+	if len(diarResult.SpeakerSegments) == 0 {
+		log.Printf("RediarizeChunk: no speakers found in chunk %d, keeping previous speakers", chunk.Index)
+		return false, nil
+	}
+
+	if len(diarResult.SpeakerEmbeddings) > 0 {
+		if mapping := s.matchSpeakersWithSession(chunk.SessionID, chunk.Index, diarResult.SpeakerEmbeddings); len(mapping) > 0 {
+			diarResult.SpeakerSegments = s.remapSpeakerSegments(diarResult.SpeakerSegments, mapping)
+		}
+	}
+
+	minRatio, minSegment, minOverlap := s.speakerConsolidation()
+	segments := applySpeakersToTranscriptSegments(sysSegmentsForDiarization(chunk.SysSegments, extractStart),
+		diarResult.SpeakerSegments, minRatio, minSegment, minOverlap)
+	sysSegments := convertSysSegmentsWithDiarization(segments, extractStart)
+
+	log.Printf("RediarizeChunk: chunk %d re-assigned %d SYS segments (%d unique speakers)",
+		chunk.Index, len(sysSegments), diarResult.NumSpeakers)
+	return true, s.SessionMgr.UpdateChunkSysSpeakers(chunk.SessionID, chunk.ID, sysSegments)
+}
+
+// sysSegmentsForDiarization переводит сохранённые сегменты в формат движка относительно offsetMs.
+// Прежние спикеры сбрасываются: сегмент без спикера из диаризации станет "Собеседник"
+func sysSegmentsForDiarization(segments []session.TranscriptSegment, offsetMs int64) []ai.TranscriptSegment {
+	result := make([]ai.TranscriptSegment, len(segments))
+	for i, seg := range segments {
+		result[i] = ai.TranscriptSegment{
+			Start: seg.Start - offsetMs,
+			End:   seg.End - offsetMs,
+			Text:  seg.Text,
+		}
+		if len(seg.Words) > 0 {
+			result[i].Words = make([]ai.TranscriptWord, len(seg.Words))
+			for j, w := range seg.Words {
+				result[i].Words[j] = ai.TranscriptWord{Start: w.Start - offsetMs, End: w.End - offsetMs, Text: w.Text, P: w.P}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"aiwisper/session"
+	"testing"
+)
+
+func TestSysSegmentsForDiarization(t *testing.T) {
+	segments := []session.TranscriptSegment{{
+		Start: 31000, End: 32500, Text: "добрый день", Speaker: session.SpeakerLabel(2),
+		Words: []session.TranscriptWord{
+			{Start: 31000, End: 31600, Text: "добрый", P: 0.9, Speaker: session.SpeakerLabel(2)},
+			{Start: 31700, End: 32500, Text: "день", P: 0.8, Speaker: session.SpeakerLabel(2)},
+		},
+	}}
+
+	got := sysSegmentsForDiarization(segments, 30000)
+	if len(got) != 1 || got[0].Start != 1000 || got[0].End != 2500 || got[0].Speaker != "" {
+		t.Fatalf("segment = %+v", got)
+	}
+	if w := got[0].Words[1]; w.Start != 1700 || w.End != 2500 || w.P != 0.8 {
+		t.Errorf("word = %+v", w)
+	}
+
+	// Без диаризации сегмент получает метку собеседника по умолчанию, тайминги возвращаются на место
+	back := convertSysSegmentsWithDiarization(got, 30000)
+	if back[0].Start != 31000 || back[0].Speaker != session.PeerSpeakerLabel() || back[0].Words[0].Start != 31000 {
+		t.Errorf("round trip = %+v", back[0])
+	}
+}
+
+func TestRediarizeChunkRequiresDiarization(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	chunk := &session.Chunk{SysSegments: []session.TranscriptSegment{{Text: "текст"}}}
+	if _, err := s.RediarizeChunk(chunk); err == nil {
+		t.Error("expected error without diarization")
+	}
+}
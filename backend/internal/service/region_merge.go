@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"log"
+)
+
+// maxRegionMergeMs верхняя граница параметров склейки: больше длины типичного чанка смысла нет
+const maxRegionMergeMs = 30000
+
+// RegionMergeConfig склейка коротких VAD регионов в per-region режиме (GigaAM).
+// Слишком мелкие регионы теряют контекст, слишком крупная склейка размывает смену спикеров
+type RegionMergeConfig struct {
+	MinRegionMs int64 // Регион короче склеивается с соседом (0 - не склеивать)
+	MaxGapMs    int64 // Максимальная пауза между склеиваемыми регионами
+}
+
+// DefaultRegionMergeConfig возвращает настройки по умолчанию (2с / 3с)
+func DefaultRegionMergeConfig() RegionMergeConfig {
+	return RegionMergeConfig{MinRegionMs: 2000, MaxGapMs: 3000}
+}
+
+// WithOverrides возвращает настройки с заменёнными заданными параметрами (nil - оставить текущее значение)
+func (c RegionMergeConfig) WithOverrides(minRegionMs, maxGapMs *int64) RegionMergeConfig {
+	if minRegionMs != nil {
+		c.MinRegionMs = *minRegionMs
+	}
+	if maxGapMs != nil {
+		c.MaxGapMs = *maxGapMs
+	}
+	return c
+}
+
+// Validate проверяет диапазоны параметров склейки
+func (c RegionMergeConfig) Validate() error {
+	if c.MinRegionMs < 0 || c.MinRegionMs > maxRegionMergeMs {
+		return fmt.Errorf("region min duration must be in [0, %d] ms, got %d", maxRegionMergeMs, c.MinRegionMs)
+	}
+	if c.MaxGapMs < 0 || c.MaxGapMs > maxRegionMergeMs {
+		return fmt.Errorf("region max gap must be in [0, %d] ms, got %d", maxRegionMergeMs, c.MaxGapMs)
+	}
+	return nil
+}
+
+// SetRegionMergeConfig устанавливает параметры склейки коротких регионов.
+// Некорректные значения отклоняются, действующие настройки не меняются
+func (s *TranscriptionService) SetRegionMergeConfig(cfg RegionMergeConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s.RegionMerge = cfg
+	log.Printf("Per-region merge: minRegion=%dms, maxGap=%dms", cfg.MinRegionMs, cfg.MaxGapMs)
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/session"
+)
+
+func TestMergeShortRegionsConfig(t *testing.T) {
+	// 1с речи, пауза 1.5с, 4с речи, пауза 4с, 1с речи
+	regions := []session.SpeechRegion{
+		{StartMs: 0, EndMs: 1000},
+		{StartMs: 2500, EndMs: 6500},
+		{StartMs: 10500, EndMs: 11500},
+	}
+
+	tests := []struct {
+		cfg  RegionMergeConfig
+		want int
+	}{
+		{DefaultRegionMergeConfig(), 2},                           // Первый регион склеивается, последний - нет (пауза 4с > 3с)
+		{RegionMergeConfig{MinRegionMs: 2000, MaxGapMs: 5000}, 1}, // Длинная пауза допускает склейку всего
+		{RegionMergeConfig{MinRegionMs: 0, MaxGapMs: 5000}, 3},    // Склейка отключена
+	}
+	for _, tt := range tests {
+		if got := mergeShortRegions(regions, tt.cfg.MinRegionMs, tt.cfg.MaxGapMs); len(got) != tt.want {
+			t.Errorf("%+v: got %d regions, want %d: %+v", tt.cfg, len(got), tt.want, got)
+		}
+	}
+}
+
+func TestMergeShortRegionsBoundaries(t *testing.T) {
+	// Быстрый диалог: короткие реплики с короткими паузами, затем длинная реплика после паузы 1.2с
+	regions := []session.SpeechRegion{
+		{StartMs: 0, EndMs: 800},
+		{StartMs: 1100, EndMs: 1700},
+		{StartMs: 2000, EndMs: 5000},
+		{StartMs: 6200, EndMs: 9000},
+	}
+
+	tests := []struct {
+		name string
+		cfg  RegionMergeConfig
+		want []session.SpeechRegion
+	}{
+		{"defaults over-merge turns", DefaultRegionMergeConfig(),
+			[]session.SpeechRegion{{StartMs: 0, EndMs: 5000}, {StartMs: 6200, EndMs: 9000}}},
+		{"short gap keeps turns apart", RegionMergeConfig{MinRegionMs: 2000, MaxGapMs: 200}, regions},
+		{"fast dialogue", RegionMergeConfig{MinRegionMs: 700, MaxGapMs: 400},
+			[]session.SpeechRegion{{StartMs: 0, EndMs: 1700}, {StartMs: 2000, EndMs: 5000}, {StartMs: 6200, EndMs: 9000}}},
+		{"slow dictation", RegionMergeConfig{MinRegionMs: 4000, MaxGapMs: 1500},
+			[]session.SpeechRegion{{StartMs: 0, EndMs: 9000}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeShortRegions(regions, tt.cfg.MinRegionMs, tt.cfg.MaxGapMs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionMergeConfigWithOverrides(t *testing.T) {
+	zero, gap := int64(0), int64(700)
+	if got := DefaultRegionMergeConfig().WithOverrides(nil, nil); got != DefaultRegionMergeConfig() {
+		t.Errorf("no overrides: %+v", got)
+	}
+	if got := DefaultRegionMergeConfig().WithOverrides(&zero, nil); got != (RegionMergeConfig{MinRegionMs: 0, MaxGapMs: 3000}) {
+		t.Errorf("zero min region must disable merging, got %+v", got)
+	}
+	if got := DefaultRegionMergeConfig().WithOverrides(nil, &gap); got != (RegionMergeConfig{MinRegionMs: 2000, MaxGapMs: 700}) {
+		t.Errorf("gap override: %+v", got)
+	}
+}
+
+func TestSetRegionMergeConfig(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	if s.RegionMerge != DefaultRegionMergeConfig() {
+		t.Fatalf("default region merge = %+v", s.RegionMerge)
+	}
+
+	for _, bad := range []RegionMergeConfig{{MinRegionMs: -1, MaxGapMs: 3000}, {MinRegionMs: 2000, MaxGapMs: 60000}} {
+		if err := s.SetRegionMergeConfig(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+	if s.RegionMerge != DefaultRegionMergeConfig() {
+		t.Errorf("invalid config must not be applied, got %+v", s.RegionMerge)
+	}
+
+	want := RegionMergeConfig{MinRegionMs: 1000, MaxGapMs: 500}
+	if err := s.SetRegionMergeConfig(want); err != nil || s.RegionMerge != want {
+		t.Errorf("SetRegionMergeConfig(%+v): err=%v, got %+v", want, err, s.RegionMerge)
+	}
+}
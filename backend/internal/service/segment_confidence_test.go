@@ -0,0 +1,49 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/session"
+	"testing"
+)
+
+func TestConvertedSegmentsCarryConfidence(t *testing.T) {
+	aiSegs := []ai.TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет мир", Speaker: "Speaker 0",
+			Words: []ai.TranscriptWord{{Start: 0, End: 400, Text: "привет", P: 0.9}, {Start: 500, End: 1000, Text: "мир", P: 0.5}}},
+		{Start: 1000, End: 2000, Text: "без слов"},
+	}
+
+	converters := map[string]func() []session.TranscriptSegment{
+		"pipeline":    func() []session.TranscriptSegment { return convertPipelineSegments(aiSegs, 5000) },
+		"offset":      func() []session.TranscriptSegment { return convertSegmentsWithGlobalOffset(aiSegs, "mic", 5000) },
+		"sys diarize": func() []session.TranscriptSegment { return convertSysSegmentsWithDiarization(aiSegs, 5000) },
+	}
+	for name, convert := range converters {
+		segs := convert()
+		if got := segs[0].Confidence; got < 0.699 || got > 0.701 {
+			t.Errorf("%s: confidence = %v, want 0.7", name, got)
+		}
+		if got := segs[1].Confidence; got != session.NoConfidence {
+			t.Errorf("%s: confidence without words = %v, want NoConfidence", name, got)
+		}
+	}
+}
+
+func TestSentenceSplitRecomputesConfidence(t *testing.T) {
+	seg := session.TranscriptSegment{
+		Start: 0, End: 2000, Text: "Да. Нет.", Confidence: 0.6,
+		Words: []session.TranscriptWord{{Start: 0, End: 500, Text: "Да.", P: 0.9}, {Start: 1000, End: 2000, Text: "Нет.", P: 0.3}},
+	}
+	got := SplitSegmentsBySentence([]session.TranscriptSegment{seg})
+	if len(got) != 2 || got[0].Confidence != 0.9 || got[1].Confidence != 0.3 {
+		t.Errorf("sentences = %+v", got)
+	}
+
+	// Без слов предложения наследуют уверенность сегмента
+	seg.Words = nil
+	for _, sentence := range SplitSegmentsBySentence([]session.TranscriptSegment{seg}) {
+		if sentence.Confidence != 0.6 {
+			t.Errorf("sentence %q confidence = %v, want 0.6", sentence.Text, sentence.Confidence)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"aiwisper/session"
+	"strings"
+)
+
+// SplitSegmentsBySentence разбивает многофразовые сегменты на отдельные предложения
+// (по endsWithSentenceBoundary) для субтитров и экспорта. Спикер сохраняется.
+// Время предложения берётся из слов сегмента, если они совпадают с текстом,
+// иначе делится пропорционально длине текста
+func SplitSegmentsBySentence(segments []session.TranscriptSegment) []session.TranscriptSegment {
+	result := make([]session.TranscriptSegment, 0, len(segments))
+	for _, seg := range segments {
+		result = append(result, splitSegmentBySentence(seg)...)
+	}
+	return result
+}
+
+// splitSegmentBySentence разбивает один сегмент, сегмент из одного предложения возвращается как есть
+func splitSegmentBySentence(seg session.TranscriptSegment) []session.TranscriptSegment {
+	tokens := strings.Fields(seg.Text)
+
+	// Границы предложений: индексы токенов после последнего слова каждого предложения
+	var bounds []int
+	for i, token := range tokens {
+		if endsWithSentenceBoundary(token) || i == len(tokens)-1 {
+			bounds = append(bounds, i+1)
+		}
+	}
+	if len(bounds) < 2 {
+		return []session.TranscriptSegment{seg}
+	}
+
+	// Слова пригодны для таймингов, если соответствуют токенам текста один к одному
+	useWords := len(seg.Words) == len(tokens)
+	for i := 0; useWords && i < len(tokens); i++ {
+		useWords = sameWords(seg.Words[i].Text, tokens[i])
+	}
+
+	totalLen := len([]rune(seg.Text))
+	result := make([]session.TranscriptSegment, 0, len(bounds))
+	from, charsBefore := 0, 0
+	for _, to := range bounds {
+		text := strings.Join(tokens[from:to], " ")
+		sentence := session.TranscriptSegment{Text: text, Speaker: seg.Speaker, Confidence: seg.Confidence,
+			Overlap: seg.Overlap, OverlapSpeaker: seg.OverlapSpeaker}
+
+		if useWords {
+			sentence.Words = append([]session.TranscriptWord(nil), seg.Words[from:to]...)
+			sentence.Start = sentence.Words[0].Start
+			sentence.End = sentence.Words[len(sentence.Words)-1].End
+			sentence.Confidence = session.SegmentConfidence(sentence.Words)
+		} else {
+			duration := seg.End - seg.Start
+			charsAfter := min(charsBefore+len([]rune(text))+1, totalLen) // +1 на пробел между предложениями
+			sentence.Start = seg.Start + duration*int64(charsBefore)/int64(totalLen)
+			sentence.End = seg.Start + duration*int64(charsAfter)/int64(totalLen)
+			charsBefore = charsAfter
+		}
+
+		result = append(result, sentence)
+		from = to
+	}
+	if !useWords {
+		result[len(result)-1].End = seg.End // Без ошибок округления в конце
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"aiwisper/session"
+	"testing"
+)
+
+func TestSplitSegmentsBySentence(t *testing.T) {
+	withWords := session.TranscriptSegment{
+		Start: 1000, End: 5000, Speaker: "Собеседник 1", Text: "Привет. Как дела?",
+		Words: []session.TranscriptWord{
+			{Start: 1000, End: 1400, Text: "Привет."},
+			{Start: 2500, End: 3000, Text: "Как"},
+			{Start: 3000, End: 4800, Text: "дела?"},
+		},
+	}
+	// Текст изменён LLM - слова не совпадают, время делится пропорционально
+	withoutWords := session.TranscriptSegment{Start: 10000, End: 12000, Speaker: "mic", Text: "Да. Нет!"}
+	single := session.TranscriptSegment{Start: 20000, End: 21000, Speaker: "mic", Text: "одно предложение"}
+
+	got := SplitSegmentsBySentence([]session.TranscriptSegment{withWords, withoutWords, single})
+	want := []session.TranscriptSegment{
+		{Start: 1000, End: 1400, Speaker: "Собеседник 1", Text: "Привет."},
+		{Start: 2500, End: 4800, Speaker: "Собеседник 1", Text: "Как дела?"},
+		{Start: 10000, End: 11000, Speaker: "mic", Text: "Да."},
+		{Start: 11000, End: 12000, Speaker: "mic", Text: "Нет!"},
+		{Start: 20000, End: 21000, Speaker: "mic", Text: "одно предложение"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		if g.Start != want[i].Start || g.End != want[i].End || g.Text != want[i].Text || g.Speaker != want[i].Speaker {
+			t.Errorf("segment %d = %d-%d %q %q, want %d-%d %q %q",
+				i, g.Start, g.End, g.Speaker, g.Text, want[i].Start, want[i].End, want[i].Speaker, want[i].Text)
+		}
+	}
+	if len(got[1].Words) != 2 {
+		t.Errorf("second sentence words = %d, want 2", len(got[1].Words))
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"aiwisper/session"
+)
+
+// DefaultSilenceGuardDBFS уровень региона, ниже которого он не распознаётся:
+// практически цифровая тишина, которую VAD изредка пропускает
+const DefaultSilenceGuardDBFS = -60.0
+
+// SetSilenceGuard устанавливает порог энергии региона речи (dBFS): регионы тише порога
+// не отправляются в ASR, чтобы Whisper не галлюцинировал на тишине. 0 - отключить
+func (s *TranscriptionService) SetSilenceGuard(dbfs float64) error {
+	if math.IsNaN(dbfs) || dbfs > 0 {
+		return fmt.Errorf("invalid silence guard %.1f dBFS: must be negative (0 disables)", dbfs)
+	}
+	s.SilenceGuardDBFS = dbfs
+	if dbfs == 0 {
+		log.Printf("Silence guard disabled")
+	} else {
+		log.Printf("Silence guard: regions below %.1f dBFS are not transcribed", dbfs)
+	}
+	return nil
+}
+
+// dropSilentRegions убирает регионы речи с RMS ниже SilenceGuardDBFS.
+// Применяется до выбора режима (compression или per-region), поэтому действует в обоих
+func (s *TranscriptionService) dropSilentRegions(label, channel string, samples []float32, regions []session.SpeechRegion, sampleRate int) []session.SpeechRegion {
+	if s.SilenceGuardDBFS == 0 || len(regions) == 0 {
+		return regions
+	}
+	kept := regions[:0:0]
+	for _, region := range regions {
+		start := max(0, int(region.StartMs*int64(sampleRate)/1000))
+		end := min(len(samples), int(region.EndMs*int64(sampleRate)/1000))
+		if start >= end {
+			continue
+		}
+		level := 20 * math.Log10(session.CalculateRMS(samples[start:end]))
+		if level < s.SilenceGuardDBFS {
+			log.Printf("Silence guard (%s, %s): skipping region %d-%dms at %.1f dBFS (floor %.1f dBFS)",
+				label, channel, region.StartMs, region.EndMs, level, s.SilenceGuardDBFS)
+			continue
+		}
+		kept = append(kept, region)
+	}
+	return kept
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/session"
+)
+
+func TestDropSilentRegions(t *testing.T) {
+	const sampleRate = 16000
+	samples := make([]float32, 3*sampleRate)
+	for i := 0; i < sampleRate; i++ {
+		samples[i] = 0.1 * float32(i%2*2-1) // -20 dBFS
+	}
+	for i := sampleRate; i < 2*sampleRate; i++ {
+		samples[i] = 0.0001 * float32(i%2*2-1) // -80 dBFS
+	}
+	regions := []session.SpeechRegion{{StartMs: 0, EndMs: 1000}, {StartMs: 1000, EndMs: 2000}, {StartMs: 2000, EndMs: 3000}}
+
+	s := NewTranscriptionService(nil, nil)
+	kept := s.dropSilentRegions("chunk 1", "mic", samples, regions, sampleRate)
+	if len(kept) != 1 || kept[0].StartMs != 0 {
+		t.Errorf("kept = %+v, want only the loud region", kept)
+	}
+	if len(regions) != 3 || regions[1].StartMs != 1000 {
+		t.Errorf("input regions modified: %+v", regions)
+	}
+
+	if err := s.SetSilenceGuard(-90); err != nil {
+		t.Fatal(err)
+	}
+	if kept := s.dropSilentRegions("chunk 1", "mic", samples, regions, sampleRate); len(kept) != 2 {
+		t.Errorf("with -90 dBFS floor kept %d regions, want 2", len(kept))
+	}
+
+	if err := s.SetSilenceGuard(3); err == nil {
+		t.Error("expected error for positive floor")
+	}
+	if err := s.SetSilenceGuard(0); err != nil {
+		t.Fatal(err)
+	}
+	if kept := s.dropSilentRegions("chunk 1", "mic", samples, regions, sampleRate); len(kept) != 3 {
+		t.Errorf("disabled guard kept %d regions, want 3", len(kept))
+	}
+}
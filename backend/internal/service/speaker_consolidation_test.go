@@ -0,0 +1,147 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+func TestSplitSegmentsBySpeakers_QuietSpeakerThreshold(t *testing.T) {
+	// Speaker 1 говорит 1.5с из 20с (7.5%)
+	speakerSegs := []ai.SpeakerSegment{
+		{Start: 0, End: 10, Speaker: 0},
+		{Start: 10, End: 11.5, Speaker: 1},
+		{Start: 11.5, End: 20, Speaker: 0},
+	}
+	segments := []ai.TranscriptSegment{{
+		Start: 0, End: 20000, Text: "Привет. Да. Конечно.",
+		Words: []ai.TranscriptWord{
+			{Start: 1000, End: 2000, Text: "Привет."},
+			{Start: 10200, End: 11200, Text: "Да."},
+			{Start: 15000, End: 16000, Text: "Конечно."},
+		},
+	}}
+
+	speakers := func(segs []ai.TranscriptSegment) map[string]bool {
+		result := make(map[string]bool)
+		for _, seg := range segs {
+			result[seg.Speaker] = true
+		}
+		return result
+	}
+
+	// По умолчанию (10%) тихий спикер поглощается
+	got := splitSegmentsBySpeakers(segments, speakerSegs, ai.DefaultMinSpeakerRatio, ai.DefaultMinSpeakerSegmentSec, ai.DefaultMinOverlapRatio)
+	if s := speakers(got); len(s) != 1 {
+		t.Errorf("default threshold: got speakers %v, want 1", s)
+	}
+
+	// С порогом 5% тихий спикер сохраняется
+	got = splitSegmentsBySpeakers(segments, speakerSegs, 0.05, ai.DefaultMinSpeakerSegmentSec, ai.DefaultMinOverlapRatio)
+	if s := speakers(got); len(s) != 2 {
+		t.Errorf("5%% threshold: got speakers %v, want 2", s)
+	}
+}
+
+func TestPipelineConfigSpeakerConsolidation(t *testing.T) {
+	cfg := ai.DefaultPipelineConfig()
+	if cfg.EffectiveMinSpeakerRatio() != ai.DefaultMinSpeakerRatio || cfg.EffectiveMinSpeakerSegmentSec() != ai.DefaultMinSpeakerSegmentSec {
+		t.Error("zero values should fall back to defaults")
+	}
+	for _, bad := range []ai.PipelineConfig{
+		{MinSpeakerRatio: -0.1},
+		{MinSpeakerRatio: 0.6},
+		{MinSpeakerSegmentSec: -1},
+		{MinSpeakerSegmentSec: 10},
+	} {
+		if err := bad.ValidateSpeakerConsolidation(); err == nil {
+			t.Errorf("expected validation error for %+v", bad)
+		}
+	}
+	if err := (ai.PipelineConfig{MinSpeakerRatio: 0.02, MinSpeakerSegmentSec: 0.5}).ValidateSpeakerConsolidation(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDiarizationParams(t *testing.T) {
+	defaults := DiarizationParams{}.pipelineConfig()
+	if defaults.ClusteringThreshold != 0.5 || defaults.MinDurationOn != 0.3 || defaults.MinDurationOff != 0.5 {
+		t.Errorf("unspecified params should keep defaults, got %+v", defaults)
+	}
+	for _, bad := range []DiarizationParams{
+		{ClusteringThreshold: 0.05},
+		{ClusteringThreshold: 0.99},
+		{MinDurationOn: -0.1},
+		{MinDurationOff: 6},
+		{MinSpeakerRatio: 0.7},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", bad)
+		}
+	}
+	params := DiarizationParams{ClusteringThreshold: 0.35, MinDurationOn: 0.2, MinDurationOff: 0.8}
+	if err := params.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg := params.pipelineConfig(); cfg.ClusteringThreshold != 0.35 || cfg.MinDurationOn != 0.2 || cfg.MinDurationOff != 0.8 {
+		t.Errorf("params are not applied: %+v", cfg)
+	}
+
+	// Без диаризации возвращаются значения по умолчанию
+	s := NewTranscriptionService(nil, nil)
+	if got := s.GetDiarizationParams(); got.ClusteringThreshold != 0.5 || got.MinSpeakerRatio != ai.DefaultMinSpeakerRatio {
+		t.Errorf("GetDiarizationParams = %+v", got)
+	}
+	if err := s.EnableDiarizationWithParams("", "", "auto", "sherpa", DiarizationParams{ClusteringThreshold: 1}); err == nil {
+		t.Error("invalid threshold accepted")
+	}
+}
+
+func TestSplitSegmentsBySpeakers_OverlappingSpeech(t *testing.T) {
+	// Два спикера говорят одновременно 0-4с, дальше - только первый
+	speakerSegs := []ai.SpeakerSegment{
+		{Start: 0, End: 4, Speaker: 0},
+		{Start: 0, End: 4, Speaker: 1},
+		{Start: 4, End: 10, Speaker: 0},
+	}
+	segments := []ai.TranscriptSegment{
+		{Start: 0, End: 3000, Text: "Подождите, я", Words: []ai.TranscriptWord{
+			{Start: 500, End: 1500, Text: "Подождите,"},
+			{Start: 1600, End: 3000, Text: "я"},
+		}},
+		{Start: 5000, End: 9000, Text: "Продолжу позже.", Words: []ai.TranscriptWord{
+			{Start: 5000, End: 6000, Text: "Продолжу"},
+			{Start: 6500, End: 9000, Text: "позже."},
+		}},
+	}
+
+	got := splitSegmentsBySpeakers(segments, speakerSegs, 0.05, 0.5, ai.DefaultMinOverlapRatio)
+	if len(got) != 2 {
+		t.Fatalf("got %d segments, want 2: %+v", len(got), got)
+	}
+	if !got[0].Overlap || got[0].Speaker != "Speaker 0" || got[0].OverlapSpeaker != "Speaker 1" {
+		t.Errorf("overlapping segment: overlap=%v speaker=%q overlapSpeaker=%q", got[0].Overlap, got[0].Speaker, got[0].OverlapSpeaker)
+	}
+	if got[1].Overlap || got[1].OverlapSpeaker != "" {
+		t.Errorf("single speaker segment marked as overlap: %+v", got[1])
+	}
+
+	// Второй спикер покрывает лишь часть слова - ниже порога наложения нет
+	partial := []ai.SpeakerSegment{
+		{Start: 0, End: 10, Speaker: 0},
+		{Start: 1.2, End: 1.5, Speaker: 1},
+	}
+	got = splitSegmentsBySpeakers(segments[:1], partial, 0.01, 0.1, ai.DefaultMinOverlapRatio)
+	for _, seg := range got {
+		if seg.Overlap {
+			t.Errorf("partial overlap below ratio marked: %+v", seg)
+		}
+	}
+
+	// Спикер перекрытия переносится в метки сессии
+	converted := convertSysSegmentsWithDiarization(splitSegmentsBySpeakers(segments[:1], speakerSegs, 0.05, 0.5, 0.5), 0)
+	if !converted[0].Overlap || converted[0].OverlapSpeaker != session.SpeakerLabel(1) {
+		t.Errorf("converted segment: %+v", converted[0])
+	}
+}
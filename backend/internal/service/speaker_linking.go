@@ -0,0 +1,100 @@
+package service
+
+import (
+	"aiwisper/session"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// SimilarSpeaker спикер другой сессии с похожим голосом (find_similar_speakers)
+type SimilarSpeaker struct {
+	SessionID    string    `json:"sessionId"`
+	SessionTitle string    `json:"sessionTitle,omitempty"`
+	StartTime    time.Time `json:"startTime"`
+	LocalID      int       `json:"localId"`        // ID в рамках сессии ("Собеседник N" -> N-1)
+	Name         string    `json:"name,omitempty"` // Имя из базы voiceprints или переименования в сессии
+	Similarity   float32   `json:"similarity"`
+	Duration     float32   `json:"duration"` // Длительность речи спикера (сек)
+}
+
+// FindSimilarSpeakers ищет в остальных сессиях спикеров, чей сохранённый embedding
+// близок к спикеру localID сессии sessionID (threshold 0 - порог сопоставления по умолчанию).
+// Позволяет связать одного человека в серии встреч без заведённого voiceprint.
+// Результат упорядочен по убыванию сходства
+func (s *TranscriptionService) FindSimilarSpeakers(sessionID string, localID int, threshold float32) ([]SimilarSpeaker, error) {
+	if threshold == 0 {
+		threshold = DefaultSpeakerMatchThreshold
+	}
+	if threshold < MinSpeakerMatchThreshold || threshold > MaxSpeakerMatchThreshold {
+		return nil, fmt.Errorf("invalid similarity threshold %.2f: must be between %.2f and %.2f",
+			threshold, MinSpeakerMatchThreshold, MaxSpeakerMatchThreshold)
+	}
+	if localID < 0 {
+		return nil, fmt.Errorf("microphone speaker does not have embedding in diarization pipeline")
+	}
+
+	profiles, err := s.LoadSessionSpeakerProfiles(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var embedding []float32
+	for _, p := range profiles {
+		if p.SpeakerID == localID {
+			embedding = p.Embedding
+			break
+		}
+	}
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("speaker %d has no stored embedding in session %s", localID, sessionID)
+	}
+
+	var result []SimilarSpeaker
+	for _, sess := range s.SessionMgr.ListSessions() {
+		if sess.ID == sessionID {
+			continue
+		}
+		// Профили активных сессий берём из памяти, остальные читаем с диска без кэширования
+		candidates := s.GetSessionSpeakerProfiles(sess.ID)
+		if len(candidates) == 0 {
+			candidates, err = readSpeakerProfiles(sess.DataDir)
+			if err != nil {
+				log.Printf("FindSimilarSpeakers: skipping session %s: %v", sess.ID, err)
+				continue
+			}
+		}
+
+		var renames map[string]string
+		for _, p := range candidates {
+			if len(p.Embedding) != len(embedding) {
+				continue // Другая модель embeddings - сравнение не имеет смысла
+			}
+			similarity := cosineSimilarity(embedding, p.Embedding)
+			if similarity < threshold {
+				continue
+			}
+			if renames == nil {
+				renames = sess.CurrentSpeakerRenames()
+			}
+			name := p.RecognizedName
+			if name == "" {
+				name = renames[session.SpeakerLabel(p.SpeakerID)]
+			}
+			result = append(result, SimilarSpeaker{
+				SessionID:    sess.ID,
+				SessionTitle: sess.Title,
+				StartTime:    sess.StartTime,
+				LocalID:      p.SpeakerID,
+				Name:         name,
+				Similarity:   similarity,
+				Duration:     p.Duration,
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Similarity > result[j].Similarity
+	})
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"aiwisper/session"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSimilarSpeakers(t *testing.T) {
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSession := func(profiles []SessionSpeakerProfile) *session.Session {
+		t.Helper()
+		sess, err := mgr.CreateSession(session.SessionConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(profiles)
+		if err := os.WriteFile(filepath.Join(sess.DataDir, "speaker_profiles.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mgr.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+
+	source := newSession([]SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: []float32{1, 0, 0}},
+		{SpeakerID: 1, Embedding: []float32{0, 1, 0}},
+	})
+	named := newSession([]SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: []float32{0, 1, 0.1}},
+		{SpeakerID: 1, Embedding: []float32{0.9, 0.1, 0}, Duration: 30},
+	})
+	recognized := newSession([]SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: []float32{1, 0.5, 0}, RecognizedName: "Олег"},
+		{SpeakerID: 1, Embedding: []float32{1, 0, 0, 0}}, // Другая размерность - не сравнивается
+	})
+	rename := session.SpeakerRename{LocalID: 1, StandardName: "Собеседник 2", OldName: "Собеседник 2", NewName: "Анна"}
+	if err := mgr.RecordSpeakerRename(named.ID, rename); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewTranscriptionService(mgr, nil)
+	got, err := s.FindSimilarSpeakers(source.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d similar speakers, want 2: %+v", len(got), got)
+	}
+	if got[0].SessionID != named.ID || got[0].LocalID != 1 || got[0].Name != "Анна" || got[0].Duration != 30 {
+		t.Errorf("best match = %+v", got[0])
+	}
+	if got[1].SessionID != recognized.ID || got[1].LocalID != 0 || got[1].Name != "Олег" {
+		t.Errorf("second match = %+v", got[1])
+	}
+	if got[0].Similarity < got[1].Similarity {
+		t.Errorf("results not sorted by similarity: %.3f < %.3f", got[0].Similarity, got[1].Similarity)
+	}
+
+	// Профиль ищется по localID: "Собеседник 2" (localID 1) связывается со своим профилем
+	if got, err := s.FindSimilarSpeakers(source.ID, 1, 0); err != nil || len(got) != 1 || got[0].SessionID != named.ID || got[0].LocalID != 0 {
+		t.Errorf("speaker 1: got %+v, err %v", got, err)
+	}
+
+	// Строгий порог оставляет только самое близкое совпадение
+	if got, err := s.FindSimilarSpeakers(source.ID, 0, 0.95); err != nil || len(got) != 1 {
+		t.Errorf("strict threshold: got %+v, err %v", got, err)
+	}
+
+	// Профили чужих сессий не кэшируются в памяти
+	if profiles := s.GetSessionSpeakerProfiles(named.ID); profiles != nil {
+		t.Errorf("other session profiles were cached: %+v", profiles)
+	}
+
+	if _, err := s.FindSimilarSpeakers(source.ID, 5, 0); err == nil {
+		t.Error("expected error for unknown speaker")
+	}
+	if _, err := s.FindSimilarSpeakers(source.ID, 0, 0.1); err == nil {
+		t.Error("expected error for threshold out of range")
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+	"aiwisper/voiceprint"
+)
+
+// voice возвращает синтетический embedding: единичный вектор по оси axis с небольшим шумом
+func voice(axis int, noise float32) []float32 {
+	emb := make([]float32, 8)
+	for i := range emb {
+		emb[i] = noise
+	}
+	emb[axis] = 1
+	return emb
+}
+
+func TestAssignSessionSpeakerIDs_StableAcrossRuns(t *testing.T) {
+	// Профили, сохранённые предыдущей транскрипцией: голос A = 0, голос B = 1
+	profiles := []SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: voice(0, 0)},
+		{SpeakerID: 1, Embedding: voice(1, 0)},
+	}
+
+	// Повторная диаризация выдала те же голоса с переставленными ID
+	embeddings := []ai.SpeakerEmbedding{
+		{Speaker: 0, Embedding: voice(1, 0.05)}, // голос B
+		{Speaker: 1, Embedding: voice(0, 0.05)}, // голос A
+	}
+
+	mapping, added := assignSessionSpeakerIDs(profiles, embeddings, DefaultSpeakerMatchThreshold)
+	if len(added) != 0 {
+		t.Errorf("expected no new profiles, got %d", len(added))
+	}
+	if mapping[0] != 1 || mapping[1] != 0 {
+		t.Errorf("mapping = %v, want map[0:1 1:0]", mapping)
+	}
+}
+
+func TestAssignSessionSpeakerIDs_NewSpeakerGetsFreeID(t *testing.T) {
+	profiles := []SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: voice(0, 0)},
+		{SpeakerID: 1, Embedding: voice(1, 0)},
+	}
+
+	// Raw ID 0 принадлежит новому голосу C, raw ID 1 - голосу A
+	embeddings := []ai.SpeakerEmbedding{
+		{Speaker: 0, Embedding: voice(2, 0)},
+		{Speaker: 1, Embedding: voice(0, 0.02)},
+	}
+
+	mapping, added := assignSessionSpeakerIDs(profiles, embeddings, DefaultSpeakerMatchThreshold)
+	if len(added) != 1 || added[0].SpeakerID != 2 {
+		t.Fatalf("added = %+v, want one profile with SpeakerID 2", added)
+	}
+	if mapping[0] != 2 || mapping[1] != 0 {
+		t.Errorf("mapping = %v, want map[0:2 1:0]", mapping)
+	}
+}
+
+func TestAssignSessionSpeakerIDs_OneToOne(t *testing.T) {
+	profiles := []SessionSpeakerProfile{
+		{SpeakerID: 0, Embedding: voice(0, 0)},
+	}
+
+	// Оба голоса похожи на профиль 0, но занять его может только более похожий
+	embeddings := []ai.SpeakerEmbedding{
+		{Speaker: 0, Embedding: voice(0, 0.3)},
+		{Speaker: 1, Embedding: voice(0, 0.01)},
+	}
+
+	mapping, added := assignSessionSpeakerIDs(profiles, embeddings, DefaultSpeakerMatchThreshold)
+	if mapping[1] != 0 {
+		t.Errorf("closest voice should keep profile 0, mapping = %v", mapping)
+	}
+	if len(added) != 1 || added[0].SpeakerID != 1 {
+		t.Errorf("added = %+v, want one profile with SpeakerID 1", added)
+	}
+	if got, ok := mapping[0]; !ok || got != 1 {
+		t.Errorf("second voice should be renumbered to 1, mapping = %v", mapping)
+	}
+}
+
+func TestSpeakerMatchThreshold(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	if s.GetSpeakerMatchThreshold() != DefaultSpeakerMatchThreshold {
+		t.Fatalf("default threshold = %.2f", s.GetSpeakerMatchThreshold())
+	}
+	for _, bad := range []float32{0.1, 0.99, -1} {
+		if err := s.SetSpeakerMatchThreshold(bad); err == nil {
+			t.Errorf("expected error for %.2f", bad)
+		}
+	}
+	if s.GetSpeakerMatchThreshold() != DefaultSpeakerMatchThreshold {
+		t.Errorf("invalid threshold applied: %.2f", s.GetSpeakerMatchThreshold())
+	}
+
+	// Сходство ~0.8: совпадает при пороге 0.65, но становится новым спикером при 0.9
+	profiles := []SessionSpeakerProfile{{SpeakerID: 0, Embedding: voice(0, 0)}}
+	similar := voice(0, 0)
+	similar[1] = 0.75
+	embeddings := []ai.SpeakerEmbedding{{Speaker: 3, Embedding: similar}}
+
+	if _, added := assignSessionSpeakerIDs(profiles, embeddings, DefaultSpeakerMatchThreshold); len(added) != 0 {
+		t.Errorf("default threshold: expected match, got %d new profiles", len(added))
+	}
+	if err := s.SetSpeakerMatchThreshold(0.9); err != nil {
+		t.Fatal(err)
+	}
+	if _, added := assignSessionSpeakerIDs(profiles, embeddings, s.GetSpeakerMatchThreshold()); len(added) != 1 {
+		t.Errorf("threshold 0.9: expected a new speaker, got %d new profiles", len(added))
+	}
+}
+
+func TestProposeSessionSpeakerMatches(t *testing.T) {
+	root := t.TempDir()
+	sessMgr, err := session.NewManager(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTranscriptionService(sessMgr, nil)
+	if _, err := s.ProposeSessionSpeakerMatches(sess.ID); err == nil {
+		t.Error("expected error without voiceprint matcher")
+	}
+
+	store, err := voiceprint.NewStore(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	anna, err := store.Add("Анна", voice(0, 0), "sys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.VoicePrintMatcher = voiceprint.NewMatcher(store)
+
+	// Профили с диска: порядок произвольный, у одного нет embedding.
+	// SpeakerID профиля совпадает с localID: профиль 0 - первый собеседник, а не микрофон (-1)
+	profiles := []SessionSpeakerProfile{
+		{SpeakerID: 2, Embedding: voice(2, 0), Duration: 4},
+		{SpeakerID: 0, Embedding: voice(0, 0.05), Duration: 30, RecognizedName: "Анна"},
+		{SpeakerID: 1, Duration: 1},
+	}
+	data, _ := json.Marshal(profiles)
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "speaker_profiles.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proposals, err := s.ProposeSessionSpeakerMatches(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proposals) != 3 {
+		t.Fatalf("proposals = %+v", proposals)
+	}
+	for i, p := range proposals {
+		if p.LocalID != i {
+			t.Errorf("proposal %d: localId = %d, want sorted by local ID", i, p.LocalID)
+		}
+	}
+	if p := proposals[0]; !p.Matched || p.VoicePrintID != anna.ID || p.Name != "Анна" || p.CurrentName != "Анна" || p.Confidence != "high" || p.Duration != 30 {
+		t.Errorf("matched speaker = %+v", p)
+	}
+	// Без embedding и без похожего голоса - предложение без совпадения
+	for _, p := range proposals[1:] {
+		if p.Matched || p.VoicePrintID != "" || p.Confidence != "none" {
+			t.Errorf("unmatched speaker = %+v", p)
+		}
+	}
+	if store.Count() != 1 {
+		t.Errorf("proposals changed the store: %d voiceprints", store.Count())
+	}
+
+	if _, err := s.ProposeSessionSpeakerMatches("missing"); err == nil {
+		t.Error("expected error for missing session")
+	}
+}
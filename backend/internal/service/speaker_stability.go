@@ -0,0 +1,120 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"aiwisper/voiceprint"
+	"sort"
+)
+
+// maxSpeakerObservations сколько последних embeddings чанков хранится в профиле спикера
+const maxSpeakerObservations = 32
+
+// addObservation запоминает embedding спикера из очередного чанка.
+// Старые наблюдения вытесняются, чтобы speaker_profiles.json не разрастался
+func (p *SessionSpeakerProfile) addObservation(embedding []float32) {
+	if len(embedding) == 0 {
+		return
+	}
+	p.Observations = append(p.Observations, embedding)
+	if extra := len(p.Observations) - maxSpeakerObservations; extra > 0 {
+		p.Observations = p.Observations[extra:]
+	}
+}
+
+// Stability оценивает стабильность спикера: среднее попарное косинусное сходство
+// его embeddings из разных чанков. Возвращает оценку и число наблюдений;
+// при менее чем двух наблюдениях оценка не определена (0)
+func (p *SessionSpeakerProfile) Stability() (float32, int) {
+	observations := p.Observations
+	if len(observations) == 0 && len(p.Embedding) > 0 {
+		// Профили старых сессий хранят только один embedding
+		observations = [][]float32{p.Embedding}
+	}
+	if len(observations) < 2 {
+		return 0, len(observations)
+	}
+
+	var sum float32
+	pairs := 0
+	for i := 0; i < len(observations); i++ {
+		for j := i + 1; j < len(observations); j++ {
+			sum += cosineSimilarity(observations[i], observations[j])
+			pairs++
+		}
+	}
+	return sum / float32(pairs), len(observations)
+}
+
+// addMatch запоминает сходство сопоставления спикера в очередном чанке.
+// Повторная обработка чанка (ретранскрипция) заменяет прежнюю запись
+func (p *SessionSpeakerProfile) addMatch(match voiceprint.SpeakerChunkMatch) {
+	for i := range p.Matches {
+		if p.Matches[i].ChunkIndex == match.ChunkIndex {
+			p.Matches[i] = match
+			return
+		}
+	}
+	p.Matches = append(p.Matches, match)
+	if extra := len(p.Matches) - maxSpeakerObservations; extra > 0 {
+		p.Matches = p.Matches[extra:]
+	}
+}
+
+// MinMatchSimilarity возвращает худшее сходство среди чанков, где спикер был узнан
+// (0 - спикер ни разу не сопоставлялся с уже известным профилем)
+func (p *SessionSpeakerProfile) MinMatchSimilarity() float32 {
+	var result float32
+	found := false
+	for _, m := range p.Matches {
+		if !m.New && (!found || m.Similarity < result) {
+			result, found = m.Similarity, true
+		}
+	}
+	return result
+}
+
+// speakerChunkMatches считает сходство спикеров чанка с профилями сессии (до обновления профилей).
+// Для сопоставленного спикера - сходство с его профилем, для нового - лучшее сходство
+// среди всех профилей, объясняющее, почему совпадение не засчитано.
+// Возвращает map[sessionSpeakerID]match
+func speakerChunkMatches(profiles []SessionSpeakerProfile, embeddings []ai.SpeakerEmbedding, mapping map[int]int, chunkIndex int) map[int]voiceprint.SpeakerChunkMatch {
+	result := make(map[int]voiceprint.SpeakerChunkMatch, len(embeddings))
+	for _, emb := range embeddings {
+		id := emb.Speaker
+		if mapped, ok := mapping[emb.Speaker]; ok {
+			id = mapped
+		}
+
+		match := voiceprint.SpeakerChunkMatch{ChunkIndex: chunkIndex, New: true}
+		for _, profile := range profiles {
+			similarity := cosineSimilarity(emb.Embedding, profile.Embedding)
+			if profile.SpeakerID == id {
+				match.Similarity, match.New = similarity, false
+				break
+			}
+			match.Similarity = max(match.Similarity, similarity)
+		}
+		result[id] = match
+	}
+	return result
+}
+
+// mergeSpeakerChunkMatches объединяет историю сопоставлений при слиянии спикеров:
+// по одной записи на чанк (с лучшим сходством), по порядку чанков
+func mergeSpeakerChunkMatches(matches []voiceprint.SpeakerChunkMatch) []voiceprint.SpeakerChunkMatch {
+	byChunk := make(map[int]voiceprint.SpeakerChunkMatch)
+	for _, m := range matches {
+		if prev, ok := byChunk[m.ChunkIndex]; !ok || (prev.New && !m.New) || (prev.New == m.New && m.Similarity > prev.Similarity) {
+			byChunk[m.ChunkIndex] = m
+		}
+	}
+	result := make([]voiceprint.SpeakerChunkMatch, 0, len(byChunk))
+	for _, m := range byChunk {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ChunkIndex < result[j].ChunkIndex })
+	if extra := len(result) - maxSpeakerObservations; extra > 0 {
+		result = result[extra:]
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+	"aiwisper/voiceprint"
+)
+
+func TestSpeakerProfileStability(t *testing.T) {
+	// Старый профиль с одним embedding - оценки нет
+	legacy := SessionSpeakerProfile{SpeakerID: 0, Embedding: []float32{1, 0}}
+	if score, n := legacy.Stability(); score != 0 || n != 1 {
+		t.Errorf("legacy profile: got %.2f (%d), want 0 (1)", score, n)
+	}
+
+	stable := SessionSpeakerProfile{Observations: [][]float32{{1, 0, 0}, {1, 0.05, 0}, {0.98, 0.02, 0}}}
+	unstable := SessionSpeakerProfile{Observations: [][]float32{{0, 1, 0}, {0.7, 0.7, 0}, {1, 0, 0.2}}}
+	stableScore, n := stable.Stability()
+	unstableScore, _ := unstable.Stability()
+	if n != 3 || stableScore < 0.99 {
+		t.Errorf("stable speaker: got %.3f (%d)", stableScore, n)
+	}
+	if unstableScore >= stableScore || unstableScore > 0.7 {
+		t.Errorf("unstable speaker: got %.3f, stable %.3f", unstableScore, stableScore)
+	}
+
+	// Число наблюдений ограничено
+	var p SessionSpeakerProfile
+	for i := 0; i < maxSpeakerObservations+5; i++ {
+		p.addObservation([]float32{float32(i), 1})
+	}
+	if len(p.Observations) != maxSpeakerObservations || p.Observations[0][0] != 5 {
+		t.Errorf("observations = %d, first = %v", len(p.Observations), p.Observations[0])
+	}
+}
+
+func TestMatchSpeakersWithSessionRecordsSimilarity(t *testing.T) {
+	mgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := mgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTranscriptionService(mgr, nil)
+
+	// Чанк 0: голоса A и B, чанк 1: A узнан, третий голос C похож на B, но ниже порога
+	s.matchSpeakersWithSession(sess.ID, 0, []ai.SpeakerEmbedding{
+		{Speaker: 0, Embedding: voice(0, 0)},
+		{Speaker: 1, Embedding: voice(1, 0)},
+	})
+	partlyB := voice(2, 0)
+	partlyB[1] = 0.8
+	s.matchSpeakersWithSession(sess.ID, 1, []ai.SpeakerEmbedding{
+		{Speaker: 0, Embedding: voice(0, 0.05)},
+		{Speaker: 1, Embedding: partlyB},
+	})
+
+	profiles := s.GetSessionSpeakerProfiles(sess.ID)
+	if len(profiles) != 3 {
+		t.Fatalf("got %d profiles, want 3", len(profiles))
+	}
+	a, c := profiles[0], profiles[2]
+	if len(a.Matches) != 2 || !a.Matches[0].New || a.Matches[1].New || a.Matches[1].ChunkIndex != 1 {
+		t.Fatalf("speaker A matches = %+v", a.Matches)
+	}
+	if sim := a.MinMatchSimilarity(); sim < 0.9 {
+		t.Errorf("speaker A min similarity = %.3f, want > 0.9", sim)
+	}
+	if len(c.Matches) != 1 || !c.Matches[0].New || c.Matches[0].Similarity < 0.5 || c.Matches[0].Similarity >= DefaultSpeakerMatchThreshold {
+		t.Errorf("new speaker C should record the best rejected similarity, got %+v", c.Matches)
+	}
+	if c.MinMatchSimilarity() != 0 {
+		t.Errorf("never matched speaker: min similarity = %.3f", c.MinMatchSimilarity())
+	}
+
+	// При слиянии C в B история объединяется по чанкам
+	merged := mergeSpeakerChunkMatches(append(append([]voiceprint.SpeakerChunkMatch(nil), profiles[1].Matches...), c.Matches...))
+	if len(merged) != 2 || merged[0].ChunkIndex != 0 || merged[1].ChunkIndex != 1 {
+		t.Errorf("merged matches = %+v", merged)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"strings"
+	"sync"
+)
+
+const (
+	// streamingBufferWords сколько последних слов хранит буфер streaming транскрипции
+	streamingBufferWords = 256
+	// streamingTimeToleranceMs допуск сопоставления слов соседних обновлений по времени:
+	// тайминги токенов одного и того же слова в перекрывающихся чанках немного расходятся
+	streamingTimeToleranceMs = 150
+)
+
+// streamingWord слово streaming транскрипции в буфере
+type streamingWord struct {
+	ai.TranscriptWord
+	confirmed bool
+}
+
+// streamingBuffer буфер последних слов streaming транскрипции по времени.
+// Новый чанк, перекрывающий неподтверждённый хвост предыдущего, заменяет его: вместо
+// дублирования текста отправляется исправление затронутого участка. Слова, уже подтверждённые
+// ранее, повторно не отправляются
+type streamingBuffer struct {
+	mu             sync.Mutex
+	words          []streamingWord // По возрастанию Start, не больше streamingBufferWords
+	confirmedEndMs int64           // Конец последнего подтверждённого слова
+}
+
+// reset очищает буфер (новая сессия)
+func (b *streamingBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.words = nil
+	b.confirmedEndMs = 0
+}
+
+// apply учитывает обновление движка и возвращает обновление для UI.
+// ok=false - обновление целиком повторяет подтверждённый текст и не отправляется.
+// Обновления без таймингов токенов передаются как есть
+func (b *streamingBuffer) apply(update ai.StreamingTranscriptionUpdate) (result StreamingTranscriptionUpdate, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result = StreamingTranscriptionUpdate{
+		Text:        update.Text,
+		IsConfirmed: update.IsConfirmed,
+		Confidence:  update.Confidence,
+		Timestamp:   update.Timestamp,
+	}
+	if len(update.TokenTimings) == 0 {
+		b.fillBoundaryLocked(&result)
+		return result, true
+	}
+
+	// Слова, попавшие в уже подтверждённый участок, - повтор перекрытия чанков
+	incoming := update.TokenTimings
+	for len(incoming) > 0 && incoming[0].Start < b.confirmedEndMs-streamingTimeToleranceMs {
+		incoming = incoming[1:]
+	}
+	if len(incoming) == 0 {
+		return result, false
+	}
+
+	// Неподтверждённый хвост, который перекрывает новый чанк, заменяется
+	from := incoming[0].Start
+	cut := len(b.words)
+	for cut > 0 && !b.words[cut-1].confirmed && b.words[cut-1].Start >= from-streamingTimeToleranceMs {
+		cut--
+	}
+	replaced := make([]ai.TranscriptWord, 0, len(b.words)-cut)
+	for _, old := range b.words[cut:] {
+		replaced = append(replaced, old.TranscriptWord)
+	}
+	revised := false
+	for _, old := range replaced {
+		if !containsStreamingWord(incoming, old) {
+			revised = true
+			break
+		}
+	}
+
+	b.words = b.words[:cut]
+	for _, w := range incoming {
+		b.words = append(b.words, streamingWord{TranscriptWord: w, confirmed: update.IsConfirmed})
+	}
+	if update.IsConfirmed {
+		// Подтверждение монотонно по времени: более ранние слова тоже окончательные
+		for i := range b.words {
+			b.words[i].confirmed = true
+		}
+		b.confirmedEndMs = max(b.confirmedEndMs, incoming[len(incoming)-1].End)
+	}
+	if n := len(b.words); n > streamingBufferWords {
+		b.words = append([]streamingWord(nil), b.words[n-streamingBufferWords:]...)
+	}
+
+	switch {
+	case revised:
+		result.IsConfirmed = false
+		result.Correction = true
+		result.RevisedFromMs = replaced[0].Start
+		result.Text = joinStreamingTokens(incoming)
+	case !update.IsConfirmed && len(replaced) > 0:
+		// Хвост повторён без изменений - отправляем только новые слова
+		var added []ai.TranscriptWord
+		for _, w := range incoming {
+			if !containsStreamingWord(replaced, w) {
+				added = append(added, w)
+			}
+		}
+		if len(added) == 0 {
+			return result, false
+		}
+		result.Text = joinStreamingTokens(added)
+	case len(incoming) < len(update.TokenTimings):
+		result.Text = joinStreamingTokens(incoming)
+	}
+	b.fillBoundaryLocked(&result)
+	return result, true
+}
+
+// fillBoundaryLocked заполняет границу подтверждённого текста и неподтверждённый хвост. Вызывается под b.mu
+func (b *streamingBuffer) fillBoundaryLocked(update *StreamingTranscriptionUpdate) {
+	update.ConfirmedEndMs = b.confirmedEndMs
+	var tentative []ai.TranscriptWord
+	for _, w := range b.words {
+		if !w.confirmed {
+			tentative = append(tentative, w.TranscriptWord)
+		}
+	}
+	update.TentativeText = joinStreamingTokens(tentative)
+}
+
+// containsStreamingWord проверяет, что слово есть среди слов с тем же текстом и близким началом
+func containsStreamingWord(words []ai.TranscriptWord, word ai.TranscriptWord) bool {
+	for _, w := range words {
+		diff := w.Start - word.Start
+		if diff >= -streamingTimeToleranceMs && diff <= streamingTimeToleranceMs && strings.TrimSpace(w.Text) == strings.TrimSpace(word.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinStreamingTokens собирает текст из токенов: токены с ведущим пробелом (SentencePiece)
+// склеиваются как есть, иначе - через пробел
+func joinStreamingTokens(words []ai.TranscriptWord) string {
+	spaced := false
+	for i, w := range words {
+		if i > 0 && strings.HasPrefix(w.Text, " ") {
+			spaced = true
+			break
+		}
+	}
+	var sb strings.Builder
+	for i, w := range words {
+		if i > 0 && !spaced {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(w.Text)
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/ai"
+)
+
+func TestStreamingBufferCorrectsOverlappingChunk(t *testing.T) {
+	word := func(start int64, text string) ai.TranscriptWord {
+		return ai.TranscriptWord{Start: start, End: start + 400, Text: text}
+	}
+	var b streamingBuffer
+
+	// Первый чанк: "завтра в три чеса" (последнее слово распознано неверно)
+	first, ok := b.apply(ai.StreamingTranscriptionUpdate{Text: "завтра в три чеса", TokenTimings: []ai.TranscriptWord{
+		word(0, "завтра"), word(500, "в"), word(1000, "три"), word(1500, "чеса"),
+	}})
+	if !ok || first.Correction || first.Text != "завтра в три чеса" || first.TentativeText != "завтра в три чеса" {
+		t.Fatalf("first update = %+v", first)
+	}
+
+	// Второй чанк перекрывает хвост первого и исправляет слово
+	second, ok := b.apply(ai.StreamingTranscriptionUpdate{Text: "три часа дня", TokenTimings: []ai.TranscriptWord{
+		word(1020, "три"), word(1480, "часа"), word(2000, "дня"),
+	}})
+	if !ok || !second.Correction || second.IsConfirmed {
+		t.Fatalf("second update must be a tentative correction: %+v", second)
+	}
+	if second.RevisedFromMs != 1000 || second.Text != "три часа дня" {
+		t.Errorf("correction span = %d %q, want 1000 \"три часа дня\"", second.RevisedFromMs, second.Text)
+	}
+	if second.TentativeText != "завтра в три часа дня" {
+		t.Errorf("tentative text = %q, duplicates are not replaced", second.TentativeText)
+	}
+
+	// Повтор хвоста без изменений отправляет только новые слова
+	third, ok := b.apply(ai.StreamingTranscriptionUpdate{Text: "дня мы", TokenTimings: []ai.TranscriptWord{
+		word(2010, "дня"), word(2500, "мы"),
+	}})
+	if !ok || third.Correction || third.Text != "мы" {
+		t.Errorf("third update = %+v", third)
+	}
+
+	// Подтверждение переносит границу, повтор подтверждённого текста не отправляется
+	confirmed, ok := b.apply(ai.StreamingTranscriptionUpdate{Text: "завтра в три часа дня мы", IsConfirmed: true, TokenTimings: []ai.TranscriptWord{
+		word(0, "завтра"), word(500, "в"), word(1000, "три"), word(1500, "часа"), word(2000, "дня"), word(2500, "мы"),
+	}})
+	if !ok || !confirmed.IsConfirmed || confirmed.ConfirmedEndMs != 2900 || confirmed.TentativeText != "" {
+		t.Errorf("confirmed update = %+v", confirmed)
+	}
+	if _, ok := b.apply(ai.StreamingTranscriptionUpdate{Text: "дня мы", TokenTimings: []ai.TranscriptWord{
+		word(2000, "дня"), word(2500, "мы"),
+	}}); ok {
+		t.Error("repeat of confirmed text is sent again")
+	}
+
+	b.reset()
+	if update, _ := b.apply(ai.StreamingTranscriptionUpdate{Text: "новая"}); update.ConfirmedEndMs != 0 || update.Text != "новая" {
+		t.Errorf("after reset = %+v", update)
+	}
+}
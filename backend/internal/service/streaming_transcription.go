@@ -14,6 +14,7 @@ type StreamingTranscriptionService struct {
 	engine   *ai.StreamingFluidASREngine
 	mu       sync.Mutex
 	isActive bool
+	buffer   streamingBuffer // Сверка перекрывающихся чанков: исправления вместо дублей
 
 	// Callback для отправки обновлений в UI
 	OnUpdate func(update StreamingTranscriptionUpdate)
@@ -25,6 +26,14 @@ type StreamingTranscriptionUpdate struct {
 	IsConfirmed bool
 	Confidence  float32
 	Timestamp   time.Time
+
+	// Correction - Text заменяет ранее отправленный неподтверждённый текст начиная с RevisedFromMs
+	Correction    bool
+	RevisedFromMs int64
+	// Граница подтверждённого текста: слова до ConfirmedEndMs окончательные,
+	// TentativeText - весь текущий неподтверждённый хвост
+	ConfirmedEndMs int64
+	TentativeText  string
 }
 
 // NewStreamingTranscriptionService создаёт новый сервис
@@ -77,14 +86,11 @@ func (s *StreamingTranscriptionService) StartWithConfig(cfg StreamingConfig) err
 	}
 
 	// Устанавливаем callback
+	s.buffer.reset()
 	engine.SetUpdateCallback(func(update ai.StreamingTranscriptionUpdate) {
-		if s.OnUpdate != nil {
-			s.OnUpdate(StreamingTranscriptionUpdate{
-				Text:        update.Text,
-				IsConfirmed: update.IsConfirmed,
-				Confidence:  update.Confidence,
-				Timestamp:   update.Timestamp,
-			})
+		result, ok := s.buffer.apply(update)
+		if ok && s.OnUpdate != nil {
+			s.OnUpdate(result)
 		}
 	})
 
@@ -136,6 +142,7 @@ func (s *StreamingTranscriptionService) Reset() error {
 		return nil
 	}
 
+	s.buffer.reset()
 	return engine.Reset()
 }
 
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// MaxConcurrentTranscriptionsLimit верхняя граница параллельных распознаваний:
+// нативные движки сами используют несколько потоков, больше только мешает
+const MaxConcurrentTranscriptionsLimit = 16
+
+// DefaultConcurrentTranscriptions число параллельных распознаваний по умолчанию: половина ядер, от 1 до 4
+func DefaultConcurrentTranscriptions() int {
+	return min(max(runtime.NumCPU()/2, 1), 4)
+}
+
+// transcribeLimiter ограничивает число одновременно распознаваемых чанков.
+// Остальные ждут свободного слота в порядке поступления
+type transcribeLimiter struct {
+	mu    sync.Mutex
+	slots chan struct{}
+}
+
+// SetMaxConcurrentTranscriptions задаёт число параллельных распознаваний (0 - по числу ядер).
+// Действует для записи и всех видов ретранскрипции; уже занятые слоты освобождаются как обычно
+func (s *TranscriptionService) SetMaxConcurrentTranscriptions(n int) error {
+	if n < 0 || n > MaxConcurrentTranscriptionsLimit {
+		return fmt.Errorf("max concurrent transcriptions must be in [0, %d], got %d", MaxConcurrentTranscriptionsLimit, n)
+	}
+	if n == 0 {
+		n = DefaultConcurrentTranscriptions()
+	}
+	s.transcribeLimit.mu.Lock()
+	s.transcribeLimit.slots = make(chan struct{}, n)
+	s.transcribeLimit.mu.Unlock()
+	log.Printf("Max concurrent transcriptions: %d", n)
+	return nil
+}
+
+// MaxConcurrentTranscriptions возвращает текущее ограничение параллельных распознаваний
+func (s *TranscriptionService) MaxConcurrentTranscriptions() int {
+	return cap(s.transcribeSlots())
+}
+
+// transcribeSlots возвращает семафор распознаваний, создавая его по умолчанию
+func (s *TranscriptionService) transcribeSlots() chan struct{} {
+	s.transcribeLimit.mu.Lock()
+	defer s.transcribeLimit.mu.Unlock()
+	if s.transcribeLimit.slots == nil {
+		s.transcribeLimit.slots = make(chan struct{}, DefaultConcurrentTranscriptions())
+	}
+	return s.transcribeLimit.slots
+}
+
+// acquireTranscribeSlot ждёт свободного слота распознавания. Возвращает функцию освобождения
+// или ошибку, если ctx отменён раньше. Освобождается тот же семафор, даже если лимит успели изменить.
+// Если нативный вызов брошен по таймауту или отмене, слот освобождается только после его возврата:
+// иначе зависшие вызовы работали бы сверх лимита
+func (s *TranscriptionService) acquireTranscribeSlot(ctx context.Context) (func(), error) {
+	slots := s.transcribeSlots()
+	select {
+	case slots <- struct{}{}:
+	default:
+		log.Printf("Transcription limit reached (%d), waiting for a free slot", cap(slots))
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+	return func() {
+		if pending := s.abandoned.pending(); pending != nil {
+			go func() {
+				<-pending
+				<-slots
+			}()
+			return
+		}
+		<-slots
+	}, nil
+}
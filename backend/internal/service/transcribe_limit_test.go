@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aiwisper/session"
+)
+
+func TestAcquireTranscribeSlot(t *testing.T) {
+	s := &TranscriptionService{}
+	if got := s.MaxConcurrentTranscriptions(); got != DefaultConcurrentTranscriptions() {
+		t.Errorf("default limit = %d, want %d", got, DefaultConcurrentTranscriptions())
+	}
+	if err := s.SetMaxConcurrentTranscriptions(MaxConcurrentTranscriptionsLimit + 1); err == nil {
+		t.Error("expected error for limit out of range")
+	}
+	if err := s.SetMaxConcurrentTranscriptions(1); err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := s.acquireTranscribeSlot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Второй чанк ждёт, пока первый не освободит слот
+	acquired := make(chan func(), 1)
+	go func() {
+		r, err := s.acquireTranscribeSlot(context.Background())
+		if err == nil {
+			acquired <- r
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second transcription started over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Отменённое ожидание не занимает слот
+	cause := errors.New("superseded")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+	if _, err := s.acquireTranscribeSlot(ctx); !errors.Is(err, cause) {
+		t.Errorf("canceled wait: err = %v, want %v", err, cause)
+	}
+
+	release()
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiting transcription did not start after release")
+	}
+
+	// Смена лимита не ломает освобождение слотов, занятых до неё
+	held, _ := s.acquireTranscribeSlot(context.Background())
+	if err := s.SetMaxConcurrentTranscriptions(2); err != nil {
+		t.Fatal(err)
+	}
+	held()
+	if s.MaxConcurrentTranscriptions() != 2 {
+		t.Errorf("limit = %d, want 2", s.MaxConcurrentTranscriptions())
+	}
+}
+
+func TestAcquireTranscribeSlot_HeldUntilAbandonedCallReturns(t *testing.T) {
+	s := &TranscriptionService{}
+	if err := s.SetMaxConcurrentTranscriptions(1); err != nil {
+		t.Fatal(err)
+	}
+	release, err := s.acquireTranscribeSlot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Чанк завершился по таймауту, но нативный вызов ещё работает
+	done := make(chan struct{})
+	s.abandoned.abandon("chunk 1", done)
+	release()
+
+	acquired := make(chan func(), 1)
+	go func() {
+		r, err := s.acquireTranscribeSlot(context.Background())
+		if err == nil {
+			acquired <- r
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("slot released while abandoned native call is running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(done)
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(2 * time.Second):
+		t.Fatal("slot not released after abandoned call returned")
+	}
+}
+
+func TestProcessStereoFromMP3_CanceledWhileWaitingForSlot(t *testing.T) {
+	sessMgr, err := session.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := sessMgr.CreateSession(session.SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := []*session.Chunk{
+		{ID: "c0", SessionID: sess.ID, Index: 0, Status: session.ChunkStatusPending},
+		{ID: "c1", SessionID: sess.ID, Index: 1, Status: session.ChunkStatusPending},
+	}
+	for _, c := range chunks {
+		if err := sessMgr.AddChunk(sess.ID, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewTranscriptionService(sessMgr, nil)
+	if err := s.SetMaxConcurrentTranscriptions(1); err != nil {
+		t.Fatal(err)
+	}
+	release, _ := s.acquireTranscribeSlot(context.Background())
+	defer release()
+
+	// Отменённый чанк не остаётся в ожидании
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrChunkRetranscribeCanceled)
+	s.processStereoFromMP3(ctx, chunks[0], false)
+	if chunks[0].Status != session.ChunkStatusFailed || chunks[0].Error == "" {
+		t.Errorf("canceled chunk: status = %s, error = %q", chunks[0].Status, chunks[0].Error)
+	}
+
+	// Заменённый более новой ретранскрипцией - не трогается
+	ctx, cancel = context.WithCancelCause(context.Background())
+	cancel(errChunkRetranscribeSuperseded)
+	s.processStereoFromMP3(ctx, chunks[1], false)
+	if chunks[1].Status != session.ChunkStatusPending || chunks[1].Error != "" {
+		t.Errorf("superseded chunk: status = %s, error = %q", chunks[1].Status, chunks[1].Error)
+	}
+}
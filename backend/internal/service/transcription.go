@@ -4,28 +4,60 @@ import (
 	"aiwisper/ai"
 	"aiwisper/session"
 	"aiwisper/voiceprint"
+	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // SessionSpeakerProfile хранит embedding спикера для сессии
 type SessionSpeakerProfile struct {
-	SpeakerID      int       // ID спикера в сессии (1, 2, 3...)
-	Embedding      []float32 // 256-мерный вектор
-	Duration       float32   // Общая длительность речи
-	RecognizedName string    // Имя из глобальной базы voiceprints (если распознан)
-	VoicePrintID   string    // ID voiceprint из глобальной базы (если распознан)
+	SpeakerID      int         // ID спикера в сессии, совпадает с localID (0, 1, 2...)
+	Embedding      []float32   // 256-мерный вектор
+	Duration       float32     // Общая длительность речи
+	RecognizedName string      // Имя из глобальной базы voiceprints (если распознан)
+	VoicePrintID   string      // ID voiceprint из глобальной базы (если распознан)
+	Observations   [][]float32 // Embeddings спикера из отдельных чанков (для оценки стабильности)
+
+	// Сходство при сопоставлении со спикером сессии в каждом чанке
+	Matches []voiceprint.SpeakerChunkMatch
+}
+
+// DefaultTranscribeTimeout таймаут ASR одного канала чанка по умолчанию
+const DefaultTranscribeTimeout = 5 * time.Minute
+
+// DefaultBacklogThreshold число ожидающих чанков, после которого отправляется предупреждение
+const DefaultBacklogThreshold = 3
+
+// QueueStatus состояние очереди транскрипции чанков
+type QueueStatus struct {
+	Pending          int   `json:"pending"`                    // Чанков в обработке и ожидании
+	OldestPendingMs  int64 `json:"oldestPendingMs"`            // Сколько ждёт самый старый чанк
+	OldestChunkIndex int   `json:"oldestChunkIndex,omitempty"` // Индекс самого старого чанка
+	Threshold        int   `json:"threshold"`                  // Порог предупреждения о задержке
+}
+
+// pendingChunk чанк, поставленный в очередь транскрипции
+type pendingChunk struct {
+	sessionID  string
+	index      int
+	enqueuedAt time.Time
 }
 
+// errTranscribeTimeout возвращается, когда нативный движок не ответил за TranscribeTimeout
+var errTranscribeTimeout = errors.New("transcription timeout")
+
 // TranscriptionService handles the core transcription logic
 type TranscriptionService struct {
 	SessionMgr *session.Manager
@@ -36,12 +68,49 @@ type TranscriptionService struct {
 	VADMode   session.VADMode   // auto, compression, per-region, off
 	VADMethod session.VADMethod // energy, silero, auto
 
+	// Отдельные настройки VAD для MIC и SYS каналов (пустой метод - общий VADMethod)
+	MicVAD session.ChannelVADConfig
+	SysVAD session.ChannelVADConfig
+
+	// Таймаут распознавания одного канала чанка (0 - без ограничения).
+	// Защищает очередь чанков от зависаний нативных декодеров
+	TranscribeTimeout time.Duration
+
+	// Нативные вызовы, брошенные по таймауту или отмене: пока они не вернулись,
+	// новые распознавания не запускаются
+	abandoned abandonedCalls
+
+	// Нормализация громкости каналов к целевому RMS перед VAD (опционально)
+	LoudnessNormalization bool
+	LoudnessTargetDBFS    float64
+
+	// Удаление эха: одинаковые фразы, попавшие и в MIC, и в SYS канал
+	CrosstalkDedup CrosstalkDedupConfig
+
+	// Перекрытие соседних чанков: начало чанка захватывает конец предыдущего,
+	// повторно распознанные слова из перекрытия удаляются (0 - без перекрытия)
+	ChunkOverlap time.Duration
+
+	// Контекст между чанками: конец текста предыдущего чанка передаётся движку
+	// как initial prompt (Whisper), не длиннее PromptCarryOverChars символов
+	PromptCarryOver      bool
+	PromptCarryOverChars int
+
+	// Автоопределение перепутанных каналов: решение по сессиям (ключ: sessionID)
+	channelSwapMu sync.Mutex
+	channelSwaps  map[string]bool
+
 	// LLM для автоматического улучшения транскрипции
 	LLMService         *LLMService
 	AutoImproveWithLLM bool   // Автоматически улучшать через LLM после транскрипции
 	OllamaURL          string // URL Ollama API
 	OllamaModel        string // Модель для улучшения
 
+	// Область автоулучшения: каждый чанк или вся сессия после остановки записи
+	AutoImproveScope   AutoImproveScope
+	deferredImprove    map[string]bool // Сессии, ждущие автоулучшения после транскрипции всех чанков (под pendingMu)
+	deferredSwapForget map[string]bool // Остановленные сессии, чьё решение о каналах удаляется после транскрипции всех чанков (под pendingMu)
+
 	// Гибридная транскрипция (двухпроходное распознавание)
 	HybridConfig      *ai.HybridTranscriptionConfig // Конфигурация гибридной транскрипции
 	hybridTranscriber *ai.HybridTranscriber         // Экземпляр гибридного транскрибера
@@ -50,12 +119,44 @@ type TranscriptionService struct {
 	// Сопоставление спикеров между чанками (embeddings)
 	// Ключ: sessionID, значение: map[localSpeakerID]embedding
 	sessionSpeakerProfiles map[string][]SessionSpeakerProfile
+	SpeakerMatchThreshold  float32 // Порог косинусного сходства (0 - DefaultSpeakerMatchThreshold)
 
 	// VoicePrint matcher для автоматического распознавания спикеров из глобальной базы
 	VoicePrintMatcher *voiceprint.Matcher
 
+	// Ограничение параллельных распознаваний (SetMaxConcurrentTranscriptions)
+	transcribeLimit transcribeLimiter
+
+	// Очередь чанков, ожидающих транскрипции (ключ: chunkID)
+	pendingMu        sync.Mutex
+	pendingChunks    map[string]pendingChunk
+	backlogWarned    bool
+	BacklogThreshold int // Порог предупреждения transcription_backlog
+
+	// Отмена ретранскрипции отдельных чанков (retranscribe_chunk)
+	chunkRuns chunkRetranscriptions
+
+	// ClipWarnRatio доля клиппированных сэмплов для предупреждения о перегрузе входа (0 - не проверять)
+	ClipWarnRatio float64
+
+	// Склейка коротких VAD регионов в per-region режиме
+	RegionMerge RegionMergeConfig
+
+	// SilenceGuardDBFS регионы речи тише порога не распознаются (0 - не проверять)
+	SilenceGuardDBFS float64
+
 	// Callbacks for UI updates
 	OnChunkTranscribed func(chunk *session.Chunk)
+	// OnBacklog вызывается при пересечении BacklogThreshold: когда очередь превысила порог
+	// (cleared=false) и когда после предупреждения опустилась до порога (cleared=true)
+	OnBacklog func(status QueueStatus, cleared bool)
+	// OnChunkProgress вызывается по ходу распознавания чанка (прогресс внутри чанка)
+	OnChunkProgress func(progress ChunkProgress)
+	// OnAudioQualityWarning вызывается при проблемах с входным аудио чанка (клиппинг)
+	OnAudioQualityWarning func(warning AudioQualityWarning)
+	// OnSessionImproveStarted и OnSessionImproved вызываются при автоулучшении всей сессии
+	OnSessionImproveStarted func(sessionID string)
+	OnSessionImproved       func(sessionID string, err error)
 }
 
 func NewTranscriptionService(sessionMgr *session.Manager, engineMgr *ai.EngineManager) *TranscriptionService {
@@ -64,6 +165,19 @@ func NewTranscriptionService(sessionMgr *session.Manager, engineMgr *ai.EngineMa
 		EngineMgr:              engineMgr,
 		VADMode:                session.VADModeAuto,   // По умолчанию автовыбор режима
 		VADMethod:              session.VADMethodAuto, // По умолчанию автовыбор метода
+		TranscribeTimeout:      DefaultTranscribeTimeout,
+		CrosstalkDedup:         DefaultCrosstalkDedupConfig(),
+		LoudnessTargetDBFS:     session.DefaultLoudnessTargetDBFS,
+		PromptCarryOverChars:   DefaultPromptCarryOverChars,
+		ClipWarnRatio:          session.DefaultClipRatioThreshold,
+		RegionMerge:            DefaultRegionMergeConfig(),
+		SilenceGuardDBFS:       DefaultSilenceGuardDBFS,
+		SpeakerMatchThreshold:  DefaultSpeakerMatchThreshold,
+		BacklogThreshold:       DefaultBacklogThreshold,
+		pendingChunks:          make(map[string]pendingChunk),
+		AutoImproveScope:       AutoImproveScopeChunk,
+		deferredImprove:        make(map[string]bool),
+		deferredSwapForget:     make(map[string]bool),
 		OllamaURL:              "http://localhost:11434",
 		OllamaModel:            "", // Модель берётся из настроек UI, не хардкодим дефолт
 		sessionSpeakerProfiles: make(map[string][]SessionSpeakerProfile),
@@ -82,10 +196,60 @@ func (s *TranscriptionService) SetVADMethod(method session.VADMethod) {
 	log.Printf("VAD method set to: %s", method)
 }
 
+// SetChannelVADConfig устанавливает отдельные настройки VAD для MIC и SYS каналов.
+// Системный звук (музыка, уведомления) обычно требует более строгого порога, чем близкий микрофон
+func (s *TranscriptionService) SetChannelVADConfig(mic, sys session.ChannelVADConfig) {
+	s.MicVAD = mic
+	s.SysVAD = sys
+	log.Printf("Channel VAD: mic=%+v, sys=%+v", mic, sys)
+}
+
+// channelVADConfig возвращает эффективные настройки VAD для канала ("mic" или "sys")
+func (s *TranscriptionService) channelVADConfig(channel string) session.ChannelVADConfig {
+	config := s.MicVAD
+	if channel == "sys" {
+		config = s.SysVAD
+	}
+	if config.Method == "" {
+		config.Method = s.getEffectiveVADMethod()
+	} else {
+		config.Method = effectiveVADMethod(config.Method)
+	}
+	return config
+}
+
+// SetTranscribeTimeout устанавливает таймаут распознавания одного канала чанка
+func (s *TranscriptionService) SetTranscribeTimeout(timeout time.Duration) {
+	s.TranscribeTimeout = timeout
+	log.Printf("Transcribe timeout set to: %v", timeout)
+}
+
+// SetLoudnessNormalization включает нормализацию громкости каналов к целевому уровню (dBFS RMS)
+func (s *TranscriptionService) SetLoudnessNormalization(enabled bool, targetDBFS float64) {
+	s.LoudnessNormalization = enabled
+	s.LoudnessTargetDBFS = targetDBFS
+	log.Printf("Loudness normalization: enabled=%v, target=%.1f dBFS", enabled, targetDBFS)
+}
+
+// normalizeChannelLoudness выравнивает громкость канала, если нормализация включена
+func (s *TranscriptionService) normalizeChannelLoudness(samples []float32, channel string) []float32 {
+	if !s.LoudnessNormalization {
+		return samples
+	}
+	normalized, gainDB := session.NormalizeLoudness(samples, 16000, s.LoudnessTargetDBFS)
+	log.Printf("Loudness normalization: %s channel gain %+.1f dB (target %.1f dBFS)", channel, gainDB, s.LoudnessTargetDBFS)
+	return normalized
+}
+
 // getEffectiveVADMethod возвращает эффективный метод VAD
 // При auto пытается использовать Silero если модель доступна
 func (s *TranscriptionService) getEffectiveVADMethod() session.VADMethod {
-	switch s.VADMethod {
+	return effectiveVADMethod(s.VADMethod)
+}
+
+// effectiveVADMethod приводит выбранный метод VAD к фактически используемому
+func effectiveVADMethod(method session.VADMethod) session.VADMethod {
+	switch method {
 	case session.VADMethodSilero:
 		return session.VADMethodSilero
 	case session.VADMethodEnergy:
@@ -213,16 +377,13 @@ func (s *TranscriptionService) SetHybridConfig(config *ai.HybridTranscriptionCon
 	// Передаём hotwords в движки (для Whisper это initial prompt)
 	if len(config.Hotwords) > 0 {
 		log.Printf("[SetHybridConfig] Setting hotwords (%d words) on engines", len(config.Hotwords))
-		primaryEngine := s.EngineMgr.GetActiveEngine()
-		if primaryEngine != nil {
-			primaryEngine.SetHotwords(config.Hotwords)
-		}
+		s.EngineMgr.SetHotwords(config.Hotwords)
 		secondaryEngine.SetHotwords(config.Hotwords)
 	}
 
 	// Создаём HybridTranscriber
 	s.hybridTranscriber = ai.NewHybridTranscriber(
-		s.EngineMgr.GetActiveEngine(),
+		s.EngineMgr.Engine(),
 		secondaryEngine,
 		*config,
 		llmSelector,
@@ -254,6 +415,13 @@ func (a *llmSelectorAdapter) SelectBestTranscription(original, alternative, cont
 // Если гибридная транскрипция включена - использует HybridTranscriber
 // Иначе - обычную транскрипцию через EngineMgr
 func (s *TranscriptionService) transcribeWithHybrid(samples []float32) ([]ai.TranscriptSegment, error) {
+	return s.transcribeWithHybridProgress(samples, "", nil)
+}
+
+// transcribeWithHybridProgress как transcribeWithHybrid, но передаёт прогресс движка в onProgress.
+// previousText - контекст предыдущего чанка (см. previousChunkPrompt), пусто - без контекста.
+// Гибридный режим прогресс внутри вызова не сообщает и контекст не использует
+func (s *TranscriptionService) transcribeWithHybridProgress(samples []float32, previousText string, onProgress ai.ProgressFunc) ([]ai.TranscriptSegment, error) {
 	// Детальное логирование состояния гибридной транскрипции
 	log.Printf("[transcribeWithHybrid] Checking hybrid state: HybridConfig=%v, hybridTranscriber=%v",
 		s.HybridConfig != nil, s.hybridTranscriber != nil)
@@ -280,7 +448,124 @@ func (s *TranscriptionService) transcribeWithHybrid(samples []float32) ([]ai.Tra
 	}
 
 	log.Printf("[transcribeWithHybrid] Hybrid disabled, using standard transcription")
-	return s.EngineMgr.TranscribeWithSegments(samples)
+	return s.EngineMgr.TranscribeWithPrompt(samples, previousText, onProgress)
+}
+
+// abandonedCalls учитывает нативные вызовы, которые перестали ждать, но которые ещё не вернулись.
+// Второй вызов в зависший движок только добавит зависших потоков, поэтому новые
+// распознавания ждут их возврата
+type abandonedCalls struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // Закрывается, когда брошенных вызовов не осталось
+}
+
+// abandon учитывает брошенный вызов до закрытия done
+func (a *abandonedCalls) abandon(target string, done <-chan struct{}) {
+	a.mu.Lock()
+	if a.n == 0 {
+		a.idle = make(chan struct{})
+	}
+	a.n++
+	a.mu.Unlock()
+
+	go func() {
+		<-done
+		log.Printf("Abandoned transcription of %s returned", target)
+		a.mu.Lock()
+		a.n--
+		if a.n == 0 {
+			close(a.idle)
+		}
+		a.mu.Unlock()
+	}()
+}
+
+// pending возвращает канал, который закроется после возврата всех брошенных вызовов,
+// или nil, если таких нет
+func (a *abandonedCalls) pending() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.n == 0 {
+		return nil
+	}
+	return a.idle
+}
+
+// waitAbandoned ждёт возврата брошенных нативных вызовов перед запуском нового.
+// Ожидание входит в таймаут распознавания: если движок так и не освободился, фрагмент
+// помечается ошибкой таймаута, а не висит в очереди
+func (s *TranscriptionService) waitAbandoned(ctx context.Context, target string, timeout <-chan time.Time) error {
+	idle := s.abandoned.pending()
+	if idle == nil {
+		return nil
+	}
+	log.Printf("Transcription of %s waits for an abandoned native call", target)
+	select {
+	case <-idle:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("%w: engine is still busy with an abandoned call", errTranscribeTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transcribeWithTimeout выполняет ASR-вызов с таймаутом TranscribeTimeout.
+// Зависший нативный вызов продолжает работать в фоне, чанк помечается ошибкой,
+// а следующие распознавания ждут его возврата (waitAbandoned)
+// target - описание распознаваемого фрагмента для лога (например, "chunk 3").
+// Отмена ctx (ретранскрипция чанка отменена или заменена) тоже прекращает ожидание
+func (s *TranscriptionService) transcribeWithTimeout(ctx context.Context, target, channel string, transcribe func() ([]ai.TranscriptSegment, error)) ([]ai.TranscriptSegment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if s.TranscribeTimeout > 0 {
+		timer := time.NewTimer(s.TranscribeTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	if err := s.waitAbandoned(ctx, target, timeout); err != nil {
+		log.Printf("Transcription of %s (%s channel) not started: %v", target, channel, err)
+		return nil, err
+	}
+	if timeout == nil && ctx.Done() == nil {
+		return transcribe()
+	}
+
+	type res struct {
+		segments []ai.TranscriptSegment
+		err      error
+	}
+
+	ch := make(chan res, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		segments, err := transcribe()
+		ch <- res{segments: segments, err: err}
+	}()
+
+	select {
+	case out := <-ch:
+		return out.segments, out.err
+	case <-timeout:
+		log.Printf("Transcription timeout for %s (%s channel) after %v", target, channel, s.TranscribeTimeout)
+		s.abandoned.abandon(target, done)
+		return nil, fmt.Errorf("%w after %v (%s channel)", errTranscribeTimeout, s.TranscribeTimeout, channel)
+	case <-ctx.Done():
+		log.Printf("Transcription of %s (%s channel) canceled", target, channel)
+		s.abandoned.abandon(target, done)
+		return nil, ctx.Err()
+	}
+}
+
+// chunkLabel возвращает описание чанка для логов
+func chunkLabel(chunk *session.Chunk) string {
+	return fmt.Sprintf("chunk %d", chunk.Index)
 }
 
 // applyHybridToPipelineResult применяет гибридную транскрипцию к результату Pipeline
@@ -433,26 +718,75 @@ func (s *TranscriptionService) EnableDiarizationWithProvider(segmentationPath, e
 // provider: "auto", "cpu", "coreml", "cuda" (только для Sherpa)
 // backend: "sherpa" (ONNX), "fluid" (FluidAudio/CoreML - рекомендуется для macOS)
 func (s *TranscriptionService) EnableDiarizationWithBackend(segmentationPath, embeddingPath, provider, backend string) error {
+	return s.EnableDiarizationWithConsolidation(segmentationPath, embeddingPath, provider, backend, 0, 0)
+}
+
+// EnableDiarizationWithConsolidation включает диаризацию с параметрами консолидации спикеров:
+// minSpeakerRatio - мин. доля речи спикера в чанке, minSegmentSec - мин. длительность сегмента (0 - по умолчанию)
+func (s *TranscriptionService) EnableDiarizationWithConsolidation(segmentationPath, embeddingPath, provider, backend string, minSpeakerRatio, minSegmentSec float32) error {
+	return s.EnableDiarizationWithParams(segmentationPath, embeddingPath, provider, backend,
+		DiarizationParams{MinSpeakerRatio: minSpeakerRatio, MinSpeakerSegmentSec: minSegmentSec})
+}
+
+// DiarizationParams настраиваемые параметры диаризации (0 - значение по умолчанию)
+type DiarizationParams struct {
+	ClusteringThreshold  float32 // Порог кластеризации (0.1-0.95): ниже - похожие голоса разделяются, выше - объединяются
+	MinDurationOn        float32 // Мин. длительность речи (сек)
+	MinDurationOff       float32 // Мин. длительность паузы (сек)
+	MinSpeakerRatio      float32 // Мин. доля речи спикера в чанке
+	MinSpeakerSegmentSec float32 // Мин. длительность сегмента диаризации (сек)
+	MinOverlapRatio      float32 // Мин. доля слова под каждым из двух спикеров для отметки наложения речи
+}
+
+// pipelineConfig конфигурация пайплайна по умолчанию с заданными параметрами диаризации
+func (p DiarizationParams) pipelineConfig() ai.PipelineConfig {
+	config := ai.DefaultPipelineConfig()
+	if p.ClusteringThreshold != 0 {
+		config.ClusteringThreshold = p.ClusteringThreshold
+	}
+	if p.MinDurationOn != 0 {
+		config.MinDurationOn = p.MinDurationOn
+	}
+	if p.MinDurationOff != 0 {
+		config.MinDurationOff = p.MinDurationOff
+	}
+	config.MinSpeakerRatio = p.MinSpeakerRatio
+	config.MinSpeakerSegmentSec = p.MinSpeakerSegmentSec
+	config.MinOverlapRatio = p.MinOverlapRatio
+	return config
+}
+
+// Validate проверяет параметры диаризации
+func (p DiarizationParams) Validate() error {
+	config := p.pipelineConfig()
+	if err := config.ValidateClustering(); err != nil {
+		return err
+	}
+	return config.ValidateSpeakerConsolidation()
+}
+
+// EnableDiarizationWithParams включает диаризацию с параметрами кластеризации и консолидации спикеров
+func (s *TranscriptionService) EnableDiarizationWithParams(segmentationPath, embeddingPath, provider, backend string, params DiarizationParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
 	if s.EngineMgr == nil {
 		return fmt.Errorf("engine manager is required")
 	}
 
-	engine := s.EngineMgr.GetActiveEngine()
-	if engine == nil {
+	// Pipeline хранит движок долго - передаём управляемый, чтобы выгрузка по простою
+	// и смена модели не оставили ему закрытый движок
+	if !s.EngineMgr.HasActiveEngine() {
 		return fmt.Errorf("no active transcription engine")
 	}
+	engine := s.EngineMgr.Engine()
 
-	config := ai.PipelineConfig{
-		EnableDiarization:     true,
-		SegmentationModelPath: segmentationPath,
-		EmbeddingModelPath:    embeddingPath,
-		ClusteringThreshold:   0.5,
-		MinDurationOn:         0.3,
-		MinDurationOff:        0.5,
-		NumThreads:            4,
-		Provider:              provider, // "auto" = автоопределение (для Sherpa)
-		DiarizationBackend:    backend,  // "sherpa" или "fluid"
-	}
+	config := params.pipelineConfig()
+	config.EnableDiarization = true
+	config.SegmentationModelPath = segmentationPath
+	config.EmbeddingModelPath = embeddingPath
+	config.Provider = provider          // "auto" = автоопределение (для Sherpa)
+	config.DiarizationBackend = backend // "sherpa" или "fluid"
 
 	pipeline, err := ai.NewAudioPipeline(engine, config)
 	if err != nil {
@@ -468,9 +802,38 @@ func (s *TranscriptionService) EnableDiarizationWithBackend(segmentationPath, em
 	actualProvider := pipeline.GetDiarizationProvider()
 	log.Printf("Diarization enabled: backend=%s, provider=%s, segmentation=%s, embedding=%s",
 		backend, actualProvider, segmentationPath, embeddingPath)
+	log.Printf("Diarization clustering: threshold=%.2f, minDurationOn=%.2fs, minDurationOff=%.2fs",
+		config.ClusteringThreshold, config.MinDurationOn, config.MinDurationOff)
+	log.Printf("Diarization speaker consolidation: minSpeakerRatio=%.3f, minSegment=%.2fs, minOverlap=%.2f",
+		config.EffectiveMinSpeakerRatio(), config.EffectiveMinSpeakerSegmentSec(), config.EffectiveMinOverlapRatio())
 	return nil
 }
 
+// GetDiarizationParams возвращает действующие параметры диаризации (по умолчанию, если диаризация не включена)
+func (s *TranscriptionService) GetDiarizationParams() DiarizationParams {
+	config := ai.DefaultPipelineConfig()
+	if s.Pipeline != nil {
+		config = s.Pipeline.GetConfig()
+	}
+	return DiarizationParams{
+		ClusteringThreshold:  config.ClusteringThreshold,
+		MinDurationOn:        config.MinDurationOn,
+		MinDurationOff:       config.MinDurationOff,
+		MinSpeakerRatio:      config.EffectiveMinSpeakerRatio(),
+		MinSpeakerSegmentSec: config.EffectiveMinSpeakerSegmentSec(),
+		MinOverlapRatio:      config.EffectiveMinOverlapRatio(),
+	}
+}
+
+// speakerConsolidation возвращает параметры консолидации спикеров и отметки наложения речи активного пайплайна
+func (s *TranscriptionService) speakerConsolidation() (minSpeakerRatio, minSegmentSec, minOverlapRatio float32) {
+	config := ai.DefaultPipelineConfig()
+	if s.Pipeline != nil {
+		config = s.Pipeline.GetConfig()
+	}
+	return config.EffectiveMinSpeakerRatio(), config.EffectiveMinSpeakerSegmentSec(), config.EffectiveMinOverlapRatio()
+}
+
 // DisableDiarization отключает диаризацию
 func (s *TranscriptionService) DisableDiarization() {
 	if s.Pipeline != nil {
@@ -509,18 +872,94 @@ func (s *TranscriptionService) HandleChunk(chunk *session.Chunk) {
 	}
 
 	sessID := chunk.SessionID
+	s.enqueueChunk(chunk)
 
 	// Process asynchronously
 	go func() {
+		defer s.dequeueChunk(chunk)
+
 		log.Printf("Starting transcription for chunk %d (session %s), isStereo=%v",
 			chunk.Index, sessID, chunk.IsStereo)
 
 		// Всегда пробуем стерео обработку. Если файл моно или каналы идентичны,
 		// processStereoFromMP3 автоматически переключится на моно режим (с включенной диаризацией).
-		s.processStereoFromMP3(chunk, true)
+		s.processStereoFromMP3(context.Background(), chunk, true)
 	}()
 }
 
+// GetQueueStatus возвращает число ожидающих чанков и возраст самого старого
+func (s *TranscriptionService) GetQueueStatus() QueueStatus {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return s.queueStatusLocked()
+}
+
+func (s *TranscriptionService) queueStatusLocked() QueueStatus {
+	status := QueueStatus{Pending: len(s.pendingChunks), Threshold: s.BacklogThreshold}
+	var oldest time.Time
+	for _, p := range s.pendingChunks {
+		if oldest.IsZero() || p.enqueuedAt.Before(oldest) {
+			oldest = p.enqueuedAt
+			status.OldestChunkIndex = p.index
+		}
+	}
+	if !oldest.IsZero() {
+		status.OldestPendingMs = time.Since(oldest).Milliseconds()
+	}
+	return status
+}
+
+// enqueueChunk учитывает чанк в очереди и предупреждает о накоплении отставания.
+// Предупреждение отправляется один раз, когда очередь превысила порог, а не на каждый чанк
+func (s *TranscriptionService) enqueueChunk(chunk *session.Chunk) {
+	s.pendingMu.Lock()
+	s.pendingChunks[chunk.ID] = pendingChunk{sessionID: chunk.SessionID, index: chunk.Index, enqueuedAt: time.Now()}
+	status := s.queueStatusLocked()
+	warn := !s.backlogWarned && s.BacklogThreshold > 0 && status.Pending > s.BacklogThreshold
+	if warn {
+		s.backlogWarned = true
+	}
+	s.pendingMu.Unlock()
+
+	if warn {
+		log.Printf("Transcription backlog: %d chunks pending, oldest chunk %d waiting %dms",
+			status.Pending, status.OldestChunkIndex, status.OldestPendingMs)
+		if s.OnBacklog != nil {
+			s.OnBacklog(status, false)
+		}
+	}
+}
+
+// dequeueChunk убирает обработанный чанк из очереди. Отбой предупреждения - когда очередь
+// опустилась до порога
+func (s *TranscriptionService) dequeueChunk(chunk *session.Chunk) {
+	s.pendingMu.Lock()
+	delete(s.pendingChunks, chunk.ID)
+	status := s.queueStatusLocked()
+	cleared := s.backlogWarned && status.Pending <= s.BacklogThreshold
+	if cleared {
+		s.backlogWarned = false
+	}
+	improveSession := s.takeDeferredImproveLocked(chunk.SessionID)
+	forgetSwap := s.takeDeferredSwapForgetLocked(chunk.SessionID)
+	s.pendingMu.Unlock()
+
+	if forgetSwap {
+		s.dropChannelSwap(chunk.SessionID)
+	}
+
+	if improveSession {
+		go s.autoImproveSession(chunk.SessionID)
+	}
+
+	if cleared {
+		log.Printf("Transcription backlog cleared")
+		if s.OnBacklog != nil {
+			s.OnBacklog(status, true)
+		}
+	}
+}
+
 // HandleChunkSync processes a chunk synchronously (for retranscription)
 func (s *TranscriptionService) HandleChunkSync(chunk *session.Chunk) {
 	s.HandleChunkSyncWithDiarization(chunk, true) // По умолчанию используем диаризацию если включена
@@ -537,52 +976,82 @@ func (s *TranscriptionService) HandleChunkSyncWithDiarization(chunk *session.Chu
 		chunk.Index, chunk.SessionID, chunk.IsStereo, useDiarization)
 
 	// Всегда пробуем стерео обработку, передавая флаг диаризации для fallback случая
-	s.processStereoFromMP3(chunk, useDiarization)
+	s.processStereoFromMP3(context.Background(), chunk, useDiarization)
 }
 
 // processStereoFromMP3 extracts stereo channels from full.mp3 and transcribes:
 // - MIC channel (left): always "Вы" - single speaker, no diarization needed
 // - SYS channel (right): diarization to identify multiple speakers (Собеседник 1, 2, 3...)
 // Results are merged by timestamps into a dialogue
-func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDiarizationFallback bool) {
+func (s *TranscriptionService) processStereoFromMP3(ctx context.Context, chunk *session.Chunk, useDiarizationFallback bool) {
+	// Перекрытие объединяется с готовым предыдущим чанком; ждём до занятия слота
+	s.waitPreviousChunk(ctx, chunk)
+
+	// Чанки сверх лимита ждут здесь, время ожидания не входит во время обработки
+	release, err := s.acquireTranscribeSlot(ctx)
+	if err != nil {
+		log.Printf("Chunk %d: canceled while waiting for a transcription slot (%v)", chunk.Index, err)
+		// Заменённую ретранскрипцию завершит новая, иначе чанк не должен остаться в ожидании
+		if !errors.Is(err, errChunkRetranscribeSuperseded) {
+			s.SessionMgr.MarkChunkCanceled(chunk.SessionID, chunk.ID, err)
+		}
+		return
+	}
+	defer release()
+
 	// Засекаем время начала обработки
 	startTime := time.Now()
 	chunk.ProcessingStartTime = &startTime
+	s.SessionMgr.MarkChunkProcessingStarted(chunk.SessionID, chunk.ID, startTime)
+	s.SessionMgr.MarkChunkModel(chunk.SessionID, chunk.ID, s.EngineMgr.GetActiveModelID())
 
 	// Get session to find MP3 path
 	sess, err := s.SessionMgr.GetSession(chunk.SessionID)
 	if err != nil {
 		log.Printf("Failed to get session: %v", err)
-		s.SessionMgr.UpdateChunkStereoWithSegments(chunk.SessionID, chunk.ID, "", "", nil, nil, err)
+		s.saveChunkStereo(ctx, chunk, "", "", nil, nil, err)
+		return
+	}
+
+	if sess.MicOnly {
+		s.processMicOnlyFromMP3(ctx, chunk, sess)
 		return
 	}
 
 	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	extractStart := s.chunkExtractStart(chunk)
 
-	log.Printf("Extracting stereo segment (pure Go): %s (start=%dms, end=%dms)", mp3Path, chunk.StartMs, chunk.EndMs)
+	log.Printf("Extracting stereo segment (pure Go): %s (start=%dms, end=%dms, overlap=%dms)",
+		mp3Path, chunk.StartMs, chunk.EndMs, chunk.StartMs-extractStart)
 
 	// Используем чистый Go декодер MP3 (без FFmpeg!)
-	micSamples, sysSamples, err := session.ExtractSegmentStereoGo(mp3Path, chunk.StartMs, chunk.EndMs, 16000)
+	micSamples, sysSamples, err := session.ExtractSegmentStereoGo(mp3Path, extractStart, chunk.EndMs, 16000)
 	if err != nil {
 		log.Printf("Failed to extract stereo segment: %v, falling back to mono", err)
-		s.processMonoFromMP3Impl(chunk, useDiarizationFallback)
+		s.processMonoFromMP3Impl(ctx, chunk, useDiarizationFallback)
 		return
 	}
 
 	// Проверяем что есть данные хотя бы в одном канале
 	if len(micSamples) == 0 && len(sysSamples) == 0 {
 		log.Printf("Both channels empty, falling back to mono extraction")
-		s.processMonoFromMP3Impl(chunk, useDiarizationFallback)
+		s.processMonoFromMP3Impl(ctx, chunk, useDiarizationFallback)
 		return
 	}
 
 	// Проверяем на дублированное моно (когда каналы идентичны)
 	if areChannelsSimilar(micSamples, sysSamples) {
 		log.Printf("Channels are similar (duplicated mono), falling back to mono processing")
-		s.processMonoFromMP3Impl(chunk, useDiarizationFallback)
+		s.processMonoFromMP3Impl(ctx, chunk, useDiarizationFallback)
 		return
 	}
 
+	// Некоторые конфигурации захвата пишут микрофон в правый канал
+	micSamples, sysSamples = s.orientStereoChannels(sess, micSamples, sysSamples, 16000)
+
+	// Клиппинг проверяем до фильтров: нормализация скрывает перегруз
+	s.checkChunkClipping(chunk, extractStart, channelSamples{"mic", micSamples}, channelSamples{"sys", sysSamples})
+
 	log.Printf("Loaded samples: mic=%d (%.1fs), sys=%d (%.1fs)",
 		len(micSamples), float64(len(micSamples))/16000,
 		len(sysSamples), float64(len(sysSamples))/16000)
@@ -593,28 +1062,59 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 	micSamples = session.FilterChannelForTranscription(micSamples, 16000)
 	sysSamples = session.FilterChannelForTranscription(sysSamples, 16000)
 
+	// Выравниваем громкость каналов, чтобы тихий микрофон не проигрывал громкому системному звуку
+	micSamples = s.normalizeChannelLoudness(micSamples, "mic")
+	sysSamples = s.normalizeChannelLoudness(sysSamples, "sys")
+
 	var micText, sysText string
 	var micSegments, sysSegments []ai.TranscriptSegment
 	var micErr, sysErr error
 
 	// 1. VAD preprocessing: определяем регионы речи
 	// Используем выбранный метод детекции (energy, silero, auto)
-	vadMethod := s.getEffectiveVADMethod()
-	micRegions := session.DetectSpeechRegionsWithMethod(micSamples, 16000, vadMethod)
-	sysRegions := session.DetectSpeechRegionsWithMethod(sysSamples, 16000, vadMethod)
-
-	log.Printf("VAD: mic %d regions, sys %d regions (method: %s)", len(micRegions), len(sysRegions), vadMethod)
+	// Для каждого канала - свой метод и порог (если заданы)
+	micVAD := s.channelVADConfig("mic")
+	sysVAD := s.channelVADConfig("sys")
+	micRegions, micMethod := session.DetectSpeechRegionsForChannel(micSamples, 16000, micVAD)
+	sysRegions, sysMethod := session.DetectSpeechRegionsForChannel(sysSamples, 16000, sysVAD)
+	micRegions = s.dropSilentRegions(chunkLabel(chunk), "mic", micSamples, micRegions, 16000)
+	sysRegions = s.dropSilentRegions(chunkLabel(chunk), "sys", sysSamples, sysRegions, 16000)
+
+	log.Printf("VAD: mic %d regions (method: %s -> %s, threshold: %.3f), sys %d regions (method: %s -> %s, threshold: %.3f)",
+		len(micRegions), micVAD.Method, micMethod, micVAD.Threshold, len(sysRegions), sysVAD.Method, sysMethod, sysVAD.Threshold)
+
+	// Запоминаем фактический метод: откат Silero -> energy иначе виден только в логах
+	vadFallback := micMethod != micVAD.Method || sysMethod != sysVAD.Method
+	if vadFallback {
+		log.Printf("VAD fallback in chunk %d: mic %s -> %s, sys %s -> %s", chunk.Index, micVAD.Method, micMethod, sysVAD.Method, sysMethod)
+	}
+	s.SessionMgr.MarkChunkVADMethods(chunk.SessionID, chunk.ID, micMethod, sysMethod, vadFallback)
+	audioMs := int64(max(len(micSamples), len(sysSamples))) * 1000 / 16000
+	s.SessionMgr.MarkChunkSpeechRatio(chunk.SessionID, chunk.ID, session.SpeechCoverage(audioMs, micRegions, sysRegions))
 
 	// Определяем использовать ли per-region транскрипцию
 	usePerRegion := s.shouldUsePerRegion()
 	log.Printf("VAD mode: %s, usePerRegion: %v", s.VADMode, usePerRegion)
+	s.SessionMgr.MarkChunkTranscriptionPath(chunk.SessionID, chunk.ID, vadTranscriptionPath(usePerRegion), s.IsHybridEnabled())
+
+	// Прогресс внутри чанка: доли каналов пропорциональны длительности речи
+	var work []channelWork
+	if len(micRegions) > 0 {
+		work = append(work, channelWork{"mic", speechDurationMs(micRegions)})
+	}
+	if len(sysRegions) > 0 {
+		work = append(work, channelWork{"sys", speechDurationMs(sysRegions)})
+	}
+	progress := s.newChunkProgress(chunk, work...)
 
 	// 2. Transcribe MIC channel - always "Вы" (single speaker, no diarization)
 	if len(micRegions) > 0 {
 		if usePerRegion {
 			// Per-region: транскрибируем каждый регион отдельно
 			log.Printf("Transcribing MIC channel (Вы) with per-region: %d regions", len(micRegions))
-			micSegments, micErr = s.transcribeRegionsSeparately(micSamples, micRegions, 16000)
+			micSegments, micErr = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "mic", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeRegionsSeparately(micSamples, micRegions, 16000, progress.regionProgress("mic"))
+			})
 		} else {
 			// Compression: используем VAD compression (склеиваем регионы)
 			micCompressed := session.CompressSpeechFromRegions(micSamples, micRegions, 16000)
@@ -623,13 +1123,28 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 				float64(len(micCompressed.CompressedSamples))/16000,
 				float64(len(micSamples))/16000)
 
-			micSegments, micErr = s.transcribeWithHybrid(micCompressed.CompressedSamples)
+			micSegments, micErr = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "mic", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeWithHybridProgress(micCompressed.CompressedSamples, s.previousChunkPrompt(chunk, "mic"), progress.engineProgress("mic"))
+			})
 			if micErr == nil {
 				// Восстанавливаем оригинальные timestamps
 				micSegments = restoreAISegmentTimestamps(micSegments, micCompressed.Regions)
 			}
 		}
 
+		// Диаризация MIC канала, если за одним микрофоном несколько человек
+		if micErr == nil && sess.DiarizeMic && s.Pipeline != nil && s.Pipeline.IsDiarizationEnabled() {
+			log.Printf("Running diarization on MIC audio (%.1f sec): session has diarizeMic enabled",
+				float64(len(micSamples))/16000)
+			diarResult, diarErr := s.Pipeline.DiarizeMicOnly(micSamples)
+			if diarErr != nil {
+				log.Printf("MIC diarization error: %v, keeping single speaker", diarErr)
+			} else if diarResult.NumSpeakers > 1 {
+				minRatio, minSegment, minOverlap := s.speakerConsolidation()
+				micSegments = applySpeakersToTranscriptSegments(micSegments, diarResult.SpeakerSegments, minRatio, minSegment, minOverlap)
+			}
+		}
+
 		if micErr != nil {
 			log.Printf("MIC transcription error: %v", micErr)
 		} else {
@@ -642,12 +1157,21 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 		}
 	}
 
+	// Движок завис на MIC канале - SYS почти наверняка упрётся в тот же вызов,
+	// поэтому сразу помечаем чанк ошибкой и освобождаем очередь
+	if errors.Is(micErr, errTranscribeTimeout) {
+		s.saveChunkStereo(ctx, chunk, "", "", nil, nil, micErr)
+		return
+	}
+
 	// 3. Transcribe SYS channel WITH DIARIZATION (multiple speakers possible)
 	if len(sysRegions) > 0 {
 		if usePerRegion {
 			// Per-region: транскрибируем каждый регион отдельно
 			log.Printf("Transcribing SYS channel with per-region: %d regions", len(sysRegions))
-			sysSegments, sysErr = s.transcribeRegionsSeparately(sysSamples, sysRegions, 16000)
+			sysSegments, sysErr = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "sys", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeRegionsSeparately(sysSamples, sysRegions, 16000, progress.regionProgress("sys"))
+			})
 
 			// Применяем диаризацию если включена (на сжатом аудио для экономии ресурсов)
 			if sysErr == nil && s.Pipeline != nil && s.Pipeline.IsDiarizationEnabled() {
@@ -666,7 +1190,9 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 			diarizationEnabled := s.Pipeline != nil && s.Pipeline.IsDiarizationEnabled()
 
 			// 1. Транскрипция на сжатом аудио (быстрее) - с поддержкой гибридного режима
-			sysSegments, sysErr = s.transcribeWithHybrid(sysCompressed.CompressedSamples)
+			sysSegments, sysErr = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "sys", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeWithHybridProgress(sysCompressed.CompressedSamples, s.previousChunkPrompt(chunk, "sys"), progress.engineProgress("sys"))
+			})
 			if sysErr == nil {
 				// Восстанавливаем оригинальные timestamps СРАЗУ
 				sysSegments = restoreAISegmentTimestamps(sysSegments, sysCompressed.Regions)
@@ -693,7 +1219,7 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 
 					// 2.5. Сопоставляем спикеров с предыдущими чанками по embeddings
 					if len(diarResult.SpeakerEmbeddings) > 0 {
-						speakerMapping := s.matchSpeakersWithSession(chunk.SessionID, diarResult.SpeakerEmbeddings)
+						speakerMapping := s.matchSpeakersWithSession(chunk.SessionID, chunk.Index, diarResult.SpeakerEmbeddings)
 						if len(speakerMapping) > 0 {
 							// Применяем маппинг к сегментам
 							diarResult.SpeakerSegments = s.remapSpeakerSegments(diarResult.SpeakerSegments, speakerMapping)
@@ -702,7 +1228,8 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 					}
 
 					// 3. Применяем спикеров к сегментам транскрипции
-					sysSegments = applySpeakersToTranscriptSegments(sysSegments, diarResult.SpeakerSegments)
+					minRatio, minSegment, minOverlap := s.speakerConsolidation()
+					sysSegments = applySpeakersToTranscriptSegments(sysSegments, diarResult.SpeakerSegments, minRatio, minSegment, minOverlap)
 				}
 			}
 		}
@@ -724,24 +1251,116 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 	var finalErr error
 	if micErr != nil && sysErr != nil {
 		finalErr = fmt.Errorf("mic: %v, sys: %v", micErr, sysErr)
+	} else if errors.Is(sysErr, errTranscribeTimeout) {
+		// Таймаут считаем ошибкой чанка, чтобы его можно было перераспознать
+		finalErr = sysErr
 	}
 
 	// 3. Apply global offset and set speakers
-	log.Printf("Applying global chunk offset: %d ms to all segments", chunk.StartMs)
+	log.Printf("Applying global chunk offset: %d ms to all segments", extractStart)
 
-	// MIC segments: speaker = "Вы"
-	sessionMicSegs := convertSegmentsWithGlobalOffset(micSegments, "Вы", chunk.StartMs)
+	// MIC segments: speaker = "Вы" (или "Вы N" при диаризации MIC канала)
+	sessionMicSegs := convertMicSegmentsWithDiarization(micSegments, extractStart)
 
 	// SYS segments: speakers from diarization ("Speaker 0" -> "Собеседник 1", etc.)
 	// or "Собеседник" if no diarization
-	sessionSysSegs := convertSysSegmentsWithDiarization(sysSegments, chunk.StartMs)
+	sessionSysSegs := convertSysSegmentsWithDiarization(sysSegments, extractStart)
+
+	// Объединяем перекрытие с предыдущим чанком
+	if extractStart < chunk.StartMs {
+		sessionMicSegs = s.trimChunkOverlap(ctx, chunk, "mic", sessionMicSegs)
+		sessionSysSegs = s.trimChunkOverlap(ctx, chunk, "sys", sessionSysSegs)
+		micText = session.JoinSegmentsText(sessionMicSegs)
+		sysText = session.JoinSegmentsText(sessionSysSegs)
+	}
+
+	// Убираем эхо: фразы, распознанные в обоих каналах
+	var dropped int
+	sessionMicSegs, sessionSysSegs, dropped = dedupeCrosstalk(sessionMicSegs, sessionSysSegs, s.CrosstalkDedup)
+	if dropped > 0 {
+		log.Printf("Crosstalk dedup for chunk %d: dropped %d echo segments", chunk.Index, dropped)
+		micText = session.JoinSegmentsText(sessionMicSegs)
+		sysText = session.JoinSegmentsText(sessionSysSegs)
+	}
 
-	s.SessionMgr.UpdateChunkStereoWithSegments(chunk.SessionID, chunk.ID, micText, sysText, sessionMicSegs, sessionSysSegs, finalErr)
+	s.saveChunkStereo(ctx, chunk, micText, sysText, sessionMicSegs, sessionSysSegs, finalErr)
 
 	log.Printf("Stereo transcription complete for chunk %d", chunk.Index)
 
 	// 4. Автоулучшение через LLM если включено
-	if s.AutoImproveWithLLM && s.LLMService != nil && finalErr == nil {
+	if s.AutoImproveWithLLM && s.LLMService != nil && finalErr == nil && s.AutoImproveScope != AutoImproveScopeSession {
+		s.autoImproveChunk(chunk)
+	}
+}
+
+// vadTranscriptionPath способ распознавания каналов с VAD
+func vadTranscriptionPath(usePerRegion bool) session.TranscriptionPath {
+	if usePerRegion {
+		return session.TranscriptionPathPerRegion
+	}
+	return session.TranscriptionPathCompression
+}
+
+// processMicOnlyFromMP3 распознаёт чанк сессии MicOnly (диктовка): только MIC канал,
+// без SYS канала, диаризации и удаления эха. Весь текст принадлежит "Вы"
+func (s *TranscriptionService) processMicOnlyFromMP3(ctx context.Context, chunk *session.Chunk, sess *session.Session) {
+	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	extractStart := s.chunkExtractStart(chunk)
+
+	// MIC всегда в левом канале: запись диктовки не переставляет каналы
+	micSamples, _, err := session.ExtractSegmentStereoGo(mp3Path, extractStart, chunk.EndMs, 16000)
+	if err != nil {
+		log.Printf("Failed to extract mic segment: %v", err)
+		s.saveChunkStereo(ctx, chunk, "", "", nil, nil, err)
+		return
+	}
+	s.checkChunkClipping(chunk, extractStart, channelSamples{"mic", micSamples})
+
+	micSamples = session.FilterChannelForTranscription(micSamples, 16000)
+	micSamples = s.normalizeChannelLoudness(micSamples, "mic")
+
+	micVAD := s.channelVADConfig("mic")
+	regions, method := session.DetectSpeechRegionsForChannel(micSamples, 16000, micVAD)
+	regions = s.dropSilentRegions(chunkLabel(chunk), "mic", micSamples, regions, 16000)
+	s.SessionMgr.MarkChunkVADMethods(chunk.SessionID, chunk.ID, method, "", method != micVAD.Method)
+	s.SessionMgr.MarkChunkSpeechRatio(chunk.SessionID, chunk.ID, session.SpeechCoverage(int64(len(micSamples))*1000/16000, regions))
+	log.Printf("Mic-only chunk %d: %d speech regions (method: %s -> %s)", chunk.Index, len(regions), micVAD.Method, method)
+
+	usePerRegion := s.shouldUsePerRegion()
+	s.SessionMgr.MarkChunkTranscriptionPath(chunk.SessionID, chunk.ID, vadTranscriptionPath(usePerRegion), s.IsHybridEnabled())
+
+	var segments []ai.TranscriptSegment
+	if len(regions) > 0 {
+		progress := s.newChunkProgress(chunk, channelWork{"mic", speechDurationMs(regions)})
+		if usePerRegion {
+			segments, err = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "mic", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeRegionsSeparately(micSamples, regions, 16000, progress.regionProgress("mic"))
+			})
+		} else {
+			compressed := session.CompressSpeechFromRegions(micSamples, regions, 16000)
+			segments, err = s.transcribeWithTimeout(ctx, chunkLabel(chunk), "mic", func() ([]ai.TranscriptSegment, error) {
+				return s.transcribeWithHybridProgress(compressed.CompressedSamples, s.previousChunkPrompt(chunk, "mic"), progress.engineProgress("mic"))
+			})
+			if err == nil {
+				segments = restoreAISegmentTimestamps(segments, compressed.Regions)
+			}
+		}
+		if err != nil {
+			log.Printf("MIC transcription error for chunk %d: %v", chunk.Index, err)
+			s.saveChunkStereo(ctx, chunk, "", "", nil, nil, err)
+			return
+		}
+	}
+
+	micSegs := convertMicSegmentsWithDiarization(segments, extractStart)
+	if extractStart < chunk.StartMs {
+		micSegs = s.trimChunkOverlap(ctx, chunk, "mic", micSegs)
+	}
+	s.saveChunkStereo(ctx, chunk, session.JoinSegmentsText(micSegs), "", micSegs, nil, nil)
+
+	log.Printf("Mic-only transcription complete for chunk %d: %d segments", chunk.Index, len(micSegs))
+
+	if s.AutoImproveWithLLM && s.LLMService != nil && s.AutoImproveScope != AutoImproveScopeSession {
 		s.autoImproveChunk(chunk)
 	}
 }
@@ -749,19 +1368,24 @@ func (s *TranscriptionService) processStereoFromMP3(chunk *session.Chunk, useDia
 // transcribeRegionsSeparately транскрибирует каждый VAD регион отдельно
 // Это важно для GigaAM, который плохо работает со склеенными регионами (теряет контекст на границах)
 // Каждый регион транскрибируется независимо, затем результаты объединяются с правильными timestamps
-// Короткие регионы (<2 сек) объединяются с соседними для лучшего контекста
-func (s *TranscriptionService) transcribeRegionsSeparately(samples []float32, regions []session.SpeechRegion, sampleRate int) ([]ai.TranscriptSegment, error) {
+// Короткие регионы (<RegionMerge.MinRegionMs, по умолчанию 2 сек) объединяются с соседними для лучшего контекста
+func (s *TranscriptionService) transcribeRegionsSeparately(samples []float32, regions []session.SpeechRegion, sampleRate int, onProgress func(fraction float64, source string)) ([]ai.TranscriptSegment, error) {
 	if len(regions) == 0 {
 		return nil, nil
 	}
 
 	// Объединяем короткие регионы для лучшего контекста Whisper
-	mergedRegions := mergeShortRegions(regions, 2000, 3000) // minDuration=2s, maxGap=3s
+	mergedRegions := mergeShortRegions(regions, s.RegionMerge.MinRegionMs, s.RegionMerge.MaxGapMs)
 
-	log.Printf("transcribeRegionsSeparately: %d regions merged to %d groups", len(regions), len(mergedRegions))
+	log.Printf("transcribeRegionsSeparately: %d regions merged to %d groups (minRegion=%dms, maxGap=%dms)",
+		len(regions), len(mergedRegions), s.RegionMerge.MinRegionMs, s.RegionMerge.MaxGapMs)
 
 	var allSegments []ai.TranscriptSegment
 
+	// Прогресс оценивается по длительности обработанных регионов
+	totalMs := speechDurationMs(mergedRegions)
+	var doneMs float64
+
 	for i, region := range mergedRegions {
 		// Извлекаем семплы для этого региона
 		startSample := int(region.StartMs * int64(sampleRate) / 1000)
@@ -784,7 +1408,18 @@ func (s *TranscriptionService) transcribeRegionsSeparately(samples []float32, re
 			i, region.StartMs, region.EndMs, regionDurationMs, len(regionSamples))
 
 		// Транскрибируем регион (с поддержкой гибридного режима)
-		segments, err := s.transcribeWithHybrid(regionSamples)
+		var engineProgress ai.ProgressFunc
+		if onProgress != nil && totalMs > 0 {
+			base := doneMs
+			engineProgress = func(percent int) {
+				onProgress((base+float64(regionDurationMs)*float64(percent)/100)/totalMs, ProgressSourceEngine)
+			}
+		}
+		segments, err := s.transcribeWithHybridProgress(regionSamples, "", engineProgress)
+		doneMs += float64(regionDurationMs)
+		if onProgress != nil && totalMs > 0 {
+			onProgress(doneMs/totalMs, ProgressSourceRegions)
+		}
 		if err != nil {
 			log.Printf("  region[%d] transcription error: %v", i, err)
 			continue
@@ -965,8 +1600,16 @@ func (s *TranscriptionService) pipelineProcessWithTimeout(samples []float32, tim
 		err    error
 	}
 
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	if err := s.waitAbandoned(context.Background(), "pipeline", timer.C); err != nil {
+		return nil, err
+	}
+
 	ch := make(chan res, 1)
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		r, err := s.Pipeline.Process(samples)
 		ch <- res{result: r, err: err}
 	}()
@@ -974,7 +1617,8 @@ func (s *TranscriptionService) pipelineProcessWithTimeout(samples []float32, tim
 	select {
 	case out := <-ch:
 		return out.result, out.err
-	case <-time.After(timeout):
+	case <-timer.C:
+		s.abandoned.abandon("pipeline", done)
 		return nil, fmt.Errorf("pipeline process timeout after %v", timeout)
 	}
 }
@@ -1004,14 +1648,14 @@ func (s *TranscriptionService) autoImproveChunk(chunk *session.Chunk) {
 
 	log.Printf("Auto-improve: improving %d dialogue segments for chunk %d", len(dialogue), chunk.Index)
 
-	improved, err := s.LLMService.ImproveTranscriptionWithLLM(dialogue, s.OllamaModel, s.OllamaURL)
+	improved, err := s.LLMService.ImproveTranscriptionWithLLM(dialogue, s.OllamaModel, s.OllamaURL, nil)
 	if err != nil {
 		log.Printf("Auto-improve: LLM error: %v", err)
 		return
 	}
 
 	// Сохраняем улучшенный диалог
-	if err := s.SessionMgr.UpdateImprovedDialogue(chunk.SessionID, improved); err != nil {
+	if err := s.SessionMgr.SetDialogueLayer(chunk.SessionID, session.DialogueLayerImproved, improved); err != nil {
 		log.Printf("Auto-improve: failed to save: %v", err)
 		return
 	}
@@ -1022,29 +1666,39 @@ func (s *TranscriptionService) autoImproveChunk(chunk *session.Chunk) {
 
 // processMonoFromMP3 extracts mono audio from full.mp3 and transcribes (uses diarization if enabled)
 func (s *TranscriptionService) processMonoFromMP3(chunk *session.Chunk) {
-	s.processMonoFromMP3Impl(chunk, true)
+	s.processMonoFromMP3Impl(context.Background(), chunk, true)
 }
 
 // processMonoFromMP3Impl extracts mono audio from full.mp3 and transcribes with explicit diarization flag
-func (s *TranscriptionService) processMonoFromMP3Impl(chunk *session.Chunk, useDiarization bool) {
+func (s *TranscriptionService) processMonoFromMP3Impl(ctx context.Context, chunk *session.Chunk, useDiarization bool) {
+	// При откате со стерео время обработки уже засекается в processStereoFromMP3
+	if chunk.ProcessingStartTime == nil {
+		startTime := time.Now()
+		chunk.ProcessingStartTime = &startTime
+		s.SessionMgr.MarkChunkProcessingStarted(chunk.SessionID, chunk.ID, startTime)
+	}
+
 	// Get session to find MP3 path
 	sess, err := s.SessionMgr.GetSession(chunk.SessionID)
 	if err != nil {
 		log.Printf("Failed to get session: %v", err)
-		s.SessionMgr.UpdateChunkTranscription(chunk.SessionID, chunk.ID, "", err)
+		s.saveChunkText(ctx, chunk, "", err)
 		return
 	}
 
 	mp3Path := filepath.Join(sess.DataDir, "full.mp3")
+	extractStart := s.chunkExtractStart(chunk)
 
 	// Extract mono segment from MP3 (pure Go, no FFmpeg!)
-	log.Printf("Extracting mono segment (pure Go): %s (start=%dms, end=%dms)", mp3Path, chunk.StartMs, chunk.EndMs)
-	samples, err := session.ExtractSegmentGo(mp3Path, chunk.StartMs, chunk.EndMs, session.WhisperSampleRate)
+	log.Printf("Extracting mono segment (pure Go): %s (start=%dms, end=%dms, overlap=%dms)",
+		mp3Path, chunk.StartMs, chunk.EndMs, chunk.StartMs-extractStart)
+	samples, err := session.ExtractSegmentGo(mp3Path, extractStart, chunk.EndMs, session.WhisperSampleRate)
 	if err != nil {
 		log.Printf("Failed to extract segment: %v", err)
-		s.SessionMgr.UpdateChunkTranscription(chunk.SessionID, chunk.ID, "", err)
+		s.saveChunkText(ctx, chunk, "", err)
 		return
 	}
+	s.checkChunkClipping(chunk, extractStart, channelSamples{"mono", samples})
 
 	log.Printf("Transcribing chunk %d: %d samples (%.1f sec), useDiarization=%v", chunk.Index, len(samples), float64(len(samples))/16000, useDiarization)
 
@@ -1056,10 +1710,15 @@ func (s *TranscriptionService) processMonoFromMP3Impl(chunk *session.Chunk, useD
 
 	// Используем Pipeline если доступен и диаризация запрошена
 	if useDiarization && s.Pipeline != nil && s.Pipeline.IsDiarizationEnabled() {
-		result, err := s.Pipeline.Process(samples)
+		var result *ai.PipelineResult
+		if s.TranscribeTimeout > 0 {
+			result, err = s.pipelineProcessWithTimeout(samples, s.TranscribeTimeout)
+		} else {
+			result, err = s.Pipeline.Process(samples)
+		}
 		if err != nil {
 			log.Printf("Pipeline error for chunk %d: %v", chunk.Index, err)
-			s.SessionMgr.UpdateChunkTranscription(chunk.SessionID, chunk.ID, "", err)
+			s.saveChunkText(ctx, chunk, "", err)
 			return
 		}
 
@@ -1074,11 +1733,13 @@ func (s *TranscriptionService) processMonoFromMP3Impl(chunk *session.Chunk, useD
 				s.HybridConfig.Mode, s.HybridConfig.SecondaryModelID, s.HybridConfig.UseLLMForMerge, s.HybridConfig.OllamaModel)
 		}
 
+		hybridApplied := false
 		if s.IsHybridEnabled() && s.HybridConfig.Mode == ai.HybridModeFullCompare {
 			log.Printf("[Hybrid+Diarization] Applying hybrid transcription to pipeline result")
 			improvedResult := s.applyHybridToPipelineResult(samples, result)
 			if improvedResult != nil {
 				result = improvedResult
+				hybridApplied = true
 				log.Printf("[Hybrid+Diarization] Hybrid applied: %d chars", len(result.FullText))
 			} else {
 				log.Printf("[Hybrid+Diarization] No improvement from hybrid (nil result)")
@@ -1092,19 +1753,30 @@ func (s *TranscriptionService) processMonoFromMP3Impl(chunk *session.Chunk, useD
 				s.IsHybridEnabled(), mode)
 		}
 
+		s.SessionMgr.MarkChunkTranscriptionPath(chunk.SessionID, chunk.ID, session.TranscriptionPathDiarization, hybridApplied)
+
 		// Конвертируем сегменты с информацией о спикерах
-		sessionSegs := convertPipelineSegments(result.Segments, chunk.StartMs)
-		s.SessionMgr.UpdateChunkWithDiarizedSegments(chunk.SessionID, chunk.ID, result.FullText, sessionSegs, nil)
+		sessionSegs := convertPipelineSegments(result.Segments, extractStart)
+		fullText := result.FullText
+		if extractStart < chunk.StartMs {
+			sessionSegs = s.trimChunkOverlap(ctx, chunk, "mono", sessionSegs)
+			fullText = session.JoinSegmentsText(sessionSegs)
+		}
+		s.saveChunkDiarized(ctx, chunk, fullText, sessionSegs, nil)
 		return
 	}
 
 	// Fallback: транскрипция с сегментами но без диаризации (спикеров)
 	// Это даёт таймкоды и разбивку на предложения
 	// Используем гибридную транскрипцию если включена
-	segments, err := s.transcribeWithHybrid(samples)
+	s.SessionMgr.MarkChunkTranscriptionPath(chunk.SessionID, chunk.ID, session.TranscriptionPathFull, s.IsHybridEnabled())
+	progress := s.newChunkProgress(chunk, channelWork{"mono", float64(len(samples))})
+	segments, err := s.transcribeWithTimeout(ctx, chunkLabel(chunk), "mono", func() ([]ai.TranscriptSegment, error) {
+		return s.transcribeWithHybridProgress(samples, s.previousChunkPrompt(chunk, "mono"), progress.engineProgress("mono"))
+	})
 	if err != nil {
 		log.Printf("Transcription error for chunk %d: %v", chunk.Index, err)
-		s.SessionMgr.UpdateChunkTranscription(chunk.SessionID, chunk.ID, "", err)
+		s.saveChunkText(ctx, chunk, "", err)
 		return
 	}
 
@@ -1119,20 +1791,29 @@ func (s *TranscriptionService) processMonoFromMP3Impl(chunk *session.Chunk, useD
 		chunk.Index, len(fullText), len(segments))
 
 	// Конвертируем сегменты без спикеров (они останутся пустыми)
-	sessionSegs := convertPipelineSegments(segments, chunk.StartMs)
-	s.SessionMgr.UpdateChunkWithDiarizedSegments(chunk.SessionID, chunk.ID, fullText, sessionSegs, nil)
+	sessionSegs := convertPipelineSegments(segments, extractStart)
+	if extractStart < chunk.StartMs {
+		sessionSegs = s.trimChunkOverlap(ctx, chunk, "mono", sessionSegs)
+		fullText = session.JoinSegmentsText(sessionSegs)
+	}
+	s.saveChunkDiarized(ctx, chunk, fullText, sessionSegs, nil)
 }
 
 // convertPipelineSegments конвертирует сегменты из pipeline в формат session
 func convertPipelineSegments(aiSegs []ai.TranscriptSegment, chunkStartMs int64) []session.TranscriptSegment {
 	result := make([]session.TranscriptSegment, len(aiSegs))
 	for i, seg := range aiSegs {
+		words := convertWordsWithSpeaker(seg.Words, seg.Speaker, chunkStartMs)
 		result[i] = session.TranscriptSegment{
-			Start:   seg.Start + chunkStartMs,
-			End:     seg.End + chunkStartMs,
-			Text:    seg.Text,
-			Speaker: seg.Speaker, // Speaker уже заполнен из Pipeline
-			Words:   convertWordsWithSpeaker(seg.Words, seg.Speaker, chunkStartMs),
+			Start:      seg.Start + chunkStartMs,
+			End:        seg.End + chunkStartMs,
+			Text:       seg.Text,
+			Speaker:    seg.Speaker, // Speaker уже заполнен из Pipeline
+			Words:      words,
+			Confidence: session.SegmentConfidence(words),
+
+			Overlap:        seg.Overlap,
+			OverlapSpeaker: seg.OverlapSpeaker,
 		}
 	}
 	return result
@@ -1165,12 +1846,14 @@ func convertWordsWithSpeaker(aiWords []ai.TranscriptWord, speaker string, chunkS
 func convertSegmentsWithGlobalOffset(aiSegs []ai.TranscriptSegment, speaker string, chunkStartMs int64) []session.TranscriptSegment {
 	result := make([]session.TranscriptSegment, len(aiSegs))
 	for i, seg := range aiSegs {
+		words := convertWords(seg.Words, speaker, chunkStartMs)
 		result[i] = session.TranscriptSegment{
-			Start:   seg.Start + chunkStartMs,
-			End:     seg.End + chunkStartMs,
-			Text:    seg.Text,
-			Speaker: speaker,
-			Words:   convertWords(seg.Words, speaker, chunkStartMs),
+			Start:      seg.Start + chunkStartMs,
+			End:        seg.End + chunkStartMs,
+			Text:       seg.Text,
+			Speaker:    speaker,
+			Words:      words,
+			Confidence: session.SegmentConfidence(words),
 		}
 	}
 	return result
@@ -1196,7 +1879,8 @@ func convertWords(aiWords []ai.TranscriptWord, speaker string, chunkStartMs int6
 // applySpeakersToTranscriptSegments применяет спикеров из диаризации к сегментам транскрипции
 // Если сегмент содержит word-level timestamps, разбивает его по границам диаризации
 // Timestamps в обоих случаях должны быть в одной системе координат (оригинальное аудио)
-func applySpeakersToTranscriptSegments(segments []ai.TranscriptSegment, speakerSegs []ai.SpeakerSegment) []ai.TranscriptSegment {
+// minSpeakerRatio, minSegmentSec и minOverlapRatio - параметры консолидации спикеров и наложения речи (см. splitSegmentsBySpeakers)
+func applySpeakersToTranscriptSegments(segments []ai.TranscriptSegment, speakerSegs []ai.SpeakerSegment, minSpeakerRatio, minSegmentSec, minOverlapRatio float32) []ai.TranscriptSegment {
 	if len(speakerSegs) == 0 {
 		log.Printf("applySpeakersToTranscriptSegments: no speaker segments, returning original")
 		return segments
@@ -1227,7 +1911,7 @@ func applySpeakersToTranscriptSegments(segments []ai.TranscriptSegment, speakerS
 
 	// Если есть word-level timestamps, разбиваем сегменты по границам диаризации
 	if hasWords {
-		return splitSegmentsBySpeakers(segments, speakerSegs)
+		return splitSegmentsBySpeakers(segments, speakerSegs, minSpeakerRatio, minSegmentSec, minOverlapRatio)
 	}
 
 	// Fallback: простое присвоение спикера целому сегменту
@@ -1388,14 +2072,18 @@ func consolidateMinorSpeakers(speakerSegs []ai.SpeakerSegment, minSpeakerRatio f
 }
 
 // splitSegmentsBySpeakers разбивает сегменты транскрипции по границам диаризации
-// используя word-level timestamps для точного разделения
-func splitSegmentsBySpeakers(segments []ai.TranscriptSegment, speakerSegs []ai.SpeakerSegment) []ai.TranscriptSegment {
-	// Шаг 1: Консолидируем минорных спикеров (< 10% от общего времени)
-	speakerSegs = consolidateMinorSpeakers(speakerSegs, 0.10)
+// используя word-level timestamps для точного разделения. Сегменты, где слово одновременно
+// покрывают два спикера (каждый больше minOverlapRatio длительности слова), отмечаются как наложение речи
+func splitSegmentsBySpeakers(segments []ai.TranscriptSegment, speakerSegs []ai.SpeakerSegment, minSpeakerRatio, minSegmentSec, minOverlapRatio float32) []ai.TranscriptSegment {
+	// Шаг 0: Чиним тайминги слов (End < Start, перекрытия, пропуски) - иначе разбиение путает спикеров
+	segments = sanitizeWordTimings(segments)
+
+	// Шаг 1: Консолидируем минорных спикеров (по умолчанию < 10% от общего времени)
+	speakerSegs = consolidateMinorSpeakers(speakerSegs, minSpeakerRatio)
 
-	// Шаг 2: Объединяем короткие сегменты диаризации (< 1 сек)
+	// Шаг 2: Объединяем короткие сегменты диаризации (по умолчанию < 1 сек)
 	// Это помогает избежать ошибок, когда короткое слово ошибочно отнесено к другому спикеру
-	speakerSegs = mergeShortDiarizationSegments(speakerSegs, 1.0)
+	speakerSegs = mergeShortDiarizationSegments(speakerSegs, minSegmentSec)
 
 	// Логируем финальные сегменты после всех преобразований
 	log.Printf("splitSegmentsBySpeakers: after consolidation and merge, %d speaker segments:", len(speakerSegs))
@@ -1512,12 +2200,84 @@ func splitSegmentsBySpeakers(segments []ai.TranscriptSegment, speakerSegs []ai.S
 		}
 	}
 
+	markOverlappingSpeech(result, speakerSegs, minOverlapRatio)
+
 	log.Printf("splitSegmentsBySpeakers: split %d segments into %d segments by speaker boundaries",
 		len(segments), len(result))
 
 	return result
 }
 
+// markOverlappingSpeech отмечает сегменты с наложением речи: хотя бы одно слово (сегмент без слов - целиком)
+// одновременно покрывают два спикера. OverlapSpeaker - второй спикер, отличный от спикера сегмента
+func markOverlappingSpeech(segments []ai.TranscriptSegment, speakerSegs []ai.SpeakerSegment, minOverlapRatio float32) {
+	overlapCount := 0
+	for i := range segments {
+		seg := &segments[i]
+		ranges := [][2]int64{{seg.Start, seg.End}}
+		if len(seg.Words) > 0 {
+			ranges = ranges[:0]
+			for _, w := range seg.Words {
+				ranges = append(ranges, [2]int64{w.Start, w.End})
+			}
+		}
+		for _, r := range ranges {
+			speakers := getOverlappingSpeakers(float32(r[0])/1000.0, float32(r[1])/1000.0, speakerSegs, minOverlapRatio)
+			if len(speakers) < 2 {
+				continue
+			}
+			seg.Overlap = true
+			seg.OverlapSpeaker = speakers[0]
+			if seg.OverlapSpeaker == seg.Speaker {
+				seg.OverlapSpeaker = speakers[1]
+			}
+			overlapCount++
+			break
+		}
+	}
+	if overlapCount > 0 {
+		log.Printf("markOverlappingSpeech: %d of %d segments contain overlapping speech", overlapCount, len(segments))
+	}
+}
+
+// getOverlappingSpeakers возвращает спикеров, каждый из которых покрывает больше minOverlapRatio
+// диапазона, в порядке убывания покрытия. Два и больше спикеров - наложение речи
+func getOverlappingSpeakers(startSec, endSec float32, speakerSegs []ai.SpeakerSegment, minOverlapRatio float32) []string {
+	duration := endSec - startSec
+	if duration <= 0 {
+		return nil
+	}
+
+	coverage := make(map[int]float32)
+	var order []int
+	for _, ss := range speakerSegs {
+		overlap := min(endSec, ss.End) - max(startSec, ss.Start)
+		if overlap <= 0 {
+			continue
+		}
+		if _, ok := coverage[ss.Speaker]; !ok {
+			order = append(order, ss.Speaker)
+		}
+		coverage[ss.Speaker] += overlap
+	}
+
+	var speakers []int
+	for _, speaker := range order {
+		if coverage[speaker]/duration > minOverlapRatio {
+			speakers = append(speakers, speaker)
+		}
+	}
+	sort.SliceStable(speakers, func(i, j int) bool {
+		return coverage[speakers[i]] > coverage[speakers[j]]
+	})
+
+	result := make([]string, len(speakers))
+	for i, speaker := range speakers {
+		result[i] = fmt.Sprintf("Speaker %d", speaker)
+	}
+	return result
+}
+
 // createSegmentFromWords создаёт сегмент транскрипции из списка слов
 // endsWithSentenceBoundary проверяет, заканчивается ли слово на знак конца предложения
 func endsWithSentenceBoundary(text string) bool {
@@ -1634,6 +2394,8 @@ func (s *TranscriptionService) MergeSpeakerProfiles(sessionID string, sourceIDs
 
 	// Собираем embeddings для усреднения
 	var embeddings [][]float32
+	var observations [][]float32
+	var matches []voiceprint.SpeakerChunkMatch
 	var totalDuration float32
 	var targetProfile *SessionSpeakerProfile
 	var targetIdx int = -1
@@ -1645,6 +2407,8 @@ func (s *TranscriptionService) MergeSpeakerProfiles(sessionID string, sourceIDs
 					embeddings = append(embeddings, profiles[i].Embedding)
 					totalDuration += profiles[i].Duration
 				}
+				observations = append(observations, profiles[i].Observations...)
+				matches = append(matches, profiles[i].Matches...)
 				if srcID == targetID {
 					targetProfile = &profiles[i]
 					targetIdx = i
@@ -1663,6 +2427,11 @@ func (s *TranscriptionService) MergeSpeakerProfiles(sessionID string, sourceIDs
 		avgEmbedding := averageEmbeddings(embeddings)
 		targetProfile.Embedding = avgEmbedding
 		targetProfile.Duration = totalDuration
+		targetProfile.Observations = nil
+		for _, obs := range observations {
+			targetProfile.addObservation(obs)
+		}
+		targetProfile.Matches = mergeSpeakerChunkMatches(matches)
 		log.Printf("MergeSpeakerProfiles: averaged %d embeddings for speaker %d", len(embeddings), targetID)
 	}
 
@@ -1746,33 +2515,70 @@ func assignSpeakersToSegments(segments []ai.TranscriptSegment, speakerSegs []ai.
 }
 
 // convertSysSegmentsWithDiarization converts SYS channel segments with speaker labels
-// "Speaker 0" -> "Собеседник 1", "Speaker 1" -> "Собеседник 2", etc.
+// "Speaker 0" -> "Собеседник 1", "Speaker 1" -> "Собеседник 2", etc. (see session.SpeakerLabelScheme)
 // If no diarization speaker, defaults to "Собеседник"
 func convertSysSegmentsWithDiarization(aiSegs []ai.TranscriptSegment, chunkStartMs int64) []session.TranscriptSegment {
 	result := make([]session.TranscriptSegment, len(aiSegs))
 	for i, seg := range aiSegs {
 		speaker := seg.Speaker
 		if speaker == "" {
-			speaker = "Собеседник"
-		} else if strings.HasPrefix(speaker, "Speaker ") {
-			// "Speaker 0" -> "Собеседник 1", "Speaker 1" -> "Собеседник 2"
-			numStr := strings.TrimPrefix(speaker, "Speaker ")
-			if num, err := strconv.Atoi(numStr); err == nil {
-				speaker = fmt.Sprintf("Собеседник %d", num+1)
-			}
+			speaker = session.PeerSpeakerLabel()
+		} else {
+			speaker = session.FormatSpeakerLabel(speaker)
 		}
 
+		words := convertWords(seg.Words, speaker, chunkStartMs)
 		result[i] = session.TranscriptSegment{
-			Start:   seg.Start + chunkStartMs,
-			End:     seg.End + chunkStartMs,
-			Text:    seg.Text,
-			Speaker: speaker,
-			Words:   convertWords(seg.Words, speaker, chunkStartMs),
+			Start:      seg.Start + chunkStartMs,
+			End:        seg.End + chunkStartMs,
+			Text:       seg.Text,
+			Speaker:    speaker,
+			Words:      words,
+			Confidence: session.SegmentConfidence(words),
+			Overlap:    seg.Overlap,
+		}
+		if seg.OverlapSpeaker != "" {
+			result[i].OverlapSpeaker = session.FormatSpeakerLabel(seg.OverlapSpeaker)
+		}
+	}
+	return result
+}
+
+// convertMicSegmentsWithDiarization конвертирует сегменты MIC канала
+// Без диаризации все сегменты принадлежат "Вы".
+// С диаризацией (DiarizeMic) "Speaker 0" -> "Вы 1", "Speaker 1" -> "Вы 2" и т.д. (по схеме имён),
+// чтобы не пересекаться с собеседниками из SYS канала
+func convertMicSegmentsWithDiarization(aiSegs []ai.TranscriptSegment, chunkStartMs int64) []session.TranscriptSegment {
+	result := make([]session.TranscriptSegment, len(aiSegs))
+	for i, seg := range aiSegs {
+		speaker := micParticipantLabel(seg.Speaker)
+		words := convertWords(seg.Words, speaker, chunkStartMs)
+		result[i] = session.TranscriptSegment{
+			Start:      seg.Start + chunkStartMs,
+			End:        seg.End + chunkStartMs,
+			Text:       seg.Text,
+			Speaker:    speaker,
+			Words:      words,
+			Confidence: session.SegmentConfidence(words),
+			Overlap:    seg.Overlap,
+		}
+		if seg.OverlapSpeaker != "" {
+			result[i].OverlapSpeaker = micParticipantLabel(seg.OverlapSpeaker)
 		}
 	}
 	return result
 }
 
+// micParticipantLabel "Speaker N" диаризации MIC канала -> session.MicSpeakerLabel(N), иначе "Вы"
+func micParticipantLabel(speaker string) string {
+	if numStr, ok := strings.CutPrefix(speaker, "Speaker "); ok {
+		if num, err := strconv.Atoi(numStr); err == nil && num >= 0 {
+			return session.MicSpeakerLabel(num)
+		}
+	}
+	return session.SelfSpeakerLabel()
+}
+
 // areChannelsSimilar проверяет, являются ли два канала идентичными (или очень похожими)
 // Используется для детектирования "фейкового" стерео (дублированного моно)
 //
@@ -1862,11 +2668,16 @@ func restoreAISegmentTimestamps(segments []ai.TranscriptSegment, regions []sessi
 		return segments
 	}
 
+	clampedCount := 0
 	restored := make([]ai.TranscriptSegment, len(segments))
 	for i, seg := range segments {
+		start, end, clamped := session.RestoreTimestampRange(seg.Start, seg.End, regions)
+		if clamped {
+			clampedCount++
+		}
 		restored[i] = ai.TranscriptSegment{
-			Start:   session.MapWhisperTimeToRealTime(seg.Start, regions),
-			End:     session.MapWhisperTimeToRealTime(seg.End, regions),
+			Start:   start,
+			End:     end,
 			Text:    seg.Text,
 			Speaker: seg.Speaker,
 		}
@@ -1875,9 +2686,13 @@ func restoreAISegmentTimestamps(segments []ai.TranscriptSegment, regions []sessi
 		if len(seg.Words) > 0 {
 			restored[i].Words = make([]ai.TranscriptWord, len(seg.Words))
 			for j, word := range seg.Words {
+				wordStart, wordEnd, wordClamped := session.RestoreTimestampRange(word.Start, word.End, regions)
+				if wordClamped {
+					clampedCount++
+				}
 				restored[i].Words[j] = ai.TranscriptWord{
-					Start: session.MapWhisperTimeToRealTime(word.Start, regions),
-					End:   session.MapWhisperTimeToRealTime(word.End, regions),
+					Start: wordStart,
+					End:   wordEnd,
 					Text:  word.Text,
 					P:     word.P,
 				}
@@ -1885,13 +2700,18 @@ func restoreAISegmentTimestamps(segments []ai.TranscriptSegment, regions []sessi
 		}
 	}
 
+	if clampedCount > 0 {
+		log.Printf("restoreAISegmentTimestamps: clamped %d timestamps to speech region bounds", clampedCount)
+	}
+
 	return restored
 }
 
 // matchSpeakersWithSession сопоставляет спикеров текущего чанка с уже известными спикерами сессии
 // и с глобальной базой voiceprints для автоматического распознавания
+// Сходство сопоставления запоминается в профилях (SessionSpeakerProfile.Matches) с индексом чанка.
 // Возвращает map[localSpeakerID]globalSpeakerID для переназначения
-func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, embeddings []ai.SpeakerEmbedding) map[int]int {
+func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, chunkIndex int, embeddings []ai.SpeakerEmbedding) map[int]int {
 	mapping := make(map[int]int)
 
 	// Получаем или создаём профили спикеров для сессии
@@ -1901,6 +2721,13 @@ func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, embedd
 
 	profiles := s.sessionSpeakerProfiles[sessionID]
 
+	// Профили от другой модели embeddings (другая размерность) сопоставить нельзя
+	if len(profiles) > 0 && len(embeddings) > 0 && len(profiles[0].Embedding) != len(embeddings[0].Embedding) {
+		log.Printf("matchSpeakersWithSession: stored profiles have embedding size %d, got %d - starting from scratch",
+			len(profiles[0].Embedding), len(embeddings[0].Embedding))
+		profiles = nil
+	}
+
 	// Если это первый чанк - пробуем распознать из глобальной базы voiceprints
 	if len(profiles) == 0 {
 		for _, emb := range embeddings {
@@ -1909,6 +2736,8 @@ func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, embedd
 				Embedding: emb.Embedding,
 				Duration:  emb.Duration,
 			}
+			profile.addObservation(emb.Embedding)
+			profile.addMatch(voiceprint.SpeakerChunkMatch{ChunkIndex: chunkIndex, New: true})
 
 			// Пробуем найти совпадение в глобальной базе voiceprints
 			if s.VoicePrintMatcher != nil {
@@ -1938,53 +2767,57 @@ func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, embedd
 		return mapping // Пустой маппинг - используем оригинальные ID
 	}
 
-	// Сопоставляем каждый embedding с известными профилями сессии
-	threshold := float32(0.65) // Порог косинусного сходства для совпадения
+	// Сопоставляем embeddings с известными профилями сессии (один к одному).
+	// Номера профилей стабильны: тот же голос сохраняет тот же номер между чанками
+	// и между повторными транскрипциями, новым голосам выдаются свободные номера
+	threshold := s.GetSpeakerMatchThreshold()
+	mapping, added := assignSessionSpeakerIDs(profiles, embeddings, threshold)
+	for rawID, sessionID := range mapping {
+		log.Printf("matchSpeakersWithSession: speaker %d mapped to session speaker %d", rawID, sessionID)
+	}
+	matches := speakerChunkMatches(profiles, embeddings, mapping, chunkIndex)
 
+	// Запоминаем embeddings чанка в профилях совпавших спикеров (для оценки стабильности)
 	for _, emb := range embeddings {
-		bestMatch := -1
-		bestSimilarity := float32(0)
-
-		for _, profile := range profiles {
-			similarity := cosineSimilarity(emb.Embedding, profile.Embedding)
-			if similarity > bestSimilarity && similarity >= threshold {
-				bestSimilarity = similarity
-				bestMatch = profile.SpeakerID
+		id := emb.Speaker
+		if mapped, ok := mapping[emb.Speaker]; ok {
+			id = mapped
+		}
+		for i := range profiles {
+			if profiles[i].SpeakerID == id {
+				profiles[i].addObservation(emb.Embedding)
+				profiles[i].addMatch(matches[id])
+				log.Printf("matchSpeakersWithSession: chunk %d speaker %d matched with similarity %.3f",
+					chunkIndex, id, matches[id].Similarity)
+				break
 			}
 		}
+	}
 
-		if bestMatch >= 0 && bestMatch != emb.Speaker {
-			// Нашли совпадение с другим ID - нужно переназначить
-			mapping[emb.Speaker] = bestMatch
-			log.Printf("matchSpeakersWithSession: speaker %d matched to existing speaker %d (similarity=%.2f)",
-				emb.Speaker, bestMatch, bestSimilarity)
-		} else if bestMatch < 0 {
-			// Новый спикер - добавляем в профили
-			newProfile := SessionSpeakerProfile{
-				SpeakerID: emb.Speaker,
-				Embedding: emb.Embedding,
-				Duration:  emb.Duration,
-			}
+	for _, newProfile := range added {
+		newProfile.addObservation(newProfile.Embedding)
+		newProfile.addMatch(matches[newProfile.SpeakerID])
+		log.Printf("matchSpeakersWithSession: chunk %d speaker %d is new (best similarity %.3f < %.2f)",
+			chunkIndex, newProfile.SpeakerID, matches[newProfile.SpeakerID].Similarity, threshold)
 
-			// Пробуем найти совпадение в глобальной базе voiceprints
-			if s.VoicePrintMatcher != nil {
-				match := s.VoicePrintMatcher.FindBestMatch(emb.Embedding)
-				if match != nil && match.Confidence != "none" {
-					newProfile.RecognizedName = match.VoicePrint.Name
-					newProfile.VoicePrintID = match.VoicePrint.ID
-					log.Printf("matchSpeakersWithSession: new speaker %d recognized as '%s' from voiceprint (similarity=%.2f)",
-						emb.Speaker, match.VoicePrint.Name, match.Similarity)
+		// Пробуем найти совпадение в глобальной базе voiceprints
+		if s.VoicePrintMatcher != nil {
+			match := s.VoicePrintMatcher.FindBestMatch(newProfile.Embedding)
+			if match != nil && match.Confidence != "none" {
+				newProfile.RecognizedName = match.VoicePrint.Name
+				newProfile.VoicePrintID = match.VoicePrint.ID
+				log.Printf("matchSpeakersWithSession: new speaker %d recognized as '%s' from voiceprint (similarity=%.2f)",
+					newProfile.SpeakerID, match.VoicePrint.Name, match.Similarity)
 
-					// Обновляем voiceprint при высокой уверенности
-					if match.Confidence == "high" {
-						s.VoicePrintMatcher.MatchWithAutoUpdate(emb.Embedding)
-					}
+				// Обновляем voiceprint при высокой уверенности
+				if match.Confidence == "high" {
+					s.VoicePrintMatcher.MatchWithAutoUpdate(newProfile.Embedding)
 				}
 			}
-
-			profiles = append(profiles, newProfile)
-			log.Printf("matchSpeakersWithSession: new speaker %d added to session profiles", emb.Speaker)
 		}
+
+		profiles = append(profiles, newProfile)
+		log.Printf("matchSpeakersWithSession: new speaker %d added to session profiles", newProfile.SpeakerID)
 	}
 
 	s.sessionSpeakerProfiles[sessionID] = profiles
@@ -1995,6 +2828,106 @@ func (s *TranscriptionService) matchSpeakersWithSession(sessionID string, embedd
 	return mapping
 }
 
+// Порог косинусного сходства для совпадения спикера чанка со спикером сессии.
+// Не зависит от порогов сопоставления с базой voiceprints: слишком высокий дробит
+// одного собеседника на нескольких, слишком низкий склеивает разных
+const (
+	DefaultSpeakerMatchThreshold float32 = 0.65
+	MinSpeakerMatchThreshold     float32 = 0.3
+	MaxSpeakerMatchThreshold     float32 = 0.95
+)
+
+// SetSpeakerMatchThreshold устанавливает порог сопоставления спикеров между чанками
+func (s *TranscriptionService) SetSpeakerMatchThreshold(threshold float32) error {
+	if threshold < MinSpeakerMatchThreshold || threshold > MaxSpeakerMatchThreshold {
+		return fmt.Errorf("speaker match threshold must be in [%.2f, %.2f], got %.3f",
+			MinSpeakerMatchThreshold, MaxSpeakerMatchThreshold, threshold)
+	}
+	s.SpeakerMatchThreshold = threshold
+	log.Printf("Cross-chunk speaker match threshold set to: %.2f", threshold)
+	return nil
+}
+
+// GetSpeakerMatchThreshold возвращает действующий порог сопоставления спикеров между чанками
+func (s *TranscriptionService) GetSpeakerMatchThreshold() float32 {
+	if s.SpeakerMatchThreshold > 0 {
+		return s.SpeakerMatchThreshold
+	}
+	return DefaultSpeakerMatchThreshold
+}
+
+// assignSessionSpeakerIDs сопоставляет embeddings чанка с профилями сессии.
+// Пары выбираются жадно по убыванию сходства, каждый профиль используется не более одного раза,
+// поэтому результат не зависит от порядка embeddings. Несопоставленные спикеры сохраняют
+// свой ID, если он свободен, иначе получают следующий свободный номер.
+// Возвращает маппинг rawID -> sessionID (только изменённые) и новые профили
+func assignSessionSpeakerIDs(profiles []SessionSpeakerProfile, embeddings []ai.SpeakerEmbedding, threshold float32) (map[int]int, []SessionSpeakerProfile) {
+	type candidate struct {
+		emb, profile int
+		similarity   float32
+	}
+
+	var candidates []candidate
+	for i, emb := range embeddings {
+		for j, profile := range profiles {
+			if similarity := cosineSimilarity(emb.Embedding, profile.Embedding); similarity >= threshold {
+				candidates = append(candidates, candidate{emb: i, profile: j, similarity: similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].similarity != candidates[j].similarity {
+			return candidates[i].similarity > candidates[j].similarity
+		}
+		return profiles[candidates[i].profile].SpeakerID < profiles[candidates[j].profile].SpeakerID
+	})
+
+	assigned := make(map[int]int) // индекс embedding -> sessionID
+	usedProfiles := make(map[int]bool)
+	usedIDs := make(map[int]bool)
+	for _, profile := range profiles {
+		usedIDs[profile.SpeakerID] = true
+	}
+	for _, c := range candidates {
+		if _, ok := assigned[c.emb]; ok || usedProfiles[c.profile] {
+			continue
+		}
+		assigned[c.emb] = profiles[c.profile].SpeakerID
+		usedProfiles[c.profile] = true
+	}
+
+	nextID := 0
+	nextFreeID := func() int {
+		for usedIDs[nextID] {
+			nextID++
+		}
+		usedIDs[nextID] = true
+		return nextID
+	}
+
+	mapping := make(map[int]int)
+	var added []SessionSpeakerProfile
+	for i, emb := range embeddings {
+		id, ok := assigned[i]
+		if !ok {
+			id = emb.Speaker
+			if usedIDs[id] {
+				id = nextFreeID()
+			}
+			usedIDs[id] = true
+			added = append(added, SessionSpeakerProfile{
+				SpeakerID: id,
+				Embedding: emb.Embedding,
+				Duration:  emb.Duration,
+			})
+		}
+		if id != emb.Speaker {
+			mapping[emb.Speaker] = id
+		}
+	}
+	return mapping, added
+}
+
 // remapSpeakerSegments применяет маппинг спикеров к сегментам
 func (s *TranscriptionService) remapSpeakerSegments(segments []ai.SpeakerSegment, mapping map[int]int) []ai.SpeakerSegment {
 	if len(mapping) == 0 {
@@ -2039,6 +2972,24 @@ func (s *TranscriptionService) ClearSessionSpeakerProfiles(sessionID string) {
 	}
 }
 
+// AnchorSessionSpeakerProfiles подготавливает профили спикеров к полной ретранскрипции.
+// Вместо очистки сохранённые профили (с диска, если их нет в памяти) используются как якоря:
+// голос, совпавший с профилем, получает прежний номер, и сохранённые переименования остаются верными.
+// Возвращает количество профилей-якорей
+func (s *TranscriptionService) AnchorSessionSpeakerProfiles(sessionID string) int {
+	profiles, err := s.LoadSessionSpeakerProfiles(sessionID)
+	if err != nil {
+		log.Printf("AnchorSessionSpeakerProfiles: failed to load profiles, starting from scratch: %v", err)
+		s.ClearSessionSpeakerProfiles(sessionID)
+		return 0
+	}
+	if len(profiles) > 0 {
+		log.Printf("AnchorSessionSpeakerProfiles: using %d stored speaker profiles as anchors for session %s",
+			len(profiles), sessionID[:8])
+	}
+	return len(profiles)
+}
+
 // SaveSessionSpeakerProfiles сохраняет профили спикеров на диск
 func (s *TranscriptionService) SaveSessionSpeakerProfiles(sessionID string) error {
 	if s.sessionSpeakerProfiles == nil {
@@ -2085,8 +3036,24 @@ func (s *TranscriptionService) LoadSessionSpeakerProfiles(sessionID string) ([]S
 		return nil, err
 	}
 
-	profilesPath := filepath.Join(sess.DataDir, "speaker_profiles.json")
-	data, err := os.ReadFile(profilesPath)
+	profiles, err := readSpeakerProfiles(sess.DataDir)
+	if err != nil || profiles == nil {
+		return nil, err
+	}
+
+	// Кэшируем в памяти
+	if s.sessionSpeakerProfiles == nil {
+		s.sessionSpeakerProfiles = make(map[string][]SessionSpeakerProfile)
+	}
+	s.sessionSpeakerProfiles[sessionID] = profiles
+
+	log.Printf("Loaded %d speaker profiles for session %s from disk", len(profiles), sessionID[:8])
+	return profiles, nil
+}
+
+// readSpeakerProfiles читает speaker_profiles.json из директории сессии без кэширования
+func readSpeakerProfiles(dataDir string) ([]SessionSpeakerProfile, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, "speaker_profiles.json"))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil // Файла нет - это нормально для старых сессий
@@ -2098,15 +3065,58 @@ func (s *TranscriptionService) LoadSessionSpeakerProfiles(sessionID string) ([]S
 	if err := json.Unmarshal(data, &profiles); err != nil {
 		return nil, err
 	}
+	return profiles, nil
+}
 
-	// Кэшируем в памяти
-	if s.sessionSpeakerProfiles == nil {
-		s.sessionSpeakerProfiles = make(map[string][]SessionSpeakerProfile)
+// SpeakerMatchProposal предлагаемое совпадение спикера сессии с глобальной базой voiceprints
+type SpeakerMatchProposal struct {
+	LocalID      int     `json:"localId"`                // ID в рамках сессии ("Собеседник N" -> N-1)
+	CurrentName  string  `json:"currentName,omitempty"`  // Уже назначенное имя из базы
+	Matched      bool    `json:"matched"`                // Найдено совпадение выше ThresholdMin
+	VoicePrintID string  `json:"voiceprintId,omitempty"` // Предлагаемый voiceprint
+	Name         string  `json:"name,omitempty"`         // Предлагаемое имя
+	Similarity   float32 `json:"similarity"`
+	Confidence   string  `json:"confidence"` // "high", "medium", "low", "none"
+	Duration     float32 `json:"duration"`   // Длительность речи спикера (сек)
+}
+
+// ProposeSessionSpeakerMatches сопоставляет все сохранённые профили спикеров сессии
+// с базой voiceprints. Ничего не применяет - только возвращает предложения,
+// включая спикеров без совпадений
+func (s *TranscriptionService) ProposeSessionSpeakerMatches(sessionID string) ([]SpeakerMatchProposal, error) {
+	if s.VoicePrintMatcher == nil {
+		return nil, fmt.Errorf("voiceprint matcher not available")
 	}
-	s.sessionSpeakerProfiles[sessionID] = profiles
 
-	log.Printf("Loaded %d speaker profiles for session %s from disk", len(profiles), sessionID[:8])
-	return profiles, nil
+	profiles, err := s.LoadSessionSpeakerProfiles(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposals := make([]SpeakerMatchProposal, 0, len(profiles))
+	for _, profile := range profiles {
+		proposal := SpeakerMatchProposal{
+			LocalID:     profile.SpeakerID,
+			CurrentName: profile.RecognizedName,
+			Confidence:  "none",
+			Duration:    profile.Duration,
+		}
+		if len(profile.Embedding) > 0 {
+			if match := s.VoicePrintMatcher.FindBestMatch(profile.Embedding); match != nil {
+				proposal.Matched = true
+				proposal.VoicePrintID = match.VoicePrint.ID
+				proposal.Name = match.VoicePrint.Name
+				proposal.Similarity = match.Similarity
+				proposal.Confidence = match.Confidence
+			}
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].LocalID < proposals[j].LocalID
+	})
+	return proposals, nil
 }
 
 // ClearVoiceprintFromProfiles очищает RecognizedName и VoicePrintID во всех профилях,
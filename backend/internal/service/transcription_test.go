@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"aiwisper/ai"
+	"aiwisper/session"
+)
+
+func TestTranscribeWithTimeout_LateReturn(t *testing.T) {
+	s := &TranscriptionService{TranscribeTimeout: 20 * time.Millisecond}
+
+	release := make(chan struct{})
+	_, err := s.transcribeWithTimeout(context.Background(), "chunk 1", "mic", func() ([]ai.TranscriptSegment, error) {
+		<-release // Зависший нативный вызов
+		return nil, nil
+	})
+	if !errors.Is(err, errTranscribeTimeout) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+
+	// Пока брошенный вызов не вернулся, новое распознавание не запускается и падает по таймауту
+	called := false
+	_, err = s.transcribeWithTimeout(context.Background(), "chunk 2", "mic", func() ([]ai.TranscriptSegment, error) {
+		called = true
+		return nil, nil
+	})
+	if !errors.Is(err, errTranscribeTimeout) || called {
+		t.Fatalf("while engine is busy: err = %v, called = %v", err, called)
+	}
+
+	// Следующий фрагмент ждёт возврата и запускается сразу после него
+	result := make(chan []ai.TranscriptSegment, 1)
+	s.TranscribeTimeout = time.Minute
+	go func() {
+		segments, _ := s.transcribeWithTimeout(context.Background(), "chunk 3", "mic", func() ([]ai.TranscriptSegment, error) {
+			return []ai.TranscriptSegment{{Text: "ok"}}, nil
+		})
+		result <- segments
+	}()
+	select {
+	case <-result:
+		t.Fatal("transcription dispatched while abandoned call is running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case segments := <-result:
+		if len(segments) != 1 || segments[0].Text != "ok" {
+			t.Errorf("segments = %+v", segments)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("transcription did not start after abandoned call returned")
+	}
+	if s.abandoned.pending() != nil {
+		t.Error("abandoned call still counted after return")
+	}
+}
+
+func TestTranscribeWithTimeout_CanceledWhileEngineBusy(t *testing.T) {
+	s := &TranscriptionService{}
+	done := make(chan struct{})
+	s.abandoned.abandon("chunk 1", done)
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.transcribeWithTimeout(ctx, "chunk 2", "mono", func() ([]ai.TranscriptSegment, error) {
+			return nil, nil
+		})
+		errc <- err
+	}()
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting for abandoned call ignored cancel")
+	}
+}
+
+func TestBacklogWarning(t *testing.T) {
+	s := NewTranscriptionService(nil, nil)
+	s.BacklogThreshold = 2
+
+	var events []bool
+	s.OnBacklog = func(status QueueStatus, cleared bool) {
+		events = append(events, cleared)
+	}
+
+	chunks := make([]*session.Chunk, 5)
+	for i := range chunks {
+		chunks[i] = &session.Chunk{ID: fmt.Sprintf("c%d", i), Index: i}
+		s.enqueueChunk(chunks[i])
+	}
+	// Очередь росла с 3 до 5 - предупреждение одно
+	if !slices.Equal(events, []bool{false}) {
+		t.Fatalf("events after enqueue = %v, want one warning", events)
+	}
+	if status := s.GetQueueStatus(); status.Pending != 5 || status.Threshold != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// 4 и 3 выше порога - без событий, 2 - отбой
+	for _, chunk := range chunks[:3] {
+		s.dequeueChunk(chunk)
+	}
+	if !slices.Equal(events, []bool{false, true}) {
+		t.Fatalf("events after dequeue = %v, want warning and clear", events)
+	}
+
+	// Повторное превышение порога снова предупреждает
+	s.enqueueChunk(chunks[0])
+	s.dequeueChunk(chunks[3])
+	s.dequeueChunk(chunks[4])
+	if !slices.Equal(events, []bool{false, true, false, true}) {
+		t.Errorf("events after second crossing = %v", events)
+	}
+}
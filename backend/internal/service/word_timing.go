@@ -0,0 +1,133 @@
+package service
+
+import (
+	"aiwisper/ai"
+	"log"
+	"sort"
+)
+
+// sanitizeWordTimings чинит word-level таймстемпы сегментов перед разбиением по спикерам.
+// Движки иногда возвращают End < Start, перекрывающиеся или пустые (0-0) тайминги слов,
+// из-за чего splitSegmentsBySpeakers путает спикеров, а экспорт - порядок субтитров
+func sanitizeWordTimings(segments []ai.TranscriptSegment) []ai.TranscriptSegment {
+	result := make([]ai.TranscriptSegment, len(segments))
+	repaired := 0
+	for i, seg := range segments {
+		var n int
+		result[i], n = sanitizeSegmentWordTimings(seg)
+		repaired += n
+	}
+	if repaired > 0 {
+		log.Printf("sanitizeWordTimings: repaired %d word timing(s) in %d segments", repaired, len(segments))
+	}
+	return result
+}
+
+// sanitizeSegmentWordTimings сортирует слова сегмента по началу, ограничивает тайминги
+// границами сегмента, интерполирует отсутствующие и убирает перекрытия.
+// Возвращает исправленный сегмент (слова копируются) и число изменённых слов
+func sanitizeSegmentWordTimings(seg ai.TranscriptSegment) (ai.TranscriptSegment, int) {
+	if len(seg.Words) == 0 {
+		return seg, 0
+	}
+	original := seg.Words
+	words := append([]ai.TranscriptWord(nil), original...)
+
+	// Границы сегмента; если они сами некорректны - берём крайние известные тайминги слов
+	lo, hi := seg.Start, seg.End
+	if hi <= lo {
+		lo, hi = -1, -1
+		for _, w := range words {
+			if hasWordTiming(w) {
+				lo = minKnown(lo, w.Start)
+				hi = max(hi, w.End, w.Start)
+			}
+		}
+		if lo < 0 {
+			return seg, 0 // Нет ни одного тайминга - чинить не от чего
+		}
+	}
+
+	// Известные тайминги ограничиваем границами сегмента
+	var known []int
+	for i := range words {
+		if !hasWordTiming(words[i]) {
+			continue
+		}
+		known = append(known, i)
+		words[i].Start = min(max(words[i].Start, lo), hi)
+		words[i].End = min(max(words[i].End, lo), hi)
+	}
+
+	// Слова с известными таймингами сортируем по началу на их же местах,
+	// слова без таймингов остаются между соседями по тексту
+	sorted := make([]ai.TranscriptWord, len(known))
+	for j, i := range known {
+		sorted[j] = words[i]
+	}
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Start < sorted[b].Start })
+	for j, i := range known {
+		words[i] = sorted[j]
+	}
+
+	// Отсутствующие тайминги интерполируем равномерно между соседями
+	for i := 0; i < len(words); {
+		if hasWordTiming(original[i]) {
+			i++
+			continue
+		}
+		from := i
+		for i < len(words) && !hasWordTiming(original[i]) {
+			i++
+		}
+		gapStart, gapEnd := lo, hi
+		if from > 0 {
+			gapStart = max(words[from-1].Start, words[from-1].End)
+		}
+		if i < len(words) {
+			gapEnd = words[i].Start
+		}
+		gapEnd = max(gapEnd, gapStart)
+		count := int64(i - from)
+		for k := int64(0); k < count; k++ {
+			words[from+int(k)].Start = gapStart + (gapEnd-gapStart)*k/count
+			words[from+int(k)].End = gapStart + (gapEnd-gapStart)*(k+1)/count
+		}
+	}
+
+	// Монотонность: End < Start продлеваем до начала следующего слова, перекрытия обрезаем
+	for i := range words {
+		if words[i].End < words[i].Start {
+			next := hi
+			if i+1 < len(words) {
+				next = max(words[i+1].Start, words[i].Start)
+			}
+			words[i].End = next
+		}
+		if i > 0 && words[i-1].End > words[i].Start {
+			words[i-1].End = max(words[i].Start, words[i-1].Start)
+		}
+	}
+
+	changed := 0
+	for i := range words {
+		if words[i] != original[i] {
+			changed++
+		}
+	}
+	seg.Words = words
+	return seg, changed
+}
+
+// hasWordTiming проверяет, что у слова есть тайминг (0-0 и отрицательные значения - отсутствие)
+func hasWordTiming(w ai.TranscriptWord) bool {
+	return w.Start >= 0 && w.End >= 0 && (w.Start > 0 || w.End > 0)
+}
+
+// minKnown минимум, где отрицательное значение означает "ещё не задано"
+func minKnown(current, v int64) int64 {
+	if current < 0 {
+		return v
+	}
+	return min(current, v)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"testing"
+
+	"aiwisper/ai"
+)
+
+// checkWordTimings проверяет, что слова упорядочены, не перекрываются и лежат в [lo, hi]
+func checkWordTimings(t *testing.T, words []ai.TranscriptWord, lo, hi int64) {
+	t.Helper()
+	for i, w := range words {
+		if w.Start < lo || w.End > hi || w.End < w.Start {
+			t.Errorf("word %d %q: %d-%d outside [%d, %d] or reversed", i, w.Text, w.Start, w.End, lo, hi)
+		}
+		if i > 0 && words[i-1].End > w.Start {
+			t.Errorf("word %d %q overlaps previous: prev end %d > start %d", i, w.Text, words[i-1].End, w.Start)
+		}
+	}
+}
+
+func TestSanitizeWordTimings(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []ai.TranscriptWord
+		want  []string // Ожидаемый порядок слов
+	}{
+		{
+			name: "reversed and overlapping",
+			words: []ai.TranscriptWord{
+				{Start: 1000, End: 800, Text: "раз"},
+				{Start: 1500, End: 2600, Text: "два"},
+				{Start: 2400, End: 3000, Text: "три"},
+			},
+			want: []string{"раз", "два", "три"},
+		},
+		{
+			name: "out of order",
+			words: []ai.TranscriptWord{
+				{Start: 3000, End: 3500, Text: "три"},
+				{Start: 1000, End: 1500, Text: "раз"},
+				{Start: 2000, End: 2500, Text: "два"},
+			},
+			want: []string{"раз", "два", "три"},
+		},
+		{
+			name: "outside segment bounds",
+			words: []ai.TranscriptWord{
+				{Start: 200, End: 1200, Text: "раз"},
+				{Start: 4500, End: 6000, Text: "два"},
+			},
+			want: []string{"раз", "два"},
+		},
+		{
+			name: "missing timings",
+			words: []ai.TranscriptWord{
+				{Start: 1000, End: 1400, Text: "раз"},
+				{Text: "два"},
+				{Text: "три"},
+				{Start: 3000, End: 3400, Text: "четыре"},
+				{Start: -1, End: -1, Text: "пять"},
+			},
+			want: []string{"раз", "два", "три", "четыре", "пять"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seg := ai.TranscriptSegment{Start: 1000, End: 5000, Words: tt.words}
+			got := sanitizeWordTimings([]ai.TranscriptSegment{seg})[0]
+			if len(got.Words) != len(tt.want) {
+				t.Fatalf("got %d words, want %d", len(got.Words), len(tt.want))
+			}
+			for i, text := range tt.want {
+				if got.Words[i].Text != text {
+					t.Errorf("word %d = %q, want %q", i, got.Words[i].Text, text)
+				}
+			}
+			checkWordTimings(t, got.Words, seg.Start, seg.End)
+		})
+	}
+
+	// Интерполированные слова делят пропуск поровну
+	seg := ai.TranscriptSegment{Start: 1000, End: 5000, Words: tests[3].words}
+	got := sanitizeWordTimings([]ai.TranscriptSegment{seg})[0].Words
+	if got[1].Start != 1400 || got[1].End != 2200 || got[2].Start != 2200 || got[2].End != 3000 {
+		t.Errorf("interpolation: got %+v %+v", got[1], got[2])
+	}
+	if got[4].Start != 3400 || got[4].End != 5000 {
+		t.Errorf("trailing missing word: got %+v", got[4])
+	}
+	// Исходные слова не изменяются
+	if tests[3].words[1].Start != 0 {
+		t.Error("input words were modified")
+	}
+}
+
+func TestSplitSegmentsBySpeakersMalformedWords(t *testing.T) {
+	// Перепутанные, перевёрнутые и перекрывающиеся тайминги дают то же разбиение, что и корректные
+	speakerSegs := []ai.SpeakerSegment{
+		{Start: 0, End: 3, Speaker: 0},
+		{Start: 3, End: 6, Speaker: 1},
+	}
+	clean := []ai.TranscriptWord{
+		{Start: 200, End: 700, Text: "Привет"},
+		{Start: 700, End: 1500, Text: "всем."},
+		{Start: 3600, End: 4400, Text: "Да."},
+		{Start: 4400, End: 5500, Text: "Согласен."},
+	}
+	malformed := []ai.TranscriptWord{
+		{Start: 3600, End: 4500, Text: "Да."},
+		{Start: 200, End: 900, Text: "Привет"},
+		{Start: 700, End: 600, Text: "всем."},
+		{Start: 4400, End: 5500, Text: "Согласен."},
+	}
+	split := func(words []ai.TranscriptWord) []ai.TranscriptSegment {
+		segments := []ai.TranscriptSegment{{Start: 0, End: 6000, Text: "Привет всем. Да. Согласен.", Words: words}}
+		return splitSegmentsBySpeakers(segments, speakerSegs, 0.05, 0.5, ai.DefaultMinOverlapRatio)
+	}
+
+	want, got := split(clean), split(malformed)
+	if len(got) != len(want) || len(got) < 2 {
+		t.Fatalf("got %d segments, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Text != want[i].Text || got[i].Speaker != want[i].Speaker {
+			t.Errorf("segment %d = %q (%s), want %q (%s)", i, got[i].Text, got[i].Speaker, want[i].Text, want[i].Speaker)
+		}
+		if i > 0 && got[i-1].End > got[i].Start {
+			t.Errorf("segment %d starts at %d before previous end %d", i, got[i].Start, got[i-1].End)
+		}
+	}
+}
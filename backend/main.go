@@ -15,6 +15,9 @@ import (
 	"os"
 )
 
+// version версия приложения, задаётся при сборке: -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
 	// 1. Load Configuration
 	cfg := config.Load()
@@ -45,12 +48,18 @@ func main() {
 		log.Fatal("Failed to create session manager:", err)
 	}
 
+	// Переопределение языков моделей применяется до любых обращений к реестру
+	if err := models.SetModelLanguages(cfg.ModelLanguages); err != nil {
+		log.Printf("Warning: %v, using registry languages", err)
+	}
+
 	modelMgr, err := models.NewManager(cfg.ModelsDir)
 	if err != nil {
 		log.Fatal("Failed to create model manager:", err)
 	}
 
 	engineMgr := ai.NewEngineManager(modelMgr)
+	engineMgr.SetIdleUnloadTimeout(cfg.EngineIdleUnload)
 
 	// Try to set default model
 	if cfg.ModelPath != "" {
@@ -66,18 +75,107 @@ func main() {
 	}
 	defer capture.Close()
 
+	// FFmpeg нужен для записи MP3, импорта, экспорта и отдачи аудио чанков
+	if cfg.FFmpegPath != "" {
+		session.SetFFmpegPath(cfg.FFmpegPath)
+	}
+	if version, err := session.CheckFFmpeg(); err != nil {
+		if cfg.FFmpegPath != "" {
+			log.Fatalf("FFmpeg check failed: %v", err)
+		}
+		log.Printf("Warning: %v. Falling back to built-in MP3 encoding, MP3/WAV-only import and WAV playback", err)
+	} else {
+		log.Printf("FFmpeg: %s", version)
+	}
+
+	if cfg.SileroVADModel != "" {
+		session.SetSileroModelPath(cfg.SileroVADModel)
+	}
+
+	if err := session.SetMP3Quality(cfg.Mp3Quality); err != nil {
+		log.Printf("Warning: %v, using default MP3 encoding", err)
+	}
+	if err := session.SetNormalizationNoiseFloor(cfg.NormalizeNoiseFloorDBFS); err != nil {
+		log.Printf("Warning: %v, using default %.0f dBFS", err, session.DefaultNormalizationNoiseFloorDBFS)
+	}
+	if err := session.SetSpeakerLabelScheme(session.SpeakerLabelScheme{
+		Prefix:  cfg.SpeakerLabelPrefix,
+		Base:    cfg.SpeakerLabelBase,
+		Letters: cfg.SpeakerLabelLetters,
+		Self:    cfg.SpeakerLabelSelf,
+	}); err != nil {
+		defaults := session.DefaultSpeakerLabelScheme()
+		log.Printf("Warning: %v, using default speaker labels %q/%q", err, defaults.Self, defaults.Label(0))
+	}
+	if err := session.SetQualityWeights(session.QualityWeights{
+		Confidence:  cfg.QualityWeightConfidence,
+		SpeechRatio: cfg.QualityWeightSpeech,
+		Clipping:    cfg.QualityWeightClipping,
+	}); err != nil {
+		defaults := session.DefaultQualityWeights()
+		log.Printf("Warning: %v, using default quality weights %.2f/%.2f/%.2f", err, defaults.Confidence, defaults.SpeechRatio, defaults.Clipping)
+	}
+
 	// 3. Initialize Services
 	transcriptionService := service.NewTranscriptionService(sessionMgr, engineMgr)
 	recordingService := service.NewRecordingService(sessionMgr, capture)
+	recordingService.SetRawCaptureLimit(int64(cfg.RawCaptureMaxMB) << 20)
 	llmService := service.NewLLMService()
+	for op, gen := range map[service.LLMOperation]config.LLMGeneration{
+		service.LLMOpSummary:    cfg.LLMSummary,
+		service.LLMOpImprove:    cfg.LLMImprove,
+		service.LLMOpSelectBest: cfg.LLMSelectBest,
+	} {
+		llmService.SetOptions(op, service.NewLLMOptions(gen.Temperature, gen.TopP, gen.MaxTokens))
+	}
 	streamingTranscriptionService := service.NewStreamingTranscriptionService(modelMgr)
 
 	// Настраиваем LLM для автоулучшения транскрипции
 	transcriptionService.SetLLMService(llmService)
+	transcriptionService.SetTranscribeTimeout(cfg.TranscribeTimeout)
+	if err := transcriptionService.SetMaxConcurrentTranscriptions(cfg.MaxConcurrentTranscriptions); err != nil {
+		log.Printf("Warning: %v, using default %d", err, service.DefaultConcurrentTranscriptions())
+	}
+	transcriptionService.SetLoudnessNormalization(cfg.NormalizeLoudness, cfg.LoudnessTargetDBFS)
+	transcriptionService.SetChunkOverlap(cfg.ChunkOverlap)
+	transcriptionService.SetClipWarnRatio(cfg.ClipWarnRatio)
+	if err := transcriptionService.SetSilenceGuard(cfg.SilenceGuardDBFS); err != nil {
+		log.Printf("Warning: %v, using default %.0f dBFS", err, service.DefaultSilenceGuardDBFS)
+	}
+	if err := transcriptionService.SetSpeakerMatchThreshold(float32(cfg.SpeakerMatchThreshold)); err != nil {
+		log.Printf("Warning: %v, using default %.2f", err, service.DefaultSpeakerMatchThreshold)
+	}
+	if err := transcriptionService.SetRegionMergeConfig(service.RegionMergeConfig{
+		MinRegionMs: cfg.RegionMinMs,
+		MaxGapMs:    cfg.RegionMaxGapMs,
+	}); err != nil {
+		defaults := service.DefaultRegionMergeConfig()
+		log.Printf("Warning: %v, using default region merge %dms/%dms", err, defaults.MinRegionMs, defaults.MaxGapMs)
+	}
+	transcriptionService.SetPromptCarryOver(cfg.PromptCarryOver, cfg.PromptCarryOverChars)
+	if err := transcriptionService.SetCrosstalkDedupConfig(service.CrosstalkDedupConfig{
+		Enabled:           cfg.CrosstalkDedup,
+		MinOverlapRatio:   cfg.CrosstalkMinOverlap,
+		MinTextSimilarity: cfg.CrosstalkMinSimilarity,
+	}); err != nil {
+		log.Printf("Warning: %v, crosstalk dedup disabled", err)
+	}
+	if err := transcriptionService.SetAutoImproveScope(service.AutoImproveScope(cfg.AutoImproveScope)); err != nil {
+		log.Printf("Warning: %v, using default %s", err, service.AutoImproveScopeChunk)
+	}
 	if cfg.AutoImproveWithLLM {
 		transcriptionService.EnableAutoImprove(cfg.OllamaURL, cfg.OllamaModel)
 	}
 
+	// Сведения о приложении для manifest.json сессий
+	sessionMgr.SetManifestEnvironment(func() session.ManifestEnvironment {
+		env := session.ManifestEnvironment{AppVersion: version}
+		if pipeline := transcriptionService.Pipeline; pipeline != nil {
+			env.DiarizationBackend = pipeline.GetDiarizationProvider()
+		}
+		return env
+	})
+
 	// 4. Initialize VoicePrint Store for speaker recognition
 	vpStore, err := voiceprint.NewStore(cfg.DataDir)
 	if err != nil {
@@ -94,6 +192,9 @@ func main() {
 	// 5. Initialize API Server
 	server := api.NewServer(cfg, sessionMgr, engineMgr, modelMgr, capture, transcriptionService, recordingService, llmService, streamingTranscriptionService, vpStore, vpMatcher)
 
+	// Очистка по политике хранения (после NewServer: итоги рассылаются клиентам)
+	sessionMgr.StartRetentionCleaner(cfg.RetentionCheckInterval)
+
 	// 5. Start Server
 	log.Println("Starting AIWisper Backend...")
 	server.Start()
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedLanguage модель не поддерживает выбранный язык
+var ErrUnsupportedLanguage = errors.New("language is not supported by the model")
+
+// SetModelLanguages переопределяет языки моделей распознавания из реестра
+// (например, если модель на практике справляется с языком, которого нет в описании).
+// Вызывается при старте до обращения к реестру; при ошибке реестр не меняется
+func SetModelLanguages(overrides map[string][]string) error {
+	for id, langs := range overrides {
+		info := GetModelByID(id)
+		if info == nil {
+			return fmt.Errorf("unknown model %q in language mapping", id)
+		}
+		if !info.IsTranscriptionModel() {
+			return fmt.Errorf("model %q is not a transcription model", id)
+		}
+		if len(langs) == 0 {
+			return fmt.Errorf("empty language list for model %q", id)
+		}
+	}
+	for i := range Registry {
+		if langs, ok := overrides[Registry[i].ID]; ok {
+			Registry[i].Languages = langs
+		}
+	}
+	return nil
+}
+
+// DefaultLanguage язык, на который переключается сессия при несовпадении:
+// первый конкретный язык модели. Пусто для мультиязычных моделей
+func (m ModelInfo) DefaultLanguage() string {
+	for _, l := range m.Languages {
+		if l == "multi" {
+			return ""
+		}
+	}
+	if len(m.Languages) == 0 {
+		return ""
+	}
+	return m.Languages[0]
+}
+
+// CheckLanguage проверяет язык для модели. Ошибка оборачивает ErrUnsupportedLanguage
+func (m ModelInfo) CheckLanguage(lang string) error {
+	if m.SupportsLanguage(lang) {
+		return nil
+	}
+	return fmt.Errorf("model %s does not support language %q (supported: %s): %w",
+		m.ID, lang, strings.Join(m.Languages, ", "), ErrUnsupportedLanguage)
+}
@@ -18,6 +18,8 @@ type Manager struct {
 	modelsDir   string
 	activeModel string
 	downloads   map[string]context.CancelFunc // Активные загрузки
+	progress    map[string]float64            // Последний прогресс активных загрузок (0-100)
+	failed      map[string]string             // Ошибка последней неудачной загрузки
 	mu          sync.RWMutex
 	onProgress  ProgressCallback
 }
@@ -32,6 +34,8 @@ func NewManager(modelsDir string) (*Manager, error) {
 	return &Manager{
 		modelsDir: modelsDir,
 		downloads: make(map[string]context.CancelFunc),
+		progress:  make(map[string]float64),
+		failed:    make(map[string]string),
 	}, nil
 }
 
@@ -175,6 +179,14 @@ func (m *Manager) GetAllModelsState() []ModelState {
 	for id := range m.downloads {
 		downloads[id] = true
 	}
+	progress := make(map[string]float64, len(m.progress))
+	for id, p := range m.progress {
+		progress[id] = p
+	}
+	failed := make(map[string]string, len(m.failed))
+	for id, e := range m.failed {
+		failed[id] = e
+	}
 	m.mu.RUnlock()
 
 	states := make([]ModelState, len(Registry))
@@ -186,12 +198,16 @@ func (m *Manager) GetAllModelsState() []ModelState {
 
 		if downloads[info.ID] {
 			state.Status = ModelStatusDownloading
+			state.Progress = progress[info.ID]
 		} else if m.IsModelDownloaded(info.ID) {
 			if info.ID == activeModel {
 				state.Status = ModelStatusActive
 			} else {
 				state.Status = ModelStatusDownloaded
 			}
+		} else if errMsg, ok := failed[info.ID]; ok {
+			state.Status = ModelStatusError
+			state.Error = errMsg
 		} else {
 			state.Status = ModelStatusNotDownloaded
 		}
@@ -219,6 +235,8 @@ func (m *Manager) DownloadModel(modelID string) error {
 	// Создаём контекст с возможностью отмены
 	ctx, cancel := context.WithCancel(context.Background())
 	m.downloads[modelID] = cancel
+	m.progress[modelID] = 0
+	delete(m.failed, modelID)
 	m.mu.Unlock()
 
 	// Запускаем скачивание в горутине
@@ -226,6 +244,7 @@ func (m *Manager) DownloadModel(modelID string) error {
 		defer func() {
 			m.mu.Lock()
 			delete(m.downloads, modelID)
+			delete(m.progress, modelID)
 			m.mu.Unlock()
 		}()
 
@@ -457,11 +476,17 @@ func (m *Manager) DeleteModel(modelID string) error {
 	return nil
 }
 
-// notifyProgress уведомляет о прогрессе
+// notifyProgress уведомляет о прогрессе и запоминает его (и ошибку) для опроса через GetAllModelsState
 func (m *Manager) notifyProgress(modelID string, progress float64, status ModelStatus, err error) {
-	m.mu.RLock()
+	m.mu.Lock()
+	if _, ok := m.downloads[modelID]; ok {
+		m.progress[modelID] = progress
+	}
+	if status == ModelStatusError && err != nil {
+		m.failed[modelID] = err.Error()
+	}
 	cb := m.onProgress
-	m.mu.RUnlock()
+	m.mu.Unlock()
 
 	if cb != nil {
 		cb(modelID, progress, status, err)
@@ -1,6 +1,8 @@
 // Package models предоставляет управление моделями транскрипции
 package models
 
+import "time"
+
 // ModelType тип модели
 type ModelType string
 
@@ -54,6 +56,13 @@ type ModelInfo struct {
 	// Поля для диаризации
 	DiarizationType DiarizationModelType `json:"diarizationType,omitempty"` // Тип модели диаризации
 	IsArchive       bool                 `json:"isArchive,omitempty"`       // Модель в архиве (tar.bz2)
+
+	// Оценка потребления памяти загруженной моделью (для выбора модели по умолчанию в UI)
+	ApproxRAM      string `json:"approxRam,omitempty"`
+	ApproxRAMBytes int64  `json:"approxRamBytes,omitempty"`
+
+	// SupportsWordTimestamps модель выдаёт таймстемпы и вероятность для каждого слова
+	SupportsWordTimestamps bool `json:"supportsWordTimestamps,omitempty"`
 }
 
 // ModelStatus статус модели на устройстве
@@ -90,6 +99,10 @@ var Registry = []ModelInfo{
 		Languages:   []string{"multi"},
 		Speed:       "~10x",
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-tiny.bin",
+
+		ApproxRAM:              "~390 MB",
+		ApproxRAMBytes:         390_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "ggml-base",
@@ -102,6 +115,10 @@ var Registry = []ModelInfo{
 		Languages:   []string{"multi"},
 		Speed:       "~7x",
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-base.bin",
+
+		ApproxRAM:              "~500 MB",
+		ApproxRAMBytes:         500_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "ggml-small",
@@ -114,6 +131,10 @@ var Registry = []ModelInfo{
 		Languages:   []string{"multi"},
 		Speed:       "~4x",
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-small.bin",
+
+		ApproxRAM:              "~1 GB",
+		ApproxRAMBytes:         1_000_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "ggml-medium",
@@ -126,6 +147,10 @@ var Registry = []ModelInfo{
 		Languages:   []string{"multi"},
 		Speed:       "~2x",
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-medium.bin",
+
+		ApproxRAM:              "~2.6 GB",
+		ApproxRAMBytes:         2_600_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "ggml-large-v3-turbo",
@@ -139,6 +164,10 @@ var Registry = []ModelInfo{
 		Speed:       "~8x",
 		Recommended: true,
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-large-v3-turbo.bin",
+
+		ApproxRAM:              "~1.8 GB",
+		ApproxRAMBytes:         1_800_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "ggml-large-v3",
@@ -152,6 +181,10 @@ var Registry = []ModelInfo{
 		Speed:       "~1x",
 		Recommended: true,
 		DownloadURL: "https://huggingface.co/ggerganov/whisper.cpp/resolve/main/ggml-large-v3.bin",
+
+		ApproxRAM:              "~3.9 GB",
+		ApproxRAMBytes:         3_900_000_000,
+		SupportsWordTimestamps: true,
 	},
 
 	// ===== CoreML модели (FluidAudio) =====
@@ -169,6 +202,10 @@ var Registry = []ModelInfo{
 		Recommended: true,
 		// Модель скачивается автоматически FluidAudio при первом использовании
 		DownloadURL: "", // Управляется FluidAudio
+
+		ApproxRAM:              "~1.2 GB",
+		ApproxRAMBytes:         1_200_000_000,
+		SupportsWordTimestamps: true,
 	},
 
 	// ===== ONNX модели (GigaAM) =====
@@ -187,6 +224,10 @@ var Registry = []ModelInfo{
 		Recommended: true,
 		DownloadURL: "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_ctc.int8.onnx",
 		VocabURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_vocab.txt",
+
+		ApproxRAM:              "~600 MB",
+		ApproxRAMBytes:         600_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "gigaam-v3-e2e-ctc",
@@ -202,6 +243,10 @@ var Registry = []ModelInfo{
 		Recommended: true,
 		DownloadURL: "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_e2e_ctc.int8.onnx",
 		VocabURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_e2e_ctc_vocab.txt",
+
+		ApproxRAM:              "~600 MB",
+		ApproxRAMBytes:         600_000_000,
+		SupportsWordTimestamps: true,
 	},
 	// RNNT модели - лучшее качество, последовательное декодирование
 	{
@@ -221,6 +266,10 @@ var Registry = []ModelInfo{
 		DecoderURL:  "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_rnnt_decoder.int8.onnx",
 		JointURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_rnnt_joint.int8.onnx",
 		VocabURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_vocab.txt",
+
+		ApproxRAM:              "~650 MB",
+		ApproxRAMBytes:         650_000_000,
+		SupportsWordTimestamps: true,
 	},
 	{
 		ID:          "gigaam-v3-e2e-rnnt",
@@ -239,6 +288,10 @@ var Registry = []ModelInfo{
 		DecoderURL:  "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_e2e_rnnt_decoder.int8.onnx",
 		JointURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_e2e_rnnt_joint.int8.onnx",
 		VocabURL:    "https://huggingface.co/istupakov/gigaam-v3-onnx/resolve/main/v3_e2e_rnnt_vocab.txt",
+
+		ApproxRAM:              "~650 MB",
+		ApproxRAMBytes:         650_000_000,
+		SupportsWordTimestamps: true,
 	},
 
 	// ===== Модели диаризации (Diarization) =====
@@ -255,6 +308,9 @@ var Registry = []ModelInfo{
 		Speed:           "~100x",
 		IsArchive:       true,
 		DownloadURL:     "https://github.com/k2-fsa/sherpa-onnx/releases/download/speaker-segmentation-models/sherpa-onnx-pyannote-segmentation-3-0.tar.bz2",
+
+		ApproxRAM:      "~40 MB",
+		ApproxRAMBytes: 40_000_000,
 	},
 	{
 		ID:              "3dspeaker-speech-eres2net",
@@ -268,6 +324,9 @@ var Registry = []ModelInfo{
 		Languages:       []string{"multi"},
 		Speed:           "~50x",
 		DownloadURL:     "https://github.com/k2-fsa/sherpa-onnx/releases/download/speaker-recongition-models/3dspeaker_speech_eres2net_base_sv_zh-cn_3dspeaker_16k.onnx",
+
+		ApproxRAM:      "~100 MB",
+		ApproxRAMBytes: 100_000_000,
 	},
 	{
 		ID:              "wespeaker-voxceleb-resnet34",
@@ -282,6 +341,9 @@ var Registry = []ModelInfo{
 		Speed:           "~40x",
 		Recommended:     true,
 		DownloadURL:     "https://github.com/k2-fsa/sherpa-onnx/releases/download/speaker-recongition-models/wespeaker_en_voxceleb_resnet34.onnx",
+
+		ApproxRAM:      "~120 MB",
+		ApproxRAMBytes: 120_000_000,
 	},
 
 	// ===== Модели VAD (Voice Activity Detection) =====
@@ -297,6 +359,9 @@ var Registry = []ModelInfo{
 		Speed:       "~1000x",
 		Recommended: true,
 		DownloadURL: "https://github.com/snakers4/silero-vad/raw/master/src/silero_vad/data/silero_vad.onnx",
+
+		ApproxRAM:      "~20 MB",
+		ApproxRAMBytes: 20_000_000,
 	},
 }
 
@@ -321,6 +386,39 @@ func GetModelByID(id string) *ModelInfo {
 	return nil
 }
 
+// SupportsLanguage проверяет, поддерживает ли модель язык.
+// Пустой язык и "auto" подходят любой модели, "multi" - любому языку
+func (m ModelInfo) SupportsLanguage(lang string) bool {
+	if lang == "" || lang == "auto" {
+		return true
+	}
+	for _, l := range m.Languages {
+		if l == "multi" || l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTranscriptionModel возвращает true для моделей распознавания речи
+func (m ModelInfo) IsTranscriptionModel() bool {
+	switch m.Engine {
+	case EngineTypeWhisper, EngineTypeGigaAM, EngineTypeFluidASR:
+		return true
+	}
+	return false
+}
+
+// MinUsefulChunkDuration минимальная длительность чанка, на которой модель распознаёт без потери качества
+// (0 - ограничений нет). Whisper рассчитан на 30-секундное окно: на коротких чанках ему не хватает
+// контекста и растёт число галлюцинаций
+func (m ModelInfo) MinUsefulChunkDuration() time.Duration {
+	if m.Engine == EngineTypeWhisper {
+		return 10 * time.Second
+	}
+	return 0
+}
+
 // GetModelsByType возвращает модели определённого типа
 func GetModelsByType(modelType ModelType) []ModelInfo {
 	var result []ModelInfo
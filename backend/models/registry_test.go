@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRegistryMetadata(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, m := range Registry {
+		if seen[m.ID] {
+			t.Errorf("duplicate model id %s", m.ID)
+		}
+		seen[m.ID] = true
+
+		if m.Engine == "" || len(m.Languages) == 0 {
+			t.Errorf("%s: engine and languages are required", m.ID)
+		}
+		if m.ApproxRAM == "" || m.ApproxRAMBytes < m.SizeBytes/2 {
+			t.Errorf("%s: approx RAM %q (%d bytes) is missing or too small for the model", m.ID, m.ApproxRAM, m.ApproxRAMBytes)
+		}
+		if m.SupportsWordTimestamps != m.IsTranscriptionModel() {
+			t.Errorf("%s: supportsWordTimestamps = %v", m.ID, m.SupportsWordTimestamps)
+		}
+	}
+}
+
+func TestSetModelLanguages(t *testing.T) {
+	saved := slices.Clone(Registry)
+	t.Cleanup(func() { Registry = saved })
+
+	if err := SetModelLanguages(map[string][]string{"no-such-model": {"en"}}); err == nil {
+		t.Error("expected error for unknown model")
+	}
+	if err := SetModelLanguages(map[string][]string{"gigaam-v3-ctc": {"kk"}, "silero-vad-v5": {"en"}}); err == nil {
+		t.Error("expected error for non-transcription model")
+	}
+	if info := GetModelByID("gigaam-v3-ctc"); info.SupportsLanguage("kk") {
+		t.Error("registry changed by a rejected mapping")
+	}
+
+	if err := SetModelLanguages(map[string][]string{"gigaam-v3-ctc": {"ru", "kk"}}); err != nil {
+		t.Fatal(err)
+	}
+	info := GetModelByID("gigaam-v3-ctc")
+	if !info.SupportsLanguage("kk") || info.CheckLanguage("en") == nil || info.DefaultLanguage() != "ru" {
+		t.Errorf("languages after override = %q", info.Languages)
+	}
+}
+
+func TestModelsStateProgress(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := func(id string) ModelState {
+		for _, s := range m.GetAllModelsState() {
+			if s.ID == id {
+				return s
+			}
+		}
+		t.Fatalf("model %s not in state", id)
+		return ModelState{}
+	}
+
+	m.downloads["ggml-tiny"] = func() {}
+	m.notifyProgress("ggml-tiny", 42, ModelStatusDownloading, nil)
+	if s := state("ggml-tiny"); s.Status != ModelStatusDownloading || s.Progress != 42 {
+		t.Errorf("downloading state = %s %.0f", s.Status, s.Progress)
+	}
+
+	m.notifyProgress("ggml-tiny", 0, ModelStatusError, errors.New("connection reset"))
+	delete(m.downloads, "ggml-tiny")
+	if s := state("ggml-tiny"); s.Status != ModelStatusError || s.Error != "connection reset" {
+		t.Errorf("failed state = %s %q", s.Status, s.Error)
+	}
+}
+
+func TestSupportsLanguage(t *testing.T) {
+	gigaam := GetModelByID("gigaam-v3-ctc")
+	whisper := GetModelByID("ggml-base")
+	for _, lang := range []string{"", "auto", "ru"} {
+		if !gigaam.SupportsLanguage(lang) {
+			t.Errorf("gigaam should support %q", lang)
+		}
+	}
+	if gigaam.SupportsLanguage("en") {
+		t.Error("gigaam should not support en")
+	}
+	if !whisper.SupportsLanguage("en") || !whisper.SupportsLanguage("kk") {
+		t.Error("multilingual whisper should support any language")
+	}
+
+	if !whisper.IsTranscriptionModel() || !GetModelByID("parakeet-tdt-v3").IsTranscriptionModel() {
+		t.Error("ASR models should be transcription models")
+	}
+	if GetModelByID("silero-vad-v5").IsTranscriptionModel() || GetModelByID("pyannote-segmentation-3.0").IsTranscriptionModel() {
+		t.Error("VAD and diarization models are not transcription models")
+	}
+}
@@ -0,0 +1,287 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Работа с аудио без FFmpeg: чтение/запись WAV, вырезка фрагментов для воспроизведения
+// и импорт MP3/WAV. Используется, когда FFmpeg недоступен (см. RequireFFmpeg)
+
+// PlaybackAudioPath возвращает файл для воспроизведения сессии: full.mp3, а если его нет - full.wav
+func PlaybackAudioPath(dataDir string) string {
+	mp3Path := filepath.Join(dataDir, "full.mp3")
+	if fileExists(mp3Path) {
+		return mp3Path
+	}
+	return filepath.Join(dataDir, "full.wav")
+}
+
+// wavFormat параметры WAV из заголовка
+type wavFormat struct {
+	format, channels, bitsPerSample uint16
+	sampleRate                      uint32
+	dataSize                        int64 // Размер блока data (0 - заголовок не заполнен)
+}
+
+// readWAVFormat читает заголовок WAV до блока data: после возврата r указывает на начало данных
+func readWAVFormat(r io.ReadSeeker, path string) (wavFormat, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return wavFormat{}, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return wavFormat{}, fmt.Errorf("not a WAV file: %s", path)
+	}
+
+	var wf wavFormat
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return wavFormat{}, fmt.Errorf("WAV data chunk not found: %w", err)
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			fmtChunk := make([]byte, size)
+			if _, err := io.ReadFull(r, fmtChunk); err != nil || size < 16 {
+				return wavFormat{}, fmt.Errorf("invalid WAV fmt chunk")
+			}
+			wf.format = binary.LittleEndian.Uint16(fmtChunk[0:2])
+			wf.channels = binary.LittleEndian.Uint16(fmtChunk[2:4])
+			wf.sampleRate = binary.LittleEndian.Uint32(fmtChunk[4:8])
+			wf.bitsPerSample = binary.LittleEndian.Uint16(fmtChunk[14:16])
+			if wf.format == 0xFFFE && size >= 26 { // WAVE_FORMAT_EXTENSIBLE: формат в первых байтах SubFormat
+				wf.format = binary.LittleEndian.Uint16(fmtChunk[24:26])
+			}
+			if size%2 == 1 {
+				r.Seek(1, io.SeekCurrent)
+			}
+
+		case "data":
+			if wf.channels == 0 || wf.sampleRate == 0 {
+				return wavFormat{}, fmt.Errorf("WAV data chunk before fmt chunk")
+			}
+			wf.dataSize = size
+			return wf, nil
+
+		default:
+			if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return wavFormat{}, err
+			}
+		}
+	}
+}
+
+// ReadWAV читает WAV файл (PCM 16 бит или float 32 бита).
+// Возвращает семплы (каналы чередуются), частоту дискретизации и число каналов
+func ReadWAV(path string) ([]float32, int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	wf, err := readWAVFormat(f, path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	// Заголовок нашего WAVWriter может остаться незаполненным, если запись прервалась
+	data, err := io.ReadAll(io.LimitReader(f, wf.dataSize))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if wf.dataSize == 0 {
+		if data, err = io.ReadAll(f); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	samples, err := decodePCM(data, wf.format, wf.bitsPerSample)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return samples, int(wf.sampleRate), int(wf.channels), nil
+}
+
+// WAVSampleRate частота дискретизации WAV файла по заголовку (данные не читаются)
+func WAVSampleRate(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	wf, err := readWAVFormat(f, path)
+	if err != nil {
+		return 0, err
+	}
+	return int(wf.sampleRate), nil
+}
+
+// decodePCM декодирует данные WAV в float32
+func decodePCM(data []byte, format, bitsPerSample uint16) ([]float32, error) {
+	switch {
+	case format == 1 && bitsPerSample == 16:
+		samples := make([]float32, len(data)/2)
+		for i := range samples {
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(data[i*2:]))) / 32768.0
+		}
+		return samples, nil
+	case format == 3 && bitsPerSample == 32:
+		samples := make([]float32, len(data)/4)
+		for i := range samples {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		}
+		return samples, nil
+	default:
+		return nil, fmt.Errorf("unsupported WAV format %d (%d bit), only 16-bit PCM and 32-bit float are supported without FFmpeg", format, bitsPerSample)
+	}
+}
+
+// EncodeWAV кодирует семплы (каналы чередуются) в WAV PCM 16 бит в памяти
+func EncodeWAV(samples []float32, sampleRate, channels int) []byte {
+	var buf bytes.Buffer
+	buf.Write(WAVHeader(uint32(len(samples)*2), sampleRate, channels))
+
+	pcm := make([]byte, 2)
+	for _, s := range samples {
+		s = max(-1, min(1, s))
+		binary.LittleEndian.PutUint16(pcm, uint16(int16(s*32767)))
+		buf.Write(pcm)
+	}
+	return buf.Bytes()
+}
+
+// WAVHeaderSize размер заголовка WAV, который пишет WAVHeader
+const WAVHeaderSize = 44
+
+// WAVHeader заголовок WAV PCM 16 бит для dataSize байт данных
+func WAVHeader(dataSize uint32, sampleRate, channels int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	return buf.Bytes()
+}
+
+// ExtractSegmentWAV вырезает фрагмент из full.mp3 или full.wav без FFmpeg и возвращает его как WAV.
+// Каналы и частота исходного файла сохраняются (MP3 декодируется в стерео)
+func ExtractSegmentWAV(audioPath string, startMs, endMs int64) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(audioPath), ".mp3") {
+		reader, err := NewMP3Reader(audioPath)
+		if err != nil {
+			return nil, err
+		}
+		sampleRate := reader.SampleRate()
+		reader.Close()
+
+		left, right, err := ExtractSegmentStereoGo(audioPath, startMs, endMs, sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		stereo := make([]float32, len(left)*2)
+		for i := range left {
+			stereo[i*2] = left[i]
+			stereo[i*2+1] = right[i]
+		}
+		return EncodeWAV(stereo, sampleRate, 2), nil
+	}
+
+	samples, sampleRate, channels, err := ReadWAV(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	frames := len(samples) / channels
+	start := max(0, int(startMs*int64(sampleRate)/1000))
+	end := min(frames, int(endMs*int64(sampleRate)/1000))
+	if start >= end {
+		return nil, fmt.Errorf("invalid segment: start=%d, end=%d", start, end)
+	}
+	return EncodeWAV(samples[start*channels:end*channels], sampleRate, channels), nil
+}
+
+// ImportAudioGo готовит импортируемый файл без FFmpeg (поддерживаются только MP3 и WAV):
+// wavPath - 16kHz моно для транскрипции, MP3 исходник копируется в mp3Path для воспроизведения
+// (WAV исходник воспроизводится из wavPath). Возвращает длительность в мс
+func ImportAudioGo(srcPath, wavPath, mp3Path string) (int64, error) {
+	var mono []float32
+	var sampleRate int
+
+	switch strings.ToLower(filepath.Ext(srcPath)) {
+	case ".mp3":
+		reader, err := NewMP3Reader(srcPath)
+		if err != nil {
+			return 0, err
+		}
+		mono, err = reader.ReadAllMono()
+		sampleRate = reader.SampleRate()
+		reader.Close()
+		if err != nil {
+			return 0, err
+		}
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(mp3Path, data, 0644); err != nil {
+			return 0, err
+		}
+
+	case ".wav":
+		samples, rate, channels, err := ReadWAV(srcPath)
+		if err != nil {
+			return 0, err
+		}
+		sampleRate = rate
+		mono = make([]float32, len(samples)/channels)
+		for i := range mono {
+			var sum float32
+			for ch := 0; ch < channels; ch++ {
+				sum += samples[i*channels+ch]
+			}
+			mono[i] = sum / float32(channels)
+		}
+
+	default:
+		return 0, fmt.Errorf("%w: %s import requires FFmpeg", ErrFFmpegNotFound, filepath.Ext(srcPath))
+	}
+
+	if sampleRate != WhisperSampleRate {
+		mono = resampleLinear(mono, sampleRate, WhisperSampleRate)
+	}
+
+	writer, err := NewWAVWriter(wavPath, WhisperSampleRate, 1, 16)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.Write(mono); err != nil {
+		writer.Close()
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	durationMs := int64(len(mono)) * 1000 / WhisperSampleRate
+	log.Printf("ImportAudioGo: %s -> %s (%d ms, pure Go, no FFmpeg)", srcPath, wavPath, durationMs)
+	return durationMs, nil
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tone генерирует синус заданной частоты с амплитудой 0.5
+func tone(n, rate int, freq float64) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+func TestEncodeReadWAV(t *testing.T) {
+	// Стерео 8kHz, 1 секунда: левый канал - тон, правый - тишина
+	left := tone(8000, 8000, 440)
+	stereo := make([]float32, len(left)*2)
+	for i, s := range left {
+		stereo[i*2] = s
+	}
+	path := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(path, EncodeWAV(stereo, 8000, 2), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, rate, channels, err := ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 8000 || channels != 2 || len(samples) != len(stereo) {
+		t.Fatalf("got rate=%d ch=%d len=%d", rate, channels, len(samples))
+	}
+	if math.Abs(float64(samples[20]-stereo[20])) > 1e-3 || samples[21] != 0 {
+		t.Errorf("samples differ: %v vs %v", samples[20:22], stereo[20:22])
+	}
+	if rate, err := WAVSampleRate(path); err != nil || rate != 8000 {
+		t.Errorf("WAVSampleRate = %d, %v", rate, err)
+	}
+
+	// Вырезка 250-750 мс
+	data, err := ExtractSegmentWAV(path, 250, 750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 44 + 4000*2*2; len(data) != want || !bytes.HasPrefix(data, []byte("RIFF")) {
+		t.Errorf("segment size = %d, want %d", len(data), want)
+	}
+
+	// Импорт без FFmpeg: 16kHz моно
+	dir := t.TempDir()
+	durationMs, err := ImportAudioGo(path, filepath.Join(dir, "full.wav"), filepath.Join(dir, "full.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if durationMs != 1000 {
+		t.Errorf("duration = %d, want 1000", durationMs)
+	}
+	mono, rate, channels, err := ReadWAV(filepath.Join(dir, "full.wav"))
+	if err != nil || rate != WhisperSampleRate || channels != 1 || len(mono) != WhisperSampleRate {
+		t.Errorf("imported wav: rate=%d ch=%d len=%d err=%v", rate, channels, len(mono), err)
+	}
+	if PlaybackAudioPath(dir) != filepath.Join(dir, "full.wav") {
+		t.Errorf("playback path = %s, want full.wav", PlaybackAudioPath(dir))
+	}
+
+	if _, err := ImportAudioGo(filepath.Join(dir, "in.flac"), "", ""); err == nil {
+		t.Error("expected error for flac without FFmpeg")
+	}
+}
+
+func TestRecordingMP3WriterWithoutFFmpeg(t *testing.T) {
+	defer SetFFmpegPath("")
+	SetFFmpegPath(filepath.Join(t.TempDir(), "no-ffmpeg"))
+
+	path := filepath.Join(t.TempDir(), "full.mp3")
+	w, err := NewRecordingMP3Writer(path, SampleRate, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mono := tone(SampleRate*2, SampleRate, 300)
+	stereo := make([]float32, len(mono)*2)
+	for i, s := range mono {
+		stereo[i*2] = s
+		stereo[i*2+1] = s / 2
+	}
+	if err := w.Write(stereo); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Файл должен читаться встроенным декодером, как и при записи через FFmpeg
+	left, right, err := ExtractSegmentStereoGo(path, 0, 1500, WhisperSampleRate)
+	if err != nil {
+		t.Fatalf("decode shine mp3: %v", err)
+	}
+	if len(left) < WhisperSampleRate || len(right) != len(left) {
+		t.Errorf("decoded %d/%d samples", len(left), len(right))
+	}
+
+	data, err := ExtractSegmentWAV(PlaybackAudioPath(filepath.Dir(path)), 500, 1000)
+	if err != nil || len(data) <= 44 {
+		t.Errorf("ExtractSegmentWAV from mp3: %d bytes, err=%v", len(data), err)
+	}
+}
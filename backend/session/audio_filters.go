@@ -1,8 +1,11 @@
 package session
 
 import (
+	"fmt"
 	"log"
 	"math"
+	"sort"
+	"sync"
 )
 
 // AudioFilterConfig конфигурация фильтров для улучшения качества аудио
@@ -14,6 +17,9 @@ type AudioFilterConfig struct {
 	// Normalization - нормализация громкости
 	NormalizationEnabled bool
 	TargetPeakLevel      float32 // Целевой уровень пика (default: 0.9)
+	// NormalizationNoiseFloorDBFS уровень канала (dBFS), ниже которого усиление не применяется,
+	// чтобы не раздувать фоновый шум почти пустого канала в ложную речь (0 - не проверять)
+	NormalizationNoiseFloorDBFS float64
 
 	// High-Pass Filter - фильтрация низкочастотных помех
 	HighPassEnabled bool
@@ -35,6 +41,8 @@ func DefaultAudioFilterConfig() AudioFilterConfig {
 		HighPassCutoff:       80, // Убираем гул ниже 80 Hz
 		DeClickEnabled:       true,
 		DeClickThreshold:     0.4, // Резкие скачки амплитуды
+
+		NormalizationNoiseFloorDBFS: GetNormalizationNoiseFloor(),
 	}
 }
 
@@ -59,13 +67,23 @@ func ApplyAudioFilters(samples []float32, sampleRate int, config AudioFilterConf
 		result = applyDeClick(result, config.DeClickThreshold)
 	}
 
+	// Уровень канала меряем до noise gate: после него тихий шум превращается в редкие всплески
+	normalize := config.NormalizationEnabled
+	if normalize && config.NormalizationNoiseFloorDBFS < 0 {
+		if level := channelLevelDBFS(result, sampleRate); level < config.NormalizationNoiseFloorDBFS {
+			log.Printf("AudioFilter: Normalization skipped (channel level %.1f dBFS below noise floor %.1f dBFS)",
+				level, config.NormalizationNoiseFloorDBFS)
+			normalize = false
+		}
+	}
+
 	// 3. Noise Gate (подавление тихих участков)
 	if config.NoiseGateEnabled {
 		result = applyNoiseGate(result, sampleRate, config.NoiseGateThreshold)
 	}
 
 	// 4. Normalization (в конце, после очистки)
-	if config.NormalizationEnabled {
+	if normalize {
 		result = applyNormalization(result, config.TargetPeakLevel)
 	}
 
@@ -233,6 +251,121 @@ func applyNormalization(samples []float32, targetPeak float32) []float32 {
 	return result
 }
 
+// DefaultNormalizationNoiseFloorDBFS уровень канала по умолчанию, ниже которого нормализация не усиливает сигнал
+const DefaultNormalizationNoiseFloorDBFS = -48.0
+
+// noiseFloorPercentile перцентиль RMS фреймов, принимаемый за уровень канала:
+// речь должна занимать хотя бы 5% записи, а редкий щелчок уровень не поднимает
+const noiseFloorPercentile = 0.95
+
+var (
+	noiseFloorMu                sync.RWMutex
+	normalizationNoiseFloorDBFS = DefaultNormalizationNoiseFloorDBFS
+)
+
+// SetNormalizationNoiseFloor устанавливает порог уровня канала (dBFS) для нормализации громкости
+func SetNormalizationNoiseFloor(dbfs float64) error {
+	if math.IsNaN(dbfs) || dbfs >= 0 {
+		return fmt.Errorf("invalid normalization noise floor %.1f dBFS: must be negative", dbfs)
+	}
+	noiseFloorMu.Lock()
+	normalizationNoiseFloorDBFS = dbfs
+	noiseFloorMu.Unlock()
+	log.Printf("Normalization noise floor set to: %.1f dBFS", dbfs)
+	return nil
+}
+
+// GetNormalizationNoiseFloor возвращает текущий порог уровня канала для нормализации
+func GetNormalizationNoiseFloor() float64 {
+	noiseFloorMu.RLock()
+	defer noiseFloorMu.RUnlock()
+	return normalizationNoiseFloorDBFS
+}
+
+// channelLevelDBFS оценивает уровень канала как перцентиль RMS фреймов 20мс (dBFS)
+func channelLevelDBFS(samples []float32, sampleRate int) float64 {
+	frameSize := sampleRate / 50
+	if frameSize <= 0 {
+		frameSize = len(samples)
+	}
+	var levels []float64
+	for start := 0; start < len(samples); start += frameSize {
+		levels = append(levels, float64(calculateRMS(samples[start:min(start+frameSize, len(samples))])))
+	}
+	if len(levels) == 0 {
+		return math.Inf(-1)
+	}
+	sort.Float64s(levels)
+	return 20 * math.Log10(levels[int(float64(len(levels)-1)*noiseFloorPercentile)])
+}
+
+// DefaultLoudnessTargetDBFS целевой уровень RMS речи по умолчанию
+const DefaultLoudnessTargetDBFS = -20.0
+
+// maxLoudnessGainDB ограничение усиления/ослабления при нормализации громкости
+const maxLoudnessGainDB = 24.0
+
+// NormalizeLoudness приводит RMS активных (неслышных участков не учитываем) фрагментов
+// к целевому уровню targetDBFS. Усиление ограничено ±24 dB и так, чтобы пик не превышал 0 dBFS.
+// Возвращает обработанные семплы (исходные не изменяются) и применённое усиление в dB
+func NormalizeLoudness(samples []float32, sampleRate int, targetDBFS float64) ([]float32, float64) {
+	if len(samples) == 0 {
+		return samples, 0
+	}
+
+	// Почти пустой канал не усиливаем: поднятый до целевого уровня шум распознаётся как речь
+	if level, floor := channelLevelDBFS(samples, sampleRate), GetNormalizationNoiseFloor(); level < floor {
+		log.Printf("Loudness normalization skipped: channel level %.1f dBFS below noise floor %.1f dBFS", level, floor)
+		return samples, 0
+	}
+
+	// RMS считаем по фреймам 20мс с речью/сигналом, чтобы паузы не занижали уровень
+	frameSize := sampleRate / 50
+	if frameSize <= 0 {
+		frameSize = len(samples)
+	}
+	const activeFrameRMS = 0.003 // ~ -50 dBFS
+	var sum float64
+	var count int
+	var peak float32
+	for start := 0; start < len(samples); start += frameSize {
+		end := min(start+frameSize, len(samples))
+		frame := samples[start:end]
+		if calculateRMS(frame) < activeFrameRMS {
+			continue
+		}
+		for _, s := range frame {
+			sum += float64(s) * float64(s)
+			if a := abs32(s); a > peak {
+				peak = a
+			}
+		}
+		count += len(frame)
+	}
+	if count == 0 {
+		return samples, 0
+	}
+
+	rms := math.Sqrt(sum / float64(count))
+	gainDB := targetDBFS - 20*math.Log10(rms)
+	gainDB = math.Max(-maxLoudnessGainDB, math.Min(maxLoudnessGainDB, gainDB))
+
+	// Не допускаем клиппинга пиков
+	if peakDB := 20 * math.Log10(float64(peak)); peakDB+gainDB > 0 {
+		gainDB = -peakDB
+	}
+	if math.Abs(gainDB) < 0.1 {
+		return samples, 0
+	}
+
+	gain := float32(math.Pow(10, gainDB/20))
+	result := make([]float32, len(samples))
+	for i, s := range samples {
+		result[i] = s * gain
+	}
+	return result, gainDB
+}
+
 // calculateRMS вычисляет RMS (Root Mean Square) для набора семплов
 func calculateRMS(samples []float32) float32 {
 	if len(samples) == 0 {
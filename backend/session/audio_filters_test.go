@@ -0,0 +1,122 @@
+package session
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sine генерирует синус 440 Гц заданной амплитуды
+func sine(amplitude float32, n int) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = amplitude * float32(math.Sin(2*math.Pi*440*float64(i)/16000))
+	}
+	return samples
+}
+
+func rmsDB(samples []float32) float64 {
+	return 20 * math.Log10(float64(calculateRMS(samples)))
+}
+
+func TestNormalizeLoudness_ReachesTarget(t *testing.T) {
+	quiet := sine(0.01, 16000) // ~ -43 dBFS RMS
+	out, gainDB := NormalizeLoudness(quiet, 16000, -30)
+
+	if gainDB <= 0 {
+		t.Fatalf("gain = %.1f dB, want positive", gainDB)
+	}
+	if got := rmsDB(out); math.Abs(got-(-30)) > 0.5 {
+		t.Errorf("RMS after normalization = %.1f dBFS, want -30", got)
+	}
+	if quiet[100] != sine(0.01, 16000)[100] {
+		t.Error("input samples must not be modified")
+	}
+}
+
+func TestNormalizeLoudness_NoClippingAndSilence(t *testing.T) {
+	loud := sine(0.9, 16000)
+	out, _ := NormalizeLoudness(loud, 16000, 0)
+	for _, s := range out {
+		if s > 1 || s < -1 {
+			t.Fatalf("sample %.3f exceeds full scale", s)
+		}
+	}
+
+	silence := make([]float32, 16000)
+	if _, gainDB := NormalizeLoudness(silence, 16000, -20); gainDB != 0 {
+		t.Errorf("silence gain = %.1f dB, want 0", gainDB)
+	}
+}
+
+// peak возвращает максимальную амплитуду
+func peak(samples []float32) float32 {
+	var p float32
+	for _, s := range samples {
+		p = max(p, abs32(s))
+	}
+	return p
+}
+
+// quietNoise шум около -54 dBFS с редкими всплесками выше noise gate (2% фреймов)
+func quietNoise(n int) []float32 {
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = 0.0035 * (rng.Float32()*2 - 1)
+	}
+	frame := 320
+	for f := 0; f*frame < n; f += 50 {
+		for i := f * frame; i < min((f+1)*frame, n); i++ {
+			samples[i] = 0.02 * (rng.Float32()*2 - 1)
+		}
+	}
+	return samples
+}
+
+func TestApplyAudioFilters_NoiseFloorGatesNormalization(t *testing.T) {
+	config := DefaultAudioFilterConfig()
+	const n = 3 * 16000
+
+	silence := make([]float32, n)
+	if p := peak(ApplyAudioFilters(silence, 16000, config)); p != 0 {
+		t.Errorf("silence: peak %.4f, want 0", p)
+	}
+
+	// Почти пустой канал: без порога всплески шума усиливаются в 20 раз
+	if p := peak(ApplyAudioFilters(quietNoise(n), 16000, config)); p > 0.05 {
+		t.Errorf("near-silent noise amplified to peak %.3f", p)
+	}
+	noFloor := config
+	noFloor.NormalizationNoiseFloorDBFS = 0
+	if p := peak(ApplyAudioFilters(quietNoise(n), 16000, noFloor)); p < 0.2 {
+		t.Errorf("without noise floor: peak %.3f, expected noise to be amplified", p)
+	}
+
+	// Тихая речь нормализуется, в том числе с паузами вокруг
+	speech := sine(0.1, n)
+	if p := peak(ApplyAudioFilters(speech, 16000, config)); p < 0.8 {
+		t.Errorf("speech: peak %.3f, want normalized to ~0.9", p)
+	}
+	mixed := append(append(make([]float32, 16000), sine(0.1, 16000)...), make([]float32, 16000)...)
+	if p := peak(ApplyAudioFilters(mixed, 16000, config)); p < 0.8 {
+		t.Errorf("mixed: peak %.3f, want normalized to ~0.9", p)
+	}
+}
+
+func TestNormalizeLoudness_NoiseFloor(t *testing.T) {
+	if _, gainDB := NormalizeLoudness(quietNoise(3*16000), 16000, -20); gainDB != 0 {
+		t.Errorf("near-silent noise gain = %.1f dB, want 0", gainDB)
+	}
+	mixed := append(make([]float32, 16000), sine(0.01, 16000)...)
+	if _, gainDB := NormalizeLoudness(mixed, 16000, -20); gainDB <= 0 {
+		t.Errorf("quiet speech with pause: gain = %.1f dB, want positive", gainDB)
+	}
+
+	if err := SetNormalizationNoiseFloor(3); err == nil {
+		t.Error("positive noise floor must be rejected")
+	}
+	if got := GetNormalizationNoiseFloor(); got != DefaultNormalizationNoiseFloorDBFS {
+		t.Errorf("noise floor changed to %.1f after invalid value", got)
+	}
+}
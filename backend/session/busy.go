@@ -0,0 +1,89 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+)
+
+// sessionBusy счётчики задач, выполняемых над сессиями (ключ: sessionID)
+type sessionBusy struct {
+	mu        sync.Mutex
+	counts    map[string]int
+	exclusive map[string]bool // Сессии, файлы которых переписываются (перенос, слияние, разделение)
+}
+
+// MarkSessionBusy отмечает, что над сессией выполняется задача, без проверки занятости.
+// Занятая сессия не очищается политикой хранения. Отметки вложенные: возвращает функцию
+// снятия этой отметки. Задачи запускаются через TryMarkSessionBusy
+func (m *Manager) MarkSessionBusy(id string) (release func()) {
+	m.busy.mu.Lock()
+	release = m.markSessionBusyLocked(id, false)
+	m.busy.mu.Unlock()
+	return release
+}
+
+// TryMarkSessionBusy атомарно проверяет занятость сессии и отмечает задачу над ней.
+// Задачи обработки (ретранскрипция, повторная диаризация) могут идти над сессией одновременно.
+// Exclusive-задача (перенос, слияние, разделение) переписывает файлы сессии: она требует,
+// чтобы сессия была свободна, и пока она идёт, другие задачи не запускаются
+func (m *Manager) TryMarkSessionBusy(id string, exclusive bool) (release func(), err error) {
+	m.busy.mu.Lock()
+	defer m.busy.mu.Unlock()
+	if m.busy.exclusive[id] || (exclusive && m.busy.counts[id] > 0) {
+		return nil, fmt.Errorf("session %s is busy, try again when processing finishes", id)
+	}
+	return m.markSessionBusyLocked(id, exclusive), nil
+}
+
+// markSessionsExclusive атомарно отмечает exclusive-задачу над несколькими сессиями: если
+// одна из них занята, уже поставленные отметки снимаются. Возвращает функцию снятия всех отметок
+func (m *Manager) markSessionsExclusive(sessions []*Session) (release func(), err error) {
+	releases := make([]func(), 0, len(sessions))
+	release = func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, sess := range sessions {
+		r, err := m.TryMarkSessionBusy(sess.ID, true)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// markSessionBusyLocked добавляет отметку задачи. Вызывается под m.busy.mu
+func (m *Manager) markSessionBusyLocked(id string, exclusive bool) func() {
+	if m.busy.counts == nil {
+		m.busy.counts = make(map[string]int)
+		m.busy.exclusive = make(map[string]bool)
+	}
+	m.busy.counts[id]++
+	if exclusive {
+		m.busy.exclusive[id] = true
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.busy.mu.Lock()
+			if exclusive {
+				delete(m.busy.exclusive, id)
+			}
+			if m.busy.counts[id]--; m.busy.counts[id] <= 0 {
+				delete(m.busy.counts, id)
+			}
+			m.busy.mu.Unlock()
+		})
+	}
+}
+
+// SessionBusy возвращает true, если над сессией выполняется задача (MarkSessionBusy)
+func (m *Manager) SessionBusy(id string) bool {
+	m.busy.mu.Lock()
+	defer m.busy.mu.Unlock()
+	return m.busy.counts[id] > 0
+}
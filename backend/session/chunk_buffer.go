@@ -39,6 +39,8 @@ type ChunkBuffer struct {
 
 	// Время начала записи
 	startTime time.Time
+	// Начало текущей паузы записи (zero - запись идёт)
+	pausedAt time.Time
 
 	// Флаг: можно ли начинать нарезку
 	chunkingEnabled bool
@@ -201,7 +203,7 @@ func (b *ChunkBuffer) tryEmitChunk() {
 			// Если достигли максимума - режем принудительно
 			if availableSamples >= maxChunkSamples {
 				splitPoint = b.emittedSamples + maxChunkSamples
-				log.Printf("Forced chunk split at max duration (5 min)")
+				log.Printf("Forced chunk split at max duration (%v)", b.config.MaxChunkDuration)
 			} else {
 				// Ждём паузу
 				return
@@ -374,6 +376,23 @@ func (b *ChunkBuffer) Reset() {
 	b.startTime = time.Now()
 }
 
+// Pause отмечает начало паузы записи: семплы не поступают, накопленное аудио не нарезается
+func (b *ChunkBuffer) Pause() {
+	if b.pausedAt.IsZero() {
+		b.pausedAt = time.Now()
+	}
+}
+
+// Resume завершает паузу: её длительность не учитывается в задержке начала нарезки.
+// Время чанков считается по семплам, поэтому пауза в них не попадает
+func (b *ChunkBuffer) Resume() {
+	if b.pausedAt.IsZero() {
+		return
+	}
+	b.startTime = b.startTime.Add(time.Since(b.pausedAt))
+	b.pausedAt = time.Time{}
+}
+
 // TotalSamples возвращает общее количество обработанных семплов
 func (b *ChunkBuffer) TotalSamples() int64 {
 	return b.totalSamples
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// feedSynthetic подаёт в буфер стерео поток пакетами по 100 мс: тон, кроме пауз silences ([startMs, endMs])
+func feedSynthetic(b *ChunkBuffer, totalMs int64, silences [][2]int64) []ChunkEvent {
+	packet := int64(SampleRate / 10)
+	var events []ChunkEvent
+	for offset := int64(0); offset*1000/SampleRate < totalMs; offset += packet {
+		mic := make([]float32, packet)
+		sys := make([]float32, packet)
+		for i := range mic {
+			ms := (offset + int64(i)) * 1000 / SampleRate
+			silent := false
+			for _, s := range silences {
+				silent = silent || (ms >= s[0] && ms < s[1])
+			}
+			if !silent {
+				mic[i] = float32(0.3 * math.Sin(float64(offset+int64(i))*2*math.Pi*440/SampleRate))
+			}
+		}
+		b.ProcessStereo(mic, sys)
+		for len(b.Output()) > 0 {
+			events = append(events, <-b.Output())
+		}
+	}
+	return events
+}
+
+func TestChunkBufferConfiguredDuration(t *testing.T) {
+	type bounds [2]int64
+	check := func(t *testing.T, events []ChunkEvent, want []bounds) {
+		t.Helper()
+		if len(events) != len(want) {
+			t.Fatalf("got %d chunks, want %d", len(events), len(want))
+		}
+		for i, event := range events {
+			if event.StartMs != want[i][0] || event.EndMs != want[i][1] {
+				t.Errorf("chunk %d = %d-%d ms, want %d-%d", i, event.StartMs, event.EndMs, want[i][0], want[i][1])
+			}
+			if len(event.MicSamples) != len(event.Samples) || len(event.SysSamples) != len(event.Samples) {
+				t.Errorf("chunk %d: channels %d/%d, mix %d", i, len(event.MicSamples), len(event.SysSamples), len(event.Samples))
+			}
+		}
+	}
+
+	t.Run("fixed interval", func(t *testing.T) {
+		config := FixedIntervalConfig().WithChunkDuration(7 * time.Second)
+		b := NewChunkBuffer(config, SampleRate)
+		defer b.Close()
+		events := feedSynthetic(b, 30000, nil)
+		check(t, append(events, b.FlushAll()...), []bounds{{0, 7000}, {7000, 14000}, {14000, 21000}, {21000, 28000}, {28000, 30000}})
+	})
+
+	t.Run("vad", func(t *testing.T) {
+		config := DefaultVADConfig().WithChunkDuration(10 * time.Second)
+		b := NewChunkBuffer(config, SampleRate)
+		defer b.Close()
+		// Пауза до 10 с не режет чанк, паузы после - режут по середине первой секунды тишины,
+		// без пауз чанк режется на 2x длительности
+		events := feedSynthetic(b, 50000, [][2]int64{{5000, 6200}, {12500, 13700}, {25000, 26200}})
+		check(t, append(events, b.FlushAll()...), []bounds{{0, 13000}, {13000, 25500}, {25500, 45500}, {45500, 50000}})
+	})
+}
+
+func TestValidateChunkDurationMs(t *testing.T) {
+	for _, ms := range []int64{0, MinChunkDurationMs, 30000, MaxChunkDurationMs} {
+		if err := ValidateChunkDurationMs(ms); err != nil {
+			t.Errorf("%d: %v", ms, err)
+		}
+	}
+	for _, ms := range []int64{-1, 4999, MaxChunkDurationMs + 1} {
+		if err := ValidateChunkDurationMs(ms); err == nil {
+			t.Errorf("%d accepted", ms)
+		}
+	}
+}
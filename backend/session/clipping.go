@@ -0,0 +1,88 @@
+package session
+
+// ClipLevel уровень сэмпла, начиная с которого он считается клиппированным
+// (после декодирования MP3 перегруженный сигнал упирается в ±1.0 с небольшим разбросом)
+const ClipLevel = 0.99
+
+// DefaultClipRatioThreshold доля клиппированных сэмплов, начиная с которой вход считается перегруженным
+const DefaultClipRatioThreshold = 0.001
+
+// clipWindowMs размер окна для поиска участков с клиппингом
+const clipWindowMs = 500
+
+// ClippedRange участок с клиппингом (мс от начала записи)
+type ClippedRange struct {
+	StartMs int64   `json:"startMs"`
+	EndMs   int64   `json:"endMs"`
+	Ratio   float64 `json:"ratio"` // Доля клиппированных сэмплов на участке
+}
+
+// ChannelClipping клиппинг в канале чанка
+type ChannelClipping struct {
+	Channel string         `json:"channel"` // mic, sys или mono
+	Ratio   float64        `json:"ratio"`   // Доля клиппированных сэмплов во всём канале
+	Ranges  []ClippedRange `json:"ranges"`
+}
+
+// DetectClipping ищет перегруженные участки: окна, где доля сэмплов с |x| >= ClipLevel
+// не меньше ratioThreshold. Соседние окна склеиваются. offsetMs - начало samples в записи.
+// Возвращает nil, если клиппинга нет или порог не задан
+func DetectClipping(samples []float32, sampleRate int, offsetMs int64, ratioThreshold float64) *ChannelClipping {
+	if ratioThreshold <= 0 || len(samples) == 0 || sampleRate <= 0 {
+		return nil
+	}
+
+	window := max(1, sampleRate*clipWindowMs/1000)
+	result := &ChannelClipping{}
+	total := 0
+	for start := 0; start < len(samples); start += window {
+		end := min(start+window, len(samples))
+		clipped := 0
+		for _, v := range samples[start:end] {
+			if v >= ClipLevel || v <= -ClipLevel {
+				clipped++
+			}
+		}
+		total += clipped
+
+		ratio := float64(clipped) / float64(end-start)
+		if clipped == 0 || ratio < ratioThreshold {
+			continue
+		}
+		startMs := offsetMs + int64(start)*1000/int64(sampleRate)
+		endMs := offsetMs + int64(end)*1000/int64(sampleRate)
+		if n := len(result.Ranges); n > 0 && result.Ranges[n-1].EndMs == startMs {
+			// Продолжение предыдущего участка: пересчитываем долю по длительности
+			prev := &result.Ranges[n-1]
+			prevMs := float64(prev.EndMs - prev.StartMs)
+			prev.Ratio = (prev.Ratio*prevMs + ratio*float64(endMs-startMs)) / (prevMs + float64(endMs-startMs))
+			prev.EndMs = endMs
+			continue
+		}
+		result.Ranges = append(result.Ranges, ClippedRange{StartMs: startMs, EndMs: endMs, Ratio: ratio})
+	}
+
+	result.Ratio = float64(total) / float64(len(samples))
+	if len(result.Ranges) == 0 && result.Ratio < ratioThreshold {
+		return nil
+	}
+	return result
+}
+
+// MarkChunkClipping запоминает клиппинг каналов чанка (сохраняется вместе с результатом транскрипции).
+// Заменяет прежние отметки: пустой список означает, что клиппинга нет
+func (m *Manager) MarkChunkClipping(sessionID, chunkID string, clipping []ChannelClipping) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.Clipping = clipping
+			return
+		}
+	}
+}
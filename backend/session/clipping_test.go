@@ -0,0 +1,40 @@
+package session
+
+import "testing"
+
+func TestDetectClipping(t *testing.T) {
+	const sampleRate = 16000
+	samples := make([]float32, 3*sampleRate) // 3 с тишины
+	for i := range samples {
+		samples[i] = 0.1
+	}
+	// Перегруз с 1.0 до 1.75 с: задевает окна 1000-1500 и 1500-2000 мс
+	for i := sampleRate; i < sampleRate*7/4; i += 2 {
+		samples[i] = 1
+		samples[i+1] = -1
+	}
+
+	got := DetectClipping(samples, sampleRate, 60000, DefaultClipRatioThreshold)
+	if got == nil {
+		t.Fatal("clipping not detected")
+	}
+	if len(got.Ranges) != 1 || got.Ranges[0].StartMs != 61000 || got.Ranges[0].EndMs != 62000 {
+		t.Fatalf("ranges = %+v, want one range 61000-62000", got.Ranges)
+	}
+	if got.Ranges[0].Ratio < 0.7 || got.Ranges[0].Ratio > 0.8 {
+		t.Errorf("range ratio = %.3f, want 0.75", got.Ranges[0].Ratio)
+	}
+	if got.Ratio < 0.24 || got.Ratio > 0.26 {
+		t.Errorf("channel ratio = %.3f, want 0.25", got.Ratio)
+	}
+
+	// Единичные пики ниже порога не считаются перегрузом
+	samples = make([]float32, sampleRate)
+	samples[100] = 1
+	if got := DetectClipping(samples, sampleRate, 0, DefaultClipRatioThreshold); got != nil {
+		t.Errorf("single peak reported as clipping: %+v", got)
+	}
+	if got := DetectClipping(samples, sampleRate, 0, 0); got != nil {
+		t.Errorf("disabled check returned %+v", got)
+	}
+}
@@ -0,0 +1,368 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Стандартные слои диалога сессии
+const (
+	DialogueLayerRaw        = "raw"        // Исходный результат ASR
+	DialogueLayerImproved   = "improved"   // Улучшен LLM (improve_transcription, автоулучшение)
+	DialogueLayerDiarized   = "diarized"   // Разбит на собеседников LLM (diarize_with_llm)
+	DialogueLayerTranslated = "translated" // Переведён LLM
+)
+
+// dialogueLayersFile файл вариантов диалога сессии
+const dialogueLayersFile = "dialogue_layers.json"
+
+// dialogueLayerNameRe допустимое имя слоя
+var dialogueLayerNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// DialogueLayer вариант диалога сессии. Хранится по чанкам, чтобы улучшение
+// отдельных чанков (автоулучшение по чанкам) дополняло слой, а не заменяло его
+type DialogueLayer struct {
+	UpdatedAt time.Time                   `json:"updatedAt"`
+	Chunks    map[int][]TranscriptSegment `json:"chunks"`          // Диалог по индексу чанка
+	Stale     map[int]bool                `json:"stale,omitempty"` // Чанки, перераспознанные после построения слоя
+}
+
+// dialogueLayerSet содержимое dialogue_layers.json
+type dialogueLayerSet struct {
+	Active string                    `json:"active,omitempty"`
+	Layers map[string]*DialogueLayer `json:"layers"`
+}
+
+// ValidateDialogueLayerName проверяет имя слоя диалога
+func ValidateDialogueLayerName(name string) error {
+	if !dialogueLayerNameRe.MatchString(name) {
+		return fmt.Errorf("invalid dialogue layer name %q: expected lowercase letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// loadDialogueLayers загружает варианты диалога из директории сессии
+func (s *Session) loadDialogueLayers() {
+	data, err := os.ReadFile(filepath.Join(s.DataDir, dialogueLayersFile))
+	if err != nil {
+		return
+	}
+	var set dialogueLayerSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		log.Printf("Failed to parse %s for session %s: %v", dialogueLayersFile, s.ID, err)
+		return
+	}
+	s.layers = &set
+	s.syncDialogueLayerNamesLocked()
+}
+
+// layerLocked возвращает слой по имени, при create - создаёт пустой. Вызывается под s.mu
+func (s *Session) layerLocked(name string, create bool) *DialogueLayer {
+	if s.layers == nil {
+		if !create {
+			return nil
+		}
+		s.layers = &dialogueLayerSet{Layers: make(map[string]*DialogueLayer)}
+	}
+	layer := s.layers.Layers[name]
+	if layer == nil && create {
+		layer = &DialogueLayer{Chunks: make(map[int][]TranscriptSegment)}
+		s.layers.Layers[name] = layer
+	}
+	return layer
+}
+
+// captureRawDialogueLocked сохраняет результат ASR чанка в слой "raw" перед первой правкой
+func (s *Session) captureRawDialogueLocked(chunk *Chunk) {
+	raw := s.layerLocked(DialogueLayerRaw, true)
+	if _, ok := raw.Chunks[chunk.Index]; !ok {
+		raw.Chunks[chunk.Index] = slices.Clone(chunk.Dialogue)
+		raw.UpdatedAt = time.Now()
+	}
+}
+
+// markChunkDialogueLayersStaleLocked обновляет варианты диалога чанка после новой транскрипции
+// и сохраняет их. Вызывается после записи нового результата в чанк, под s.mu
+func (s *Session) markChunkDialogueLayersStaleLocked(chunk *Chunk) {
+	if s.layers == nil {
+		return
+	}
+	s.staleChunkDialogueLocked(chunk)
+	if err := s.saveDialogueLayersLocked(); err != nil {
+		log.Printf("Failed to update dialogue layers for session %s: %v", s.ID, err)
+	}
+}
+
+// markDialogueLayersStaleLocked то же для всех чанков сессии (полная ретранскрипция)
+func (s *Session) markDialogueLayersStaleLocked() {
+	if s.layers == nil {
+		return
+	}
+	for _, chunk := range s.Chunks {
+		s.staleChunkDialogueLocked(chunk)
+	}
+	if err := s.saveDialogueLayersLocked(); err != nil {
+		log.Printf("Failed to update dialogue layers for session %s: %v", s.ID, err)
+	}
+}
+
+// staleChunkDialogueLocked записывает новый результат ASR чанка в слой "raw". Остальные слои
+// построены по прежнему результату: они не удаляются, а чанк в них помечается устаревшим,
+// чтобы клиент мог предложить повторить улучшение
+func (s *Session) staleChunkDialogueLocked(chunk *Chunk) {
+	for name, layer := range s.layers.Layers {
+		if _, ok := layer.Chunks[chunk.Index]; !ok || name == DialogueLayerRaw {
+			continue
+		}
+		if layer.Stale == nil {
+			layer.Stale = make(map[int]bool)
+		}
+		layer.Stale[chunk.Index] = true
+	}
+	raw := s.layerLocked(DialogueLayerRaw, true)
+	raw.Chunks[chunk.Index] = slices.Clone(chunk.Dialogue)
+	raw.UpdatedAt = time.Now()
+}
+
+// saveDialogueLayersLocked сохраняет варианты диалога на диск. Пустые слои удаляются
+func (s *Session) saveDialogueLayersLocked() error {
+	for name, layer := range s.layers.Layers {
+		if len(layer.Chunks) == 0 {
+			delete(s.layers.Layers, name)
+			continue
+		}
+		for index := range layer.Stale {
+			if _, ok := layer.Chunks[index]; !ok {
+				delete(layer.Stale, index)
+			}
+		}
+	}
+	if s.layers.Layers[s.layers.Active] == nil {
+		s.layers.Active = ""
+	}
+	s.syncDialogueLayerNamesLocked()
+
+	data, err := json.MarshalIndent(s.layers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dialogue layers: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.DataDir, dialogueLayersFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save dialogue layers: %w", err)
+	}
+	return nil
+}
+
+// syncDialogueLayerNamesLocked обновляет публичные поля DialogueLayers, StaleDialogueLayers и ActiveDialogueLayer
+func (s *Session) syncDialogueLayerNamesLocked() {
+	s.DialogueLayers, s.StaleDialogueLayers, s.ActiveDialogueLayer = nil, nil, ""
+	if s.layers == nil {
+		return
+	}
+	for name, layer := range s.layers.Layers {
+		s.DialogueLayers = append(s.DialogueLayers, name)
+		if len(layer.Stale) > 0 {
+			s.StaleDialogueLayers = append(s.StaleDialogueLayers, name)
+		}
+	}
+	slices.Sort(s.DialogueLayers)
+	slices.Sort(s.StaleDialogueLayers)
+	s.ActiveDialogueLayer = s.layers.Active
+}
+
+// dialogueLayerChunkLocked диалог чанка в слое name: сам слой, иначе исходный результат ASR
+func (s *Session) dialogueLayerChunkLocked(name string, chunk *Chunk) []TranscriptSegment {
+	if layer := s.layerLocked(name, false); layer != nil {
+		if dialogue, ok := layer.Chunks[chunk.Index]; ok {
+			return dialogue
+		}
+	}
+	if raw := s.layerLocked(DialogueLayerRaw, false); raw != nil {
+		if dialogue, ok := raw.Chunks[chunk.Index]; ok {
+			return dialogue
+		}
+	}
+	// Чанк не правился ни одним слоем - показанный диалог и есть исходный
+	return chunk.Dialogue
+}
+
+// renameDialogueLayersSpeakerLocked переименовывает спикера во всех слоях. Возвращает true если были изменения
+func (s *Session) renameDialogueLayersSpeakerLocked(oldName, newName string) bool {
+	if s.layers == nil {
+		return false
+	}
+	modified := false
+	for _, layer := range s.layers.Layers {
+		for _, dialogue := range layer.Chunks {
+			for i := range dialogue {
+				if dialogue[i].Speaker == oldName {
+					dialogue[i].Speaker = newName
+					modified = true
+				}
+			}
+		}
+	}
+	return modified
+}
+
+// splitDialogueByChunks распределяет диалог сессии по чанкам на основе timestamps.
+// Возвращает map[индекс чанка]сегменты, только для чанков с сегментами
+func splitDialogueByChunks(chunks []*Chunk, dialogue []TranscriptSegment) map[int][]TranscriptSegment {
+	result := make(map[int][]TranscriptSegment)
+	if len(chunks) == 1 {
+		if len(dialogue) > 0 {
+			result[chunks[0].Index] = dialogue
+		}
+		return result
+	}
+
+	var offset int64
+	for _, chunk := range chunks {
+		start, end := offset, offset+int64(chunk.Duration/time.Millisecond)
+		offset = end
+		for _, seg := range dialogue {
+			// Сегмент попадает в чанк если его начало в диапазоне чанка
+			if seg.Start >= start && seg.Start < end {
+				result[chunk.Index] = append(result[chunk.Index], seg)
+			}
+		}
+	}
+	return result
+}
+
+// SetDialogueLayer сохраняет вариант диалога сессии (улучшенный, диаризованный LLM и т.п.)
+// как слой name и показывает его в чанках. Исходный результат ASR каждого чанка
+// сохраняется в слое "raw" перед первой правкой, поэтому к нему всегда можно вернуться
+func (m *Manager) SetDialogueLayer(sessionID, name string, dialogue []TranscriptSegment) error {
+	if err := ValidateDialogueLayerName(name); err != nil {
+		return err
+	}
+	if name == DialogueLayerRaw {
+		return fmt.Errorf("dialogue layer %q is reserved for the original transcription", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if len(session.Chunks) == 0 {
+		return fmt.Errorf("session has no chunks")
+	}
+
+	byChunk := splitDialogueByChunks(session.Chunks, dialogue)
+	layer := session.layerLocked(name, true)
+	for _, chunk := range session.Chunks {
+		chunkDialogue, ok := byChunk[chunk.Index]
+		if !ok {
+			continue
+		}
+		session.captureRawDialogueLocked(chunk)
+		layer.Chunks[chunk.Index] = chunkDialogue
+		delete(layer.Stale, chunk.Index)
+		chunk.Dialogue = slices.Clone(chunkDialogue)
+		chunk.Transcription = formatDialogue(chunkDialogue)
+
+		// Сохраняем метаданные чанка
+		chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+		data, _ := json.MarshalIndent(chunk, "", "  ")
+		os.WriteFile(chunkMetaPath, data, 0644)
+	}
+	layer.UpdatedAt = time.Now()
+	session.layers.Active = name
+	if err := session.saveDialogueLayersLocked(); err != nil {
+		return err
+	}
+
+	log.Printf("SetDialogueLayer: session %s layer %q updated in %d chunks with %d segments",
+		sessionID, name, len(byChunk), len(dialogue))
+	return nil
+}
+
+// ApplyDialogueLayer показывает в чанках сессии сохранённый слой диалога ("raw" - исходный результат ASR)
+func (m *Manager) ApplyDialogueLayer(sessionID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.layerLocked(name, false) == nil {
+		if name != DialogueLayerRaw {
+			return fmt.Errorf("dialogue layer %q not found", name)
+		}
+		return nil // Исходник ещё не правился - чанки уже показывают его
+	}
+
+	for _, chunk := range session.Chunks {
+		dialogue := session.dialogueLayerChunkLocked(name, chunk)
+		chunk.Dialogue = slices.Clone(dialogue)
+		chunk.Transcription = formatDialogue(dialogue)
+
+		chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+		data, _ := json.MarshalIndent(chunk, "", "  ")
+		os.WriteFile(chunkMetaPath, data, 0644)
+	}
+
+	session.layers.Active = name
+	if name == DialogueLayerRaw {
+		session.layers.Active = ""
+	}
+	return session.saveDialogueLayersLocked()
+}
+
+// GetDialogueLayer возвращает диалог сессии в слое name (пусто - показанный в чанках).
+// Чанки, которых нет в слое, берутся из исходного результата ASR
+func (m *Manager) GetDialogueLayer(sessionID, name string) ([]TranscriptSegment, error) {
+	byChunk, err := m.GetDialogueLayerChunks(sessionID, name)
+	if err != nil {
+		return nil, err
+	}
+	var dialogue []TranscriptSegment
+	for _, chunkDialogue := range byChunk {
+		dialogue = append(dialogue, chunkDialogue...)
+	}
+	return dialogue, nil
+}
+
+// GetDialogueLayerChunks возвращает диалог слоя name по чанкам в порядке чанков сессии
+// (элемент i - чанк session.Chunks[i]), по тем же правилам, что и GetDialogueLayer
+func (m *Manager) GetDialogueLayerChunks(sessionID, name string) ([][]TranscriptSegment, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	if name != "" && name != DialogueLayerRaw && session.layerLocked(name, false) == nil {
+		return nil, fmt.Errorf("dialogue layer %q not found", name)
+	}
+
+	result := make([][]TranscriptSegment, len(session.Chunks))
+	for i, chunk := range session.Chunks {
+		if name == "" {
+			result[i] = slices.Clone(chunk.Dialogue)
+		} else {
+			result[i] = slices.Clone(session.dialogueLayerChunkLocked(name, chunk))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,270 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDialogueLayers(t *testing.T) {
+	dataDir := t.TempDir()
+	m, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := []*Chunk{
+		{ID: "c0", SessionID: sess.ID, Index: 0, Duration: 10 * time.Second, Dialogue: []TranscriptSegment{
+			{Start: 0, End: 1000, Text: "привет как дела", Speaker: "Собеседник"},
+		}},
+		{ID: "c1", SessionID: sess.ID, Index: 1, Duration: 10 * time.Second, Dialogue: []TranscriptSegment{
+			{Start: 10000, End: 11000, Text: "нормально", Speaker: "Собеседник"},
+		}},
+	}
+	for _, c := range chunks {
+		if err := m.AddChunk(sess.ID, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	speakers := func(name string) []string {
+		t.Helper()
+		dialogue, err := m.GetDialogueLayer(sess.ID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result []string
+		for _, seg := range dialogue {
+			result = append(result, seg.Speaker+": "+seg.Text)
+		}
+		return result
+	}
+	raw := []string{"Собеседник: привет как дела", "Собеседник: нормально"}
+
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerRaw, nil); err == nil {
+		t.Error("expected error when overwriting the raw layer")
+	}
+	if err := m.ApplyDialogueLayer(sess.ID, DialogueLayerImproved); err == nil {
+		t.Error("expected error for missing layer")
+	}
+
+	// Улучшение затрагивает только первый чанк, диаризация - оба
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет, как дела?", Speaker: "Собеседник"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerDiarized, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет как дела", Speaker: "Собеседник 1"},
+		{Start: 10000, End: 11000, Text: "нормально", Speaker: "Собеседник 2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sess.ActiveDialogueLayer != DialogueLayerDiarized || chunks[1].Dialogue[0].Speaker != "Собеседник 2" {
+		t.Errorf("active layer = %q, chunk speaker = %q", sess.ActiveDialogueLayer, chunks[1].Dialogue[0].Speaker)
+	}
+	if got := speakers(DialogueLayerRaw); !slices.Equal(got, raw) {
+		t.Errorf("raw layer = %q", got)
+	}
+	if got := speakers(DialogueLayerImproved); !slices.Equal(got, []string{"Собеседник: Привет, как дела?", "Собеседник: нормально"}) {
+		t.Errorf("improved layer = %q", got)
+	}
+
+	// Переименование спикера применяется ко всем слоям
+	if err := m.UpdateSpeakerName(sess.ID, "Собеседник 2", "Анна"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Слои и выбранный вариант переживают перезапуск, к исходнику можно вернуться
+	reloaded, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{DialogueLayerDiarized, DialogueLayerImproved, DialogueLayerRaw}
+	if !slices.Equal(got.DialogueLayers, want) || got.ActiveDialogueLayer != DialogueLayerDiarized {
+		t.Fatalf("reloaded layers = %q, active = %q", got.DialogueLayers, got.ActiveDialogueLayer)
+	}
+	m = reloaded
+	if got := speakers(DialogueLayerDiarized); got[1] != "Анна: нормально" {
+		t.Errorf("diarized layer after rename = %q", got)
+	}
+	if err := m.ApplyDialogueLayer(sess.ID, DialogueLayerRaw); err != nil {
+		t.Fatal(err)
+	}
+	if got := speakers(""); !slices.Equal(got, raw) {
+		t.Errorf("displayed dialogue after switching to raw = %q", got)
+	}
+
+	// Новая транскрипция чанка не удаляет его варианты, а помечает устаревшими
+	if err := m.UpdateChunkWithDiarizedSegments(sess.ID, "c0", "здравствуй", []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "здравствуй", Speaker: "Собеседник"},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	sess, _ = m.GetSession(sess.ID)
+	if got := speakers(DialogueLayerDiarized); !slices.Equal(got, []string{"Собеседник 1: привет как дела", "Анна: нормально"}) {
+		t.Errorf("diarized layer after retranscription = %q", got)
+	}
+	if got := speakers(DialogueLayerImproved); got[0] != "Собеседник: Привет, как дела?" {
+		t.Errorf("improved layer after retranscription = %q", got)
+	}
+	if got := speakers(DialogueLayerRaw); !slices.Equal(got, []string{"Собеседник: здравствуй", "Собеседник: нормально"}) {
+		t.Errorf("raw layer after retranscription = %q", got)
+	}
+	if want := []string{DialogueLayerDiarized, DialogueLayerImproved}; !slices.Equal(sess.StaleDialogueLayers, want) {
+		t.Errorf("stale layers = %q, want %q", sess.StaleDialogueLayers, want)
+	}
+
+	// Повторное улучшение чанка снимает отметку
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Здравствуй!", Speaker: "Собеседник"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sess.StaleDialogueLayers, []string{DialogueLayerDiarized}) {
+		t.Errorf("stale layers after re-improve = %q", sess.StaleDialogueLayers)
+	}
+	if got := speakers(DialogueLayerRaw); got[0] != "Собеседник: здравствуй" {
+		t.Errorf("raw layer after re-improve = %q", got)
+	}
+}
+
+func TestDialogueLayers_FullRetranscription(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Index: 0, EndMs: 10000, Duration: 10 * time.Second, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет", Speaker: "Speaker 0"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerTranslated, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "hello", Speaker: "Speaker 0"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.UpdateFullTranscriptionMonoWithSegments(sess.ID, []TranscriptSegment{{Start: 0, End: 1200, Text: "привет всем"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Чанк показывает новый результат, перевод сохранён и помечен устаревшим
+	if chunk.Dialogue[0].Text != "привет всем" {
+		t.Errorf("chunk dialogue = %+v", chunk.Dialogue)
+	}
+	translated, err := m.GetDialogueLayer(sess.ID, DialogueLayerTranslated)
+	if err != nil || len(translated) != 1 || translated[0].Text != "hello" {
+		t.Errorf("translated layer = %+v, err = %v", translated, err)
+	}
+	if raw, _ := m.GetDialogueLayer(sess.ID, DialogueLayerRaw); len(raw) != 1 || raw[0].Text != "привет всем" {
+		t.Errorf("raw layer = %+v", raw)
+	}
+	if !slices.Equal(sess.StaleDialogueLayers, []string{DialogueLayerTranslated}) {
+		t.Errorf("stale layers = %q", sess.StaleDialogueLayers)
+	}
+
+	// Отметка переживает перезапуск
+	reloaded, err := NewManager(m.dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := reloaded.GetSession(sess.ID)
+	if !slices.Equal(got.StaleDialogueLayers, []string{DialogueLayerTranslated}) {
+		t.Errorf("reloaded stale layers = %q", got.StaleDialogueLayers)
+	}
+}
+
+func TestDialogueLayers_RangeRetranscription(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Index: 0, EndMs: 10000, Duration: 10 * time.Second, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет", Speaker: "Собеседник"},
+		{Start: 2000, End: 3000, Text: "как дела", Speaker: "Собеседник"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет!", Speaker: "Собеседник"},
+		{Start: 2000, End: 3000, Text: "Как дела?", Speaker: "Собеседник"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.ReplaceSegmentsInRange(sess.ID, 2000, 3000, []TranscriptSegment{
+		{Start: 2000, End: 3000, Text: "как ваши дела"},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Новый результат попадает в "raw", улучшенный вариант помечается устаревшим
+	if !slices.Equal(sess.StaleDialogueLayers, []string{DialogueLayerImproved}) {
+		t.Errorf("stale layers = %q", sess.StaleDialogueLayers)
+	}
+	if err := m.ApplyDialogueLayer(sess.ID, DialogueLayerRaw); err != nil {
+		t.Fatal(err)
+	}
+	if got := JoinSegmentsText(chunk.Dialogue); got != "привет как ваши дела" {
+		t.Errorf("raw dialogue after range retranscription = %q", got)
+	}
+}
+
+func TestDialogueLayers_MergeSpeakers(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Index: 0, EndMs: 10000, Duration: 10 * time.Second, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "привет", Speaker: "Собеседник 1"},
+		{Start: 2000, End: 3000, Text: "здравствуйте", Speaker: "Собеседник 2"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет!", Speaker: "Собеседник 1"},
+		{Start: 2000, End: 3000, Text: "Здравствуйте!", Speaker: "Собеседник 2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.MergeSpeakers(sess.ID, []int{0, 1}, 0, "Анна"); err != nil {
+		t.Fatal(err)
+	}
+	// Слияние применяется ко всем слоям и переживает перезапуск
+	reloaded, err := NewManager(m.dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{DialogueLayerImproved, DialogueLayerRaw} {
+		dialogue, err := reloaded.GetDialogueLayer(sess.ID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, seg := range dialogue {
+			if seg.Speaker != "Анна" {
+				t.Errorf("layer %q: speaker = %q, want merged name", name, seg.Speaker)
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package session
+
+import "syscall"
+
+// freeDiskSpace свободное место на диске с директорией dir (байт). false - не удалось определить
+func freeDiskSpace(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
@@ -0,0 +1,284 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultKeywordIndexSize количество ключевых терминов в индексе по умолчанию
+const DefaultKeywordIndexSize = 30
+
+// minKeywordLength минимальная длина термина в символах
+const minKeywordLength = 4
+
+// KeywordOccurrence место в записи, где прозвучал термин
+type KeywordOccurrence struct {
+	StartMs int64  `json:"startMs"`
+	EndMs   int64  `json:"endMs"`
+	Speaker string `json:"speaker,omitempty"`
+}
+
+// Keyword значимый термин сессии с таймстемпами упоминаний
+type Keyword struct {
+	Term        string              `json:"term"`
+	Score       float64             `json:"score"` // TF-IDF по сегментам диалога
+	Count       int                 `json:"count"` // Сколько раз термин прозвучал
+	Occurrences []KeywordOccurrence `json:"occurrences"`
+}
+
+// KeywordIndex кешированный индекс ключевых терминов сессии
+type KeywordIndex struct {
+	Fingerprint string    `json:"fingerprint"` // Отпечаток диалога, по которому построен индекс
+	CreatedAt   time.Time `json:"createdAt"`
+	Keywords    []Keyword `json:"keywords"`
+}
+
+// keywordStopWords частые служебные слова, не несущие темы
+var keywordStopWords = map[string]bool{
+	// Русский
+	"если": true, "чтобы": true, "когда": true, "потому": true, "тоже": true, "также": true,
+	"есть": true, "было": true, "была": true, "были": true, "будет": true, "будут": true,
+	"быть": true, "этот": true, "этого": true, "этом": true, "этой": true, "этих": true,
+	"того": true, "тому": true, "тогда": true, "здесь": true, "какой": true, "какая": true,
+	"какие": true, "какое": true, "который": true, "которые": true, "которая": true, "которое": true,
+	"которых": true, "очень": true, "просто": true, "вообще": true, "может": true, "можно": true,
+	"нужно": true, "надо": true, "сейчас": true, "потом": true, "теперь": true, "даже": true,
+	"только": true, "ладно": true, "хорошо": true, "конечно": true, "значит": true, "типа": true,
+	"вроде": true, "наверное": true, "сказать": true, "говорю": true, "знаю": true, "думаю": true,
+	"давай": true, "давайте": true, "понятно": true, "спасибо": true, "пожалуйста": true, "меня": true,
+	"мене": true, "тебя": true, "тебе": true, "него": true, "нему": true, "нами": true,
+	"вами": true, "ними": true, "свой": true, "своих": true, "свои": true, "себя": true,
+	"себе": true, "всех": true, "всем": true, "всего": true, "весь": true, "чего": true,
+	"чему": true, "кого": true, "кому": true, "куда": true, "почему": true, "зачем": true,
+	"сколько": true, "много": true, "мало": true, "более": true, "между": true, "через": true,
+	"после": true, "перед": true, "около": true, "кроме": true, "такой": true, "такая": true,
+	"такие": true, "такое": true, "этим": true, "этому": true, "сами": true, "самом": true,
+	"деле": true,
+	// English
+	"that": true, "this": true, "with": true, "have": true, "from": true, "they": true,
+	"what": true, "there": true, "their": true, "about": true, "would": true, "could": true,
+	"should": true, "which": true, "when": true, "were": true, "been": true, "will": true,
+	"just": true, "like": true, "know": true, "think": true, "yeah": true, "okay": true,
+	"really": true, "some": true, "them": true, "then": true, "than": true, "into": true,
+	"your": true, "yours": true, "because": true, "also": true, "very": true, "much": true,
+	"well": true, "right": true, "going": true, "want": true, "here": true, "thing": true,
+	"things": true, "something": true, "actually": true, "maybe": true, "gonna": true, "other": true,
+}
+
+// keywordToken термин и время его появления внутри сегмента
+type keywordToken struct {
+	term       string
+	start, end int64
+}
+
+// tokenizeSegment разбивает сегмент на термины. Если есть word-level таймстемпы,
+// у каждого термина своё время, иначе - время всего сегмента
+func tokenizeSegment(seg TranscriptSegment) []keywordToken {
+	var tokens []keywordToken
+	if len(seg.Words) > 0 {
+		for _, w := range seg.Words {
+			for _, term := range splitKeywordTerms(w.Text) {
+				tokens = append(tokens, keywordToken{term: term, start: w.Start, end: w.End})
+			}
+		}
+		return tokens
+	}
+	for _, term := range splitKeywordTerms(seg.Text) {
+		tokens = append(tokens, keywordToken{term: term, start: seg.Start, end: seg.End})
+	}
+	return tokens
+}
+
+// splitKeywordTerms выделяет из текста значимые слова (нижний регистр, без стоп-слов и чисел)
+func splitKeywordTerms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+
+	var terms []string
+	for _, f := range fields {
+		f = strings.Trim(f, "-")
+		if utf8.RuneCountInString(f) < minKeywordLength || keywordStopWords[f] {
+			continue
+		}
+		hasLetter := false
+		for _, r := range f {
+			if unicode.IsLetter(r) {
+				hasLetter = true
+				break
+			}
+		}
+		if hasLetter {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// BuildKeywordIndex выбирает до maxTerms значимых терминов диалога по TF-IDF,
+// где документом считается сегмент. Термины, встреченные один раз, пропускаются,
+// если в диалоге достаточно повторяющихся терминов
+func BuildKeywordIndex(dialogue []TranscriptSegment, maxTerms int) []Keyword {
+	if maxTerms <= 0 {
+		maxTerms = DefaultKeywordIndexSize
+	}
+
+	docFreq := make(map[string]int)
+	occurrences := make(map[string][]KeywordOccurrence)
+	docs := 0
+	for _, seg := range dialogue {
+		tokens := tokenizeSegment(seg)
+		if len(tokens) == 0 {
+			continue
+		}
+		docs++
+		seen := make(map[string]bool)
+		for _, tok := range tokens {
+			occurrences[tok.term] = append(occurrences[tok.term], KeywordOccurrence{
+				StartMs: tok.start,
+				EndMs:   tok.end,
+				Speaker: seg.Speaker,
+			})
+			if !seen[tok.term] {
+				seen[tok.term] = true
+				docFreq[tok.term]++
+			}
+		}
+	}
+	if docs == 0 {
+		return nil
+	}
+
+	keywords := make([]Keyword, 0, len(occurrences))
+	repeated := 0
+	for term, occ := range occurrences {
+		// Сглаженный IDF: термин во всех сегментах получает минимальный, но ненулевой вес
+		idf := math.Log(1 + float64(docs)/float64(docFreq[term]))
+		keywords = append(keywords, Keyword{
+			Term:        term,
+			Score:       float64(len(occ)) * idf,
+			Count:       len(occ),
+			Occurrences: occ,
+		})
+		if len(occ) > 1 {
+			repeated++
+		}
+	}
+
+	if repeated >= maxTerms {
+		filtered := keywords[:0]
+		for _, kw := range keywords {
+			if kw.Count > 1 {
+				filtered = append(filtered, kw)
+			}
+		}
+		keywords = filtered
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+	if len(keywords) > maxTerms {
+		keywords = keywords[:maxTerms]
+	}
+	for i := range keywords {
+		keywords[i].Score = math.Round(keywords[i].Score*1000) / 1000
+		sort.Slice(keywords[i].Occurrences, func(a, b int) bool {
+			return keywords[i].Occurrences[a].StartMs < keywords[i].Occurrences[b].StartMs
+		})
+	}
+	return keywords
+}
+
+// sessionDialogueLocked собирает диалог завершённых чанков сессии по времени.
+// Вызывается под session.mu
+func sessionDialogueLocked(session *Session) []TranscriptSegment {
+	var dialogue []TranscriptSegment
+	for _, chunk := range session.Chunks {
+		if chunk.Status != ChunkStatusCompleted {
+			continue
+		}
+		if len(chunk.Dialogue) > 0 {
+			dialogue = append(dialogue, chunk.Dialogue...)
+		} else {
+			dialogue = append(dialogue, chunk.MicSegments...)
+			dialogue = append(dialogue, chunk.SysSegments...)
+		}
+	}
+	sort.SliceStable(dialogue, func(i, j int) bool {
+		return dialogue[i].Start < dialogue[j].Start
+	})
+	return dialogue
+}
+
+// dialogueFingerprint вычисляет отпечаток диалога для проверки актуальности кеша
+func dialogueFingerprint(dialogue []TranscriptSegment, maxTerms int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", maxTerms)
+	for _, seg := range dialogue {
+		fmt.Fprintf(h, "%d|%d|%s|%s|%d\n", seg.Start, seg.End, seg.Speaker, seg.Text, len(seg.Words))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// GetKeywordIndex возвращает индекс ключевых терминов сессии.
+// Индекс кешируется в памяти и в keyword_index.json и пересобирается при изменении диалога
+func (m *Manager) GetKeywordIndex(sessionID string, maxTerms int) (*KeywordIndex, error) {
+	if maxTerms <= 0 {
+		maxTerms = DefaultKeywordIndexSize
+	}
+
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	dialogue := sessionDialogueLocked(session)
+	fingerprint := dialogueFingerprint(dialogue, maxTerms)
+	indexPath := filepath.Join(session.DataDir, "keyword_index.json")
+
+	if session.keywordIndex == nil {
+		if data, err := os.ReadFile(indexPath); err == nil {
+			var cached KeywordIndex
+			if err := json.Unmarshal(data, &cached); err == nil {
+				session.keywordIndex = &cached
+			}
+		}
+	}
+	if session.keywordIndex != nil && session.keywordIndex.Fingerprint == fingerprint {
+		return session.keywordIndex, nil
+	}
+
+	index := &KeywordIndex{
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		Keywords:    BuildKeywordIndex(dialogue, maxTerms),
+	}
+	session.keywordIndex = index
+
+	if data, err := json.MarshalIndent(index, "", "  "); err == nil {
+		if err := os.WriteFile(indexPath, data, 0644); err != nil {
+			log.Printf("GetKeywordIndex: failed to save index for session %s: %v", sessionID, err)
+		}
+	}
+
+	log.Printf("GetKeywordIndex: built %d keywords for session %s from %d segments", len(index.Keywords), sessionID, len(dialogue))
+	return index, nil
+}
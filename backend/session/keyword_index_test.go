@@ -0,0 +1,43 @@
+package session
+
+import "testing"
+
+func TestBuildKeywordIndex(t *testing.T) {
+	dialogue := []TranscriptSegment{
+		{Start: 0, End: 2000, Speaker: "mic", Text: "Давайте обсудим бюджет проекта"},
+		{Start: 2000, End: 4000, Speaker: "sys", Text: "Бюджет уже согласован, но есть вопрос по срокам"},
+		{Start: 4000, End: 6000, Speaker: "mic", Text: "Сроки сдвигаются из-за бюджета?"},
+		{Start: 6000, End: 8000, Speaker: "sys", Words: []TranscriptWord{
+			{Start: 6100, End: 6500, Text: "Нет,"},
+			{Start: 6600, End: 7200, Text: "бюджет"},
+			{Start: 7300, End: 7900, Text: "нормальный"},
+		}, Text: "Нет, бюджет нормальный"},
+	}
+
+	keywords := BuildKeywordIndex(dialogue, 5)
+	if len(keywords) == 0 {
+		t.Fatal("expected keywords")
+	}
+	top := keywords[0]
+	if top.Term != "бюджет" || top.Count != 3 {
+		t.Fatalf("top keyword = %+v, want бюджет x3", top)
+	}
+
+	// Для сегмента со словами используется время слова, а не сегмента
+	last := top.Occurrences[len(top.Occurrences)-1]
+	if last.StartMs != 6600 || last.EndMs != 7200 || last.Speaker != "sys" {
+		t.Errorf("word-level occurrence = %+v", last)
+	}
+
+	for _, kw := range keywords {
+		if kw.Term == "давайте" || kw.Term == "есть" {
+			t.Errorf("stop word %q in index", kw.Term)
+		}
+	}
+}
+
+func TestBuildKeywordIndex_Empty(t *testing.T) {
+	if got := BuildKeywordIndex(nil, 10); got != nil {
+		t.Errorf("expected nil for empty dialogue, got %v", got)
+	}
+}
@@ -24,6 +24,16 @@ type Manager struct {
 	// Callbacks
 	onChunkReady       func(chunk *Chunk)
 	onChunkTranscribed func(chunk *Chunk)
+
+	manifestEnv func() ManifestEnvironment // Сведения о приложении для manifest.json
+
+	// Политика хранения и фоновая очистка (retention.go)
+	retention          RetentionPolicy
+	retentionStop      chan struct{}
+	onRetentionCleanup func(report *RetentionReport)
+
+	// Сессии, над которыми выполняются задачи (busy.go)
+	busy sessionBusy
 }
 
 // NewManager создаёт новый менеджер сессий
@@ -36,6 +46,7 @@ func NewManager(dataDir string) (*Manager, error) {
 		sessions: make(map[string]*Session),
 		dataDir:  dataDir,
 	}
+	m.retention = m.loadRetentionPolicy()
 
 	// Загружаем существующие сессии
 	if err := m.LoadSessions(); err != nil {
@@ -63,13 +74,16 @@ func (m *Manager) CreateSession(cfg SessionConfig) (*Session, error) {
 	}
 
 	session := &Session{
-		ID:        id,
-		StartTime: time.Now(),
-		Status:    SessionStatusRecording,
-		Language:  cfg.Language,
-		Model:     cfg.Model,
-		DataDir:   sessionDir,
-		Chunks:    make([]*Chunk, 0),
+		ID:           id,
+		StartTime:    time.Now(),
+		Status:       SessionStatusRecording,
+		Language:     cfg.Language,
+		Model:        cfg.Model,
+		DataDir:      sessionDir,
+		DiarizeMic:   cfg.DiarizeMic,
+		SwapChannels: cfg.SwapChannels,
+		MicOnly:      cfg.MicOnly,
+		Chunks:       make([]*Chunk, 0),
 	}
 
 	m.sessions[id] = session
@@ -96,13 +110,16 @@ func (m *Manager) CreateImportSession(cfg SessionConfig) (*Session, error) {
 	}
 
 	session := &Session{
-		ID:        id,
-		StartTime: time.Now(),
-		Status:    SessionStatusCompleted, // Импортированная сессия сразу completed
-		Language:  cfg.Language,
-		Model:     cfg.Model,
-		DataDir:   sessionDir,
-		Chunks:    make([]*Chunk, 0),
+		ID:           id,
+		StartTime:    time.Now(),
+		Status:       SessionStatusCompleted, // Импортированная сессия сразу completed
+		Language:     cfg.Language,
+		Model:        cfg.Model,
+		DataDir:      sessionDir,
+		DiarizeMic:   cfg.DiarizeMic,
+		SwapChannels: cfg.SwapChannels,
+		MicOnly:      cfg.MicOnly,
+		Chunks:       make([]*Chunk, 0),
 	}
 
 	m.sessions[id] = session
@@ -127,8 +144,11 @@ func (m *Manager) StopSession() (*Session, error) {
 	session := m.sessions[m.activeID]
 	now := time.Now()
 	session.EndTime = &now
+	if session.Status == SessionStatusPaused {
+		session.PausedDuration += now.Sub(session.pausedAt)
+	}
 	session.Status = SessionStatusCompleted
-	session.TotalDuration = now.Sub(session.StartTime)
+	session.TotalDuration = now.Sub(session.StartTime) - session.PausedDuration
 
 	if session.Title == "" {
 		session.Title = generateSessionTitle(session.StartTime, session.TotalDuration)
@@ -141,6 +161,13 @@ func (m *Manager) StopSession() (*Session, error) {
 		return nil, err
 	}
 
+	// Манифест для внешних инструментов; дальше обновляется по мере распознавания
+	session.mu.RLock()
+	if err := m.writeManifestLocked(session); err != nil {
+		log.Printf("Session %s: %v", session.ID, err)
+	}
+	session.mu.RUnlock()
+
 	return session, nil
 }
 
@@ -300,6 +327,12 @@ func (m *Manager) DeleteSession(id string) error {
 	if m.activeID == id {
 		return fmt.Errorf("cannot delete active session")
 	}
+	return m.deleteSessionLocked(session)
+}
+
+// deleteSessionLocked удаляет файлы сессии и убирает её из менеджера. Вызывается под m.mu
+func (m *Manager) deleteSessionLocked(session *Session) error {
+	id := session.ID
 
 	// Удаляем файлы
 	if err := os.RemoveAll(session.DataDir); err != nil {
@@ -307,6 +340,14 @@ func (m *Manager) DeleteSession(id string) error {
 	}
 
 	delete(m.sessions, id)
+
+	// Перенесённая сессия больше не должна искаться при старте
+	if index := m.loadRelocatedIndex(); index[id] != "" {
+		delete(index, id)
+		if err := m.saveRelocatedIndex(index); err != nil {
+			log.Printf("DeleteSession: failed to update %s: %v", relocatedIndexFile, err)
+		}
+	}
 	return nil
 }
 
@@ -358,16 +399,15 @@ func (m *Manager) UpdateChunkTranscription(sessionID, chunkID, text string, err
 
 		session.mu.Lock()
 		defer session.mu.Unlock()
+		defer m.refreshManifestLocked(session)
 
 		for _, chunk := range session.Chunks {
 			if chunk.ID == chunkID {
 				now := time.Now()
 				chunk.TranscribedAt = &now
 
-				// Вычисляем время обработки если есть время начала
-				if chunk.ProcessingStartTime != nil {
-					chunk.ProcessingTime = now.Sub(*chunk.ProcessingStartTime).Milliseconds()
-				}
+				// Вычисляем время обработки и RTF если есть время начала
+				chunk.finishProcessing(now)
 
 				if err != nil {
 					chunk.Status = ChunkStatusFailed
@@ -384,6 +424,10 @@ func (m *Manager) UpdateChunkTranscription(sessionID, chunkID, text string, err
 					chunk.Transcription = text
 					chunk.Error = "" // Очищаем ошибку при успехе
 				}
+				chunk.updateQuality()
+
+				// Прежние варианты диалога построены по старому результату ASR
+				session.markChunkDialogueLayersStaleLocked(chunk)
 
 				// Сохраняем метаданные чанка
 				chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
@@ -408,6 +452,51 @@ func (m *Manager) UpdateChunkTranscription(sessionID, chunkID, text string, err
 	return nil
 }
 
+// MarkChunkCanceled отмечает, что распознавание чанка отменено до начала (reason - причина).
+// Прежний результат сохраняется, а ожидавший распознавания чанк становится failed:
+// иначе он навсегда остался бы в ожидании
+func (m *Manager) MarkChunkCanceled(sessionID, chunkID string, reason error) error {
+	var callbackChunk *Chunk
+
+	func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		session, ok := m.sessions[sessionID]
+		if !ok {
+			return
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		defer m.refreshManifestLocked(session)
+
+		for _, chunk := range session.Chunks {
+			if chunk.ID == chunkID {
+				if chunk.Status == ChunkStatusPending || chunk.Status == ChunkStatusTranscribing {
+					chunk.Status = ChunkStatusFailed
+				}
+				chunk.Error = reason.Error()
+
+				chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+				data, _ := json.MarshalIndent(chunk, "", "  ")
+				os.WriteFile(chunkMetaPath, data, 0644)
+
+				callbackChunk = chunk
+				return
+			}
+		}
+	}()
+
+	if callbackChunk == nil {
+		return fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	if m.onChunkTranscribed != nil {
+		m.onChunkTranscribed(callbackChunk)
+	}
+	return nil
+}
+
 // UpdateChunkStereoTranscription обновляет раздельные транскрипции для mic и system
 func (m *Manager) UpdateChunkStereoTranscription(sessionID, chunkID, micText, sysText string, err error) error {
 	return m.UpdateChunkStereoWithSegments(sessionID, chunkID, micText, sysText, nil, nil, err)
@@ -429,16 +518,15 @@ func (m *Manager) UpdateChunkStereoWithSegments(sessionID, chunkID, micText, sys
 
 		session.mu.Lock()
 		defer session.mu.Unlock()
+		defer m.refreshManifestLocked(session)
 
 		for _, chunk := range session.Chunks {
 			if chunk.ID == chunkID {
 				now := time.Now()
 				chunk.TranscribedAt = &now
 
-				// Вычисляем время обработки если есть время начала
-				if chunk.ProcessingStartTime != nil {
-					chunk.ProcessingTime = now.Sub(*chunk.ProcessingStartTime).Milliseconds()
-				}
+				// Вычисляем время обработки и RTF если есть время начала
+				chunk.finishProcessing(now)
 
 				if err != nil {
 					chunk.Status = ChunkStatusFailed
@@ -464,6 +552,10 @@ func (m *Manager) UpdateChunkStereoWithSegments(sessionID, chunkID, micText, sys
 					// Формируем общую транскрипцию из диалога
 					chunk.Transcription = formatDialogue(chunk.Dialogue)
 				}
+				chunk.updateQuality()
+
+				// Прежние варианты диалога построены по старому результату ASR
+				session.markChunkDialogueLayersStaleLocked(chunk)
 
 				// Сохраняем метаданные чанка
 				chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
@@ -488,6 +580,53 @@ func (m *Manager) UpdateChunkStereoWithSegments(sessionID, chunkID, micText, sys
 	return nil
 }
 
+// UpdateChunkSysSpeakers заменяет сегменты SYS канала после повторной диаризации (rediarize_full):
+// диалог пересобирается с прежними сегментами MIC. Статус, время и метрики обработки не меняются -
+// распознавание не выполнялось
+func (m *Manager) UpdateChunkSysSpeakers(sessionID, chunkID string, sysSegments []TranscriptSegment) error {
+	var callbackChunk *Chunk
+
+	func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		session, ok := m.sessions[sessionID]
+		if !ok {
+			return
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		defer m.refreshManifestLocked(session)
+
+		for _, chunk := range session.Chunks {
+			if chunk.ID == chunkID {
+				chunk.SysSegments = sysSegments
+				chunk.Dialogue = mergeSegmentsToDialogue(chunk.MicSegments, sysSegments)
+				chunk.Transcription = formatDialogue(chunk.Dialogue)
+
+				// Варианты диалога содержат прежних спикеров
+				session.markChunkDialogueLayersStaleLocked(chunk)
+
+				chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+				data, _ := json.MarshalIndent(chunk, "", "  ")
+				os.WriteFile(chunkMetaPath, data, 0644)
+
+				callbackChunk = chunk
+				return
+			}
+		}
+	}()
+
+	if callbackChunk == nil {
+		return fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	if m.onChunkTranscribed != nil {
+		m.onChunkTranscribed(callbackChunk)
+	}
+	return nil
+}
+
 // UpdateChunkWithDiarizedSegments обновляет чанк с диаризованными сегментами (для mono режима с диаризацией)
 func (m *Manager) UpdateChunkWithDiarizedSegments(sessionID, chunkID, text string, segments []TranscriptSegment, err error) error {
 	var callbackChunk *Chunk
@@ -504,16 +643,15 @@ func (m *Manager) UpdateChunkWithDiarizedSegments(sessionID, chunkID, text strin
 
 		session.mu.Lock()
 		defer session.mu.Unlock()
+		defer m.refreshManifestLocked(session)
 
 		for _, chunk := range session.Chunks {
 			if chunk.ID == chunkID {
 				now := time.Now()
 				chunk.TranscribedAt = &now
 
-				// Вычисляем время обработки если есть время начала
-				if chunk.ProcessingStartTime != nil {
-					chunk.ProcessingTime = now.Sub(*chunk.ProcessingStartTime).Milliseconds()
-				}
+				// Вычисляем время обработки и RTF если есть время начала
+				chunk.finishProcessing(now)
 
 				if err != nil {
 					chunk.Status = ChunkStatusFailed
@@ -527,6 +665,10 @@ func (m *Manager) UpdateChunkWithDiarizedSegments(sessionID, chunkID, text strin
 					// Сохраняем сегменты как диалог (уже с метками спикеров)
 					chunk.Dialogue = segments
 				}
+				chunk.updateQuality()
+
+				// Прежние варианты диалога построены по старому результату ASR
+				session.markChunkDialogueLayersStaleLocked(chunk)
 
 				// Сохраняем метаданные чанка
 				chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
@@ -846,11 +988,11 @@ func mergeSegmentsWithOverlapHandling(micSegments, sysSegments []TranscriptSegme
 		// Устанавливаем спикера
 		if tagged.isMic {
 			if seg.Speaker == "" || seg.Speaker == "mic" {
-				seg.Speaker = "Вы"
+				seg.Speaker = SelfSpeakerLabel()
 			}
 		} else {
 			if seg.Speaker == "" || seg.Speaker == "sys" {
-				seg.Speaker = "Собеседник"
+				seg.Speaker = PeerSpeakerLabel()
 			}
 		}
 
@@ -908,7 +1050,7 @@ const (
 
 // isMicSpeaker проверяет является ли спикер микрофоном пользователя
 func isMicSpeaker(speaker string) bool {
-	return speaker == "mic" || speaker == "Вы"
+	return IsSelfSpeakerLabel(speaker)
 }
 
 // postProcessDialogue объединяет соседние короткие фразы одного спикера
@@ -950,6 +1092,9 @@ func postProcessDialogue(phrases []TranscriptSegment) []TranscriptSegment {
 				prev.End = phrase.End
 				prev.Text = prev.Text + " " + phrase.Text
 				prev.Words = append(prev.Words, phrase.Words...)
+				if phrase.Overlap && !prev.Overlap {
+					prev.Overlap, prev.OverlapSpeaker = true, phrase.OverlapSpeaker
+				}
 				continue
 			}
 		}
@@ -1158,9 +1303,9 @@ func formatDialogue(dialogue []TranscriptSegment) string {
 
 	var parts []string
 	for _, seg := range dialogue {
-		speaker := "Вы"
+		speaker := SelfSpeakerLabel()
 		if seg.Speaker == "sys" {
-			speaker = "Собеседник"
+			speaker = PeerSpeakerLabel()
 		}
 		// Форматируем время в MM:SS
 		startSec := seg.Start / 1000
@@ -1187,113 +1332,164 @@ func (m *Manager) SetOnChunkTranscribed(fn func(chunk *Chunk)) {
 	m.onChunkTranscribed = fn
 }
 
-// LoadSessions загружает сессии с диска при старте
+// LoadSessions загружает сессии с диска при старте: из dataDir и перенесённые
+// на другие диски (см. MoveSession)
 func (m *Manager) LoadSessions() error {
 	entries, err := os.ReadDir(m.dataDir)
 	if err != nil {
 		return err
 	}
 
+	// Перенесённые сессии загружаются первыми: в dataDir может остаться неполная копия,
+	// если после переноса копированием не удалось удалить прежние файлы
+	for id, dir := range m.loadRelocatedIndex() {
+		session, ok := m.loadSessionDir(dir)
+		if !ok {
+			// Внешний диск может быть не подключён - запись в индексе сохраняем,
+			// а сессия загружается из dataDir, если там осталась копия
+			log.Printf("LoadSessions: relocated session %s is not available at %s", id, dir)
+			continue
+		}
+		m.sessions[session.ID] = session
+	}
+
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
-
-		metaPath := filepath.Join(m.dataDir, entry.Name(), "meta.json")
-		data, err := os.ReadFile(metaPath)
-		if err != nil {
+		if _, exists := m.sessions[entry.Name()]; exists {
 			continue
 		}
-
-		// Используем промежуточную структуру для правильной загрузки TotalDuration
-		// В JSON TotalDuration хранится в миллисекундах, а не наносекундах
-		var meta struct {
-			ID            string        `json:"id"`
-			StartTime     time.Time     `json:"startTime"`
-			EndTime       *time.Time    `json:"endTime,omitempty"`
-			Status        SessionStatus `json:"status"`
-			Language      string        `json:"language"`
-			Model         string        `json:"model"`
-			Title         string        `json:"title,omitempty"`
-			Tags          []string      `json:"tags,omitempty"`
-			TotalDuration int64         `json:"totalDuration"` // миллисекунды!
-			SampleCount   int64         `json:"sampleCount"`
-			Waveform      *WaveformData `json:"waveform,omitempty"`
-		}
-		if err := json.Unmarshal(data, &meta); err != nil {
+		session, ok := m.loadSessionDir(filepath.Join(m.dataDir, entry.Name()))
+		if !ok {
 			continue
 		}
+		if _, exists := m.sessions[session.ID]; exists {
+			continue
+		}
+		m.sessions[session.ID] = session
+	}
 
-		session := Session{
-			ID:            meta.ID,
-			StartTime:     meta.StartTime,
-			EndTime:       meta.EndTime,
-			Status:        meta.Status,
-			Language:      meta.Language,
-			Model:         meta.Model,
-			Title:         meta.Title,
-			Tags:          meta.Tags,
-			TotalDuration: time.Duration(meta.TotalDuration) * time.Millisecond, // конвертируем из мс
-			SampleCount:   meta.SampleCount,
-			Waveform:      meta.Waveform,
-		}
-
-		// Устанавливаем DataDir (не сохраняется в JSON)
-		session.DataDir = filepath.Join(m.dataDir, entry.Name())
-
-		// Fallback: если totalDuration == 0, но есть waveform с длительностью,
-		// используем длительность из waveform (для незавершённых сессий)
-		if session.TotalDuration == 0 && session.Waveform != nil && session.Waveform.Duration > 0 {
-			session.TotalDuration = time.Duration(session.Waveform.Duration * float64(time.Second))
-			log.Printf("LoadSessions: session %s has no totalDuration, using waveform duration %.2f sec",
-				session.ID, session.Waveform.Duration)
-		}
-
-		// Автогенерация названия, если отсутствует (для старых записей)
-		if session.Title == "" {
-			session.Title = generateSessionTitle(session.StartTime, session.TotalDuration)
-			if err := m.SaveSessionMeta(&session); err != nil {
-				log.Printf("LoadSessions: failed to backfill title for %s: %v", session.ID, err)
-			}
+	return nil
+}
+
+// loadSessionDir загружает сессию (meta.json, summary, чанки) из директории
+func (m *Manager) loadSessionDir(dir string) (*Session, bool) {
+	metaPath := filepath.Join(dir, "meta.json")
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, false
+	}
+
+	// Используем промежуточную структуру для правильной загрузки TotalDuration
+	// В JSON TotalDuration хранится в миллисекундах, а не наносекундах
+	var meta struct {
+		ID             string          `json:"id"`
+		StartTime      time.Time       `json:"startTime"`
+		EndTime        *time.Time      `json:"endTime,omitempty"`
+		Status         SessionStatus   `json:"status"`
+		Language       string          `json:"language"`
+		Model          string          `json:"model"`
+		Title          string          `json:"title,omitempty"`
+		Tags           []string        `json:"tags,omitempty"`
+		TotalDuration  int64           `json:"totalDuration"`            // миллисекунды!
+		PausedDuration int64           `json:"pausedDuration,omitempty"` // миллисекунды
+		SampleCount    int64           `json:"sampleCount"`
+		Waveform       *WaveformData   `json:"waveform,omitempty"`
+		DiarizeMic     bool            `json:"diarizeMic,omitempty"`
+		SwapChannels   ChannelSwapMode `json:"swapChannels,omitempty"`
+		MicOnly        bool            `json:"micOnly,omitempty"`
+		Pinned         bool            `json:"pinned,omitempty"`
+		AudioDropped   bool            `json:"audioDropped,omitempty"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, false
+	}
+
+	session := Session{
+		ID:             meta.ID,
+		StartTime:      meta.StartTime,
+		EndTime:        meta.EndTime,
+		Status:         meta.Status,
+		Language:       meta.Language,
+		Model:          meta.Model,
+		Title:          meta.Title,
+		Tags:           meta.Tags,
+		TotalDuration:  time.Duration(meta.TotalDuration) * time.Millisecond, // конвертируем из мс
+		PausedDuration: time.Duration(meta.PausedDuration) * time.Millisecond,
+		SampleCount:    meta.SampleCount,
+		Waveform:       meta.Waveform,
+		DiarizeMic:     meta.DiarizeMic,
+		SwapChannels:   meta.SwapChannels,
+		MicOnly:        meta.MicOnly,
+		Pinned:         meta.Pinned,
+		AudioDropped:   meta.AudioDropped,
+	}
+
+	// DataDir - фактическое расположение (в meta.json только для справки)
+	session.DataDir = dir
+
+	// Fallback: если totalDuration == 0, но есть waveform с длительностью,
+	// используем длительность из waveform (для незавершённых сессий)
+	if session.TotalDuration == 0 && session.Waveform != nil && session.Waveform.Duration > 0 {
+		session.TotalDuration = time.Duration(session.Waveform.Duration * float64(time.Second))
+		log.Printf("LoadSessions: session %s has no totalDuration, using waveform duration %.2f sec",
+			session.ID, session.Waveform.Duration)
+	}
+
+	// Автогенерация названия, если отсутствует (для старых записей)
+	if session.Title == "" {
+		session.Title = generateSessionTitle(session.StartTime, session.TotalDuration)
+		if err := m.SaveSessionMeta(&session); err != nil {
+			log.Printf("LoadSessions: failed to backfill title for %s: %v", session.ID, err)
 		}
+	}
 
-		// Загружаем summary если есть
-		summaryPath := filepath.Join(m.dataDir, entry.Name(), "summary.txt")
-		if summaryData, err := os.ReadFile(summaryPath); err == nil {
-			session.Summary = string(summaryData)
+	// Загружаем summary если есть
+	summaryPath := filepath.Join(dir, "summary.txt")
+	if summaryData, err := os.ReadFile(summaryPath); err == nil {
+		session.Summary = string(summaryData)
+	}
+
+	// Загружаем диалог с восстановленной пунктуацией если есть
+	if data, err := os.ReadFile(filepath.Join(dir, punctuatedDialogueFile)); err == nil {
+		if err := json.Unmarshal(data, &session.PunctuatedDialogue); err != nil {
+			log.Printf("Failed to parse %s for session %s: %v", punctuatedDialogueFile, session.ID, err)
 		}
+	}
 
-		// Загружаем чанки
-		chunksDir := filepath.Join(m.dataDir, entry.Name(), "chunks")
-		// Поддерживаем оба формата: chunk_*.json (старый) и *.json (новый)
-		chunkFiles1, _ := filepath.Glob(filepath.Join(chunksDir, "chunk_*.json"))
-		chunkFiles2, _ := filepath.Glob(filepath.Join(chunksDir, "[0-9]*.json"))
-		chunkFiles := append(chunkFiles1, chunkFiles2...)
-		log.Printf("LoadSessions: session %s found %d chunk files in %s (formats: chunk_*.json + [0-9]*.json)", session.ID, len(chunkFiles), chunksDir)
-		for _, chunkFile := range chunkFiles {
-			chunkData, err := os.ReadFile(chunkFile)
-			if err != nil {
-				log.Printf("LoadSessions: failed to read chunk file %s: %v", chunkFile, err)
-				continue
-			}
-			var chunk Chunk
-			if err := json.Unmarshal(chunkData, &chunk); err != nil {
-				log.Printf("LoadSessions: failed to unmarshal chunk %s: %v", chunkFile, err)
-				continue
-			}
-			session.Chunks = append(session.Chunks, &chunk)
+	// Загружаем чанки
+	chunksDir := filepath.Join(dir, "chunks")
+	// Поддерживаем оба формата: chunk_*.json (старый) и *.json (новый)
+	chunkFiles1, _ := filepath.Glob(filepath.Join(chunksDir, "chunk_*.json"))
+	chunkFiles2, _ := filepath.Glob(filepath.Join(chunksDir, "[0-9]*.json"))
+	chunkFiles := append(chunkFiles1, chunkFiles2...)
+	log.Printf("LoadSessions: session %s found %d chunk files in %s (formats: chunk_*.json + [0-9]*.json)", session.ID, len(chunkFiles), chunksDir)
+	for _, chunkFile := range chunkFiles {
+		chunkData, err := os.ReadFile(chunkFile)
+		if err != nil {
+			log.Printf("LoadSessions: failed to read chunk file %s: %v", chunkFile, err)
+			continue
 		}
+		var chunk Chunk
+		if err := json.Unmarshal(chunkData, &chunk); err != nil {
+			log.Printf("LoadSessions: failed to unmarshal chunk %s: %v", chunkFile, err)
+			continue
+		}
+		session.Chunks = append(session.Chunks, &chunk)
+	}
 
-		// Сортируем чанки по индексу
-		sort.Slice(session.Chunks, func(i, j int) bool {
-			return session.Chunks[i].Index < session.Chunks[j].Index
-		})
+	// Сортируем чанки по индексу
+	sort.Slice(session.Chunks, func(i, j int) bool {
+		return session.Chunks[i].Index < session.Chunks[j].Index
+	})
 
-		log.Printf("LoadSessions: session %s loaded with %d chunks", session.ID, len(session.Chunks))
-		m.sessions[session.ID] = &session
-	}
+	// Загружаем историю переименований спикеров (после чанков: нужна для миграции старых сессий)
+	session.loadSpeakerRenames()
+	session.loadDialogueLayers()
 
-	return nil
+	log.Printf("LoadSessions: session %s loaded with %d chunks", session.ID, len(session.Chunks))
+	return &session, true
 }
 
 // SaveSessionMeta сохраняет метаданные сессии
@@ -1305,31 +1501,45 @@ func (m *Manager) SaveSessionMeta(s *Session) error {
 
 	// Создаём копию без чанков для meta.json
 	meta := struct {
-		ID            string        `json:"id"`
-		StartTime     time.Time     `json:"startTime"`
-		EndTime       *time.Time    `json:"endTime,omitempty"`
-		Status        SessionStatus `json:"status"`
-		Language      string        `json:"language"`
-		Model         string        `json:"model"`
-		Title         string        `json:"title,omitempty"`
-		Tags          []string      `json:"tags,omitempty"`
-		TotalDuration int64         `json:"totalDuration"`
-		SampleCount   int64         `json:"sampleCount"`
-		ChunksCount   int           `json:"chunksCount"`
-		Waveform      *WaveformData `json:"waveform,omitempty"`
+		ID             string          `json:"id"`
+		StartTime      time.Time       `json:"startTime"`
+		EndTime        *time.Time      `json:"endTime,omitempty"`
+		Status         SessionStatus   `json:"status"`
+		Language       string          `json:"language"`
+		Model          string          `json:"model"`
+		Title          string          `json:"title,omitempty"`
+		Tags           []string        `json:"tags,omitempty"`
+		TotalDuration  int64           `json:"totalDuration"`
+		PausedDuration int64           `json:"pausedDuration,omitempty"`
+		SampleCount    int64           `json:"sampleCount"`
+		ChunksCount    int             `json:"chunksCount"`
+		Waveform       *WaveformData   `json:"waveform,omitempty"`
+		DiarizeMic     bool            `json:"diarizeMic,omitempty"`
+		SwapChannels   ChannelSwapMode `json:"swapChannels,omitempty"`
+		MicOnly        bool            `json:"micOnly,omitempty"`
+		Pinned         bool            `json:"pinned,omitempty"`
+		AudioDropped   bool            `json:"audioDropped,omitempty"`
+		DataDir        string          `json:"dataDir,omitempty"`
 	}{
-		ID:            s.ID,
-		StartTime:     s.StartTime,
-		EndTime:       s.EndTime,
-		Status:        s.Status,
-		Language:      s.Language,
-		Model:         s.Model,
-		Title:         s.Title,
-		Tags:          s.Tags,
-		TotalDuration: int64(s.TotalDuration / time.Millisecond),
-		SampleCount:   s.SampleCount,
-		ChunksCount:   len(s.Chunks),
-		Waveform:      s.Waveform,
+		ID:             s.ID,
+		StartTime:      s.StartTime,
+		EndTime:        s.EndTime,
+		Status:         s.Status,
+		Language:       s.Language,
+		Model:          s.Model,
+		Title:          s.Title,
+		Tags:           s.Tags,
+		TotalDuration:  int64(s.TotalDuration / time.Millisecond),
+		PausedDuration: int64(s.PausedDuration / time.Millisecond),
+		SampleCount:    s.SampleCount,
+		ChunksCount:    len(s.Chunks),
+		Waveform:       s.Waveform,
+		DiarizeMic:     s.DiarizeMic,
+		SwapChannels:   s.SwapChannels,
+		MicOnly:        s.MicOnly,
+		Pinned:         s.Pinned,
+		AudioDropped:   s.AudioDropped,
+		DataDir:        s.DataDir,
 	}
 
 	data, err := json.MarshalIndent(meta, "", "  ")
@@ -1371,6 +1581,181 @@ func (m *Manager) GetChunkWAVPath(sessionID string, chunkIndex int) (string, err
 	return filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.wav", chunkIndex)), nil
 }
 
+// PreviousChunkText возвращает текст канала ("mic", "sys" или "mono") чанка, предшествующего index.
+// Пусто, если чанка нет или он ещё не распознан
+func (m *Manager) PreviousChunkText(sessionID string, index int, channel string) string {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return ""
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	for _, chunk := range session.Chunks {
+		if chunk.Index != index-1 || chunk.Status != ChunkStatusCompleted {
+			continue
+		}
+		switch channel {
+		case "mic":
+			return chunk.MicText
+		case "sys":
+			return chunk.SysText
+		default:
+			return chunk.Transcription
+		}
+	}
+	return ""
+}
+
+// PreviousChunkSegments возвращает копию сегментов канала ("mic", "sys" или "mono") чанка,
+// предшествующего index, и признак того, что он уже распознан
+func (m *Manager) PreviousChunkSegments(sessionID string, index int, channel string) ([]TranscriptSegment, bool) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	for _, chunk := range session.Chunks {
+		if chunk.Index != index-1 || chunk.Status != ChunkStatusCompleted {
+			continue
+		}
+		return append([]TranscriptSegment(nil), chunkChannelSegments(chunk, channel)...), true
+	}
+	return nil, false
+}
+
+// PreviousChunkPending сообщает, что чанк, предшествующий index, ещё ждёт распознавания или распознаётся
+func (m *Manager) PreviousChunkPending(sessionID string, index int) bool {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return false
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	for _, chunk := range session.Chunks {
+		if chunk.Index == index-1 {
+			return chunk.Status == ChunkStatusPending || chunk.Status == ChunkStatusTranscribing
+		}
+	}
+	return false
+}
+
+// TrimPreviousChunkTail убирает из канала чанка, предшествующего index, слова с серединой
+// после fromMs: это обрывок слова, целиком распознанного следующим чанком в перекрытии.
+// Возвращает true, если чанк изменился
+func (m *Manager) TrimPreviousChunkTail(sessionID string, index int, channel string, fromMs int64) bool {
+	var callbackChunk *Chunk
+
+	func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		session, ok := m.sessions[sessionID]
+		if !ok {
+			return
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+
+		for _, chunk := range session.Chunks {
+			if chunk.Index != index-1 || chunk.Status != ChunkStatusCompleted {
+				continue
+			}
+			segments := chunkChannelSegments(chunk, channel)
+			trimmed := trimSegmentsTail(segments, fromMs)
+			if len(trimmed) == len(segments) && JoinSegmentsText(trimmed) == JoinSegmentsText(segments) {
+				return
+			}
+
+			switch channel {
+			case "mic":
+				chunk.MicSegments = trimmed
+				chunk.MicText = JoinSegmentsText(trimmed)
+			case "sys":
+				chunk.SysSegments = trimmed
+				chunk.SysText = JoinSegmentsText(trimmed)
+			default:
+				chunk.Dialogue = trimmed
+				chunk.Transcription = JoinSegmentsText(trimmed)
+			}
+			if channel == "mic" || channel == "sys" {
+				chunk.Dialogue = mergeSegmentsToDialogue(chunk.MicSegments, chunk.SysSegments)
+				chunk.Transcription = formatDialogue(chunk.Dialogue)
+			}
+			chunk.updateQuality()
+			session.markChunkDialogueLayersStaleLocked(chunk)
+			m.refreshManifestLocked(session)
+
+			chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+			data, _ := json.MarshalIndent(chunk, "", "  ")
+			os.WriteFile(chunkMetaPath, data, 0644)
+
+			callbackChunk = chunk
+			return
+		}
+	}()
+
+	if callbackChunk == nil {
+		return false
+	}
+	if m.onChunkTranscribed != nil {
+		m.onChunkTranscribed(callbackChunk)
+	}
+	return true
+}
+
+// chunkChannelSegments возвращает сегменты канала чанка: "mic", "sys" или диалог для "mono"
+func chunkChannelSegments(chunk *Chunk, channel string) []TranscriptSegment {
+	switch channel {
+	case "mic":
+		return chunk.MicSegments
+	case "sys":
+		return chunk.SysSegments
+	default:
+		return chunk.Dialogue
+	}
+}
+
+// trimSegmentsTail оставляет слова с серединой до fromMs. Сегмент без слов остаётся,
+// если начат до fromMs
+func trimSegmentsTail(segments []TranscriptSegment, fromMs int64) []TranscriptSegment {
+	result := make([]TranscriptSegment, 0, len(segments))
+	for _, seg := range segments {
+		if len(seg.Words) == 0 {
+			if seg.Start < fromMs {
+				result = append(result, seg)
+			}
+			continue
+		}
+
+		words := make([]TranscriptWord, 0, len(seg.Words))
+		for _, w := range seg.Words {
+			if (w.Start+w.End)/2 < fromMs {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) != len(seg.Words) {
+			texts := make([]string, len(words))
+			for i, w := range words {
+				texts[i] = strings.TrimSpace(w.Text)
+			}
+			seg.Text = strings.Join(texts, " ")
+			seg.End = words[len(words)-1].End
+			seg.Words = words
+			seg.Confidence = SegmentConfidence(words)
+		}
+		result = append(result, seg)
+	}
+	return result
+}
+
 // SetSessionSummary устанавливает summary для сессии
 func (m *Manager) SetSessionSummary(sessionID string, summary string) error {
 	m.mu.Lock()
@@ -1394,6 +1779,33 @@ func (m *Manager) SetSessionSummary(sessionID string, summary string) error {
 	return nil
 }
 
+// punctuatedDialogueFile файл альтернативного диалога с восстановленной пунктуацией
+const punctuatedDialogueFile = "punctuated.json"
+
+// SetPunctuatedDialogue сохраняет диалог с восстановленной пунктуацией отдельно от основного
+func (m *Manager) SetPunctuatedDialogue(sessionID string, dialogue []TranscriptSegment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	data, err := json.MarshalIndent(dialogue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal punctuated dialogue: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(session.DataDir, punctuatedDialogueFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save punctuated dialogue: %w", err)
+	}
+
+	session.mu.Lock()
+	session.PunctuatedDialogue = dialogue
+	session.mu.Unlock()
+	return nil
+}
+
 // UpdateFullTranscription обновляет сессию с полной транскрипцией (стерео режим)
 // ВАЖНО: Сохраняет структуру чанков, обновляя каждый чанк отдельно
 // micSegments и sysSegments содержат сегменты с глобальными timestamps (относительно начала записи)
@@ -1408,6 +1820,10 @@ func (m *Manager) UpdateFullTranscription(sessionID string, micSegments, sysSegm
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
+	defer m.refreshManifestLocked(session)
+
+	// Полная ретранскрипция заменяет исходник - варианты диалога устаревают (после обновления чанков)
+	defer session.markDialogueLayersStaleLocked()
 
 	log.Printf("UpdateFullTranscription: session %s has %d chunks in memory, mic=%d segments, sys=%d segments",
 		sessionID, len(session.Chunks), len(micSegments), len(sysSegments))
@@ -1595,6 +2011,10 @@ func (m *Manager) UpdateFullTranscriptionMono(sessionID string, text string) err
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
+	defer m.refreshManifestLocked(session)
+
+	// Полная ретранскрипция заменяет исходник - варианты диалога устаревают (после обновления чанков)
+	defer session.markDialogueLayersStaleLocked()
 
 	log.Printf("UpdateFullTranscriptionMono: session %s has %d chunks, text=%d chars",
 		sessionID, len(session.Chunks), len(text))
@@ -1688,6 +2108,10 @@ func (m *Manager) UpdateFullTranscriptionMonoWithSegments(sessionID string, segm
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
+	defer m.refreshManifestLocked(session)
+
+	// Полная ретранскрипция заменяет исходник - варианты диалога устаревают (после обновления чанков)
+	defer session.markDialogueLayersStaleLocked()
 
 	log.Printf("UpdateFullTranscriptionMonoWithSegments: session %s has %d chunks, %d segments",
 		sessionID, len(session.Chunks), len(segments))
@@ -1819,130 +2243,98 @@ func (m *Manager) UpdateSpeakerName(sessionID string, oldName, newName string) e
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
+	session.renameSpeakerLocked(oldName, newName)
 
-	// Обновляем имя спикера во всех чанках
-	for _, chunk := range session.Chunks {
-		modified := false
+	log.Printf("UpdateSpeakerName: session %s, '%s' -> '%s'", sessionID, oldName, newName)
+	return nil
+}
 
-		// Dialogue
-		for i := range chunk.Dialogue {
-			if chunk.Dialogue[i].Speaker == oldName {
-				chunk.Dialogue[i].Speaker = newName
-				modified = true
-			}
+// renameSpeakerLocked заменяет имя спикера во всех чанках и вариантах диалога. Вызывается под s.mu
+func (s *Session) renameSpeakerLocked(oldName, newName string) {
+	for _, chunk := range s.Chunks {
+		if renameChunkSegmentsSpeaker(chunk, oldName, newName) {
+			s.saveChunkLocked(chunk)
 		}
+	}
 
-		// SysSegments
-		for i := range chunk.SysSegments {
-			if chunk.SysSegments[i].Speaker == oldName {
-				chunk.SysSegments[i].Speaker = newName
-				modified = true
-			}
+	// И в сохранённых вариантах диалога
+	if s.renameDialogueLayersSpeakerLocked(oldName, newName) {
+		if err := s.saveDialogueLayersLocked(); err != nil {
+			log.Printf("UpdateSpeakerName: %v", err)
 		}
+	}
+}
 
-		// MicSegments
-		for i := range chunk.MicSegments {
-			if chunk.MicSegments[i].Speaker == oldName {
-				chunk.MicSegments[i].Speaker = newName
+// renameChunkSpeakerLocked заменяет имя спикера в одном чанке и его вариантах диалога.
+// Варианты не сохраняются: возвращает true, если они изменились. Вызывается под s.mu
+func (s *Session) renameChunkSpeakerLocked(chunk *Chunk, oldName, newName string) bool {
+	if renameChunkSegmentsSpeaker(chunk, oldName, newName) {
+		s.saveChunkLocked(chunk)
+	}
+	if s.layers == nil {
+		return false
+	}
+	modified := false
+	for _, layer := range s.layers.Layers {
+		dialogue := layer.Chunks[chunk.Index]
+		for i := range dialogue {
+			if dialogue[i].Speaker == oldName {
+				dialogue[i].Speaker = newName
 				modified = true
 			}
 		}
-
-		// Сохраняем чанк если был изменён
-		if modified {
-			chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
-			data, _ := json.MarshalIndent(chunk, "", "  ")
-			os.WriteFile(chunkMetaPath, data, 0644)
-		}
 	}
-
-	log.Printf("UpdateSpeakerName: session %s, '%s' -> '%s'", sessionID, oldName, newName)
-	return nil
+	return modified
 }
 
-// UpdateImprovedDialogue обновляет диалог сессии улучшенной версией от LLM
-// Распределяет улучшенный диалог по всем чанкам на основе timestamps
-func (m *Manager) UpdateImprovedDialogue(sessionID string, improvedDialogue []TranscriptSegment) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// renameChunkSegmentsSpeaker заменяет имя спикера в диалоге и каналах чанка
+func renameChunkSegmentsSpeaker(chunk *Chunk, oldName, newName string) bool {
+	modified := false
 
-	session, ok := m.sessions[sessionID]
-	if !ok {
-		return fmt.Errorf("session not found: %s", sessionID)
-	}
-
-	session.mu.Lock()
-	defer session.mu.Unlock()
-
-	if len(session.Chunks) == 0 {
-		return fmt.Errorf("session has no chunks")
-	}
-
-	// Если только один чанк - просто обновляем его
-	if len(session.Chunks) == 1 {
-		chunk := session.Chunks[0]
-		chunk.Dialogue = improvedDialogue
-		chunk.Transcription = formatDialogue(improvedDialogue)
-
-		chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
-		data, _ := json.MarshalIndent(chunk, "", "  ")
-		os.WriteFile(chunkMetaPath, data, 0644)
-
-		log.Printf("UpdateImprovedDialogue: session %s (single chunk) updated with %d improved segments", sessionID, len(improvedDialogue))
-		return nil
-	}
-
-	// Для нескольких чанков - распределяем сегменты по чанкам на основе timestamps
-	// Сначала собираем информацию о временных диапазонах чанков
-	type chunkRange struct {
-		chunk   *Chunk
-		startMs int64
-		endMs   int64
-	}
-
-	var chunkRanges []chunkRange
-	var currentOffset int64 = 0
-
-	for _, chunk := range session.Chunks {
-		durationMs := int64(chunk.Duration / time.Millisecond) // time.Duration -> миллисекунды
-		chunkRanges = append(chunkRanges, chunkRange{
-			chunk:   chunk,
-			startMs: currentOffset,
-			endMs:   currentOffset + durationMs,
-		})
-		currentOffset += durationMs
+	// Dialogue
+	for i := range chunk.Dialogue {
+		if chunk.Dialogue[i].Speaker == oldName {
+			chunk.Dialogue[i].Speaker = newName
+			modified = true
+		}
+		if chunk.Dialogue[i].OverlapSpeaker == oldName {
+			chunk.Dialogue[i].OverlapSpeaker = newName
+			modified = true
+		}
 	}
 
-	// Распределяем сегменты по чанкам
-	chunkDialogues := make(map[int][]TranscriptSegment)
-
-	for _, seg := range improvedDialogue {
-		// Находим чанк, в который попадает этот сегмент
-		for i, cr := range chunkRanges {
-			// Сегмент попадает в чанк если его начало в диапазоне чанка
-			if seg.Start >= cr.startMs && seg.Start < cr.endMs {
-				chunkDialogues[i] = append(chunkDialogues[i], seg)
-				break
-			}
+	// SysSegments
+	for i := range chunk.SysSegments {
+		if chunk.SysSegments[i].Speaker == oldName {
+			chunk.SysSegments[i].Speaker = newName
+			modified = true
+		}
+		if chunk.SysSegments[i].OverlapSpeaker == oldName {
+			chunk.SysSegments[i].OverlapSpeaker = newName
+			modified = true
 		}
 	}
 
-	// Обновляем каждый чанк
-	for i, chunk := range session.Chunks {
-		if dialogue, ok := chunkDialogues[i]; ok && len(dialogue) > 0 {
-			chunk.Dialogue = dialogue
-			chunk.Transcription = formatDialogue(dialogue)
+	// MicSegments
+	for i := range chunk.MicSegments {
+		if chunk.MicSegments[i].Speaker == oldName {
+			chunk.MicSegments[i].Speaker = newName
+			modified = true
 		}
-		// Сохраняем метаданные чанка
-		chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
-		data, _ := json.MarshalIndent(chunk, "", "  ")
-		os.WriteFile(chunkMetaPath, data, 0644)
 	}
+	return modified
+}
 
-	log.Printf("UpdateImprovedDialogue: session %s updated %d chunks with %d total improved segments",
-		sessionID, len(session.Chunks), len(improvedDialogue))
+// saveChunkLocked сохраняет метаданные чанка. Вызывается под s.mu
+func (s *Session) saveChunkLocked(chunk *Chunk) {
+	chunkMetaPath := filepath.Join(s.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+	data, _ := json.MarshalIndent(chunk, "", "  ")
+	os.WriteFile(chunkMetaPath, data, 0644)
+}
 
-	return nil
+// UpdateImprovedDialogue сохраняет улучшенную LLM версию диалога как слой "improved"
+func (m *Manager) UpdateImprovedDialogue(sessionID string, improvedDialogue []TranscriptSegment) error {
+	return m.SetDialogueLayer(sessionID, DialogueLayerImproved, improvedDialogue)
 }
 
 // SearchParams параметры для поиска сессий
@@ -2131,22 +2523,8 @@ func (m *Manager) MergeSpeakers(sessionID string, sourceLocalIDs []int, targetLo
 	// Определяем финальное имя СНАЧАЛА
 	finalName := newName
 	if finalName == "" {
-		// Берём имя от target спикера
-		targetNames := getSpeakerNamesForLocalID(targetLocalID)
-		if len(targetNames) > 0 {
-			// Предпочитаем "Собеседник N" формат
-			for _, name := range targetNames {
-				if strings.HasPrefix(name, "Собеседник") {
-					finalName = name
-					break
-				}
-			}
-			if finalName == "" {
-				finalName = targetNames[0]
-			}
-		} else {
-			finalName = fmt.Sprintf("Собеседник %d", targetLocalID+1)
-		}
+		// Берём стандартное имя target спикера ("Собеседник N")
+		finalName = SpeakerLabel(targetLocalID)
 	}
 
 	// Собираем все возможные имена спикеров для замены
@@ -2223,18 +2601,24 @@ func (m *Manager) MergeSpeakers(sessionID string, sourceLocalIDs []int, targetLo
 		}
 	}
 
+	// И в сохранённых вариантах диалога, иначе переключение слоя вернёт прежних спикеров
+	layersModified := false
+	for oldName := range oldNames {
+		if session.renameDialogueLayersSpeakerLocked(oldName, finalName) {
+			layersModified = true
+		}
+	}
+	if layersModified {
+		if err := session.saveDialogueLayersLocked(); err != nil {
+			log.Printf("MergeSpeakers: %v", err)
+		}
+	}
+
 	log.Printf("MergeSpeakers: updated %d segments in %d chunks", updatedSegments, updatedChunks)
 	return updatedSegments, nil
 }
 
 // getSpeakerNamesForLocalID возвращает все возможные имена спикера по localID
 func getSpeakerNamesForLocalID(localID int) []string {
-	if localID == -1 {
-		return []string{"Вы", "mic"}
-	}
-	// Стандартные имена для sys спикеров
-	return []string{
-		fmt.Sprintf("Speaker %d", localID),
-		fmt.Sprintf("Собеседник %d", localID+1),
-	}
+	return SpeakerLabelVariants(localID)
 }
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestFile машиночитаемое описание сессии в её директории
+const ManifestFile = "manifest.json"
+
+// ManifestFormatVersion версия формата manifest.json
+const ManifestFormatVersion = 1
+
+// ManifestEnvironment сведения о приложении для манифеста, которых нет в самой сессии
+type ManifestEnvironment struct {
+	AppVersion         string
+	DiarizationBackend string // sherpa, fluid; пусто - диаризация выключена
+}
+
+// SessionManifest описание сессии для внешних инструментов и воспроизводимости:
+// аудиофайлы, границы чанков и чем они распознаны
+type SessionManifest struct {
+	FormatVersion      int             `json:"formatVersion"`
+	AppVersion         string          `json:"appVersion,omitempty"`
+	GeneratedAt        time.Time       `json:"generatedAt"`
+	SessionID          string          `json:"sessionId"`
+	Title              string          `json:"title,omitempty"`
+	StartTime          time.Time       `json:"startTime"`
+	EndTime            *time.Time      `json:"endTime,omitempty"`
+	DurationMs         int64           `json:"durationMs"`
+	Language           string          `json:"language"`
+	Models             []string        `json:"models"` // Модель сессии и модели, которыми распознаны чанки
+	DiarizationBackend string          `json:"diarizationBackend,omitempty"`
+	AudioFiles         []ManifestAudio `json:"audioFiles"`
+	Chunks             []ManifestChunk `json:"chunks"`
+}
+
+// ManifestAudio аудиофайл сессии (путь относительно директории сессии)
+type ManifestAudio struct {
+	Path      string `json:"path"`
+	Role      string `json:"role"` // recording - запись (MIC слева, SYS справа), raw-capture - отладочный дамп захвата
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+// ManifestChunk границы чанка в записи и результат его распознавания
+type ManifestChunk struct {
+	Index         int         `json:"index"`
+	StartMs       int64       `json:"startMs"`
+	EndMs         int64       `json:"endMs"`
+	Status        ChunkStatus `json:"status"`
+	IsStereo      bool        `json:"isStereo,omitempty"`
+	Model         string      `json:"model,omitempty"`
+	TranscribedAt *time.Time  `json:"transcribedAt,omitempty"`
+}
+
+// manifestAudioFiles известные аудиофайлы директории сессии и их роли
+var manifestAudioFiles = []struct{ name, role string }{
+	{"full.mp3", "recording"},
+	{"full.wav", "recording"},
+	{"raw_capture.bin", "raw-capture"},
+}
+
+// SetManifestEnvironment устанавливает источник сведений о приложении для manifest.json
+func (m *Manager) SetManifestEnvironment(fn func() ManifestEnvironment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.manifestEnv = fn
+}
+
+// GetSessionManifest возвращает манифест сессии: сохранённый, а для старых сессий без него - собранный заново
+func (m *Manager) GetSessionManifest(sessionID string) (*SessionManifest, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(session.DataDir, ManifestFile))
+	if err == nil {
+		var manifest SessionManifest
+		if err := json.Unmarshal(data, &manifest); err == nil {
+			return &manifest, nil
+		}
+		log.Printf("Failed to parse %s for session %s, rebuilding", ManifestFile, sessionID)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	return m.buildManifestLocked(session), nil
+}
+
+// buildManifestLocked собирает манифест. Вызывается под m.mu и session.mu
+func (m *Manager) buildManifestLocked(session *Session) *SessionManifest {
+	manifest := &SessionManifest{
+		FormatVersion: ManifestFormatVersion,
+		GeneratedAt:   time.Now(),
+		SessionID:     session.ID,
+		Title:         session.Title,
+		StartTime:     session.StartTime,
+		EndTime:       session.EndTime,
+		DurationMs:    int64(session.TotalDuration / time.Millisecond),
+		Language:      session.Language,
+		AudioFiles:    []ManifestAudio{},
+		Chunks:        make([]ManifestChunk, 0, len(session.Chunks)),
+	}
+	if m.manifestEnv != nil {
+		env := m.manifestEnv()
+		manifest.AppVersion = env.AppVersion
+		manifest.DiarizationBackend = env.DiarizationBackend
+	}
+
+	seen := make(map[string]bool)
+	addModel := func(model string) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			manifest.Models = append(manifest.Models, model)
+		}
+	}
+	addModel(session.Model)
+
+	for _, f := range manifestAudioFiles {
+		if info, err := os.Stat(filepath.Join(session.DataDir, f.name)); err == nil {
+			manifest.AudioFiles = append(manifest.AudioFiles, ManifestAudio{Path: f.name, Role: f.role, SizeBytes: info.Size()})
+		}
+	}
+
+	for _, chunk := range session.Chunks {
+		addModel(chunk.Model)
+		manifest.Chunks = append(manifest.Chunks, ManifestChunk{
+			Index:         chunk.Index,
+			StartMs:       chunk.StartMs,
+			EndMs:         chunk.EndMs,
+			Status:        chunk.Status,
+			IsStereo:      chunk.IsStereo,
+			Model:         chunk.Model,
+			TranscribedAt: chunk.TranscribedAt,
+		})
+	}
+	if manifest.Models == nil {
+		manifest.Models = []string{}
+	}
+	return manifest
+}
+
+// writeManifestLocked сохраняет manifest.json. Вызывается под m.mu и session.mu
+func (m *Manager) writeManifestLocked(session *Session) error {
+	data, err := json.MarshalIndent(m.buildManifestLocked(session), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(session.DataDir, ManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	return nil
+}
+
+// refreshManifestLocked обновляет манифест после распознавания, если запись уже остановлена
+// (во время записи манифест ещё не создан). Вызывается под m.mu и session.mu
+func (m *Manager) refreshManifestLocked(session *Session) {
+	if session.Status == SessionStatusRecording || session.Status == SessionStatusPaused {
+		return
+	}
+	if err := m.writeManifestLocked(session); err != nil {
+		log.Printf("Session %s: %v", session.ID, err)
+	}
+}
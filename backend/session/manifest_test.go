@@ -0,0 +1,89 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionManifest(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetManifestEnvironment(func() ManifestEnvironment {
+		return ManifestEnvironment{AppVersion: "1.2.3", DiarizationBackend: "sherpa"}
+	})
+	sess, err := m.CreateSession(SessionConfig{Language: "ru", Model: "ggml-base"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, StartMs: 0, EndMs: 30000, Duration: 30 * time.Second, IsStereo: true}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() SessionManifest {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(sess.DataDir, ManifestFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var manifest SessionManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatal(err)
+		}
+		return manifest
+	}
+
+	// Во время записи манифест не пишется
+	m.MarkChunkModel(sess.ID, chunk.ID, "ggml-base")
+	if err := m.UpdateChunkTranscription(sess.ID, chunk.ID, "привет", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(sess.DataDir, ManifestFile)); !os.IsNotExist(err) {
+		t.Fatalf("manifest written while recording: %v", err)
+	}
+
+	if _, err := m.StopSession(); err != nil {
+		t.Fatal(err)
+	}
+	manifest := read()
+	if manifest.SessionID != sess.ID || manifest.Language != "ru" || manifest.AppVersion != "1.2.3" || manifest.DiarizationBackend != "sherpa" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if len(manifest.AudioFiles) != 1 || manifest.AudioFiles[0].Path != "full.mp3" || manifest.AudioFiles[0].SizeBytes != 3 {
+		t.Errorf("audio files = %+v", manifest.AudioFiles)
+	}
+	if len(manifest.Chunks) != 1 || manifest.Chunks[0].EndMs != 30000 || manifest.Chunks[0].Status != ChunkStatusCompleted || !manifest.Chunks[0].IsStereo {
+		t.Errorf("chunks = %+v", manifest.Chunks)
+	}
+
+	// Ретранскрипция другой моделью обновляет манифест
+	m.MarkChunkModel(sess.ID, chunk.ID, "gigaam-v3-ctc")
+	if err := m.UpdateChunkTranscription(sess.ID, chunk.ID, "", os.ErrDeadlineExceeded); err != nil {
+		t.Fatal(err)
+	}
+	manifest = read()
+	if len(manifest.Models) != 2 || manifest.Models[0] != "ggml-base" || manifest.Models[1] != "gigaam-v3-ctc" {
+		t.Errorf("models = %q", manifest.Models)
+	}
+	if manifest.Chunks[0].Status != ChunkStatusFailed || manifest.Chunks[0].Model != "gigaam-v3-ctc" {
+		t.Errorf("chunk after retranscription = %+v", manifest.Chunks[0])
+	}
+
+	// Старая сессия без manifest.json - манифест собирается на лету
+	os.Remove(filepath.Join(sess.DataDir, ManifestFile))
+	got, err := m.GetSessionManifest(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != sess.ID || len(got.Chunks) != 1 {
+		t.Errorf("rebuilt manifest = %+v", got)
+	}
+}
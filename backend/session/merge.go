@@ -0,0 +1,501 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// speakerProfilesFile профили спикеров сессии (пишет TranscriptionService)
+const speakerProfilesFile = "speaker_profiles.json"
+
+// MergeSessions присоединяет к целевой сессии сессии sourceIDs в указанном порядке:
+// full.mp3 склеиваются через FFmpeg, чанки источников сдвигаются на суммарную длительность
+// предыдущих записей и добавляются в конец. Собеседники источников получают новые номера
+// (метки в сегментах и профили speaker_profiles.json), чтобы не совпасть с собеседниками цели.
+// Варианты диалога, история переименований и summary источников переносятся в цель,
+// диалог с пунктуацией - только если он есть у всех сессий.
+// Источники удаляются после слияния, кеш waveform цели сбрасывается
+func (m *Manager) MergeSessions(targetID string, sourceIDs []string) (*Session, error) {
+	if len(sourceIDs) == 0 {
+		return nil, fmt.Errorf("no sessions to merge")
+	}
+
+	// FFmpeg работает без m.mu: сессии атомарно отмечаются занятыми, чтобы их не удалил
+	// ретеншн и не взяли в работу другие операции (ретранскрипция, перенос)
+	m.mu.Lock()
+	all, err := m.mergeCandidatesLocked(targetID, sourceIDs)
+	var release func()
+	if err == nil {
+		release, err = m.markSessionsExclusive(all)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	target, sources := all[0], all[1:]
+
+	// Склеиваем аудио во временный файл: при ошибке FFmpeg сессии остаются нетронутыми
+	inputs := make([]string, len(all))
+	for i, sess := range all {
+		inputs[i] = filepath.Join(sess.DataDir, "full.mp3")
+		if !fileExists(inputs[i]) {
+			return nil, fmt.Errorf("session %s has no full.mp3", sess.ID)
+		}
+	}
+	mergedPath := filepath.Join(target.DataDir, "full.merged.mp3")
+	if err := concatMP3(inputs, mergedPath); err != nil {
+		os.Remove(mergedPath)
+		return nil, err
+	}
+
+	// Смещения - по декодированной длительности full.mp3: FFmpeg склеивает аудио целиком,
+	// а TotalDuration может с ним расходиться (паузы, недописанный хвост)
+	durations := make([]int64, len(all))
+	for i, sess := range all {
+		if durations[i], err = mp3DurationMs(inputs[i]); err != nil {
+			sess.mu.RLock()
+			durations[i] = sessionDurationMs(sess)
+			sess.mu.RUnlock()
+			log.Printf("MergeSessions: using stored duration of %s: %v", sess.ID, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sess := range all {
+		if m.sessions[sess.ID] != sess {
+			os.Remove(mergedPath)
+			return nil, fmt.Errorf("session %s was deleted during merge", sess.ID)
+		}
+	}
+
+	targetProfiles, err := readProfilesRaw(target.DataDir)
+	if err != nil {
+		os.Remove(mergedPath)
+		return nil, err
+	}
+
+	target.mu.Lock()
+	offsetMs := durations[0]
+	speakerOffset := maxSpeakerNumber(target.Chunks, targetProfiles)
+	nextIndex := 0
+	for _, chunk := range target.Chunks {
+		nextIndex = max(nextIndex, chunk.Index+1)
+	}
+	punctuated := slices.Clone(target.PunctuatedDialogue)
+	var summaries []string
+	if target.Summary != "" {
+		summaries = append(summaries, target.Summary)
+	}
+
+	var appended []*Chunk
+	for i, source := range sources {
+		source.mu.RLock()
+		sourceProfiles, err := readProfilesRaw(source.DataDir)
+		if err != nil {
+			// Профили только ускоряют сопоставление голосов, без них слияние возможно
+			log.Printf("MergeSessions: skipping speaker profiles of %s: %v", source.ID, err)
+		}
+		indexes := make(map[int]int, len(source.Chunks))
+		for _, chunk := range source.Chunks {
+			moved := shiftChunk(chunk, offsetMs, speakerOffset)
+			moved.SessionID = target.ID
+			moved.Index = nextIndex
+			indexes[chunk.Index] = nextIndex
+			nextIndex++
+			appended = append(appended, moved)
+		}
+		mergeDialogueLayersLocked(target, source, indexes, offsetMs, speakerOffset)
+		targetProfiles = append(targetProfiles, remapProfilesRaw(sourceProfiles, speakerOffset)...)
+		target.SpeakerRenames = append(slices.Clone(target.SpeakerRenames), shiftSpeakerRenames(source.SpeakerRenames, speakerOffset)...)
+		// Диалог с пунктуацией частью сессии не показать: без него у источника он сбрасывается
+		if punctuated != nil && source.PunctuatedDialogue != nil {
+			punctuated = append(punctuated, shiftSegments(source.PunctuatedDialogue, offsetMs, speakerOffset)...)
+		} else {
+			punctuated = nil
+		}
+		if source.Summary != "" {
+			summaries = append(summaries, source.Summary)
+		}
+		speakerOffset += maxSpeakerNumber(source.Chunks, sourceProfiles)
+
+		offsetMs += durations[i+1]
+		target.SampleCount += source.SampleCount
+		if source.EndTime != nil && (target.EndTime == nil || source.EndTime.After(*target.EndTime)) {
+			endTime := *source.EndTime
+			target.EndTime = &endTime
+		}
+		source.mu.RUnlock()
+	}
+
+	target.Chunks = append(target.Chunks, appended...)
+	target.TotalDuration = time.Duration(offsetMs) * time.Millisecond
+	target.PunctuatedDialogue = punctuated
+	target.Summary = strings.Join(summaries, "\n\n")
+	// Пики прежнего full.mp3 не соответствуют склеенному аудио: UI пересчитает waveform
+	target.Waveform = nil
+	var layersErr error
+	if target.layers != nil {
+		layersErr = target.saveDialogueLayersLocked()
+	}
+	target.mu.Unlock()
+	if layersErr != nil {
+		return nil, layersErr
+	}
+
+	if err := os.Rename(mergedPath, filepath.Join(target.DataDir, "full.mp3")); err != nil {
+		return nil, fmt.Errorf("failed to replace full.mp3: %w", err)
+	}
+	// full.wav (если остался) содержит только прежнюю запись
+	os.Remove(filepath.Join(target.DataDir, "full.wav"))
+
+	for _, chunk := range appended {
+		chunkMetaPath := filepath.Join(target.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+		data, err := json.MarshalIndent(chunk, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(chunkMetaPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save chunk %d: %w", chunk.Index, err)
+		}
+	}
+	if len(targetProfiles) > 0 {
+		data, err := json.Marshal(targetProfiles)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(target.DataDir, speakerProfilesFile), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save speaker profiles: %w", err)
+		}
+	}
+	if err := saveSessionExtras(target); err != nil {
+		return nil, err
+	}
+	if err := m.SaveSessionMeta(target); err != nil {
+		return nil, fmt.Errorf("failed to save session meta: %w", err)
+	}
+
+	for _, source := range sources {
+		if err := m.deleteSessionLocked(source); err != nil {
+			log.Printf("MergeSessions: failed to delete merged session %s: %v", source.ID, err)
+		}
+	}
+
+	target.mu.Lock()
+	m.refreshManifestLocked(target)
+	target.mu.Unlock()
+
+	log.Printf("MergeSessions: merged %d sessions into %s (%d chunks appended, duration %v)",
+		len(sources), target.ID, len(appended), target.TotalDuration)
+	return target, nil
+}
+
+// mergeCandidatesLocked проверяет цель и источники слияния, возвращает их (цель первой).
+// Вызывается под m.mu
+func (m *Manager) mergeCandidatesLocked(targetID string, sourceIDs []string) ([]*Session, error) {
+	target, err := m.mergeCandidateLocked(targetID)
+	if err != nil {
+		return nil, err
+	}
+	all := []*Session{target}
+	seen := map[string]bool{targetID: true}
+	for _, id := range sourceIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("session %s is listed twice", id)
+		}
+		seen[id] = true
+		source, err := m.mergeCandidateLocked(id)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, source)
+	}
+	return all, nil
+}
+
+// mergeCandidateLocked возвращает сессию, которую можно объединять или разделять (запись
+// завершена). Занятость проверяется при отметке задачи (TryMarkSessionBusy). Вызывается под m.mu
+func (m *Manager) mergeCandidateLocked(id string) (*Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if id == m.activeID || session.Status == SessionStatusRecording {
+		return nil, fmt.Errorf("session %s is still recording", id)
+	}
+	return session, nil
+}
+
+// mp3DurationMs длительность MP3 по декодеру (с учётом всех фреймов, а не метаданных сессии)
+func mp3DurationMs(path string) (int64, error) {
+	reader, err := NewMP3Reader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return int64(reader.Duration() * 1000), nil
+}
+
+// mergeDialogueLayersLocked переносит варианты диалога источника в цель: чанки получают
+// новые индексы (indexes), сегменты сдвигаются так же, как чанки. Вызывается под target.mu и source.mu
+func mergeDialogueLayersLocked(target, source *Session, indexes map[int]int, offsetMs int64, speakerOffset int) {
+	if source.layers == nil {
+		return
+	}
+	for name, layer := range source.layers.Layers {
+		merged := target.layerLocked(name, true)
+		for index, dialogue := range layer.Chunks {
+			newIndex, ok := indexes[index]
+			if !ok {
+				continue
+			}
+			merged.Chunks[newIndex] = shiftSegments(dialogue, offsetMs, speakerOffset)
+			if layer.Stale[index] {
+				if merged.Stale == nil {
+					merged.Stale = make(map[int]bool)
+				}
+				merged.Stale[newIndex] = true
+			}
+		}
+		if layer.UpdatedAt.After(merged.UpdatedAt) {
+			merged.UpdatedAt = layer.UpdatedAt
+		}
+	}
+	if target.layers != nil && target.layers.Active == "" {
+		target.layers.Active = source.layers.Active
+	}
+}
+
+// shiftSpeakerRenames копия истории переименований с номерами собеседников, увеличенными на offset
+func shiftSpeakerRenames(history []SpeakerRename, offset int) []SpeakerRename {
+	result := make([]SpeakerRename, len(history))
+	for i, r := range history {
+		if r.LocalID >= 0 {
+			r.LocalID += offset
+		}
+		r.StandardName = shiftSpeakerLabel(r.StandardName, offset)
+		r.OldName = shiftSpeakerLabel(r.OldName, offset)
+		r.NewName = shiftSpeakerLabel(r.NewName, offset)
+		r.Labels = slices.Clone(r.Labels)
+		for j, label := range r.Labels {
+			r.Labels[j] = shiftSpeakerLabel(label, offset)
+		}
+		r.Segments = slices.Clone(r.Segments)
+		for j := range r.Segments {
+			r.Segments[j].Label = shiftSpeakerLabel(r.Segments[j].Label, offset)
+		}
+		result[i] = r
+	}
+	return result
+}
+
+// saveSessionExtras сохраняет историю переименований, диалог с пунктуацией и summary сессии,
+// собранные при слиянии или разделении
+func saveSessionExtras(target *Session) error {
+	target.mu.RLock()
+	renames, punctuated, summary := target.SpeakerRenames, target.PunctuatedDialogue, target.Summary
+	target.mu.RUnlock()
+
+	if len(renames) > 0 {
+		if err := saveSpeakerRenames(target.DataDir, renames); err != nil {
+			return err
+		}
+	}
+	punctuatedPath := filepath.Join(target.DataDir, punctuatedDialogueFile)
+	if punctuated != nil {
+		data, err := json.MarshalIndent(punctuated, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal punctuated dialogue: %w", err)
+		}
+		if err := os.WriteFile(punctuatedPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to save punctuated dialogue: %w", err)
+		}
+	} else if err := os.Remove(punctuatedPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove punctuated dialogue: %w", err)
+	}
+	if summary != "" {
+		if err := os.WriteFile(filepath.Join(target.DataDir, "summary.txt"), []byte(summary), 0644); err != nil {
+			return fmt.Errorf("failed to save summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// concatMP3 склеивает MP3 файлы подряд в output (с перекодированием: каналы записей могут отличаться)
+func concatMP3(inputs []string, outputPath string) error {
+	if err := RequireFFmpeg(); err != nil {
+		return err
+	}
+
+	var args []string
+	var filter strings.Builder
+	for i, input := range inputs {
+		args = append(args, "-i", input)
+		fmt.Fprintf(&filter, "[%d:a]", i)
+	}
+	fmt.Fprintf(&filter, "concat=n=%d:v=0:a=1[out]", len(inputs))
+	args = append(args, "-y", "-filter_complex", filter.String(), "-map", "[out]")
+	args = append(args, MP3EncodeArgs(MP3TargetRecording)...)
+	args = append(args, outputPath)
+
+	output, err := exec.Command(getFFmpegPath(), args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg concat failed: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// sessionDurationMs длительность записи сессии (без TotalDuration - по концу последнего чанка)
+func sessionDurationMs(session *Session) int64 {
+	if session.TotalDuration > 0 {
+		return int64(session.TotalDuration / time.Millisecond)
+	}
+	var end int64
+	for _, chunk := range session.Chunks {
+		end = max(end, chunk.EndMs)
+	}
+	return end
+}
+
+// shiftChunk копия чанка, сдвинутая на offsetMs, с номерами собеседников, увеличенными на speakerOffset.
+// Пути к WAV чанков источника не переносятся: аудио берётся из склеенного full.mp3
+func shiftChunk(chunk *Chunk, offsetMs int64, speakerOffset int) *Chunk {
+	moved := *chunk
+	moved.StartMs += offsetMs
+	moved.EndMs += offsetMs
+	moved.StartOffset, moved.EndOffset = 0, 0
+	moved.FilePath, moved.MicFilePath, moved.SysFilePath = "", "", ""
+	moved.ProcessingStartTime = nil
+	moved.MicSegments = shiftSegments(chunk.MicSegments, offsetMs, speakerOffset)
+	moved.SysSegments = shiftSegments(chunk.SysSegments, offsetMs, speakerOffset)
+	moved.Dialogue = shiftSegments(chunk.Dialogue, offsetMs, speakerOffset)
+	if len(moved.Dialogue) > 0 {
+		moved.Transcription = formatDialogue(moved.Dialogue)
+	}
+
+	moved.Clipping = make([]ChannelClipping, len(chunk.Clipping))
+	for i, clipping := range chunk.Clipping {
+		ranges := make([]ClippedRange, len(clipping.Ranges))
+		for j, r := range clipping.Ranges {
+			r.StartMs += offsetMs
+			r.EndMs += offsetMs
+			ranges[j] = r
+		}
+		clipping.Ranges = ranges
+		moved.Clipping[i] = clipping
+	}
+	if len(chunk.Clipping) == 0 {
+		moved.Clipping = nil
+	}
+	return &moved
+}
+
+// shiftSegments сдвигает сегменты и слова на offsetMs и перенумеровывает собеседников
+func shiftSegments(segments []TranscriptSegment, offsetMs int64, speakerOffset int) []TranscriptSegment {
+	if segments == nil {
+		return nil
+	}
+	result := make([]TranscriptSegment, len(segments))
+	for i, seg := range segments {
+		seg.Start += offsetMs
+		seg.End += offsetMs
+		seg.Speaker = shiftSpeakerLabel(seg.Speaker, speakerOffset)
+		if seg.Words != nil {
+			words := make([]TranscriptWord, len(seg.Words))
+			for j, w := range seg.Words {
+				w.Start += offsetMs
+				w.End += offsetMs
+				w.Speaker = shiftSpeakerLabel(w.Speaker, speakerOffset)
+				words[j] = w
+			}
+			seg.Words = words
+		}
+		result[i] = seg
+	}
+	return result
+}
+
+// shiftSpeakerLabel "Собеседник N" -> "Собеседник N+offset", "Speaker N" (моно-диаризация) ->
+// "Speaker N+offset"; остальные метки и имена не меняются
+func shiftSpeakerLabel(speaker string, offset int) string {
+	if offset == 0 {
+		return speaker
+	}
+	if localID, ok := ParseSpeakerLabel(speaker); ok {
+		return SpeakerLabel(localID + offset)
+	}
+	if localID, ok := parseDiarizationLabel(speaker); ok {
+		return fmt.Sprintf("Speaker %d", localID+offset)
+	}
+	return speaker
+}
+
+// maxSpeakerNumber число занятых localID собеседников (наибольший localID меток или SpeakerID профиля + 1) в сессии
+func maxSpeakerNumber(chunks []*Chunk, profiles []map[string]json.RawMessage) int {
+	result := 0
+	note := func(segments []TranscriptSegment) {
+		for _, seg := range segments {
+			if localID, ok := ParseSpeakerLabel(seg.Speaker); ok {
+				result = max(result, localID+1)
+			} else if localID, ok := parseDiarizationLabel(seg.Speaker); ok {
+				result = max(result, localID+1)
+			}
+		}
+	}
+	for _, chunk := range chunks {
+		note(chunk.MicSegments)
+		note(chunk.SysSegments)
+		note(chunk.Dialogue)
+	}
+	for _, profile := range profiles {
+		var id int
+		if json.Unmarshal(profile["SpeakerID"], &id) == nil {
+			result = max(result, id+1)
+		}
+	}
+	return result
+}
+
+// readProfilesRaw читает speaker_profiles.json как есть: поля профиля, кроме SpeakerID, не меняются
+func readProfilesRaw(dataDir string) ([]map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, speakerProfilesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var profiles []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", speakerProfilesFile, err)
+	}
+	return profiles, nil
+}
+
+// remapProfilesRaw увеличивает SpeakerID профилей на offset
+func remapProfilesRaw(profiles []map[string]json.RawMessage, offset int) []map[string]json.RawMessage {
+	result := make([]map[string]json.RawMessage, 0, len(profiles))
+	for _, profile := range profiles {
+		var id int
+		if err := json.Unmarshal(profile["SpeakerID"], &id); err != nil {
+			continue
+		}
+		remapped := make(map[string]json.RawMessage, len(profile))
+		for key, value := range profile {
+			remapped[key] = value
+		}
+		remapped["SpeakerID"] = json.RawMessage(fmt.Sprint(id + offset))
+		result = append(result, remapped)
+	}
+	return result
+}
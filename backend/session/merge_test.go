@@ -0,0 +1,342 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFakeFFmpeg подменяет FFmpeg скриптом: на -version печатает версию,
+// иначе пишет свои аргументы в выходной файл (последний аргумент)
+func useFakeFFmpeg(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	fake := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nif [ \"$1\" = \"-version\" ]; then echo 'ffmpeg version test'; exit 0; fi\nfor last; do :; done\necho \"$@\" > \"$last\"\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetFFmpegPath(fake)
+	t.Cleanup(func() { SetFFmpegPath("") })
+}
+
+func TestMergeSessions(t *testing.T) {
+	useFakeFFmpeg(t)
+
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Завершённая сессия длительностью durationMs с одним чанком, сегментом собеседника speaker и профилями profileIDs
+	create := func(durationMs int64, speaker string, profileIDs ...int) *Session {
+		t.Helper()
+		sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		sess.TotalDuration = time.Duration(durationMs) * time.Millisecond
+		sess.Waveform = &WaveformData{Duration: float64(durationMs) / 1000}
+		if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		seg := TranscriptSegment{Start: 1000, End: 2000, Text: "привет", Speaker: speaker,
+			Words: []TranscriptWord{{Start: 1000, End: 2000, Text: "привет", Speaker: speaker}}}
+		chunk := &Chunk{ID: sess.ID + "-0", SessionID: sess.ID, Index: 0, StartMs: 0, EndMs: durationMs, Status: ChunkStatusCompleted,
+			Duration: time.Duration(durationMs) * time.Millisecond,
+			Dialogue: []TranscriptSegment{seg}, Clipping: []ChannelClipping{{Channel: "mono", Ranges: []ClippedRange{{StartMs: 1000, EndMs: 1500}}}}}
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+		var profiles []map[string]any
+		for _, id := range profileIDs {
+			profiles = append(profiles, map[string]any{"SpeakerID": id, "Duration": 1.5})
+		}
+		if profiles != nil {
+			data, _ := json.Marshal(profiles)
+			if err := os.WriteFile(filepath.Join(sess.DataDir, speakerProfilesFile), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := m.SetPunctuatedDialogue(sess.ID, []TranscriptSegment{{Start: 1000, End: 2000, Text: "Привет.", Speaker: speaker}}); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+	target := create(10000, SpeakerLabel(1), 0, 1)
+	first := create(5000, SpeakerLabel(0), 0)
+	second := create(3000, SelfSpeakerLabel())
+
+	// Summary, переименование и улучшенный слой диалога переносятся из источников
+	if err := m.SetSessionSummary(target.ID, "Итоги цели"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSessionSummary(first.ID, "Итоги первой"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RecordSpeakerRename(first.ID, SpeakerRename{LocalID: 0, StandardName: SpeakerLabel(0), OldName: SpeakerLabel(0), NewName: "Анна"}); err != nil {
+		t.Fatal(err)
+	}
+	improved := []TranscriptSegment{{Start: 1000, End: 2000, Text: "Привет!", Speaker: SpeakerLabel(0),
+		Words: []TranscriptWord{{Start: 1000, End: 2000, Text: "Привет!", Speaker: SpeakerLabel(0)}}}}
+	if err := m.SetDialogueLayer(first.ID, DialogueLayerImproved, improved); err != nil {
+		t.Fatal(err)
+	}
+
+	m.mu.Lock()
+	release := m.MarkSessionBusy(second.ID)
+	m.mu.Unlock()
+	if _, err := m.MergeSessions(target.ID, []string{first.ID, second.ID}); err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("busy source: err = %v", err)
+	}
+	release()
+
+	// Занятость проверяется атомарно с отметкой: источник, на котором идёт ретранскрипция,
+	// отклоняет слияние, а уже отмеченные сессии освобождаются
+	releaseTask, err := m.TryMarkSessionBusy(second.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.markSessionsExclusive([]*Session{target, first, second}); err == nil {
+		t.Error("busy source marked for merge")
+	}
+	releaseTask()
+	for _, id := range []string{target.ID, first.ID, second.ID} {
+		if m.SessionBusy(id) {
+			t.Errorf("session %s left busy after a rejected merge", id)
+		}
+	}
+	releaseMerge, err := m.markSessionsExclusive([]*Session{target, first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.TryMarkSessionBusy(first.ID, false); err == nil {
+		t.Error("processing task started on a session being merged")
+	}
+	releaseMerge()
+
+	if _, err := m.MergeSessions(target.ID, []string{first.ID, first.ID}); err == nil {
+		t.Error("duplicate source accepted")
+	}
+	if _, err := m.MergeSessions(target.ID, []string{target.ID}); err == nil {
+		t.Error("merge into itself accepted")
+	}
+
+	merged, err := m.MergeSessions(target.ID, []string{first.ID, second.ID})
+	if err != nil {
+		t.Fatalf("MergeSessions: %v", err)
+	}
+	if merged.Waveform != nil {
+		t.Error("waveform cache is not invalidated")
+	}
+	if merged.TotalDuration != 18*time.Second {
+		t.Errorf("TotalDuration = %v, want 18s", merged.TotalDuration)
+	}
+	if len(merged.Chunks) != 3 {
+		t.Fatalf("chunks = %d, want 3", len(merged.Chunks))
+	}
+	for i, want := range []struct {
+		start   int64
+		speaker string
+	}{{0, SpeakerLabel(1)}, {10000, SpeakerLabel(2)}, {15000, SelfSpeakerLabel()}} {
+		chunk := merged.Chunks[i]
+		seg := chunk.Dialogue[0]
+		if chunk.Index != i || chunk.SessionID != target.ID || chunk.StartMs != want.start {
+			t.Errorf("chunk %d: index=%d session=%s start=%d", i, chunk.Index, chunk.SessionID, chunk.StartMs)
+		}
+		if seg.Start != want.start+1000 || seg.Words[0].Start != want.start+1000 || chunk.Clipping[0].Ranges[0].StartMs != want.start+1000 {
+			t.Errorf("chunk %d: segment/word/clipping timestamps are not shifted: %+v", i, chunk)
+		}
+		if seg.Speaker != want.speaker || seg.Words[0].Speaker != want.speaker {
+			t.Errorf("chunk %d: speaker = %q, want %q", i, seg.Speaker, want.speaker)
+		}
+	}
+
+	// Источники удалены, склеенное аудио и профили записаны в цель
+	for _, id := range []string{first.ID, second.ID} {
+		if _, err := m.GetSession(id); err == nil {
+			t.Errorf("source %s is not deleted", id)
+		}
+	}
+	if _, err := os.Stat(first.DataDir); !os.IsNotExist(err) {
+		t.Errorf("source files are not deleted: %v", err)
+	}
+	audio, err := os.ReadFile(filepath.Join(target.DataDir, "full.mp3"))
+	if err != nil || !strings.Contains(string(audio), "concat=n=3") {
+		t.Errorf("full.mp3 = %q, %v", audio, err)
+	}
+	profiles, err := readProfilesRaw(target.DataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, profile := range profiles {
+		ids = append(ids, string(profile["SpeakerID"]))
+	}
+	if strings.Join(ids, ",") != "0,1,2" {
+		t.Errorf("profile ids = %v, want 0,1,2", ids)
+	}
+	checkExtras := func(sess *Session) {
+		t.Helper()
+		if sess.Summary != "Итоги цели\n\nИтоги первой" {
+			t.Errorf("summary = %q", sess.Summary)
+		}
+		if len(sess.SpeakerRenames) != 1 || sess.SpeakerRenames[0].LocalID != 2 || sess.SpeakerRenames[0].StandardName != SpeakerLabel(2) {
+			t.Errorf("speaker renames = %+v", sess.SpeakerRenames)
+		}
+		var starts []int64
+		for _, seg := range sess.PunctuatedDialogue {
+			starts = append(starts, seg.Start)
+		}
+		if len(starts) != 3 || starts[1] != 11000 || starts[2] != 16000 || sess.PunctuatedDialogue[1].Speaker != SpeakerLabel(2) {
+			t.Errorf("punctuated dialogue = %+v", sess.PunctuatedDialogue)
+		}
+		if sess.ActiveDialogueLayer != DialogueLayerImproved {
+			t.Errorf("active layer = %q", sess.ActiveDialogueLayer)
+		}
+	}
+	checkExtras(merged)
+	layer, err := m.GetDialogueLayerChunks(target.ID, DialogueLayerImproved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seg := layer[1][0]; seg.Text != "Привет!" || seg.Start != 11000 || seg.Speaker != SpeakerLabel(2) {
+		t.Errorf("improved layer of merged chunk = %+v", seg)
+	}
+	if raw, err := m.GetDialogueLayerChunks(target.ID, DialogueLayerRaw); err != nil || raw[1][0].Text != "привет" {
+		t.Errorf("raw layer of merged chunk = %+v, %v", raw, err)
+	}
+
+	// После перезагрузки сессия читается с диска с присоединёнными чанками
+	reloaded, err := NewManager(m.dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := reloaded.GetSession(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.Chunks) != 3 || sess.Chunks[2].StartMs != 15000 || sess.Waveform != nil {
+		t.Errorf("reloaded session: %d chunks, waveform %v", len(sess.Chunks), sess.Waveform)
+	}
+	checkExtras(sess)
+}
+
+func TestMergeSessions_MonoDiarization(t *testing.T) {
+	useFakeFFmpeg(t)
+
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Моно-диаризация хранит в сегментах сырые метки "Speaker N"
+	create := func(speakers ...string) *Session {
+		t.Helper()
+		sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		sess.TotalDuration = 10 * time.Second
+		if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var dialogue []TranscriptSegment
+		for i, speaker := range speakers {
+			start := int64(i) * 1000
+			dialogue = append(dialogue, TranscriptSegment{Start: start, End: start + 500, Text: "да", Speaker: speaker,
+				Words: []TranscriptWord{{Start: start, End: start + 500, Text: "да", Speaker: speaker}}})
+		}
+		chunk := &Chunk{ID: sess.ID + "-0", SessionID: sess.ID, EndMs: 10000, Status: ChunkStatusCompleted, Dialogue: dialogue}
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+	target := create("Speaker 0", "Speaker 1")
+	source := create("Speaker 0", "Speaker 1", SelfSpeakerLabel())
+
+	merged, err := m.MergeSessions(target.ID, []string{source.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, chunk := range merged.Chunks {
+		for _, seg := range chunk.Dialogue {
+			if seg.Words[0].Speaker != seg.Speaker {
+				t.Errorf("word speaker %q != segment speaker %q", seg.Words[0].Speaker, seg.Speaker)
+			}
+			got = append(got, seg.Speaker)
+		}
+	}
+	want := "Speaker 0,Speaker 1,Speaker 2,Speaker 3," + SelfSpeakerLabel()
+	if strings.Join(got, ",") != want {
+		t.Errorf("speakers = %v, want %s", got, want)
+	}
+}
+
+func TestMergeSessions_DecodedDuration(t *testing.T) {
+	useFakeFFmpeg(t)
+
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TotalDuration сессий больше настоящей длины аудио (1 с)
+	create := func() *Session {
+		t.Helper()
+		sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		sess.TotalDuration = 10 * time.Second
+		w, err := NewShineMP3Writer(filepath.Join(sess.DataDir, "full.mp3"), 16000, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteStereoInterleaved(make([]float32, 16000), make([]float32, 16000)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.AddChunk(sess.ID, &Chunk{ID: sess.ID + "-0", SessionID: sess.ID, EndMs: 1000, Status: ChunkStatusCompleted}); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+	target, source := create(), create()
+	if err := m.SetPunctuatedDialogue(target.ID, []TranscriptSegment{{Start: 0, End: 500, Text: "Да."}}); err != nil {
+		t.Fatal(err)
+	}
+	decodedMs, err := mp3DurationMs(filepath.Join(target.DataDir, "full.mp3"))
+	if err != nil || decodedMs < 1000 || decodedMs > 1500 {
+		t.Fatalf("decoded duration = %d ms, %v", decodedMs, err)
+	}
+
+	merged, err := m.MergeSessions(target.ID, []string{source.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Chunks[1].StartMs != decodedMs || merged.TotalDuration != 2*time.Duration(decodedMs)*time.Millisecond {
+		t.Errorf("second chunk starts at %d, duration %v; want offset %d ms", merged.Chunks[1].StartMs, merged.TotalDuration, decodedMs)
+	}
+	// Диалог с пунктуацией был только у цели - после слияния он сбрасывается
+	if merged.PunctuatedDialogue != nil || fileExists(filepath.Join(target.DataDir, punctuatedDialogueFile)) {
+		t.Errorf("partial punctuated dialogue kept: %+v", merged.PunctuatedDialogue)
+	}
+}
@@ -1,13 +1,17 @@
 package session
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unsafe"
@@ -85,6 +89,141 @@ func getFFmpegPath() string {
 	return ffmpegPath
 }
 
+// ErrFFmpegNotFound FFmpeg не найден или не запускается
+var ErrFFmpegNotFound = errors.New("ffmpeg not found")
+
+// ffmpegCheckTimeout таймаут проверки `ffmpeg -version`
+const ffmpegCheckTimeout = 10 * time.Second
+
+var (
+	ffmpegCheckMu sync.Mutex
+	ffmpegVersion string // Первая строка `ffmpeg -version` после успешной проверки
+)
+
+// SetFFmpegPath задаёт путь к FFmpeg вместо автоматического поиска (пустая строка - автопоиск)
+func SetFFmpegPath(path string) {
+	ffmpegCheckMu.Lock()
+	defer ffmpegCheckMu.Unlock()
+	ffmpegPath = path
+	ffmpegVersion = ""
+	if path != "" {
+		log.Printf("Using FFmpeg from config: %s", path)
+	}
+}
+
+// CheckFFmpeg проверяет FFmpeg запуском `ffmpeg -version` и возвращает его версию.
+// Успешный результат кешируется, неудачный - нет (FFmpeg могут установить без перезапуска).
+// Ошибка оборачивает ErrFFmpegNotFound
+func CheckFFmpeg() (string, error) {
+	ffmpegCheckMu.Lock()
+	defer ffmpegCheckMu.Unlock()
+	if ffmpegVersion != "" {
+		return ffmpegVersion, nil
+	}
+
+	path := getFFmpegPath()
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegCheckTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w at %q: %v (install FFmpeg or set the path with -ffmpeg)", ErrFFmpegNotFound, path, err)
+	}
+
+	version, _, _ := strings.Cut(string(output), "\n")
+	ffmpegVersion = strings.TrimSpace(version)
+	return ffmpegVersion, nil
+}
+
+// RequireFFmpeg возвращает ошибку, если FFmpeg недоступен
+func RequireFFmpeg() error {
+	_, err := CheckFFmpeg()
+	return err
+}
+
+// Диапазон качества VBR кодека LAME (-q:a): 0 - лучшее качество, 9 - минимальный размер.
+// MP3QualityUnset - качество не задано, каждый кодировщик использует свои параметры (MP3Target)
+const (
+	MinMP3Quality   = 0
+	MaxMP3Quality   = 9
+	MP3QualityUnset = -1
+)
+
+var (
+	mp3QualityMu sync.RWMutex
+	mp3Quality   = MP3QualityUnset
+)
+
+// MP3Target назначение кодируемого MP3: определяет параметры, пока качество не задано настройкой
+type MP3Target int
+
+const (
+	MP3TargetRecording MP3Target = iota // Запись, конвертация WAV, склейка и нарезка сессий: CBR 128 кбит/с
+	MP3TargetImport                     // Импортированные файлы: VBR -q:a 2
+	MP3TargetSample                     // Фрагменты голоса спикеров: VBR -q:a 4
+)
+
+// defaultArgs параметры LAME по умолчанию для назначения
+func (t MP3Target) defaultArgs() []string {
+	switch t {
+	case MP3TargetImport:
+		return []string{"-q:a", "2"}
+	case MP3TargetSample:
+		return []string{"-q:a", "4"}
+	default:
+		return []string{"-b:a", "128k"}
+	}
+}
+
+// SetMP3Quality устанавливает качество VBR для всех MP3, которые кодирует FFmpeg.
+// MP3QualityUnset возвращает параметры по умолчанию каждого кодировщика
+func SetMP3Quality(quality int) error {
+	if quality != MP3QualityUnset && (quality < MinMP3Quality || quality > MaxMP3Quality) {
+		return fmt.Errorf("invalid mp3 quality %d: must be between %d (best) and %d (smallest)", quality, MinMP3Quality, MaxMP3Quality)
+	}
+	mp3QualityMu.Lock()
+	mp3Quality = quality
+	mp3QualityMu.Unlock()
+	if quality != MP3QualityUnset {
+		log.Printf("MP3 quality set to: %d", quality)
+	}
+	return nil
+}
+
+// GetMP3Quality возвращает текущее качество VBR для MP3 (MP3QualityUnset - не задано)
+func GetMP3Quality() int {
+	mp3QualityMu.RLock()
+	defer mp3QualityMu.RUnlock()
+	return mp3Quality
+}
+
+// MP3EncodeArgs возвращает аргументы FFmpeg для кодирования MP3: VBR с настроенным качеством,
+// а если оно не задано - прежние параметры назначения target
+func MP3EncodeArgs(target MP3Target) []string {
+	args := []string{"-c:a", "libmp3lame"}
+	if quality := GetMP3Quality(); quality != MP3QualityUnset {
+		return append(args, "-q:a", strconv.Itoa(quality))
+	}
+	return append(args, target.defaultArgs()...)
+}
+
+// mp3WriterArgs аргументы FFmpeg для MP3Writer: читает raw PCM из stdin, пишет MP3 в файл
+func mp3WriterArgs(filePath string, sampleRate, channels int, bitrate string) []string {
+	// Формат входа: signed 16-bit little-endian PCM
+	args := []string{
+		"-y",          // перезаписать файл
+		"-f", "s16le", // формат входа: signed 16-bit little-endian
+		"-ar", fmt.Sprintf("%d", sampleRate), // sample rate
+		"-ac", fmt.Sprintf("%d", channels), // channels
+		"-i", "pipe:0", // читать из stdin
+	}
+	if bitrate != "" {
+		args = append(args, "-c:a", "libmp3lame", "-b:a", bitrate) // фиксированный битрейт
+	} else {
+		args = append(args, MP3EncodeArgs(MP3TargetRecording)...)
+	}
+	return append(args, "-f", "mp3", filePath) // формат выхода
+}
+
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -97,7 +236,7 @@ type MP3Writer struct {
 	filePath   string
 	sampleRate int
 	channels   int
-	bitrate    string // например "128k"
+	bitrate    string // например "128k", пусто - MP3EncodeArgs(MP3TargetRecording)
 
 	samplesWritten int64
 	startTime      time.Time
@@ -105,25 +244,39 @@ type MP3Writer struct {
 	closed         bool
 }
 
+// AudioWriter потоковый писатель аудио сессии (MP3Writer или ShineMP3Writer)
+type AudioWriter interface {
+	Write(samples []float32) error
+	SamplesWritten() int64
+	Duration() time.Duration
+	Close() error
+	FilePath() string
+}
+
+// NewRecordingMP3Writer создаёт MP3 writer для записи сессии: через FFmpeg, а если он
+// недоступен - через встроенный кодировщик shine (чистый Go, фиксированный битрейт 128k).
+// Shine поддерживает только стерео и частоты MPEG (16/22.05/24/32/44.1/48 kHz)
+func NewRecordingMP3Writer(filePath string, sampleRate, channels int) (AudioWriter, error) {
+	err := RequireFFmpeg()
+	if err == nil {
+		return NewMP3Writer(filePath, sampleRate, channels, "")
+	}
+	if channels != 2 {
+		return nil, err
+	}
+	log.Printf("FFmpeg unavailable (%v), recording MP3 with built-in encoder", err)
+	return NewShineMP3Writer(filePath, sampleRate, channels)
+}
+
 // NewMP3Writer создаёт новый MP3 writer через FFmpeg pipe
+// Если bitrate пустой - используются параметры записи (MP3EncodeArgs): CBR 128k
+// или VBR с качеством, заданным SetMP3Quality
 func NewMP3Writer(filePath string, sampleRate, channels int, bitrate string) (*MP3Writer, error) {
-	if bitrate == "" {
-		bitrate = "128k"
+	if err := RequireFFmpeg(); err != nil {
+		return nil, err
 	}
 
-	// FFmpeg команда: читает raw PCM из stdin, пишет MP3 в файл
-	// Формат входа: signed 16-bit little-endian PCM
-	cmd := exec.Command(getFFmpegPath(),
-		"-y",          // перезаписать файл
-		"-f", "s16le", // формат входа: signed 16-bit little-endian
-		"-ar", fmt.Sprintf("%d", sampleRate), // sample rate
-		"-ac", fmt.Sprintf("%d", channels), // channels
-		"-i", "pipe:0", // читать из stdin
-		"-c:a", "libmp3lame", // кодек MP3
-		"-b:a", bitrate, // битрейт
-		"-f", "mp3", // формат выхода
-		filePath,
-	)
+	cmd := exec.Command(getFFmpegPath(), mp3WriterArgs(filePath, sampleRate, channels, bitrate)...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -235,17 +388,20 @@ func ConvertWAVToMP3(wavPath, mp3Path string) error {
 	if !fileExists(wavPath) {
 		return fmt.Errorf("WAV file not found: %s", wavPath)
 	}
+	if err := RequireFFmpeg(); err != nil {
+		return err
+	}
 
 	ffmpegBin := getFFmpegPath()
 	log.Printf("Converting WAV to MP3: ffmpeg=%s, wav=%s, mp3=%s", ffmpegBin, wavPath, mp3Path)
 
-	cmd := exec.Command(ffmpegBin,
+	args := []string{
 		"-y",          // перезаписать
 		"-i", wavPath, // вход
-		"-c:a", "libmp3lame",
-		"-b:a", "128k", // битрейт
-		mp3Path,
-	)
+	}
+	args = append(args, MP3EncodeArgs(MP3TargetRecording)...)
+	args = append(args, mp3Path)
+	cmd := exec.Command(ffmpegBin, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSetMP3Quality(t *testing.T) {
+	defer SetMP3Quality(MP3QualityUnset)
+
+	if got := GetMP3Quality(); got != MP3QualityUnset {
+		t.Errorf("default quality = %d, want unset", got)
+	}
+	for _, q := range []int{-2, 10} {
+		if err := SetMP3Quality(q); err == nil {
+			t.Errorf("SetMP3Quality(%d): expected error", q)
+		}
+	}
+	if got := GetMP3Quality(); got != MP3QualityUnset {
+		t.Errorf("invalid value changed quality to %d", got)
+	}
+	if err := SetMP3Quality(2); err != nil {
+		t.Fatalf("SetMP3Quality(2): %v", err)
+	}
+	if err := SetMP3Quality(MP3QualityUnset); err != nil || GetMP3Quality() != MP3QualityUnset {
+		t.Errorf("reset to unset: err = %v, quality = %d", err, GetMP3Quality())
+	}
+}
+
+func TestMP3EncodeArgs(t *testing.T) {
+	defer SetMP3Quality(MP3QualityUnset)
+
+	// Качество не задано - прежние параметры каждого кодировщика
+	unset := map[MP3Target][]string{
+		MP3TargetRecording: {"-c:a", "libmp3lame", "-b:a", "128k"},
+		MP3TargetImport:    {"-c:a", "libmp3lame", "-q:a", "2"},
+		MP3TargetSample:    {"-c:a", "libmp3lame", "-q:a", "4"},
+	}
+	for target, want := range unset {
+		if got := MP3EncodeArgs(target); !reflect.DeepEqual(got, want) {
+			t.Errorf("unset: MP3EncodeArgs(%d) = %v, want %v", target, got, want)
+		}
+	}
+
+	// Заданное качество применяется ко всем кодировщикам
+	SetMP3Quality(6)
+	for target := range unset {
+		if got, want := MP3EncodeArgs(target), []string{"-c:a", "libmp3lame", "-q:a", "6"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("quality 6: MP3EncodeArgs(%d) = %v, want %v", target, got, want)
+		}
+	}
+}
+
+func TestMP3WriterArgs(t *testing.T) {
+	defer SetMP3Quality(MP3QualityUnset)
+	input := []string{"-y", "-f", "s16le", "-ar", "24000", "-ac", "2", "-i", "pipe:0"}
+	tests := []struct {
+		name    string
+		quality int
+		bitrate string
+		encode  []string
+	}{
+		{"recording default", MP3QualityUnset, "", []string{"-c:a", "libmp3lame", "-b:a", "128k"}},
+		{"configured quality", 3, "", []string{"-c:a", "libmp3lame", "-q:a", "3"}},
+		{"explicit bitrate", 3, "64k", []string{"-c:a", "libmp3lame", "-b:a", "64k"}},
+	}
+	for _, tt := range tests {
+		SetMP3Quality(tt.quality)
+		want := append(append(append([]string{}, input...), tt.encode...), "-f", "mp3", "out.mp3")
+		if got := mp3WriterArgs("out.mp3", 24000, 2, tt.bitrate); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: args = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestCheckFFmpeg(t *testing.T) {
+	defer SetFFmpegPath("")
+
+	SetFFmpegPath(filepath.Join(t.TempDir(), "no-ffmpeg"))
+	if _, err := CheckFFmpeg(); !errors.Is(err, ErrFFmpegNotFound) {
+		t.Fatalf("missing binary: got %v, want ErrFFmpegNotFound", err)
+	}
+	if _, err := NewMP3Writer(filepath.Join(t.TempDir(), "out.mp3"), SampleRate, 2, ""); !errors.Is(err, ErrFFmpegNotFound) {
+		t.Errorf("NewMP3Writer without ffmpeg: got %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	fake := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\necho 'ffmpeg version 6.1-test Copyright'\necho 'built with gcc'\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetFFmpegPath(fake)
+	version, err := CheckFFmpeg()
+	if err != nil {
+		t.Fatalf("CheckFFmpeg: %v", err)
+	}
+	if version != "ffmpeg version 6.1-test Copyright" {
+		t.Errorf("version = %q", version)
+	}
+}
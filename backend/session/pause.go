@@ -0,0 +1,50 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// PauseSession приостанавливает активную сессию: она остаётся активной, а время паузы
+// не входит в длительность записи
+func (m *Manager) PauseSession() (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.activeID == "" {
+		return nil, fmt.Errorf("no active session")
+	}
+	session := m.sessions[m.activeID]
+	if session.Status == SessionStatusPaused {
+		return nil, fmt.Errorf("session already paused")
+	}
+
+	session.Status = SessionStatusPaused
+	session.pausedAt = time.Now()
+	if err := m.SaveSessionMeta(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ResumeSession продолжает приостановленную сессию и добавляет паузу к PausedDuration
+func (m *Manager) ResumeSession() (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.activeID == "" {
+		return nil, fmt.Errorf("no active session")
+	}
+	session := m.sessions[m.activeID]
+	if session.Status != SessionStatusPaused {
+		return nil, fmt.Errorf("session is not paused")
+	}
+
+	session.PausedDuration += time.Since(session.pausedAt)
+	session.Status = SessionStatusRecording
+	session.pausedAt = time.Time{}
+	if err := m.SaveSessionMeta(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
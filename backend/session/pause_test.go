@@ -0,0 +1,72 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseSessionExcludesPausedTime(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ResumeSession(); err == nil {
+		t.Error("resume without active session accepted")
+	}
+	sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ResumeSession(); err == nil {
+		t.Error("resume of recording session accepted")
+	}
+
+	if _, err := m.PauseSession(); err != nil {
+		t.Fatalf("PauseSession: %v", err)
+	}
+	if sess.Status != SessionStatusPaused {
+		t.Errorf("status = %s, want paused", sess.Status)
+	}
+	if _, err := m.PauseSession(); err == nil {
+		t.Error("second pause accepted")
+	}
+
+	// Сдвигаем время: около 1 с записи и 2 с паузы
+	sess.StartTime = sess.StartTime.Add(-3 * time.Second)
+	sess.pausedAt = sess.pausedAt.Add(-2 * time.Second)
+	if _, err := m.ResumeSession(); err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	if sess.Status != SessionStatusRecording || sess.PausedDuration < 2*time.Second {
+		t.Errorf("after resume: status=%s paused=%v", sess.Status, sess.PausedDuration)
+	}
+
+	// Остановка во время паузы тоже её учитывает
+	if _, err := m.PauseSession(); err != nil {
+		t.Fatal(err)
+	}
+	sess.pausedAt = sess.pausedAt.Add(-500 * time.Millisecond)
+	stopped, err := m.StopSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Status != SessionStatusCompleted || stopped.PausedDuration < 2500*time.Millisecond {
+		t.Errorf("after stop: status=%s paused=%v", stopped.Status, stopped.PausedDuration)
+	}
+	if stopped.TotalDuration < 0 || stopped.TotalDuration > time.Second {
+		t.Errorf("total duration = %v, want recorded time without pauses", stopped.TotalDuration)
+	}
+
+	// Длительность пауз сохраняется в meta.json
+	reloaded, err := NewManager(m.dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.PausedDuration != stopped.PausedDuration.Truncate(time.Millisecond) {
+		t.Errorf("reloaded paused duration = %v, want %v", loaded.PausedDuration, stopped.PausedDuration)
+	}
+}
@@ -0,0 +1,214 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProcessingStats статистика скорости распознавания сессии
+type ProcessingStats struct {
+	ChunksMeasured    int     `json:"chunksMeasured"`              // Чанков с известным временем обработки
+	AudioMs           int64   `json:"audioMs"`                     // Суммарная длительность этих чанков
+	ProcessingMs      int64   `json:"processingMs"`                // Суммарное время обработки
+	RealTimeFactor    float64 `json:"realTimeFactor"`              // ProcessingMs / AudioMs (< 1 - быстрее реального времени)
+	SlowestChunkIndex int     `json:"slowestChunkIndex,omitempty"` // Чанк с максимальным RTF
+	SlowestChunkRTF   float64 `json:"slowestChunkRtf,omitempty"`
+
+	VADMethods        map[VADMethod]int `json:"vadMethods,omitempty"`        // Каналов чанков по фактическому методу VAD
+	VADFallbackChunks int               `json:"vadFallbackChunks,omitempty"` // Чанков с откатом Silero -> energy
+
+	ClippedChunks   int     `json:"clippedChunks,omitempty"`   // Чанков с перегруженным входом
+	MaxClippedRatio float64 `json:"maxClippedRatio,omitempty"` // Максимальная доля клиппированных сэмплов в канале
+
+	QualityChunks      int     `json:"qualityChunks,omitempty"`      // Чанков с оценкой качества
+	AverageQuality     float64 `json:"averageQuality,omitempty"`     // Средняя оценка качества (0-100)
+	LowestQuality      int     `json:"lowestQuality,omitempty"`      // Худшая оценка
+	LowestQualityChunk int     `json:"lowestQualityChunk,omitempty"` // Чанк с худшей оценкой
+	LowQualityChunks   []int   `json:"lowQualityChunks,omitempty"`   // Индексы чанков с оценкой ниже LowQualityScore
+
+	TranscriptionPaths map[TranscriptionPath]int `json:"transcriptionPaths,omitempty"` // Чанков по способу распознавания
+	HybridChunks       int                       `json:"hybridChunks,omitempty"`       // Чанков с гибридной транскрипцией
+}
+
+// TranscriptionPath способ распознавания чанка
+type TranscriptionPath string
+
+const (
+	TranscriptionPathPerRegion   TranscriptionPath = "per-region"  // Каждый регион речи VAD отдельно
+	TranscriptionPathCompression TranscriptionPath = "compression" // Регионы речи склеены без пауз
+	TranscriptionPathFull        TranscriptionPath = "full"        // Всё аудио чанка без VAD (моно)
+	TranscriptionPathDiarization TranscriptionPath = "diarization" // Pipeline с диаризацией (моно)
+)
+
+// LowQualityScore оценка качества, ниже которой чанк стоит проверить или перезаписать
+const LowQualityScore = 50
+
+// finishProcessing фиксирует время обработки чанка и его RTF.
+// Вызывается под блокировкой сессии при сохранении результата транскрипции
+func (c *Chunk) finishProcessing(now time.Time) {
+	if c.ProcessingStartTime == nil {
+		return
+	}
+	c.ProcessingTime = now.Sub(*c.ProcessingStartTime).Milliseconds()
+	c.RealTimeFactor = 0
+	if audioMs := c.EndMs - c.StartMs; audioMs > 0 {
+		c.RealTimeFactor = float64(c.ProcessingTime) / float64(audioMs)
+	}
+}
+
+// MarkChunkProcessingStarted запоминает начало обработки чанка в сессии.
+// Чанк, переданный в транскрипцию, может быть копией, поэтому время пишется в сохранённый чанк
+func (m *Manager) MarkChunkProcessingStarted(sessionID, chunkID string, start time.Time) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.ProcessingStartTime = &start
+			return
+		}
+	}
+}
+
+// MarkChunkVADMethods запоминает фактические методы VAD каналов чанка
+// (сохраняются на диск вместе с результатом транскрипции)
+func (m *Manager) MarkChunkVADMethods(sessionID, chunkID string, mic, sys VADMethod, fallback bool) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.MicVADMethod = mic
+			chunk.SysVADMethod = sys
+			chunk.VADFallback = fallback
+			return
+		}
+	}
+}
+
+// MarkChunkModel запоминает модель, которой распознаётся чанк
+// (сохраняется на диск вместе с результатом транскрипции)
+func (m *Manager) MarkChunkModel(sessionID, chunkID, model string) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.Model = model
+			return
+		}
+	}
+}
+
+// MarkChunkTranscriptionPath запоминает способ распознавания чанка и применение гибридной транскрипции
+// (сохраняются на диск вместе с результатом транскрипции)
+func (m *Manager) MarkChunkTranscriptionPath(sessionID, chunkID string, path TranscriptionPath, hybrid bool) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.TranscriptionPath = path
+			chunk.HybridApplied = hybrid
+			return
+		}
+	}
+}
+
+// GetProcessingStats считает RTF сессии по чанкам с известным временем обработки
+func (m *Manager) GetProcessingStats(sessionID string) (*ProcessingStats, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	stats := &ProcessingStats{}
+	for _, chunk := range session.Chunks {
+		for _, method := range []VADMethod{chunk.MicVADMethod, chunk.SysVADMethod} {
+			if method == "" {
+				continue
+			}
+			if stats.VADMethods == nil {
+				stats.VADMethods = make(map[VADMethod]int)
+			}
+			stats.VADMethods[method]++
+		}
+		if chunk.VADFallback {
+			stats.VADFallbackChunks++
+		}
+		if chunk.TranscriptionPath != "" {
+			if stats.TranscriptionPaths == nil {
+				stats.TranscriptionPaths = make(map[TranscriptionPath]int)
+			}
+			stats.TranscriptionPaths[chunk.TranscriptionPath]++
+		}
+		if chunk.HybridApplied {
+			stats.HybridChunks++
+		}
+		if len(chunk.Clipping) > 0 {
+			stats.ClippedChunks++
+			for _, c := range chunk.Clipping {
+				stats.MaxClippedRatio = max(stats.MaxClippedRatio, c.Ratio)
+			}
+		}
+
+		if q := chunk.Quality; q != nil {
+			if stats.QualityChunks == 0 || q.Score < stats.LowestQuality {
+				stats.LowestQuality = q.Score
+				stats.LowestQualityChunk = chunk.Index
+			}
+			stats.QualityChunks++
+			stats.AverageQuality += float64(q.Score)
+			if q.Score < LowQualityScore {
+				stats.LowQualityChunks = append(stats.LowQualityChunks, chunk.Index)
+			}
+		}
+
+		audioMs := chunk.EndMs - chunk.StartMs
+		if chunk.ProcessingTime <= 0 || audioMs <= 0 {
+			continue
+		}
+		stats.ChunksMeasured++
+		stats.AudioMs += audioMs
+		stats.ProcessingMs += chunk.ProcessingTime
+
+		rtf := float64(chunk.ProcessingTime) / float64(audioMs)
+		if rtf > stats.SlowestChunkRTF {
+			stats.SlowestChunkRTF = rtf
+			stats.SlowestChunkIndex = chunk.Index
+		}
+	}
+	if stats.AudioMs > 0 {
+		stats.RealTimeFactor = float64(stats.ProcessingMs) / float64(stats.AudioMs)
+	}
+	if stats.QualityChunks > 0 {
+		stats.AverageQuality /= float64(stats.QualityChunks)
+	}
+	return stats, nil
+}
+
+// String краткое описание для логов
+func (st *ProcessingStats) String() string {
+	return fmt.Sprintf("%d chunks, audio=%.1fs, processing=%.1fs, RTF=%.2f (slowest chunk %d: %.2f), VAD=%v (fallbacks: %d), clipped chunks=%d",
+		st.ChunksMeasured, float64(st.AudioMs)/1000, float64(st.ProcessingMs)/1000,
+		st.RealTimeFactor, st.SlowestChunkIndex, st.SlowestChunkRTF, st.VADMethods, st.VADFallbackChunks, st.ClippedChunks)
+}
@@ -0,0 +1,181 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcessingStats(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Чанк 0: 10с аудио за 2с, чанк 1: 10с за 6с, чанк 2 не обработан
+	for i, processing := range []time.Duration{2 * time.Second, 6 * time.Second, 0} {
+		chunk := &Chunk{ID: sess.ID + "-" + string(rune('0'+i)), SessionID: sess.ID, Index: i,
+			StartMs: int64(i) * 10000, EndMs: int64(i+1) * 10000}
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+		if processing == 0 {
+			continue
+		}
+		// Время начала пишется в сохранённый чанк, даже если у вызывающего копия
+		m.MarkChunkProcessingStarted(sess.ID, chunk.ID, time.Now().Add(-processing))
+		if err := m.UpdateChunkTranscription(sess.ID, chunk.ID, "текст", nil); err != nil {
+			t.Fatal(err)
+		}
+		if chunk.ProcessingTime < processing.Milliseconds() || chunk.RealTimeFactor < processing.Seconds()/10 {
+			t.Errorf("chunk %d: processing=%dms rtf=%.2f", i, chunk.ProcessingTime, chunk.RealTimeFactor)
+		}
+	}
+
+	stats, err := m.GetProcessingStats(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ChunksMeasured != 2 || stats.AudioMs != 20000 || stats.SlowestChunkIndex != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if stats.RealTimeFactor < 0.4 || stats.RealTimeFactor > 0.45 {
+		t.Errorf("session RTF = %.3f, want ~0.4", stats.RealTimeFactor)
+	}
+}
+
+func TestProcessingStatsVADMethods(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		chunk := &Chunk{ID: sess.ID + "-" + string(rune('0'+i)), SessionID: sess.ID, Index: i}
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.MarkChunkVADMethods(sess.ID, sess.ID+"-0", VADMethodSilero, VADMethodSilero, false)
+	m.MarkChunkVADMethods(sess.ID, sess.ID+"-1", VADMethodEnergy, VADMethodSilero, true)
+
+	stats, err := m.GetProcessingStats(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.VADMethods[VADMethodSilero] != 3 || stats.VADMethods[VADMethodEnergy] != 1 || stats.VADFallbackChunks != 1 {
+		t.Errorf("unexpected VAD stats: methods=%v fallbacks=%d", stats.VADMethods, stats.VADFallbackChunks)
+	}
+}
+
+func TestChunkTranscriptionPathPersisted(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []TranscriptionPath{TranscriptionPathPerRegion, TranscriptionPathDiarization, TranscriptionPathPerRegion}
+	for i, path := range paths {
+		chunk := &Chunk{ID: sess.ID + "-" + string(rune('0'+i)), SessionID: sess.ID, Index: i}
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+		m.MarkChunkTranscriptionPath(sess.ID, chunk.ID, path, i == 1)
+		if err := m.UpdateChunkTranscription(sess.ID, chunk.ID, "текст", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := m.GetProcessingStats(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TranscriptionPaths[TranscriptionPathPerRegion] != 2 || stats.TranscriptionPaths[TranscriptionPathDiarization] != 1 || stats.HybridChunks != 1 {
+		t.Errorf("unexpected path stats: paths=%v hybrid=%d", stats.TranscriptionPaths, stats.HybridChunks)
+	}
+
+	// Способ распознавания сохраняется в файле чанка
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk := loaded.Chunks[1]; chunk.TranscriptionPath != TranscriptionPathDiarization || !chunk.HybridApplied {
+		t.Errorf("reloaded chunk: path=%q hybrid=%v", chunk.TranscriptionPath, chunk.HybridApplied)
+	}
+}
+
+func TestDetectSpeechRegionsForChannelReportsFallback(t *testing.T) {
+	if _, err := GetGlobalSileroVAD(); err == nil {
+		t.Skip("Silero VAD model is available, fallback is not exercised")
+	}
+	samples := make([]float32, 16000)
+	for _, method := range []VADMethod{VADMethodSilero, VADMethodEnergy} {
+		if _, got := DetectSpeechRegionsForChannel(samples, 16000, ChannelVADConfig{Method: method}); got != VADMethodEnergy {
+			t.Errorf("%s without Silero model: used %q, want energy", method, got)
+		}
+	}
+}
+
+func TestSileroModelPathMissingFallsBackToEnergy(t *testing.T) {
+	SetSileroModelPath(filepath.Join(t.TempDir(), "missing.onnx"))
+	t.Cleanup(func() { SetSileroModelPath("") })
+
+	if _, err := GetGlobalSileroVAD(); err == nil {
+		t.Fatal("Silero VAD initialized from a missing model")
+	}
+	samples := make([]float32, 16000)
+	for _, method := range []VADMethod{VADMethodSilero, VADMethodAuto} {
+		if _, got := DetectSpeechRegionsWithMethod(samples, 16000, method); got != VADMethodEnergy {
+			t.Errorf("%s with missing model: used %q, want energy", method, got)
+		}
+	}
+}
+
+func TestUpdateChunkSysSpeakersKeepsProcessingMetrics(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	mic := []TranscriptSegment{{Start: 0, End: 1000, Text: "привет", Speaker: SelfSpeakerLabel()}}
+	sys := []TranscriptSegment{{Start: 1500, End: 2500, Text: "здравствуйте", Speaker: SpeakerLabel(0)}}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Status: ChunkStatusCompleted, EndMs: 5000,
+		MicSegments: mic, SysSegments: sys, ProcessingTime: 1200, ProcessingStartTime: &start}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	resegmented := []TranscriptSegment{{Start: 1500, End: 2500, Text: "здравствуйте", Speaker: SpeakerLabel(1)}}
+	if err := m.UpdateChunkSysSpeakers(sess.ID, "c0", resegmented); err != nil {
+		t.Fatal(err)
+	}
+	if chunk.ProcessingTime != 1200 || chunk.Status != ChunkStatusCompleted {
+		t.Errorf("processing metrics changed: time=%d status=%s", chunk.ProcessingTime, chunk.Status)
+	}
+	if len(chunk.Dialogue) != 2 || chunk.Dialogue[1].Speaker != SpeakerLabel(1) {
+		t.Errorf("dialogue is not rebuilt: %+v", chunk.Dialogue)
+	}
+	if err := m.UpdateChunkSysSpeakers(sess.ID, "missing", resegmented); err == nil {
+		t.Error("expected error for missing chunk")
+	}
+}
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+// QualityWeights веса сигналов в оценке качества чанка.
+//
+// Оценка (0-100) - взвешенное среднее доступных сигналов, каждый в диапазоне 0-1:
+//
+//	score = 100 * (Wc*confidence + Ws*speechRatio + Wk*noClipping) / (сумма весов доступных сигналов)
+//
+// confidence - средняя вероятность P слов (нет слов с P - сигнал недоступен),
+// speechRatio - доля аудио чанка, где VAD нашёл речь (VAD не запускался - недоступен),
+// noClipping - 0, если вход чанка перегружен (Chunk.Clipping), иначе 1 (доступен всегда).
+// Недоступный сигнал не тянет оценку вниз: его вес просто не учитывается
+type QualityWeights struct {
+	Confidence  float64 `json:"confidence"`
+	SpeechRatio float64 `json:"speechRatio"`
+	Clipping    float64 `json:"clipping"`
+}
+
+// DefaultQualityWeights возвращает веса по умолчанию: уверенность модели важнее всего
+func DefaultQualityWeights() QualityWeights {
+	return QualityWeights{Confidence: 0.6, SpeechRatio: 0.25, Clipping: 0.15}
+}
+
+// Validate проверяет веса
+func (w QualityWeights) Validate() error {
+	for _, v := range []float64{w.Confidence, w.SpeechRatio, w.Clipping} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid quality weights %+v: must be finite and non-negative", w)
+		}
+	}
+	if w.Confidence+w.SpeechRatio+w.Clipping == 0 {
+		return fmt.Errorf("invalid quality weights: at least one weight must be positive")
+	}
+	return nil
+}
+
+var (
+	qualityWeightsMu sync.RWMutex
+	qualityWeights   = DefaultQualityWeights()
+)
+
+// SetQualityWeights устанавливает веса оценки качества для новых транскрипций
+func SetQualityWeights(w QualityWeights) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	qualityWeightsMu.Lock()
+	qualityWeights = w
+	qualityWeightsMu.Unlock()
+	log.Printf("Chunk quality weights set to: confidence=%.2f, speech=%.2f, clipping=%.2f", w.Confidence, w.SpeechRatio, w.Clipping)
+	return nil
+}
+
+// GetQualityWeights возвращает текущие веса оценки качества
+func GetQualityWeights() QualityWeights {
+	qualityWeightsMu.RLock()
+	defer qualityWeightsMu.RUnlock()
+	return qualityWeights
+}
+
+// ChunkQuality оценка качества распознанного чанка и сигналы, из которых она сложена
+type ChunkQuality struct {
+	Score       int      `json:"score"`                 // 0-100
+	Confidence  *float64 `json:"confidence,omitempty"`  // Средняя вероятность слов (нет - модель не даёт P)
+	SpeechRatio *float64 `json:"speechRatio,omitempty"` // Доля речи по VAD (нет - VAD не запускался)
+	Clipped     bool     `json:"clipped,omitempty"`
+}
+
+// SpeechCoverage доля durationMs, покрытая регионами речи хотя бы одного канала
+func SpeechCoverage(durationMs int64, channels ...[]SpeechRegion) float64 {
+	if durationMs <= 0 {
+		return 0
+	}
+	var regions []SpeechRegion
+	for _, channel := range channels {
+		regions = append(regions, channel...)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].StartMs < regions[j].StartMs })
+
+	var covered, end int64
+	for _, r := range regions {
+		start := max(r.StartMs, end, 0)
+		stop := min(r.EndMs, durationMs)
+		if stop > start {
+			covered += stop - start
+		}
+		end = max(end, r.EndMs)
+	}
+	return math.Min(1, float64(covered)/float64(durationMs))
+}
+
+// MarkChunkSpeechRatio запоминает долю речи по VAD в аудио чанка
+// (учитывается в оценке качества при сохранении результата транскрипции)
+func (m *Manager) MarkChunkSpeechRatio(sessionID, chunkID string, ratio float64) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, chunk := range session.Chunks {
+		if chunk.ID == chunkID {
+			chunk.SpeechRatio = &ratio
+			return
+		}
+	}
+}
+
+// updateQuality пересчитывает оценку качества чанка по текущим весам.
+// Вызывается под блокировкой сессии при сохранении результата транскрипции
+func (c *Chunk) updateQuality() {
+	if c.Status != ChunkStatusCompleted {
+		c.Quality = nil
+		return
+	}
+	c.Quality = computeChunkQuality(c, GetQualityWeights())
+}
+
+// computeChunkQuality складывает оценку качества чанка из доступных сигналов
+func computeChunkQuality(c *Chunk, w QualityWeights) *ChunkQuality {
+	quality := &ChunkQuality{Clipped: len(c.Clipping) > 0}
+
+	var sum, weights float64
+	if confidence, ok := chunkWordConfidence(c); ok {
+		quality.Confidence = &confidence
+		sum += w.Confidence * confidence
+		weights += w.Confidence
+	}
+	if c.SpeechRatio != nil {
+		ratio := *c.SpeechRatio
+		quality.SpeechRatio = &ratio
+		sum += w.SpeechRatio * ratio
+		weights += w.SpeechRatio
+	}
+	if !quality.Clipped {
+		sum += w.Clipping
+	}
+	weights += w.Clipping
+
+	// Все доступные сигналы с нулевым весом - оценивать нечего, претензий к чанку нет
+	quality.Score = 100
+	if weights > 0 {
+		quality.Score = int(math.Round(100 * sum / weights))
+	}
+	return quality
+}
+
+// NoConfidence значение TranscriptSegment.Confidence, если у слов сегмента нет вероятностей
+const NoConfidence float32 = -1
+
+// SegmentConfidence средняя вероятность слов сегмента (слова без P не учитываются),
+// NoConfidence - если вероятностей нет
+func SegmentConfidence(words []TranscriptWord) float32 {
+	var sum float64
+	count := 0
+	for _, word := range words {
+		if word.P > 0 {
+			sum += float64(word.P)
+			count++
+		}
+	}
+	if count == 0 {
+		return NoConfidence
+	}
+	return float32(sum / float64(count))
+}
+
+// chunkWordConfidence средняя вероятность слов чанка (слова без P не учитываются)
+func chunkWordConfidence(c *Chunk) (float64, bool) {
+	segments := c.Dialogue
+	if len(c.MicSegments) > 0 || len(c.SysSegments) > 0 {
+		segments = append(append([]TranscriptSegment{}, c.MicSegments...), c.SysSegments...)
+	}
+
+	var sum float64
+	count := 0
+	for _, seg := range segments {
+		for _, word := range seg.Words {
+			if word.P > 0 {
+				sum += float64(word.P)
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
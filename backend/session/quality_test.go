@@ -0,0 +1,121 @@
+package session
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpeechCoverage(t *testing.T) {
+	mic := []SpeechRegion{{StartMs: 0, EndMs: 2000}, {StartMs: 5000, EndMs: 6000}}
+	sys := []SpeechRegion{{StartMs: 1000, EndMs: 3000}, {StartMs: 9000, EndMs: 12000}}
+	// Объединение: 0-3000, 5000-6000, 9000-10000 (обрезано по длительности)
+	if got := SpeechCoverage(10000, mic, sys); got != 0.5 {
+		t.Errorf("coverage = %.3f, want 0.5", got)
+	}
+	if got := SpeechCoverage(10000); got != 0 {
+		t.Errorf("coverage without regions = %.3f", got)
+	}
+	if got := SpeechCoverage(0, mic); got != 0 {
+		t.Errorf("coverage of empty audio = %.3f", got)
+	}
+}
+
+func TestChunkQuality(t *testing.T) {
+	words := func(p ...float32) []TranscriptSegment {
+		seg := TranscriptSegment{Text: "текст"}
+		for _, v := range p {
+			seg.Words = append(seg.Words, TranscriptWord{Text: "слово", P: v})
+		}
+		return []TranscriptSegment{seg}
+	}
+	ratio := func(v float64) *float64 { return &v }
+	w := QualityWeights{Confidence: 0.5, SpeechRatio: 0.3, Clipping: 0.2}
+
+	tests := []struct {
+		name  string
+		chunk Chunk
+		want  int
+	}{
+		{"all signals", Chunk{MicSegments: words(0.9, 0.7), SpeechRatio: ratio(0.5)}, 75}, // 0.5*0.8 + 0.3*0.5 + 0.2
+		{"clipped", Chunk{MicSegments: words(0.8), SpeechRatio: ratio(0.5), Clipping: []ChannelClipping{{Channel: "mic"}}}, 55},
+		{"no confidence", Chunk{Dialogue: words(0, 0), SpeechRatio: ratio(0.5)}, 70}, // (0.3*0.5 + 0.2) / 0.5
+		{"no vad", Chunk{Dialogue: words(0.6)}, 71},                                  // (0.5*0.6 + 0.2) / 0.7
+		{"nothing but clipping", Chunk{Clipping: []ChannelClipping{{Channel: "mono"}}}, 0},
+	}
+	for _, tt := range tests {
+		if got := computeChunkQuality(&tt.chunk, w); got.Score != tt.want {
+			t.Errorf("%s: score = %d, want %d", tt.name, got.Score, tt.want)
+		}
+	}
+
+	for _, bad := range []QualityWeights{{Confidence: -1, Clipping: 1}, {}} {
+		if err := SetQualityWeights(bad); err == nil {
+			t.Errorf("weights %+v accepted", bad)
+		}
+	}
+}
+
+func TestChunkQualityStored(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := m.AddChunk(sess.ID, &Chunk{ID: sess.ID + "-" + string(rune('0'+i)), SessionID: sess.ID, Index: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	good := []TranscriptSegment{{Text: "привет", Words: []TranscriptWord{{Text: "привет", P: 0.95}}}}
+	bad := []TranscriptSegment{{Text: "шум", Words: []TranscriptWord{{Text: "шум", P: 0.1}}}}
+	m.MarkChunkSpeechRatio(sess.ID, sess.ID+"-0", 0.9)
+	m.MarkChunkSpeechRatio(sess.ID, sess.ID+"-1", 0.1)
+	m.MarkChunkClipping(sess.ID, sess.ID+"-1", []ChannelClipping{{Channel: "mic", Ratio: 0.05}})
+	if err := m.UpdateChunkStereoWithSegments(sess.ID, sess.ID+"-0", "привет", "", good, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateChunkStereoWithSegments(sess.ID, sess.ID+"-1", "шум", "", bad, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := m.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := stored.Chunks[0].Quality, stored.Chunks[1].Quality
+	if first == nil || second == nil || first.Score <= LowQualityScore || second.Score >= LowQualityScore || !second.Clipped {
+		t.Fatalf("chunk quality: %+v, %+v", first, second)
+	}
+	if stored.Chunks[2].Quality != nil {
+		t.Errorf("quality of an untranscribed chunk: %+v", stored.Chunks[2].Quality)
+	}
+
+	stats, err := m.GetProcessingStats(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.QualityChunks != 2 || stats.LowestQualityChunk != 1 || stats.LowestQuality != second.Score ||
+		len(stats.LowQualityChunks) != 1 || stats.LowQualityChunks[0] != 1 {
+		t.Errorf("quality stats: %+v", stats)
+	}
+	if want := float64(first.Score+second.Score) / 2; stats.AverageQuality != want {
+		t.Errorf("average quality = %.1f, want %.1f", stats.AverageQuality, want)
+	}
+}
+
+func TestSegmentConfidence(t *testing.T) {
+	words := []TranscriptWord{{Text: "раз", P: 0.9}, {Text: "два", P: 0}, {Text: "три", P: 0.5}}
+	if got := SegmentConfidence(words); math.Abs(float64(got)-0.7) > 1e-6 {
+		t.Errorf("SegmentConfidence = %v, want 0.7 (words without P are skipped)", got)
+	}
+	if got := SegmentConfidence(words[1:2]); got != NoConfidence {
+		t.Errorf("without probabilities = %v, want NoConfidence", got)
+	}
+	if got := SegmentConfidence(nil); got != NoConfidence {
+		t.Errorf("without words = %v, want NoConfidence", got)
+	}
+}
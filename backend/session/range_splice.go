@@ -0,0 +1,180 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExpandRangeToSegments расширяет диапазон [startMs, endMs] до границ сегментов,
+// которые его пересекают. Так при замене не теряется текст частично попавших сегментов
+func ExpandRangeToSegments(chunks []*Chunk, startMs, endMs int64) (int64, int64) {
+	expand := func(segs []TranscriptSegment) {
+		for _, seg := range segs {
+			if !segmentOverlapsRange(seg, startMs, endMs) {
+				continue
+			}
+			if seg.Start < startMs {
+				startMs = seg.Start
+			}
+			if seg.End > endMs {
+				endMs = seg.End
+			}
+		}
+	}
+
+	for _, chunk := range chunks {
+		if chunk.EndMs <= startMs || chunk.StartMs >= endMs {
+			continue
+		}
+		expand(chunk.MicSegments)
+		expand(chunk.SysSegments)
+		expand(chunk.Dialogue)
+	}
+	return startMs, endMs
+}
+
+// segmentOverlapsRange проверяет пересечение сегмента с диапазоном
+func segmentOverlapsRange(seg TranscriptSegment, startMs, endMs int64) bool {
+	return seg.End > startMs && seg.Start < endMs
+}
+
+// spliceSegments заменяет сегменты, пересекающие [startMs, endMs], новыми.
+// Новые сегменты наследуют метку спикера у заменённых сегментов с наибольшим перекрытием
+func spliceSegments(existing, replacement []TranscriptSegment, startMs, endMs int64) []TranscriptSegment {
+	var kept, replaced []TranscriptSegment
+	for _, seg := range existing {
+		if segmentOverlapsRange(seg, startMs, endMs) {
+			replaced = append(replaced, seg)
+		} else {
+			kept = append(kept, seg)
+		}
+	}
+
+	result := append(kept, inheritSpeakers(replacement, replaced)...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
+
+// inheritSpeakers переносит метки спикеров (включая переименования) со старых сегментов на новые
+func inheritSpeakers(segments, previous []TranscriptSegment) []TranscriptSegment {
+	result := make([]TranscriptSegment, len(segments))
+	for i, seg := range segments {
+		var bestOverlap int64
+		speaker := ""
+		for _, prev := range previous {
+			overlap := min(seg.End, prev.End) - max(seg.Start, prev.Start)
+			if overlap > bestOverlap {
+				bestOverlap = overlap
+				speaker = prev.Speaker
+			}
+		}
+
+		if speaker != "" && speaker != seg.Speaker {
+			seg.Speaker = speaker
+			words := make([]TranscriptWord, len(seg.Words))
+			for j, w := range seg.Words {
+				w.Speaker = speaker
+				words[j] = w
+			}
+			seg.Words = words
+		}
+		result[i] = seg
+	}
+	return result
+}
+
+// segmentsInChunk отбирает сегменты, начало которых попадает в чанк
+func segmentsInChunk(segments []TranscriptSegment, chunk *Chunk, isLast bool) []TranscriptSegment {
+	var result []TranscriptSegment
+	for _, seg := range segments {
+		if seg.Start >= chunk.StartMs && (seg.Start < chunk.EndMs || isLast) {
+			result = append(result, seg)
+		}
+	}
+	return result
+}
+
+// JoinSegmentsText собирает текст сегментов через пробел
+func JoinSegmentsText(segments []TranscriptSegment) string {
+	texts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		texts = append(texts, seg.Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// ReplaceSegmentsInRange заменяет сегменты в диапазоне [startMs, endMs] результатом
+// повторной транскрипции этого диапазона. Сегменты вне диапазона и метки спикеров сохраняются.
+// Для стерео чанков заменяются MIC и SYS сегменты с пересборкой диалога,
+// для моно чанков (только Dialogue) - сегменты диалога из micSegments и sysSegments.
+// Новый результат попадает в слой "raw", остальные слои диалога чанков помечаются устаревшими.
+// Возвращает изменённые чанки
+func (m *Manager) ReplaceSegmentsInRange(sessionID string, startMs, endMs int64, micSegments, sysSegments []TranscriptSegment) ([]*Chunk, error) {
+	var updated []*Chunk
+
+	err := func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		session, ok := m.sessions[sessionID]
+		if !ok {
+			return fmt.Errorf("session not found: %s", sessionID)
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+
+		for i, chunk := range session.Chunks {
+			if chunk.EndMs <= startMs || chunk.StartMs >= endMs {
+				continue
+			}
+			isLast := i == len(session.Chunks)-1
+			chunkMic := segmentsInChunk(micSegments, chunk, isLast)
+			chunkSys := segmentsInChunk(sysSegments, chunk, isLast)
+
+			if len(chunk.MicSegments) > 0 || len(chunk.SysSegments) > 0 {
+				chunk.MicSegments = spliceSegments(chunk.MicSegments, chunkMic, startMs, endMs)
+				chunk.SysSegments = spliceSegments(chunk.SysSegments, chunkSys, startMs, endMs)
+				chunk.MicText = JoinSegmentsText(chunk.MicSegments)
+				chunk.SysText = JoinSegmentsText(chunk.SysSegments)
+				chunk.Dialogue = mergeSegmentsToDialogue(chunk.MicSegments, chunk.SysSegments)
+			} else {
+				// Заменяем в исходном результате ASR: показанный диалог может быть улучшенным слоем
+				raw := session.dialogueLayerChunkLocked(DialogueLayerRaw, chunk)
+				chunk.Dialogue = spliceSegments(raw, append(chunkMic, chunkSys...), startMs, endMs)
+			}
+			chunk.Transcription = formatDialogue(chunk.Dialogue)
+			chunk.Status = ChunkStatusCompleted
+			chunk.Error = ""
+
+			// Сохраняем метаданные чанка
+			chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+			data, _ := json.MarshalIndent(chunk, "", "  ")
+			os.WriteFile(chunkMetaPath, data, 0644)
+			session.markChunkDialogueLayersStaleLocked(chunk)
+
+			updated = append(updated, chunk)
+		}
+		return nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("ReplaceSegmentsInRange: session %s, range %d-%dms, updated %d chunks", sessionID, startMs, endMs, len(updated))
+
+	// Callback ВЫЗЫВАЕТСЯ ВНЕ БЛОКИРОВКИ чтобы избежать дедлока
+	if m.onChunkTranscribed != nil {
+		for _, chunk := range updated {
+			m.onChunkTranscribed(chunk)
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,55 @@
+package session
+
+import "testing"
+
+func TestExpandRangeToSegments(t *testing.T) {
+	chunks := []*Chunk{{
+		StartMs: 0,
+		EndMs:   30000,
+		MicSegments: []TranscriptSegment{
+			{Start: 1000, End: 4000, Text: "a"},
+			{Start: 9000, End: 12000, Text: "b"},
+		},
+		SysSegments: []TranscriptSegment{
+			{Start: 5000, End: 7000, Text: "c"},
+		},
+	}}
+
+	start, end := ExpandRangeToSegments(chunks, 3000, 10000)
+	if start != 1000 || end != 12000 {
+		t.Errorf("ExpandRangeToSegments = %d-%d, want 1000-12000", start, end)
+	}
+
+	start, end = ExpandRangeToSegments(chunks, 4500, 4800)
+	if start != 4500 || end != 4800 {
+		t.Errorf("range without segments changed to %d-%d", start, end)
+	}
+}
+
+func TestSpliceSegments_PreservesSurroundingAndSpeakers(t *testing.T) {
+	existing := []TranscriptSegment{
+		{Start: 0, End: 2000, Text: "до", Speaker: "Собеседник 1"},
+		{Start: 3000, End: 5000, Text: "плохо", Speaker: "Иван", Words: []TranscriptWord{{Start: 3000, End: 5000, Text: "плохо", Speaker: "Иван"}}},
+		{Start: 6000, End: 8000, Text: "после", Speaker: "Собеседник 2"},
+	}
+	replacement := []TranscriptSegment{
+		{Start: 3100, End: 4900, Text: "хорошо", Speaker: "Собеседник", Words: []TranscriptWord{{Start: 3100, End: 4900, Text: "хорошо", Speaker: "Собеседник"}}},
+	}
+
+	got := spliceSegments(existing, replacement, 3000, 5000)
+	if len(got) != 3 {
+		t.Fatalf("got %d segments, want 3: %+v", len(got), got)
+	}
+	if got[0].Text != "до" || got[2].Text != "после" {
+		t.Errorf("surrounding segments changed: %+v", got)
+	}
+	if got[1].Text != "хорошо" || got[1].Speaker != "Иван" {
+		t.Errorf("replacement = %+v, want text 'хорошо' with inherited speaker 'Иван'", got[1])
+	}
+	if got[1].Words[0].Speaker != "Иван" {
+		t.Errorf("word speaker = %q, want 'Иван'", got[1].Words[0].Speaker)
+	}
+	if replacement[0].Speaker != "Собеседник" || replacement[0].Words[0].Speaker != "Собеседник" {
+		t.Error("spliceSegments must not modify the replacement slice")
+	}
+}
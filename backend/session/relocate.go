@@ -0,0 +1,246 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// relocatedIndexFile индекс сессий, перенесённых из dataDir (ключ: ID сессии, значение: директория)
+const relocatedIndexFile = "relocated.json"
+
+// MoveSession переносит файлы сессии (аудио, чанки, метаданные) в targetDir/<id>, например на внешний диск.
+// Сессия остаётся в приложении: новое расположение запоминается в индексе dataDir.
+// Перенос обратно в dataDir убирает сессию из индекса
+func (m *Manager) MoveSession(sessionID, targetDir string) (*Session, error) {
+	if targetDir == "" || !filepath.IsAbs(targetDir) {
+		return nil, fmt.Errorf("target directory must be an absolute path: %q", targetDir)
+	}
+
+	m.mu.Lock()
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if m.activeID == sessionID {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("cannot move active session")
+	}
+	// Ретранскрипция, слияние или разделение пишут в директорию сессии - переносить нельзя,
+	// а пока идёт перенос, новые задачи над сессией не запускаются
+	release, err := m.TryMarkSessionBusy(sessionID, true)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	session.mu.RLock()
+	oldDir := session.DataDir
+	session.mu.RUnlock()
+
+	newDir := filepath.Join(filepath.Clean(targetDir), sessionID)
+	if newDir == filepath.Clean(oldDir) {
+		return session, nil
+	}
+	if _, err := os.Stat(newDir); err == nil {
+		return nil, fmt.Errorf("target already exists: %s", newDir)
+	}
+
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create target dir: %w", err)
+	}
+	if err := checkDirWritable(targetDir); err != nil {
+		return nil, err
+	}
+	size, err := dirSize(oldDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure session size: %w", err)
+	}
+	if free, ok := freeDiskSpace(targetDir); ok && free < size {
+		return nil, fmt.Errorf("not enough space in %s: need %d MB, available %d MB", targetDir, size>>20, free>>20)
+	}
+
+	// В пределах одного диска - rename под блокировкой сессии, он мгновенный
+	session.mu.Lock()
+	renamed := os.Rename(oldDir, newDir) == nil
+	var rewritten []*Chunk
+	if renamed {
+		session.DataDir = newDir
+		rewritten = rewriteChunkPaths(session.Chunks, oldDir, newDir)
+	}
+	session.mu.Unlock()
+
+	if !renamed {
+		// Копирование идёт без блокировок: сессия отмечена занятой (TryMarkSessionBusy),
+		// задачи над ней не запускаются, а чтение идёт из прежнего места, пока путь не заменён
+		if err := copyDir(oldDir, newDir); err != nil {
+			os.RemoveAll(newDir)
+			return nil, fmt.Errorf("failed to copy session files: %w", err)
+		}
+		session.mu.Lock()
+		session.DataDir = newDir
+		rewritten = rewriteChunkPaths(session.Chunks, oldDir, newDir)
+		session.mu.Unlock()
+
+		if err := os.RemoveAll(oldDir); err != nil {
+			// Копия уже полная - сессия работает из нового места
+			log.Printf("MoveSession: failed to remove old files %s: %v", oldDir, err)
+		}
+	}
+
+	for _, chunk := range rewritten {
+		chunkMetaPath := filepath.Join(newDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+		data, err := json.MarshalIndent(chunk, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(chunkMetaPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to update chunk %d: %w", chunk.Index, err)
+		}
+	}
+	if err := m.SaveSessionMeta(session); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index := m.loadRelocatedIndex()
+	if filepath.Clean(targetDir) == filepath.Clean(m.dataDir) {
+		delete(index, sessionID)
+	} else {
+		index[sessionID] = newDir
+	}
+	if err := m.saveRelocatedIndex(index); err != nil {
+		return nil, fmt.Errorf("session moved to %s, but failed to update index: %w", newDir, err)
+	}
+
+	log.Printf("MoveSession: session %s moved %s -> %s (%d MB)", sessionID, oldDir, newDir, size>>20)
+	return session, nil
+}
+
+// loadRelocatedIndex читает индекс перенесённых сессий (пустой, если его нет)
+func (m *Manager) loadRelocatedIndex() map[string]string {
+	index := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(m.dataDir, relocatedIndexFile))
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		log.Printf("Failed to parse %s: %v", relocatedIndexFile, err)
+	}
+	return index
+}
+
+// saveRelocatedIndex сохраняет индекс перенесённых сессий
+func (m *Manager) saveRelocatedIndex(index map[string]string) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dataDir, relocatedIndexFile), data, 0644)
+}
+
+// rewriteChunkPaths заменяет абсолютные пути к файлам чанков из oldDir на newDir.
+// Возвращает изменённые чанки
+func rewriteChunkPaths(chunks []*Chunk, oldDir, newDir string) []*Chunk {
+	rewrite := func(path *string) bool {
+		rel, err := filepath.Rel(oldDir, *path)
+		if *path == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		*path = filepath.Join(newDir, rel)
+		return true
+	}
+
+	var changed []*Chunk
+	for _, chunk := range chunks {
+		a := rewrite(&chunk.FilePath)
+		b := rewrite(&chunk.MicFilePath)
+		c := rewrite(&chunk.SysFilePath)
+		if a || b || c {
+			changed = append(changed, chunk)
+		}
+	}
+	return changed
+}
+
+// checkDirWritable проверяет запись в директорию пробным файлом
+func checkDirWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".aiwisper-write-test-*")
+	if err != nil {
+		return fmt.Errorf("target directory is not writable: %w", err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// dirSize суммарный размер файлов в директории
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// copyDir рекурсивно копирует директорию
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile копирует файл с синхронизацией на диск
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
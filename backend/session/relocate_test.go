@@ -0,0 +1,153 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveSession(t *testing.T) {
+	dataDir := t.TempDir()
+	m, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateImportSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDir := sess.DataDir
+	if err := os.WriteFile(filepath.Join(oldDir, "full.mp3"), []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, FilePath: filepath.Join(oldDir, "chunks", "000.wav")}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.MoveSession(sess.ID, "relative/dir"); err == nil {
+		t.Error("relative target directory should be rejected")
+	}
+
+	archive := t.TempDir()
+
+	// Пока над сессией идёт задача, перенос запрещён
+	release := m.MarkSessionBusy(sess.ID)
+	if _, err := m.MoveSession(sess.ID, archive); err == nil {
+		t.Fatal("busy session should not be moved")
+	}
+	release()
+	if sess.DataDir != oldDir {
+		t.Fatalf("rejected move changed DataDir to %s", sess.DataDir)
+	}
+
+	// Задачи обработки идут одновременно, но не во время переноса (exclusive)
+	releaseTask, err := m.TryMarkSessionBusy(sess.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.TryMarkSessionBusy(sess.ID, true); err == nil {
+		t.Error("exclusive task started while the session is processed")
+	}
+	releaseNested, err := m.TryMarkSessionBusy(sess.ID, false)
+	if err != nil {
+		t.Errorf("concurrent processing task rejected: %v", err)
+	} else {
+		releaseNested()
+	}
+	releaseTask()
+	releaseMove, err := m.TryMarkSessionBusy(sess.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.TryMarkSessionBusy(sess.ID, false); err == nil {
+		t.Error("processing task started while the session is moved")
+	}
+	releaseMove()
+
+	moved, err := m.MoveSession(sess.ID, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDir := filepath.Join(archive, sess.ID)
+	if moved.DataDir != newDir {
+		t.Errorf("DataDir = %s, want %s", moved.DataDir, newDir)
+	}
+	if _, err := os.Stat(filepath.Join(newDir, "full.mp3")); err != nil {
+		t.Errorf("audio not moved: %v", err)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("old directory should be removed, stat err = %v", err)
+	}
+	if want := filepath.Join(newDir, "chunks", "000.wav"); chunk.FilePath != want {
+		t.Errorf("chunk path = %s, want %s", chunk.FilePath, want)
+	}
+	if m.SessionBusy(sess.ID) {
+		t.Error("session still busy after move")
+	}
+
+	// После перезапуска сессия находится по индексу перенесённых
+	reloaded, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatalf("relocated session not loaded: %v", err)
+	}
+	if got.DataDir != newDir || len(got.Chunks) != 1 || got.Chunks[0].FilePath != chunk.FilePath {
+		t.Errorf("reloaded session: dir=%s chunks=%+v", got.DataDir, got.Chunks)
+	}
+
+	// Неполная копия, оставшаяся в dataDir после неудачного удаления, не заменяет перенесённую сессию
+	meta, err := os.ReadFile(filepath.Join(newDir, "meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(oldDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "meta.json"), meta, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err = NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = reloaded.GetSession(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.DataDir != newDir {
+		t.Errorf("session with leftover copy loaded from %s, want %s", got.DataDir, newDir)
+	}
+
+	// Диск с перенесённой сессией не подключён - загружается копия из dataDir
+	unplugged := archive + "-unplugged"
+	if err := os.Rename(archive, unplugged); err != nil {
+		t.Fatal(err)
+	}
+	fallback, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = fallback.GetSession(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.DataDir != oldDir {
+		t.Errorf("unavailable relocated session loaded from %s, want %s", got.DataDir, oldDir)
+	}
+	if err := os.Rename(unplugged, archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(oldDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Возврат в dataDir убирает сессию из индекса
+	if _, err := reloaded.MoveSession(sess.ID, dataDir); err != nil {
+		t.Fatal(err)
+	}
+	if index := reloaded.loadRelocatedIndex(); len(index) != 0 {
+		t.Errorf("index should be empty after moving back, got %v", index)
+	}
+}
@@ -0,0 +1,328 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// retentionPolicyFile политика хранения сессий в dataDir
+const retentionPolicyFile = "retention.json"
+
+// RetentionMode что делать с сессией старше срока хранения
+type RetentionMode string
+
+const (
+	RetentionDelete    RetentionMode = "delete"     // Удалить сессию целиком
+	RetentionDropAudio RetentionMode = "drop-audio" // Удалить аудио, оставить транскрипцию
+)
+
+// RetentionPolicy политика хранения сессий. Закреплённые (Pinned), записываемые и занятые задачами сессии не очищаются
+type RetentionPolicy struct {
+	MaxAgeDays int           `json:"maxAgeDays"`     // Срок хранения в днях (0 - хранить бессрочно)
+	Mode       RetentionMode `json:"mode,omitempty"` // delete или drop-audio
+}
+
+// Enabled возвращает true, если политика что-то очищает
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAgeDays > 0
+}
+
+// Validate проверяет политику
+func (p RetentionPolicy) Validate() error {
+	if p.MaxAgeDays < 0 {
+		return fmt.Errorf("retention maxAgeDays must not be negative: %d", p.MaxAgeDays)
+	}
+	switch p.Mode {
+	case RetentionDelete, RetentionDropAudio:
+	case "":
+		if p.Enabled() {
+			return fmt.Errorf("retention mode is required (%s or %s)", RetentionDelete, RetentionDropAudio)
+		}
+	default:
+		return fmt.Errorf("invalid retention mode %q (expected %s or %s)", p.Mode, RetentionDelete, RetentionDropAudio)
+	}
+	return nil
+}
+
+// RetentionEntry сессия, очищенная политикой хранения
+type RetentionEntry struct {
+	SessionID  string `json:"sessionId"`
+	Title      string `json:"title,omitempty"`
+	FreedBytes int64  `json:"freedBytes"`
+}
+
+// RetentionReport итог очистки по политике хранения
+type RetentionReport struct {
+	Policy       RetentionPolicy  `json:"policy"`
+	Deleted      []RetentionEntry `json:"deleted,omitempty"`      // Удалённые сессии
+	AudioDropped []RetentionEntry `json:"audioDropped,omitempty"` // Сессии, у которых удалено аудио
+	Skipped      int              `json:"skipped,omitempty"`      // Просроченные, но закреплённые, записываемые или занятые
+	FreedBytes   int64            `json:"freedBytes"`
+	Errors       []string         `json:"errors,omitempty"`
+}
+
+// Empty возвращает true, если очистка ничего не изменила
+func (r *RetentionReport) Empty() bool {
+	return len(r.Deleted) == 0 && len(r.AudioDropped) == 0 && len(r.Errors) == 0
+}
+
+// retentionAudioFiles аудиофайлы, удаляемые в режиме drop-audio (помимо chunks/*.wav)
+var retentionAudioFiles = []string{"full.mp3", "full.wav", "raw_capture.bin"}
+
+// GetRetentionPolicy возвращает текущую политику хранения
+func (m *Manager) GetRetentionPolicy() RetentionPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.retention
+}
+
+// SetRetentionPolicy проверяет и сохраняет политику хранения (применяется при следующей очистке)
+func (m *Manager) SetRetentionPolicy(policy RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.WriteFile(filepath.Join(m.dataDir, retentionPolicyFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	m.retention = policy
+	return nil
+}
+
+// loadRetentionPolicy читает сохранённую политику хранения (нет файла - хранить бессрочно)
+func (m *Manager) loadRetentionPolicy() RetentionPolicy {
+	var policy RetentionPolicy
+	data, err := os.ReadFile(filepath.Join(m.dataDir, retentionPolicyFile))
+	if err != nil {
+		return policy
+	}
+	if err := json.Unmarshal(data, &policy); err != nil || policy.Validate() != nil {
+		log.Printf("Failed to parse %s, keeping sessions forever", retentionPolicyFile)
+		return RetentionPolicy{}
+	}
+	return policy
+}
+
+// SetSessionPinned закрепляет сессию: закреплённые сессии не очищаются политикой хранения
+func (m *Manager) SetSessionPinned(id string, pinned bool) error {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("session not found: %s", id)
+	}
+
+	session.mu.Lock()
+	session.Pinned = pinned
+	session.mu.Unlock()
+	m.mu.Unlock()
+
+	// Сохраняем метаданные (SaveSessionMeta использует свой лок)
+	if err := m.SaveSessionMeta(session); err != nil {
+		return fmt.Errorf("failed to save session meta: %w", err)
+	}
+	return nil
+}
+
+// SetOnRetentionCleanup устанавливает callback для итогов фоновой очистки
+func (m *Manager) SetOnRetentionCleanup(fn func(report *RetentionReport)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRetentionCleanup = fn
+}
+
+// StartRetentionCleaner запускает фоновую очистку по политике хранения с заданным интервалом
+// (0 - остановить). Первая очистка выполняется сразу
+func (m *Manager) StartRetentionCleaner(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.retentionStop != nil {
+		close(m.retentionStop)
+		m.retentionStop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	m.retentionStop = make(chan struct{})
+	go m.retentionCleaner(interval, m.retentionStop)
+	log.Printf("SessionManager: retention cleaner started (interval=%v)", interval)
+}
+
+// retentionCleaner периодически очищает просроченные сессии и сообщает итог, если что-то изменилось
+func (m *Manager) retentionCleaner(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report := m.ApplyRetention(time.Now())
+		if !report.Empty() {
+			m.mu.RLock()
+			callback := m.onRetentionCleanup
+			m.mu.RUnlock()
+			if callback != nil {
+				callback(report)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ApplyRetention очищает сессии старше срока хранения на момент now.
+// Закреплённые, записываемые и занятые задачами сессии пропускаются
+func (m *Manager) ApplyRetention(now time.Time) *RetentionReport {
+	m.mu.RLock()
+	policy := m.retention
+	report := &RetentionReport{Policy: policy}
+	if !policy.Enabled() {
+		m.mu.RUnlock()
+		return report
+	}
+
+	cutoff := now.Add(-time.Duration(policy.MaxAgeDays) * 24 * time.Hour)
+	var expired []string
+	for id, session := range m.sessions {
+		session.mu.RLock()
+		ended := session.StartTime
+		if session.EndTime != nil {
+			ended = *session.EndTime
+		}
+		switch {
+		case !ended.Before(cutoff):
+		case policy.Mode == RetentionDropAudio && session.AudioDropped:
+		case m.retentionExemptLocked(session):
+			report.Skipped++
+		default:
+			expired = append(expired, id)
+		}
+		session.mu.RUnlock()
+	}
+	m.mu.RUnlock()
+
+	for _, id := range expired {
+		var entry *RetentionEntry
+		var err error
+		if policy.Mode == RetentionDelete {
+			entry, err = m.deleteExpiredSession(id)
+		} else {
+			entry, err = m.dropSessionAudio(id)
+		}
+		switch {
+		case err != nil:
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", id, err))
+		case entry == nil:
+			report.Skipped++
+		case policy.Mode == RetentionDelete:
+			report.Deleted = append(report.Deleted, *entry)
+			report.FreedBytes += entry.FreedBytes
+		default:
+			report.AudioDropped = append(report.AudioDropped, *entry)
+			report.FreedBytes += entry.FreedBytes
+		}
+	}
+
+	if !report.Empty() {
+		log.Printf("Retention (%s, %d days): deleted %d, audio dropped %d, freed %d MB, skipped %d, errors %d",
+			policy.Mode, policy.MaxAgeDays, len(report.Deleted), len(report.AudioDropped), report.FreedBytes>>20, report.Skipped, len(report.Errors))
+	}
+	return report
+}
+
+// retentionExemptLocked повторная проверка перед очисткой: сессию могли закрепить, начать запись
+// или запустить над ней задачу (MarkSessionBusy). Вызывается под m.mu и session.mu
+func (m *Manager) retentionExemptLocked(session *Session) bool {
+	return session.Pinned || session.ID == m.activeID || session.Status == SessionStatusRecording || m.SessionBusy(session.ID)
+}
+
+// deleteExpiredSession удаляет просроченную сессию (nil - сессия исключена из очистки)
+func (m *Manager) deleteExpiredSession(id string) (*RetentionEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	session.mu.RLock()
+	exempt := m.retentionExemptLocked(session)
+	entry := &RetentionEntry{SessionID: id, Title: session.Title}
+	session.mu.RUnlock()
+	if exempt {
+		return nil, nil
+	}
+
+	if size, err := dirSize(session.DataDir); err == nil {
+		entry.FreedBytes = int64(size)
+	}
+	if err := m.deleteSessionLocked(session); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// dropSessionAudio удаляет аудио просроченной сессии, оставляя транскрипцию (nil - сессия исключена из очистки)
+func (m *Manager) dropSessionAudio(id string) (*RetentionEntry, error) {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, nil
+	}
+
+	session.mu.Lock()
+	if m.retentionExemptLocked(session) {
+		session.mu.Unlock()
+		m.mu.Unlock()
+		return nil, nil
+	}
+
+	entry := &RetentionEntry{SessionID: id, Title: session.Title}
+	paths := make([]string, 0, len(retentionAudioFiles))
+	for _, name := range retentionAudioFiles {
+		paths = append(paths, filepath.Join(session.DataDir, name))
+	}
+	chunkWAVs, _ := filepath.Glob(filepath.Join(session.DataDir, "chunks", "*.wav"))
+	paths = append(paths, chunkWAVs...)
+
+	var removeErr error
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			removeErr = fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+			continue
+		}
+		entry.FreedBytes += info.Size()
+	}
+	if removeErr == nil {
+		session.AudioDropped = true
+	}
+	m.refreshManifestLocked(session)
+	session.mu.Unlock()
+	m.mu.Unlock()
+
+	if removeErr != nil {
+		return nil, removeErr
+	}
+	if err := m.SaveSessionMeta(session); err != nil {
+		return nil, fmt.Errorf("failed to save session meta: %w", err)
+	}
+	return entry, nil
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Создаёт завершённую сессию, закончившуюся ageDays дней назад, с аудио
+	create := func(ageDays int) *Session {
+		t.Helper()
+		sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.StopSession(); err != nil {
+			t.Fatal(err)
+		}
+		ended := time.Now().AddDate(0, 0, -ageDays)
+		sess.StartTime, sess.EndTime = ended.Add(-time.Hour), &ended
+		if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+	old := create(40)
+	pinned := create(40)
+	fresh := create(5)
+	if err := m.SetSessionPinned(pinned.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	active, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	active.StartTime = time.Now().AddDate(0, 0, -40)
+
+	for _, policy := range []RetentionPolicy{{MaxAgeDays: -1, Mode: RetentionDelete}, {MaxAgeDays: 30}, {MaxAgeDays: 30, Mode: "archive"}} {
+		if err := m.SetRetentionPolicy(policy); err == nil {
+			t.Errorf("policy %+v accepted", policy)
+		}
+	}
+	if report := m.ApplyRetention(time.Now()); !report.Empty() {
+		t.Fatalf("cleanup without policy: %+v", report)
+	}
+
+	// Сначала удаляем только аудио: транскрипция остаётся
+	if err := m.SetRetentionPolicy(RetentionPolicy{MaxAgeDays: 30, Mode: RetentionDropAudio}); err != nil {
+		t.Fatal(err)
+	}
+	report := m.ApplyRetention(time.Now())
+	if len(report.AudioDropped) != 1 || report.AudioDropped[0].SessionID != old.ID || report.FreedBytes != 7 || report.Skipped != 2 {
+		t.Fatalf("drop-audio report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(old.DataDir, "full.mp3")); !os.IsNotExist(err) {
+		t.Errorf("audio of an expired session kept: %v", err)
+	}
+	if _, err := m.GetSession(old.ID); err != nil || !old.AudioDropped {
+		t.Errorf("session after drop-audio: err=%v audioDropped=%v", err, old.AudioDropped)
+	}
+	if report := m.ApplyRetention(time.Now()); len(report.AudioDropped) != 0 {
+		t.Errorf("audio dropped twice: %+v", report)
+	}
+
+	if err := m.SetRetentionPolicy(RetentionPolicy{MaxAgeDays: 30, Mode: RetentionDelete}); err != nil {
+		t.Fatal(err)
+	}
+	report = m.ApplyRetention(time.Now())
+	if len(report.Deleted) != 1 || report.Deleted[0].SessionID != old.ID || report.Skipped != 2 {
+		t.Fatalf("delete report = %+v", report)
+	}
+	if _, err := m.GetSession(old.ID); err == nil {
+		t.Error("expired session not deleted")
+	}
+	for _, sess := range []*Session{pinned, fresh, active} {
+		if _, err := os.Stat(sess.DataDir); err != nil {
+			t.Errorf("session %s removed: %v", sess.ID, err)
+		}
+	}
+
+	// Политика и закрепление переживают перезапуск
+	if _, err := m.StopSession(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy := reloaded.GetRetentionPolicy(); policy.MaxAgeDays != 30 || policy.Mode != RetentionDelete {
+		t.Errorf("reloaded policy = %+v", policy)
+	}
+	if sess, err := reloaded.GetSession(pinned.ID); err != nil || !sess.Pinned {
+		t.Errorf("reloaded pinned session: %v", err)
+	}
+}
+
+func TestRetentionSkipsBusySession(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.StopSession(); err != nil {
+		t.Fatal(err)
+	}
+	ended := time.Now().AddDate(0, 0, -40)
+	sess.StartTime, sess.EndTime = ended.Add(-time.Hour), &ended
+	if err := m.SetRetentionPolicy(RetentionPolicy{MaxAgeDays: 30, Mode: RetentionDelete}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Отметки вложенные: сессия свободна после снятия обеих
+	release := m.MarkSessionBusy(sess.ID)
+	releaseNested := m.MarkSessionBusy(sess.ID)
+	if report := m.ApplyRetention(time.Now()); len(report.Deleted) != 0 || report.Skipped != 1 {
+		t.Fatalf("busy session cleaned up: %+v", report)
+	}
+	releaseNested()
+	releaseNested() // Повторный вызов не снимает чужую отметку
+	if !m.SessionBusy(sess.ID) {
+		t.Fatal("session not busy while a task is still running")
+	}
+	release()
+	if m.SessionBusy(sess.ID) {
+		t.Fatal("session still busy after all tasks finished")
+	}
+
+	if report := m.ApplyRetention(time.Now()); len(report.Deleted) != 1 {
+		t.Errorf("expired session kept after tasks finished: %+v", report)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"aiwisper/ai"
 	"aiwisper/models"
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -20,10 +21,42 @@ var (
 	globalSileroVAD     *SileroVADWrapper
 	globalSileroVADOnce sync.Once
 	globalSileroVADErr  error
+
+	// sileroUnavailableLogged недоступность Silero логируется один раз, а не на каждый чанк
+	sileroUnavailableLogged sync.Once
+
+	sileroModelMu         sync.Mutex
+	configuredSileroModel string // Путь к модели из конфигурации (пусто - стандартное расположение)
 )
 
+// SetSileroModelPath задаёт путь к ONNX модели Silero VAD вместо стандартного расположения
+// (пустая строка - стандартное). Указанная модель не скачивается: если файла нет, используется energy VAD
+func SetSileroModelPath(path string) {
+	sileroModelMu.Lock()
+	configuredSileroModel = path
+	sileroModelMu.Unlock()
+	ResetGlobalSileroVAD()
+	if path != "" {
+		log.Printf("Using Silero VAD model from config: %s", path)
+	}
+}
+
+// logSileroUnavailable сообщает об откате на energy VAD (один раз до сброса глобального экземпляра)
+func logSileroUnavailable(err error) {
+	sileroUnavailableLogged.Do(func() {
+		log.Printf("Silero VAD not available: %v, using energy-based", err)
+	})
+}
+
 // getSileroModelPath возвращает путь к модели Silero VAD
 func getSileroModelPath() string {
+	sileroModelMu.Lock()
+	path := configuredSileroModel
+	sileroModelMu.Unlock()
+	if path != "" {
+		return path
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return ""
@@ -39,6 +72,12 @@ func ensureSileroModelDownloaded() (string, error) {
 	if _, err := os.Stat(modelPath); err == nil {
 		return modelPath, nil
 	}
+	sileroModelMu.Lock()
+	configured := configuredSileroModel != ""
+	sileroModelMu.Unlock()
+	if configured {
+		return "", fmt.Errorf("silero VAD model not found: %s: %w", modelPath, os.ErrNotExist)
+	}
 
 	// Модель не найдена - скачиваем
 	log.Printf("Silero VAD model not found, downloading...")
@@ -104,6 +143,7 @@ func ResetGlobalSileroVAD() {
 	}
 	globalSileroVADOnce = sync.Once{}
 	globalSileroVADErr = nil
+	sileroUnavailableLogged = sync.Once{}
 }
 
 // NewSileroVADWrapper создаёт новый Silero VAD wrapper
@@ -142,9 +182,22 @@ func NewSileroVADWrapperWithPath(modelPath string) (*SileroVADWrapper, error) {
 // DetectSpeechRegions определяет участки речи используя Silero VAD
 // Возвращает SpeechRegion совместимые с существующим API
 func (w *SileroVADWrapper) DetectSpeechRegions(samples []float32, sampleRate int) []SpeechRegion {
+	return w.DetectSpeechRegionsWithThreshold(samples, sampleRate, 0)
+}
+
+// DetectSpeechRegionsWithThreshold определяет участки речи с указанным порогом вероятности
+// (0 - порог по умолчанию)
+func (w *SileroVADWrapper) DetectSpeechRegionsWithThreshold(samples []float32, sampleRate int, threshold float32) []SpeechRegion {
+	regions, _ := w.detectSpeechRegions(samples, sampleRate, threshold)
+	return regions
+}
+
+// detectSpeechRegions определяет участки речи и возвращает фактически использованный метод:
+// при ошибке Silero регионы считаются energy VAD
+func (w *SileroVADWrapper) detectSpeechRegions(samples []float32, sampleRate int, threshold float32) ([]SpeechRegion, VADMethod) {
 	if w.vad == nil {
 		log.Printf("SileroVADWrapper: VAD not initialized, falling back to energy-based")
-		return DetectSpeechRegions(samples, sampleRate)
+		return DetectSpeechRegions(samples, sampleRate), VADMethodEnergy
 	}
 
 	// Silero VAD работает только с 16kHz
@@ -155,10 +208,10 @@ func (w *SileroVADWrapper) DetectSpeechRegions(samples []float32, sampleRate int
 	}
 
 	// Используем Silero VAD
-	sileroSegments, err := w.vad.DetectSpeechRegions(samples)
+	sileroSegments, err := w.vad.DetectSpeechRegionsWithThreshold(samples, threshold)
 	if err != nil {
 		log.Printf("SileroVADWrapper: Silero VAD failed: %v, falling back to energy-based", err)
-		return DetectSpeechRegions(samples, sampleRate)
+		return DetectSpeechRegions(samples, sampleRate), VADMethodEnergy
 	}
 
 	// Конвертируем в SpeechRegion
@@ -171,7 +224,7 @@ func (w *SileroVADWrapper) DetectSpeechRegions(samples []float32, sampleRate int
 	}
 
 	log.Printf("SileroVADWrapper: detected %d speech regions using Silero VAD", len(regions))
-	return regions
+	return regions, VADMethodSilero
 }
 
 // Close освобождает ресурсы
@@ -193,27 +246,55 @@ func DetectSpeechRegionsSilero(samples []float32, sampleRate int) ([]SpeechRegio
 	return wrapper.DetectSpeechRegions(samples, sampleRate), nil
 }
 
-// DetectSpeechRegionsWithMethod определяет участки речи указанным методом
-func DetectSpeechRegionsWithMethod(samples []float32, sampleRate int, method VADMethod) []SpeechRegion {
-	switch method {
-	case VADMethodSilero:
-		regions, err := DetectSpeechRegionsSilero(samples, sampleRate)
+// DetectSpeechRegionsWithChannelConfig определяет участки речи методом и порогом канала.
+// Порог трактуется как вероятность речи для Silero и как RMS энергия для energy VAD;
+// 0 - порог метода по умолчанию
+func DetectSpeechRegionsWithChannelConfig(samples []float32, sampleRate int, config ChannelVADConfig) []SpeechRegion {
+	regions, _ := DetectSpeechRegionsForChannel(samples, sampleRate, config)
+	return regions
+}
+
+// DetectSpeechRegionsForChannel как DetectSpeechRegionsWithChannelConfig, но также возвращает
+// фактически использованный метод (energy, если Silero недоступен или упал)
+func DetectSpeechRegionsForChannel(samples []float32, sampleRate int, config ChannelVADConfig) ([]SpeechRegion, VADMethod) {
+	if config.Threshold <= 0 {
+		return detectSpeechRegionsWithMethod(samples, sampleRate, config.Method, config.Energy)
+	}
+
+	switch config.Method {
+	case VADMethodSilero, VADMethodAuto:
+		wrapper, err := GetGlobalSileroVAD()
 		if err != nil {
-			log.Printf("Silero VAD failed: %v, falling back to energy-based", err)
-			return DetectSpeechRegions(samples, sampleRate)
+			logSileroUnavailable(err)
+			return DetectSpeechRegionsWithConfig(samples, sampleRate, config.Energy), VADMethodEnergy
 		}
-		return regions
-	case VADMethodEnergy:
-		return DetectSpeechRegions(samples, sampleRate)
-	case VADMethodAuto:
+		return wrapper.detectSpeechRegions(samples, sampleRate, float32(config.Threshold))
+	default:
+		energy := config.Energy
+		energy.EnergyThreshold = config.Threshold
+		return DetectSpeechRegionsWithConfig(samples, sampleRate, energy), VADMethodEnergy
+	}
+}
+
+// DetectSpeechRegionsWithMethod определяет участки речи указанным методом и возвращает
+// фактически применённый метод (energy, если Silero недоступен или упал)
+func DetectSpeechRegionsWithMethod(samples []float32, sampleRate int, method VADMethod) ([]SpeechRegion, VADMethod) {
+	return detectSpeechRegionsWithMethod(samples, sampleRate, method, DefaultEnergyVADConfig())
+}
+
+// detectSpeechRegionsWithMethod определяет участки речи и возвращает фактически использованный метод.
+// energy - параметры energy VAD (в том числе при откате с Silero)
+func detectSpeechRegionsWithMethod(samples []float32, sampleRate int, method VADMethod, energy EnergyVADConfig) ([]SpeechRegion, VADMethod) {
+	switch method {
+	case VADMethodSilero, VADMethodAuto:
 		// Автовыбор: пробуем Silero, если не получается - Energy
-		regions, err := DetectSpeechRegionsSilero(samples, sampleRate)
+		wrapper, err := GetGlobalSileroVAD()
 		if err != nil {
-			log.Printf("Silero VAD not available: %v, using energy-based", err)
-			return DetectSpeechRegions(samples, sampleRate)
+			logSileroUnavailable(err)
+			return DetectSpeechRegionsWithConfig(samples, sampleRate, energy), VADMethodEnergy
 		}
-		return regions
+		return wrapper.detectSpeechRegions(samples, sampleRate, 0)
 	default:
-		return DetectSpeechRegions(samples, sampleRate)
+		return DetectSpeechRegionsWithConfig(samples, sampleRate, energy), VADMethodEnergy
 	}
 }
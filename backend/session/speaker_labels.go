@@ -0,0 +1,220 @@
+package session
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SpeakerLabelScheme схема стандартных имён спикеров: "Вы" для микрофона,
+// "Собеседник N" для собеседников. Общая для транскрипции, списка спикеров и экспорта
+type SpeakerLabelScheme struct {
+	Prefix  string // Префикс собеседника ("Собеседник", "Участник", "Guest")
+	Base    int    // Номер первого собеседника (обычно 1)
+	Letters bool   // Нумерация буквами: "Guest A", "Guest B"
+	Self    string // Имя владельца микрофона ("Вы")
+}
+
+// Допустимый диапазон номера первого собеседника
+const (
+	MinSpeakerLabelBase = 0
+	MaxSpeakerLabelBase = 1
+)
+
+// DefaultSpeakerLabelScheme возвращает схему по умолчанию: "Вы", "Собеседник 1", "Собеседник 2"...
+func DefaultSpeakerLabelScheme() SpeakerLabelScheme {
+	return SpeakerLabelScheme{Prefix: "Собеседник", Base: 1, Self: "Вы"}
+}
+
+// Validate проверяет схему
+func (s SpeakerLabelScheme) Validate() error {
+	prefix, self := strings.TrimSpace(s.Prefix), strings.TrimSpace(s.Self)
+	switch {
+	case prefix == "" || self == "":
+		return fmt.Errorf("invalid speaker label scheme: prefix and self label must not be empty")
+	case prefix != s.Prefix || self != s.Self:
+		return fmt.Errorf("invalid speaker label scheme: labels must not have leading or trailing spaces")
+	case prefix == self:
+		return fmt.Errorf("invalid speaker label scheme: prefix and self label must differ")
+	case s.Base < MinSpeakerLabelBase || s.Base > MaxSpeakerLabelBase:
+		return fmt.Errorf("invalid speaker label base %d: must be %d or %d", s.Base, MinSpeakerLabelBase, MaxSpeakerLabelBase)
+	case s.Self == "mic" || s.Self == "sys" || s.Prefix == "mic" || s.Prefix == "sys":
+		return fmt.Errorf("invalid speaker label scheme: %q and %q are reserved channel names", "mic", "sys")
+	}
+	return nil
+}
+
+var (
+	speakerLabelsMu sync.RWMutex
+	speakerLabels   = DefaultSpeakerLabelScheme()
+)
+
+// SetSpeakerLabelScheme устанавливает схему стандартных имён спикеров для новых транскрипций и экспорта
+func SetSpeakerLabelScheme(scheme SpeakerLabelScheme) error {
+	if err := scheme.Validate(); err != nil {
+		return err
+	}
+	speakerLabelsMu.Lock()
+	speakerLabels = scheme
+	speakerLabelsMu.Unlock()
+	log.Printf("Speaker label scheme set to: self=%q, prefix=%q, base=%d, letters=%v", scheme.Self, scheme.Prefix, scheme.Base, scheme.Letters)
+	return nil
+}
+
+// GetSpeakerLabelScheme возвращает текущую схему стандартных имён спикеров
+func GetSpeakerLabelScheme() SpeakerLabelScheme {
+	speakerLabelsMu.RLock()
+	defer speakerLabelsMu.RUnlock()
+	return speakerLabels
+}
+
+// SelfSpeakerLabel имя владельца микрофона ("Вы")
+func SelfSpeakerLabel() string {
+	return GetSpeakerLabelScheme().Self
+}
+
+// PeerSpeakerLabel имя единственного собеседника без номера ("Собеседник")
+func PeerSpeakerLabel() string {
+	return GetSpeakerLabelScheme().Prefix
+}
+
+// SpeakerLabel имя собеседника по localID (0 - первый собеседник): "Собеседник 1"
+func SpeakerLabel(localID int) string {
+	return GetSpeakerLabelScheme().Label(localID)
+}
+
+// Label имя собеседника по localID в этой схеме
+func (s SpeakerLabelScheme) Label(localID int) string {
+	return s.numbered(s.Prefix, localID)
+}
+
+// MicLabel имя человека у микрофона по localID при диаризации MIC канала: "Вы 1", "Вы 2".
+// Нумеруется отдельно от собеседников и не пересекается с их именами
+func (s SpeakerLabelScheme) MicLabel(localID int) string {
+	return s.numbered(s.Self, localID)
+}
+
+// numbered имя с номером (или буквой) спикера по правилам схемы
+func (s SpeakerLabelScheme) numbered(prefix string, localID int) string {
+	if s.Letters && localID >= 0 && localID < 26 {
+		return prefix + " " + string(rune('A'+localID))
+	}
+	return fmt.Sprintf("%s %d", prefix, localID+s.Base)
+}
+
+// parse разбирает имя собеседника этой схемы. Имя без номера соответствует localID 0
+func (s SpeakerLabelScheme) parse(label string) (int, bool) {
+	if label == s.Prefix {
+		return 0, true
+	}
+	return s.parseNumbered(s.Prefix, label)
+}
+
+// parseNumbered разбирает имя с номером (или буквой) после prefix
+func (s SpeakerLabelScheme) parseNumbered(prefix, label string) (int, bool) {
+	suffix, ok := strings.CutPrefix(label, prefix+" ")
+	if !ok {
+		return 0, false
+	}
+	if s.Letters && utf8.RuneCountInString(suffix) == 1 && suffix[0] >= 'A' && suffix[0] <= 'Z' {
+		return int(suffix[0] - 'A'), true
+	}
+	num, err := strconv.Atoi(suffix)
+	if err != nil || num < s.Base {
+		return 0, false
+	}
+	return num - s.Base, true
+}
+
+// MicSpeakerLabel имя человека у микрофона по localID при диаризации MIC канала ("Вы 1")
+func MicSpeakerLabel(localID int) string {
+	return GetSpeakerLabelScheme().MicLabel(localID)
+}
+
+// IsMicSpeakerLabel проверяет, что имя назначено диаризацией MIC канала (текущая схема или схема по умолчанию)
+func IsMicSpeakerLabel(label string) bool {
+	if _, ok := GetSpeakerLabelScheme().parseNumbered(SelfSpeakerLabel(), label); ok {
+		return true
+	}
+	defaults := DefaultSpeakerLabelScheme()
+	_, ok := defaults.parseNumbered(defaults.Self, label)
+	return ok
+}
+
+// ParseSpeakerLabel возвращает localID собеседника по стандартному имени.
+// Понимает текущую схему и схему по умолчанию (сессии, записанные до её смены)
+func ParseSpeakerLabel(label string) (int, bool) {
+	if id, ok := GetSpeakerLabelScheme().parse(label); ok {
+		return id, true
+	}
+	return DefaultSpeakerLabelScheme().parse(label)
+}
+
+// IsSelfSpeakerLabel проверяет, что имя принадлежит владельцу микрофона
+func IsSelfSpeakerLabel(label string) bool {
+	return label == "mic" || label == SelfSpeakerLabel() || label == DefaultSpeakerLabelScheme().Self
+}
+
+// IsPeerSpeakerLabel проверяет, что имя - собеседник без номера (SYS канал без диаризации)
+func IsPeerSpeakerLabel(label string) bool {
+	return label == "sys" || label == PeerSpeakerLabel() || label == DefaultSpeakerLabelScheme().Prefix
+}
+
+// IsStandardSpeakerLabel проверяет, что имя назначено автоматически, а не пользователем
+func IsStandardSpeakerLabel(label string) bool {
+	if IsSelfSpeakerLabel(label) || IsPeerSpeakerLabel(label) || IsMicSpeakerLabel(label) || strings.HasPrefix(label, "Speaker ") {
+		return true
+	}
+	_, ok := ParseSpeakerLabel(label)
+	return ok
+}
+
+// FormatSpeakerLabel приводит служебные имена каналов и диаризации ("mic", "sys", "Speaker N")
+// к стандартным именам текущей схемы. Остальные имена возвращаются как есть
+func FormatSpeakerLabel(speaker string) string {
+	switch speaker {
+	case "mic":
+		return SelfSpeakerLabel()
+	case "sys":
+		return PeerSpeakerLabel()
+	}
+	if num, ok := parseDiarizationLabel(speaker); ok {
+		return SpeakerLabel(num)
+	}
+	return speaker
+}
+
+// parseDiarizationLabel возвращает localID из сырой метки диаризации "Speaker N"
+func parseDiarizationLabel(speaker string) (int, bool) {
+	numStr, ok := strings.CutPrefix(speaker, "Speaker ")
+	if !ok {
+		return 0, false
+	}
+	num, err := strconv.Atoi(numStr)
+	if err != nil || num < 0 {
+		return 0, false
+	}
+	return num, true
+}
+
+// SpeakerLabelVariants возвращает все стандартные имена, под которыми спикер с localID
+// может храниться в сессии (-1 - микрофон): текущая схема, схема по умолчанию, метка диаризации
+func SpeakerLabelVariants(localID int) []string {
+	var names []string
+	if localID == -1 {
+		names = []string{SelfSpeakerLabel(), DefaultSpeakerLabelScheme().Self, "mic"}
+	} else {
+		names = []string{SpeakerLabel(localID), DefaultSpeakerLabelScheme().Label(localID), fmt.Sprintf("Speaker %d", localID)}
+	}
+	var result []string
+	for _, name := range names {
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
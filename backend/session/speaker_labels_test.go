@@ -0,0 +1,99 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSpeakerLabelScheme(t *testing.T) {
+	t.Cleanup(func() { SetSpeakerLabelScheme(DefaultSpeakerLabelScheme()) })
+
+	// Схема по умолчанию сохраняет прежние имена
+	if got := SpeakerLabel(0); got != "Собеседник 1" {
+		t.Errorf("default SpeakerLabel(0) = %q", got)
+	}
+	if got := FormatSpeakerLabel("Speaker 1"); got != "Собеседник 2" {
+		t.Errorf("default FormatSpeakerLabel(Speaker 1) = %q", got)
+	}
+
+	for _, bad := range []SpeakerLabelScheme{
+		{Prefix: "", Base: 1, Self: "Вы"},
+		{Prefix: "Гость", Base: 2, Self: "Вы"},
+		{Prefix: "Гость", Base: 1, Self: "Гость"},
+		{Prefix: "Гость ", Base: 1, Self: "Вы"},
+		{Prefix: "sys", Base: 1, Self: "Вы"},
+	} {
+		if err := SetSpeakerLabelScheme(bad); err == nil {
+			t.Errorf("SetSpeakerLabelScheme(%+v) accepted invalid scheme", bad)
+		}
+	}
+
+	if err := SetSpeakerLabelScheme(SpeakerLabelScheme{Prefix: "Guest", Letters: true, Self: "Host"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		speaker, want string
+	}{
+		{"mic", "Host"},
+		{"sys", "Guest"},
+		{"Speaker 0", "Guest A"},
+		{"Speaker 2", "Guest C"},
+		{"Иван", "Иван"},
+	}
+	for _, tt := range tests {
+		if got := FormatSpeakerLabel(tt.speaker); got != tt.want {
+			t.Errorf("FormatSpeakerLabel(%q) = %q, want %q", tt.speaker, got, tt.want)
+		}
+	}
+
+	// Разбор понимает и текущую схему, и имена старых сессий
+	for label, want := range map[string]int{"Guest": 0, "Guest B": 1, "Собеседник 3": 2} {
+		if got, ok := ParseSpeakerLabel(label); !ok || got != want {
+			t.Errorf("ParseSpeakerLabel(%q) = %d, %v, want %d", label, got, ok, want)
+		}
+	}
+	for _, label := range []string{"Guest b", "Guest AB", "Собеседник 0", "Иван"} {
+		if _, ok := ParseSpeakerLabel(label); ok {
+			t.Errorf("ParseSpeakerLabel(%q) parsed a custom name", label)
+		}
+	}
+	if !IsSelfSpeakerLabel("Вы") || !IsStandardSpeakerLabel("Guest Z") || IsStandardSpeakerLabel("Иван") {
+		t.Error("standard label detection mismatch")
+	}
+	if got, want := SpeakerLabelVariants(1), []string{"Guest B", "Собеседник 2", "Speaker 1"}; !slices.Equal(got, want) {
+		t.Errorf("SpeakerLabelVariants(1) = %q, want %q", got, want)
+	}
+}
+
+func TestMicSpeakerLabel(t *testing.T) {
+	t.Cleanup(func() { SetSpeakerLabelScheme(DefaultSpeakerLabelScheme()) })
+
+	if got := MicSpeakerLabel(1); got != "Вы 2" {
+		t.Errorf("default MicSpeakerLabel(1) = %q", got)
+	}
+	// Префикс собеседников "Участник" не пересекается с людьми у микрофона
+	if err := SetSpeakerLabelScheme(SpeakerLabelScheme{Prefix: "Участник", Base: 1, Self: "Вы"}); err != nil {
+		t.Fatal(err)
+	}
+	if mic, peer := MicSpeakerLabel(0), SpeakerLabel(0); mic == peer || mic != "Вы 1" {
+		t.Errorf("MicSpeakerLabel(0) = %q, SpeakerLabel(0) = %q", mic, peer)
+	}
+	if _, ok := ParseSpeakerLabel(MicSpeakerLabel(0)); ok {
+		t.Error("mic participant parsed as peer speaker")
+	}
+
+	if err := SetSpeakerLabelScheme(SpeakerLabelScheme{Prefix: "Guest", Letters: true, Self: "Host"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := MicSpeakerLabel(1); got != "Host B" {
+		t.Errorf("letters MicSpeakerLabel(1) = %q", got)
+	}
+	for _, label := range []string{"Host A", "Вы 3"} {
+		if !IsMicSpeakerLabel(label) || !IsStandardSpeakerLabel(label) {
+			t.Errorf("%q is not detected as mic participant", label)
+		}
+	}
+	if IsMicSpeakerLabel("Host") || IsMicSpeakerLabel("Guest A") || IsMicSpeakerLabel("Вы все") {
+		t.Error("mic participant detection mismatch")
+	}
+}
@@ -0,0 +1,182 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ReassignSpeakerRange назначает собеседника localSpeakerID всем сегментам SYS канала и диалога
+// в диапазоне [startMs, endMs] (ручная правка ошибки диаризации). Сегменты на границе диапазона
+// делятся по словам, метки слов обновляются вместе с сегментом. Если у собеседника есть
+// пользовательское имя, назначается оно. Диапазон с речью микрофона отклоняется: это всегда "Вы".
+// Возвращает количество изменённых сегментов
+func (m *Manager) ReassignSpeakerRange(sessionID string, startMs, endMs int64, localSpeakerID int) (int, error) {
+	if startMs < 0 || endMs <= startMs {
+		return 0, fmt.Errorf("invalid range: %d-%d ms", startMs, endMs)
+	}
+	if localSpeakerID < 0 {
+		return 0, fmt.Errorf("cannot assign microphone speaker to a range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return 0, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	speaker := SpeakerLabel(localSpeakerID)
+	if name, ok := session.CurrentSpeakerRenames()[speaker]; ok {
+		speaker = name
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// Сначала проверяем весь диапазон, чтобы не изменить сессию частично
+	for _, chunk := range session.Chunks {
+		for _, seg := range chunk.MicSegments {
+			if segmentOverlapsRange(seg, startMs, endMs) {
+				return 0, fmt.Errorf("range %d-%d ms covers microphone speech", startMs, endMs)
+			}
+		}
+		for _, seg := range chunk.Dialogue {
+			if segmentOverlapsRange(seg, startMs, endMs) && isMicSpeaker(seg.Speaker) {
+				return 0, fmt.Errorf("range %d-%d ms covers microphone speech", startMs, endMs)
+			}
+		}
+	}
+
+	updatedSegments := 0
+	updatedChunks := 0
+	for _, chunk := range session.Chunks {
+		if chunk.EndMs <= startMs || chunk.StartMs >= endMs {
+			continue
+		}
+		sysSegments, sysCount := reassignSegmentsSpeaker(chunk.SysSegments, startMs, endMs, speaker)
+		dialogue, dialogueCount := reassignSegmentsSpeaker(chunk.Dialogue, startMs, endMs, speaker)
+		if sysCount+dialogueCount == 0 {
+			continue
+		}
+		chunk.SysSegments = sysSegments
+		chunk.Dialogue = dialogue
+		if dialogueCount > 0 {
+			chunk.Transcription = formatDialogue(dialogue)
+		}
+		updatedSegments += max(sysCount, dialogueCount)
+		updatedChunks++
+
+		chunkMetaPath := filepath.Join(session.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+		data, _ := json.MarshalIndent(chunk, "", "  ")
+		if err := os.WriteFile(chunkMetaPath, data, 0644); err != nil {
+			return updatedSegments, fmt.Errorf("failed to save chunk %d: %w", chunk.Index, err)
+		}
+	}
+
+	// И в сохранённых вариантах диалога
+	if session.reassignDialogueLayersSpeakerLocked(startMs, endMs, speaker) {
+		if err := session.saveDialogueLayersLocked(); err != nil {
+			log.Printf("ReassignSpeakerRange: %v", err)
+		}
+	}
+	m.refreshManifestLocked(session)
+
+	log.Printf("ReassignSpeakerRange: session %s, range %d-%d ms -> '%s', updated %d segments in %d chunks",
+		sessionID, startMs, endMs, speaker, updatedSegments, updatedChunks)
+	return updatedSegments, nil
+}
+
+// reassignDialogueLayersSpeakerLocked назначает спикера в диапазоне во всех слоях диалога. Вызывается под s.mu
+func (s *Session) reassignDialogueLayersSpeakerLocked(startMs, endMs int64, speaker string) bool {
+	if s.layers == nil {
+		return false
+	}
+	modified := false
+	for _, layer := range s.layers.Layers {
+		for index, dialogue := range layer.Chunks {
+			if updated, count := reassignSegmentsSpeaker(dialogue, startMs, endMs, speaker); count > 0 {
+				layer.Chunks[index] = updated
+				modified = true
+			}
+		}
+	}
+	return modified
+}
+
+// reassignSegmentsSpeaker назначает спикера сегментам в диапазоне [startMs, endMs].
+// Сегмент на границе делится по словам (слово относится к диапазону по началу),
+// а без слов назначается целиком, если его середина внутри диапазона. Сегменты микрофона не меняются.
+// Возвращает новые сегменты и количество назначенных
+func reassignSegmentsSpeaker(segments []TranscriptSegment, startMs, endMs int64, speaker string) ([]TranscriptSegment, int) {
+	inRange := func(ms int64) bool { return ms >= startMs && ms < endMs }
+	assign := func(seg TranscriptSegment) TranscriptSegment {
+		seg.Speaker = speaker
+		if seg.Words != nil {
+			words := make([]TranscriptWord, len(seg.Words))
+			for i, w := range seg.Words {
+				w.Speaker = speaker
+				words[i] = w
+			}
+			seg.Words = words
+		}
+		return seg
+	}
+
+	var result []TranscriptSegment
+	count := 0
+	for _, seg := range segments {
+		if !segmentOverlapsRange(seg, startMs, endMs) || isMicSpeaker(seg.Speaker) {
+			result = append(result, seg)
+			continue
+		}
+		if seg.Start >= startMs && seg.End <= endMs {
+			if seg.Speaker != speaker {
+				count++
+			}
+			result = append(result, assign(seg))
+			continue
+		}
+		if len(seg.Words) == 0 {
+			if inRange((seg.Start + seg.End) / 2) {
+				seg = assign(seg)
+				count++
+			}
+			result = append(result, seg)
+			continue
+		}
+
+		// Делим по словам на части до, внутри и после диапазона
+		var parts [][]TranscriptWord
+		var inside []bool
+		for _, w := range seg.Words {
+			wordInside := inRange(w.Start)
+			if n := len(parts); n > 0 && inside[n-1] == wordInside {
+				parts[n-1] = append(parts[n-1], w)
+				continue
+			}
+			parts = append(parts, []TranscriptWord{w})
+			inside = append(inside, wordInside)
+		}
+		if len(parts) == 1 {
+			if inside[0] {
+				seg = assign(seg)
+				count++
+			}
+			result = append(result, seg)
+			continue
+		}
+		for i, words := range parts {
+			part := segmentFromWords(seg, words)
+			if inside[i] {
+				part = assign(part)
+				count++
+			}
+			result = append(result, part)
+		}
+	}
+	return result, count
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReassignSpeakerRange(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.StopSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := SpeakerLabel(0), SpeakerLabel(1)
+	words := []TranscriptWord{
+		{Start: 1000, End: 1400, Text: "раз", Speaker: first, P: 0.9},
+		{Start: 1500, End: 1900, Text: "два", Speaker: first, P: 0.5},
+		{Start: 2000, End: 2400, Text: "три", Speaker: first, P: 0.7},
+	}
+	sys := []TranscriptSegment{
+		{Start: 1000, End: 2400, Text: "раз два три", Speaker: first, Words: words},
+		{Start: 3000, End: 4000, Text: "четыре", Speaker: first},
+	}
+	mic := []TranscriptSegment{{Start: 6000, End: 7000, Text: "привет", Speaker: SelfSpeakerLabel()}}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Index: 0, StartMs: 0, EndMs: 10000, Status: ChunkStatusCompleted,
+		MicSegments: mic, SysSegments: sys, Dialogue: append(slices.Clone(sys), mic...)}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.ReassignSpeakerRange(sess.ID, 5000, 6500, 1); err == nil {
+		t.Error("range with microphone speech accepted")
+	}
+	if _, err := m.ReassignSpeakerRange(sess.ID, 1000, 2000, -1); err == nil {
+		t.Error("microphone speaker accepted")
+	}
+
+	// 1500-3600 мс: "два три" отделяются от "раз", "четыре" (середина 3500) назначается целиком
+	updated, err := m.ReassignSpeakerRange(sess.ID, 1500, 3600, 1)
+	if err != nil {
+		t.Fatalf("ReassignSpeakerRange: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("updated = %d, want 2", updated)
+	}
+	for _, segments := range [][]TranscriptSegment{chunk.SysSegments, chunk.Dialogue} {
+		var got []string
+		for _, seg := range segments {
+			got = append(got, seg.Speaker+":"+seg.Text)
+		}
+		if len(segments) < 3 || segments[0].Speaker != first || segments[0].Text != "раз" ||
+			segments[1].Speaker != second || segments[1].Text != "два три" || segments[1].Start != 1500 ||
+			segments[2].Speaker != second {
+			t.Fatalf("segments = %v", got)
+		}
+		if w := segments[1].Words; len(w) != 2 || w[0].Speaker != second || w[1].Speaker != second {
+			t.Errorf("word speakers are not updated: %+v", w)
+		}
+		if segments[1].Confidence != 0.6 {
+			t.Errorf("confidence = %v, want 0.6", segments[1].Confidence)
+		}
+	}
+
+	// Пользовательское имя собеседника назначается вместо стандартного
+	if err := m.RecordSpeakerRename(sess.ID, SpeakerRename{LocalID: 0, StandardName: first, OldName: first, NewName: "Иван"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ReassignSpeakerRange(sess.ID, 1000, 1400, 0); err != nil {
+		t.Fatal(err)
+	}
+	if chunk.SysSegments[0].Speaker != "Иван" {
+		t.Errorf("speaker = %q, want renamed name", chunk.SysSegments[0].Speaker)
+	}
+}
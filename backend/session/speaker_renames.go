@@ -0,0 +1,351 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// speakerRenamesFile история переименований спикеров сессии
+const speakerRenamesFile = "speaker_renames.json"
+
+// SpeakerRename запись о переименовании спикера пользователем
+type SpeakerRename struct {
+	LocalID      int       `json:"localId"`      // ID спикера в сессии (-1 - микрофон)
+	StandardName string    `json:"standardName"` // Исходное стандартное имя ("Вы", "Собеседник N")
+	OldName      string    `json:"oldName"`      // Имя до переименования
+	NewName      string    `json:"newName"`      // Имя после переименования
+	RenamedAt    time.Time `json:"renamedAt"`
+	Inferred     bool      `json:"inferred,omitempty"` // Восстановлено по диалогу при миграции старой сессии
+	// Labels метки, заменённые переименованием (варианты имени спикера: "Speaker 0", "Собеседник 1"...)
+	Labels []string `json:"labels,omitempty"`
+	// Segments переименованные сегменты: undo возвращает прежнюю метку только им.
+	// Нет у записей, сделанных до их появления, - тогда undo переименовывает по имени
+	Segments []RenamedSegment `json:"segments,omitempty"`
+}
+
+// RenamedSegment сегмент, которому переименование заменило метку спикера
+type RenamedSegment struct {
+	ChunkID string `json:"chunkId"`
+	Layer   string `json:"layer,omitempty"` // Вариант диалога; пусто - сегменты самого чанка
+	Field   string `json:"field,omitempty"` // Сегменты чанка: "dialogue", "mic" или "sys"
+	Index   int    `json:"index"`
+	Overlap bool   `json:"overlap,omitempty"` // Заменён OverlapSpeaker, а не Speaker
+	Label   string `json:"label"`             // Метка до переименования
+}
+
+// Поля сегментов чанка в RenamedSegment
+const (
+	renamedFieldDialogue = "dialogue"
+	renamedFieldMic      = "mic"
+	renamedFieldSys      = "sys"
+)
+
+// RecordSpeakerRename добавляет переименование в историю сессии и сохраняет её на диск
+func (m *Manager) RecordSpeakerRename(sessionID string, rename SpeakerRename) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if rename.RenamedAt.IsZero() {
+		rename.RenamedAt = time.Now()
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	history := append(append([]SpeakerRename(nil), session.SpeakerRenames...), rename)
+	if err := saveSpeakerRenames(session.DataDir, history); err != nil {
+		return err
+	}
+	session.SpeakerRenames = history
+	return nil
+}
+
+// RenameSpeakerLabels заменяет метки labels на newName во всех сегментах сессии (диалог, каналы,
+// варианты диалога). Возвращает заменённые сегменты - по ним undo вернёт прежние метки
+func (m *Manager) RenameSpeakerLabels(sessionID string, labels []string, newName string) ([]RenamedSegment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	replace := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if label != newName {
+			replace[label] = true
+		}
+	}
+
+	var renamed []RenamedSegment
+	rename := func(chunk *Chunk, layer, field string, segments []TranscriptSegment, overlap bool) {
+		for i := range segments {
+			if replace[segments[i].Speaker] {
+				renamed = append(renamed, RenamedSegment{ChunkID: chunk.ID, Layer: layer, Field: field, Index: i, Label: segments[i].Speaker})
+				segments[i].Speaker = newName
+			}
+			if overlap && replace[segments[i].OverlapSpeaker] {
+				renamed = append(renamed, RenamedSegment{ChunkID: chunk.ID, Layer: layer, Field: field, Index: i, Overlap: true, Label: segments[i].OverlapSpeaker})
+				segments[i].OverlapSpeaker = newName
+			}
+		}
+	}
+
+	layersModified := false
+	for _, chunk := range session.Chunks {
+		before := len(renamed)
+		rename(chunk, "", renamedFieldDialogue, chunk.Dialogue, true)
+		rename(chunk, "", renamedFieldSys, chunk.SysSegments, true)
+		rename(chunk, "", renamedFieldMic, chunk.MicSegments, false)
+		if len(renamed) > before {
+			session.saveChunkLocked(chunk)
+		}
+
+		if session.layers == nil {
+			continue
+		}
+		before = len(renamed)
+		for _, name := range slices.Sorted(maps.Keys(session.layers.Layers)) {
+			rename(chunk, name, "", session.layers.Layers[name].Chunks[chunk.Index], false)
+		}
+		layersModified = layersModified || len(renamed) > before
+	}
+	if layersModified {
+		if err := session.saveDialogueLayersLocked(); err != nil {
+			log.Printf("RenameSpeakerLabels: %v", err)
+		}
+	}
+
+	log.Printf("RenameSpeakerLabels: session %s, %v -> '%s' (%d segments)", sessionID, labels, newName, len(renamed))
+	return renamed, nil
+}
+
+// UndoSpeakerRename отменяет последнее переименование спикера: возвращает прежние метки
+// переименованным сегментам и удаляет запись из истории. Возвращает отменённую запись
+func (m *Manager) UndoSpeakerRename(sessionID string) (*SpeakerRename, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	// Запись и история читаются и меняются под одной блокировкой: параллельное
+	// переименование или undo не сдвинет последнюю запись
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	n := len(session.SpeakerRenames)
+	if n == 0 {
+		return nil, fmt.Errorf("no speaker renames to undo")
+	}
+	last := session.SpeakerRenames[n-1]
+
+	if len(last.Segments) == 0 {
+		session.renameSpeakerLocked(last.NewName, last.OldName)
+	} else {
+		session.revertSpeakerRenameLocked(last)
+	}
+
+	history := slices.Clone(session.SpeakerRenames[:n-1])
+	if err := saveSpeakerRenames(session.DataDir, history); err != nil {
+		return nil, err
+	}
+	session.SpeakerRenames = history
+	log.Printf("UndoSpeakerRename: session %s, '%s' -> '%s'", sessionID, last.NewName, last.OldName)
+	return &last, nil
+}
+
+// revertSpeakerRenameLocked возвращает прежние метки сегментам, переименованным записью rename.
+// Чанк, перераспознанный после переименования или изменённый так, что сегмент уже не найти,
+// возвращается к прежнему имени целиком. Вызывается под s.mu
+func (s *Session) revertSpeakerRenameLocked(rename SpeakerRename) {
+	byChunk := make(map[string][]RenamedSegment)
+	for _, seg := range rename.Segments {
+		byChunk[seg.ChunkID] = append(byChunk[seg.ChunkID], seg)
+	}
+
+	layersModified := false
+	for _, chunk := range s.Chunks {
+		refs, ok := byChunk[chunk.ID]
+		if !ok {
+			continue
+		}
+
+		targets := make([]*string, len(refs))
+		valid := chunk.TranscribedAt == nil || !chunk.TranscribedAt.After(rename.RenamedAt)
+		for i, ref := range refs {
+			if !valid {
+				break
+			}
+			targets[i] = s.renamedSpeakerFieldLocked(chunk, ref)
+			valid = targets[i] != nil && *targets[i] == rename.NewName
+		}
+
+		if !valid {
+			log.Printf("UndoSpeakerRename: chunk %d changed after rename, reverting by name", chunk.Index)
+			if s.renameChunkSpeakerLocked(chunk, rename.NewName, rename.OldName) {
+				layersModified = true
+			}
+			continue
+		}
+		for i, ref := range refs {
+			*targets[i] = ref.Label
+			layersModified = layersModified || ref.Layer != ""
+		}
+		s.saveChunkLocked(chunk)
+	}
+	if layersModified {
+		if err := s.saveDialogueLayersLocked(); err != nil {
+			log.Printf("UndoSpeakerRename: %v", err)
+		}
+	}
+}
+
+// renamedSpeakerFieldLocked возвращает поле метки сегмента ref или nil, если сегмента нет.
+// Вызывается под s.mu
+func (s *Session) renamedSpeakerFieldLocked(chunk *Chunk, ref RenamedSegment) *string {
+	var segments []TranscriptSegment
+	if ref.Layer != "" {
+		layer := s.layerLocked(ref.Layer, false)
+		if layer == nil {
+			return nil
+		}
+		segments = layer.Chunks[chunk.Index]
+	} else {
+		switch ref.Field {
+		case renamedFieldDialogue:
+			segments = chunk.Dialogue
+		case renamedFieldMic:
+			segments = chunk.MicSegments
+		case renamedFieldSys:
+			segments = chunk.SysSegments
+		}
+	}
+	if ref.Index < 0 || ref.Index >= len(segments) {
+		return nil
+	}
+	if ref.Overlap {
+		return &segments[ref.Index].OverlapSpeaker
+	}
+	return &segments[ref.Index].Speaker
+}
+
+// saveSpeakerRenames сохраняет историю переименований в директорию сессии
+func saveSpeakerRenames(dir string, history []SpeakerRename) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal speaker renames: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, speakerRenamesFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save speaker renames: %w", err)
+	}
+	return nil
+}
+
+// CurrentSpeakerRenames возвращает действующие переименования по истории:
+// стандартное имя ("Собеседник N") -> последнее пользовательское имя.
+// Используется для восстановления имён после полной ретранскрипции
+func (s *Session) CurrentSpeakerRenames() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	renames := make(map[string]string)
+	for _, r := range s.SpeakerRenames {
+		if r.StandardName == "" {
+			continue
+		}
+		if r.NewName == r.StandardName {
+			delete(renames, r.StandardName)
+			continue
+		}
+		renames[r.StandardName] = r.NewName
+	}
+	return renames
+}
+
+// loadSpeakerRenames загружает историю переименований из директории сессии.
+// У старых сессий истории нет: пользовательские имена в диалоге сопоставляются
+// со стандартными один раз и сохраняются как явные записи
+func (s *Session) loadSpeakerRenames() {
+	path := filepath.Join(s.DataDir, speakerRenamesFile)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &s.SpeakerRenames); err != nil {
+			log.Printf("Failed to parse %s for session %s: %v", speakerRenamesFile, s.ID, err)
+		}
+		return
+	}
+	if !os.IsNotExist(err) {
+		log.Printf("Failed to read %s for session %s: %v", speakerRenamesFile, s.ID, err)
+		return
+	}
+
+	inferred := inferLegacySpeakerRenames(s.Chunks)
+	if len(inferred) == 0 {
+		return
+	}
+	if err := saveSpeakerRenames(s.DataDir, inferred); err != nil {
+		log.Printf("Failed to migrate speaker renames for session %s: %v", s.ID, err)
+		return
+	}
+	s.SpeakerRenames = inferred
+	for _, r := range inferred {
+		log.Printf("LoadSessions: session %s migrated speaker rename '%s' -> '%s'", s.ID, r.StandardName, r.NewName)
+	}
+}
+
+// maxLegacySpeakers сколько стандартных имён "Собеседник N" проверяется при миграции
+const maxLegacySpeakers = 5
+
+// inferLegacySpeakerRenames сопоставляет пользовательские имена старой сессии со стандартными:
+// по порядку первого появления в диалоге каждое имя занимает наименьший отсутствующий "Собеседник N"
+func inferLegacySpeakerRenames(chunks []*Chunk) []SpeakerRename {
+	present := make(map[string]bool)
+	var custom []string
+	for _, chunk := range chunks {
+		for _, seg := range chunk.Dialogue {
+			name := seg.Speaker
+			if name == "" || present[name] {
+				continue
+			}
+			present[name] = true
+			if !IsStandardSpeakerLabel(name) {
+				custom = append(custom, name)
+			}
+		}
+	}
+
+	var missing []int
+	for i := 1; i <= maxLegacySpeakers; i++ {
+		if !present[fmt.Sprintf("Собеседник %d", i)] {
+			missing = append(missing, i)
+		}
+	}
+
+	var result []SpeakerRename
+	for i, name := range custom {
+		if i >= len(missing) {
+			break
+		}
+		standard := fmt.Sprintf("Собеседник %d", missing[i])
+		result = append(result, SpeakerRename{
+			LocalID:      missing[i] - 1,
+			StandardName: standard,
+			OldName:      standard,
+			NewName:      name,
+			Inferred:     true,
+		})
+	}
+	return result
+}
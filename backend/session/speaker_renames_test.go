@@ -0,0 +1,218 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSpeakerRenameUndo(t *testing.T) {
+	dataDir := t.TempDir()
+	m, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет", Speaker: "Собеседник 1"},
+		{Start: 1000, End: 2000, Text: "Здравствуйте", Speaker: "Собеседник 2"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.UndoSpeakerRename(sess.ID); err == nil {
+		t.Error("expected error when there is nothing to undo")
+	}
+
+	// Собеседник 1 -> Иван -> Пётр
+	for _, r := range []SpeakerRename{
+		{LocalID: 0, StandardName: "Собеседник 1", OldName: "Собеседник 1", NewName: "Иван"},
+		{LocalID: 0, StandardName: "Собеседник 1", OldName: "Иван", NewName: "Пётр"},
+	} {
+		if err := m.UpdateSpeakerName(sess.ID, r.OldName, r.NewName); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.RecordSpeakerRename(sess.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	undone, err := m.UndoSpeakerRename(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.OldName != "Иван" || chunk.Dialogue[0].Speaker != "Иван" || chunk.Dialogue[1].Speaker != "Собеседник 2" {
+		t.Errorf("after first undo: undone=%+v, speakers=%q/%q", undone, chunk.Dialogue[0].Speaker, chunk.Dialogue[1].Speaker)
+	}
+
+	// История переживает перезапуск
+	reloaded, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.SpeakerRenames) != 1 || got.SpeakerRenames[0].NewName != "Иван" || got.SpeakerRenames[0].RenamedAt.IsZero() {
+		t.Fatalf("reloaded history = %+v", got.SpeakerRenames)
+	}
+	if _, err := reloaded.UndoSpeakerRename(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if speaker := got.Chunks[0].Dialogue[0].Speaker; speaker != "Собеседник 1" || len(got.SpeakerRenames) != 0 {
+		t.Errorf("after second undo: speaker=%q, history=%+v", speaker, got.SpeakerRenames)
+	}
+}
+
+func TestSpeakerRenamesMigration(t *testing.T) {
+	dataDir := t.TempDir()
+	m, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Старая сессия: имена переименованы, истории переименований нет
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Index: 0, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет", Speaker: "Вы"},
+		{Start: 1000, End: 2000, Text: "Здравствуйте", Speaker: "Анна"},
+		{Start: 2000, End: 3000, Text: "Добрый день", Speaker: "Собеседник 2"},
+		{Start: 3000, End: 4000, Text: "Начнём", Speaker: "Борис"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveSessionMeta(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"Собеседник 1": "Анна", "Собеседник 3": "Борис"}
+	for attempt := 0; attempt < 2; attempt++ {
+		reloaded, err := NewManager(dataDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := reloaded.GetSession(sess.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renames := got.CurrentSpeakerRenames()
+		if len(renames) != len(want) {
+			t.Fatalf("attempt %d: renames = %v, want %v", attempt, renames, want)
+		}
+		for k, v := range want {
+			if renames[k] != v {
+				t.Errorf("attempt %d: %s -> %q, want %q", attempt, k, renames[k], v)
+			}
+		}
+		for _, r := range got.SpeakerRenames {
+			if !r.Inferred {
+				t.Errorf("attempt %d: migrated record not marked inferred: %+v", attempt, r)
+			}
+		}
+	}
+
+	// Явное переименование после миграции замещает выведенное
+	reloaded, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.RecordSpeakerRename(sess.ID, SpeakerRename{LocalID: 0, StandardName: "Собеседник 1", OldName: "Анна", NewName: "Алла"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := reloaded.GetSession(sess.ID)
+	if name := got.CurrentSpeakerRenames()["Собеседник 1"]; name != "Алла" {
+		t.Errorf("after explicit rename: %q, want %q", name, "Алла")
+	}
+}
+
+func TestSpeakerRenameUndoTouchedSegments(t *testing.T) {
+	dataDir := t.TempDir()
+	m, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &Chunk{ID: "c0", SessionID: sess.ID, Dialogue: []TranscriptSegment{
+		{Start: 0, End: 1000, Text: "Привет", Speaker: "Собеседник 1"},
+		{Start: 1000, End: 2000, Text: "Как дела", Speaker: "Speaker 0"}, // Другой вариант метки того же собеседника
+		{Start: 2000, End: 3000, Text: "Здравствуйте", Speaker: "Собеседник 2"},
+	}}
+	if err := m.AddChunk(sess.ID, chunk); err != nil {
+		t.Fatal(err)
+	}
+	layer := []TranscriptSegment{{Start: 2000, End: 3000, Text: "Здравствуйте!", Speaker: "Собеседник 2"}}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, layer); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyDialogueLayer(sess.ID, DialogueLayerRaw); err != nil {
+		t.Fatal(err)
+	}
+
+	rename := func(localID int, labels []string, newName string) {
+		t.Helper()
+		renamed, err := m.RenameSpeakerLabels(sess.ID, labels, newName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := SpeakerRename{LocalID: localID, StandardName: labels[0], OldName: labels[0], NewName: newName, Labels: labels, Segments: renamed}
+		if err := m.RecordSpeakerRename(sess.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Оба собеседника получают одно имя, у первого схлопываются две метки
+	rename(1, []string{"Собеседник 2"}, "Анна")
+	rename(0, []string{"Собеседник 1", "Speaker 0"}, "Анна")
+
+	check := func(m *Manager, want ...string) {
+		t.Helper()
+		s, err := m.GetSession(sess.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, seg := range s.Chunks[0].Dialogue {
+			got = append(got, seg.Speaker)
+		}
+		improved, err := m.GetDialogueLayer(sess.ID, DialogueLayerImproved)
+		if err != nil || len(improved) != 1 {
+			t.Fatalf("improved layer = %+v (err %v)", improved, err)
+		}
+		if got = append(got, improved[0].Speaker); !slices.Equal(got, want) {
+			t.Errorf("speakers = %q, want %q", got, want)
+		}
+	}
+	check(m, "Анна", "Анна", "Анна", "Анна")
+
+	// Отмена возвращает прежние метки только сегментам первого собеседника
+	if _, err := m.UndoSpeakerRename(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	check(m, "Собеседник 1", "Speaker 0", "Анна", "Анна")
+
+	// Затронутые сегменты переживают перезапуск
+	reloaded, err := NewManager(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reloaded.GetSession(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.SpeakerRenames) != 1 || len(got.SpeakerRenames[0].Segments) != 3 || !slices.Equal(got.SpeakerRenames[0].Labels, []string{"Собеседник 2"}) {
+		t.Fatalf("reloaded history = %+v", got.SpeakerRenames)
+	}
+	if _, err := reloaded.UndoSpeakerRename(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded, "Собеседник 1", "Speaker 0", "Собеседник 2", "Собеседник 2")
+}
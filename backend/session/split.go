@@ -0,0 +1,391 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// splitCopiedFiles файлы сессии, которые копируются в обе части без изменений:
+// номера собеседников при разделении не меняются
+var splitCopiedFiles = []string{speakerProfilesFile}
+
+// SplitSession разделяет сессию в момент splitMs на две новые сессии: full.mp3 нарезается через FFmpeg,
+// чанки распределяются по частям (чанк на границе делится надвое, его аудио извлекается уже из новых full.mp3),
+// время второй части отсчитывается от нуля. Если splitMs попадает внутрь слова, граница сдвигается
+// на начало слова. Варианты диалога и диалог с пунктуацией делятся так же, как чанки; summary,
+// история переименований и закрепление достаются обеим частям. Исходная сессия удаляется после разделения
+func (m *Manager) SplitSession(sessionID string, splitMs int64) (first, second *Session, err error) {
+	// FFmpeg работает без m.mu: сессия атомарно отмечается занятой, чтобы её не удалил
+	// ретеншн и не взяли в работу другие операции (ретранскрипция, перенос)
+	m.mu.Lock()
+	session, err := m.mergeCandidateLocked(sessionID)
+	var release func()
+	if err == nil {
+		release, err = m.TryMarkSessionBusy(sessionID, true)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	audioPath := filepath.Join(session.DataDir, "full.mp3")
+	if !fileExists(audioPath) {
+		return nil, nil, fmt.Errorf("session %s has no full.mp3", sessionID)
+	}
+
+	session.mu.RLock()
+	durationMs := sessionDurationMs(session)
+	splitMs = wordBoundaryBefore(session.Chunks, splitMs)
+	session.mu.RUnlock()
+	if splitMs <= 0 || splitMs >= durationMs {
+		return nil, nil, fmt.Errorf("split point must be inside the recording (0 - %d ms)", durationMs)
+	}
+
+	first, err = m.newSplitPart(session, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	second, err = m.newSplitPart(session, 2)
+	if err != nil {
+		os.RemoveAll(first.DataDir)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		os.RemoveAll(first.DataDir)
+		os.RemoveAll(second.DataDir)
+	}
+
+	if err := cutMP3(audioPath, filepath.Join(first.DataDir, "full.mp3"), 0, splitMs); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := cutMP3(audioPath, filepath.Join(second.DataDir, "full.mp3"), splitMs, 0); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[sessionID] != session {
+		cleanup()
+		return nil, nil, fmt.Errorf("session %s was deleted during split", sessionID)
+	}
+
+	session.mu.RLock()
+	for _, chunk := range session.Chunks {
+		switch {
+		case chunk.EndMs <= splitMs:
+			appendSplitChunk(first, session, chunk, shiftChunk(chunk, 0, 0), func(dialogue []TranscriptSegment) []TranscriptSegment {
+				return shiftSegments(dialogue, 0, 0)
+			})
+		case chunk.StartMs >= splitMs:
+			appendSplitChunk(second, session, chunk, shiftChunk(chunk, -splitMs, 0), func(dialogue []TranscriptSegment) []TranscriptSegment {
+				return shiftSegments(dialogue, -splitMs, 0)
+			})
+		default:
+			before, after := splitChunkAt(chunk, splitMs)
+			appendSplitChunk(first, session, chunk, before, func(dialogue []TranscriptSegment) []TranscriptSegment {
+				head, _ := splitSegmentsAt(dialogue, splitMs)
+				return head
+			})
+			appendSplitChunk(second, session, chunk, shiftChunk(after, -splitMs, 0), func(dialogue []TranscriptSegment) []TranscriptSegment {
+				_, tail := splitSegmentsAt(dialogue, splitMs)
+				return shiftSegments(tail, -splitMs, 0)
+			})
+		}
+	}
+	if session.PunctuatedDialogue != nil {
+		head, tail := splitSegmentsAt(session.PunctuatedDialogue, splitMs)
+		first.PunctuatedDialogue = head
+		second.PunctuatedDialogue = shiftSegments(tail, -splitMs, 0)
+	}
+	for _, name := range splitCopiedFiles {
+		data, err := os.ReadFile(filepath.Join(session.DataDir, name))
+		if err != nil {
+			continue
+		}
+		for _, part := range []*Session{first, second} {
+			if err := os.WriteFile(filepath.Join(part.DataDir, name), data, 0644); err != nil {
+				log.Printf("SplitSession: failed to copy %s: %v", name, err)
+			}
+		}
+	}
+	first.SpeakerRenames = append([]SpeakerRename(nil), session.SpeakerRenames...)
+	second.SpeakerRenames = append([]SpeakerRename(nil), session.SpeakerRenames...)
+
+	first.EndTime = timePtr(session.StartTime.Add(time.Duration(splitMs) * time.Millisecond))
+	second.StartTime = *first.EndTime
+	second.EndTime = session.EndTime
+	first.TotalDuration = time.Duration(splitMs) * time.Millisecond
+	second.TotalDuration = time.Duration(durationMs-splitMs) * time.Millisecond
+	first.SampleCount = session.SampleCount * splitMs / durationMs
+	second.SampleCount = session.SampleCount - first.SampleCount
+	session.mu.RUnlock()
+
+	for _, part := range []*Session{first, second} {
+		for _, chunk := range part.Chunks {
+			chunkMetaPath := filepath.Join(part.DataDir, "chunks", fmt.Sprintf("%03d.json", chunk.Index))
+			data, err := json.MarshalIndent(chunk, "", "  ")
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			if err := os.WriteFile(chunkMetaPath, data, 0644); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to save chunk %d: %w", chunk.Index, err)
+			}
+		}
+		if part.layers != nil {
+			if err := part.saveDialogueLayersLocked(); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+		}
+		if err := saveSessionExtras(part); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if err := m.SaveSessionMeta(part); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to save session meta: %w", err)
+		}
+	}
+
+	if err := m.deleteSessionLocked(session); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	for _, part := range []*Session{first, second} {
+		m.sessions[part.ID] = part
+		part.mu.Lock()
+		m.refreshManifestLocked(part)
+		part.mu.Unlock()
+	}
+
+	log.Printf("SplitSession: split %s at %d ms into %s (%d chunks) and %s (%d chunks)",
+		sessionID, splitMs, first.ID, len(first.Chunks), second.ID, len(second.Chunks))
+	return first, second, nil
+}
+
+// appendSplitChunk добавляет чанк chunk, полученный из source исходной сессии, в часть part
+// и переносит диалог source из слоёв сессии, преобразуя его так же, как чанк (dialogue).
+// Вызывается под session.mu, part ещё не опубликована
+func appendSplitChunk(part, session *Session, source, chunk *Chunk, dialogue func([]TranscriptSegment) []TranscriptSegment) {
+	chunk.Index = len(part.Chunks)
+	chunk.SessionID = part.ID
+	part.Chunks = append(part.Chunks, chunk)
+	if session.layers == nil {
+		return
+	}
+	for name, layer := range session.layers.Layers {
+		segments, ok := layer.Chunks[source.Index]
+		if !ok {
+			continue
+		}
+		partLayer := part.layerLocked(name, true)
+		partLayer.Chunks[chunk.Index] = dialogue(segments)
+		partLayer.UpdatedAt = layer.UpdatedAt
+		if layer.Stale[source.Index] {
+			if partLayer.Stale == nil {
+				partLayer.Stale = make(map[int]bool)
+			}
+			partLayer.Stale[chunk.Index] = true
+		}
+	}
+	if part.layers != nil {
+		part.layers.Active = session.layers.Active
+	}
+}
+
+// newSplitPart создаёт директорию части part (1 или 2) разделяемой сессии с её настройками
+func (m *Manager) newSplitPart(session *Session, part int) (*Session, error) {
+	id := uuid.New().String()
+	sessionDir := filepath.Join(m.dataDir, id)
+	if err := os.MkdirAll(filepath.Join(sessionDir, "chunks"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session dir: %w", err)
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	title := session.Title
+	if title != "" {
+		title = fmt.Sprintf("%s (%d)", title, part)
+	}
+	return &Session{
+		ID:           id,
+		StartTime:    session.StartTime,
+		Status:       SessionStatusCompleted,
+		Language:     session.Language,
+		Model:        session.Model,
+		Title:        title,
+		Tags:         append([]string(nil), session.Tags...),
+		Summary:      session.Summary,
+		Pinned:       session.Pinned,
+		DataDir:      sessionDir,
+		DiarizeMic:   session.DiarizeMic,
+		SwapChannels: session.SwapChannels,
+		MicOnly:      session.MicOnly,
+		Chunks:       make([]*Chunk, 0),
+	}, nil
+}
+
+// wordBoundaryBefore сдвигает splitMs на начало слова, если момент попадает внутрь слова
+// (слова разных каналов могут перекрываться, поэтому сдвиг повторяется до границы)
+func wordBoundaryBefore(chunks []*Chunk, splitMs int64) int64 {
+	for moved := true; moved; {
+		moved = false
+		for _, chunk := range chunks {
+			for _, segments := range [][]TranscriptSegment{chunk.MicSegments, chunk.SysSegments, chunk.Dialogue} {
+				for _, seg := range segments {
+					for _, w := range seg.Words {
+						if w.Start < splitMs && splitMs < w.End {
+							splitMs = w.Start
+							moved = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return splitMs
+}
+
+// splitChunkAt делит чанк на границе splitMs: сегменты до границы остаются в первой половине,
+// после - во второй. Сегмент на границе делится по словам
+func splitChunkAt(chunk *Chunk, splitMs int64) (before, after *Chunk) {
+	before = shiftChunk(chunk, 0, 0)
+	after = shiftChunk(chunk, 0, 0)
+	after.ID = uuid.New().String()
+
+	before.EndMs, after.StartMs = splitMs, splitMs
+	before.Duration = time.Duration(before.EndMs-before.StartMs) * time.Millisecond
+	after.Duration = time.Duration(after.EndMs-after.StartMs) * time.Millisecond
+
+	before.MicSegments, after.MicSegments = splitSegmentsAt(chunk.MicSegments, splitMs)
+	before.SysSegments, after.SysSegments = splitSegmentsAt(chunk.SysSegments, splitMs)
+	before.Dialogue, after.Dialogue = splitSegmentsAt(chunk.Dialogue, splitMs)
+	for _, half := range []*Chunk{before, after} {
+		if len(chunk.MicSegments) > 0 {
+			half.MicText = JoinSegmentsText(half.MicSegments)
+		}
+		if len(chunk.SysSegments) > 0 {
+			half.SysText = JoinSegmentsText(half.SysSegments)
+		}
+		if len(chunk.Dialogue) > 0 {
+			half.Transcription = formatDialogue(half.Dialogue)
+		}
+		half.Clipping = clipRanges(half.Clipping, half.StartMs, half.EndMs)
+	}
+	// Текст без сегментов нельзя разделить по времени - он остаётся в первой половине
+	if len(chunk.MicSegments) == 0 {
+		after.MicText = ""
+	}
+	if len(chunk.SysSegments) == 0 {
+		after.SysText = ""
+	}
+	if len(chunk.Dialogue) == 0 {
+		after.Transcription = ""
+	}
+	return before, after
+}
+
+// splitSegmentsAt делит сегменты на границе splitMs. Сегмент на границе делится по словам,
+// а без слов целиком достаётся стороне, где он начинается
+func splitSegmentsAt(segments []TranscriptSegment, splitMs int64) (before, after []TranscriptSegment) {
+	for _, seg := range segments {
+		switch {
+		case seg.End <= splitMs:
+			before = append(before, seg)
+		case seg.Start >= splitMs:
+			after = append(after, seg)
+		case len(seg.Words) == 0:
+			if seg.Start < splitMs {
+				before = append(before, seg)
+			} else {
+				after = append(after, seg)
+			}
+		default:
+			var head, tail []TranscriptWord
+			for _, w := range seg.Words {
+				if w.Start < splitMs {
+					head = append(head, w)
+				} else {
+					tail = append(tail, w)
+				}
+			}
+			if len(head) > 0 {
+				before = append(before, segmentFromWords(seg, head))
+			}
+			if len(tail) > 0 {
+				after = append(after, segmentFromWords(seg, tail))
+			}
+		}
+	}
+	return before, after
+}
+
+// segmentFromWords сегмент из части слов исходного сегмента
+func segmentFromWords(seg TranscriptSegment, words []TranscriptWord) TranscriptSegment {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = strings.TrimSpace(w.Text)
+	}
+	seg.Words = words
+	seg.Start = words[0].Start
+	seg.End = words[len(words)-1].End
+	seg.Text = strings.Join(texts, " ")
+	seg.Confidence = SegmentConfidence(words)
+	return seg
+}
+
+// clipRanges оставляет участки клиппинга, попадающие в [startMs, endMs]
+func clipRanges(clipping []ChannelClipping, startMs, endMs int64) []ChannelClipping {
+	var result []ChannelClipping
+	for _, channel := range clipping {
+		var ranges []ClippedRange
+		for _, r := range channel.Ranges {
+			r.StartMs, r.EndMs = max(r.StartMs, startMs), min(r.EndMs, endMs)
+			if r.EndMs > r.StartMs {
+				ranges = append(ranges, r)
+			}
+		}
+		if len(ranges) > 0 {
+			channel.Ranges = ranges
+			result = append(result, channel)
+		}
+	}
+	return result
+}
+
+// cutMP3 вырезает из MP3 участок [startMs, endMs) (endMs 0 - до конца файла)
+func cutMP3(inputPath, outputPath string, startMs, endMs int64) error {
+	if err := RequireFFmpeg(); err != nil {
+		return err
+	}
+
+	args := []string{"-y", "-ss", fmt.Sprintf("%.3f", float64(startMs)/1000), "-i", inputPath}
+	if endMs > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", float64(endMs-startMs)/1000))
+	}
+	args = append(args, MP3EncodeArgs(MP3TargetRecording)...)
+	args = append(args, outputPath)
+
+	output, err := exec.Command(getFFmpegPath(), args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg cut failed: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// timePtr возвращает указатель на копию t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,201 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitSession(t *testing.T) {
+	useFakeFFmpeg(t)
+
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := m.CreateSession(SessionConfig{Language: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.StopSession(); err != nil {
+		t.Fatal(err)
+	}
+	sess.Title = "Планёрка"
+	sess.TotalDuration = 10 * time.Second
+	if err := os.WriteFile(filepath.Join(sess.DataDir, "full.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sess.DataDir, speakerProfilesFile), []byte(`[{"SpeakerID":1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	speaker := SpeakerLabel(0)
+	words := []TranscriptWord{
+		{Start: 5500, End: 6000, Text: "раз", Speaker: speaker},
+		{Start: 6200, End: 7000, Text: "два", Speaker: speaker},
+		{Start: 7100, End: 7500, Text: "три", Speaker: speaker},
+	}
+	chunks := []*Chunk{
+		{ID: "c0", SessionID: sess.ID, Index: 0, StartMs: 0, EndMs: 5000, Duration: 5 * time.Second, Status: ChunkStatusCompleted,
+			Dialogue: []TranscriptSegment{{Start: 1000, End: 2000, Text: "привет", Speaker: SelfSpeakerLabel()}}},
+		{ID: "c1", SessionID: sess.ID, Index: 1, StartMs: 5000, EndMs: 10000, Duration: 5 * time.Second, Status: ChunkStatusCompleted,
+			Dialogue: []TranscriptSegment{{Start: 5500, End: 7500, Text: "раз два три", Speaker: speaker, Words: words}},
+			Clipping: []ChannelClipping{{Channel: "mono", Ranges: []ClippedRange{{StartMs: 5000, EndMs: 9000}}}}},
+	}
+	for _, chunk := range chunks {
+		if err := m.AddChunk(sess.ID, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Улучшенный слой, диалог с пунктуацией, summary, переименование и закрепление
+	improvedWords := []TranscriptWord{
+		{Start: 5500, End: 6000, Text: "Раз,", Speaker: speaker},
+		{Start: 6200, End: 7000, Text: "два,", Speaker: speaker},
+		{Start: 7100, End: 7500, Text: "три!", Speaker: speaker},
+	}
+	improved := []TranscriptSegment{
+		{Start: 1000, End: 2000, Text: "Привет!", Speaker: SelfSpeakerLabel()},
+		{Start: 5500, End: 7500, Text: "Раз, два, три!", Speaker: speaker, Words: improvedWords},
+	}
+	if err := m.SetDialogueLayer(sess.ID, DialogueLayerImproved, improved); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetPunctuatedDialogue(sess.ID, improved); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSessionSummary(sess.ID, "Итоги"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSessionPinned(sess.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RecordSpeakerRename(sess.ID, SpeakerRename{LocalID: 0, StandardName: speaker, OldName: speaker, NewName: "Анна"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Идёт ретранскрипция: разделение отклоняется, а сессия остаётся занятой только ею
+	release, err := m.TryMarkSessionBusy(sess.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.SplitSession(sess.ID, 6500); err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("busy session: err = %v", err)
+	}
+	release()
+	if m.SessionBusy(sess.ID) {
+		t.Error("session left busy after a rejected split")
+	}
+
+	for _, splitMs := range []int64{0, 10000} {
+		if _, _, err := m.SplitSession(sess.ID, splitMs); err == nil {
+			t.Errorf("split at %d ms accepted", splitMs)
+		}
+	}
+
+	// 6500 мс - внутри слова "два": граница сдвигается на его начало (6200 мс)
+	first, second, err := m.SplitSession(sess.ID, 6500)
+	if err != nil {
+		t.Fatalf("SplitSession: %v", err)
+	}
+	if _, err := m.GetSession(sess.ID); err == nil {
+		t.Error("original session is not deleted")
+	}
+	if first.Title != "Планёрка (1)" || second.Title != "Планёрка (2)" {
+		t.Errorf("titles = %q, %q", first.Title, second.Title)
+	}
+	if first.TotalDuration != 6200*time.Millisecond || second.TotalDuration != 3800*time.Millisecond {
+		t.Errorf("durations = %v, %v", first.TotalDuration, second.TotalDuration)
+	}
+	if !second.StartTime.Equal(sess.StartTime.Add(6200 * time.Millisecond)) {
+		t.Errorf("second part starts at %v", second.StartTime)
+	}
+
+	if len(first.Chunks) != 2 || len(second.Chunks) != 1 {
+		t.Fatalf("chunks = %d + %d, want 2 + 1", len(first.Chunks), len(second.Chunks))
+	}
+	head := first.Chunks[1]
+	if head.StartMs != 5000 || head.EndMs != 6200 || len(head.Dialogue) != 1 || head.Dialogue[0].Text != "Раз," {
+		t.Errorf("first half of split chunk: %+v", head)
+	}
+	tail := second.Chunks[0]
+	if tail.Index != 0 || tail.SessionID != second.ID || tail.StartMs != 0 || tail.EndMs != 3800 {
+		t.Errorf("second half of split chunk: index=%d start=%d end=%d", tail.Index, tail.StartMs, tail.EndMs)
+	}
+	if len(tail.Dialogue) != 1 {
+		t.Fatalf("second half dialogue: %+v", tail.Dialogue)
+	}
+	seg := tail.Dialogue[0]
+	if seg.Text != "два, три!" || seg.Start != 0 || seg.End != 1300 || seg.Words[1].Start != 900 {
+		t.Errorf("rebased segment: %+v", seg)
+	}
+	if r := tail.Clipping[0].Ranges[0]; r.StartMs != 0 || r.EndMs != 2800 {
+		t.Errorf("rebased clipping: %+v", r)
+	}
+
+	for _, part := range []struct {
+		sess *Session
+		arg  string
+	}{{first, "-t 6.200"}, {second, "-ss 6.200"}} {
+		audio, err := os.ReadFile(filepath.Join(part.sess.DataDir, "full.mp3"))
+		if err != nil || !strings.Contains(string(audio), part.arg) {
+			t.Errorf("%s full.mp3 = %q, %v", part.sess.ID, audio, err)
+		}
+		if _, err := os.Stat(filepath.Join(part.sess.DataDir, speakerProfilesFile)); err != nil {
+			t.Errorf("speaker profiles are not copied: %v", err)
+		}
+	}
+
+	// Слои, диалог с пунктуацией и остальное делятся по границе и переживают перезагрузку
+	reloaded, err := NewManager(m.dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []struct {
+		id         string
+		raw        []string
+		improved   []string
+		punctuated []string
+	}{
+		{first.ID, []string{"привет", "раз"}, []string{"Привет!", "Раз,"}, []string{"Привет!", "Раз,"}},
+		{second.ID, []string{"два три"}, []string{"два, три!"}, []string{"два, три!"}},
+	} {
+		sess, err := reloaded.GetSession(part.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sess.Pinned || sess.Summary != "Итоги" || len(sess.SpeakerRenames) != 1 || sess.ActiveDialogueLayer != DialogueLayerImproved {
+			t.Errorf("%s: pinned=%v summary=%q renames=%+v active=%q", part.id, sess.Pinned, sess.Summary, sess.SpeakerRenames, sess.ActiveDialogueLayer)
+		}
+		for _, layer := range []struct {
+			name string
+			want []string
+		}{{DialogueLayerRaw, part.raw}, {DialogueLayerImproved, part.improved}} {
+			chunks, err := reloaded.GetDialogueLayerChunks(part.id, layer.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var texts []string
+			for _, dialogue := range chunks {
+				for _, seg := range dialogue {
+					texts = append(texts, seg.Text)
+				}
+			}
+			if strings.Join(texts, "|") != strings.Join(layer.want, "|") {
+				t.Errorf("%s: layer %s = %q, want %q", part.id, layer.name, texts, layer.want)
+			}
+		}
+		var texts []string
+		for _, seg := range sess.PunctuatedDialogue {
+			texts = append(texts, seg.Text)
+		}
+		if strings.Join(texts, "|") != strings.Join(part.punctuated, "|") {
+			t.Errorf("%s: punctuated = %q, want %q", part.id, texts, part.punctuated)
+		}
+	}
+	if seg := second.PunctuatedDialogue[0]; seg.Start != 0 || seg.Words[0].Start != 0 {
+		t.Errorf("punctuated dialogue is not rebased: %+v", seg)
+	}
+}
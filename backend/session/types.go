@@ -1,6 +1,7 @@
 package session
 
 import (
+	"fmt"
 	"sync"
 	"time"
 )
@@ -10,6 +11,7 @@ type SessionStatus string
 
 const (
 	SessionStatusRecording SessionStatus = "recording"
+	SessionStatusPaused    SessionStatus = "paused" // Запись приостановлена, сессия остаётся активной
 	SessionStatusCompleted SessionStatus = "completed"
 	SessionStatusFailed    SessionStatus = "failed"
 )
@@ -37,22 +39,46 @@ type WaveformData struct {
 
 // Session представляет сессию записи
 type Session struct {
-	ID            string        `json:"id"`
-	StartTime     time.Time     `json:"startTime"`
-	EndTime       *time.Time    `json:"endTime,omitempty"`
-	Status        SessionStatus `json:"status"`
-	Language      string        `json:"language"`
-	Model         string        `json:"model"`
-	Title         string        `json:"title,omitempty"`
-	Tags          []string      `json:"tags,omitempty"` // User-defined tags for categorization
-	DataDir       string        `json:"dataDir"`
-	TotalDuration time.Duration `json:"totalDuration"`
-	SampleCount   int64         `json:"sampleCount"`
-	Summary       string        `json:"summary,omitempty"`  // AI-generated summary
-	Waveform      *WaveformData `json:"waveform,omitempty"` // Cached waveform data for visualization
+	ID            string          `json:"id"`
+	StartTime     time.Time       `json:"startTime"`
+	EndTime       *time.Time      `json:"endTime,omitempty"`
+	Status        SessionStatus   `json:"status"`
+	Language      string          `json:"language"`
+	Model         string          `json:"model"`
+	Title         string          `json:"title,omitempty"`
+	Tags          []string        `json:"tags,omitempty"` // User-defined tags for categorization
+	DataDir       string          `json:"dataDir"`
+	TotalDuration time.Duration   `json:"totalDuration"`
+	SampleCount   int64           `json:"sampleCount"`
+	Summary       string          `json:"summary,omitempty"`      // AI-generated summary
+	Waveform      *WaveformData   `json:"waveform,omitempty"`     // Cached waveform data for visualization
+	DiarizeMic    bool            `json:"diarizeMic,omitempty"`   // Диаризация MIC канала (несколько человек у одного микрофона)
+	SwapChannels  ChannelSwapMode `json:"swapChannels,omitempty"` // Перестановка MIC/SYS каналов (пусто - off)
+	MicOnly       bool            `json:"micOnly,omitempty"`      // Диктовка: распознаётся только микрофон, весь текст - "Вы"
+	Pinned        bool            `json:"pinned,omitempty"`       // Закреплена: не очищается политикой хранения
+	AudioDropped  bool            `json:"audioDropped,omitempty"` // Аудио удалено политикой хранения, осталась транскрипция
+
+	// PausedDuration суммарная длительность пауз записи (pause_session), не входит в TotalDuration
+	PausedDuration time.Duration `json:"pausedDuration,omitempty"`
+
+	// PunctuatedDialogue альтернативный диалог с восстановленной LLM пунктуацией (restore_punctuation)
+	PunctuatedDialogue []TranscriptSegment `json:"punctuatedDialogue,omitempty"`
+
+	// SpeakerRenames история переименований спикеров (для undo_speaker_rename)
+	SpeakerRenames []SpeakerRename `json:"speakerRenames,omitempty"`
+
+	// DialogueLayers сохранённые варианты диалога ("raw", "improved", ...), ActiveDialogueLayer - показанный в чанках.
+	// StaleDialogueLayers - варианты, построенные по прежнему результату ASR перераспознанных чанков
+	DialogueLayers      []string `json:"dialogueLayers,omitempty"`
+	StaleDialogueLayers []string `json:"staleDialogueLayers,omitempty"`
+	ActiveDialogueLayer string   `json:"activeDialogueLayer,omitempty"`
 
 	Chunks []*Chunk `json:"chunks"`
 
+	keywordIndex *KeywordIndex     // Кеш индекса ключевых терминов (см. GetKeywordIndex)
+	layers       *dialogueLayerSet // Варианты диалога (dialogue_layers.json)
+	pausedAt     time.Time         // Начало текущей паузы записи (SessionStatusPaused)
+
 	mu sync.RWMutex `json:"-"`
 }
 
@@ -72,6 +98,12 @@ type TranscriptSegment struct {
 	Text    string           `json:"text"`            // Текст сегмента
 	Speaker string           `json:"speaker"`         // "mic" или "sys"
 	Words   []TranscriptWord `json:"words,omitempty"` // Слова с точными timestamps (word-level)
+	// Confidence средняя вероятность слов (SegmentConfidence): NoConfidence - модель не даёт P,
+	// 0 - не вычислялась (сессии, записанные до появления поля)
+	Confidence float32 `json:"confidence,omitempty"`
+	// Overlap наложение речи: одновременно со Speaker говорит OverlapSpeaker (по диаризации)
+	Overlap        bool   `json:"overlap,omitempty"`
+	OverlapSpeaker string `json:"overlapSpeaker,omitempty"`
 }
 
 // Chunk представляет фрагмент аудио для распознавания
@@ -111,6 +143,27 @@ type Chunk struct {
 	Error               string     `json:"error,omitempty"`
 	ProcessingStartTime *time.Time `json:"-"`                        // Время начала обработки (не сериализуется)
 	ProcessingTime      int64      `json:"processingTime,omitempty"` // Время обработки в миллисекундах
+	RealTimeFactor      float64    `json:"realTimeFactor,omitempty"` // ProcessingTime / длительность чанка
+
+	// Фактически использованный метод VAD по каналам (energy, если Silero недоступен или упал)
+	MicVADMethod VADMethod `json:"micVadMethod,omitempty"`
+	SysVADMethod VADMethod `json:"sysVadMethod,omitempty"`
+	VADFallback  bool      `json:"vadFallback,omitempty"` // Хотя бы один канал откатился с Silero на energy
+
+	// Clipping перегруженные участки входа по каналам (см. DetectClipping)
+	Clipping []ChannelClipping `json:"clipping,omitempty"`
+
+	// SpeechRatio доля аудио чанка, где VAD нашёл речь (nil - VAD не запускался)
+	SpeechRatio *float64 `json:"speechRatio,omitempty"`
+	// Quality оценка качества распознавания 0-100 для быстрого поиска мест на проверку (см. QualityWeights)
+	Quality *ChunkQuality `json:"quality,omitempty"`
+
+	// Model модель, которой чанк распознан последний раз
+	Model string `json:"model,omitempty"`
+
+	// Способ распознавания чанка и применение гибридной транскрипции (для отображения вместе с ProcessingTime)
+	TranscriptionPath TranscriptionPath `json:"transcriptionPath,omitempty"`
+	HybridApplied     bool              `json:"hybridApplied,omitempty"`
 }
 
 // VADMode режим Voice Activity Detection
@@ -132,16 +185,43 @@ const (
 	VADMethodAuto   VADMethod = "auto"   // Автовыбор: Silero если доступен, иначе Energy
 )
 
+// ChannelSwapMode режим перестановки каналов стерео записи (MIC слева, SYS справа)
+type ChannelSwapMode string
+
+const (
+	ChannelSwapAuto ChannelSwapMode = "auto" // Автоопределение перепутанных каналов
+	ChannelSwapOn   ChannelSwapMode = "on"   // Всегда менять каналы местами
+	ChannelSwapOff  ChannelSwapMode = "off"  // Никогда не менять каналы (по умолчанию)
+)
+
 // SessionConfig конфигурация для создания сессии
 type SessionConfig struct {
-	Language      string
-	Model         string
-	MicDevice     string
-	SystemDevice  string
-	CaptureSystem bool
-	UseNative     bool
-	VADMode       VADMode   // Режим VAD (auto, compression, per-region, off)
-	VADMethod     VADMethod // Метод детекции речи (energy, silero, auto)
+	Language         string
+	Model            string
+	MicDevice        string
+	SystemDevice     string
+	CaptureSystem    bool
+	UseNative        bool
+	VADMode          VADMode         // Режим VAD (auto, compression, per-region, off)
+	VADMethod        VADMethod       // Метод детекции речи (energy, silero, auto)
+	DiarizeMic       bool            // Диаризировать MIC канал (по умолчанию MIC = один спикер "Вы")
+	MicOnly          bool            // Только микрофон (диктовка): без системного звука, SYS канала и диаризации
+	CaptureApp       string          // Bundle ID приложения для захвата системного звука (ScreenCaptureKit), пусто - весь звук
+	SwapChannels     ChannelSwapMode // Перестановка MIC/SYS каналов (auto, on, off; пусто - off)
+	RecordRawCapture bool            // Сохранять сырой поток кадров захвата в raw_capture.bin (для отладки)
+	VoiceMixing      string          // Стратегия микширования каналов Voice Isolation (paired, padded, resampled; пусто - paired)
+	ChunkDurationMs  int64           // Длительность чанков для распознавания (0 - по умолчанию, см. ValidateChunkDurationMs)
+
+	// Отдельные настройки VAD для каналов стерео записи (пусто - общий VADMethod)
+	MicVAD ChannelVADConfig
+	SysVAD ChannelVADConfig
+}
+
+// ChannelVADConfig настройки VAD одного канала (MIC или SYS)
+type ChannelVADConfig struct {
+	Method    VADMethod       // Метод детекции речи (пусто - общий метод сессии)
+	Threshold float64         // Порог: вероятность речи для Silero (0-1), RMS для energy; 0 - по умолчанию
+	Energy    EnergyVADConfig // Параметры energy VAD (Threshold > 0 переопределяет EnergyThreshold)
 }
 
 // VADConfig конфигурация Voice Activity Detection
@@ -187,6 +267,42 @@ func FixedIntervalConfig() VADConfig {
 	}
 }
 
+// DictationVADConfig возвращает конфигурацию для диктовки (SessionConfig.MicOnly):
+// короткие чанки по паузам с первых секунд, чтобы текст появлялся быстрее
+func DictationVADConfig() VADConfig {
+	config := DefaultVADConfig()
+	config.SilenceDuration = 700 * time.Millisecond
+	config.MinChunkDuration = 5 * time.Second
+	config.MaxChunkDuration = 30 * time.Second
+	config.ChunkingStartDelay = 0
+	return config
+}
+
+// Допустимая длительность чанков, задаваемая пользователем (SessionConfig.ChunkDurationMs)
+const (
+	MinChunkDurationMs = 5000
+	MaxChunkDurationMs = 120000
+)
+
+// ValidateChunkDurationMs проверяет длительность чанков (0 - по умолчанию)
+func ValidateChunkDurationMs(ms int64) error {
+	if ms != 0 && (ms < MinChunkDurationMs || ms > MaxChunkDurationMs) {
+		return fmt.Errorf("chunk duration must be between %d and %d ms, got %d", MinChunkDurationMs, MaxChunkDurationMs, ms)
+	}
+	return nil
+}
+
+// WithChunkDuration возвращает конфигурацию с чанками длительностью d: фиксированный интервал равен d,
+// с VAD чанк режется по первой паузе после d (не позже 2d). Нарезка начинается сразу: чанк
+// и так не бывает короче d
+func (c VADConfig) WithChunkDuration(d time.Duration) VADConfig {
+	c.FixedChunkDuration = d
+	c.MinChunkDuration = d
+	c.MaxChunkDuration = 2 * d
+	c.ChunkingStartDelay = 0
+	return c
+}
+
 // SampleRate константа частоты дискретизации для записи
 // Используем 24kHz - это native rate Voice Isolation микрофона на macOS.
 // При 48kHz требуется ресемплинг, который создаёт рассинхронизацию и артефакты.
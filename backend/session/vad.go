@@ -125,25 +125,78 @@ type SpeechRegion struct {
 	EndMs   int64 // Конец речи в миллисекундах
 }
 
+// DefaultEnergyVADThreshold минимальный RMS порог речи для energy VAD
+const DefaultEnergyVADThreshold = 0.005
+
+// EnergyVADConfig параметры energy VAD (нулевые поля - значения по умолчанию)
+type EnergyVADConfig struct {
+	EnergyThreshold float64 // Минимальный RMS порог речи (может быть поднят адаптивно)
+	MinSilenceMs    int64   // Длительность тишины, завершающая регион речи
+	MinSpeechMs     int64   // Минимальная длина региона речи, более короткие отбрасываются
+	HangoverMs      int64   // Сколько держать регион после конца речи (padding после речи)
+}
+
+// DefaultEnergyVADConfig возвращает параметры energy VAD по умолчанию
+func DefaultEnergyVADConfig() EnergyVADConfig {
+	return EnergyVADConfig{
+		EnergyThreshold: DefaultEnergyVADThreshold,
+		MinSilenceMs:    300,
+		MinSpeechMs:     100,
+		HangoverMs:      100,
+	}
+}
+
+// withDefaults заполняет нулевые поля значениями по умолчанию
+func (c EnergyVADConfig) withDefaults() EnergyVADConfig {
+	defaults := DefaultEnergyVADConfig()
+	if c.EnergyThreshold <= 0 {
+		c.EnergyThreshold = defaults.EnergyThreshold
+	}
+	if c.MinSilenceMs <= 0 {
+		c.MinSilenceMs = defaults.MinSilenceMs
+	}
+	if c.MinSpeechMs <= 0 {
+		c.MinSpeechMs = defaults.MinSpeechMs
+	}
+	if c.HangoverMs <= 0 {
+		c.HangoverMs = defaults.HangoverMs
+	}
+	return c
+}
+
 // DetectSpeechRegions находит все участки речи в аудио
 // Возвращает список регионов с началом и концом каждого участка речи
 func DetectSpeechRegions(samples []float32, sampleRate int) []SpeechRegion {
+	return DetectSpeechRegionsWithConfig(samples, sampleRate, DefaultEnergyVADConfig())
+}
+
+// DetectSpeechRegionsWithEnergyThreshold находит участки речи с указанным минимальным RMS порогом
+// (порог может быть поднят адаптивно по средней энергии записи)
+func DetectSpeechRegionsWithEnergyThreshold(samples []float32, sampleRate int, energyThreshold float64) []SpeechRegion {
+	return DetectSpeechRegionsWithConfig(samples, sampleRate, EnergyVADConfig{EnergyThreshold: energyThreshold})
+}
+
+// DetectSpeechRegionsWithConfig находит участки речи energy VAD с указанными порогом, длительностью
+// тишины между регионами, минимальной длиной региона и hangover после конца речи
+func DetectSpeechRegionsWithConfig(samples []float32, sampleRate int, config EnergyVADConfig) []SpeechRegion {
 	if len(samples) == 0 {
 		return nil
 	}
+	config = config.withDefaults()
+	energyThreshold := config.EnergyThreshold
 
 	const (
-		windowMs        = 20 // Размер окна для анализа (20 мс)
-		energyThreshold = 0.005
-		confirmWindows  = 3   // Окон подряд для подтверждения начала речи
-		silenceWindows  = 15  // Окон тишины для завершения региона (300ms)
-		minRegionMs     = 100 // Минимальная длина региона речи (100ms)
-		// Speech padding: добавляем буфер до и после детектированной речи
+		windowMs       = 20 // Размер окна для анализа (20 мс)
+		confirmWindows = 3  // Окон подряд для подтверждения начала речи
+		// Speech padding: добавляем буфер до детектированной речи
 		// Это необходимо для захвата глухих согласных (С, Т, К, П...) которые имеют низкую энергию
 		// 500ms padding необходим для захвата тихих слов типа "Как" перед громкими "говорится"
 		speechPaddingStartMs = 500 // Padding перед началом речи (500ms)
-		speechPaddingEndMs   = 100 // Padding после конца речи (100ms)
 	)
+	// Окон тишины для завершения региона
+	silenceWindows := max(int(config.MinSilenceMs/windowMs), 1)
+	minRegionMs := config.MinSpeechMs
+	speechPaddingEndMs := config.HangoverMs
 
 	windowSamples := (sampleRate * windowMs) / 1000
 	if windowSamples <= 0 {
@@ -425,32 +478,96 @@ func MapRealTimeToCompressedTime(realMs int64, speechRegions []SpeechRegion) int
 
 // MapWhisperTimeToRealTime маппит одиночный таймстемп Whisper на реальное время
 // Используется для маппинга word-level timestamps
+// На границе двух регионов таймстемп относится к концу предыдущего региона (семантика "конец слова")
 func MapWhisperTimeToRealTime(whisperMs int64, speechRegions []SpeechRegion) int64 {
 	if len(speechRegions) == 0 {
 		return whisperMs
 	}
+	return mapCompressedTime(whisperMs, speechRegions, false)
+}
+
+// mapCompressedTime маппит время в сжатом аудио на реальное время
+// preferNext - на границе регионов относить точку к началу следующего региона (для начала слова/сегмента)
+// Регионы нулевой длины пропускаются, отрицательное время считается нулём,
+// время за пределами речи прижимается к концу последнего региона
+func mapCompressedTime(whisperMs int64, speechRegions []SpeechRegion, preferNext bool) int64 {
+	if whisperMs < 0 {
+		whisperMs = 0
+	}
 
 	// Накапливаем время речи до нужной позиции
 	var accumulatedSpeech int64
-	var realTimeMs int64 = 0
+	realTimeMs := speechRegions[0].StartMs
 
 	for _, region := range speechRegions {
 		regionDuration := region.EndMs - region.StartMs
+		if regionDuration <= 0 {
+			continue
+		}
 
-		if accumulatedSpeech+regionDuration >= whisperMs {
+		regionEnd := accumulatedSpeech + regionDuration
+		if whisperMs < regionEnd || (whisperMs == regionEnd && !preferNext) {
 			// Нашли регион, в котором находится этот таймстемп
-			offsetInRegion := whisperMs - accumulatedSpeech
-			realTimeMs = region.StartMs + offsetInRegion
-			return realTimeMs
+			return region.StartMs + (whisperMs - accumulatedSpeech)
 		}
 
-		accumulatedSpeech += regionDuration
+		accumulatedSpeech = regionEnd
 		realTimeMs = region.EndMs // На случай если выйдем за пределы
 	}
 
 	return realTimeMs
 }
 
+// clampToSpeechRegion прижимает реальное время к ближайшей границе региона речи,
+// если оно попало в паузу или за пределы регионов. Возвращает true если потребовалась коррекция.
+// preferNext - точку в паузе переносить на начало следующего региона (иначе на конец предыдущего)
+func clampToSpeechRegion(realMs int64, speechRegions []SpeechRegion, preferNext bool) (int64, bool) {
+	var prev *SpeechRegion
+	for i := range speechRegions {
+		region := &speechRegions[i]
+		if region.EndMs <= region.StartMs {
+			continue
+		}
+		if realMs >= region.StartMs && realMs <= region.EndMs {
+			return realMs, false
+		}
+		if realMs < region.StartMs {
+			if preferNext || prev == nil {
+				return region.StartMs, true
+			}
+			return prev.EndMs, true
+		}
+		prev = region
+	}
+
+	if prev == nil {
+		return realMs, false // Нет непустых регионов
+	}
+	return prev.EndMs, true
+}
+
+// RestoreTimestampRange восстанавливает реальное время для пары start/end из сжатого аудио
+// и проверяет, что результат лежит внутри регионов речи:
+// начало на границе регионов относится к следующему региону, конец - к предыдущему,
+// точки в паузах прижимаются к ближайшему региону, end не может быть меньше start.
+// Возвращает true если потребовалась коррекция.
+func RestoreTimestampRange(startMs, endMs int64, speechRegions []SpeechRegion) (int64, int64, bool) {
+	if len(speechRegions) == 0 {
+		return startMs, endMs, false
+	}
+
+	realStart, startClamped := clampToSpeechRegion(mapCompressedTime(startMs, speechRegions, true), speechRegions, true)
+	realEnd, endClamped := clampToSpeechRegion(mapCompressedTime(endMs, speechRegions, false), speechRegions, false)
+
+	clamped := startClamped || endClamped || startMs < 0
+	if realEnd < realStart {
+		realEnd = realStart
+		clamped = true
+	}
+
+	return realStart, realEnd, clamped
+}
+
 // CompressSpeechResult результат сжатия аудио
 type CompressSpeechResult struct {
 	CompressedSamples  []float32      // Сжатое аудио (только речь)
@@ -570,11 +687,16 @@ func RestoreSegmentTimestamps(segments []TranscriptSegment, regions []SpeechRegi
 		return segments
 	}
 
+	clampedCount := 0
 	restored := make([]TranscriptSegment, len(segments))
 	for i, seg := range segments {
+		start, end, clamped := RestoreTimestampRange(seg.Start, seg.End, regions)
+		if clamped {
+			clampedCount++
+		}
 		restored[i] = TranscriptSegment{
-			Start:   MapWhisperTimeToRealTime(seg.Start, regions),
-			End:     MapWhisperTimeToRealTime(seg.End, regions),
+			Start:   start,
+			End:     end,
 			Text:    seg.Text,
 			Speaker: seg.Speaker,
 		}
@@ -583,9 +705,13 @@ func RestoreSegmentTimestamps(segments []TranscriptSegment, regions []SpeechRegi
 		if len(seg.Words) > 0 {
 			restored[i].Words = make([]TranscriptWord, len(seg.Words))
 			for j, word := range seg.Words {
+				wordStart, wordEnd, wordClamped := RestoreTimestampRange(word.Start, word.End, regions)
+				if wordClamped {
+					clampedCount++
+				}
 				restored[i].Words[j] = TranscriptWord{
-					Start:   MapWhisperTimeToRealTime(word.Start, regions),
-					End:     MapWhisperTimeToRealTime(word.End, regions),
+					Start:   wordStart,
+					End:     wordEnd,
 					Text:    word.Text,
 					P:       word.P,
 					Speaker: word.Speaker,
@@ -594,5 +720,9 @@ func RestoreSegmentTimestamps(segments []TranscriptSegment, regions []SpeechRegi
 		}
 	}
 
+	if clampedCount > 0 {
+		log.Printf("RestoreSegmentTimestamps: clamped %d timestamps to speech region bounds", clampedCount)
+	}
+
 	return restored
 }
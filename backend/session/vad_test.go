@@ -0,0 +1,199 @@
+package session
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMapWhisperTimeToRealTime проверяет маппинг времени сжатого аудио на реальное
+func TestMapWhisperTimeToRealTime(t *testing.T) {
+	regions := []SpeechRegion{
+		{StartMs: 1000, EndMs: 2000},
+		{StartMs: 3000, EndMs: 3500},
+		{StartMs: 5000, EndMs: 6000},
+	}
+	withZeroLength := []SpeechRegion{
+		{StartMs: 1000, EndMs: 2000},
+		{StartMs: 2500, EndMs: 2500},
+		{StartMs: 3000, EndMs: 4000},
+	}
+
+	tests := []struct {
+		name      string
+		whisperMs int64
+		regions   []SpeechRegion
+		want      int64
+	}{
+		{"no regions", 700, nil, 700},
+		{"start of first region", 0, regions, 1000},
+		{"inside first region", 500, regions, 1500},
+		{"boundary belongs to previous region", 1000, regions, 2000},
+		{"just after boundary", 1001, regions, 3001},
+		{"inside last region", 2000, regions, 5500},
+		{"end of speech", 2500, regions, 6000},
+		{"trailing silence clamps to last region", 3200, regions, 6000},
+		{"negative time clamps to first region", -100, regions, 1000},
+		{"zero-length region skipped", 1001, withZeroLength, 3001},
+		{"boundary before zero-length region", 1000, withZeroLength, 2000},
+		{"only zero-length regions", 300, []SpeechRegion{{StartMs: 500, EndMs: 500}}, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapWhisperTimeToRealTime(tt.whisperMs, tt.regions); got != tt.want {
+				t.Errorf("MapWhisperTimeToRealTime(%d) = %d, want %d", tt.whisperMs, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRestoreTimestampRange проверяет восстановление пар start/end и аудит границ регионов
+func TestRestoreTimestampRange(t *testing.T) {
+	regions := []SpeechRegion{
+		{StartMs: 1000, EndMs: 2000},
+		{StartMs: 3000, EndMs: 3500},
+		{StartMs: 5000, EndMs: 6000},
+	}
+
+	tests := []struct {
+		name        string
+		start, end  int64
+		wantStart   int64
+		wantEnd     int64
+		wantClamped bool
+	}{
+		{"word inside region", 100, 400, 1100, 1400, false},
+		{"word starting at boundary goes to next region", 1000, 1200, 3000, 3200, false},
+		{"word ending at boundary stays in previous region", 800, 1000, 1800, 2000, false},
+		{"word spanning regions", 900, 1100, 1900, 3100, false},
+		{"word in trailing silence", 2600, 2900, 6000, 6000, false},
+		{"negative start clamped", -50, 100, 1000, 1100, true},
+		{"end before start fixed", 400, 300, 1400, 1400, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, clamped := RestoreTimestampRange(tt.start, tt.end, regions)
+			if start != tt.wantStart || end != tt.wantEnd || clamped != tt.wantClamped {
+				t.Errorf("RestoreTimestampRange(%d, %d) = (%d, %d, %v), want (%d, %d, %v)",
+					tt.start, tt.end, start, end, clamped, tt.wantStart, tt.wantEnd, tt.wantClamped)
+			}
+		})
+	}
+}
+
+// TestClampToSpeechRegion проверяет прижатие точек из пауз к границам регионов
+func TestClampToSpeechRegion(t *testing.T) {
+	regions := []SpeechRegion{
+		{StartMs: 1000, EndMs: 2000},
+		{StartMs: 3000, EndMs: 3500},
+	}
+
+	tests := []struct {
+		name        string
+		realMs      int64
+		preferNext  bool
+		want        int64
+		wantClamped bool
+	}{
+		{"inside region", 1500, false, 1500, false},
+		{"on region edge", 2000, true, 2000, false},
+		{"before first region", 500, false, 1000, true},
+		{"gap as end", 2500, false, 2000, true},
+		{"gap as start", 2500, true, 3000, true},
+		{"after last region", 4000, true, 3500, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clamped := clampToSpeechRegion(tt.realMs, regions, tt.preferNext)
+			if got != tt.want || clamped != tt.wantClamped {
+				t.Errorf("clampToSpeechRegion(%d, %v) = (%d, %v), want (%d, %v)",
+					tt.realMs, tt.preferNext, got, clamped, tt.want, tt.wantClamped)
+			}
+		})
+	}
+}
+
+// TestMapRealTimeToCompressedTime_RoundTrip проверяет обратный маппинг
+func TestMapRealTimeToCompressedTime_RoundTrip(t *testing.T) {
+	regions := []SpeechRegion{
+		{StartMs: 1000, EndMs: 2000},
+		{StartMs: 3000, EndMs: 3500},
+		{StartMs: 5000, EndMs: 6000},
+	}
+
+	for _, compressed := range []int64{0, 250, 999, 1200, 1499, 1700, 2499} {
+		real := MapWhisperTimeToRealTime(compressed, regions)
+		if back := MapRealTimeToCompressedTime(real, regions); back != compressed {
+			t.Errorf("round trip %d -> %d -> %d", compressed, real, back)
+		}
+	}
+
+	// Точка в паузе маппится на конец предыдущего региона в сжатом времени
+	if got := MapRealTimeToCompressedTime(2500, regions); got != 1000 {
+		t.Errorf("MapRealTimeToCompressedTime(2500) = %d, want 1000", got)
+	}
+}
+
+func TestDetectSpeechRegionsWithEnergyThreshold(t *testing.T) {
+	const sampleRate = 16000
+	// 1с тишины, 1с тихого сигнала (RMS ~0.014), 1с тишины
+	samples := make([]float32, 3*sampleRate)
+	for i := sampleRate; i < 2*sampleRate; i++ {
+		samples[i] = 0.02 * float32(math.Sin(2*math.Pi*300*float64(i)/sampleRate))
+	}
+
+	if regions := DetectSpeechRegionsWithEnergyThreshold(samples, sampleRate, DefaultEnergyVADThreshold); len(regions) != 1 {
+		t.Fatalf("default threshold: got %d regions, want 1", len(regions))
+	}
+	if regions := DetectSpeechRegionsWithEnergyThreshold(samples, sampleRate, 0.05); len(regions) != 0 {
+		t.Errorf("strict threshold: got %d regions, want 0", len(regions))
+	}
+}
+
+// TestDetectSpeechRegionsWithConfig проверяет сдвиг границ регионов при изменении параметров energy VAD
+func TestDetectSpeechRegionsWithConfig(t *testing.T) {
+	const sampleRate = 16000
+	// Тишина, громкий тон 1000-2000 мс, пауза 1 с, тихий тон 3000-3500 мс (RMS ~0.035), тишина до 6 с
+	samples := make([]float32, 6*sampleRate)
+	tone := func(fromMs, toMs int, amplitude float64) {
+		for i := fromMs * sampleRate / 1000; i < toMs*sampleRate/1000; i++ {
+			samples[i] = float32(amplitude * math.Sin(2*math.Pi*300*float64(i)/sampleRate))
+		}
+	}
+	tone(1000, 2000, 0.5)
+	tone(3000, 3500, 0.05)
+
+	tests := []struct {
+		name   string
+		config EnergyVADConfig
+		want   []SpeechRegion
+	}{
+		{"defaults", EnergyVADConfig{}, []SpeechRegion{{500, 2080}, {2500, 3580}}},
+		{"long silence merges regions", EnergyVADConfig{MinSilenceMs: 1200}, []SpeechRegion{{500, 3580}}},
+		{"hangover extends region ends", EnergyVADConfig{HangoverMs: 300}, []SpeechRegion{{500, 2280}, {2500, 3780}}},
+		{"min speech drops short region", EnergyVADConfig{MinSpeechMs: 600}, []SpeechRegion{{500, 2080}}},
+		{"threshold drops quiet region", EnergyVADConfig{EnergyThreshold: 0.1}, []SpeechRegion{{500, 2080}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectSpeechRegionsWithConfig(samples, sampleRate, tt.config)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got regions %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got regions %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	// Обёртки без параметров совпадают с конфигурацией по умолчанию
+	if got := DetectSpeechRegions(samples, sampleRate); len(got) != 2 || got[1] != (SpeechRegion{2500, 3580}) {
+		t.Errorf("DetectSpeechRegions = %v", got)
+	}
+}
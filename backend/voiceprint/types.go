@@ -46,6 +46,22 @@ type SessionSpeaker struct {
 	SegmentCount  int       `json:"segmentCount"`  // Количество сегментов речи
 	TotalDuration float32   `json:"totalDuration"` // Общая длительность речи (сек)
 	HasSample     bool      `json:"hasSample"`     // Есть ли аудио сэмпл для воспроизведения
+
+	// Стабильность: среднее сходство embeddings спикера между чанками (0 - недостаточно данных)
+	Stability        float32 `json:"stability,omitempty"`
+	StabilitySamples int     `json:"stabilitySamples,omitempty"` // Сколько embeddings учтено
+	LowStability     bool    `json:"lowStability,omitempty"`     // Метка ненадёжна, вероятно нужно ручное объединение
+
+	// Сопоставление спикера между чанками: с каким сходством он узнан в каждом чанке
+	Matches            []SpeakerChunkMatch `json:"matches,omitempty"`
+	MinMatchSimilarity float32             `json:"minMatchSimilarity,omitempty"` // Худшее сходство среди узнанных чанков
+}
+
+// SpeakerChunkMatch сопоставление спикера чанка с профилем спикера сессии
+type SpeakerChunkMatch struct {
+	ChunkIndex int     `json:"chunkIndex"`
+	Similarity float32 `json:"similarity"`    // Косинусное сходство с профилем; для нового спикера - лучшее среди отвергнутых
+	New        bool    `json:"new,omitempty"` // Совпадения выше порога нет - спикер заведён в этом чанке
 }
 
 // SpeakerMapping маппинг спикеров для хранения в session.json
@@ -63,6 +79,9 @@ const (
 	ThresholdMin    float32 = 0.50 // Минимальный порог для любого matching
 )
 
+// StabilityLow порог стабильности спикера сессии, ниже которого метка считается ненадёжной
+const StabilityLow float32 = 0.60
+
 // GetConfidence возвращает уровень уверенности для similarity
 func GetConfidence(similarity float32) string {
 	switch {
@@ -944,7 +944,12 @@ function App() {
                             setFullTranscriptionStatus(null);
                             setFullTranscriptionError(null);
                             setIsCancellingTranscription(false);
-                            addLog('Full transcription cancelled');
+                            // Уже обработанные чанки сохранены - показываем частичный результат
+                            if (msg.session) {
+                                setSelectedSession(msg.session);
+                                wsRef.current?.send(JSON.stringify({ type: 'get_session_speakers', sessionId: msg.session.id }));
+                            }
+                            addLog(`Full transcription cancelled: ${msg.data || ''}`);
                             break;
 
                         // === Audio Import ===
@@ -5124,6 +5129,8 @@ const BatchExportModal: React.FC<{
     onExportComplete: () => void;
 }> = ({ sessionIds, onClose, onExportComplete }) => {
     const [format, setFormat] = useState<'txt' | 'srt' | 'vtt' | 'json' | 'md'>('txt');
+    const [perSpeaker, setPerSpeaker] = useState(false); // Отдельный файл на каждого спикера
+    const [splitSentences, setSplitSentences] = useState(false); // Одно предложение на реплику
     const [isExporting, setIsExporting] = useState(false);
 
     const handleExport = async () => {
@@ -5132,7 +5139,7 @@ const BatchExportModal: React.FC<{
             const response = await fetch(`http://localhost:${process.env.AIWISPER_HTTP_PORT || 18080}/api/export/batch`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ sessionIds, format }),
+                body: JSON.stringify({ sessionIds, format, perSpeaker, splitSentences }),
             });
 
             if (!response.ok) {
@@ -5251,6 +5258,26 @@ const BatchExportModal: React.FC<{
                     </div>
                 </div>
 
+                <label style={{ display: 'flex', alignItems: 'center', gap: '8px', marginBottom: '10px', fontSize: '0.9rem', color: 'var(--text-primary)', cursor: 'pointer' }}>
+                    <input
+                        type="checkbox"
+                        checked={perSpeaker}
+                        onChange={(e) => setPerSpeaker(e.target.checked)}
+                        style={{ accentColor: 'var(--primary)' }}
+                    />
+                    Отдельный файл для каждого спикера
+                </label>
+
+                <label style={{ display: 'flex', alignItems: 'center', gap: '8px', marginBottom: '20px', fontSize: '0.9rem', color: 'var(--text-primary)', cursor: 'pointer' }}>
+                    <input
+                        type="checkbox"
+                        checked={splitSentences}
+                        onChange={(e) => setSplitSentences(e.target.checked)}
+                        style={{ accentColor: 'var(--primary)' }}
+                    />
+                    Разбить реплики по предложениям
+                </label>
+
                 <div style={{ display: 'flex', gap: '10px', justifyContent: 'flex-end' }}>
                     <button
                         onClick={onClose}
@@ -238,6 +238,13 @@ export const MainLayout: React.FC<MainLayoutProps> = ({ addLog }) => {
             ));
         });
 
+        const unsubSpeakerRenameUndone = subscribe('speaker_rename_undone', (msg: any) => {
+            // Прежнее имя могло объединиться с другими вариантами - перечитываем список целиком
+            if (msg.sessionId) {
+                sendMessage({ type: 'get_session_speakers', sessionId: msg.sessionId });
+            }
+        });
+
         const unsubSpeakersMerged = subscribe('speakers_merged', (msg: any) => {
             console.log('[MainLayout] Speakers merged:', msg);
             // Запрашиваем обновлённый список спикеров после объединения
@@ -280,6 +287,7 @@ export const MainLayout: React.FC<MainLayoutProps> = ({ addLog }) => {
         return () => {
             unsubSpeakers();
             unsubSpeakerRenamed();
+            unsubSpeakerRenameUndone();
             unsubSpeakersMerged();
             unsubChunkTranscribed();
             unsubDiarizationStatus();
@@ -330,6 +338,12 @@ export const MainLayout: React.FC<MainLayoutProps> = ({ addLog }) => {
         console.log(`[MainLayout] Renaming speaker ${localId} to "${newName}"${saveAsVoiceprint ? ' (saving voiceprint)' : ''}`);
     }, [selectedSession, sendMessage]);
 
+    // Undo last speaker rename handler
+    const handleUndoSpeakerRename = useCallback(() => {
+        if (!selectedSession) return;
+        sendMessage({ type: 'undo_speaker_rename', sessionId: selectedSession.id });
+    }, [selectedSession, sendMessage]);
+
     // Merge speakers handler
     const handleMergeSpeakers = useCallback((
         sourceSpeakerIds: number[],
@@ -835,6 +849,7 @@ export const MainLayout: React.FC<MainLayoutProps> = ({ addLog }) => {
                                 sessionSpeakers={sessionSpeakers}
                                 onRetranscribeAll={handleRetranscribeAll}
                                 onRenameSpeaker={handleRenameSpeaker}
+                                onUndoSpeakerRename={handleUndoSpeakerRename}
                                 onMergeSpeakers={handleMergeSpeakers}
                                 onPlaySpeakerSample={handlePlaySpeakerSample}
                                 onStopSpeakerSample={handleStopSpeakerSample}
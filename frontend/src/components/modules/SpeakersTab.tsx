@@ -5,6 +5,7 @@ interface SpeakersTabProps {
     sessionId: string;
     speakers: SessionSpeaker[];
     onRename: (localId: number, name: string, saveAsVoiceprint: boolean) => void;
+    onUndoRename?: () => void;
     onMergeSpeakers?: (sourceSpeakerIds: number[], targetSpeakerId: number, newName: string, mergeEmbeddings: boolean, saveAsVoiceprint: boolean) => void;
     onPlaySample?: (localId: number) => void;
     onStopSample?: () => void;
@@ -381,6 +382,7 @@ export default function SpeakersTab({
     sessionId: _sessionId,
     speakers,
     onRename,
+    onUndoRename,
     onMergeSpeakers,
     onPlaySample,
     onStopSample,
@@ -515,23 +517,44 @@ export default function SpeakersTab({
                     )}
                 </div>
 
-                {/* Кнопка режима выбора / отмены */}
-                {canMerge && onMergeSpeakers && (
-                    <button
-                        onClick={isSelectionMode ? handleCancelSelection : () => setIsSelectionMode(true)}
-                        style={{
-                            padding: '0.4rem 0.8rem',
-                            fontSize: '0.8rem',
-                            borderRadius: 'var(--radius-md)',
-                            border: '1px solid var(--border)',
-                            backgroundColor: isSelectionMode ? 'var(--danger)' : 'var(--surface-strong)',
-                            color: isSelectionMode ? 'white' : 'var(--text-secondary)',
-                            cursor: 'pointer',
-                        }}
-                    >
-                        {isSelectionMode ? 'Отмена' : 'Объединить'}
-                    </button>
-                )}
+                <div style={{ display: 'flex', gap: '0.5rem' }}>
+                    {/* Отмена последнего переименования */}
+                    {onUndoRename && !isSelectionMode && (
+                        <button
+                            onClick={onUndoRename}
+                            title="Вернуть прежнее имя спикера"
+                            style={{
+                                padding: '0.4rem 0.8rem',
+                                fontSize: '0.8rem',
+                                borderRadius: 'var(--radius-md)',
+                                border: '1px solid var(--border)',
+                                backgroundColor: 'var(--surface-strong)',
+                                color: 'var(--text-secondary)',
+                                cursor: 'pointer',
+                            }}
+                        >
+                            Отменить переименование
+                        </button>
+                    )}
+
+                    {/* Кнопка режима выбора / отмены */}
+                    {canMerge && onMergeSpeakers && (
+                        <button
+                            onClick={isSelectionMode ? handleCancelSelection : () => setIsSelectionMode(true)}
+                            style={{
+                                padding: '0.4rem 0.8rem',
+                                fontSize: '0.8rem',
+                                borderRadius: 'var(--radius-md)',
+                                border: '1px solid var(--border)',
+                                backgroundColor: isSelectionMode ? 'var(--danger)' : 'var(--surface-strong)',
+                                color: isSelectionMode ? 'white' : 'var(--text-secondary)',
+                                cursor: 'pointer',
+                            }}
+                        >
+                            {isSelectionMode ? 'Отмена' : 'Объединить'}
+                        </button>
+                    )}
+                </div>
             </div>
 
             {/* Список спикеров */}
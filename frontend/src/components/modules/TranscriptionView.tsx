@@ -38,6 +38,7 @@ interface TranscriptionViewProps {
     onRetranscribeAll?: () => void;
     // Speaker management
     onRenameSpeaker?: (localId: number, newName: string, saveAsVoiceprint: boolean) => void;
+    onUndoSpeakerRename?: () => void;
     onMergeSpeakers?: (sourceSpeakerIds: number[], targetSpeakerId: number, newName: string, mergeEmbeddings: boolean, saveAsVoiceprint: boolean) => void;
     onPlaySpeakerSample?: (localId: number) => void;
     onStopSpeakerSample?: () => void;
@@ -54,6 +55,7 @@ export const TranscriptionView: React.FC<TranscriptionViewProps> = ({
     sessionSpeakers = [],
     onRetranscribeAll,
     onRenameSpeaker,
+    onUndoSpeakerRename,
     onMergeSpeakers,
     onPlaySpeakerSample,
     onStopSpeakerSample,
@@ -570,6 +572,7 @@ export const TranscriptionView: React.FC<TranscriptionViewProps> = ({
                                 sessionId={displaySession.id}
                                 speakers={sessionSpeakers}
                                 onRename={onRenameSpeaker}
+                                onUndoRename={onUndoSpeakerRename}
                                 onMergeSpeakers={onMergeSpeakers}
                                 onPlaySample={onPlaySpeakerSample}
                                 onStopSample={onStopSpeakerSample}
@@ -208,12 +208,16 @@ export const SessionProvider: React.FC<{ children: React.ReactNode }> = ({ child
             setFullTranscriptionSessionId(null);
         });
 
-        const unsubFullCancelled = subscribe('full_transcription_cancelled', () => {
+        const unsubFullCancelled = subscribe('full_transcription_cancelled', (msg) => {
             setIsFullTranscribing(false);
             setFullTranscriptionProgress(0);
             setFullTranscriptionStatus(null);
             setFullTranscriptionError(null);
             setFullTranscriptionSessionId(null);
+            // Уже обработанные чанки сохранены - показываем частичный результат
+            if (msg.session) {
+                setSelectedSession(msg.session);
+            }
         });
 
         return () => {